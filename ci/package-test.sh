@@ -16,14 +16,6 @@
 
 set -euo pipefail
 
-export GEM_HOME=$PWD/gems
-export LANG=en_US.UTF-8
-export LANGUAGE=en_US.UTF-8
-export LC_ALL=en_US.UTF-8
-
-eval "$(rbenv init -)"
-
 pushd java-buildpack
-  bundle install --quiet
-  bundle exec rake package
+  ./scripts/package.sh --cached
 popd
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"go.yaml.in/yaml/v3"
+)
+
+// defaultPrefix is the prefix of the operator defaults that common.MigrateLegacyConfig applies as JBP_CONFIG_*
+const defaultPrefix = "JBP_DEFAULT_"
+
+// finding is a problem with one configuration variable of an application
+type finding struct {
+	File    string
+	App     string
+	Var     string
+	Message string
+}
+
+func (f finding) String() string {
+	parts := []string{f.File}
+	if f.App != "" {
+		parts = append(parts, f.App)
+	}
+	if f.Var != "" {
+		parts = append(parts, f.Var)
+	}
+	return strings.Join(append(parts, f.Message), ": ")
+}
+
+// appEnv is the environment of one application read from a file
+type appEnv struct {
+	app string
+	env map[string]string
+}
+
+// lint validates the JBP_CONFIG_* and JBP_DEFAULT_* variables in every manifest, env file and environment JSON file
+// below paths. It returns the findings and the number of files that held an environment.
+func lint(paths []string) ([]finding, int, error) {
+	var findings []finding
+	files := 0
+
+	for _, root := range paths {
+		err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				if path != root && strings.HasPrefix(entry.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			apps, recognized, err := readAppEnvs(path)
+			if err != nil {
+				findings = append(findings, finding{File: path, Message: err.Error()})
+				return nil
+			}
+			if !recognized {
+				return nil
+			}
+			files++
+
+			for _, app := range apps {
+				for _, name := range sortedNames(app.env) {
+					for _, message := range checkVar(name, app.env[name]) {
+						findings = append(findings, finding{File: path, App: app.app, Var: name, Message: message})
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, files, err
+		}
+	}
+	return findings, files, nil
+}
+
+// readAppEnvs reads the application environments in path, returning false for files that hold none:
+//   - application manifests (*.yml, *.yaml): the env of every application and the top-level env
+//   - env files (.env, *.env): KEY=VALUE lines
+//   - environment JSON (*.json) as returned by 'cf curl /v3/apps/<guid>/environment_variables' or
+//     '/v3/apps/<guid>/env'
+func readAppEnvs(path string) ([]appEnv, bool, error) {
+	name := filepath.Base(path)
+	switch ext := filepath.Ext(name); {
+	case ext == ".yml" || ext == ".yaml":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, false, err
+		}
+		return readManifest(data)
+	case ext == ".env" || name == ".env":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, false, err
+		}
+		env, err := readEnvFile(data)
+		return []appEnv{{env: env}}, true, err
+	case ext == ".json":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, false, err
+		}
+		return readEnvJSON(data)
+	default:
+		return nil, false, nil
+	}
+}
+
+// readManifest reads an application manifest. YAML files without applications or env are not manifests.
+func readManifest(data []byte) ([]appEnv, bool, error) {
+	var manifest struct {
+		Env          map[string]interface{} `yaml:"env"`
+		Applications []struct {
+			Name string                 `yaml:"name"`
+			Env  map[string]interface{} `yaml:"env"`
+		} `yaml:"applications"`
+	}
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		// Other YAML files, e.g. CI pipelines, may not decode as a manifest
+		return nil, false, nil
+	}
+	if manifest.Env == nil && len(manifest.Applications) == 0 {
+		return nil, false, nil
+	}
+
+	var apps []appEnv
+	if manifest.Env != nil {
+		env, err := stringValues(manifest.Env)
+		if err != nil {
+			return nil, true, err
+		}
+		apps = append(apps, appEnv{env: env})
+	}
+	for _, app := range manifest.Applications {
+		env, err := stringValues(app.Env)
+		if err != nil {
+			return nil, true, fmt.Errorf("application %s: %w", app.Name, err)
+		}
+		apps = append(apps, appEnv{app: app.Name, env: env})
+	}
+	return apps, true, nil
+}
+
+// stringValues returns the manifest env values as the strings the application sees. A mapping or sequence, e.g. an
+// unquoted JBP_CONFIG_* flow mapping, is converted back to YAML.
+func stringValues(env map[string]interface{}) (map[string]string, error) {
+	values := make(map[string]string, len(env))
+	for name, value := range env {
+		switch v := value.(type) {
+		case string:
+			values[name] = v
+		case map[string]interface{}, []interface{}:
+			data, err := yaml.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			values[name] = string(data)
+		case nil:
+			values[name] = ""
+		default:
+			values[name] = fmt.Sprint(v)
+		}
+	}
+	return values, nil
+}
+
+// readEnvFile reads KEY=VALUE lines, ignoring blank lines, comments and an export prefix. A value may be enclosed in
+// single or double quotes.
+func readEnvFile(data []byte) (map[string]string, error) {
+	env := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", number)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '\'' || value[0] == '"') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		env[strings.TrimSpace(name)] = value
+	}
+	return env, scanner.Err()
+}
+
+// readEnvJSON reads the user-provided variables of an environment JSON file
+func readEnvJSON(data []byte) ([]appEnv, bool, error) {
+	var document struct {
+		Var                  map[string]interface{} `json:"var"`
+		EnvironmentVariables map[string]interface{} `json:"environment_variables"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, false, nil
+	}
+
+	vars := document.Var
+	if vars == nil {
+		vars = document.EnvironmentVariables
+	}
+	if vars == nil {
+		return nil, false, nil
+	}
+
+	env := make(map[string]string, len(vars))
+	for name, value := range vars {
+		if s, ok := value.(string); ok {
+			env[name] = s
+		} else {
+			env[name] = fmt.Sprint(value)
+		}
+	}
+	return []appEnv{{env: env}}, true, nil
+}
+
+// checkVar returns the problems of one environment variable. Variables other than JBP_CONFIG_* and JBP_DEFAULT_*
+// are ignored.
+func checkVar(name, value string) []string {
+	var configName string
+	switch {
+	case strings.HasPrefix(name, config.EnvPrefix):
+		configName = name
+	case strings.HasPrefix(name, defaultPrefix):
+		configName = config.EnvPrefix + strings.TrimPrefix(name, defaultPrefix)
+	default:
+		return nil
+	}
+
+	var problems []string
+	if target, guidance, ok := common.LegacyConfig(configName); ok {
+		problems = append(problems, "Ruby buildpack setting: "+guidance)
+		if target == "" {
+			return problems
+		}
+		configName = target
+	}
+
+	component, known := config.ComponentForEnvVar(configName)
+	if !known {
+		return append(problems, fmt.Sprintf("no buildpack component reads %s", configName))
+	}
+	return append(problems, config.Validate(component, value)...)
+}
+
+func sortedNames(env map[string]string) []string {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("jbp-lint", func() {
+	var (
+		dir    string
+		stdout *bytes.Buffer
+		stderr *bytes.Buffer
+	)
+
+	writeFile := func(name, content string) {
+		path := filepath.Join(dir, name)
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(content), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "jbp-lint")
+		Expect(err).NotTo(HaveOccurred())
+		stdout = new(bytes.Buffer)
+		stderr = new(bytes.Buffer)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("passes valid configuration", func() {
+		writeFile("orders/manifest.yml", `applications:
+- name: orders
+  env:
+    JBP_CONFIG_OPEN_JDK_JRE: '{jre: {version: 21.+}, memory_calculator: {stack_threads: 200}}'
+    JBP_CONFIG_TOMCAT: '{tomcat: {version: 10.+}}'
+    JBP_CONFIG_GRAAL_VM_JRE: '{native_image: {enabled: true}}'
+    SPRING_PROFILES_ACTIVE: cloud
+`)
+
+		Expect(run([]string{dir}, stdout, stderr)).To(Equal(0))
+		Expect(stdout.String()).To(BeEmpty())
+		Expect(stderr.String()).To(Equal("0 problem(s) in 1 file(s)\n"))
+	})
+
+	It("reports problems in every application of a manifest", func() {
+		writeFile("manifest.yml", `applications:
+- name: orders
+  env:
+    JBP_CONFIG_JMX: '{enabled: true, prot: 5000}'
+- name: billing
+  env:
+    JBP_CONFIG_OPEN_JDK_JRE: '{memory_calculator: {headroom: 120}}'
+    JBP_CONFIG_DEBUG: '{enabled: yes please}'
+`)
+
+		Expect(run([]string{dir}, stdout, stderr)).To(Equal(1))
+		manifest := filepath.Join(dir, "manifest.yml")
+		Expect(stdout.String()).To(Equal(
+			manifest + ": orders: JBP_CONFIG_JMX: line 1: field prot not found in type frameworks.jmxConfig\n" +
+				manifest + ": billing: JBP_CONFIG_DEBUG: line 1: cannot unmarshal !!str `yes please` into bool\n" +
+				manifest + ": billing: JBP_CONFIG_OPEN_JDK_JRE: headroom must be a percentage between 0 and 99, found 120\n"))
+	})
+
+	It("reads env files and operator defaults", func() {
+		writeFile("ops.env", `# running environment variable group
+export JBP_DEFAULT_FEATURE_FLAGS="{app_cds: true, turbo: true}"
+JBP_CONFIG_SPRING_AUTO_RECONFIGURATION='[enabled: 1]'
+`)
+
+		Expect(run([]string{filepath.Join(dir, "ops.env")}, stdout, stderr)).To(Equal(1))
+		Expect(stdout.String()).To(ContainSubstring(`JBP_DEFAULT_FEATURE_FLAGS: unknown feature flag "turbo"`))
+		Expect(stdout.String()).To(ContainSubstring("JBP_CONFIG_SPRING_AUTO_RECONFIGURATION: line 1: cannot unmarshal !!int `1` into bool"))
+	})
+
+	It("reads environment JSON and reports legacy and unknown variables", func() {
+		writeFile("env.json", `{"var": {"JBP_CONFIG_JREBEL_AGENT": "{enabled: true}", "JBP_CONFIG_TAKIPI_AGENT": "{}",`+
+			` "JBP_CONFIG_FOO": "{}"}}`)
+
+		Expect(run([]string{dir}, stdout, stderr)).To(Equal(1))
+		Expect(stdout.String()).To(ContainSubstring("JBP_CONFIG_FOO: no buildpack component reads JBP_CONFIG_FOO"))
+		Expect(stdout.String()).To(ContainSubstring("JBP_CONFIG_JREBEL_AGENT: Ruby buildpack setting: rename the variable to JBP_CONFIG_JREBEL"))
+		Expect(stdout.String()).To(ContainSubstring("JBP_CONFIG_TAKIPI_AGENT: Ruby buildpack setting: The Takipi (OverOps) agent is not supported"))
+	})
+
+	It("skips files and hidden directories that hold no environment", func() {
+		writeFile("pipeline.yml", "jobs: [{name: build}]\n")
+		writeFile("package.json", `{"name": "frontend"}`)
+		writeFile(".git/manifest.yml", "env: {JBP_CONFIG_FOO: '{}'}\n")
+
+		Expect(run([]string{dir}, stdout, stderr)).To(Equal(0))
+		Expect(stderr.String()).To(Equal("0 problem(s) in 0 file(s)\n"))
+	})
+
+	It("lists the known configuration variables", func() {
+		Expect(run([]string{"-list"}, stdout, stderr)).To(Equal(0))
+		Expect(stdout.String()).To(ContainSubstring("JBP_CONFIG_OPEN_JDK_JRE\n"))
+		Expect(stdout.String()).To(ContainSubstring("JBP_CONFIG_TOMCAT\n"))
+	})
+
+	It("fails without a path", func() {
+		Expect(run(nil, stdout, stderr)).To(Equal(2))
+		Expect(stderr.String()).To(ContainSubstring("Usage: jbp-lint"))
+	})
+})
@@ -0,0 +1,72 @@
+// Command jbp-lint validates the JBP_CONFIG_* and JBP_DEFAULT_* variables of application manifests, env files and
+// environment JSON files against the configuration schemas of the buildpack, so that operators can check the
+// applications of a foundation before rolling out a new buildpack version.
+//
+// Usage:
+//
+//	jbp-lint [-list] PATH...
+//
+// Every PATH is a file or a directory that is searched recursively. Problems are printed one per line as
+// file: application: variable: problem. The exit status is 1 if any problem is found and 2 if the files cannot be
+// read.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	_ "github.com/cloudfoundry/java-buildpack/src/java/common/features" // Register configuration schemas
+	_ "github.com/cloudfoundry/java-buildpack/src/java/containers"
+	_ "github.com/cloudfoundry/java-buildpack/src/java/finalize"
+	_ "github.com/cloudfoundry/java-buildpack/src/java/frameworks"
+	_ "github.com/cloudfoundry/java-buildpack/src/java/jres"
+	_ "github.com/cloudfoundry/java-buildpack/src/java/supply"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("jbp-lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	list := flags.Bool("list", false, "list the configuration variables known to the buildpack and exit")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: jbp-lint [-list] PATH...")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *list {
+		for _, component := range config.Components() {
+			fmt.Fprintln(stdout, config.EnvVar(component))
+		}
+		return 0
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	findings, files, err := lint(flags.Args())
+	if err != nil {
+		fmt.Fprintf(stderr, "jbp-lint: %s\n", err.Error())
+		return 2
+	}
+
+	for _, f := range findings {
+		fmt.Fprintln(stdout, f.String())
+	}
+	fmt.Fprintf(stderr, "%d problem(s) in %d file(s)\n", len(findings), files)
+
+	if len(findings) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,143 @@
+// Command manifest-check validates the manifest.yml of the buildpack before a release, so that a dependency that
+// cannot be downloaded or installed fails the build of the release instead of the staging of applications.
+//
+// Usage:
+//
+//	manifest-check [-dir DIR] [-offline | -download]
+//
+// The manifest must match the schema of manifest.yml, which rejects unknown keys, and every dependency needs a name,
+// a semantic version, an http(s) URI, a SHA-256 checksum and at least one stack. The URI of every dependency must
+// be reachable; -download also downloads the dependencies and verifies their checksums, while -offline skips the
+// network checks. Every dependency that the Go code of the buildpack looks up in the manifest must be listed in it,
+// unless operators add it to the manifest themselves (see operatorDependencies).
+//
+// Problems are printed one per line as file: problem. The exit status is 1 if any problem is found and 2 if the
+// manifest or the code cannot be read.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// sourceDir is the directory of the Go code of the buildpack, relative to its root
+const sourceDir = "src/java"
+
+// operatorDependencies are looked up by the buildpack but are not part of its manifest: operators add them to the
+// manifest of their buildpack, e.g. because their licenses do not allow redistribution. The buildpack warns or fails
+// staging with a clear message if one of them is used but missing.
+var operatorDependencies = map[string]bool{
+	// JREs
+	"graalvm": true,
+	"ibm":     true,
+	"oracle":  true,
+	"semeru":  true,
+	"zing":    true,
+
+	// Frameworks
+	"appdynamics":                   true,
+	"container-customizer":          true,
+	"introscope-agent":              true,
+	"metrics-forwarder-agent":       true,
+	"protect-app-security-provider": true,
+	"riverbed-appinternals-agent":   true,
+
+	// Tomcat
+	"tomcat-external-configuration": true,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("manifest-check", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	dir := flags.String("dir", ".", "root of the buildpack")
+	offline := flags.Bool("offline", false, "skip the checks that need the network")
+	download := flags.Bool("download", false, "download every dependency and verify its SHA-256 checksum")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: manifest-check [-dir DIR] [-offline | -download]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 || (*offline && *download) {
+		flags.Usage()
+		return 2
+	}
+
+	c := checker{
+		dir:      *dir,
+		offline:  *offline,
+		remote:   remoteChecker{client: &http.Client{Timeout: 30 * time.Minute}, download: *download},
+		optional: operatorDependencies,
+	}
+	problems, err := c.check()
+	if err != nil {
+		fmt.Fprintf(stderr, "manifest-check: %s\n", err.Error())
+		return 2
+	}
+
+	for _, problem := range problems {
+		fmt.Fprintln(stdout, problem)
+	}
+	fmt.Fprintf(stderr, "%d problem(s)\n", len(problems))
+
+	if len(problems) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// checker checks the manifest of a buildpack
+type checker struct {
+	dir     string
+	offline bool
+	remote  remoteChecker
+	// optional are the dependencies that may be looked up although the manifest does not list them
+	optional map[string]bool
+}
+
+// check returns the problems of the manifest, each prefixed with the file it was found in
+func (c checker) check() ([]string, error) {
+	m, err := loadManifest(filepath.Join(c.dir, "manifest.yml"))
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	for _, problem := range m.validate() {
+		problems = append(problems, "manifest.yml: "+problem)
+	}
+
+	references, err := findReferences(c.dir, sourceDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range references {
+		if len(m.versions(ref.Name)) == 0 && !c.optional[ref.Name] {
+			problems = append(problems, fmt.Sprintf("%s: dependency %s is not in manifest.yml", ref.Position, ref.Name))
+		}
+	}
+
+	if !c.offline {
+		// Dependencies without a valid URI are reported by validate already
+		var deps []dependency
+		for _, dep := range m.Dependencies {
+			if isHTTPURL(dep.URI) {
+				deps = append(deps, dep)
+			}
+		}
+		for _, problem := range c.remote.check(deps) {
+			problems = append(problems, "manifest.yml: "+problem)
+		}
+	}
+	return problems, nil
+}
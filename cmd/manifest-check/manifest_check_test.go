@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("manifest-check", func() {
+	var (
+		dir    string
+		server *httptest.Server
+		heads  int
+		stdout *bytes.Buffer
+		stderr *bytes.Buffer
+	)
+
+	agent := []byte("agent jar")
+	agentSum := sha256.Sum256(agent)
+	agentSHA := hex.EncodeToString(agentSum[:])
+
+	writeFile := func(name, content string) {
+		path := filepath.Join(dir, name)
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(content), 0644)).To(Succeed())
+	}
+
+	writeManifest := func(dependencies string) {
+		writeFile("manifest.yml", `---
+language: java
+default_versions:
+- name: agent
+  version: 1.x
+dependency_licenses:
+  agent:
+  - type: Apache-2.0
+    uri: https://www.apache.org/licenses/LICENSE-2.0
+dependency_deprecation_dates:
+- version_line: 1.x
+  name: agent
+  date: 2030-01-31
+  link: https://example.com/agent
+  match: 1\.\d+\.\d+
+dependencies:
+`+dependencies)
+	}
+
+	dependency := func(name, version, path, sha string) string {
+		return fmt.Sprintf(`- name: %s
+  version: %s
+  uri: %s%s
+  sha256: %s
+  cf_stacks:
+  - cflinuxfs4
+`, name, version, server.URL, path, sha)
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "manifest-check")
+		Expect(err).NotTo(HaveOccurred())
+		stdout = new(bytes.Buffer)
+		stderr = new(bytes.Buffer)
+
+		heads = 0
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/agent-1.2.0.jar":
+				if r.Method == http.MethodHead {
+					heads++
+				}
+				w.Write(agent)
+			case "/no-head.jar":
+				if r.Method == http.MethodHead {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				w.Write(agent)
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+
+		writeManifest(dependency("agent", "1.2.0", "/agent-1.2.0.jar", agentSHA))
+		writeFile("src/java/frameworks/agent.go", `package frameworks
+
+const agentDependency = "agent"
+
+func (a *Agent) Supply() error {
+	dep, err := a.context.Manifest.DefaultVersion(agentDependency)
+	return a.context.Installer.InstallDependency(dep, a.dir)
+}
+`)
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(dir)
+	})
+
+	check := func(args ...string) int {
+		return run(append([]string{"-dir", dir}, args...), stdout, stderr)
+	}
+
+	It("accepts a valid manifest whose dependencies can be reached", func() {
+		Expect(check()).To(Equal(0), stdout.String())
+		Expect(stdout.String()).To(BeEmpty())
+		Expect(stderr.String()).To(Equal("0 problem(s)\n"))
+		Expect(heads).To(Equal(1))
+	})
+
+	It("rejects keys that the schema does not declare", func() {
+		writeManifest(dependency("agent", "1.2.0", "/agent-1.2.0.jar", agentSHA) + "  cf_stack: cflinuxfs4\n")
+
+		Expect(check("-offline")).To(Equal(2))
+		Expect(stderr.String()).To(ContainSubstring("does not match the manifest schema"))
+		Expect(stderr.String()).To(ContainSubstring("field cf_stack not found"))
+	})
+
+	It("reports malformed dependencies", func() {
+		writeManifest(dependency("agent", "1.2.0", "/agent-1.2.0.jar", agentSHA) + `- name: driver
+  version: latest
+  uri: ftp://example.com/driver.jar
+  sha256: ABC
+`)
+
+		Expect(check("-offline")).To(Equal(1))
+		Expect(stdout.String()).To(Equal(`manifest.yml: driver latest: version "latest" is not a semantic version
+manifest.yml: driver latest: uri "ftp://example.com/driver.jar" is not an http or https URL
+manifest.yml: driver latest: sha256 "ABC" is not a lowercase hex SHA-256 checksum
+manifest.yml: driver latest: cf_stacks is empty
+`))
+	})
+
+	It("reports default versions that match no dependency", func() {
+		writeManifest(dependency("agent", "2.0.0", "/agent-1.2.0.jar", agentSHA))
+
+		Expect(check("-offline")).To(Equal(1))
+		Expect(stdout.String()).To(ContainSubstring("manifest.yml: default_versions: agent 1.x matches no dependency"))
+	})
+
+	It("reports dependencies that cannot be reached", func() {
+		writeManifest(dependency("agent", "1.2.0", "/agent-1.2.0.jar", agentSHA) +
+			dependency("agent", "1.1.0", "/missing.jar", agentSHA))
+
+		Expect(check()).To(Equal(1))
+		Expect(stdout.String()).To(Equal(fmt.Sprintf("manifest.yml: agent 1.1.0: uri %s/missing.jar is not reachable: 404 Not Found\n", server.URL)))
+	})
+
+	It("retries with GET if the server rejects HEAD requests", func() {
+		writeManifest(dependency("agent", "1.2.0", "/no-head.jar", agentSHA))
+
+		Expect(check()).To(Equal(0), stdout.String())
+	})
+
+	It("verifies the checksums of downloaded dependencies", func() {
+		wrong := "0000000000000000000000000000000000000000000000000000000000000000"
+		writeManifest(dependency("agent", "1.2.0", "/agent-1.2.0.jar", wrong))
+
+		Expect(check()).To(Equal(0), stdout.String())
+		Expect(check("-download")).To(Equal(1))
+		Expect(stdout.String()).To(Equal(fmt.Sprintf("manifest.yml: agent 1.2.0: sha256 of %s/agent-1.2.0.jar is %s, manifest.yml expects %s\n",
+			server.URL, agentSHA, wrong)))
+		Expect(heads).To(Equal(1))
+	})
+
+	It("reports dependencies that the code looks up but the manifest does not list", func() {
+		writeFile("src/java/frameworks/driver.go", `package frameworks
+
+import "github.com/cloudfoundry/libbuildpack"
+
+var driverNames = []string{"driver-ng", "driver"}
+
+func (d *Driver) Supply() error {
+	for _, name := range driverNames {
+		d.context.Manifest.DefaultVersion(name)
+	}
+	dependency := "driver-cli"
+	d.context.Manifest.AllDependencyVersions(dependency)
+	return d.context.Installer.InstallDependency(libbuildpack.Dependency{Name: "driver-jar"}, d.dir)
+}
+
+func (d *Driver) DependencyIdentifier() string {
+	return "driver-plugin"
+}
+`)
+		writeFile("src/java/frameworks/driver_test.go", `package frameworks_test
+
+var _ = manifest.DefaultVersion("test-only")
+`)
+
+		Expect(check("-offline")).To(Equal(1))
+		Expect(stdout.String()).To(Equal(`src/java/frameworks/driver.go:9: dependency driver is not in manifest.yml
+src/java/frameworks/driver.go:12: dependency driver-cli is not in manifest.yml
+src/java/frameworks/driver.go:13: dependency driver-jar is not in manifest.yml
+src/java/frameworks/driver.go:9: dependency driver-ng is not in manifest.yml
+src/java/frameworks/driver.go:17: dependency driver-plugin is not in manifest.yml
+`))
+	})
+
+	It("accepts dependencies that operators add to the manifest", func() {
+		writeFile("src/java/jres/oracle.go", `package jres
+
+func (o *OracleJRE) Supply() error {
+	_, err := GetJREVersion(o.ctx, "oracle")
+	return err
+}
+`)
+
+		Expect(check("-offline")).To(Equal(0), stdout.String())
+	})
+
+	It("rejects -offline with -download", func() {
+		Expect(check("-offline", "-download")).To(Equal(2))
+		Expect(stderr.String()).To(ContainSubstring("Usage: manifest-check"))
+	})
+
+	It("accepts the manifest of the buildpack", func() {
+		root, err := filepath.Abs(filepath.Join("..", ".."))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(run([]string{"-dir", root, "-offline"}, stdout, stderr)).To(Equal(0), stdout.String())
+	})
+})
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// lookupFuncs are the functions that look a dependency up in the manifest, by the index of their name argument
+var lookupFuncs = map[string]int{
+	"DefaultVersion":        0,
+	"AllDependencyVersions": 0,
+	"InstallOnlyVersion":    0,
+	"GetJREVersion":         1,
+}
+
+// reference is a dependency name that the buildpack looks up in the manifest
+type reference struct {
+	Name string
+	// Position is the file and line of the lookup, relative to the root of the buildpack
+	Position string
+}
+
+// findReferences returns the dependency names that the Go code below src looks up in the manifest. Names are found in string literals and in string constants and variables of
+// the same package passed to lookupFuncs, in the Name of libbuildpack.Dependency literals and in the return value
+// of DependencyIdentifier methods; names computed at runtime, e.g. from configuration, are not found.
+func findReferences(dir, src string) ([]reference, error) {
+	var references []reference
+
+	packages := map[string][]*ast.File{}
+	fset := token.NewFileSet()
+	err := filepath.WalkDir(filepath.Join(dir, src), func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if entry.Name() == "vendor" || entry.Name() == "testdata" || strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		file, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return err
+		}
+		packages[filepath.Dir(path)] = append(packages[filepath.Dir(path)], file)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, files := range packages {
+		s := scanner{fset: fset, dir: dir, constants: stringConstants(files)}
+		for _, file := range files {
+			s.scan(file)
+		}
+		references = append(references, s.references...)
+	}
+
+	sort.Slice(references, func(i, j int) bool {
+		if references[i].Name != references[j].Name {
+			return references[i].Name < references[j].Name
+		}
+		return references[i].Position < references[j].Position
+	})
+	return references, nil
+}
+
+// scanner finds the dependency references of the files of a package
+type scanner struct {
+	fset *token.FileSet
+	dir  string
+	// constants are the package-level string constants and variables, and string slices, by name
+	constants  map[string][]string
+	references []reference
+}
+
+func (s *scanner) scan(file *ast.File) {
+	ast.Inspect(file, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.FuncDecl:
+			// Names may be bound to local variables, e.g. dependency := "java-cfenv"
+			locals := s.locals(n)
+			if n.Name.Name == "DependencyIdentifier" && n.Recv != nil {
+				ast.Inspect(n.Body, func(node ast.Node) bool {
+					if ret, ok := node.(*ast.ReturnStmt); ok && len(ret.Results) == 1 {
+						s.add(ret.Results[0], locals)
+					}
+					return true
+				})
+			}
+			ast.Inspect(n, func(node ast.Node) bool {
+				s.inspect(node, locals)
+				return true
+			})
+			return false
+		default:
+			s.inspect(node, nil)
+		}
+		return true
+	})
+}
+
+// inspect adds the references of a lookup call or of a libbuildpack.Dependency literal
+func (s *scanner) inspect(node ast.Node, locals map[string][]string) {
+	switch n := node.(type) {
+	case *ast.CallExpr:
+		var name string
+		switch fun := n.Fun.(type) {
+		case *ast.SelectorExpr:
+			name = fun.Sel.Name
+		case *ast.Ident:
+			name = fun.Name
+		}
+		if index, ok := lookupFuncs[name]; ok && index < len(n.Args) {
+			s.add(n.Args[index], locals)
+		}
+	case *ast.CompositeLit:
+		if sel, ok := n.Type.(*ast.SelectorExpr); !ok || sel.Sel.Name != "Dependency" {
+			return
+		}
+		for _, elt := range n.Elts {
+			if kv, ok := elt.(*ast.KeyValueExpr); ok {
+				if key, ok := kv.Key.(*ast.Ident); ok && key.Name == "Name" {
+					s.add(kv.Value, locals)
+				}
+			}
+		}
+	}
+}
+
+// add records the names that expr evaluates to, if they are known before the buildpack runs
+func (s *scanner) add(expr ast.Expr, locals map[string][]string) {
+	position := s.fset.Position(expr.Pos())
+	rel, err := filepath.Rel(s.dir, position.Filename)
+	if err != nil {
+		rel = position.Filename
+	}
+	for _, name := range s.evaluate(expr, locals) {
+		s.references = append(s.references, reference{Name: name, Position: fmt.Sprintf("%s:%d", filepath.ToSlash(rel), position.Line)})
+	}
+}
+
+// evaluate returns the strings that expr may be: a string literal, a local or package-level string, or an element
+// of a string slice
+func (s *scanner) evaluate(expr ast.Expr, locals map[string][]string) []string {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if value, err := strconv.Unquote(e.Value); err == nil && e.Kind == token.STRING {
+			return []string{value}
+		}
+	case *ast.Ident:
+		if values, ok := locals[e.Name]; ok {
+			return values
+		}
+		return s.constants[e.Name]
+	case *ast.IndexExpr:
+		if ident, ok := e.X.(*ast.Ident); ok {
+			return s.constants[ident.Name]
+		}
+	}
+	return nil
+}
+
+// stringConstants returns the package-level constants and variables of files that are string literals or slices of
+// string literals
+func stringConstants(files []*ast.File) map[string][]string {
+	constants := map[string][]string{}
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || (gen.Tok != token.CONST && gen.Tok != token.VAR) {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				value, ok := spec.(*ast.ValueSpec)
+				if !ok || len(value.Names) != len(value.Values) {
+					continue
+				}
+				for i, name := range value.Names {
+					if values := literalStrings(value.Values[i]); len(values) > 0 {
+						constants[name.Name] = values
+					}
+				}
+			}
+		}
+	}
+	return constants
+}
+
+// locals returns the variables of fn that are assigned string literals, and the range variables of loops over
+// string slice literals or package-level string slices, e.g. for _, name := range []string{"a", "b"}
+func (s *scanner) locals(fn *ast.FuncDecl) map[string][]string {
+	locals := map[string][]string{}
+	ast.Inspect(fn, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.AssignStmt:
+			if n.Tok != token.DEFINE || len(n.Lhs) != len(n.Rhs) {
+				return true
+			}
+			for i, lhs := range n.Lhs {
+				if ident, ok := lhs.(*ast.Ident); ok {
+					if lit, ok := n.Rhs[i].(*ast.BasicLit); ok {
+						locals[ident.Name] = literalStrings(lit)
+					}
+				}
+			}
+		case *ast.RangeStmt:
+			if ident, ok := n.Value.(*ast.Ident); ok {
+				locals[ident.Name] = s.evaluate(n.X, nil)
+				if slice, ok := n.X.(*ast.CompositeLit); ok {
+					locals[ident.Name] = literalStrings(slice)
+				}
+			}
+		}
+		return true
+	})
+	return locals
+}
+
+// literalStrings returns the value of a string literal or the elements of a string slice literal
+func literalStrings(expr ast.Expr) []string {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if value, err := strconv.Unquote(e.Value); err == nil && e.Kind == token.STRING {
+			return []string{value}
+		}
+	case *ast.CompositeLit:
+		var values []string
+		for _, elt := range e.Elts {
+			values = append(values, literalStrings(elt)...)
+		}
+		return values
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// parallelRequests is the number of dependencies that are checked at the same time
+const parallelRequests = 8
+
+// remoteChecker checks the URIs of dependencies
+type remoteChecker struct {
+	client *http.Client
+	// download fetches every dependency and verifies its SHA-256 checksum instead of only checking that its URI can
+	// be reached
+	download bool
+}
+
+// check returns the problems of the URIs of deps, in the order of deps
+func (r remoteChecker) check(deps []dependency) []string {
+	results := make([]string, len(deps))
+
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < parallelRequests; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				if err := r.checkDependency(deps[i]); err != nil {
+					results[i] = fmt.Sprintf("%s: %s", deps[i], err.Error())
+				}
+			}
+		}()
+	}
+	for i := range deps {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	var problems []string
+	for _, result := range results {
+		if result != "" {
+			problems = append(problems, result)
+		}
+	}
+	return problems
+}
+
+// checkDependency returns an error if the URI of dep cannot be reached or, when downloading, if its content does not
+// match the SHA-256 checksum of the manifest
+func (r remoteChecker) checkDependency(dep dependency) error {
+	if !r.download {
+		response, err := r.client.Head(dep.URI)
+		if err == nil {
+			response.Body.Close()
+			// Some servers reject HEAD requests, e.g. presigned URLs with 403 Forbidden, so any other status is retried
+			// with GET
+			if response.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+	}
+
+	response, err := r.client.Get(dep.URI)
+	if err != nil {
+		return fmt.Errorf("uri %s is not reachable: %w", dep.URI, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("uri %s is not reachable: %s", dep.URI, response.Status)
+	}
+	if !r.download {
+		return nil
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, response.Body); err != nil {
+		return fmt.Errorf("failed to download %s: %w", dep.URI, err)
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != dep.SHA256 {
+		return fmt.Errorf("sha256 of %s is %s, manifest.yml expects %s", dep.URI, sum, dep.SHA256)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/cloudfoundry/libbuildpack"
+	"go.yaml.in/yaml/v3"
+)
+
+// sha256Pattern matches a hex-encoded SHA-256 checksum
+var sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// manifest is the schema of manifest.yml. Decoding rejects keys it does not declare, so that a misspelled key, e.g.
+// cf_stack, fails the check instead of being ignored by libbuildpack.
+type manifest struct {
+	Language                   string               `yaml:"language"`
+	Stack                      string               `yaml:"stack"`
+	IncludeFiles               []string             `yaml:"include_files"`
+	PrePackage                 string               `yaml:"pre_package"`
+	PackagingProfiles          map[string]profile   `yaml:"packaging_profiles"`
+	DefaultVersions            []defaultVersion     `yaml:"default_versions"`
+	URLToDependencyMap         []urlMapping         `yaml:"url_to_dependency_map"`
+	DependencyLicenses         map[string][]license `yaml:"dependency_licenses"`
+	DependencyDeprecationDates []deprecationDate    `yaml:"dependency_deprecation_dates"`
+	Dependencies               []dependency         `yaml:"dependencies"`
+}
+
+// profile is a packaging profile, a named set of dependencies that a cached package leaves out
+type profile struct {
+	Description string   `yaml:"description"`
+	Exclude     []string `yaml:"exclude"`
+}
+
+// defaultVersion is the version pattern a dependency is installed with unless the application configures another
+type defaultVersion struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+}
+
+// urlMapping maps the URL of a downloaded file to a dependency name and version, e.g. for the cache of a cached
+// package
+type urlMapping struct {
+	Match   string `yaml:"match"`
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+}
+
+// license is a license of a dependency, as listed in the license report of the droplet
+type license struct {
+	Type string `yaml:"type"`
+	URI  string `yaml:"uri"`
+}
+
+// deprecationDate is the end of support of a version line of a dependency
+type deprecationDate struct {
+	VersionLine string `yaml:"version_line"`
+	Name        string `yaml:"name"`
+	Date        string `yaml:"date"`
+	Link        string `yaml:"link"`
+	Match       string `yaml:"match"`
+}
+
+// dependency is an entry of the dependencies of the manifest
+type dependency struct {
+	Name         string   `yaml:"name"`
+	Version      string   `yaml:"version"`
+	URI          string   `yaml:"uri"`
+	SHA256       string   `yaml:"sha256"`
+	CFStacks     []string `yaml:"cf_stacks"`
+	Source       string   `yaml:"source"`
+	SourceSHA256 string   `yaml:"source_sha256"`
+	File         string   `yaml:"file"`
+}
+
+func (d dependency) String() string {
+	return d.Name + " " + d.Version
+}
+
+// loadManifest reads the manifest.yml at file, rejecting keys that the schema does not declare
+func loadManifest(file string) (*manifest, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &manifest{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(m); err != nil {
+		return nil, fmt.Errorf("%s does not match the manifest schema: %w", file, err)
+	}
+	return m, nil
+}
+
+// validate returns the problems of the manifest that can be found without downloading its dependencies
+func (m *manifest) validate() []string {
+	var problems []string
+	if m.Language == "" {
+		problems = append(problems, "language is not set")
+	}
+
+	seen := map[string]bool{}
+	for i, dep := range m.Dependencies {
+		name := dep.String()
+		if dep.Name == "" || dep.Version == "" {
+			problems = append(problems, fmt.Sprintf("dependency %d has no name or version", i+1))
+			name = fmt.Sprintf("dependency %d", i+1)
+		} else if !parseable(dep.Version) {
+			problems = append(problems, fmt.Sprintf("%s: version %q is not a semantic version", name, dep.Version))
+		}
+		if !isHTTPURL(dep.URI) {
+			problems = append(problems, fmt.Sprintf("%s: uri %q is not an http or https URL", name, dep.URI))
+		}
+		if !sha256Pattern.MatchString(dep.SHA256) {
+			problems = append(problems, fmt.Sprintf("%s: sha256 %q is not a lowercase hex SHA-256 checksum", name, dep.SHA256))
+		}
+		if dep.Source != "" && !isHTTPURL(dep.Source) {
+			problems = append(problems, fmt.Sprintf("%s: source %q is not an http or https URL", name, dep.Source))
+		}
+		if dep.SourceSHA256 != "" && !sha256Pattern.MatchString(dep.SourceSHA256) {
+			problems = append(problems, fmt.Sprintf("%s: source_sha256 %q is not a lowercase hex SHA-256 checksum", name, dep.SourceSHA256))
+		}
+		if len(dep.CFStacks) == 0 {
+			problems = append(problems, fmt.Sprintf("%s: cf_stacks is empty", name))
+		}
+		for _, stack := range dep.CFStacks {
+			key := dep.Name + "\x00" + dep.Version + "\x00" + stack
+			if seen[key] {
+				problems = append(problems, fmt.Sprintf("%s: listed more than once for stack %s", name, stack))
+			}
+			seen[key] = true
+		}
+	}
+
+	for _, def := range m.DefaultVersions {
+		if _, err := libbuildpack.FindMatchingVersion(def.Version, m.versions(def.Name)); err != nil {
+			problems = append(problems, fmt.Sprintf("default_versions: %s %s matches no dependency", def.Name, def.Version))
+		}
+	}
+
+	for _, mapping := range m.URLToDependencyMap {
+		if _, err := regexp.Compile(mapping.Match); err != nil {
+			problems = append(problems, fmt.Sprintf("url_to_dependency_map: match %q of %s is not a regular expression", mapping.Match, mapping.Name))
+		}
+	}
+
+	for _, deprecation := range m.DependencyDeprecationDates {
+		if _, err := time.Parse("2006-01-02", deprecation.Date); err != nil {
+			problems = append(problems, fmt.Sprintf("dependency_deprecation_dates: date %q of %s %s is not YYYY-MM-DD", deprecation.Date, deprecation.Name, deprecation.VersionLine))
+		}
+		if _, err := regexp.Compile(deprecation.Match); err != nil {
+			problems = append(problems, fmt.Sprintf("dependency_deprecation_dates: match %q of %s %s is not a regular expression", deprecation.Match, deprecation.Name, deprecation.VersionLine))
+		}
+	}
+
+	names := make([]string, 0, len(m.DependencyLicenses))
+	for name := range m.DependencyLicenses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, l := range m.DependencyLicenses[name] {
+			if l.Type == "" || !isHTTPURL(l.URI) {
+				problems = append(problems, fmt.Sprintf("dependency_licenses: %s needs a type and an http or https uri", name))
+			}
+		}
+	}
+
+	return problems
+}
+
+// versions returns the versions of the dependency name
+func (m *manifest) versions(name string) []string {
+	var versions []string
+	for _, dep := range m.Dependencies {
+		if dep.Name == name {
+			versions = append(versions, dep.Version)
+		}
+	}
+	return versions
+}
+
+// parseable returns true if libbuildpack can match version, which it parses as a semantic version
+func parseable(version string) bool {
+	_, err := libbuildpack.FindMatchingVersion(version, []string{version})
+	return err == nil
+}
+
+// isHTTPURL returns true if uri is an absolute http or https URL
+func isHTTPURL(uri string) bool {
+	u, err := url.Parse(uri)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
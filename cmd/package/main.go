@@ -0,0 +1,104 @@
+// Command package builds the buildpack zip that is uploaded with cf create-buildpack, without Ruby or the
+// buildpack-packager tool.
+//
+// Usage:
+//
+//	package [-version VERSION] [-stack STACK] [-cached [-profile NAME] [-exclude DEPS] [-include DEPS]] [-output FILE]
+//
+// The manifest.yml of the buildpack is validated first. The files of its include_files are packaged after its
+// pre_package script has run in a copy of the buildpack. A cached package also embeds the manifest dependencies of
+// the stack, except those excluded by the packaging profile or -exclude; they are downloaded to a cache directory
+// and verified against their SHA-256 checksum. The same tree and options always produce the same archive.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("package", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	var opts options
+	var exclude, include string
+	flags.StringVar(&opts.dir, "dir", ".", "root of the buildpack")
+	flags.StringVar(&opts.version, "version", "", "version of the package (default: the VERSION file)")
+	flags.StringVar(&opts.stack, "stack", "cflinuxfs4", `stack of the package, or "any" for all stacks`)
+	flags.BoolVar(&opts.cached, "cached", false, "embed the manifest dependencies in the package")
+	flags.StringVar(&opts.profile, "profile", "", "packaging profile of manifest.yml that excludes dependencies (cached only)")
+	flags.StringVar(&exclude, "exclude", "", "comma-separated dependency names to exclude (cached only)")
+	flags.StringVar(&include, "include", "", "comma-separated dependency names to restore, overriding profile exclusions (cached only)")
+	flags.StringVar(&opts.output, "output", "", "path of the package (default: build/<language>_buildpack[-cached][-<profile>]-<stack>-v<version>.zip)")
+	flags.StringVar(&opts.cacheDir, "cache-dir", "", "directory the downloaded dependencies are kept in (default: the user cache directory)")
+	flags.BoolVar(&opts.verify, "verify", false, "download and verify the dependencies of an uncached package too")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: package [OPTIONS]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return 2
+	}
+
+	opts.exclude = splitList(exclude)
+	opts.include = splitList(include)
+	if opts.version == "" {
+		if version, err := os.ReadFile(filepath.Join(opts.dir, "VERSION")); err == nil {
+			opts.version = strings.TrimSpace(string(version))
+		}
+	}
+	if opts.cacheDir == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			fmt.Fprintf(stderr, "package: %s\n", err.Error())
+			return 2
+		}
+		opts.cacheDir = filepath.Join(cacheDir, "java-buildpack", "dependencies")
+	}
+
+	modified, err := modificationTime()
+	if err != nil {
+		fmt.Fprintf(stderr, "package: %s\n", err.Error())
+		return 2
+	}
+
+	p := &packager{
+		options:  opts,
+		log:      stderr,
+		client:   &http.Client{Timeout: 30 * time.Minute},
+		modified: modified,
+	}
+	output, err := p.build()
+	if err != nil {
+		fmt.Fprintf(stderr, "package: %s\n", err.Error())
+		return 1
+	}
+
+	fmt.Fprintln(stdout, output)
+	return 0
+}
+
+// splitList returns the non-empty entries of the comma-separated list
+func splitList(list string) []string {
+	var entries []string
+	for _, entry := range strings.Split(list, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/cloudfoundry/libbuildpack"
+	"go.yaml.in/yaml/v3"
+)
+
+// sha256Pattern matches a hex-encoded SHA-256 checksum
+var sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// manifest is the part of manifest.yml that packaging reads
+type manifest struct {
+	Language          string                      `yaml:"language"`
+	IncludeFiles      []string                    `yaml:"include_files"`
+	PrePackage        string                      `yaml:"pre_package"`
+	PackagingProfiles map[string]packagingProfile `yaml:"packaging_profiles"`
+	DefaultVersions   []libbuildpack.Dependency   `yaml:"default_versions"`
+	Dependencies      []dependency                `yaml:"dependencies"`
+
+	// node is the parsed document, which the packaged manifest is written from so that the sections packaging does
+	// not read, e.g. dependency_licenses, are kept as they are
+	node yaml.Node
+}
+
+// packagingProfile is a named set of dependencies that a cached package leaves out
+type packagingProfile struct {
+	Description string   `yaml:"description"`
+	Exclude     []string `yaml:"exclude"`
+}
+
+// dependency is an entry of the dependencies of the manifest
+type dependency struct {
+	Name     string   `yaml:"name"`
+	Version  string   `yaml:"version"`
+	URI      string   `yaml:"uri"`
+	SHA256   string   `yaml:"sha256"`
+	CFStacks []string `yaml:"cf_stacks"`
+}
+
+func (d dependency) String() string {
+	return d.Name + " " + d.Version
+}
+
+// supports returns true if the dependency can be installed on stack, or on every stack if stack is "any"
+func (d dependency) supports(stack string) bool {
+	if stack == anyStack {
+		return true
+	}
+	for _, s := range d.CFStacks {
+		if s == stack {
+			return true
+		}
+	}
+	return false
+}
+
+// loadManifest reads the manifest.yml at file
+func loadManifest(file string) (*manifest, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &manifest{}
+	if err := yaml.Unmarshal(data, &m.node); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	if err := m.node.Decode(m); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return m, nil
+}
+
+// validate returns the problems of the manifest: dependencies without a name, version, absolute http(s) URI,
+// SHA-256 checksum or stack, dependencies listed twice for a stack and default versions that match no dependency.
+// Packaging profiles may exclude dependencies that are not listed, e.g. JDBC drivers operators add themselves.
+func (m *manifest) validate() []string {
+	var problems []string
+	if m.Language == "" {
+		problems = append(problems, "language is not set")
+	}
+
+	seen := map[string]bool{}
+	for i, dep := range m.Dependencies {
+		name := dep.String()
+		if dep.Name == "" || dep.Version == "" {
+			problems = append(problems, fmt.Sprintf("dependency %d has no name or version", i+1))
+			name = fmt.Sprintf("dependency %d", i+1)
+		}
+		if u, err := url.Parse(dep.URI); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("%s: uri %q is not an http or https URL", name, dep.URI))
+		}
+		if !sha256Pattern.MatchString(dep.SHA256) {
+			problems = append(problems, fmt.Sprintf("%s: sha256 %q is not a lowercase hex SHA-256 checksum", name, dep.SHA256))
+		}
+		if len(dep.CFStacks) == 0 {
+			problems = append(problems, fmt.Sprintf("%s: cf_stacks is empty", name))
+		}
+		for _, stack := range dep.CFStacks {
+			key := dep.Name + "\x00" + dep.Version + "\x00" + stack
+			if seen[key] {
+				problems = append(problems, fmt.Sprintf("%s: listed more than once for stack %s", name, stack))
+			}
+			seen[key] = true
+		}
+	}
+
+	for _, def := range m.DefaultVersions {
+		if _, err := libbuildpack.FindMatchingVersion(def.Version, m.versions(def.Name)); err != nil {
+			problems = append(problems, fmt.Sprintf("default version %s of %s matches no dependency", def.Version, def.Name))
+		}
+	}
+
+	return problems
+}
+
+// versions returns the versions of the dependency name
+func (m *manifest) versions(name string) []string {
+	var versions []string
+	for _, dep := range m.Dependencies {
+		if dep.Name == name {
+			versions = append(versions, dep.Version)
+		}
+	}
+	return versions
+}
+
+// profileNames returns the names of the packaging profiles in sorted order
+func (m *manifest) profileNames() []string {
+	names := make([]string, 0, len(m.PackagingProfiles))
+	for name := range m.PackagingProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// exclusions returns the names of the dependencies that a cached package leaves out: those of the profile and
+// exclude, except those of include
+func (m *manifest) exclusions(profile string, exclude, include []string) (map[string]bool, error) {
+	excluded := map[string]bool{}
+	if profile != "" {
+		p, ok := m.PackagingProfiles[profile]
+		if !ok {
+			return nil, fmt.Errorf("unknown packaging profile %q, valid values: %s", profile, strings.Join(m.profileNames(), ", "))
+		}
+		for _, name := range p.Exclude {
+			excluded[name] = true
+		}
+	}
+
+	// Names given on the command line must be listed, so that a typo does not silently package a dependency
+	for _, name := range append(append([]string{}, exclude...), include...) {
+		if len(m.versions(name)) == 0 {
+			return nil, fmt.Errorf("unknown dependency %q", name)
+		}
+	}
+	for _, name := range exclude {
+		excluded[name] = true
+	}
+	for _, name := range include {
+		delete(excluded, name)
+	}
+	return excluded, nil
+}
+
+// packaged returns the manifest of the package: the dependencies that do not support stack are removed, the stack is
+// recorded unless it is "any", and the dependencies in files, by their index, refer to the file embedded for them
+func (m *manifest) packaged(stack string, files map[int]string) ([]byte, error) {
+	root := m.node.Content[0]
+
+	if stack != anyStack {
+		setKey(root, "stack", stack)
+	}
+
+	if deps := mappingValue(root, "dependencies"); deps != nil {
+		var kept []*yaml.Node
+		for i, node := range deps.Content {
+			dep := m.Dependencies[i]
+			if !dep.supports(stack) {
+				continue
+			}
+			if file, ok := files[i]; ok {
+				setKey(node, "file", file)
+			}
+			kept = append(kept, node)
+		}
+		deps.Content = kept
+	}
+
+	var b strings.Builder
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&m.node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return []byte("---\n" + b.String()), nil
+}
+
+// dependencyFile returns the path, relative to the root of the package, of the file embedded for dep
+func dependencyFile(dep dependency) string {
+	u, _ := url.Parse(dep.URI)
+	return path.Join("dependencies", dep.SHA256, path.Base(u.Path))
+}
+
+// mappingValue returns the value of key in the mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setKey sets key of the mapping node to the string value, adding it if the mapping has no such key
+func setKey(node *yaml.Node, key, value string) {
+	if existing := mappingValue(node, key); existing != nil {
+		existing.SetString(value)
+		return
+	}
+	v := &yaml.Node{}
+	v.SetString(value)
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, v)
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// anyStack packages the dependencies of every stack and does not restrict the package to a stack
+const anyStack = "any"
+
+// zipEpoch is the modification time of every file in a package unless SOURCE_DATE_EPOCH is set, so that packaging
+// the same tree twice produces the same archive. It is the earliest time a zip file can record.
+var zipEpoch = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// skippedDirs are not copied to the directory pre_package runs in
+var skippedDirs = map[string]bool{".git": true, "build": true}
+
+// options are the options of a package
+type options struct {
+	// dir is the root of the buildpack checkout
+	dir     string
+	version string
+	stack   string
+	cached  bool
+	profile string
+	exclude []string
+	include []string
+	// output is the path of the package, or "" for build/<name> below dir
+	output string
+	// cacheDir holds downloaded dependencies by their SHA-256 checksum
+	cacheDir string
+	// verify downloads and checks the dependencies of an uncached package too
+	verify bool
+}
+
+// packager builds a buildpack package
+type packager struct {
+	options
+	log    io.Writer
+	client *http.Client
+	// modified is the modification time of the files in the package
+	modified time.Time
+}
+
+// validate returns an error if the options cannot be combined
+func (o options) validate() error {
+	if o.version == "" {
+		return fmt.Errorf("no version given and no VERSION file found")
+	}
+	if !o.cached && (o.profile != "" || len(o.exclude) > 0 || len(o.include) > 0) {
+		return fmt.Errorf("-profile, -exclude and -include require -cached")
+	}
+	if len(o.include) > 0 && o.profile == "" {
+		return fmt.Errorf("-include requires -profile")
+	}
+	return nil
+}
+
+// name returns the file name of the package, e.g. java_buildpack-cached-minimal+custom-cflinuxfs4-v5.0.0.zip
+func (o options) name(language string) string {
+	parts := []string{language + "_buildpack"}
+	if o.cached {
+		parts = append(parts, "cached")
+	}
+
+	variant := o.profile
+	if len(o.exclude) > 0 || len(o.include) > 0 {
+		variant = strings.TrimPrefix(variant+"+custom", "+")
+	}
+	if variant != "" {
+		parts = append(parts, variant)
+	}
+
+	if o.stack != anyStack {
+		parts = append(parts, o.stack)
+	}
+	return strings.Join(append(parts, "v"+o.version), "-") + ".zip"
+}
+
+// build packages the buildpack and returns the path of the package
+func (p *packager) build() (string, error) {
+	if err := p.validate(); err != nil {
+		return "", err
+	}
+
+	m, err := loadManifest(filepath.Join(p.dir, "manifest.yml"))
+	if err != nil {
+		return "", err
+	}
+	if problems := m.validate(); len(problems) > 0 {
+		return "", fmt.Errorf("invalid manifest.yml:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	excluded, err := m.exclusions(p.profile, p.exclude, p.include)
+	if err != nil {
+		return "", err
+	}
+
+	stage, err := os.MkdirTemp("", "java-buildpack-package")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(stage)
+
+	if err := p.prePackage(m, stage); err != nil {
+		return "", err
+	}
+
+	files := map[string]string{}
+	for _, include := range m.IncludeFiles {
+		source := filepath.Join(stage, filepath.FromSlash(include))
+		if _, err := os.Stat(source); err != nil {
+			return "", fmt.Errorf("include_files: %w", err)
+		}
+		files[filepath.ToSlash(include)] = source
+	}
+
+	embedded := map[int]string{}
+	for i, dep := range m.Dependencies {
+		if !dep.supports(p.stack) || excluded[dep.Name] || (!p.cached && !p.verify) {
+			continue
+		}
+		cached, err := p.fetch(dep)
+		if err != nil {
+			return "", err
+		}
+		if p.cached {
+			embedded[i] = dependencyFile(dep)
+			files[embedded[i]] = cached
+		}
+	}
+	names := make([]string, 0, len(excluded))
+	for name := range excluded {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(p.log, "Excluding %s\n", name)
+	}
+
+	manifestFile := filepath.Join(stage, "manifest.yml")
+	data, err := m.packaged(p.stack, embedded)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(manifestFile, data, 0644); err != nil {
+		return "", err
+	}
+	files["manifest.yml"] = manifestFile
+
+	versionFile := filepath.Join(stage, "VERSION")
+	if err := os.WriteFile(versionFile, []byte(p.version), 0644); err != nil {
+		return "", err
+	}
+	files["VERSION"] = versionFile
+
+	output := p.output
+	if output == "" {
+		output = filepath.Join(p.dir, "build", p.name(m.Language))
+	}
+	if err := p.archive(output, files); err != nil {
+		return "", err
+	}
+	return output, nil
+}
+
+// prePackage copies the buildpack to stage and runs the pre_package script of the manifest there, so that the
+// binaries it builds do not replace the scripts of the checkout
+func (p *packager) prePackage(m *manifest, stage string) error {
+	err := filepath.WalkDir(p.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(p.dir, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(stage, rel)
+
+		switch {
+		case entry.IsDir() && skippedDirs[rel]:
+			return filepath.SkipDir
+		case entry.IsDir():
+			return os.MkdirAll(target, 0755)
+		case entry.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case !entry.Type().IsRegular():
+			return nil
+		}
+		return copyFile(path, target)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy the buildpack: %w", err)
+	}
+
+	if m.PrePackage == "" {
+		return nil
+	}
+	fmt.Fprintf(p.log, "Running %s\n", m.PrePackage)
+	cmd := exec.Command(filepath.Join(stage, filepath.FromSlash(m.PrePackage)))
+	cmd.Dir = stage
+	cmd.Stdout = p.log
+	cmd.Stderr = p.log
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("pre_package %s failed: %w", m.PrePackage, err)
+	}
+	return nil
+}
+
+// fetch returns the path of the downloaded dep in the cache directory, downloading it if it is not cached. The file
+// is verified against the SHA-256 checksum of the manifest.
+func (p *packager) fetch(dep dependency) (string, error) {
+	file := filepath.Join(p.cacheDir, dep.SHA256)
+	if sum, err := fileSHA256(file); err == nil && sum == dep.SHA256 {
+		fmt.Fprintf(p.log, "Using cached %s\n", dep)
+		return file, nil
+	}
+
+	fmt.Fprintf(p.log, "Downloading %s from %s\n", dep, dep.URI)
+	if err := os.MkdirAll(p.cacheDir, 0755); err != nil {
+		return "", err
+	}
+
+	response, err := p.client.Get(dep.URI)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", dep, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download %s from %s: %s", dep, dep.URI, response.Status)
+	}
+
+	tmp, err := os.CreateTemp(p.cacheDir, dep.SHA256+".*.part")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, hash), response.Body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", dep, err)
+	}
+
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != dep.SHA256 {
+		return "", fmt.Errorf("sha256 of %s downloaded from %s is %s, manifest.yml expects %s", dep, dep.URI, sum, dep.SHA256)
+	}
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return "", err
+	}
+	return file, nil
+}
+
+// archive writes the files, by their path in the package, to a zip file at output. Entries are sorted and carry
+// the same modification time and normalized permissions, so that the same files always produce the same archive.
+func (p *packager) archive(output string, files map[string]string) error {
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	w := zip.NewWriter(out)
+	for _, name := range names {
+		if err := p.addFile(w, name, files[name]); err != nil {
+			return fmt.Errorf("failed to add %s to the package: %w", name, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// addFile adds the file at source to w as name. Directories are added with their files, in sorted order.
+func (p *packager) addFile(w *zip.Writer, name, source string) error {
+	info, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		entries, err := os.ReadDir(source)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := p.addFile(w, name+"/"+entry.Name(), filepath.Join(source, entry.Name())); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	mode := fs.FileMode(0644)
+	if info.Mode()&0111 != 0 {
+		mode = 0755
+	}
+	header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: p.modified}
+	header.SetMode(mode)
+
+	writer, err := w.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	_, err = io.Copy(writer, in)
+	return err
+}
+
+// modificationTime returns the time of SOURCE_DATE_EPOCH, if it is set, or zipEpoch
+func modificationTime() (time.Time, error) {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		return zipEpoch, nil
+	}
+	seconds, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("SOURCE_DATE_EPOCH %q is not a number of seconds", epoch)
+	}
+	if t := time.Unix(seconds, 0).UTC(); t.After(zipEpoch) {
+		return t, nil
+	}
+	return zipEpoch, nil
+}
+
+// copyFile copies the regular file source to target, keeping its permissions
+func copyFile(source, target string) error {
+	info, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// fileSHA256 returns the hex-encoded SHA-256 checksum of the file
+func fileSHA256(file string) (string, error) {
+	in, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, in); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("package", func() {
+	var (
+		dir      string
+		cacheDir string
+		server   *httptest.Server
+		requests int
+		stdout   *bytes.Buffer
+		stderr   *bytes.Buffer
+	)
+
+	agent := []byte("agent jar")
+	agentSHA := sha256.Sum256(agent)
+
+	writeFile := func(name, content string, mode os.FileMode) {
+		path := filepath.Join(dir, name)
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(content), mode)).To(Succeed())
+	}
+
+	writeManifest := func(sha string) {
+		writeFile("manifest.yml", fmt.Sprintf(`---
+language: java
+include_files:
+- README.md
+- VERSION
+- bin/supply
+- manifest.yml
+pre_package: scripts/build.sh
+packaging_profiles:
+  minimal:
+    description: No agents
+    exclude:
+    - jdbc-driver
+default_versions:
+- name: agent
+  version: 1.x
+dependencies:
+- name: agent
+  version: 1.2.0
+  uri: %[1]s/agent-1.2.0.jar
+  sha256: %[2]s
+  cf_stacks:
+  - cflinuxfs4
+- name: agent
+  version: 1.1.0
+  uri: %[1]s/agent-1.1.0.jar
+  sha256: %[2]s
+  cf_stacks:
+  - cflinuxfs3
+- name: jdbc-driver
+  version: 3.0.0
+  uri: %[1]s/jdbc-driver-3.0.0.jar
+  sha256: %[2]s
+  cf_stacks:
+  - cflinuxfs4
+`, server.URL, sha), 0644)
+	}
+
+	// unzip returns the contents of the files of the package by their name
+	unzip := func(file string) map[string]string {
+		r, err := zip.OpenReader(file)
+		Expect(err).NotTo(HaveOccurred())
+		defer r.Close()
+
+		files := map[string]string{}
+		for _, f := range r.File {
+			in, err := f.Open()
+			Expect(err).NotTo(HaveOccurred())
+			content, err := io.ReadAll(in)
+			Expect(err).NotTo(HaveOccurred())
+			in.Close()
+			files[f.Name] = string(content)
+		}
+		return files
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "package")
+		Expect(err).NotTo(HaveOccurred())
+		cacheDir = filepath.Join(dir, ".cache")
+		stdout = new(bytes.Buffer)
+		stderr = new(bytes.Buffer)
+
+		requests = 0
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.Write(agent)
+		}))
+
+		writeFile("README.md", "# Buildpack\n", 0644)
+		writeFile("VERSION", "1.0.0\n", 0644)
+		writeFile("bin/supply", "#!/bin/bash\necho source\n", 0755)
+		writeFile("scripts/build.sh", "#!/bin/bash\necho built > bin/supply\n", 0755)
+		writeManifest(hex.EncodeToString(agentSHA[:]))
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(dir)
+	})
+
+	build := func(args ...string) int {
+		return run(append([]string{"-dir", dir, "-cache-dir", cacheDir}, args...), stdout, stderr)
+	}
+
+	It("packages the include_files after running pre_package in a copy of the buildpack", func() {
+		Expect(build()).To(Equal(0), stderr.String())
+
+		output := filepath.Join(dir, "build", "java_buildpack-cflinuxfs4-v1.0.0.zip")
+		Expect(stdout.String()).To(Equal(output + "\n"))
+
+		files := unzip(output)
+		Expect(files).To(HaveLen(4))
+		Expect(files["README.md"]).To(Equal("# Buildpack\n"))
+		Expect(files["VERSION"]).To(Equal("1.0.0"))
+		Expect(files["bin/supply"]).To(Equal("built\n"))
+		Expect(files["manifest.yml"]).To(ContainSubstring("stack: cflinuxfs4"))
+		Expect(files["manifest.yml"]).NotTo(ContainSubstring("1.1.0"))
+		Expect(files["manifest.yml"]).NotTo(ContainSubstring("file:"))
+		Expect(requests).To(Equal(0))
+
+		source, err := os.ReadFile(filepath.Join(dir, "bin", "supply"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(source)).To(Equal("#!/bin/bash\necho source\n"))
+	})
+
+	It("embeds the dependencies of the stack in a cached package", func() {
+		Expect(build("-cached", "-version", "2.0.0", "-output", filepath.Join(dir, "out", "bp.zip"))).To(Equal(0), stderr.String())
+
+		files := unzip(filepath.Join(dir, "out", "bp.zip"))
+		sha := hex.EncodeToString(agentSHA[:])
+		Expect(files["dependencies/"+sha+"/agent-1.2.0.jar"]).To(Equal("agent jar"))
+		Expect(files["VERSION"]).To(Equal("2.0.0"))
+		Expect(files["manifest.yml"]).To(ContainSubstring("file: dependencies/" + sha + "/agent-1.2.0.jar"))
+		Expect(files["manifest.yml"]).To(ContainSubstring("file: dependencies/" + sha + "/jdbc-driver-3.0.0.jar"))
+		Expect(files["manifest.yml"]).NotTo(ContainSubstring("agent-1.1.0.jar"))
+	})
+
+	It("leaves the dependencies of the profile and -exclude out, except those of -include", func() {
+		Expect(build("-cached", "-profile", "minimal")).To(Equal(0), stderr.String())
+		files := unzip(filepath.Join(dir, "build", "java_buildpack-cached-minimal-cflinuxfs4-v1.0.0.zip"))
+		Expect(files["manifest.yml"]).To(ContainSubstring("jdbc-driver-3.0.0.jar"))
+		Expect(files["manifest.yml"]).NotTo(ContainSubstring("file: dependencies/" + hex.EncodeToString(agentSHA[:]) + "/jdbc-driver-3.0.0.jar"))
+		Expect(stderr.String()).To(ContainSubstring("Excluding jdbc-driver"))
+
+		Expect(build("-cached", "-profile", "minimal", "-include", "jdbc-driver", "-exclude", "agent")).To(Equal(0), stderr.String())
+		files = unzip(filepath.Join(dir, "build", "java_buildpack-cached-minimal+custom-cflinuxfs4-v1.0.0.zip"))
+		Expect(files["manifest.yml"]).To(ContainSubstring("/jdbc-driver-3.0.0.jar"))
+		Expect(files["manifest.yml"]).NotTo(ContainSubstring("file: dependencies/" + hex.EncodeToString(agentSHA[:]) + "/agent-1.2.0.jar"))
+	})
+
+	It("downloads each file once", func() {
+		// The dependencies of the manifest share a checksum, so they are downloaded once
+		Expect(build("-cached")).To(Equal(0), stderr.String())
+		Expect(requests).To(Equal(1))
+		Expect(stderr.String()).To(ContainSubstring("Downloading agent 1.2.0 from " + server.URL + "/agent-1.2.0.jar"))
+		Expect(stderr.String()).To(ContainSubstring("Using cached jdbc-driver 3.0.0"))
+
+		Expect(build("-cached", "-stack", "any")).To(Equal(0), stderr.String())
+		Expect(requests).To(Equal(1))
+		Expect(stderr.String()).To(ContainSubstring("Using cached agent 1.1.0"))
+		Expect(filepath.Join(dir, "build", "java_buildpack-cached-v1.0.0.zip")).To(BeAnExistingFile())
+	})
+
+	It("produces the same archive from the same tree", func() {
+		Expect(build("-cached", "-output", filepath.Join(dir, "first.zip"))).To(Equal(0), stderr.String())
+		Expect(build("-cached", "-output", filepath.Join(dir, "second.zip"))).To(Equal(0), stderr.String())
+
+		first, err := os.ReadFile(filepath.Join(dir, "first.zip"))
+		Expect(err).NotTo(HaveOccurred())
+		second, err := os.ReadFile(filepath.Join(dir, "second.zip"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(first).To(Equal(second))
+	})
+
+	It("fails if a dependency does not match its checksum", func() {
+		writeManifest("0000000000000000000000000000000000000000000000000000000000000000")
+
+		Expect(build("-verify")).To(Equal(1))
+		Expect(stderr.String()).To(ContainSubstring("sha256 of agent 1.2.0 downloaded from " + server.URL + "/agent-1.2.0.jar is " +
+			hex.EncodeToString(agentSHA[:]) + ", manifest.yml expects 0000000000000000000000000000000000000000000000000000000000000000"))
+		Expect(filepath.Join(dir, "build")).NotTo(BeADirectory())
+	})
+
+	It("reports the problems of the manifest", func() {
+		writeFile("manifest.yml", `---
+language: java
+default_versions:
+- name: agent
+  version: 2.x
+dependencies:
+- name: agent
+  version: 1.2.0
+  uri: ftp://example.com/agent.jar
+  sha256: ABC
+  cf_stacks: []
+`, 0644)
+
+		Expect(build()).To(Equal(1))
+		Expect(stderr.String()).To(ContainSubstring(`agent 1.2.0: uri "ftp://example.com/agent.jar" is not an http or https URL`))
+		Expect(stderr.String()).To(ContainSubstring(`agent 1.2.0: sha256 "ABC" is not a lowercase hex SHA-256 checksum`))
+		Expect(stderr.String()).To(ContainSubstring("agent 1.2.0: cf_stacks is empty"))
+		Expect(stderr.String()).To(ContainSubstring("default version 2.x of agent matches no dependency"))
+	})
+
+	It("rejects options that cannot be combined", func() {
+		Expect(build("-profile", "minimal")).To(Equal(1))
+		Expect(stderr.String()).To(ContainSubstring("-profile, -exclude and -include require -cached"))
+
+		Expect(build("-cached", "-include", "agent")).To(Equal(1))
+		Expect(stderr.String()).To(ContainSubstring("-include requires -profile"))
+
+		Expect(build("-cached", "-profile", "tiny")).To(Equal(1))
+		Expect(stderr.String()).To(ContainSubstring(`unknown packaging profile "tiny", valid values: minimal`))
+
+		Expect(build("-cached", "-exclude", "agnet")).To(Equal(1))
+		Expect(stderr.String()).To(ContainSubstring(`unknown dependency "agnet"`))
+	})
+})
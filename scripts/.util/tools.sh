@@ -45,7 +45,7 @@ function util::tools::ginkgo::install() {
   fi
 }
 
-function util::tools::buildpack-packager::install() {
+function util::tools::benchstat::install() {
   local dir
   while [[ "${#}" != 0 ]]; do
     case "${1}" in
@@ -62,13 +62,13 @@ function util::tools::buildpack-packager::install() {
   mkdir -p "${dir}"
   util::tools::path::export "${dir}"
 
-  if [[ ! -f "${dir}/buildpack-packager" ]]; then
-    util::print::title "Installing buildpack-packager"
+  if [[ ! -f "${dir}/benchstat" ]]; then
+    util::print::title "Installing benchstat"
 
     pushd /tmp > /dev/null || return
       GOBIN="${dir}" \
         go install \
-          github.com/cloudfoundry/libbuildpack/packager/buildpack-packager@latest
+          golang.org/x/perf/cmd/benchstat@latest
     popd > /dev/null || return
   fi
 }
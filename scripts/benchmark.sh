@@ -0,0 +1,156 @@
+#!/usr/bin/env bash
+
+set -euo pipefail
+
+ROOTDIR="$(cd "$(dirname "${BASH_SOURCE[0]}")/.." && pwd)"
+readonly ROOTDIR
+
+# shellcheck source=SCRIPTDIR/.util/print.sh
+source "${ROOTDIR}/scripts/.util/print.sh"
+
+# shellcheck source=SCRIPTDIR/.util/tools.sh
+source "${ROOTDIR}/scripts/.util/tools.sh"
+
+function usage() {
+  cat <<-USAGE
+benchmark.sh [OPTIONS]
+
+Runs the staging benchmarks and, with --baseline, compares them with the benchmarks of another commit.
+
+OPTIONS
+  --help                         -h  prints the command usage
+  --baseline <ref>                   Git ref to compare with, e.g. origin/main
+  --count <n>                        Number of times each benchmark runs (default: 6)
+  --threshold <percent>              Slowdown of a phase that fails the comparison (default: 20)
+  --fat-jar-size <mb>                Size of the fat JAR fixture in MB (default: 300)
+
+EXAMPLES
+  # Run the benchmarks
+  ./scripts/benchmark.sh
+
+  # Fail if a phase is more than 20% slower than on main
+  ./scripts/benchmark.sh --baseline origin/main
+USAGE
+}
+
+function main() {
+  local baseline count threshold
+  baseline=""
+  count="6"
+  threshold="20"
+
+  while [[ "${#}" != 0 ]]; do
+    case "${1}" in
+      --baseline)
+        baseline="${2}"
+        shift 2
+        ;;
+
+      --count)
+        count="${2}"
+        shift 2
+        ;;
+
+      --threshold)
+        threshold="${2}"
+        shift 2
+        ;;
+
+      --fat-jar-size)
+        export JBP_BENCHMARK_FAT_JAR_SIZE="${2}"
+        shift 2
+        ;;
+
+      --help|-h)
+        shift 1
+        usage
+        exit 0
+        ;;
+
+      "")
+        # skip if the argument is empty
+        shift 1
+        ;;
+
+      *)
+        util::print::error "unknown argument \"${1}\""
+    esac
+  done
+
+  local output
+  output="${ROOTDIR}/build/benchmarks"
+  mkdir -p "${output}"
+
+  # Both runs share the fixtures, so the fat JAR is generated once
+  export JBP_BENCHMARK_FIXTURES="${ROOTDIR}/.bin/benchmark-fixtures"
+  mkdir -p "${JBP_BENCHMARK_FIXTURES}"
+
+  util::print::title "Running benchmarks"
+  benchmarks::run "${ROOTDIR}" "${count}" | tee "${output}/current.txt"
+
+  if [[ -z "${baseline}" ]]; then
+    return
+  fi
+
+  local worktree
+  worktree="$(mktemp -d)"
+  # shellcheck disable=SC2064
+  trap "git -C '${ROOTDIR}' worktree remove --force '${worktree}'" EXIT
+  git -C "${ROOTDIR}" worktree add --detach "${worktree}" "${baseline}" > /dev/null
+
+  if [[ ! -d "${worktree}/src/benchmarks" ]]; then
+    util::print::info "${baseline} has no benchmarks, skipping the comparison"
+    return
+  fi
+
+  util::print::title "Running benchmarks of ${baseline}"
+  benchmarks::run "${worktree}" "${count}" | tee "${output}/baseline.txt"
+
+  util::tools::benchstat::install --directory "${ROOTDIR}/.bin"
+
+  util::print::title "Comparing with ${baseline}"
+  benchstat "${output}/baseline.txt" "${output}/current.txt" | tee "${output}/comparison.txt"
+  benchmarks::check "${output}/baseline.txt" "${output}/current.txt" "${threshold}"
+}
+
+function benchmarks::run() {
+  local dir count
+  dir="${1}"
+  count="${2}"
+
+  pushd "${dir}" > /dev/null
+    go test \
+      -mod vendor \
+      -run '^$' \
+      -bench . \
+      -benchmem \
+      -count "${count}" \
+      -timeout 60m \
+      ./src/benchmarks
+  popd > /dev/null
+}
+
+# Fails if the time of a phase changed significantly by more than threshold percent. benchstat reports
+# changes that are not significant as ~.
+function benchmarks::check() {
+  local baseline current threshold regressions
+  baseline="${1}"
+  current="${2}"
+  threshold="${3}"
+
+  regressions="$(
+    benchstat -format csv "${baseline}" "${current}" 2> /dev/null \
+      | awk -F, -v threshold="${threshold}" '
+          $2 == "sec/op" { timing = 1; next }
+          $2 ~ /\/op$/   { timing = 0; next }
+          timing && $1 != "geomean" && $6 ~ /^\+[0-9.]+%$/ && $6 + 0 > threshold { print "  " $1 " " $6 }
+        '
+  )"
+
+  if [[ -n "${regressions}" ]]; then
+    util::print::error "Phases more than ${threshold}% slower than the baseline:\n${regressions}"
+  fi
+  util::print::info "No phase is more than ${threshold}% slower than the baseline"
+}
+
+main "${@:-}"
@@ -33,6 +33,7 @@ function main() {
       GOARCH=amd64 \
         go build \
           -mod vendor \
+          -trimpath \
           -ldflags="-s -w" \
           -o "${output}" \
             "${path}"
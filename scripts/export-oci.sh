@@ -0,0 +1,279 @@
+#!/usr/bin/env bash
+
+set -e
+set -u
+set -o pipefail
+
+# Stages an application with this buildpack inside the stack image and exports the resulting
+# droplet as an OCI image. The image reproduces the Cloud Foundry runtime layout
+# (/home/vcap/app, /home/vcap/deps, profile.d ordering, release command) so runtime-only issues
+# can be debugged with docker or podman without a Cloud Foundry deployment.
+#
+# This is a developer tool: it is not used during staging and is not part of the packaged buildpack.
+
+ROOTDIR="$(cd "$(dirname "${BASH_SOURCE[0]}")/.." && pwd)"
+readonly ROOTDIR
+
+# shellcheck source=SCRIPTDIR/.util/print.sh
+source "${ROOTDIR}/scripts/.util/print.sh"
+
+function usage() {
+  cat <<-USAGE
+export-oci.sh --app <path> [OPTIONS]
+
+Stages an application with the local buildpack sources and exports the droplet as an OCI image.
+
+OPTIONS
+  --app <path>          path to the application directory, JAR or WAR (required)
+  --tag <name>          image name to create (default: java-buildpack-droplet:local)
+  --stack <image>       stack image used for staging and as the image base (default: cloudfoundry/cflinuxfs4)
+  --arch <arch>         GOARCH of the supply and finalize binaries and platform of the image
+                        (default: the architecture of the stack image)
+  --memory <limit>      MEMORY_LIMIT seen by the memory calculator (default: 1024m)
+  --env <KEY=VALUE>     environment variable for staging and runtime, e.g. JBP_CONFIG_OPEN_JDK_JRE (repeatable)
+  --help                prints the command usage
+
+EXAMPLE
+  ./scripts/export-oci.sh --app target/app.jar --env 'JBP_CONFIG_OPEN_JDK_JRE={jre: {version: 21.+}}'
+  docker run --rm -p 8080:8080 java-buildpack-droplet:local
+USAGE
+}
+
+function main() {
+  local app tag stack arch memory
+  local -a envs
+  app=""
+  tag="java-buildpack-droplet:local"
+  stack="cloudfoundry/cflinuxfs4"
+  arch=""
+  memory="1024m"
+  envs=()
+
+  while [[ "${#}" != 0 ]]; do
+    case "${1}" in
+      --app)
+        app="${2}"
+        shift 2
+        ;;
+
+      --tag)
+        tag="${2}"
+        shift 2
+        ;;
+
+      --stack)
+        stack="${2}"
+        shift 2
+        ;;
+
+      --arch)
+        arch="${2}"
+        shift 2
+        ;;
+
+      --memory)
+        memory="${2}"
+        shift 2
+        ;;
+
+      --env)
+        if [[ ! "${2}" =~ ^[A-Za-z_][A-Za-z0-9_]*= ]]; then
+          util::print::error "--env expects KEY=VALUE, got \"${2}\""
+        fi
+        envs+=("${2}")
+        shift 2
+        ;;
+
+      --help|-h)
+        shift 1
+        usage
+        exit 0
+        ;;
+
+      *)
+        util::print::error "unknown argument \"${1}\""
+    esac
+  done
+
+  if [[ -z "${app}" ]]; then
+    usage
+    util::print::error "--app is required"
+  fi
+
+  local container_cmd
+  container_cmd="$(container::runtime)"
+
+  if [[ -z "${arch}" ]]; then
+    arch="$(stack::arch "${container_cmd}" "${stack}")"
+  fi
+
+  local workdir
+  workdir="$(mktemp -d -t java-buildpack-oci.XXXXXX)"
+  # shellcheck disable=SC2064
+  trap "rm -rf '${workdir}'" EXIT
+
+  mkdir -p "${workdir}/app" "${workdir}/deps/0" "${workdir}/cache" "${workdir}/buildpack/bin"
+
+  util::print::title "Building supply and finalize for linux/${arch}"
+  for phase in supply finalize; do
+    CGO_ENABLED=0 GOOS=linux GOARCH="${arch}" \
+      go build -mod vendor -o "${workdir}/buildpack/bin/${phase}" "${ROOTDIR}/src/java/${phase}/cli"
+  done
+  cp "${ROOTDIR}/manifest.yml" "${ROOTDIR}/VERSION" "${workdir}/buildpack/"
+  # The configuration defaults, e.g. config/open_jdk_jre.yml, are packaged with the buildpack
+  cp -R "${ROOTDIR}/config" "${workdir}/buildpack/"
+
+  util::print::title "Preparing application ${app}"
+  app::extract "${app}" "${workdir}/app"
+
+  local -a env_flags
+  env_flags=(--env "MEMORY_LIMIT=${memory}")
+  for env in "${envs[@]+"${envs[@]}"}"; do
+    env_flags+=(--env "${env}")
+  done
+
+  # Stage with the runtime paths so that every path written into profile.d scripts and the
+  # release command is the one the droplet sees on Cloud Foundry
+  util::print::title "Staging in ${stack}"
+  "${container_cmd}" run --rm \
+    --platform "linux/${arch}" \
+    "${env_flags[@]}" \
+    --env BUILDPACK_DIR=/tmp/buildpack \
+    --env CF_STACK="$(basename "${stack}")" \
+    -v "${workdir}/buildpack:/tmp/buildpack:z" \
+    -v "${workdir}/app:/home/vcap/app:z" \
+    -v "${workdir}/deps:/home/vcap/deps:z" \
+    -v "${workdir}/cache:/tmp/cache:z" \
+    "${stack}" \
+    bash -c '
+      set -euo pipefail
+      /tmp/buildpack/bin/supply /home/vcap/app /tmp/cache /home/vcap/deps 0
+      /tmp/buildpack/bin/finalize /home/vcap/app /tmp/cache /home/vcap/deps 0 /home/vcap/app/.profile.d
+    '
+
+  local release
+  release="${workdir}/app/tmp/java-buildpack-release-step.yml"
+  if [[ ! -f "${release}" ]]; then
+    util::print::error "staging did not produce ${release}"
+  fi
+
+  launcher::write "${workdir}/launcher.sh"
+  env::write "${workdir}/env.sh" "${envs[@]+"${envs[@]}"}"
+  dockerfile::write "${workdir}/Dockerfile" "${stack}" "${memory}"
+
+  util::print::title "Building image ${tag}"
+  "${container_cmd}" build --platform "linux/${arch}" -t "${tag}" -f "${workdir}/Dockerfile" "${workdir}"
+
+  util::print::success "Image ${tag} created, run it with: ${container_cmd} run --rm -p 8080:8080 ${tag}"
+}
+
+function container::runtime() {
+  if command -v podman &> /dev/null; then
+    echo "podman"
+  elif command -v docker &> /dev/null; then
+    echo "docker"
+  else
+    util::print::error "Neither podman nor docker found. Please install one of them."
+  fi
+}
+
+# Prints the GOARCH of the stack image, pulling the image if it is not present yet
+function stack::arch() {
+  local container_cmd stack
+  container_cmd="${1}"
+  stack="${2}"
+
+  if ! "${container_cmd}" image inspect "${stack}" &> /dev/null; then
+    "${container_cmd}" pull "${stack}" > /dev/null
+  fi
+  "${container_cmd}" image inspect --format '{{.Architecture}}' "${stack}"
+}
+
+# Copies a directory or unpacks a JAR/WAR the same way the cf CLI does when pushing a single archive
+function app::extract() {
+  local source target
+  source="${1}"
+  target="${2}"
+
+  if [[ -d "${source}" ]]; then
+    cp -a "${source}/." "${target}/"
+  elif [[ -f "${source}" ]]; then
+    unzip -q "${source}" -d "${target}"
+  else
+    util::print::error "application ${source} does not exist"
+  fi
+}
+
+# The launcher mirrors the Cloud Foundry app lifecycle: dependency profile.d scripts, then the
+# application's .profile.d scripts and .profile, then the release command
+function launcher::write() {
+  cat > "${1}" <<-'LAUNCHER'
+#!/bin/bash
+set -e
+
+cd "${HOME}"
+
+# shellcheck disable=SC1091
+source /home/vcap/env.sh
+
+for script in "${DEPS_DIR}"/*/profile.d/*.sh "${HOME}"/.profile.d/*.sh; do
+  if [[ -f "${script}" ]]; then
+    # shellcheck disable=SC1090
+    source "${script}"
+  fi
+done
+
+if [[ -f "${HOME}/.profile" ]]; then
+  # shellcheck disable=SC1091
+  source "${HOME}/.profile"
+fi
+
+if [[ "${#}" -gt 0 ]]; then
+  exec "${@}"
+fi
+
+command="$(sed -n "s/^web: '\(.*\)'$/\1/p" "${HOME}/tmp/java-buildpack-release-step.yml" | sed "s/''/'/g")"
+echo "Starting: ${command}"
+eval "${command}"
+LAUNCHER
+  chmod 0755 "${1}"
+}
+
+# Writes the --env variables as shell-quoted assignments sourced by the launcher, so that their values
+# reach the application verbatim instead of going through the variable substitution and quoting of
+# Dockerfile ENV instructions. Variables set with "run --env" take precedence.
+function env::write() {
+  local file
+  file="${1}"
+  shift 1
+
+  {
+    echo "#!/bin/bash"
+    for env in "${@}"; do
+      printf '[[ -v %s ]] || export %s=%q\n' "${env%%=*}" "${env%%=*}" "${env#*=}"
+    done
+  } > "${file}"
+}
+
+function dockerfile::write() {
+  local file stack memory
+  file="${1}"
+  stack="${2}"
+  memory="${3}"
+
+  {
+    echo "FROM ${stack}"
+    echo "COPY --chown=2000:2000 app /home/vcap/app"
+    echo "COPY --chown=2000:2000 deps /home/vcap/deps"
+    echo "COPY --chown=2000:2000 launcher.sh env.sh /home/vcap/"
+    echo "ENV HOME=/home/vcap/app DEPS_DIR=/home/vcap/deps PORT=8080 MEMORY_LIMIT=${memory}"
+    echo "ENV VCAP_APPLICATION='{\"application_name\":\"local\",\"name\":\"local\",\"instance_index\":0}'"
+    echo "ENV VCAP_SERVICES='{}'"
+    echo "USER 2000:2000"
+    echo "WORKDIR /home/vcap/app"
+    echo "EXPOSE 8080"
+    echo "ENTRYPOINT [\"/home/vcap/launcher.sh\"]"
+  } > "${file}"
+}
+
+main "${@}"
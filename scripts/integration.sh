@@ -25,6 +25,8 @@ OPTIONS
   --parallel <true|false>            Run tests in parallel (default: false)
   --stack <stack>                    Stack to use for tests (default: cflinuxfs4)
   --keep-failed-containers           Preserve failed test containers for debugging (default: false)
+  --fake-vendors-address <address>   Address of this host that the apps under test reach the fake vendor endpoints at
+  --java-version <version>           Run only the Java version tests, staging the apps with this Java major version
 
 EXAMPLES
   # Serial mode
@@ -35,17 +37,25 @@ EXAMPLES
 
   # Keep failed containers for debugging
   ./scripts/integration.sh --platform docker --keep-failed-containers
+
+  # Run the agent tests against fake vendor endpoints
+  ./scripts/integration.sh --platform docker --fake-vendors-address 172.17.0.1
+
+  # Stage the apps with Java 25
+  ./scripts/integration.sh --platform docker --java-version 25
 USAGE
 }
 
 function main() {
-  local src stack platform token cached parallel keep_failed
+  local src stack platform token cached parallel keep_failed fake_vendors java_version
   src="${ROOTDIR}/src/java/integration"
   stack="${CF_STACK:-cflinuxfs4}"
   platform="cf"
   cached="false"
   parallel="false"
   keep_failed="false"
+  fake_vendors=""
+  java_version=""
   token="${GITHUB_TOKEN:-}"
 
   while [[ "${#}" != 0 ]]; do
@@ -80,6 +90,16 @@ function main() {
         shift 1
         ;;
 
+      --fake-vendors-address)
+        fake_vendors="${2}"
+        shift 2
+        ;;
+
+      --java-version)
+        java_version="${2}"
+        shift 2
+        ;;
+
       --help|-h)
         shift 1
         usage
@@ -105,27 +125,33 @@ function main() {
   echo "Cached:             ${cached}"
   echo "Parallel:           ${parallel}"
   echo "Keep Failed:        ${keep_failed}"
+  echo "Fake Vendors:       ${fake_vendors:-disabled}"
+  echo "Java Version:       ${java_version:-all tests}"
   echo ""
 
-  specs::run "${cached}" "${parallel}" "${stack}" "${platform}" "${token}" "${keep_failed}"
+  specs::run "${cached}" "${parallel}" "${stack}" "${platform}" "${token}" "${keep_failed}" "${fake_vendors}" "${java_version}"
 }
 
 function specs::run() {
-  local cached parallel stack platform token keep_failed
+  local cached parallel stack platform token keep_failed fake_vendors java_version
   cached="${1}"
   parallel="${2}"
   stack="${3}"
   platform="${4}"
   token="${5}"
   keep_failed="${6}"
+  fake_vendors="${7}"
+  java_version="${8}"
 
-  local nodes cached_flag serial_flag platform_flag stack_flag token_flag keep_failed_flag
+  local nodes cached_flag serial_flag platform_flag stack_flag token_flag keep_failed_flag fake_vendors_flag java_version_flag
   cached_flag="--cached=${cached}"
   serial_flag="--serial=true"
   platform_flag="--platform=${platform}"
   stack_flag="--stack=${stack}"
   token_flag="--github-token=${token}"
   keep_failed_flag="--keep-failed-containers=${keep_failed}"
+  fake_vendors_flag="--fake-vendors-address=${fake_vendors}"
+  java_version_flag="--java-version=${java_version}"
   nodes=1
 
   if [[ "${parallel}" == "true" ]]; then
@@ -153,7 +179,9 @@ function specs::run() {
          ${token_flag} \
          ${stack_flag} \
          ${serial_flag} \
-         ${keep_failed_flag}
+         ${keep_failed_flag} \
+         ${fake_vendors_flag} \
+         ${java_version_flag}
 }
 
 function buildpack::package() {
@@ -111,35 +111,19 @@ function package::buildpack() {
   include="${7:-}"
 
   mkdir -p "$(dirname "${output}")"
-
-  util::tools::buildpack-packager::install --directory "${ROOTDIR}/.bin"
+  output="$(cd "$(dirname "${output}")" && pwd)/$(basename "${output}")"
 
   echo "Building buildpack (version: ${version}, stack: ${stack}, cached: ${cached}, output: ${output})"
 
-  local stack_flag
-  stack_flag="--any-stack"
-  if [[ "${stack}" != "any" ]]; then
-    stack_flag="--stack=${stack}"
-  fi
+  local args
+  args=("-dir=${ROOTDIR}" "-version=${version}" "-cached=${cached}" "-stack=${stack}" "-output=${output}")
+  [[ -n "${profile}" ]] && args+=("-profile=${profile}")
+  [[ -n "${exclude}" ]] && args+=("-exclude=${exclude}")
+  [[ -n "${include}" ]] && args+=("-include=${include}")
 
-  local profile_flag="" exclude_flag="" include_flag=""
-  [[ -n "${profile}" ]] && profile_flag="--profile=${profile}"
-  [[ -n "${exclude}" ]] && exclude_flag="--exclude=${exclude}"
-  [[ -n "${include}" ]] && include_flag="--include=${include}"
-
-  local file
-  file="$(
-    "${ROOTDIR}/.bin/buildpack-packager" build \
-      "--version=${version}" \
-      "--cached=${cached}" \
-      "${stack_flag}" \
-      ${profile_flag:+"${profile_flag}"} \
-      ${exclude_flag:+"${exclude_flag}"} \
-      ${include_flag:+"${include_flag}"} \
-    | xargs -n1 | grep -e '\.zip$'
-  )"
-
-  mv "${file}" "${output}"
+  pushd "${ROOTDIR}" > /dev/null
+    go run -mod vendor ./cmd/package "${args[@]}" > /dev/null
+  popd > /dev/null
 }
 
 main "${@:-}"
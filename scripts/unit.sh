@@ -12,10 +12,9 @@ source "${ROOTDIR}/scripts/.util/tools.sh"
 
 function main() {
   local src
-  src="$(find "${ROOTDIR}/src" -mindepth 1 -maxdepth 1 -type d )"
+  src="$(find "${ROOTDIR}/src" -mindepth 1 -maxdepth 1 -type d ) ${ROOTDIR}/cmd"
 
   util::tools::ginkgo::install --directory "${ROOTDIR}/.bin"
-  util::tools::buildpack-packager::install --directory "${ROOTDIR}/.bin"
 
   ginkgo \
     -r \
@@ -0,0 +1,70 @@
+package benchmarks_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/java-buildpack/src/benchmarks"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// The specs make sure each fixture exercises the phases it is benchmarked for
+var _ = Describe("Fixtures", func() {
+	var fixtureDir string
+
+	BeforeEach(func() {
+		var err error
+		fixtureDir, err = os.MkdirTemp("", "fixtures")
+		Expect(err).NotTo(HaveOccurred())
+		os.Setenv("JBP_BENCHMARK_FAT_JAR_SIZE", "2")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(fixtureDir)
+		os.Unsetenv("JBP_BENCHMARK_FAT_JAR_SIZE")
+	})
+
+	stage := func(fixture benchmarks.Fixture) *benchmarks.Platform {
+		app, err := fixture.Write(fixtureDir)
+		Expect(err).NotTo(HaveOccurred())
+		platform, err := benchmarks.NewPlatform(filepath.Join(fixtureDir, fixture.Name, "platform"), app)
+		Expect(err).NotTo(HaveOccurred())
+		return platform
+	}
+
+	for _, fixture := range benchmarks.Fixtures() {
+		It("detects the "+fixture.Name+" fixture as "+fixture.Container, func() {
+			platform := stage(fixture)
+
+			Expect(platform.Detect()).To(Equal(fixture.Container))
+			Expect(platform.CountClasses()).To(BeNumerically(">", 0))
+		})
+	}
+
+	It("explodes the WAR and counts the classes inside it", func() {
+		platform := stage(benchmarks.Fixtures()[2])
+
+		Expect(platform.Extract()).To(Succeed())
+		buildDir := platform.Context.Stager.BuildDir()
+		jsps, err := filepath.Glob(filepath.Join(buildDir, ".java-buildpack", "tomcat_webapps", "app", "WEB-INF", "views", "*.jsp"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(jsps).To(HaveLen(200))
+		Expect(platform.CountClasses()).To(BeNumerically(">", 1000))
+	})
+
+	It("generates the same fixture on every run", func() {
+		fixture := benchmarks.Fixtures()[1]
+		first, err := fixture.Write(filepath.Join(fixtureDir, "first"))
+		Expect(err).NotTo(HaveOccurred())
+		second, err := fixture.Write(filepath.Join(fixtureDir, "second"))
+		Expect(err).NotTo(HaveOccurred())
+
+		firstContent, err := os.ReadFile(first)
+		Expect(err).NotTo(HaveOccurred())
+		secondContent, err := os.ReadFile(second)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(firstContent).To(Equal(secondContent))
+		Expect(len(firstContent)).To(BeNumerically(">", 2<<20))
+	})
+})
@@ -0,0 +1,185 @@
+// Package benchmarks measures the staging phases of the buildpack against representative applications, so that
+// performance regressions in detection, class counting and extraction show up in CI. The fixtures are generated
+// rather than checked in, and are the same on every run so that results can be compared across commits.
+//
+// Run the benchmarks with scripts/benchmark.sh, or directly with
+//
+//	go test -run '^$' -bench . ./src/benchmarks
+package benchmarks
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// DefaultFatJarSize is the size in MB of the fat JAR fixture, unless JBP_BENCHMARK_FAT_JAR_SIZE sets it
+const DefaultFatJarSize = 300
+
+// Fixture is an application staged by the benchmarks
+type Fixture struct {
+	// Name identifies the fixture in benchmark names, e.g. BenchmarkStaging/fat_jar/detect
+	Name string
+	// File is the name of the application file in the build directory, e.g. app.war
+	File string
+	// Container is the name the application is expected to be detected as
+	Container string
+
+	write func(path string) error
+}
+
+// Fixtures returns the small JAR, fat JAR and WAR fixtures
+func Fixtures() []Fixture {
+	return []Fixture{
+		{Name: "small_jar", File: "app.jar", Container: "Java Main", write: writeSmallJar},
+		{Name: "fat_jar", File: "app.jar", Container: "Spring Boot", write: writeFatJar},
+		{Name: "jsp_war", File: "app.war", Container: "Tomcat", write: writeJSPWar},
+	}
+}
+
+// Write creates the fixture's application file in dir and returns its path. An existing file is reused, as the fat
+// JAR takes a while to generate.
+func (f Fixture) Write(dir string) (string, error) {
+	path := filepath.Join(dir, f.Name, f.File)
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+	if err := f.write(path + ".tmp"); err != nil {
+		return "", fmt.Errorf("failed to write %s fixture: %w", f.Name, err)
+	}
+	return path, os.Rename(path+".tmp", path)
+}
+
+// fatJarSize returns the configured size of the fat JAR fixture in MB
+func fatJarSize() int {
+	if size, err := strconv.Atoi(os.Getenv("JBP_BENCHMARK_FAT_JAR_SIZE")); err == nil && size > 0 {
+		return size
+	}
+	return DefaultFatJarSize
+}
+
+// writeSmallJar writes an executable JAR with 50 classes
+func writeSmallJar(path string) error {
+	return writeArchive(path, func(a *archive) {
+		a.add("META-INF/MANIFEST.MF", []byte("Manifest-Version: 1.0\nMain-Class: com.example.Main\n"))
+		a.classes("com/example/", 50)
+	})
+}
+
+// writeFatJar writes a Spring Boot fat JAR with 2,000 application classes and as many 1 MB library JARs as make up
+// the configured size. Libraries are stored uncompressed, like Spring Boot's nested JARs.
+func writeFatJar(path string) error {
+	return writeArchive(path, func(a *archive) {
+		a.add("META-INF/MANIFEST.MF", []byte("Manifest-Version: 1.0\n"+
+			"Main-Class: org.springframework.boot.loader.launch.JarLauncher\n"+
+			"Start-Class: com.example.Application\n"+
+			"Spring-Boot-Version: 3.3.5\n"+
+			"Spring-Boot-Classes: BOOT-INF/classes/\n"+
+			"Spring-Boot-Lib: BOOT-INF/lib/\n"))
+		a.classes("BOOT-INF/classes/com/example/", 2000)
+		a.classes("org/springframework/boot/loader/launch/", 100)
+		for i := 0; i < fatJarSize(); i++ {
+			a.store(fmt.Sprintf("BOOT-INF/lib/library-%03d.jar", i), a.library(1<<20))
+		}
+	})
+}
+
+// writeJSPWar writes a WAR with 200 JSPs, 500 classes and 20 library JARs
+func writeJSPWar(path string) error {
+	return writeArchive(path, func(a *archive) {
+		a.add("META-INF/MANIFEST.MF", []byte("Manifest-Version: 1.0\n"))
+		a.add("WEB-INF/web.xml", []byte("<web-app/>"))
+		for i := 0; i < 200; i++ {
+			a.add(fmt.Sprintf("WEB-INF/views/page-%03d.jsp", i), a.random(8<<10))
+		}
+		a.classes("WEB-INF/classes/com/example/", 500)
+		for i := 0; i < 20; i++ {
+			a.store(fmt.Sprintf("WEB-INF/lib/library-%02d.jar", i), a.library(256<<10))
+		}
+	})
+}
+
+// archive writes ZIP entries with content from a fixed seed, so fixtures are identical across runs
+type archive struct {
+	writer *zip.Writer
+	rand   *rand.Rand
+	err    error
+}
+
+func writeArchive(path string, entries func(a *archive)) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	a := &archive{writer: zip.NewWriter(f), rand: rand.New(rand.NewSource(1))}
+	entries(a)
+	if a.err != nil {
+		return a.err
+	}
+	return a.writer.Close()
+}
+
+// add writes a compressed entry
+func (a *archive) add(name string, content []byte) {
+	a.write(&zip.FileHeader{Name: name, Method: zip.Deflate}, content)
+}
+
+// store writes an uncompressed entry
+func (a *archive) store(name string, content []byte) {
+	a.write(&zip.FileHeader{Name: name, Method: zip.Store}, content)
+}
+
+func (a *archive) write(header *zip.FileHeader, content []byte) {
+	if a.err != nil {
+		return
+	}
+	w, err := a.writer.CreateHeader(header)
+	if err == nil {
+		_, err = w.Write(content)
+	}
+	a.err = err
+}
+
+// classes writes count class files of 2 KB each below dir
+func (a *archive) classes(dir string, count int) {
+	for i := 0; i < count; i++ {
+		a.add(fmt.Sprintf("%sClass%04d.class", dir, i), a.random(2<<10))
+	}
+}
+
+// library returns a JAR of about size bytes, made of 4 KB classes
+func (a *archive) library(size int) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for i := 0; i < size/(4<<10); i++ {
+		e, err := w.CreateHeader(&zip.FileHeader{Name: fmt.Sprintf("com/library/Class%04d.class", i), Method: zip.Store})
+		if err == nil {
+			_, err = e.Write(a.random(4 << 10))
+		}
+		if err != nil && a.err == nil {
+			a.err = err
+		}
+	}
+	if err := w.Close(); err != nil && a.err == nil {
+		a.err = err
+	}
+	return buf.Bytes()
+}
+
+// random returns size bytes that do not compress, like class files do not compress much
+func (a *archive) random(size int) []byte {
+	content := make([]byte, size)
+	_, _ = io.ReadFull(a.rand, content)
+	return content
+}
@@ -0,0 +1,111 @@
+package benchmarks
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/containers"
+	"github.com/cloudfoundry/java-buildpack/src/java/jres"
+	"github.com/cloudfoundry/libbuildpack"
+)
+
+// Platform lays out the directories the platform passes to the buildpack, with the application pushed to the build
+// directory and a JRE with the memory calculator installed in the first deps directory
+type Platform struct {
+	Context *common.Context
+	// JREDir is the directory of the fake JRE, which only contains the files the buildpack inspects
+	JREDir string
+}
+
+// NewPlatform creates the directories below root and pushes the application file app. The file is linked rather
+// than copied where possible, so the fat JAR does not dominate the setup time.
+func NewPlatform(root, app string) (*Platform, error) {
+	buildDir := filepath.Join(root, "build")
+	cacheDir := filepath.Join(root, "cache")
+	depsDir := filepath.Join(root, "deps")
+	jreDir := filepath.Join(depsDir, "0", "jre")
+
+	for _, dir := range []string{buildDir, cacheDir, filepath.Join(jreDir, "bin")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(jreDir, "release"), []byte("JAVA_VERSION=\"21.0.5\"\n"), 0644); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(jreDir, "bin", "java-buildpack-memory-calculator-4.2.0"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		return nil, err
+	}
+	if err := linkOrCopy(app, filepath.Join(buildDir, filepath.Base(app))); err != nil {
+		return nil, fmt.Errorf("failed to push %s: %w", filepath.Base(app), err)
+	}
+
+	logger := libbuildpack.NewLogger(io.Discard)
+	manifest := &libbuildpack.Manifest{}
+	return &Platform{
+		Context: &common.Context{
+			Stager:   libbuildpack.NewStager([]string{buildDir, cacheDir, depsDir, "0"}, logger, manifest),
+			Manifest: manifest,
+			Log:      logger,
+		},
+		JREDir: jreDir,
+	}, nil
+}
+
+// Detect runs the container detection of supply and returns the name of the detected container
+func (p *Platform) Detect() (string, error) {
+	registry := containers.NewRegistry(p.Context)
+	registry.RegisterStandardContainers()
+	_, name, err := registry.Detect()
+	return name, err
+}
+
+// Extract runs the Tomcat finalize step, which explodes the WARs in the build directory
+func (p *Platform) Extract() error {
+	return containers.NewTomcatContainer(p.Context).Finalize()
+}
+
+// loadedClassCount matches the class count the memory calculator is started with
+var loadedClassCount = regexp.MustCompile(`--loaded-class-count=(\d+)`)
+
+// CountClasses runs the memory calculator finalize step, which counts the classes in the build directory, and
+// returns the number of classes the calculator expects to be loaded
+func (p *Platform) CountClasses() (int, error) {
+	calculator := jres.NewMemoryCalculator(p.Context, p.JREDir, "21.0.5", 21)
+	if err := calculator.Finalize(); err != nil {
+		return 0, err
+	}
+
+	match := loadedClassCount.FindStringSubmatch(calculator.GetCalculatorCommand())
+	if match == nil {
+		return 0, fmt.Errorf("memory calculator command has no class count")
+	}
+	return strconv.Atoi(match[1])
+}
+
+func linkOrCopy(source, destination string) error {
+	if err := os.Link(source, destination); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(destination)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
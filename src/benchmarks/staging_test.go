@@ -0,0 +1,100 @@
+package benchmarks_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudfoundry/java-buildpack/src/benchmarks"
+)
+
+// fixtureDir holds the generated fixtures. JBP_BENCHMARK_FIXTURES keeps them between runs, e.g. to compare commits
+// without generating the fat JAR twice.
+var fixtureDir string
+
+func TestMain(m *testing.M) {
+	fixtureDir = os.Getenv("JBP_BENCHMARK_FIXTURES")
+	if fixtureDir != "" {
+		os.Exit(m.Run())
+	}
+
+	var err error
+	if fixtureDir, err = os.MkdirTemp("", "fixtures"); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	code := m.Run()
+	os.RemoveAll(fixtureDir)
+	os.Exit(code)
+}
+
+// BenchmarkStaging times each staging phase per fixture, e.g. BenchmarkStaging/fat_jar/count_classes
+func BenchmarkStaging(b *testing.B) {
+	for _, fixture := range benchmarks.Fixtures() {
+		b.Run(fixture.Name, func(b *testing.B) {
+			app, err := fixture.Write(fixtureDir)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.Run("detect", func(b *testing.B) {
+				benchmarkPhase(b, app, nil, func(p *benchmarks.Platform) error {
+					name, err := p.Detect()
+					if err == nil && name != fixture.Container {
+						err = fmt.Errorf("detected %q instead of %q", name, fixture.Container)
+					}
+					return err
+				})
+			})
+
+			// Only WARs are extracted during staging; the classes of a WAR are counted after it is exploded
+			var setup func(p *benchmarks.Platform) error
+			if filepath.Ext(app) == ".war" {
+				setup = (*benchmarks.Platform).Extract
+				b.Run("extract", func(b *testing.B) {
+					if info, err := os.Stat(app); err == nil {
+						b.SetBytes(info.Size())
+					}
+					benchmarkPhase(b, app, nil, (*benchmarks.Platform).Extract)
+				})
+			}
+
+			b.Run("count_classes", func(b *testing.B) {
+				benchmarkPhase(b, app, setup, func(p *benchmarks.Platform) error {
+					_, err := p.CountClasses()
+					return err
+				})
+			})
+		})
+	}
+}
+
+// benchmarkPhase stages app on a fresh platform for every iteration and times phase only
+func benchmarkPhase(b *testing.B, app string, setup, phase func(p *benchmarks.Platform) error) {
+	b.ReportAllocs()
+	root := b.TempDir()
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		dir := filepath.Join(root, fmt.Sprint(i))
+		platform, err := benchmarks.NewPlatform(dir, app)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if setup != nil {
+			if err := setup(platform); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.StartTimer()
+
+		if err := phase(platform); err != nil {
+			b.Fatal(err)
+		}
+
+		b.StopTimer()
+		os.RemoveAll(dir)
+		b.StartTimer()
+	}
+}
@@ -0,0 +1,159 @@
+package conformance_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// The conformance suite runs the lifecycle executables the way the platform does: bin/detect and
+// bin/release directly, supply and finalize as compiled binaries with the platform's arguments.
+// bin/supply and bin/finalize only compile these binaries before invoking them.
+//
+// The buildpack under test is assembled in a temporary directory with the repository's bin/
+// scripts and a manifest of buildpack-cached placeholder dependencies, so no network is needed.
+
+const buildpackVersion = "0.0.0-conformance"
+
+var (
+	rootDir      string
+	buildpackDir string
+	supplyBin    string
+	finalizeBin  string
+)
+
+func TestConformance(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Conformance Suite")
+}
+
+var _ = BeforeSuite(func() {
+	var err error
+	rootDir, err = filepath.Abs(filepath.Join("..", ".."))
+	Expect(err).NotTo(HaveOccurred())
+
+	buildpackDir, err = os.MkdirTemp("", "conformance-buildpack")
+	Expect(err).NotTo(HaveOccurred())
+
+	binDir := filepath.Join(buildpackDir, "bin")
+	Expect(os.MkdirAll(binDir, 0755)).To(Succeed())
+	for _, script := range []string{"detect", "release"} {
+		data, err := os.ReadFile(filepath.Join(rootDir, "bin", script))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(filepath.Join(binDir, script), data, 0755)).To(Succeed())
+	}
+
+	supplyBin = filepath.Join(buildpackDir, "compiled", "supply")
+	finalizeBin = filepath.Join(buildpackDir, "compiled", "finalize")
+	for bin, pkg := range map[string]string{supplyBin: "./src/java/supply/cli", finalizeBin: "./src/java/finalize/cli"} {
+		cmd := exec.Command("go", "build", "-mod=vendor", "-o", bin, pkg)
+		cmd.Dir = rootDir
+		output, err := cmd.CombinedOutput()
+		Expect(err).NotTo(HaveOccurred(), string(output))
+	}
+
+	Expect(os.WriteFile(filepath.Join(buildpackDir, "VERSION"), []byte(buildpackVersion), 0644)).To(Succeed())
+	writeManifest(buildpackDir)
+})
+
+var _ = AfterSuite(func() {
+	os.RemoveAll(buildpackDir)
+})
+
+// placeholder describes a buildpack-cached dependency and the files its archive contains.
+// Placeholders without files are plain JARs, like the agents frameworks install.
+type placeholder struct {
+	name    string
+	version string
+	files   map[string]string
+}
+
+func writeManifest(dir string) {
+	placeholders := []placeholder{
+		{"openjdk", "17.0.13", map[string]string{
+			"jdk-17.0.13/bin/java": "#!/bin/sh\necho 'openjdk version \"17.0.13\"' >&2\n",
+			"jdk-17.0.13/release":  "JAVA_VERSION=\"17.0.13\"\n",
+		}},
+		{"jvmkill", "1.17.0", map[string]string{
+			"jvmkill-1.17.0.so": "placeholder",
+		}},
+		{"memory-calculator", "4.2.0", map[string]string{
+			"java-buildpack-memory-calculator": "#!/bin/sh\necho '-Xmx512M'\n",
+		}},
+		{"client-certificate-mapper", "2.0.1", nil},
+		{"container-security-provider", "1.20.0", nil},
+	}
+
+	var manifest strings.Builder
+	manifest.WriteString("---\nlanguage: java\ndefault_versions:\n")
+	for _, p := range placeholders {
+		fmt.Fprintf(&manifest, "- name: %s\n  version: %s\n", p.name, p.version)
+	}
+	manifest.WriteString("dependencies:\n")
+	for _, p := range placeholders {
+		var archive, sum string
+		if p.files == nil {
+			archive = fmt.Sprintf("%s-%s.jar", p.name, p.version)
+			sum = writeFile(filepath.Join(dir, "dependencies", archive), "placeholder")
+		} else {
+			archive = fmt.Sprintf("%s-%s.tar.gz", p.name, p.version)
+			sum = writeTarGz(filepath.Join(dir, "dependencies", archive), p.files)
+		}
+		fmt.Fprintf(&manifest, `- name: %s
+  version: %s
+  uri: https://example.com/%s
+  sha256: %s
+  cf_stacks:
+  - cflinuxfs4
+  file: dependencies/%s
+`, p.name, p.version, archive, sum, archive)
+	}
+
+	Expect(os.WriteFile(filepath.Join(dir, "manifest.yml"), []byte(manifest.String()), 0644)).To(Succeed())
+}
+
+// writeTarGz writes files into a .tar.gz archive at path and returns the archive's SHA-256
+func writeTarGz(path string, files map[string]string) string {
+	Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+	f, err := os.Create(path)
+	Expect(err).NotTo(HaveOccurred())
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		Expect(tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0755,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		})).To(Succeed())
+		_, err := tw.Write([]byte(content))
+		Expect(err).NotTo(HaveOccurred())
+	}
+	Expect(tw.Close()).To(Succeed())
+	Expect(gz.Close()).To(Succeed())
+	Expect(f.Close()).To(Succeed())
+
+	data, err := os.ReadFile(path)
+	Expect(err).NotTo(HaveOccurred())
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// writeFile writes content to path and returns its SHA-256
+func writeFile(path, content string) string {
+	Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+	Expect(os.WriteFile(path, []byte(content), 0644)).To(Succeed())
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,252 @@
+package conformance_test
+
+import (
+	"archive/zip"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.yaml.in/yaml/v3"
+)
+
+// result is the outcome of running one lifecycle executable
+type result struct {
+	output   string
+	exitCode int
+}
+
+// run executes a lifecycle executable with the environment the platform provides during staging
+func run(executable string, args ...string) result {
+	return runOnStack("cflinuxfs4", executable, args...)
+}
+
+// runOnStack executes a lifecycle executable like run, staging on the given stack
+func runOnStack(stack string, executable string, args ...string) result {
+	cmd := exec.Command(executable, args...)
+	cmd.Env = append(stagingEnv(), "BUILDPACK_DIR="+buildpackDir, "CF_STACK="+stack)
+
+	output, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return result{output: string(output), exitCode: exitErr.ExitCode()}
+	}
+	Expect(err).NotTo(HaveOccurred())
+	return result{output: string(output)}
+}
+
+// stagingEnv returns the test process environment without variables that configure the buildpack
+func stagingEnv() []string {
+	var env []string
+	for _, e := range os.Environ() {
+		if strings.HasPrefix(e, "JBP_") || strings.HasPrefix(e, "JAVA_OPTS=") || strings.HasPrefix(e, "BUILDPACK_DIR=") || strings.HasPrefix(e, "CF_STACK=") {
+			continue
+		}
+		env = append(env, e)
+	}
+	return env
+}
+
+func writeJar(path string, manifest string) {
+	Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+	f, err := os.Create(path)
+	Expect(err).NotTo(HaveOccurred())
+	w := zip.NewWriter(f)
+	entry, err := w.Create("META-INF/MANIFEST.MF")
+	Expect(err).NotTo(HaveOccurred())
+	_, err = entry.Write([]byte(manifest))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(w.Close()).To(Succeed())
+	Expect(f.Close()).To(Succeed())
+}
+
+// fixtures create representative applications in an empty build directory
+var fixtures = map[string]func(buildDir string){
+	"Java Main": func(buildDir string) {
+		writeJar(filepath.Join(buildDir, "app.jar"), "Manifest-Version: 1.0\nMain-Class: com.example.Main\n")
+	},
+	"Spring Boot": func(buildDir string) {
+		Expect(os.MkdirAll(filepath.Join(buildDir, "BOOT-INF", "classes"), 0755)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(buildDir, "BOOT-INF", "lib"), 0755)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(buildDir, "META-INF"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(buildDir, "META-INF", "MANIFEST.MF"), []byte(
+			"Manifest-Version: 1.0\nMain-Class: org.springframework.boot.loader.JarLauncher\nStart-Class: com.example.App\nSpring-Boot-Version: 2.7.18\n",
+		), 0644)).To(Succeed())
+	},
+	"Dist ZIP": func(buildDir string) {
+		Expect(os.MkdirAll(filepath.Join(buildDir, "bin"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(buildDir, "bin", "app"), []byte("#!/bin/sh\nexec \"$JAVA_HOME/bin/java\" $JAVA_OPTS -cp \"$APP_HOME/lib/*\" com.example.Main\n"), 0755)).To(Succeed())
+		writeJar(filepath.Join(buildDir, "lib", "app.jar"), "Manifest-Version: 1.0\n")
+	},
+}
+
+var _ = Describe("Buildpack lifecycle", func() {
+	var (
+		buildDir string
+		cacheDir string
+		depsDir  string
+	)
+
+	BeforeEach(func() {
+		var err error
+		buildDir, err = os.MkdirTemp("", "conformance-build")
+		Expect(err).NotTo(HaveOccurred())
+		cacheDir, err = os.MkdirTemp("", "conformance-cache")
+		Expect(err).NotTo(HaveOccurred())
+		depsDir, err = os.MkdirTemp("", "conformance-deps")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(buildDir)
+		os.RemoveAll(cacheDir)
+		os.RemoveAll(depsDir)
+	})
+
+	Describe("detect", func() {
+		for name, fixture := range fixtures {
+			It("accepts a "+name+" application and prints the buildpack name and version", func() {
+				fixture(buildDir)
+
+				r := run(filepath.Join(buildpackDir, "bin", "detect"), buildDir)
+				Expect(r.exitCode).To(Equal(0), r.output)
+				Expect(r.output).To(Equal("java " + buildpackVersion + "\n"))
+			})
+		}
+
+		It("rejects an application that is not a Java application with exit code 1", func() {
+			Expect(os.WriteFile(filepath.Join(buildDir, "index.html"), []byte("<html/>"), 0644)).To(Succeed())
+
+			r := run(filepath.Join(buildpackDir, "bin", "detect"), buildDir)
+			Expect(r.exitCode).To(Equal(1))
+			Expect(r.output).To(BeEmpty())
+		})
+
+		It("rejects a Java application on a Windows stack with exit code 1 and explains why", func() {
+			fixtures["Java Main"](buildDir)
+
+			r := runOnStack("windows", filepath.Join(buildpackDir, "bin", "detect"), buildDir)
+			Expect(r.exitCode).To(Equal(1))
+			Expect(r.output).To(ContainSubstring("does not support the windows stack"))
+			Expect(r.output).NotTo(ContainSubstring("java " + buildpackVersion))
+		})
+
+		It("rejects a Java application on a stack the manifest has no dependencies for", func() {
+			fixtures["Java Main"](buildDir)
+
+			r := runOnStack("cflinuxfs2", filepath.Join(buildpackDir, "bin", "detect"), buildDir)
+			Expect(r.exitCode).To(Equal(1))
+			Expect(r.output).To(ContainSubstring("does not support the cflinuxfs2 stack: its manifest.yml has dependencies for cflinuxfs4"))
+		})
+	})
+
+	Describe("supply and finalize", func() {
+		for name, fixture := range fixtures {
+			Context("with a "+name+" application", func() {
+				BeforeEach(func() {
+					fixture(buildDir)
+				})
+
+				It("stages into its own deps directory and produces a release command", func() {
+					// deps/0 belongs to another buildpack in a multi-buildpack staging
+					Expect(os.MkdirAll(filepath.Join(depsDir, "0"), 0755)).To(Succeed())
+					Expect(os.WriteFile(filepath.Join(depsDir, "0", "config.yml"), []byte("name: other\n"), 0644)).To(Succeed())
+					Expect(os.MkdirAll(filepath.Join(depsDir, "1"), 0755)).To(Succeed())
+
+					r := run(supplyBin, buildDir, cacheDir, depsDir, "1")
+					Expect(r.exitCode).To(Equal(0), r.output)
+
+					By("writing config.yml with the buildpack name, version and supply results")
+					var config struct {
+						Name    string            `yaml:"name"`
+						Version string            `yaml:"version"`
+						Config  map[string]string `yaml:"config"`
+					}
+					data, err := os.ReadFile(filepath.Join(depsDir, "1", "config.yml"))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(yaml.Unmarshal(data, &config)).To(Succeed())
+					Expect(config.Name).To(Equal("java"))
+					Expect(config.Version).To(Equal(buildpackVersion))
+					Expect(config.Config).To(HaveKeyWithValue("container", name))
+					Expect(config.Config).To(HaveKeyWithValue("jre", "OpenJDK"))
+
+					By("exporting JAVA_HOME for later buildpacks")
+					javaHome, err := os.ReadFile(filepath.Join(depsDir, "1", "env", "JAVA_HOME"))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(filepath.Join(string(javaHome), "bin", "java")).To(BeARegularFile())
+
+					By("leaving other buildpacks' deps directories untouched")
+					entries, err := os.ReadDir(filepath.Join(depsDir, "0"))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(entries).To(HaveLen(1))
+
+					By("not producing release output before finalize")
+					Expect(filepath.Join(buildDir, "tmp", "java-buildpack-release-step.yml")).NotTo(BeAnExistingFile())
+
+					r = run(finalizeBin, buildDir, cacheDir, depsDir, "1", filepath.Join(buildDir, ".profile.d"))
+					Expect(r.exitCode).To(Equal(0), r.output)
+
+					By("writing profile.d scripts that are valid shell")
+					scripts, err := filepath.Glob(filepath.Join(depsDir, "1", "profile.d", "*.sh"))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(scripts).NotTo(BeEmpty())
+					for _, script := range scripts {
+						output, err := exec.Command("bash", "-n", script).CombinedOutput()
+						Expect(err).NotTo(HaveOccurred(), script+": "+string(output))
+					}
+
+					By("printing release YAML with a web process from bin/release")
+					r = run(filepath.Join(buildpackDir, "bin", "release"), buildDir)
+					Expect(r.exitCode).To(Equal(0), r.output)
+
+					var release struct {
+						DefaultProcessTypes map[string]string `yaml:"default_process_types"`
+					}
+					Expect(yaml.Unmarshal([]byte(r.output), &release)).To(Succeed())
+					Expect(release.DefaultProcessTypes).To(HaveKey("web"))
+					Expect(release.DefaultProcessTypes["web"]).NotTo(BeEmpty())
+				})
+			})
+		}
+
+		It("fails supply with a non-zero exit code when no container supports the application", func() {
+			Expect(os.MkdirAll(filepath.Join(depsDir, "0"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(buildDir, "index.html"), []byte("<html/>"), 0644)).To(Succeed())
+
+			r := run(supplyBin, buildDir, cacheDir, depsDir, "0")
+			Expect(r.exitCode).NotTo(Equal(0))
+			Expect(r.output).To(ContainSubstring("No suitable container found"))
+		})
+
+		It("fails supply with a non-zero exit code on a Windows stack", func() {
+			fixtures["Java Main"](buildDir)
+			Expect(os.MkdirAll(filepath.Join(depsDir, "0"), 0755)).To(Succeed())
+
+			r := runOnStack("windows", supplyBin, buildDir, cacheDir, depsDir, "0")
+			Expect(r.exitCode).NotTo(Equal(0))
+			Expect(r.output).To(ContainSubstring("does not support the windows stack"))
+		})
+
+		It("explains that an application with only Windows start scripts needs a Windows buildpack", func() {
+			Expect(os.MkdirAll(filepath.Join(depsDir, "0"), 0755)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(buildDir, "bin"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(buildDir, "bin", "app.bat"), []byte("@echo off\r\n"), 0644)).To(Succeed())
+			writeJar(filepath.Join(buildDir, "lib", "app.jar"), "Manifest-Version: 1.0\n")
+
+			r := run(supplyBin, buildDir, cacheDir, depsDir, "0")
+			Expect(r.exitCode).NotTo(Equal(0))
+			Expect(r.output).To(ContainSubstring("bin/app.bat"))
+		})
+
+		It("fails finalize with a non-zero exit code when supply did not run", func() {
+			fixtures["Java Main"](buildDir)
+			Expect(os.MkdirAll(filepath.Join(depsDir, "0"), 0755)).To(Succeed())
+
+			r := run(finalizeBin, buildDir, cacheDir, depsDir, "0", filepath.Join(buildDir, ".profile.d"))
+			Expect(r.exitCode).NotTo(Equal(0), r.output)
+		})
+	})
+})
@@ -0,0 +1,71 @@
+package integration_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudfoundry/java-buildpack/src/internal/fakevendors"
+	"github.com/cloudfoundry/switchblade"
+)
+
+// fakeVendorOptions returns the options that start a fake vendor endpoint reachable from the applications under test
+// at settings.FakeVendorsAddress, and skips the test if that is not set
+func fakeVendorOptions(t *testing.T, opts ...fakevendors.Option) []fakevendors.Option {
+	t.Helper()
+	if settings.FakeVendorsAddress == "" {
+		t.Skip("requires -fake-vendors-address, an address of this host that the applications under test can reach")
+	}
+	return append([]fakevendors.Option{fakevendors.WithAddress("0.0.0.0:0", settings.FakeVendorsAddress)}, opts...)
+}
+
+// startFakeDynatrace starts a fake Dynatrace API for the test
+func startFakeDynatrace(t *testing.T) *fakevendors.Dynatrace {
+	api := fakevendors.NewDynatrace(fakeVendorOptions(t)...)
+	t.Cleanup(api.Close)
+	return api
+}
+
+// startFakeNewRelic starts a fake New Relic collector for the test, served over HTTPS like the real one
+func startFakeNewRelic(t *testing.T) *fakevendors.NewRelic {
+	collector := fakevendors.NewNewRelic(fakeVendorOptions(t, fakevendors.WithTLS())...)
+	t.Cleanup(collector.Close)
+	return collector
+}
+
+// service returns credentials as the credentials of a service binding
+func service(credentials map[string]interface{}) switchblade.Service {
+	return switchblade.Service(credentials)
+}
+
+// withCertificate returns a copy of the application in fixture whose .certs directory holds certificate, which the
+// Platform CA Certificates framework adds to the trust store of the JVM
+func withCertificate(t *testing.T, fixture string, certificate []byte) string {
+	t.Helper()
+	app := t.TempDir()
+	err := filepath.WalkDir(fixture, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(app, path[len(fixture):])
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, 0644)
+	})
+	if err == nil {
+		err = os.MkdirAll(filepath.Join(app, ".certs"), 0755)
+	}
+	if err == nil {
+		err = os.WriteFile(filepath.Join(app, ".certs", "fake-vendor.pem"), certificate, 0644)
+	}
+	if err != nil {
+		t.Fatalf("failed to copy %s: %v", fixture, err)
+	}
+	return app
+}
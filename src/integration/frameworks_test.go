@@ -1,9 +1,11 @@
 package integration_test
 
 import (
+	"net/http"
 	"path/filepath"
 	"testing"
 
+	"github.com/cloudfoundry/java-buildpack/src/internal/fakevendors"
 	"github.com/cloudfoundry/switchblade"
 	"github.com/cloudfoundry/switchblade/matchers"
 	"github.com/sclevine/spec"
@@ -72,6 +74,24 @@ func testFrameworks(platform switchblade.Platform, fixtures string) func(*testin
 					Expect(logs.String()).To(ContainSubstring("New Relic Agent"))
 					Eventually(deployment).Should(matchers.Serve(ContainSubstring("")))
 				})
+
+				it("reports to the collector of the service binding", func() {
+					collector := startFakeNewRelic(t)
+					deployment, logs, err := platform.Deploy.
+						WithServices(map[string]switchblade.Service{
+							"newrelic": service(collector.Credentials()),
+						}).
+						WithEnv(map[string]string{
+							"BP_JAVA_VERSION":                  "17",
+							"JBP_CONFIG_PLATFORM_CERTIFICATES": "{enabled: true}",
+						}).
+						Execute(name, withCertificate(t, filepath.Join(fixtures, "apps", "integration_valid"), collector.CertificatePEM()))
+					Expect(err).NotTo(HaveOccurred(), logs.String)
+
+					Expect(logs.String()).To(ContainSubstring("Platform CA Certificates"))
+					Eventually(deployment).Should(matchers.Serve(ContainSubstring("")))
+					Eventually(collector.Methods).Should(ContainElement("connect"))
+				})
 			})
 
 			context("with AppDynamics service binding", func() {
@@ -123,14 +143,10 @@ func testFrameworks(platform switchblade.Platform, fixtures string) func(*testin
 
 			context("with Dynatrace service binding", func() {
 				it("detects and installs Dynatrace agent", func() {
-					t.Skip("Dynatrace agent downloads from API at runtime and requires valid credentials - this test cannot run with fake credentials")
+					api := startFakeDynatrace(t)
 					deployment, logs, err := platform.Deploy.
 						WithServices(map[string]switchblade.Service{
-							"dynatrace": {
-								"environmentid": "abc12345",
-								"apitoken":      "test-api-token-xyz",
-								"apiurl":        "https://abc12345.live.dynatrace.com/api",
-							},
+							"dynatrace": service(api.Credentials()),
 						}).
 						WithEnv(map[string]string{
 							"BP_JAVA_VERSION": "11",
@@ -139,19 +155,17 @@ func testFrameworks(platform switchblade.Platform, fixtures string) func(*testin
 					Expect(err).NotTo(HaveOccurred(), logs.String)
 
 					// Verify Dynatrace agent was detected and installed
-					Expect(logs.String()).To(ContainSubstring("Dynatrace"))
+					Expect(logs.String()).To(ContainSubstring("Dynatrace OneAgent injection is set up"))
+					Expect(api.RequestCount(api.Path(fakevendors.DynatraceInstallerPath))).To(Equal(1))
 					Eventually(deployment).Should(matchers.Serve(ContainSubstring("")))
 				})
 
 				it("configures Dynatrace with environment ID from service binding", func() {
-					t.Skip("Dynatrace agent downloads from API at runtime and requires valid credentials - this test cannot run with fake credentials")
+					api := startFakeDynatrace(t)
+					api.EnvironmentID = "xyz78901"
 					deployment, logs, err := platform.Deploy.
 						WithServices(map[string]switchblade.Service{
-							"my-dynatrace-service": {
-								"environmentid": "xyz78901",
-								"apitoken":      "dt0c01.XXXXXXXXX.YYYYYYYYYYYY",
-								"apiurl":        "https://xyz78901.live.dynatrace.com/api",
-							},
+							"my-dynatrace-service": service(api.Credentials()),
 						}).
 						WithEnv(map[string]string{
 							"BP_JAVA_VERSION": "17",
@@ -159,7 +173,52 @@ func testFrameworks(platform switchblade.Platform, fixtures string) func(*testin
 						Execute(name, filepath.Join(fixtures, "apps", "integration_valid"))
 					Expect(err).NotTo(HaveOccurred(), logs.String)
 
-					Expect(logs.String()).To(ContainSubstring("Dynatrace"))
+					Expect(logs.String()).To(ContainSubstring("Dynatrace OneAgent injection is set up"))
+					Expect(api.RequestCount(api.Path(fakevendors.DynatraceConfigPath))).To(BeNumerically(">", 0))
+					Eventually(deployment).Should(matchers.Serve(ContainSubstring("")))
+				})
+
+				it("retries the download of the OneAgent installer", func() {
+					api := startFakeDynatrace(t)
+					api.FailNext(api.Path(fakevendors.DynatraceInstallerPath), 2, http.StatusServiceUnavailable)
+					_, logs, err := platform.Deploy.
+						WithServices(map[string]switchblade.Service{
+							"dynatrace": service(api.Credentials()),
+						}).
+						Execute(name, filepath.Join(fixtures, "apps", "integration_valid"))
+					Expect(err).NotTo(HaveOccurred(), logs.String)
+
+					Expect(logs.String()).To(ContainSubstring("Error during installer download, retrying"))
+					Expect(logs.String()).To(ContainSubstring("Dynatrace OneAgent injection is set up"))
+					Expect(api.RequestCount(api.Path(fakevendors.DynatraceInstallerPath))).To(Equal(3))
+				})
+
+				it("fails staging when the installer cannot be downloaded", func() {
+					api := startFakeDynatrace(t)
+					api.FailAlways(api.Path(fakevendors.DynatraceInstallerPath), http.StatusBadGateway)
+					_, logs, err := platform.Deploy.
+						WithServices(map[string]switchblade.Service{
+							"dynatrace": service(api.Credentials()),
+						}).
+						Execute(name, filepath.Join(fixtures, "apps", "integration_valid"))
+					Expect(err).To(HaveOccurred())
+
+					Expect(logs.String()).To(ContainSubstring("Maximum number of retries attempted"))
+				})
+
+				it("stages without the OneAgent when the service allows errors", func() {
+					api := startFakeDynatrace(t)
+					api.FailAlways(api.Path(fakevendors.DynatraceInstallerPath), http.StatusBadGateway)
+					credentials := api.Credentials()
+					credentials["skiperrors"] = "true"
+					deployment, logs, err := platform.Deploy.
+						WithServices(map[string]switchblade.Service{
+							"dynatrace": service(credentials),
+						}).
+						Execute(name, filepath.Join(fixtures, "apps", "integration_valid"))
+					Expect(err).NotTo(HaveOccurred(), logs.String)
+
+					Expect(logs.String()).To(ContainSubstring("Error during installer download, skipping installation"))
 					Eventually(deployment).Should(matchers.Serve(ContainSubstring("")))
 				})
 			})
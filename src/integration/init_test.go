@@ -26,6 +26,8 @@ var settings struct {
 	KeepFailedContainers bool
 	FixturesPath         string
 	GitHubToken          string
+	FakeVendorsAddress   string
+	JavaVersion          string
 	Platform             string
 	Stack                string
 }
@@ -37,6 +39,8 @@ func init() {
 	flag.StringVar(&settings.Platform, "platform", "cf", `switchblade platform to test against ("cf" or "docker")`)
 	flag.StringVar(&settings.GitHubToken, "github-token", "", "use the token to make GitHub API requests")
 	flag.StringVar(&settings.Stack, "stack", "cflinuxfs4", "stack to use as default when pushing apps")
+	flag.StringVar(&settings.JavaVersion, "java-version", "", "run only the Java version tests, staging the applications with this Java version")
+	flag.StringVar(&settings.FakeVendorsAddress, "fake-vendors-address", "", "address of this host that the apps under test reach the fake vendor endpoints at")
 }
 
 func TestIntegration(t *testing.T) {
@@ -73,6 +77,14 @@ func TestIntegration(t *testing.T) {
 		suite = spec.New("integration", spec.Report(report.Terminal{}), spec.Parallel())
 	}
 
+	if settings.JavaVersion != "" {
+		suite("JavaVersion", testJavaVersion(platform, fixtures))
+		suite.Run(t)
+
+		Expect(platform.Deinitialize()).To(Succeed())
+		return
+	}
+
 	// Core container tests
 	suite("Tomcat", testTomcat(platform, fixtures))
 	suite("SpringBoot", testSpringBoot(platform, fixtures))
@@ -86,6 +98,7 @@ func TestIntegration(t *testing.T) {
 
 	// Framework tests (APM agents, security providers, etc.)
 	suite("Frameworks", testFrameworks(platform, fixtures))
+	suite("SecurityProviders", testSecurityProviders(platform, fixtures))
 
 	suite.Run(t)
 
@@ -0,0 +1,84 @@
+package integration_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudfoundry/switchblade"
+	"github.com/cloudfoundry/switchblade/matchers"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+// testJavaVersion stages the container fixtures with the Java version of the --java-version flag, which a CI matrix
+// entry sets to check a new Java release, e.g. 25
+func testJavaVersion(platform switchblade.Platform, fixtures string) func(*testing.T, spec.G, spec.S) {
+	return func(t *testing.T, context spec.G, it spec.S) {
+		var (
+			Expect     = NewWithT(t).Expect
+			Eventually = NewWithT(t).Eventually
+			name       string
+		)
+
+		it.Before(func() {
+			var err error
+			name, err = switchblade.RandomName()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it.After(func() {
+			if t.Failed() && name != "" {
+				t.Logf("❌ FAILED TEST - App/Container: %s", name)
+				t.Logf("   Platform: %s", settings.Platform)
+				t.Logf("   Java Version: %s", settings.JavaVersion)
+			}
+			if name != "" && (!settings.KeepFailedContainers || !t.Failed()) {
+				Expect(platform.Delete.Execute(name)).To(Succeed())
+			}
+		})
+
+		context("with a Tomcat application", func() {
+			it("installs the Java version and serves requests", func() {
+				deployment, logs, err := platform.Deploy.
+					WithEnv(map[string]string{
+						"BP_JAVA_VERSION": settings.JavaVersion,
+					}).
+					Execute(name, filepath.Join(fixtures, "containers", "tomcat_jakarta"))
+				Expect(err).NotTo(HaveOccurred(), logs.String)
+
+				Expect(logs.String()).To(ContainSubstring("Installing OpenJDK (" + settings.JavaVersion + "."))
+				Eventually(deployment).Should(matchers.Serve(ContainSubstring("OK")))
+			})
+		})
+
+		context("with a Java Main application", func() {
+			it("installs the Java version and starts", func() {
+				_, logs, err := platform.Deploy.
+					WithEnv(map[string]string{
+						"BP_JAVA_VERSION": settings.JavaVersion,
+					}).
+					Execute(name, filepath.Join(fixtures, "containers", "main"))
+				Expect(err).NotTo(HaveOccurred(), logs.String)
+
+				Expect(logs.String()).To(ContainSubstring("Installing OpenJDK (" + settings.JavaVersion + "."))
+				Expect(logs.String()).To(ContainSubstring("Java Main"))
+			})
+		})
+
+		context("with options that the Java version no longer supports", func() {
+			it("removes them so that the application starts", func() {
+				deployment, logs, err := platform.Deploy.
+					WithEnv(map[string]string{
+						"BP_JAVA_VERSION":      settings.JavaVersion,
+						"JBP_CONFIG_JAVA_OPTS": `'{java_opts: ["-XX:+UseConcMarkSweepGC", "-XX:+UseCGroupMemoryLimitForHeap", "--illegal-access=permit"]}'`,
+					}).
+					Execute(name, filepath.Join(fixtures, "containers", "tomcat_jakarta"))
+				Expect(err).NotTo(HaveOccurred(), logs.String)
+
+				Expect(logs.String()).To(ContainSubstring("Removing -XX:+UseConcMarkSweepGC"))
+				Eventually(deployment).Should(matchers.Serve(ContainSubstring("OK")))
+			})
+		})
+	}
+}
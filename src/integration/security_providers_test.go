@@ -0,0 +1,138 @@
+package integration_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudfoundry/switchblade"
+	"github.com/cloudfoundry/switchblade/matchers"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testSecurityProviders(platform switchblade.Platform, fixtures string) func(*testing.T, spec.G, spec.S) {
+	return func(t *testing.T, context spec.G, it spec.S) {
+		var (
+			Expect     = NewWithT(t).Expect
+			Eventually = NewWithT(t).Eventually
+			name       string
+			app        string
+		)
+
+		// loadService returns the credentials of a fake HSM or KMS from testdata/services
+		loadService := func(file string) switchblade.Service {
+			content, err := os.ReadFile(filepath.Join(fixtures, "services", file))
+			Expect(err).NotTo(HaveOccurred())
+
+			var service switchblade.Service
+			Expect(json.Unmarshal(content, &service)).To(Succeed())
+			return service
+		}
+
+		it.Before(func() {
+			var err error
+			name, err = switchblade.RandomName()
+			Expect(err).NotTo(HaveOccurred())
+
+			// The application reports the registered providers and the generated configuration it finds at runtime
+			app = filepath.Join(fixtures, "frameworks", "security_providers")
+		})
+
+		it.After(func() {
+			if t.Failed() && name != "" {
+				t.Logf("❌ FAILED TEST - App/Container: %s", name)
+				t.Logf("   Platform: %s", settings.Platform)
+			}
+			// Only attempt cleanup if test actually ran (not skipped) and name was set
+			if name != "" && !t.Skipped() && (!settings.KeepFailedContainers || !t.Failed()) {
+				Expect(platform.Delete.Execute(name)).To(Succeed())
+			}
+		})
+
+		context("with a Luna HSM service binding", func() {
+			it("boots the JVM with the Luna provider and the generated Chrystoki.conf", func() {
+				deployment, logs, err := platform.Deploy.
+					WithServices(map[string]switchblade.Service{
+						"luna": loadService("luna.json"),
+					}).
+					WithEnv(map[string]string{
+						"BP_JAVA_VERSION": "17",
+					}).
+					Execute(name, app)
+				Expect(err).NotTo(HaveOccurred(), logs.String)
+
+				Expect(logs.String()).To(ContainSubstring("Luna Security Provider"))
+				Eventually(deployment).Should(matchers.Serve(SatisfyAll(
+					ContainSubstring("com.safenetinc.luna.provider.LunaProvider: loadable"),
+					ContainSubstring("ServerName00 = hsm-1.example.com;"),
+					ContainSubstring("ServerName01 = hsm-2.example.com;"),
+					ContainSubstring("VirtualToken00Members = 1001,1002;"),
+					ContainSubstring("fake-ha-group = 1;"),
+				)))
+			})
+		})
+
+		context("with a ProtectApp KMS service binding", func() {
+			it("boots the JVM with the Ingrian provider and the generated key store", func() {
+				t.Skip("SKIPPED: protect-app-security-provider is not in manifest.yml - the provider must be added to the manifest by the operator")
+				deployment, logs, err := platform.Deploy.
+					WithServices(map[string]switchblade.Service{
+						"protectapp": loadService("protectapp.json"),
+					}).
+					WithEnv(map[string]string{
+						"BP_JAVA_VERSION": "17",
+					}).
+					Execute(name, app)
+				Expect(err).NotTo(HaveOccurred(), logs.String)
+
+				Expect(logs.String()).To(ContainSubstring("protect-app-security-provider"))
+				Eventually(deployment).Should(matchers.Serve(SatisfyAll(
+					ContainSubstring("com.ingrian.security.nae.IngrianProvider: loadable"),
+					ContainSubstring("client PrivateKeyEntry"),
+					ContainSubstring("trusted-0 trustedCertEntry"),
+				)))
+			})
+		})
+
+		context("with a Seeker service binding", func() {
+			it("boots the JVM with the Seeker agent", func() {
+				t.Skip("SKIPPED: Seeker agent is downloaded from the Seeker server at staging - requires a Seeker server reachable from the platform")
+				deployment, logs, err := platform.Deploy.
+					WithServices(map[string]switchblade.Service{
+						"seeker": {
+							"seeker_server_url": "https://seeker.example.com",
+						},
+					}).
+					WithEnv(map[string]string{
+						"BP_JAVA_VERSION": "17",
+					}).
+					Execute(name, app)
+				Expect(err).NotTo(HaveOccurred(), logs.String)
+
+				Expect(logs.String()).To(ContainSubstring("seeker-security-provider"))
+				Eventually(deployment).Should(matchers.Serve(ContainSubstring("https://seeker.example.com")))
+			})
+
+			it("stages without the Seeker agent when the server is unreachable and errors are skipped", func() {
+				deployment, logs, err := platform.Deploy.
+					WithServices(map[string]switchblade.Service{
+						"seeker": {
+							"seeker_server_url": "https://seeker.invalid",
+							"skiperrors":        "true",
+						},
+					}).
+					WithEnv(map[string]string{
+						"BP_JAVA_VERSION": "17",
+					}).
+					Execute(name, app)
+				Expect(err).NotTo(HaveOccurred(), logs.String)
+
+				Expect(logs.String()).To(ContainSubstring("skipping the Seeker agent because the service sets skiperrors"))
+				Eventually(deployment).Should(matchers.Serve(Not(ContainSubstring("seeker-agent.jar"))))
+			})
+		})
+	}
+}
@@ -0,0 +1,84 @@
+/*
+ * Cloud Foundry Java Buildpack
+ * Copyright 2013-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import com.sun.net.httpserver.HttpServer
+
+import java.security.KeyStore
+import java.security.Security
+
+// Serves a report of the security providers the JVM booted with and of the configuration the buildpack generated
+// for them, so that the integration tests can check both without access to the container's file system.
+class Application {
+
+    static final PROVIDER_CLASSES = [
+        'com.ingrian.security.nae.IngrianProvider',
+        'com.safenetinc.luna.provider.LunaProvider',
+    ]
+
+    static void main(String[] args) {
+        def report = new StringBuilder()
+
+        report << 'Providers\n'
+        Security.providers.each { provider -> report << "\t${provider.class.name}\n" }
+
+        report << 'Provider Classes\n'
+        PROVIDER_CLASSES.each { name ->
+            try {
+                Class.forName(name, false, ClassLoader.systemClassLoader)
+                report << "\t${name}: loadable\n"
+            } catch (ClassNotFoundException ignored) {
+                report << "\t${name}: missing\n"
+            }
+        }
+
+        def chrystoki = System.getenv('ChrystokiConfigurationPath')
+        if (chrystoki != null) {
+            report << 'Chrystoki.conf\n'
+            new File(chrystoki, 'Chrystoki.conf').eachLine { line -> report << "\t${line}\n" }
+        }
+
+        def keyStoreLocation = System.getProperty('com.ingrian.security.nae.Key_Store_Location')
+        if (keyStoreLocation != null) {
+            def keyStore = KeyStore.getInstance(KeyStore.defaultType)
+            new File(keyStoreLocation).withInputStream { stream ->
+                keyStore.load(stream, System.getProperty('com.ingrian.security.nae.Key_Store_Password').toCharArray())
+            }
+
+            report << 'Key Store\n'
+            Collections.list(keyStore.aliases()).sort().each { alias ->
+                report << "\t${alias} ${keyStore.isKeyEntry(alias) ? 'PrivateKeyEntry' : 'trustedCertEntry'}\n"
+            }
+        }
+
+        def seeker = System.getenv('SEEKER_SERVER_URL')
+        if (seeker != null) {
+            report << "Seeker Server\n\t${seeker}\n"
+        }
+
+        def port = (System.getenv('PORT') ?: '8080') as int
+        def server = HttpServer.create(new InetSocketAddress(port), 0)
+        server.createContext('/') { exchange ->
+            def body = report.toString().bytes
+            exchange.responseHeaders.add('Content-Type', 'text/plain')
+            exchange.sendResponseHeaders(200, body.length)
+            exchange.responseBody.withStream { it.write(body) }
+        }
+        server.start()
+
+        println "Server started on port ${port}"
+    }
+}
@@ -0,0 +1,118 @@
+package fakevendors
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Paths of the Dynatrace API below Dynatrace.APIURL that the Dynatrace hook requests
+const (
+	DynatraceInstallerPath = "/v1/deployment/installer/agent/unix/paas-sh/latest"
+	DynatraceConfigPath    = "/v1/deployment/installer/agent/processmoduleconfig"
+)
+
+// DynatraceProperty is a OneAgent setting that the processmoduleconfig endpoint returns
+type DynatraceProperty struct {
+	Section string `json:"section"`
+	Key     string `json:"key"`
+	Value   string `json:"value"`
+}
+
+// dynatraceInstaller is the PaaS installer the fake serves. Like the real one, it is run with the directory of the
+// application and unpacks the OneAgent to dynatrace/oneagent in it; the agent library is an empty file.
+const dynatraceInstaller = `#!/bin/sh
+set -e
+dir="$1/dynatrace/oneagent"
+mkdir -p "$dir/agent/lib64" "$dir/agent/conf"
+: > "$dir/agent/lib64/liboneagentproc.so"
+cat > "$dir/manifest.json" <<'EOF'
+{"technologies": {"process": {"linux-x86-64": [{"path": "agent/lib64/liboneagentproc.so", "binarytype": "primary"}]}}}
+EOF
+cat > "$dir/dynatrace-env.sh" <<'EOF'
+export DT_TENANT=%[1]s
+export DT_CONNECTION_POINT="%[2]s/communication"
+EOF
+cat > "$dir/agent/conf/ruxitagentproc.conf" <<'EOF'
+[general]
+key1 installer
+EOF
+`
+
+// Dynatrace is a fake Dynatrace API. It serves the OneAgent installer to requests with its API token and the
+// OneAgent configuration of the environment.
+type Dynatrace struct {
+	*Server
+	EnvironmentID string
+	APIToken      string
+	// Config is the OneAgent configuration that the processmoduleconfig endpoint returns
+	Config []DynatraceProperty
+}
+
+// NewDynatrace starts a fake Dynatrace API for the environment fake-environment
+func NewDynatrace(opts ...Option) *Dynatrace {
+	d := &Dynatrace{
+		EnvironmentID: "fake-environment",
+		APIToken:      "fake-api-token",
+		Config:        []DynatraceProperty{{Section: "general", Key: "key1", Value: "api"}},
+	}
+	d.Server = newServer(http.HandlerFunc(d.serve), opts...)
+	return d
+}
+
+// APIURL returns the URL of the API, the apiurl credential of a Dynatrace service
+func (d *Dynatrace) APIURL() string {
+	return d.URL() + d.apiPath()
+}
+
+// Path returns the path of the API endpoint endpoint, e.g. DynatraceInstallerPath, to pass to FailNext and
+// RequestCount
+func (d *Dynatrace) Path(endpoint string) string {
+	return d.apiPath() + endpoint
+}
+
+// Credentials returns the credentials of a Dynatrace service bound to the environment
+func (d *Dynatrace) Credentials() map[string]interface{} {
+	return map[string]interface{}{
+		"environmentid": d.EnvironmentID,
+		"apitoken":      d.APIToken,
+		"apiurl":        d.APIURL(),
+	}
+}
+
+// VCAPServices returns VCAP_SERVICES with a user-provided service named name, which must contain "dynatrace", with
+// Credentials and extra credentials, e.g. skiperrors
+func (d *Dynatrace) VCAPServices(name string, extra map[string]interface{}) string {
+	credentials := d.Credentials()
+	for key, value := range extra {
+		credentials[key] = value
+	}
+	services := map[string][]map[string]interface{}{
+		"user-provided": {{"name": name, "label": "user-provided", "tags": []string{}, "credentials": credentials}},
+	}
+	data, _ := json.Marshal(services)
+	return string(data)
+}
+
+func (d *Dynatrace) apiPath() string {
+	return "/e/" + d.EnvironmentID + "/api"
+}
+
+func (d *Dynatrace) serve(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Api-Token "+d.APIToken {
+		http.Error(w, `{"error":{"code":401,"message":"Token Authentication failed"}}`, http.StatusUnauthorized)
+		return
+	}
+
+	endpoint, ok := strings.CutPrefix(r.URL.Path, d.apiPath())
+	switch {
+	case ok && endpoint == DynatraceInstallerPath:
+		fmt.Fprintf(w, dynatraceInstaller, d.EnvironmentID, d.URL())
+	case ok && endpoint == DynatraceConfigPath:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"properties": d.Config})
+	default:
+		http.NotFound(w, r)
+	}
+}
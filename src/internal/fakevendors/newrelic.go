@@ -0,0 +1,79 @@
+package fakevendors
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// NewRelicCollectorPath is the path of the New Relic collector that agents invoke methods on, e.g.
+// /agent_listener/invoke_raw_method?method=connect&license_key=...
+const NewRelicCollectorPath = "/agent_listener/invoke_raw_method"
+
+// NewRelic is a fake New Relic collector. It accepts agents with its license key and answers preconnect with itself
+// as the collector to connect to, connect with a run ID and any other method, e.g. metric_data, with null.
+type NewRelic struct {
+	*Server
+	LicenseKey string
+}
+
+// NewNewRelic starts a fake New Relic collector. Agents only connect to collectors over HTTPS, so those that run
+// agents against it start it WithTLS.
+func NewNewRelic(opts ...Option) *NewRelic {
+	n := &NewRelic{LicenseKey: "fake-license-key"}
+	n.Server = newServer(http.HandlerFunc(n.serve), opts...)
+	return n
+}
+
+// Credentials returns the credentials of a New Relic service that reports to the collector: its license key and the
+// host and port, which the New Relic framework passes to the agent as newrelic.config.host and newrelic.config.port
+func (n *NewRelic) Credentials() map[string]interface{} {
+	return map[string]interface{}{
+		"license_key": n.LicenseKey,
+		"host":        n.Host(),
+		"port":        n.Port(),
+	}
+}
+
+// Methods returns the methods that agents invoked, in order, e.g. preconnect, connect, metric_data
+func (n *NewRelic) Methods() []string {
+	var methods []string
+	for _, r := range n.Requests() {
+		if r.Path == NewRelicCollectorPath {
+			methods = append(methods, r.Query.Get("method"))
+		}
+	}
+	return methods
+}
+
+func (n *NewRelic) serve(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != NewRelicCollectorPath {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Query().Get("license_key") != n.LicenseKey {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]interface{}{"exception": map[string]string{
+			"error_type": "NewRelic::Agent::LicenseException",
+			"message":    "Invalid license key, please contact support@newrelic.com",
+		}})
+		return
+	}
+
+	var value interface{}
+	switch r.URL.Query().Get("method") {
+	case "preconnect":
+		value = map[string]string{"redirect_host": n.Host()}
+	case "connect":
+		value = map[string]interface{}{
+			"agent_run_id":                      "fake-run-id",
+			"data_report_period":                60,
+			"collect_errors":                    true,
+			"collect_traces":                    true,
+			"sampling_target":                   10,
+			"sampling_target_period_in_seconds": 60,
+		}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"return_value": value})
+}
@@ -0,0 +1,95 @@
+package fakevendors
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Repository is a fake artifact repository in the format of the buildpack's repository_root settings: an index.yml
+// that maps versions to the URI and SHA-256 checksum of their artifact, next to the artifacts.
+type Repository struct {
+	*Server
+
+	mu        sync.Mutex
+	artifacts map[string][]byte
+	index     map[string]string
+}
+
+// NewRepository starts an empty fake repository
+func NewRepository(opts ...Option) *Repository {
+	r := &Repository{artifacts: map[string][]byte{}, index: map[string]string{}}
+	r.Server = newServer(http.HandlerFunc(r.serve), opts...)
+	return r
+}
+
+// RepositoryRoot returns the URL of the repository, the repository_root setting to configure
+func (r *Repository) RepositoryRoot() string {
+	return r.URL()
+}
+
+// IndexPath is the path of index.yml, to pass to FailNext and RequestCount
+func (r *Repository) IndexPath() string {
+	return "/index.yml"
+}
+
+// AddArtifact serves content as /name and lists it in index.yml as version, with its checksum
+func (r *Repository) AddArtifact(version, name string, content []byte) {
+	sum := sha256.Sum256(content)
+	r.addArtifact(version, name, content, fmt.Sprintf("{uri: %s/%s, sha256: %s}", r.URL(), name, hex.EncodeToString(sum[:])))
+}
+
+// AddUnverifiedArtifact serves content as /name and lists it in index.yml as version without a checksum
+func (r *Repository) AddUnverifiedArtifact(version, name string, content []byte) {
+	r.addArtifact(version, name, content, fmt.Sprintf("%s/%s", r.URL(), name))
+}
+
+// SetIndexEntry lists version in index.yml with entry, e.g. to list an artifact with a wrong checksum
+func (r *Repository) SetIndexEntry(version, entry string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.index[version] = entry
+}
+
+// ArtifactPath returns the path of the artifact name, to pass to FailNext and RequestCount
+func (r *Repository) ArtifactPath(name string) string {
+	return "/" + name
+}
+
+func (r *Repository) addArtifact(version, name string, content []byte, entry string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.artifacts[name] = content
+	r.index[version] = entry
+}
+
+func (r *Repository) serve(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if req.URL.Path == r.IndexPath() {
+		versions := make([]string, 0, len(r.index))
+		for version := range r.index {
+			versions = append(versions, version)
+		}
+		sort.Strings(versions)
+
+		var index strings.Builder
+		for _, version := range versions {
+			fmt.Fprintf(&index, "'%s': %s\n", version, r.index[version])
+		}
+		w.Write([]byte(index.String()))
+		return
+	}
+
+	content, ok := r.artifacts[strings.TrimPrefix(req.URL.Path, "/")]
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	w.Write(content)
+}
@@ -0,0 +1,241 @@
+// Package fakevendors provides in-process fakes of the vendor endpoints that the buildpack and its agents talk to: the
+// Dynatrace API, the New Relic collector and index.yml artifact repositories. They let unit and integration tests
+// exercise download flows, retries and error handling without reaching the network.
+//
+// Every fake records the requests it serves and can be told to fail the next requests to a path, e.g.
+//
+//	repository := fakevendors.NewRepository()
+//	defer repository.Close()
+//	repository.AddArtifact("1.2.3", "agent-1.2.3.jar", jar)
+//	repository.FailNext("/agent-1.2.3.jar", 2, http.StatusServiceUnavailable)
+//
+// By default the fakes listen on the loopback interface. Integration tests whose applications stage in containers or
+// on Cloud Foundry start them WithAddress, on an address those can reach.
+package fakevendors
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Request is a request served by a fake
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+}
+
+// Option configures a fake
+type Option func(*options)
+
+type options struct {
+	listen    string
+	advertise string
+	tls       bool
+}
+
+// WithAddress makes the fake listen on listen, e.g. 0.0.0.0:0, and report URLs with the host advertise, e.g. the
+// address of the test host on the network of the containers under test
+func WithAddress(listen, advertise string) Option {
+	return func(o *options) {
+		o.listen = listen
+		o.advertise = advertise
+	}
+}
+
+// WithTLS serves HTTPS with a self-signed certificate, for the advertised host if any, that Server.CertificatePEM
+// returns
+func WithTLS() Option {
+	return func(o *options) { o.tls = true }
+}
+
+type failure struct {
+	remaining int
+	status    int
+}
+
+// Server is the HTTP server that the fakes are built on
+type Server struct {
+	server *httptest.Server
+	url    string
+
+	mu       sync.Mutex
+	requests []Request
+	failures map[string]*failure
+}
+
+// newServer starts a Server that serves handler, unless a failure is pending for the path of the request
+func newServer(handler http.Handler, opts ...Option) *Server {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	s := &Server{failures: map[string]*failure{}}
+	s.server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.record(r)
+		if status := s.failure(r.URL.Path); status != 0 {
+			http.Error(w, fmt.Sprintf("fake failure of %s", r.URL.Path), status)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+
+	if o.listen != "" {
+		listener, err := net.Listen("tcp", o.listen)
+		if err != nil {
+			panic(fmt.Sprintf("fakevendors: failed to listen on %s: %v", o.listen, err))
+		}
+		s.server.Listener.Close()
+		s.server.Listener = listener
+	}
+	if o.tls {
+		if o.advertise != "" {
+			// Clients verify the advertised host, which the certificate of httptest does not name
+			s.server.TLS = &tls.Config{Certificates: []tls.Certificate{certificate(o.advertise)}}
+		}
+		s.server.StartTLS()
+	} else {
+		s.server.Start()
+	}
+
+	s.url = s.server.URL
+	if o.advertise != "" {
+		_, port, _ := net.SplitHostPort(s.server.Listener.Addr().String())
+		u, _ := url.Parse(s.server.URL)
+		u.Host = net.JoinHostPort(o.advertise, port)
+		s.url = u.String()
+	}
+	return s
+}
+
+// URL returns the base URL of the server, e.g. http://127.0.0.1:40123
+func (s *Server) URL() string {
+	return s.url
+}
+
+// Host returns the host name of URL
+func (s *Server) Host() string {
+	u, _ := url.Parse(s.url)
+	return u.Hostname()
+}
+
+// Port returns the port of URL
+func (s *Server) Port() string {
+	u, _ := url.Parse(s.url)
+	return u.Port()
+}
+
+// Client returns an HTTP client that trusts the certificate of a server started WithTLS
+func (s *Server) Client() *http.Client {
+	return s.server.Client()
+}
+
+// CertificatePEM returns the certificate of a server started WithTLS in PEM, or nil
+func (s *Server) CertificatePEM() []byte {
+	if s.server.Certificate() == nil {
+		return nil
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.server.Certificate().Raw})
+}
+
+// certificate returns a self-signed certificate for host, localhost and 127.0.0.1
+func certificate(host string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(fmt.Sprintf("fakevendors: failed to generate key: %v", err))
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: host},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		template.IPAddresses = append(template.IPAddresses, ip)
+	} else {
+		template.DNSNames = append(template.DNSNames, host)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		panic(fmt.Sprintf("fakevendors: failed to create certificate: %v", err))
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// Close shuts the server down
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// FailNext answers the next count requests to path with status instead of serving them
+func (s *Server) FailNext(path string, count, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[path] = &failure{remaining: count, status: status}
+}
+
+// FailAlways answers every request to path with status
+func (s *Server) FailAlways(path string, status int) {
+	s.FailNext(path, -1, status)
+}
+
+// Requests returns the requests served so far, failed ones included, in the order they were received
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// RequestCount returns the number of requests to path served so far, failed ones included
+func (s *Server) RequestCount(path string) int {
+	count := 0
+	for _, r := range s.Requests() {
+		if r.Path == path {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *Server) record(r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path, Query: r.URL.Query(), Header: r.Header.Clone()})
+}
+
+// failure returns the status to fail a request to path with, or 0 to serve it
+func (s *Server) failure(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, ok := s.failures[path]
+	if !ok || f.remaining == 0 {
+		return 0
+	}
+	if f.remaining > 0 {
+		f.remaining--
+	}
+	return f.status
+}
@@ -0,0 +1,108 @@
+// Package applibs finds the libraries an application brings with it, so frameworks do not install a second copy of
+// a library, such as a JDBC driver, that would conflict with the application's own on the classpath, and only attach
+// agents to applications that use the libraries they instrument.
+package applibs
+
+import (
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Dirs are the directories, relative to the application root, from which the containers load application
+// libraries: WEB-INF/lib of web applications, BOOT-INF/lib of Spring Boot applications, lib and <name>/lib of
+// distributions, and the application root itself.
+var Dirs = []string{
+	filepath.Join("WEB-INF", "lib"),
+	filepath.Join("BOOT-INF", "lib"),
+	"lib",
+	filepath.Join("*", "lib"),
+	".",
+}
+
+// Find returns the path, relative to buildDir, of the first JAR in Dirs whose file name matches one of patterns,
+// e.g. "postgresql-*.jar". Dirs are searched in order and, within a directory, patterns in order.
+func Find(buildDir string, patterns ...string) (string, bool) {
+	return scan(buildDir).Find(patterns...)
+}
+
+// Classpath is the JARs an application brings with it, as found by Scan
+type Classpath struct {
+	// jars are the paths of the JARs relative to the application root, grouped by the index of their directory in
+	// Dirs and sorted within each group
+	jars [][]string
+}
+
+// scans caches the Classpath of every application root that Scan was called for
+var scans = struct {
+	sync.Mutex
+	classpaths map[string]*Classpath
+}{classpaths: map[string]*Classpath{}}
+
+// Scan returns the JARs in Dirs of the application in buildDir. The directories are listed once per staging
+// process and the result is shared by all callers, so that frameworks can inspect the classpath in Detect without
+// each listing the application again. Use Find for libraries that may have been added since the first scan.
+func Scan(buildDir string) *Classpath {
+	scans.Lock()
+	defer scans.Unlock()
+
+	if classpath, ok := scans.classpaths[buildDir]; ok {
+		return classpath
+	}
+	classpath := scan(buildDir)
+	scans.classpaths[buildDir] = classpath
+	return classpath
+}
+
+// scan lists the JARs in Dirs of the application in buildDir. A JAR in a directory that matches several Dirs, e.g.
+// WEB-INF/lib and */lib, belongs to the first of them.
+func scan(buildDir string) *Classpath {
+	classpath := &Classpath{jars: make([][]string, len(Dirs))}
+	seen := map[string]bool{}
+	for i, dir := range Dirs {
+		matches, err := filepath.Glob(filepath.Join(buildDir, dir, "*.jar"))
+		if err != nil {
+			continue
+		}
+		sort.Strings(matches)
+		for _, match := range matches {
+			rel, err := filepath.Rel(buildDir, match)
+			if err != nil || seen[rel] {
+				continue
+			}
+			seen[rel] = true
+			classpath.jars[i] = append(classpath.jars[i], rel)
+		}
+	}
+	return classpath
+}
+
+// JARs returns the paths of all JARs relative to the application root, in the order of Dirs
+func (c *Classpath) JARs() []string {
+	var jars []string
+	for _, group := range c.jars {
+		jars = append(jars, group...)
+	}
+	return jars
+}
+
+// Find returns the path, relative to the application root, of the first JAR whose file name matches one of
+// patterns, e.g. "micrometer-core-*.jar". Dirs are searched in order and, within a directory, patterns in order.
+func (c *Classpath) Find(patterns ...string) (string, bool) {
+	for _, group := range c.jars {
+		for _, pattern := range patterns {
+			for _, jar := range group {
+				if matched, _ := filepath.Match(pattern, filepath.Base(jar)); matched {
+					return jar, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// Contains returns true if a JAR's file name matches one of patterns
+func (c *Classpath) Contains(patterns ...string) bool {
+	_, ok := c.Find(patterns...)
+	return ok
+}
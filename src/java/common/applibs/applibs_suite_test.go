@@ -0,0 +1,13 @@
+package applibs_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestApplibs(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Applibs Suite")
+}
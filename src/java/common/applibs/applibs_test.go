@@ -0,0 +1,121 @@
+package applibs_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common/applibs"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Find", func() {
+	var buildDir string
+
+	writeJar := func(path string) {
+		Expect(os.MkdirAll(filepath.Join(buildDir, filepath.Dir(path)), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(buildDir, path), []byte("jar"), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		buildDir, err = os.MkdirTemp("", "applibs")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(buildDir)
+	})
+
+	DescribeTable("finds libraries in the directories the containers load them from",
+		func(path string) {
+			writeJar(path)
+
+			found, ok := applibs.Find(buildDir, "postgresql-*.jar")
+			Expect(ok).To(BeTrue())
+			Expect(found).To(Equal(path))
+		},
+		Entry("WEB-INF/lib", "WEB-INF/lib/postgresql-42.7.3.jar"),
+		Entry("BOOT-INF/lib", "BOOT-INF/lib/postgresql-42.7.3.jar"),
+		Entry("lib", "lib/postgresql-42.7.3.jar"),
+		Entry("the lib directory of a distribution", "orders-1.0/lib/postgresql-42.7.3.jar"),
+		Entry("the application root", "postgresql-42.7.3.jar"),
+	)
+
+	It("matches any of the patterns", func() {
+		writeJar("BOOT-INF/lib/mysql-connector-j-8.4.0.jar")
+
+		found, ok := applibs.Find(buildDir, "mariadb-java-client*.jar", "mysql-connector-j*.jar")
+		Expect(ok).To(BeTrue())
+		Expect(found).To(Equal("BOOT-INF/lib/mysql-connector-j-8.4.0.jar"))
+	})
+
+	It("ignores libraries in other directories", func() {
+		writeJar("WEB-INF/classes/postgresql-42.7.3.jar")
+		writeJar("src/main/resources/postgresql-42.7.3.jar")
+
+		_, ok := applibs.Find(buildDir, "postgresql-*.jar")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("returns false for an application without libraries", func() {
+		_, ok := applibs.Find(buildDir, "postgresql-*.jar")
+		Expect(ok).To(BeFalse())
+	})
+})
+
+var _ = Describe("Scan", func() {
+	var buildDir string
+
+	writeJar := func(path string) {
+		Expect(os.MkdirAll(filepath.Join(buildDir, filepath.Dir(path)), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(buildDir, path), []byte("jar"), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		buildDir, err = os.MkdirTemp("", "applibs")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(buildDir)
+	})
+
+	It("lists the JARs of the application in the order of the directories", func() {
+		writeJar("postgresql-42.7.3.jar")
+		writeJar("WEB-INF/lib/spring-core-6.1.0.jar")
+		writeJar("WEB-INF/lib/micrometer-core-1.12.0.jar")
+		writeJar("WEB-INF/classes/ignored.jar")
+		writeJar("orders-1.0/lib/orders.jar")
+
+		Expect(applibs.Scan(buildDir).JARs()).To(Equal([]string{
+			"WEB-INF/lib/micrometer-core-1.12.0.jar",
+			"WEB-INF/lib/spring-core-6.1.0.jar",
+			"orders-1.0/lib/orders.jar",
+			"postgresql-42.7.3.jar",
+		}))
+	})
+
+	It("finds and matches JARs by file name patterns", func() {
+		writeJar("BOOT-INF/lib/micrometer-core-1.12.0.jar")
+
+		classpath := applibs.Scan(buildDir)
+		found, ok := classpath.Find("micrometer-registry-*.jar", "micrometer-core-*.jar")
+		Expect(ok).To(BeTrue())
+		Expect(found).To(Equal("BOOT-INF/lib/micrometer-core-1.12.0.jar"))
+		Expect(classpath.Contains("micrometer-*.jar")).To(BeTrue())
+		Expect(classpath.Contains("aspectjweaver-*.jar")).To(BeFalse())
+	})
+
+	It("shares the scan of an application", func() {
+		writeJar("lib/aspectjweaver-1.9.22.jar")
+		classpath := applibs.Scan(buildDir)
+
+		writeJar("lib/jacocoagent.jar")
+		Expect(applibs.Scan(buildDir)).To(BeIdenticalTo(classpath))
+		Expect(applibs.Scan(buildDir).Contains("jacocoagent.jar")).To(BeFalse())
+		_, ok := applibs.Find(buildDir, "jacocoagent.jar")
+		Expect(ok).To(BeTrue())
+	})
+})
@@ -0,0 +1,95 @@
+// Package appname names the application consistently across the agents that report it to a backend. Without
+// configuration every agent keeps its own default, e.g. the application name for New Relic and
+// <space>:<application> for AppDynamics; a template configured with JBP_CONFIG_APPLICATION_NAME applies to all of
+// them.
+package appname
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/libbuildpack"
+)
+
+// Default templates of the agents
+const (
+	// App names the application by its name alone
+	App = "{app}"
+	// SpaceApp qualifies the name of the application with its space
+	SpaceApp = "{space}:{app}"
+)
+
+// Config is the JBP_CONFIG_APPLICATION_NAME configuration, e.g. '{template: "{org}-{space}-{app}"}'
+type Config struct {
+	// Template is the name the agents report the application as. It may reference {app}, {space} and {org}, or
+	// their VCAP_APPLICATION names {application_name}, {space_name} and {organization_name}.
+	Template string `yaml:"template"`
+}
+
+func init() {
+	config.RegisterSchema("application_name", func() interface{} { return &Config{} })
+}
+
+// Template returns the configured template, or defaultTemplate if none is configured
+func Template(log *libbuildpack.Logger, defaultTemplate string) (string, error) {
+	cfg := Config{}
+	if err := config.Load(log, "application_name", &cfg); err != nil {
+		return "", err
+	}
+	if cfg.Template == "" {
+		return defaultTemplate, nil
+	}
+	return cfg.Template, nil
+}
+
+// Name returns the name the agents report the application as: the configured template or defaultTemplate, resolved
+// with Resolve. If the template references a value that VCAP_APPLICATION does not provide, e.g. the space, the
+// application name alone is used.
+func Name(log *libbuildpack.Logger, defaultTemplate string) (string, error) {
+	template, err := Template(log, defaultTemplate)
+	if err != nil {
+		return "", err
+	}
+	if name := Resolve(template); name != "" {
+		return name, nil
+	}
+	return Resolve(App), nil
+}
+
+// Placeholders returns the values of the placeholders of a template, read from VCAP_APPLICATION. Values that
+// VCAP_APPLICATION does not provide are empty.
+func Placeholders() map[string]string {
+	var app struct {
+		ApplicationName  string `json:"application_name"`
+		SpaceName        string `json:"space_name"`
+		OrganizationName string `json:"organization_name"`
+	}
+	_ = json.Unmarshal([]byte(os.Getenv("VCAP_APPLICATION")), &app)
+
+	return map[string]string{
+		"{app}":               app.ApplicationName,
+		"{application_name}":  app.ApplicationName,
+		"{space}":             app.SpaceName,
+		"{space_name}":        app.SpaceName,
+		"{org}":               app.OrganizationName,
+		"{organization_name}": app.OrganizationName,
+	}
+}
+
+// Resolve returns template with its placeholders replaced from VCAP_APPLICATION, or "" if it references a value
+// that VCAP_APPLICATION does not provide, so that the agent falls back to its own default
+func Resolve(template string) string {
+	name := template
+	for placeholder, value := range Placeholders() {
+		if !strings.Contains(name, placeholder) {
+			continue
+		}
+		if value == "" {
+			return ""
+		}
+		name = strings.ReplaceAll(name, placeholder, value)
+	}
+	return name
+}
@@ -0,0 +1,13 @@
+package appname_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestAppname(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Appname Suite")
+}
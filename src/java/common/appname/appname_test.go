@@ -0,0 +1,64 @@
+package appname_test
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common/appname"
+	"github.com/cloudfoundry/libbuildpack"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Application name", func() {
+	var logger *libbuildpack.Logger
+
+	BeforeEach(func() {
+		logger = libbuildpack.NewLogger(new(bytes.Buffer))
+		os.Setenv("VCAP_APPLICATION", `{"application_name":"orders","space_name":"prod","organization_name":"acme"}`)
+	})
+
+	AfterEach(func() {
+		os.Unsetenv("VCAP_APPLICATION")
+		os.Unsetenv("JBP_CONFIG_APPLICATION_NAME")
+	})
+
+	It("uses the default template of the agent", func() {
+		Expect(appname.Name(logger, appname.App)).To(Equal("orders"))
+		Expect(appname.Name(logger, appname.SpaceApp)).To(Equal("prod:orders"))
+	})
+
+	It("applies the configured template to every agent", func() {
+		os.Setenv("JBP_CONFIG_APPLICATION_NAME", `{template: "{org}-{space}-{app}"}`)
+
+		Expect(appname.Name(logger, appname.App)).To(Equal("acme-prod-orders"))
+		Expect(appname.Name(logger, appname.SpaceApp)).To(Equal("acme-prod-orders"))
+		Expect(appname.Template(logger, appname.SpaceApp)).To(Equal("{org}-{space}-{app}"))
+	})
+
+	It("accepts the VCAP_APPLICATION names of the placeholders", func() {
+		Expect(appname.Resolve("{organization_name}/{space_name}/{application_name}")).To(Equal("acme/prod/orders"))
+	})
+
+	It("falls back to the application name if a referenced value is missing", func() {
+		os.Setenv("VCAP_APPLICATION", `{"application_name":"orders"}`)
+
+		Expect(appname.Resolve(appname.SpaceApp)).To(BeEmpty())
+		Expect(appname.Name(logger, appname.SpaceApp)).To(Equal("orders"))
+	})
+
+	It("is empty without VCAP_APPLICATION", func() {
+		os.Unsetenv("VCAP_APPLICATION")
+
+		Expect(appname.Name(logger, appname.App)).To(BeEmpty())
+	})
+
+	It("rejects unknown configuration keys", func() {
+		os.Setenv("JBP_CONFIG_APPLICATION_NAME", `{templte: "{app}"}`)
+		os.Setenv("JBP_STRICT_CONFIG", "true")
+		defer os.Unsetenv("JBP_STRICT_CONFIG")
+
+		_, err := appname.Name(logger, appname.App)
+		Expect(err).To(HaveOccurred())
+	})
+})
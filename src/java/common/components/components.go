@@ -0,0 +1,100 @@
+// Package components reads the Ruby buildpack's JBP_CONFIG_COMPONENTS, which lists the JREs, containers and
+// frameworks the buildpack detects by their Ruby class names, e.g.
+//
+//	{jres: ["JavaBuildpack::Jre::ZuluJRE"], frameworks: ["NewRelicAgent", "-DatadogJavaagent"]}
+//
+// A name prefixed with "-" excludes the component. If a list names any component without the prefix, only the
+// components it names are detected.
+package components
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/libbuildpack"
+)
+
+// Config is JBP_CONFIG_COMPONENTS
+type Config struct {
+	JREs       []string `yaml:"jres"`
+	Containers []string `yaml:"containers"`
+	Frameworks []string `yaml:"frameworks"`
+}
+
+func init() {
+	config.RegisterSchema("components", func() interface{} { return &Config{} })
+}
+
+// Load reads JBP_CONFIG_COMPONENTS
+func Load(log *libbuildpack.Logger) (Config, error) {
+	cfg := Config{}
+	if err := config.Load(log, "components", &cfg); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Selection is one list of JBP_CONFIG_COMPONENTS, e.g. the frameworks
+type Selection struct {
+	// kind names the components of the list in errors, e.g. "framework"
+	kind string
+	// Included are the class names listed without the "-" prefix, in their order
+	Included []string
+	// Excluded are the class names listed with the "-" prefix
+	Excluded []string
+}
+
+// NewSelection parses the entries of the list of kind components
+func NewSelection(kind string, entries []string) Selection {
+	selection := Selection{kind: kind}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if name := strings.TrimPrefix(entry, "-"); name != entry {
+			selection.Excluded = append(selection.Excluded, ClassName(name))
+		} else if entry != "" {
+			selection.Included = append(selection.Included, ClassName(entry))
+		}
+	}
+	return selection
+}
+
+// ClassName returns name without its Ruby module prefix, e.g. "NewRelicAgent" for
+// "JavaBuildpack::Framework::NewRelicAgent"
+func ClassName(name string) string {
+	if i := strings.LastIndex(name, "::"); i >= 0 {
+		return name[i+2:]
+	}
+	return name
+}
+
+// Allows returns true if the component with the given class name is not excluded and, if the list names included
+// components, is one of them. Names are compared ignoring case.
+func (s Selection) Allows(name string) bool {
+	if contains(s.Excluded, name) {
+		return false
+	}
+	return len(s.Included) == 0 || contains(s.Included, name)
+}
+
+// Validate returns an error for the first listed name that is none of the known class names, so that a typo does
+// not silently detect or skip a component
+func (s Selection) Validate(known []string) error {
+	for _, name := range append(append([]string{}, s.Included...), s.Excluded...) {
+		if !contains(known, name) {
+			return fmt.Errorf("unknown %s component %q in JBP_CONFIG_COMPONENTS, valid values: %s",
+				s.kind, name, strings.Join(known, ", "))
+		}
+	}
+	return nil
+}
+
+// contains returns true if names contains name, ignoring case
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
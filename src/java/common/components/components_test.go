@@ -0,0 +1,62 @@
+package components_test
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common/components"
+	"github.com/cloudfoundry/libbuildpack"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Components", func() {
+	AfterEach(func() {
+		os.Unsetenv("JBP_CONFIG_COMPONENTS")
+	})
+
+	It("reads the lists of JBP_CONFIG_COMPONENTS", func() {
+		os.Setenv("JBP_CONFIG_COMPONENTS", `{jres: ["ZuluJRE"], containers: ["-Groovy"], frameworks: ["NewRelicAgent"]}`)
+
+		cfg, err := components.Load(libbuildpack.NewLogger(new(bytes.Buffer)))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.JREs).To(Equal([]string{"ZuluJRE"}))
+		Expect(cfg.Containers).To(Equal([]string{"-Groovy"}))
+		Expect(cfg.Frameworks).To(Equal([]string{"NewRelicAgent"}))
+	})
+
+	It("strips the Ruby module prefix", func() {
+		selection := components.NewSelection("framework",
+			[]string{"JavaBuildpack::Framework::NewRelicAgent", "-JavaBuildpack::Framework::DatadogJavaagent"})
+
+		Expect(selection.Included).To(Equal([]string{"NewRelicAgent"}))
+		Expect(selection.Excluded).To(Equal([]string{"DatadogJavaagent"}))
+	})
+
+	It("allows all components that are not excluded", func() {
+		selection := components.NewSelection("framework", []string{"-DatadogJavaagent"})
+
+		Expect(selection.Allows("NewRelicAgent")).To(BeTrue())
+		Expect(selection.Allows("datadogjavaagent")).To(BeFalse())
+	})
+
+	It("allows only the included components if any are listed", func() {
+		selection := components.NewSelection("framework", []string{"NewRelicAgent", "-DatadogJavaagent"})
+
+		Expect(selection.Allows("NewRelicAgent")).To(BeTrue())
+		Expect(selection.Allows("DatadogJavaagent")).To(BeFalse())
+		Expect(selection.Allows("ElasticApmAgent")).To(BeFalse())
+	})
+
+	It("allows all components without a list", func() {
+		Expect(components.NewSelection("framework", nil).Allows("NewRelicAgent")).To(BeTrue())
+	})
+
+	It("rejects unknown components", func() {
+		selection := components.NewSelection("framework", []string{"NewRelicAgent", "-DataDogAgent"})
+
+		Expect(selection.Validate([]string{"NewRelicAgent", "DatadogJavaagent"})).To(MatchError(
+			`unknown framework component "DataDogAgent" in JBP_CONFIG_COMPONENTS, valid values: NewRelicAgent, DatadogJavaagent`))
+		Expect(selection.Validate([]string{"newrelicagent", "datadogagent"})).To(Succeed())
+	})
+})
@@ -0,0 +1,152 @@
+// Package config loads component configuration from the buildpack defaults and JBP_CONFIG_* environment variables.
+//
+// Every component describes its configuration as a typed struct pre-populated with its built-in defaults.
+// Load merges, in order:
+//  1. the buildpack's config/<component>.yml file, if the buildpack ships one
+//  2. the JBP_CONFIG_<COMPONENT> environment variable set by the application, into which common.MigrateLegacyConfig
+//     has merged the operator's JBP_DEFAULT_<COMPONENT>
+//
+// The merged result of every Load is recorded and can be written out with WriteEffective.
+//
+// Nested mappings are merged key by key, so '{tomcat: {version: 9.+}}' only replaces tomcat.version and
+// keeps every other default. Scalars and sequences replace the previous value.
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/libbuildpack"
+)
+
+// EnvPrefix is the prefix of all component configuration environment variables
+const EnvPrefix = "JBP_CONFIG_"
+
+// EnvVar returns the environment variable that configures component, e.g. "open_jdk_jre" -> "JBP_CONFIG_OPEN_JDK_JRE"
+func EnvVar(component string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(component, "-", "_"))
+}
+
+// IsSet returns true if the application sets the JBP_CONFIG_* variable of component
+func IsSet(component string) bool {
+	return strings.TrimSpace(os.Getenv(EnvVar(component))) != ""
+}
+
+// Load merges the buildpack defaults file and the JBP_CONFIG_<COMPONENT> environment variable into out,
+// which must be a pointer to a struct holding the built-in defaults.
+// Keys unknown to out are reported through YamlHandler.ValidateConfig, which fails in strict mode.
+// The result is recorded for WriteEffective.
+func Load(log *libbuildpack.Logger, component string, out interface{}) error {
+	sources := []string{SourceBuiltIn}
+
+	defaultsFile, err := loadDefaults(component, out)
+	if err != nil {
+		return err
+	}
+	if defaultsFile != "" {
+		sources = append(sources, "config/"+filepath.Base(defaultsFile))
+	}
+
+	envVar := EnvVar(component)
+	value := os.Getenv(envVar)
+	if strings.TrimSpace(value) == "" {
+		record(component, sources, out)
+		return nil
+	}
+
+	data, err := Normalize([]byte(value))
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", envVar, err)
+	}
+
+	yamlHandler := common.YamlHandler{}
+	if err := yamlHandler.ValidateConfig(log, envVar, data, out); err != nil {
+		return err
+	}
+	if err := yamlHandler.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", envVar, err)
+	}
+
+	record(component, append(sources, envSources(component)...), out)
+	return nil
+}
+
+// Decode merges the buildpack defaults file and the JBP_CONFIG_<COMPONENT> environment variable into out without
+// reporting unknown keys or recording the result.
+// It is meant for callers that read a single setting out of a configuration owned by another component.
+func Decode(component string, out interface{}) error {
+	if _, err := loadDefaults(component, out); err != nil {
+		return err
+	}
+
+	envVar := EnvVar(component)
+	value := os.Getenv(envVar)
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+
+	data, err := Normalize([]byte(value))
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", envVar, err)
+	}
+	if err := (common.YamlHandler{}).Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", envVar, err)
+	}
+	return nil
+}
+
+// DecodeOperator merges the buildpack defaults file and the operator's JBP_DEFAULT_<COMPONENT> environment variable
+// into out, ignoring the application's JBP_CONFIG_<COMPONENT>, which is merged over the operator default when it is set.
+// It is meant for settings the application must not be able to loosen.
+func DecodeOperator(component string, out interface{}) error {
+	if _, err := loadDefaults(component, out); err != nil {
+		return err
+	}
+
+	envVar := "JBP_DEFAULT_" + strings.TrimPrefix(EnvVar(component), EnvPrefix)
+	value := os.Getenv(envVar)
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+
+	data, err := Normalize([]byte(value))
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", envVar, err)
+	}
+	if err := (common.YamlHandler{}).Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", envVar, err)
+	}
+	return nil
+}
+
+// Normalize converts the formats accepted by the Ruby buildpack into a single YAML mapping:
+//   - a flow or block mapping: '{enabled: true}'
+//   - a mapping quoted as a YAML string: "'{enabled: true}'"
+//   - the legacy sequence of single-key mappings: '[enabled: true, port: 8000]'
+func Normalize(data []byte) ([]byte, error) {
+	return common.NormalizeConfig(data)
+}
+
+// loadDefaults merges $BUILDPACK_DIR/config/<component>.yml into out if the file exists and returns its path
+func loadDefaults(component string, out interface{}) (string, error) {
+	buildpackDir := os.Getenv("BUILDPACK_DIR")
+	if buildpackDir == "" {
+		return "", nil
+	}
+
+	path := filepath.Join(buildpackDir, "config", component+".yml")
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if err := (common.YamlHandler{}).Unmarshal(data, out); err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return path, nil
+}
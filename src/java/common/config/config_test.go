@@ -0,0 +1,218 @@
+package config_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/libbuildpack"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Config", func() {
+	type logging struct {
+		AccessLogging string `yaml:"access_logging"`
+		Level         string `yaml:"level"`
+	}
+	type component struct {
+		Version string `yaml:"version"`
+		Enabled bool   `yaml:"enabled"`
+	}
+	type testConfig struct {
+		Component component         `yaml:"component"`
+		Logging   logging           `yaml:"logging"`
+		Opts      []string          `yaml:"opts"`
+		Labels    map[string]string `yaml:"labels"`
+	}
+
+	var (
+		buffer   *bytes.Buffer
+		logger   *libbuildpack.Logger
+		defaults testConfig
+	)
+
+	BeforeEach(func() {
+		buffer = new(bytes.Buffer)
+		logger = libbuildpack.NewLogger(buffer)
+		defaults = testConfig{
+			Component: component{Version: "10.+", Enabled: true},
+			Logging:   logging{AccessLogging: "disabled", Level: "INFO"},
+			Opts:      []string{"-Xdefault"},
+			Labels:    map[string]string{"team": "core"},
+		}
+	})
+
+	AfterEach(func() {
+		os.Unsetenv("JBP_CONFIG_TEST_COMPONENT")
+		os.Unsetenv("JBP_DEFAULT_TEST_COMPONENT")
+		os.Unsetenv("JBP_STRICT_CONFIG")
+		os.Unsetenv("BUILDPACK_DIR")
+	})
+
+	Describe("EnvVar", func() {
+		It("derives the JBP_CONFIG_* name from the component name", func() {
+			Expect(config.EnvVar("open_jdk_jre")).To(Equal("JBP_CONFIG_OPEN_JDK_JRE"))
+			Expect(config.EnvVar("your-kit-profiler")).To(Equal("JBP_CONFIG_YOUR_KIT_PROFILER"))
+		})
+	})
+
+	Describe("Load", func() {
+		It("keeps the defaults when the variable is not set", func() {
+			cfg := defaults
+			Expect(config.Load(logger, "test_component", &cfg)).To(Succeed())
+			Expect(cfg).To(Equal(defaults))
+			Expect(config.IsSet("test_component")).To(BeFalse())
+		})
+
+		It("deep-merges nested mappings over the defaults", func() {
+			os.Setenv("JBP_CONFIG_TEST_COMPONENT", `{component: {version: "9.+"}, logging: {access_logging: enabled}, labels: {env: prod}}`)
+
+			cfg := defaults
+			Expect(config.Load(logger, "test_component", &cfg)).To(Succeed())
+			Expect(cfg.Component).To(Equal(component{Version: "9.+", Enabled: true}))
+			Expect(cfg.Logging).To(Equal(logging{AccessLogging: "enabled", Level: "INFO"}))
+			Expect(cfg.Labels).To(Equal(map[string]string{"team": "core", "env": "prod"}))
+			Expect(config.IsSet("test_component")).To(BeTrue())
+		})
+
+		It("replaces sequences", func() {
+			os.Setenv("JBP_CONFIG_TEST_COMPONENT", `{opts: ["-Xone", "-Xtwo"]}`)
+
+			cfg := defaults
+			Expect(config.Load(logger, "test_component", &cfg)).To(Succeed())
+			Expect(cfg.Opts).To(Equal([]string{"-Xone", "-Xtwo"}))
+		})
+
+		It("parses block style YAML and quoted values containing YAML syntax", func() {
+			os.Setenv("JBP_CONFIG_TEST_COMPONENT", "component:\n  version: '9.0.1'\nlogging:\n  level: \"false, true: {x}\"\n")
+
+			cfg := defaults
+			Expect(config.Load(logger, "test_component", &cfg)).To(Succeed())
+			Expect(cfg.Component.Version).To(Equal("9.0.1"))
+			Expect(cfg.Component.Enabled).To(BeTrue())
+			Expect(cfg.Logging.Level).To(Equal("false, true: {x}"))
+		})
+
+		It("accepts a mapping quoted as a YAML string", func() {
+			os.Setenv("JBP_CONFIG_TEST_COMPONENT", `'{component: {enabled: false}}'`)
+
+			cfg := defaults
+			Expect(config.Load(logger, "test_component", &cfg)).To(Succeed())
+			Expect(cfg.Component.Enabled).To(BeFalse())
+		})
+
+		It("accepts the legacy sequence form", func() {
+			os.Setenv("JBP_CONFIG_TEST_COMPONENT", `[component: {enabled: false}, opts: ["-Xlegacy"]]`)
+
+			cfg := defaults
+			Expect(config.Load(logger, "test_component", &cfg)).To(Succeed())
+			Expect(cfg.Component).To(Equal(component{Version: "10.+", Enabled: false}))
+			Expect(cfg.Opts).To(Equal([]string{"-Xlegacy"}))
+		})
+
+		It("returns an error for a value that is not a mapping", func() {
+			os.Setenv("JBP_CONFIG_TEST_COMPONENT", "invalid config")
+
+			cfg := defaults
+			err := config.Load(logger, "test_component", &cfg)
+			Expect(err).To(MatchError(ContainSubstring("failed to parse JBP_CONFIG_TEST_COMPONENT")))
+		})
+
+		It("returns an error naming the variable for invalid YAML", func() {
+			os.Setenv("JBP_CONFIG_TEST_COMPONENT", `{component: {version: [}`)
+
+			cfg := defaults
+			err := config.Load(logger, "test_component", &cfg)
+			Expect(err).To(MatchError(ContainSubstring("failed to parse JBP_CONFIG_TEST_COMPONENT")))
+		})
+
+		It("warns about unknown keys and fails on them in strict mode", func() {
+			os.Setenv("JBP_CONFIG_TEST_COMPONENT", `{component: {verison: "9.+"}}`)
+
+			cfg := defaults
+			Expect(config.Load(logger, "test_component", &cfg)).To(Succeed())
+			Expect(buffer.String()).To(ContainSubstring("Unknown user config values in JBP_CONFIG_TEST_COMPONENT"))
+
+			os.Setenv("JBP_STRICT_CONFIG", "true")
+			cfg = defaults
+			err := config.Load(logger, "test_component", &cfg)
+			Expect(common.IsUnknownConfigKeysError(err)).To(BeTrue())
+		})
+
+		Context("with a buildpack defaults file", func() {
+			var buildpackDir string
+
+			BeforeEach(func() {
+				var err error
+				buildpackDir, err = os.MkdirTemp("", "buildpack")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(os.MkdirAll(filepath.Join(buildpackDir, "config"), 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(buildpackDir, "config", "test_component.yml"),
+					[]byte("component:\n  version: 11.+\nlogging:\n  level: DEBUG\n"), 0644)).To(Succeed())
+				os.Setenv("BUILDPACK_DIR", buildpackDir)
+			})
+
+			AfterEach(func() {
+				os.RemoveAll(buildpackDir)
+			})
+
+			It("merges the file over the built-in defaults and the environment over the file", func() {
+				os.Setenv("JBP_CONFIG_TEST_COMPONENT", `{logging: {access_logging: enabled}}`)
+
+				cfg := defaults
+				Expect(config.Load(logger, "test_component", &cfg)).To(Succeed())
+				Expect(cfg.Component).To(Equal(component{Version: "11.+", Enabled: true}))
+				Expect(cfg.Logging).To(Equal(logging{AccessLogging: "enabled", Level: "DEBUG"}))
+			})
+		})
+	})
+
+	Describe("Decode", func() {
+		It("reads a subset of the configuration without reporting other keys", func() {
+			os.Setenv("JBP_CONFIG_TEST_COMPONENT", `{component: {version: 17}, memory_calculator: {stack_threads: 200}}`)
+
+			var cfg struct {
+				Component component `yaml:"component"`
+			}
+			Expect(config.Decode("test_component", &cfg)).To(Succeed())
+			Expect(cfg.Component.Version).To(Equal("17"))
+			Expect(buffer.String()).To(BeEmpty())
+		})
+
+		It("merges the buildpack defaults file under the variable", func() {
+			buildpackDir := GinkgoT().TempDir()
+			Expect(os.MkdirAll(filepath.Join(buildpackDir, "config"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(buildpackDir, "config", "test_component.yml"),
+				[]byte("component:\n  version: 11.+\n  enabled: true\n"), 0644)).To(Succeed())
+			os.Setenv("BUILDPACK_DIR", buildpackDir)
+			os.Setenv("JBP_CONFIG_TEST_COMPONENT", `{component: {version: 17}}`)
+
+			var cfg struct {
+				Component component `yaml:"component"`
+			}
+			Expect(config.Decode("test_component", &cfg)).To(Succeed())
+			Expect(cfg.Component).To(Equal(component{Version: "17", Enabled: true}))
+		})
+	})
+
+	Describe("DecodeOperator", func() {
+		It("merges the operator default over the buildpack defaults file and ignores the application's variable", func() {
+			buildpackDir := GinkgoT().TempDir()
+			Expect(os.MkdirAll(filepath.Join(buildpackDir, "config"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(buildpackDir, "config", "test_component.yml"),
+				[]byte("component:\n  version: 11.+\n  enabled: true\n"), 0644)).To(Succeed())
+			os.Setenv("BUILDPACK_DIR", buildpackDir)
+			os.Setenv("JBP_DEFAULT_TEST_COMPONENT", `{component: {version: 17}}`)
+			os.Setenv("JBP_CONFIG_TEST_COMPONENT", `{component: {version: 21, enabled: false}}`)
+
+			var cfg struct {
+				Component component `yaml:"component"`
+			}
+			Expect(config.DecodeOperator("test_component", &cfg)).To(Succeed())
+			Expect(cfg.Component).To(Equal(component{Version: "17", Enabled: true}))
+		})
+	})
+})
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/libbuildpack"
+)
+
+// EffectiveConfigFile is the name of the file in the dependency directory that holds the effective configuration
+const EffectiveConfigFile = "effective-config.json"
+
+// Redacted replaces the value of secret configuration keys in the effective configuration
+const Redacted = "[REDACTED]"
+
+// Configuration layers, lowest precedence first
+const (
+	SourceBuiltIn         = "built-in defaults"
+	SourceOperatorDefault = "operator default"
+)
+
+// secretKeyPattern matches configuration keys whose values must not be logged
+var secretKeyPattern = regexp.MustCompile(`(?i)(password|passwd|secret|token|credential|license|api_?key|access_?key|private_?key)`)
+
+// EffectiveConfig is the configuration a component resolved after all layers were merged
+type EffectiveConfig struct {
+	// Sources lists the layers that contributed, lowest precedence first
+	Sources []string `json:"sources"`
+	// Values holds the merged configuration with secrets redacted
+	Values interface{} `json:"values"`
+}
+
+var (
+	effectiveMu      sync.Mutex
+	effectiveConfigs = map[string]EffectiveConfig{}
+)
+
+// record remembers the configuration Load resolved for component
+func record(component string, sources []string, out interface{}) {
+	effectiveMu.Lock()
+	defer effectiveMu.Unlock()
+	effectiveConfigs[component] = EffectiveConfig{Sources: sources, Values: redact(out)}
+}
+
+// Effective returns the effective configuration of every component loaded so far, keyed by component name
+func Effective() map[string]EffectiveConfig {
+	effectiveMu.Lock()
+	defer effectiveMu.Unlock()
+
+	result := make(map[string]EffectiveConfig, len(effectiveConfigs))
+	for component, effective := range effectiveConfigs {
+		result[component] = effective
+	}
+	return result
+}
+
+// ResetEffective forgets all recorded configuration
+func ResetEffective() {
+	effectiveMu.Lock()
+	defer effectiveMu.Unlock()
+	effectiveConfigs = map[string]EffectiveConfig{}
+}
+
+// WriteEffective logs the components whose configuration differs from the built-in defaults and writes the
+// effective configuration of all components to path as JSON. Components recorded in an existing file at path,
+// e.g. by the supply phase, are kept unless they were loaded again, and are not logged again if they are unchanged,
+// so finalize only logs the components supply did not load.
+func WriteEffective(log *libbuildpack.Logger, path string) error {
+	all := map[string]EffectiveConfig{}
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &all); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	current := Effective()
+	components := make([]string, 0, len(current))
+	for component, effective := range current {
+		if previous, ok := all[component]; !ok || !sameEffective(previous, effective) {
+			components = append(components, component)
+		}
+		all[component] = effective
+	}
+	sort.Strings(components)
+
+	for _, component := range components {
+		effective := current[component]
+		if len(effective.Sources) < 2 {
+			continue
+		}
+		values, err := json.Marshal(effective.Values)
+		if err != nil {
+			return err
+		}
+		log.Info("Effective %s configuration (from %s): %s", component, strings.Join(effective.Sources, " < "), values)
+	}
+
+	data, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	log.Debug("Wrote effective configuration of %d components to %s", len(all), path)
+	return nil
+}
+
+// sameEffective returns true if a and b have the same sources and values once written as JSON
+func sameEffective(a, b EffectiveConfig) bool {
+	dataA, errA := json.Marshal(a)
+	dataB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(dataA) == string(dataB)
+}
+
+// envSources describes where the JBP_CONFIG_* value of component came from: the operator default, the application,
+// or the application's value merged over the operator default
+func envSources(component string) []string {
+	envVar := EnvVar(component)
+	defaultVar := "JBP_DEFAULT_" + strings.TrimPrefix(envVar, EnvPrefix)
+	value, ok := os.LookupEnv(defaultVar)
+	if !ok {
+		return []string{envVar}
+	}
+	operator := defaultVar + " (" + SourceOperatorDefault + ")"
+	if value == os.Getenv(envVar) {
+		return []string{operator}
+	}
+	return []string{operator, envVar}
+}
+
+// redact converts out into generic YAML values keyed by the configuration's YAML names and replaces the
+// values of secret keys
+func redact(out interface{}) interface{} {
+	yamlHandler := common.YamlHandler{}
+	data, err := yamlHandler.Marshal(out)
+	if err != nil {
+		return nil
+	}
+
+	var values interface{}
+	if err := yamlHandler.Unmarshal(data, &values); err != nil {
+		return nil
+	}
+	return redactValue(values)
+}
+
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if secretKeyPattern.MatchString(key) && item != nil && item != "" {
+				v[key] = Redacted
+				continue
+			}
+			v[key] = redactValue(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+		return v
+	case string:
+		// URIs such as repository roots may embed credentials
+		if u, err := url.Parse(v); err == nil && u.User != nil {
+			return u.Redacted()
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"go.yaml.in/yaml/v3"
+)
+
+// Schema returns a pointer to a new, empty configuration struct of a component, the type its Load call decodes into
+type Schema func() interface{}
+
+// Checker is implemented by configuration structs with constraints beyond their types, e.g. allowed values.
+// Check returns a message for every violated constraint.
+type Checker interface {
+	Check() []string
+}
+
+var (
+	schemaMu sync.Mutex
+	schemas  = map[string]Schema{}
+)
+
+// RegisterSchema records the configuration struct of component, so that JBP_CONFIG_<COMPONENT> values can be
+// validated without staging an application. Packages register their components in init.
+func RegisterSchema(component string, schema Schema) {
+	schemaMu.Lock()
+	defer schemaMu.Unlock()
+	schemas[component] = schema
+}
+
+// Components returns the names of all components with a registered schema, sorted
+func Components() []string {
+	schemaMu.Lock()
+	defer schemaMu.Unlock()
+
+	components := make([]string, 0, len(schemas))
+	for component := range schemas {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+	return components
+}
+
+// ComponentForEnvVar returns the component configured by a JBP_CONFIG_* environment variable, e.g. "open_jdk_jre"
+// for JBP_CONFIG_OPEN_JDK_JRE, and whether it has a registered schema
+func ComponentForEnvVar(name string) (string, bool) {
+	for _, component := range Components() {
+		if EnvVar(component) == name {
+			return component, true
+		}
+	}
+	return strings.ToLower(strings.TrimPrefix(name, EnvPrefix)), false
+}
+
+// Validate checks a JBP_CONFIG_<COMPONENT> value against the registered schema of component and returns a message
+// for every problem: values that are not a mapping, unknown keys, values of the wrong type and violated constraints.
+// Unlike Load, unknown keys are always reported, whether or not JBP_STRICT_CONFIG is set.
+func Validate(component, value string) []string {
+	schemaMu.Lock()
+	schema, ok := schemas[component]
+	schemaMu.Unlock()
+	if !ok {
+		return []string{fmt.Sprintf("unknown component %s", component)}
+	}
+
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+
+	data, err := Normalize([]byte(value))
+	if err != nil {
+		return []string{fmt.Sprintf("invalid value: %s", err.Error())}
+	}
+
+	out := schema()
+	if err := (common.YamlHandler{}).ValidateFields(data, out); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return []string{fmt.Sprintf("invalid value: %s", err.Error())}
+		}
+		problems := make([]string, 0, len(typeErr.Errors))
+		for _, msg := range typeErr.Errors {
+			problems = append(problems, strings.TrimSpace(msg))
+		}
+		return problems
+	}
+
+	if checker, ok := out.(Checker); ok {
+		return checker.Check()
+	}
+	return nil
+}
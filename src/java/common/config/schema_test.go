@@ -0,0 +1,68 @@
+package config_test
+
+import (
+	"fmt"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type schemaTestConfig struct {
+	Enabled bool `yaml:"enabled"`
+	Port    int  `yaml:"port"`
+}
+
+// Check rejects privileged ports
+func (c *schemaTestConfig) Check() []string {
+	if c.Port > 0 && c.Port < 1024 {
+		return []string{fmt.Sprintf("port %d is privileged", c.Port)}
+	}
+	return nil
+}
+
+var _ = Describe("Schema", func() {
+	BeforeEach(func() {
+		config.RegisterSchema("schema_test", func() interface{} { return &schemaTestConfig{} })
+	})
+
+	It("lists registered components", func() {
+		Expect(config.Components()).To(ContainElement("schema_test"))
+	})
+
+	It("maps environment variables to components", func() {
+		component, known := config.ComponentForEnvVar("JBP_CONFIG_SCHEMA_TEST")
+		Expect(known).To(BeTrue())
+		Expect(component).To(Equal("schema_test"))
+
+		component, known = config.ComponentForEnvVar("JBP_CONFIG_UNREGISTERED")
+		Expect(known).To(BeFalse())
+		Expect(component).To(Equal("unregistered"))
+	})
+
+	It("accepts valid values in every supported format", func() {
+		Expect(config.Validate("schema_test", "{enabled: true, port: 8080}")).To(BeEmpty())
+		Expect(config.Validate("schema_test", "'{enabled: true}'")).To(BeEmpty())
+		Expect(config.Validate("schema_test", "[enabled: true, port: 8080]")).To(BeEmpty())
+		Expect(config.Validate("schema_test", "")).To(BeEmpty())
+	})
+
+	It("reports unknown keys and values of the wrong type", func() {
+		Expect(config.Validate("schema_test", "{enabled: maybe, prot: 8080}")).To(ConsistOf(
+			ContainSubstring("cannot unmarshal !!str `maybe` into bool"),
+			ContainSubstring("field prot not found"),
+		))
+	})
+
+	It("reports values that are not a mapping", func() {
+		Expect(config.Validate("schema_test", "enabled")).To(ConsistOf(ContainSubstring("expected a mapping")))
+	})
+
+	It("reports violated constraints", func() {
+		Expect(config.Validate("schema_test", "{port: 80}")).To(ConsistOf("port 80 is privileged"))
+	})
+
+	It("reports unknown components", func() {
+		Expect(config.Validate("unregistered", "{enabled: true}")).To(ConsistOf("unknown component unregistered"))
+	})
+})
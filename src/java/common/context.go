@@ -3,10 +3,12 @@ package common
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/applibs"
 	"github.com/cloudfoundry/libbuildpack"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -40,6 +42,17 @@ type Installer interface {
 	InstallDependencyWithStrip(libbuildpack.Dependency, string, int) error
 }
 
+// InstallDependency installs dep into outputDir with the installer of ctx and returns the dependency installed,
+// which differs from dep when the installer falls back to another patch version (see InstallerConfig)
+func InstallDependency(ctx *Context, dep libbuildpack.Dependency, outputDir string) (libbuildpack.Dependency, error) {
+	if installer, ok := ctx.Installer.(interface {
+		InstallDependencyVersion(libbuildpack.Dependency, string, int) (libbuildpack.Dependency, error)
+	}); ok {
+		return installer.InstallDependencyVersion(dep, outputDir, 0)
+	}
+	return dep, ctx.Installer.InstallDependency(dep, outputDir)
+}
+
 // Context holds shared dependencies for buildpack components
 // Used by containers, frameworks, and JREs to access buildpack infrastructure
 type Context struct {
@@ -50,6 +63,13 @@ type Context struct {
 	Command   Command
 }
 
+// Classpath returns the JARs the application brings with it. The application is scanned once and the result shared
+// by all components, so that frameworks can cheaply inspect its libraries in Detect, e.g. to attach an agent only to
+// applications that use the library it instruments.
+func (c *Context) Classpath() *applibs.Classpath {
+	return applibs.Scan(c.Stager.BuildDir())
+}
+
 // DetermineJavaVersion determines the major Java version from a Java installation
 // by reading the JAVA_VERSION field from the release file.
 //
@@ -196,23 +216,38 @@ func (v VCAPServices) HasServiceByNamePattern(pattern string) bool {
 	return v.GetServiceByNamePattern(pattern) != nil
 }
 
-// GetServiceByNamePattern returns the first service that matches the pattern
+// GetServiceByNamePattern returns the first service, in the order of Sorted, that matches the pattern
 // Returns nil if no matching service is found
 // Pattern matching is case-insensitive substring matching (e.g., "newrelic" matches "my-newrelic-service")
 // Searches across all service labels, not just "user-provided"
 func (v VCAPServices) GetServiceByNamePattern(pattern string) *VCAPService {
 	// Case-insensitive substring matching
 	patternLower := strings.ToLower(pattern)
-	for _, services := range v {
-		for _, service := range services {
-			if strings.Contains(strings.ToLower(service.Name), patternLower) {
-				return &service
-			}
+	for _, service := range v.Sorted() {
+		if strings.Contains(strings.ToLower(service.Name), patternLower) {
+			return &service
 		}
 	}
 	return nil
 }
 
+// Sorted returns all bound services ordered by label and, within a label, in the order of VCAP_SERVICES.
+// Go randomizes map iteration, so callers that pick the first of several matching services must iterate
+// Sorted to pick the same service on every staging and in every phase.
+func (v VCAPServices) Sorted() []VCAPService {
+	labels := make([]string, 0, len(v))
+	for label := range v {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	var services []VCAPService
+	for _, label := range labels {
+		services = append(services, v[label]...)
+	}
+	return services
+}
+
 // HasTag checks if this service has the specified tag
 func (s *VCAPService) HasTag(tag string) bool {
 	for _, t := range s.Tags {
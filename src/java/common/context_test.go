@@ -0,0 +1,57 @@
+package common_test
+
+import (
+	"os"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("VCAPServices", func() {
+	// services binds several services whose names contain "luna", under labels that are not in sorted order
+	const services = `{
+		"user-provided": [{"name": "luna-backup", "label": "user-provided"}, {"name": "luna-primary", "label": "user-provided"}],
+		"luna": [{"name": "luna-hsm", "label": "luna"}],
+		"dynatrace": [{"name": "monitoring", "label": "dynatrace"}]
+	}`
+
+	BeforeEach(func() {
+		os.Setenv("VCAP_SERVICES", services)
+	})
+
+	AfterEach(func() {
+		os.Unsetenv("VCAP_SERVICES")
+	})
+
+	It("sorts services by label and keeps the binding order within a label", func() {
+		vcapServices, err := common.GetVCAPServices()
+		Expect(err).NotTo(HaveOccurred())
+
+		var names []string
+		for _, service := range vcapServices.Sorted() {
+			names = append(names, service.Name)
+		}
+		Expect(names).To(Equal([]string{"monitoring", "luna-hsm", "luna-backup", "luna-primary"}))
+	})
+
+	It("picks the same service by name pattern on every call", func() {
+		for i := 0; i < 20; i++ {
+			vcapServices, err := common.GetVCAPServices()
+			Expect(err).NotTo(HaveOccurred())
+
+			service := vcapServices.GetServiceByNamePattern("luna")
+			Expect(service).NotTo(BeNil())
+			Expect(service.Name).To(Equal("luna-hsm"))
+		}
+	})
+
+	It("returns nothing without VCAP_SERVICES", func() {
+		os.Unsetenv("VCAP_SERVICES")
+
+		vcapServices, err := common.GetVCAPServices()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(vcapServices.Sorted()).To(BeEmpty())
+		Expect(vcapServices.GetServiceByNamePattern("luna")).To(BeNil())
+	})
+})
@@ -0,0 +1,223 @@
+package common
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/cloudfoundry/libbuildpack"
+)
+
+const megabyte = 1024 * 1024
+
+// DiskGuard watches the disk space that staging uses. A JRE and several agents are downloaded and extracted in turn,
+// and while a dependency is installed its archive, the copy in the application cache and the extracted files exist
+// side by side, so staging can run out of disk space well before the droplet is complete. The guard warns before
+// installations that are likely to exceed the space left (see Preflight), removes the temporary files each step leaves
+// behind as soon as it completes (see Groom) and reports the peak usage (see Report).
+type DiskGuard struct {
+	log   *libbuildpack.Logger
+	limit int64
+	dirs  []string
+
+	tempDir  string
+	origTemp string
+	hadTemp  bool
+
+	baseline int64
+	peak     int64
+	peakStep string
+}
+
+// NewDiskGuard creates a guard of the disk usage of dirs, usually the build, deps and cache directories, against a
+// staging disk limit of limitMB megabytes. With a limit of 0 installations are only checked against the free space of
+// the filesystem.
+func NewDiskGuard(log *libbuildpack.Logger, limitMB int, dirs ...string) *DiskGuard {
+	g := &DiskGuard{log: log, limit: int64(limitMB) * megabyte, dirs: dirs}
+	g.baseline = g.Used()
+	g.peak = g.baseline
+	return g
+}
+
+// UseTempDir points TMPDIR to a directory of its own, so that the temporary files of the following steps, including
+// those of libbuildpack, can be told apart and removed by Groom. Close removes the directory and restores TMPDIR.
+func (g *DiskGuard) UseTempDir() error {
+	dir, err := os.MkdirTemp("", "java-buildpack-staging")
+	if err != nil {
+		return err
+	}
+	g.origTemp, g.hadTemp = os.LookupEnv("TMPDIR")
+	g.tempDir = dir
+	return os.Setenv("TMPDIR", dir)
+}
+
+// Close removes the temporary directory of UseTempDir and restores TMPDIR
+func (g *DiskGuard) Close() {
+	if g.tempDir == "" {
+		return
+	}
+	if g.hadTemp {
+		os.Setenv("TMPDIR", g.origTemp)
+	} else {
+		os.Unsetenv("TMPDIR")
+	}
+	os.RemoveAll(g.tempDir)
+	g.tempDir = ""
+}
+
+// Used returns the size of the watched directories and of the temporary directory, in bytes
+func (g *DiskGuard) Used() int64 {
+	var used int64
+	for _, dir := range g.dirs {
+		used += dirSize(dir)
+	}
+	if g.tempDir != "" {
+		used += dirSize(g.tempDir)
+	}
+	return used
+}
+
+// Available returns the space left for staging, in bytes: the free space of the filesystem of the first watched
+// directory, but no more than what remains of the limit. It returns -1 if neither is known.
+func (g *DiskGuard) Available() int64 {
+	available := int64(-1)
+	if len(g.dirs) > 0 {
+		available = freeSpace(g.dirs[0])
+	}
+	if g.limit > 0 {
+		remaining := g.limit - g.Used()
+		if remaining < 0 {
+			remaining = 0
+		}
+		if available < 0 || remaining < available {
+			available = remaining
+		}
+	}
+	return available
+}
+
+// Preflight warns if installing what is estimated to need more than the space left. need is in bytes, see
+// EstimateInstallSize; unknown estimates (< 0) are not checked. With suggestStreaming the warning suggests
+// stream_extract, for downloaded tarballs that are not extracted while they are downloaded yet.
+func (g *DiskGuard) Preflight(what string, need int64, suggestStreaming bool) {
+	if need < 0 {
+		g.log.Debug("Not checking the disk space for %s: its size is unknown", what)
+		return
+	}
+
+	available := g.Available()
+	g.log.Debug("Installing %s needs about %s of disk space, %s is available", what, formatMegabytes(need), formatMegabytes(available))
+	if available < 0 || need <= available {
+		return
+	}
+
+	advice := "increase the disk quota of the application, e.g. with cf push -k"
+	if suggestStreaming {
+		advice += ", or set stream_extract: true in JBP_CONFIG_DEPENDENCY_INSTALLER to extract archives while they are downloaded"
+	}
+	g.log.Warning("Installing %s needs about %s of disk space during staging, but only %s is left.\n"+
+		"  Staging is likely to fail with 'no space left on device': %s",
+		what, formatMegabytes(need), formatMegabytes(available), advice)
+}
+
+// Groom removes the temporary files that remain after step, which has completed, and records the disk usage after
+// it. Only files in the directory of UseTempDir are removed.
+func (g *DiskGuard) Groom(step string) {
+	if g.tempDir != "" {
+		entries, _ := os.ReadDir(g.tempDir)
+		var removed []string
+		var freed int64
+		for _, entry := range entries {
+			path := filepath.Join(g.tempDir, entry.Name())
+			size := dirSize(path)
+			if err := os.RemoveAll(path); err != nil {
+				g.log.Debug("Could not remove temporary file %s: %s", path, err.Error())
+				continue
+			}
+			removed = append(removed, entry.Name())
+			freed += size
+		}
+		if len(removed) > 0 {
+			g.log.Debug("Removed temporary files left by %s (%s): %s", step, formatMegabytes(freed), strings.Join(removed, ", "))
+		}
+	}
+
+	used := g.Used()
+	if used > g.peak {
+		g.peak, g.peakStep = used, step
+	}
+	g.log.Debug("Disk usage after %s: %s", step, formatMegabytes(used))
+}
+
+// Report logs the disk usage of staging: the usage at the end, its peak and, if there is one, the limit
+func (g *DiskGuard) Report() {
+	used := g.Used()
+	message := fmt.Sprintf("Staging uses %s of disk space (%s before supply", formatMegabytes(used), formatMegabytes(g.baseline))
+	if g.peakStep != "" && g.peak > used {
+		message += fmt.Sprintf(", peak of %s after %s", formatMegabytes(g.peak), g.peakStep)
+	}
+	if g.limit > 0 {
+		message += fmt.Sprintf(", limit of %s", formatMegabytes(g.limit))
+	}
+	g.log.Info("%s)", message)
+}
+
+// EstimateInstallSize estimates the peak disk space that installing an archive of archiveSize bytes published as uri
+// needs: the extracted files plus, unless the archive is extracted while it is downloaded, the download and its copy
+// in the application cache. It returns -1 if archiveSize is unknown.
+func EstimateInstallSize(archiveSize int64, uri string, streamed bool) int64 {
+	if archiveSize < 0 {
+		return -1
+	}
+
+	// Typical ratios of the extracted size of JREs and agents to their archives
+	var extracted int64
+	switch {
+	case strings.HasSuffix(uri, ".tar.xz") || IsZstdArchive(uri):
+		extracted = archiveSize * 4
+	case strings.HasSuffix(uri, ".tar.gz") || strings.HasSuffix(uri, ".tgz") || strings.HasSuffix(uri, ".zip"):
+		extracted = archiveSize * 5 / 2
+	default:
+		extracted = archiveSize
+	}
+
+	if streamed {
+		return extracted
+	}
+	return extracted + 2*archiveSize
+}
+
+// dirSize returns the total size of the regular files below path, ignoring files that cannot be read
+func dirSize(path string) int64 {
+	var size int64
+	filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// freeSpace returns the space available to unprivileged users on the filesystem of path, or -1 if it is unknown
+func freeSpace(path string) int64 {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return -1
+	}
+	return int64(stat.Bavail) * int64(stat.Bsize)
+}
+
+// formatMegabytes formats a size in bytes as whole megabytes, the unit of the disk_limit setting
+func formatMegabytes(size int64) string {
+	if size < 0 {
+		return "an unknown amount"
+	}
+	return fmt.Sprintf("%d MB", (size+megabyte-1)/megabyte)
+}
@@ -0,0 +1,151 @@
+package common_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/libbuildpack"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DiskGuard", func() {
+	const megabyte = 1024 * 1024
+
+	var (
+		depsDir string
+		logs    *bytes.Buffer
+		logger  *libbuildpack.Logger
+	)
+
+	BeforeEach(func() {
+		var err error
+		depsDir, err = os.MkdirTemp("", "deps")
+		Expect(err).NotTo(HaveOccurred())
+		logs = new(bytes.Buffer)
+		logger = libbuildpack.NewLogger(logs)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(depsDir)
+	})
+
+	Describe("Preflight", func() {
+		It("warns when an installation needs more than what remains of the limit", func() {
+			Expect(os.WriteFile(filepath.Join(depsDir, "jre.tar.gz"), bytes.Repeat([]byte{0}, megabyte), 0644)).To(Succeed())
+			guard := common.NewDiskGuard(logger, 3, depsDir)
+
+			guard.Preflight("openjdk 17.0.13", 3*megabyte, true)
+
+			Expect(logs.String()).To(ContainSubstring("Installing openjdk 17.0.13 needs about 3 MB of disk space during staging, but only 2 MB is left"))
+			Expect(logs.String()).To(ContainSubstring("increase the disk quota of the application, e.g. with cf push -k"))
+			Expect(logs.String()).To(ContainSubstring("extract archives while they are downloaded"))
+		})
+
+		It("does not suggest streaming unless asked to", func() {
+			guard := common.NewDiskGuard(logger, 1, depsDir)
+
+			guard.Preflight("openjdk 17.0.13", 2*megabyte, false)
+
+			Expect(logs.String()).To(ContainSubstring("only 1 MB is left"))
+			Expect(logs.String()).NotTo(ContainSubstring("stream_extract"))
+		})
+
+		It("does not warn about installations within the limit", func() {
+			common.NewDiskGuard(logger, 3, depsDir).Preflight("openjdk 17.0.13", 2*megabyte, true)
+			Expect(logs.String()).NotTo(ContainSubstring("WARNING"))
+		})
+
+		It("does not check installations of unknown size", func() {
+			common.NewDiskGuard(logger, 1, depsDir).Preflight("openjdk 17.0.13", -1, true)
+			Expect(logs.String()).NotTo(ContainSubstring("WARNING"))
+		})
+
+		It("checks installations against the free space without a limit", func() {
+			guard := common.NewDiskGuard(logger, 0, depsDir)
+			Expect(guard.Available()).To(BeNumerically(">", 0))
+
+			guard.Preflight("openjdk 17.0.13", guard.Available()+megabyte, true)
+			Expect(logs.String()).To(ContainSubstring("Installing openjdk 17.0.13"))
+		})
+	})
+
+	Describe("Groom", func() {
+		var (
+			guard    *common.DiskGuard
+			origTemp string
+		)
+
+		BeforeEach(func() {
+			origTemp = os.TempDir()
+			guard = common.NewDiskGuard(logger, 0, depsDir)
+			Expect(guard.UseTempDir()).To(Succeed())
+		})
+
+		AfterEach(func() {
+			guard.Close()
+		})
+
+		It("removes the temporary files a step leaves behind", func() {
+			tempDir := os.TempDir()
+			Expect(tempDir).NotTo(Equal(origTemp))
+			leftover, err := os.MkdirTemp("", "downloads")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(leftover, "archive"), []byte("archive"), 0644)).To(Succeed())
+
+			guard.Groom("OpenJDK")
+
+			Expect(leftover).NotTo(BeAnExistingFile())
+			Expect(tempDir).To(BeADirectory())
+		})
+
+		It("keeps the files of the watched directories", func() {
+			Expect(os.WriteFile(filepath.Join(depsDir, "agent.jar"), []byte("agent"), 0644)).To(Succeed())
+
+			guard.Groom("New Relic Agent")
+
+			Expect(filepath.Join(depsDir, "agent.jar")).To(BeARegularFile())
+		})
+
+		It("restores TMPDIR and removes its temporary directory when closed", func() {
+			tempDir := os.TempDir()
+
+			guard.Close()
+
+			Expect(os.TempDir()).To(Equal(origTemp))
+			Expect(tempDir).NotTo(BeAnExistingFile())
+		})
+	})
+
+	Describe("Report", func() {
+		It("reports the usage and its peak", func() {
+			guard := common.NewDiskGuard(logger, 10, depsDir)
+			jre := filepath.Join(depsDir, "jre")
+			Expect(os.WriteFile(jre, bytes.Repeat([]byte{0}, 2*megabyte), 0644)).To(Succeed())
+			guard.Groom("OpenJDK")
+			Expect(os.Remove(jre)).To(Succeed())
+
+			guard.Report()
+
+			Expect(logs.String()).To(ContainSubstring("Staging uses 0 MB of disk space (0 MB before supply, peak of 2 MB after OpenJDK, limit of 10 MB)"))
+		})
+	})
+
+	Describe("EstimateInstallSize", func() {
+		It("adds the download and its cached copy to the extracted size", func() {
+			Expect(common.EstimateInstallSize(100, "https://example.com/jre.tar.gz", false)).To(Equal(int64(450)))
+			Expect(common.EstimateInstallSize(100, "https://example.com/jre.tar.zst", false)).To(Equal(int64(600)))
+			Expect(common.EstimateInstallSize(100, "https://example.com/agent.jar", false)).To(Equal(int64(300)))
+		})
+
+		It("only counts the extracted size of streamed archives", func() {
+			Expect(common.EstimateInstallSize(100, "https://example.com/jre.tar.gz", true)).To(Equal(int64(250)))
+		})
+
+		It("does not estimate archives of unknown size", func() {
+			Expect(common.EstimateInstallSize(-1, "https://example.com/jre.tar.gz", false)).To(Equal(int64(-1)))
+		})
+	})
+})
@@ -0,0 +1,136 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultRuntimeRoot is the directory that Cloud Foundry's Diego cells run the droplet from
+const DefaultRuntimeRoot = "/home/vcap"
+
+// RuntimeRootEnvVar overrides DefaultRuntimeRoot for platforms that run the droplet from another directory, e.g. a
+// Korifi installation that mounts it elsewhere
+const RuntimeRootEnvVar = "JBP_RUNTIME_ROOT"
+
+// RuntimeRoot returns the directory the droplet runs from, which holds the application in app and the deps
+// directories in deps
+func RuntimeRoot() string {
+	if root := strings.TrimSpace(os.Getenv(RuntimeRootEnvVar)); root != "" {
+		return path.Clean(root)
+	}
+	return DefaultRuntimeRoot
+}
+
+// RuntimeAppDir returns the directory of the application at runtime, $HOME
+func RuntimeAppDir() string {
+	return path.Join(RuntimeRoot(), "app")
+}
+
+// RuntimeDepsDir returns the directory of the deps directories of all buildpacks at runtime, $DEPS_DIR
+func RuntimeDepsDir() string {
+	return path.Join(RuntimeRoot(), "deps")
+}
+
+// Droplet maps the paths of the build directory and the deps directories during staging to the paths of the same
+// files when the application runs. The directories are staged at temporary locations, e.g. /tmp/app and
+// /tmp/deps/0, but run from $HOME and $DEPS_DIR/0, so paths that are written to JAVA_OPTS, profile.d scripts or the
+// start command must be converted.
+//
+// The paths that Droplet returns use $HOME and $DEPS_DIR, which the profile.d scripts and the start command expand;
+// the Absolute* methods return the values of these variables instead, for commands that run before the profile.d
+// scripts set $DEPS_DIR.
+type Droplet struct {
+	stager Stager
+}
+
+// NewDroplet returns the droplet staged by stager
+func NewDroplet(stager Stager) Droplet {
+	return Droplet{stager: stager}
+}
+
+// Droplet returns the droplet staged by the context's stager
+func (c *Context) Droplet() Droplet {
+	return NewDroplet(c.Stager)
+}
+
+// Dep returns the runtime path of elem in the deps directory of this buildpack, e.g. $DEPS_DIR/0/jacoco_agent/jacocoagent.jar
+// for Dep("jacoco_agent", "jacocoagent.jar")
+func (d Droplet) Dep(elem ...string) string {
+	return runtimeJoin("$DEPS_DIR", append([]string{d.stager.DepsIdx()}, elem...))
+}
+
+// App returns the runtime path of elem in the application directory, e.g. $HOME/BOOT-INF/lib/* for
+// App("BOOT-INF", "lib", "*")
+func (d Droplet) App(elem ...string) string {
+	return runtimeJoin("$HOME", elem)
+}
+
+// AbsoluteDep returns Dep with $DEPS_DIR replaced by its value, e.g. /home/vcap/deps/0/jre/bin/jvmkill.so. The
+// values follow RuntimeRoot.
+func (d Droplet) AbsoluteDep(elem ...string) string {
+	return absolute(d.Dep(elem...))
+}
+
+// RuntimePath returns the runtime path of a file staged in the build directory or the deps directory of any
+// buildpack, e.g. $DEPS_DIR/1/new_relic_agent/newrelic.jar for /tmp/deps/1/new_relic_agent/newrelic.jar. It returns
+// an error for paths outside these directories.
+func (d Droplet) RuntimePath(stagingPath string) (string, error) {
+	if rel, ok := relativeTo(filepath.Dir(d.stager.DepDir()), stagingPath); ok {
+		return runtimeJoin("$DEPS_DIR", []string{rel}), nil
+	}
+	if rel, ok := relativeTo(d.stager.BuildDir(), stagingPath); ok {
+		return runtimeJoin("$HOME", []string{rel}), nil
+	}
+	return "", fmt.Errorf("%s is neither in the build directory nor in a deps directory", stagingPath)
+}
+
+// AbsolutePath returns RuntimePath with $HOME and $DEPS_DIR replaced by their values
+func (d Droplet) AbsolutePath(stagingPath string) (string, error) {
+	runtimePath, err := d.RuntimePath(stagingPath)
+	if err != nil {
+		return "", err
+	}
+	return absolute(runtimePath), nil
+}
+
+// Absolute returns runtimePath, e.g. $DEPS_DIR/0/jacoco_agent/jacocoagent.jar, with a leading $HOME or $DEPS_DIR replaced by
+// its value
+func (d Droplet) Absolute(runtimePath string) string {
+	return absolute(runtimePath)
+}
+
+// runtimeJoin joins root and the slash-separated elements
+func runtimeJoin(root string, elem []string) string {
+	parts := []string{root}
+	for _, e := range elem {
+		if e = filepath.ToSlash(e); e != "" && e != "." {
+			parts = append(parts, e)
+		}
+	}
+	return path.Join(parts...)
+}
+
+// absolute replaces the leading $HOME or $DEPS_DIR of a runtime path by its value
+func absolute(runtimePath string) string {
+	for variable, value := range map[string]string{"$HOME": RuntimeAppDir(), "$DEPS_DIR": RuntimeDepsDir()} {
+		if runtimePath == variable || strings.HasPrefix(runtimePath, variable+"/") {
+			return value + strings.TrimPrefix(runtimePath, variable)
+		}
+	}
+	return runtimePath
+}
+
+// relativeTo returns the path of target relative to dir, if target is dir or is below it
+func relativeTo(dir, target string) (string, bool) {
+	if dir == "" || dir == "." {
+		return "", false
+	}
+	rel, err := filepath.Rel(dir, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel) {
+		return "", false
+	}
+	return rel, true
+}
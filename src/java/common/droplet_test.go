@@ -0,0 +1,63 @@
+package common_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/libbuildpack"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Droplet", func() {
+	var droplet common.Droplet
+
+	BeforeEach(func() {
+		logger := libbuildpack.NewLogger(new(bytes.Buffer))
+		stager := libbuildpack.NewStager([]string{"/tmp/app", "/tmp/cache", "/tmp/deps", "1"}, logger, &libbuildpack.Manifest{})
+		droplet = common.NewDroplet(stager)
+	})
+
+	It("returns runtime paths in the deps directory of the buildpack and the application directory", func() {
+		Expect(droplet.Dep("jacoco_agent", "jacocoagent.jar")).To(Equal("$DEPS_DIR/1/jacoco_agent/jacocoagent.jar"))
+		Expect(droplet.Dep("jacoco_agent", "jacocoagent.jar")).To(Equal("$DEPS_DIR/1/jacoco_agent/jacocoagent.jar"))
+		Expect(droplet.Dep()).To(Equal("$DEPS_DIR/1"))
+		Expect(droplet.App("BOOT-INF", "lib", "*")).To(Equal("$HOME/BOOT-INF/lib/*"))
+		Expect(droplet.App()).To(Equal("$HOME"))
+		Expect(droplet.AbsoluteDep("jre", "bin", "jvmkill.so")).To(Equal("/home/vcap/deps/1/jre/bin/jvmkill.so"))
+	})
+
+	It("maps staging paths to runtime paths", func() {
+		Expect(droplet.RuntimePath(filepath.Join("/tmp/deps/1", "new_relic_agent", "newrelic.jar"))).
+			To(Equal("$DEPS_DIR/1/new_relic_agent/newrelic.jar"))
+		Expect(droplet.RuntimePath("/tmp/deps/0/tomcat/lib")).To(Equal("$DEPS_DIR/0/tomcat/lib"))
+		Expect(droplet.RuntimePath("/tmp/app/lib/app.jar")).To(Equal("$HOME/lib/app.jar"))
+		Expect(droplet.RuntimePath("/tmp/app")).To(Equal("$HOME"))
+	})
+
+	It("maps staging paths to absolute runtime paths", func() {
+		Expect(droplet.AbsolutePath("/tmp/deps/1/jre/bin/java-buildpack-memory-calculator")).
+			To(Equal("/home/vcap/deps/1/jre/bin/java-buildpack-memory-calculator"))
+		Expect(droplet.AbsolutePath("/tmp/app/BOOT-INF/classes")).To(Equal("/home/vcap/app/BOOT-INF/classes"))
+	})
+
+	It("maps to absolute runtime paths below a configured runtime root", func() {
+		os.Setenv(common.RuntimeRootEnvVar, "/workspace/")
+		defer os.Unsetenv(common.RuntimeRootEnvVar)
+
+		Expect(common.RuntimeAppDir()).To(Equal("/workspace/app"))
+		Expect(common.RuntimeDepsDir()).To(Equal("/workspace/deps"))
+		Expect(droplet.AbsoluteDep("jre", "bin", "jvmkill.so")).To(Equal("/workspace/deps/1/jre/bin/jvmkill.so"))
+		Expect(droplet.AbsolutePath("/tmp/app/BOOT-INF/classes")).To(Equal("/workspace/app/BOOT-INF/classes"))
+	})
+
+	It("rejects paths outside the droplet", func() {
+		_, err := droplet.RuntimePath("/tmp/application/lib/app.jar")
+		Expect(err).To(MatchError("/tmp/application/lib/app.jar is neither in the build directory nor in a deps directory"))
+
+		_, err = droplet.AbsolutePath("/opt/jdk/bin/java")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,157 @@
+// Package features gates new buildpack behaviors behind feature flags, so operators can roll them out
+// gradually before they become the default.
+//
+// Flags are configuration component feature_flags. Only the operator sets them: the buildpack's
+// config/feature_flags.yml is merged with JBP_DEFAULT_FEATURE_FLAGS, and the application's JBP_CONFIG_FEATURE_FLAGS
+// is ignored. A flag is either a boolean or limited to organizations and spaces, e.g.
+//
+//	app_cds:
+//	  enabled: true
+//	  spaces: [development, staging]
+package features
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/libbuildpack"
+	"go.yaml.in/yaml/v3"
+)
+
+// AppCDS lets applications that set JBP_CONFIG_APP_CDS create an AppCDS archive with a training run during staging.
+// The flag alone does not enable the training run.
+const AppCDS = "app_cds"
+
+// descriptions lists the known flags with the behavior they gate
+var descriptions = map[string]string{
+	AppCDS: "AppCDS training run during staging for applications that enable it",
+}
+
+// Flag is the state of a feature flag
+type Flag struct {
+	Enabled bool `yaml:"enabled"`
+	// Organizations and Spaces limit an enabled flag to applications in the named organizations and spaces
+	Organizations []string `yaml:"organizations"`
+	Spaces        []string `yaml:"spaces"`
+}
+
+// UnmarshalYAML accepts a boolean as shorthand for a flag enabled everywhere
+func (f *Flag) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		return value.Decode(&f.Enabled)
+	}
+	type plain Flag
+	return value.Decode((*plain)(f))
+}
+
+// Flags holds the configured feature flags by name
+type Flags map[string]Flag
+
+func init() {
+	config.RegisterSchema("feature_flags", func() interface{} { return &Flags{} })
+}
+
+// Load reads the operator's feature flags. Unknown flags are reported like unknown configuration keys.
+func Load(log *libbuildpack.Logger) (Flags, error) {
+	flags := Flags{}
+	if err := config.DecodeOperator("feature_flags", &flags); err != nil {
+		return nil, err
+	}
+
+	if unknown := flags.Check(); len(unknown) > 0 {
+		if common.IsStrictConfig() {
+			return nil, &common.UnknownConfigKeysError{EnvVar: "JBP_DEFAULT_FEATURE_FLAGS", Fields: unknown}
+		}
+		log.Warning("Ignoring %s", strings.Join(unknown, "; "))
+	}
+	return flags, nil
+}
+
+// Check returns a message for every flag that is not known to this buildpack
+func (f Flags) Check() []string {
+	var unknown []string
+	for _, name := range f.names() {
+		if _, ok := descriptions[name]; !ok {
+			unknown = append(unknown, fmt.Sprintf("unknown feature flag %q", name))
+		}
+	}
+	return unknown
+}
+
+// Enabled returns true if the flag is enabled for the application's organization and space
+func (f Flags) Enabled(name string) bool {
+	flag, ok := f[name]
+	if !ok || !flag.Enabled {
+		return false
+	}
+
+	app := application()
+	return matches(flag.Organizations, app.OrganizationName) && matches(flag.Spaces, app.SpaceName)
+}
+
+// Log reports the state of every known flag, so that the staging log shows which behaviors were active
+func (f Flags) Log(log *libbuildpack.Logger) {
+	names := make([]string, 0, len(descriptions))
+	for name := range descriptions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		state := "disabled"
+		if f.Enabled(name) {
+			state = "enabled"
+		} else if f[name].Enabled {
+			state = "disabled for this organization or space"
+		}
+		log.Info("Feature flag %s: %s (%s)", name, state, descriptions[name])
+	}
+}
+
+// Enabled loads the feature flags and returns true if name is enabled. Configuration errors are reported by
+// Load during supply, so here they disable the flag.
+func Enabled(log *libbuildpack.Logger, name string) bool {
+	flags, err := Load(log)
+	return err == nil && flags.Enabled(name)
+}
+
+// names returns the configured flag names, sorted
+func (f Flags) names() []string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// matches returns true if allowed is empty or contains value
+func matches(allowed []string, value string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if a == value {
+			return true
+		}
+	}
+	return false
+}
+
+// vcapApplication holds the fields of VCAP_APPLICATION that flags can be limited to
+type vcapApplication struct {
+	OrganizationName string `json:"organization_name"`
+	SpaceName        string `json:"space_name"`
+}
+
+// application returns the organization and space from VCAP_APPLICATION
+func application() vcapApplication {
+	app := vcapApplication{}
+	_ = json.Unmarshal([]byte(os.Getenv("VCAP_APPLICATION")), &app)
+	return app
+}
@@ -0,0 +1,112 @@
+package features_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common/features"
+	"github.com/cloudfoundry/libbuildpack"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Feature flags", func() {
+	var (
+		logger       *libbuildpack.Logger
+		output       *bytes.Buffer
+		buildpackDir string
+	)
+
+	BeforeEach(func() {
+		var err error
+		buildpackDir, err = os.MkdirTemp("", "buildpack")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.MkdirAll(filepath.Join(buildpackDir, "config"), 0755)).To(Succeed())
+		os.Setenv("BUILDPACK_DIR", buildpackDir)
+
+		output = new(bytes.Buffer)
+		logger = libbuildpack.NewLogger(output)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(buildpackDir)
+		os.Unsetenv("BUILDPACK_DIR")
+		os.Unsetenv("JBP_CONFIG_FEATURE_FLAGS")
+		os.Unsetenv("JBP_DEFAULT_FEATURE_FLAGS")
+		os.Unsetenv("VCAP_APPLICATION")
+		os.Unsetenv("JBP_STRICT_CONFIG")
+	})
+
+	writeFlagsFile := func(content string) {
+		Expect(os.WriteFile(filepath.Join(buildpackDir, "config", "feature_flags.yml"), []byte(content), 0644)).To(Succeed())
+	}
+
+	It("disables every flag by default", func() {
+		Expect(features.Enabled(logger, features.AppCDS)).To(BeFalse())
+	})
+
+	It("reads the buildpack's flags file and lets the operator override it", func() {
+		writeFlagsFile("app_cds: true\n")
+		Expect(features.Enabled(logger, features.AppCDS)).To(BeTrue())
+
+		os.Setenv("JBP_DEFAULT_FEATURE_FLAGS", "{app_cds: false}")
+		Expect(features.Enabled(logger, features.AppCDS)).To(BeFalse())
+	})
+
+	It("ignores flags set by the application", func() {
+		os.Setenv("JBP_CONFIG_FEATURE_FLAGS", "{app_cds: true}")
+		Expect(features.Enabled(logger, features.AppCDS)).To(BeFalse())
+
+		writeFlagsFile("app_cds: true\n")
+		os.Setenv("JBP_CONFIG_FEATURE_FLAGS", "{app_cds: false}")
+		Expect(features.Enabled(logger, features.AppCDS)).To(BeTrue())
+	})
+
+	It("reads the flags file shipped with the buildpack", func() {
+		root, err := filepath.Abs("../../../..")
+		Expect(err).NotTo(HaveOccurred())
+		os.Setenv("BUILDPACK_DIR", root)
+
+		flags, err := features.Load(logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(flags).To(HaveKey(features.AppCDS))
+		Expect(flags.Enabled(features.AppCDS)).To(BeFalse())
+	})
+
+	It("limits a flag to organizations and spaces", func() {
+		writeFlagsFile("app_cds:\n  enabled: true\n  organizations: [platform]\n  spaces: [development, staging]\n")
+
+		os.Setenv("VCAP_APPLICATION", `{"organization_name": "platform", "space_name": "staging"}`)
+		Expect(features.Enabled(logger, features.AppCDS)).To(BeTrue())
+
+		os.Setenv("VCAP_APPLICATION", `{"organization_name": "platform", "space_name": "production"}`)
+		Expect(features.Enabled(logger, features.AppCDS)).To(BeFalse())
+
+		os.Setenv("VCAP_APPLICATION", `{"organization_name": "payments", "space_name": "staging"}`)
+		Expect(features.Enabled(logger, features.AppCDS)).To(BeFalse())
+	})
+
+	It("logs the state of every flag", func() {
+		writeFlagsFile("app_cds: {enabled: true, spaces: [development]}\n")
+		os.Setenv("VCAP_APPLICATION", `{"space_name": "production"}`)
+
+		flags, err := features.Load(logger)
+		Expect(err).NotTo(HaveOccurred())
+		flags.Log(logger)
+
+		Expect(output.String()).To(ContainSubstring("Feature flag app_cds: disabled for this organization or space"))
+	})
+
+	It("reports unknown flags", func() {
+		os.Setenv("JBP_DEFAULT_FEATURE_FLAGS", "{parallel_supply: true}")
+
+		_, err := features.Load(logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.String()).To(ContainSubstring(`unknown feature flag "parallel_supply"`))
+
+		os.Setenv("JBP_STRICT_CONFIG", "true")
+		_, err = features.Load(logger)
+		Expect(err).To(MatchError(ContainSubstring(`unknown feature flag "parallel_supply"`)))
+	})
+})
@@ -0,0 +1,105 @@
+package httpclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/libbuildpack"
+)
+
+// cache keeps verified downloads across staging runs. Entries are named after the SHA256 of the URL and the expected
+// checksum, so a changed checksum never returns stale content, and the least recently used entries are evicted
+// once the total size exceeds maxBytes.
+type cache struct {
+	dir      string
+	maxBytes int64
+}
+
+// path returns the cache entry of the download of url with the checksum expectedSHA256
+func (c *cache) path(url, expectedSHA256 string) string {
+	key := sha256.Sum256([]byte(url + "\n" + strings.ToLower(strings.TrimSpace(expectedSHA256))))
+	return filepath.Join(c.dir, hex.EncodeToString(key[:]))
+}
+
+// fetch copies the cache entry of url to destFile and returns true if there is one whose content still has the
+// checksum expectedSHA256. Corrupt entries are removed.
+func (c *cache) fetch(url, expectedSHA256, destFile string) bool {
+	entry := c.path(url, expectedSHA256)
+	actual, err := fileSHA256(entry)
+	if err != nil {
+		return false
+	}
+	if !strings.EqualFold(actual, strings.TrimSpace(expectedSHA256)) {
+		os.Remove(entry)
+		return false
+	}
+	if err := libbuildpack.CopyFile(entry, destFile); err != nil {
+		return false
+	}
+
+	now := time.Now()
+	os.Chtimes(entry, now, now)
+	return true
+}
+
+// store adds srcFile, downloaded from url with the checksum expectedSHA256, to the cache and evicts old entries
+func (c *cache) store(url, expectedSHA256, srcFile string) error {
+	info, err := os.Stat(srcFile)
+	if err != nil {
+		return err
+	}
+	if info.Size() > c.maxBytes {
+		return nil
+	}
+
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+	entry := c.path(url, expectedSHA256)
+	tmp := entry + ".tmp"
+	if err := libbuildpack.CopyFile(srcFile, tmp); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, entry); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return c.evict()
+}
+
+// evict removes the least recently used entries until the cache fits into maxBytes
+func (c *cache) evict() error {
+	dirEntries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+
+	var files []os.FileInfo
+	var total int64
+	for _, dirEntry := range dirEntries {
+		info, err := dirEntry.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, info)
+		total += info.Size()
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].ModTime().Before(files[j].ModTime()) })
+	for _, file := range files {
+		if total <= c.maxBytes {
+			break
+		}
+		if err := os.Remove(filepath.Join(c.dir, file.Name())); err != nil {
+			return err
+		}
+		total -= file.Size()
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common/interrupt"
+)
+
+// errTooLarge marks downloads that exceed max_download_size, which are not resumed
+var errTooLarge = errors.New("download too large")
+
+// SetProgressInterval sets how often the progress of a download is logged, 10 seconds by default
+func (c *Client) SetProgressInterval(interval time.Duration) {
+	c.progressInterval = interval
+}
+
+// Download writes the content of url to destFile. A download that breaks off, e.g. because the connection is reset,
+// is resumed where it stopped with a Range request, up to the configured number of retries. The request carries the
+// ETag or Last-Modified of the first response in If-Range, so that a server whose content changed meanwhile, or that
+// does not support Range requests, sends the whole content again; without either validator the download starts
+// over. Downloads larger than max_download_size fail before they fill the disk. On failure destFile is removed.
+func (c *Client) Download(url, destFile string) error {
+	out, err := os.Create(destFile)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if err := c.download(url, out); err != nil {
+		out.Close()
+		os.Remove(destFile)
+		return err
+	}
+	return out.Close()
+}
+
+// download writes the content of url to out, resuming it as Download describes
+func (c *Client) download(url string, out *os.File) error {
+	progress := &progress{client: c, url: url, total: -1, started: time.Now()}
+	interval := c.retryInterval
+	for resumes := 0; ; resumes++ {
+		offset := progress.written
+		if progress.validator == "" {
+			// The content cannot be told apart from a changed one, so it is not resumed
+			offset = 0
+		}
+		resp, err := c.get(url, offset, progress.validator)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusPartialContent {
+			progress.validator = validator(resp)
+		}
+
+		switch {
+		case resp.StatusCode != http.StatusPartialContent && progress.written > 0:
+			// The server ignored the Range request and sends the whole content again
+			err = progress.restart(out)
+		case resp.StatusCode == http.StatusPartialContent && !continues(resp, progress.written):
+			err = fmt.Errorf("unexpected Content-Range %q in response to a request from byte %d", resp.Header.Get("Content-Range"), progress.written)
+			if restartErr := progress.restart(out); restartErr != nil {
+				resp.Body.Close()
+				return restartErr
+			}
+		}
+		if err == nil && progress.total < 0 && resp.ContentLength >= 0 {
+			progress.total = progress.written + resp.ContentLength
+		}
+		if err == nil {
+			err = c.checkSize(url, progress.total)
+		}
+		if err == nil {
+			err = progress.copy(out, resp.Body)
+		}
+		resp.Body.Close()
+
+		if err == nil {
+			if progress.total >= 0 && progress.written < progress.total {
+				err = io.ErrUnexpectedEOF
+			} else {
+				progress.done()
+				return nil
+			}
+		}
+		if errors.Is(err, errTooLarge) || resumes >= c.retries {
+			return err
+		}
+
+		if c.log != nil {
+			c.log.Warning("Download of %s broke off after %s: %s; resuming in %s", redact(url), megabytes(progress.written), err.Error(), interval)
+		}
+		if sleepErr := interrupt.Sleep(interval); sleepErr != nil {
+			return err
+		}
+		interval *= 2
+	}
+}
+
+// validator returns the strong ETag of resp or, without one, its Last-Modified date, either of which identifies the
+// content in an If-Range header
+func validator(resp *http.Response) string {
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return resp.Header.Get("Last-Modified")
+}
+
+// continues returns true if resp is a 206 Partial Content response that continues the content from byte offset
+func continues(resp *http.Response, offset int64) bool {
+	return strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset))
+}
+
+// checkSize returns errTooLarge if size exceeds max_download_size
+func (c *Client) checkSize(url string, size int64) error {
+	if c.maxDownloadSize > 0 && size > c.maxDownloadSize {
+		return fmt.Errorf("%w: %s is larger than max_download_size of %s", errTooLarge, redact(url), megabytes(c.maxDownloadSize))
+	}
+	return nil
+}
+
+// progress counts the bytes of a download and logs them every progressInterval
+type progress struct {
+	client  *Client
+	url     string
+	written int64
+	total   int64
+	started time.Time
+	logged  time.Time
+	// validator is the If-Range value of the content being downloaded, see validator
+	validator string
+}
+
+// restart empties out for a download that starts over, whose size is announced anew
+func (p *progress) restart(out *os.File) error {
+	p.written = 0
+	p.total = -1
+	if err := out.Truncate(0); err != nil {
+		return err
+	}
+	_, err := out.Seek(0, io.SeekStart)
+	return err
+}
+
+// copy appends body to out, stopping as soon as the download exceeds max_download_size, even if its size was not
+// announced
+func (p *progress) copy(out io.Writer, body io.Reader) error {
+	buffer := make([]byte, 32*1024)
+	for {
+		n, err := body.Read(buffer)
+		if n > 0 {
+			if _, writeErr := out.Write(buffer[:n]); writeErr != nil {
+				return writeErr
+			}
+			p.written += int64(n)
+			if sizeErr := p.client.checkSize(p.url, p.written); sizeErr != nil {
+				return sizeErr
+			}
+			p.log()
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// log logs the progress if progressInterval passed since the download started or was last logged
+func (p *progress) log() {
+	last := p.logged
+	if last.IsZero() {
+		last = p.started
+	}
+	if p.client.log == nil || time.Since(last) < p.client.progressInterval {
+		return
+	}
+	p.logged = time.Now()
+
+	if p.total >= 0 {
+		p.client.log.Info("Downloaded %s of %s from %s", megabytes(p.written), megabytes(p.total), redact(p.url))
+		return
+	}
+	p.client.log.Info("Downloaded %s from %s", megabytes(p.written), redact(p.url))
+}
+
+// done logs the completed download if its progress was logged before
+func (p *progress) done() {
+	if p.client.log != nil && !p.logged.IsZero() {
+		p.client.log.Info("Downloaded %s from %s in %s", megabytes(p.written), redact(p.url), time.Since(p.started).Round(time.Second))
+	}
+}
+
+// megabytes formats a size in bytes in megabytes, the unit of max_download_size
+func megabytes(size int64) string {
+	return fmt.Sprintf("%.1f MB", float64(size)/(1024*1024))
+}
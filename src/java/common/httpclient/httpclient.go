@@ -0,0 +1,329 @@
+// Package httpclient provides the HTTP client used for every download the buildpack performs itself, as opposed to
+// the dependencies that libbuildpack.Installer installs from the manifest.
+//
+// The client honors the http_proxy, https_proxy and no_proxy environment variables, trusts an optional CA bundle in
+// addition to the system certificates, bounds connections and requests with timeouts, and retries requests that
+// fail with a network error or a 429 or 5xx status with exponential backoff. Downloads that break off are resumed
+// with Range requests, report their progress and are limited in size. DownloadVerified additionally checks
+// the SHA256 digest of the downloaded file and, once EnableCache was called, keeps verified downloads in the
+// buildpack cache so that later staging runs do not download them again.
+//
+// The supply phase loads the JBP_CONFIG_HTTP_CLIENT configuration and installs it with Configure;
+// components use the result through Default.
+package httpclient
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common/interrupt"
+	"github.com/cloudfoundry/libbuildpack"
+)
+
+// Config is the JBP_CONFIG_HTTP_CLIENT configuration, e.g. '{ca_bundle: certs/corp.pem, retries: 5}'
+type Config struct {
+	// ConnectTimeout bounds establishing a connection, in seconds
+	ConnectTimeout int `yaml:"connect_timeout"`
+	// Timeout bounds a whole request including reading the response body, in seconds
+	Timeout int `yaml:"timeout"`
+	// Retries is the number of times a failed request is repeated
+	Retries int `yaml:"retries"`
+	// CABundle is a PEM file with certificates trusted in addition to the system ones, relative to the application root
+	CABundle string `yaml:"ca_bundle"`
+	// SkipChecksumVerification stages downloads whose SHA256 digest does not match the expected one, with a warning
+	SkipChecksumVerification bool `yaml:"skip_checksum_verification"`
+	// CacheSize limits the size of the cache of verified downloads, in megabytes; 0 disables the cache
+	CacheSize int `yaml:"cache_size"`
+	// MaxDownloadSize limits the size of a single download, in megabytes; 0 does not limit it
+	MaxDownloadSize int `yaml:"max_download_size"`
+}
+
+// DefaultConfig returns the built-in client configuration
+func DefaultConfig() Config {
+	return Config{
+		ConnectTimeout:  30,
+		Timeout:         600,
+		Retries:         3,
+		CacheSize:       1024,
+		MaxDownloadSize: 2048,
+	}
+}
+
+// Client performs GET requests with the configured proxy, TLS, timeout and retry settings
+type Client struct {
+	http          *http.Client
+	tlsConfig     *tls.Config
+	retries       int
+	retryInterval time.Duration
+	skipChecksums bool
+	cacheSize     int64
+	cache         *cache
+	log           *libbuildpack.Logger
+
+	maxDownloadSize  int64
+	progressInterval time.Duration
+}
+
+var (
+	defaultMu     sync.Mutex
+	defaultClient *Client
+)
+
+// New creates a client from cfg
+func New(cfg Config) (*Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	transport.DialContext = (&net.Dialer{
+		Timeout:   time.Duration(cfg.ConnectTimeout) * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.CABundle != "" {
+		pool, err := certPool(cfg.CABundle)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return &Client{
+		http:          &http.Client{Transport: transport, Timeout: time.Duration(cfg.Timeout) * time.Second},
+		tlsConfig:     tlsConfig,
+		retries:       cfg.Retries,
+		retryInterval: time.Second,
+		skipChecksums: cfg.SkipChecksumVerification,
+		cacheSize:     int64(cfg.CacheSize) * 1024 * 1024,
+
+		maxDownloadSize:  int64(cfg.MaxDownloadSize) * 1024 * 1024,
+		progressInterval: 10 * time.Second,
+	}, nil
+}
+
+// Configure replaces the client returned by Default with one created from cfg
+func Configure(cfg Config) error {
+	client, err := New(cfg)
+	if err != nil {
+		return err
+	}
+
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultClient = client
+	return nil
+}
+
+// Default returns the client installed by Configure, or one with the built-in configuration
+func Default() *Client {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	if defaultClient == nil {
+		// The built-in configuration has no CA bundle, so creating the client cannot fail
+		defaultClient, _ = New(DefaultConfig())
+	}
+	return defaultClient
+}
+
+// SetRetryInterval sets the delay before the first retry, which doubles with every further retry
+func (c *Client) SetRetryInterval(interval time.Duration) {
+	c.retryInterval = interval
+}
+
+// SetLogger sets the logger that reports the progress of downloads, resumed downloads and checksum mismatches
+// accepted because of skip_checksum_verification
+func (c *Client) SetLogger(log *libbuildpack.Logger) {
+	c.log = log
+}
+
+// EnableCache keeps verified downloads in dir, usually in the buildpack's cache directory, up to the configured
+// cache_size
+func (c *Client) EnableCache(dir string) {
+	if c.cacheSize <= 0 {
+		c.cache = nil
+		return
+	}
+	c.cache = &cache{dir: dir, maxBytes: c.cacheSize}
+}
+
+// StatusError is returned for a response whose status is not the expected one
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("HTTP %d", e.StatusCode)
+}
+
+// IsNotFound returns true if err is a StatusError for a 404 Not Found response
+func IsNotFound(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound
+}
+
+// Get requests url and returns the response if its status is 200 OK.
+// The caller must close the response body.
+func (c *Client) Get(url string) (*http.Response, error) {
+	return c.get(url, 0, "")
+}
+
+// get requests url, from offset on with a Range request conditional on ifRange if offset is not 0, and returns the
+// response if its status is 200 OK or, for a Range request, 206 Partial Content. Requests that fail with a network error or a 429 or 5xx
+// status are retried.
+func (c *Client) get(url string, offset int64, ifRange string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(interrupt.Context(), http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", ifRange)
+	}
+
+	interval := c.retryInterval
+	for attempt := 0; ; attempt++ {
+		resp, err := c.http.Do(req)
+		if err == nil && (resp.StatusCode == http.StatusOK || (offset > 0 && resp.StatusCode == http.StatusPartialContent)) {
+			return resp, nil
+		}
+
+		retryable := err != nil
+		if err == nil {
+			resp.Body.Close()
+			err = &StatusError{StatusCode: resp.StatusCode}
+			retryable = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		}
+		if !retryable || attempt >= c.retries {
+			return nil, err
+		}
+
+		if sleepErr := interrupt.Sleep(interval); sleepErr != nil {
+			return nil, err
+		}
+		interval *= 2
+	}
+}
+
+// ContentLength returns the size of the content of url that the server announces in response to a HEAD request, or
+// -1 if it announces none. HEAD requests only inform estimates, so they are not retried.
+func (c *Client) ContentLength(url string) (int64, error) {
+	req, err := http.NewRequestWithContext(interrupt.Context(), http.MethodHead, url, nil)
+	if err != nil {
+		return -1, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return -1, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return -1, &StatusError{StatusCode: resp.StatusCode}
+	}
+	return resp.ContentLength, nil
+}
+
+// DownloadVerified writes the content of url to destFile and checks that its SHA256 digest is expectedSHA256.
+// On a mismatch destFile is removed and an error returned, unless the client skips checksum verification.
+// An empty expectedSHA256 downloads without verification. Verified downloads are served from and added to the
+// cache, if it is enabled; downloads without a checksum are never cached, as their content may change.
+func (c *Client) DownloadVerified(url, destFile, expectedSHA256 string) error {
+	if expectedSHA256 != "" && c.cache != nil && c.cache.fetch(url, expectedSHA256, destFile) {
+		if c.log != nil {
+			c.log.Debug("Using cached download of %s", redact(url))
+		}
+		return nil
+	}
+
+	if err := c.Download(url, destFile); err != nil {
+		return err
+	}
+	if expectedSHA256 == "" {
+		return nil
+	}
+
+	actual, err := fileSHA256(destFile)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(actual, strings.TrimSpace(expectedSHA256)) {
+		if c.cache != nil {
+			if err := c.cache.store(url, expectedSHA256, destFile); err != nil && c.log != nil {
+				c.log.Warning("Could not cache the download of %s: %s", redact(url), err.Error())
+			}
+		}
+		return nil
+	}
+
+	if c.skipChecksums {
+		if c.log != nil {
+			c.log.Warning("SHA256 of %s is %s instead of %s, staging it because skip_checksum_verification is set",
+				redact(url), actual, expectedSHA256)
+		}
+		return nil
+	}
+	os.Remove(destFile)
+	return fmt.Errorf("SHA256 of %s is %s instead of %s: the download is corrupt or was tampered with "+
+		"(set skip_checksum_verification: true in JBP_CONFIG_HTTP_CLIENT to stage it anyway)",
+		redact(url), actual, expectedSHA256)
+}
+
+// fileSHA256 returns the hex encoded SHA256 digest of the file at path
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// redact hides the credentials in rawURL, its password and the values of its query string, like common.RedactURI
+func redact(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	if parsed.RawQuery != "" {
+		query := parsed.Query()
+		for key := range query {
+			query[key] = []string{"xxxxx"}
+		}
+		parsed.RawQuery = query.Encode()
+	}
+	return parsed.Redacted()
+}
+
+// certPool returns the system certificates together with the certificates in the PEM file at path
+func certPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle %s: %w", path, err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in CA bundle %s", path)
+	}
+	return pool, nil
+}
@@ -0,0 +1,459 @@
+package httpclient_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common/httpclient"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/interrupt"
+	"github.com/cloudfoundry/libbuildpack"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client", func() {
+	var (
+		tmpDir   string
+		requests atomic.Int32
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "httpclient")
+		Expect(err).NotTo(HaveOccurred())
+		requests.Store(0)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	// newClient creates a client from the built-in configuration that retries without delay
+	newClient := func(cfg httpclient.Config) *httpclient.Client {
+		client, err := httpclient.New(cfg)
+		Expect(err).NotTo(HaveOccurred())
+		client.SetRetryInterval(time.Millisecond)
+		return client
+	}
+
+	// failingServer answers the first failures requests with status and then serves "content"
+	failingServer := func(failures int32, status int) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if requests.Add(1) <= failures {
+				w.WriteHeader(status)
+				return
+			}
+			w.Write([]byte("content"))
+		}))
+		DeferCleanup(server.Close)
+		return server
+	}
+
+	It("retries server errors", func() {
+		server := failingServer(2, http.StatusServiceUnavailable)
+
+		destFile := filepath.Join(tmpDir, "download")
+		Expect(newClient(httpclient.DefaultConfig()).Download(server.URL, destFile)).To(Succeed())
+		Expect(os.ReadFile(destFile)).To(Equal([]byte("content")))
+		Expect(requests.Load()).To(Equal(int32(3)))
+	})
+
+	It("gives up after the configured retries", func() {
+		server := failingServer(10, http.StatusTooManyRequests)
+
+		cfg := httpclient.DefaultConfig()
+		cfg.Retries = 1
+		_, err := newClient(cfg).Get(server.URL)
+		Expect(err).To(MatchError("HTTP 429"))
+		Expect(requests.Load()).To(Equal(int32(2)))
+	})
+
+	It("does not retry client errors", func() {
+		server := failingServer(10, http.StatusNotFound)
+
+		_, err := newClient(httpclient.DefaultConfig()).Get(server.URL)
+		Expect(err).To(MatchError("HTTP 404"))
+		Expect(requests.Load()).To(Equal(int32(1)))
+	})
+
+	It("trusts the certificates of the CA bundle", func() {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("content"))
+		}))
+		defer server.Close()
+
+		cfg := httpclient.DefaultConfig()
+		cfg.Retries = 0
+		_, err := newClient(cfg).Get(server.URL)
+		Expect(err).To(MatchError(ContainSubstring("certificate")))
+
+		bundle := filepath.Join(tmpDir, "ca.pem")
+		certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+		Expect(os.WriteFile(bundle, certificate, 0644)).To(Succeed())
+		cfg.CABundle = bundle
+		resp, err := newClient(cfg).Get(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+	})
+
+	It("rejects a CA bundle without certificates", func() {
+		bundle := filepath.Join(tmpDir, "ca.pem")
+		Expect(os.WriteFile(bundle, []byte("not a certificate"), 0644)).To(Succeed())
+
+		cfg := httpclient.DefaultConfig()
+		cfg.CABundle = bundle
+		_, err := httpclient.New(cfg)
+		Expect(err).To(MatchError(ContainSubstring("no certificates found in CA bundle")))
+	})
+
+	Describe("Probe", func() {
+		It("resolves and connects to a reachable server without sending a request", func() {
+			server := failingServer(0, http.StatusOK)
+
+			addresses, err := newClient(httpclient.DefaultConfig()).Probe(server.URL, time.Second)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(addresses).To(ContainElement("127.0.0.1"))
+			Expect(requests.Load()).To(BeZero())
+		})
+
+		It("completes a TLS handshake with servers trusted by the CA bundle", func() {
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			defer server.Close()
+
+			_, err := newClient(httpclient.DefaultConfig()).Probe(server.URL, time.Second)
+			Expect(err).To(MatchError(ContainSubstring("TLS handshake with " + server.Listener.Addr().String() + " failed")))
+
+			bundle := filepath.Join(tmpDir, "ca.pem")
+			certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+			Expect(os.WriteFile(bundle, certificate, 0644)).To(Succeed())
+			cfg := httpclient.DefaultConfig()
+			cfg.CABundle = bundle
+			_, err = newClient(cfg).Probe(server.URL, time.Second)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("reports a refused connection", func() {
+			server := failingServer(0, http.StatusOK)
+			server.Close()
+
+			_, err := newClient(httpclient.DefaultConfig()).Probe(server.URL, time.Second)
+			Expect(err).To(MatchError(ContainSubstring("connection to " + server.Listener.Addr().String() + " failed")))
+		})
+
+		It("reports a host name that does not resolve", func() {
+			_, err := newClient(httpclient.DefaultConfig()).Probe("https://collector.example.invalid", time.Second)
+			Expect(err).To(MatchError(ContainSubstring("DNS lookup of collector.example.invalid failed")))
+		})
+	})
+
+	Describe("ContentLength", func() {
+		It("returns the size the server announces", func() {
+			server := failingServer(0, http.StatusOK)
+
+			Expect(newClient(httpclient.DefaultConfig()).ContentLength(server.URL)).To(Equal(int64(len("content"))))
+		})
+
+		It("does not retry server errors", func() {
+			server := failingServer(1, http.StatusServiceUnavailable)
+
+			_, err := newClient(httpclient.DefaultConfig()).ContentLength(server.URL)
+			Expect(err).To(MatchError("HTTP 503"))
+			Expect(requests.Load()).To(Equal(int32(1)))
+		})
+	})
+
+	Describe("Download", func() {
+		content := []byte(strings.Repeat("0123456789", 1000))
+
+		var (
+			ranges   []string
+			ifRanges []string
+			etag     string
+			// changed is the content, and changedETag its ETag, served after the first response broke off
+			changed     []byte
+			changedETag string
+		)
+
+		// breakingServer breaks off the first response after half of content and answers Range requests with
+		// 206 Partial Content, unless rangesIgnored or their If-Range is not the ETag of the content
+		breakingServer := func(rangesIgnored bool) *httptest.Server {
+			ranges, ifRanges, etag, changed, changedETag = nil, nil, `"v1"`, content, `"v1"`
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ranges = append(ranges, r.Header.Get("Range"))
+				ifRanges = append(ifRanges, r.Header.Get("If-Range"))
+				if requests.Add(1) == 1 {
+					if etag != "" {
+						w.Header().Set("ETag", etag)
+					}
+					w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+					w.Write(content[:len(content)/2])
+					w.(http.Flusher).Flush()
+					panic(http.ErrAbortHandler)
+				}
+
+				if changedETag != "" {
+					w.Header().Set("ETag", changedETag)
+				}
+				var offset int
+				_, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-", &offset)
+				if err != nil || rangesIgnored || r.Header.Get("If-Range") != changedETag {
+					w.Write(changed)
+					return
+				}
+				w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, len(changed)-1, len(changed)))
+				w.WriteHeader(http.StatusPartialContent)
+				w.Write(changed[offset:])
+			}))
+			DeferCleanup(server.Close)
+			return server
+		}
+
+		It("resumes a download that broke off where it stopped", func() {
+			server := breakingServer(false)
+			buffer := new(bytes.Buffer)
+			client := newClient(httpclient.DefaultConfig())
+			client.SetLogger(libbuildpack.NewLogger(buffer))
+
+			destFile := filepath.Join(tmpDir, "download")
+			Expect(client.Download(server.URL, destFile)).To(Succeed())
+			Expect(os.ReadFile(destFile)).To(Equal(content))
+			Expect(ranges).To(Equal([]string{"", fmt.Sprintf("bytes=%d-", len(content)/2)}))
+			Expect(ifRanges).To(Equal([]string{"", `"v1"`}))
+			Expect(buffer.String()).To(ContainSubstring("Download of " + server.URL + " broke off after 0.0 MB: unexpected EOF; resuming in 1ms"))
+		})
+
+		It("starts over if the server ignores the Range request", func() {
+			server := breakingServer(true)
+
+			destFile := filepath.Join(tmpDir, "download")
+			Expect(newClient(httpclient.DefaultConfig()).Download(server.URL, destFile)).To(Succeed())
+			Expect(os.ReadFile(destFile)).To(Equal(content))
+		})
+
+		It("starts over if the content changed since the download broke off", func() {
+			server := breakingServer(false)
+			changed, changedETag = []byte(strings.Repeat("abcdefghij", 800)), `"v2"`
+
+			destFile := filepath.Join(tmpDir, "download")
+			Expect(newClient(httpclient.DefaultConfig()).Download(server.URL, destFile)).To(Succeed())
+			Expect(os.ReadFile(destFile)).To(Equal(changed))
+			Expect(ifRanges).To(Equal([]string{"", `"v1"`}))
+		})
+
+		It("starts over without a validator of the content", func() {
+			server := breakingServer(false)
+			etag, changedETag = "", ""
+
+			destFile := filepath.Join(tmpDir, "download")
+			Expect(newClient(httpclient.DefaultConfig()).Download(server.URL, destFile)).To(Succeed())
+			Expect(os.ReadFile(destFile)).To(Equal(content))
+			Expect(ranges).To(Equal([]string{"", ""}))
+		})
+
+		It("removes a download that keeps breaking off after the configured retries", func() {
+			server := breakingServer(false)
+
+			cfg := httpclient.DefaultConfig()
+			cfg.Retries = 0
+			destFile := filepath.Join(tmpDir, "download")
+			Expect(newClient(cfg).Download(server.URL, destFile)).To(MatchError(ContainSubstring("unexpected EOF")))
+			Expect(destFile).NotTo(BeAnExistingFile())
+		})
+
+		It("rejects a download larger than max_download_size", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				w.Write(bytes.Repeat([]byte{0}, 2*1024*1024))
+			}))
+			DeferCleanup(server.Close)
+
+			cfg := httpclient.DefaultConfig()
+			cfg.MaxDownloadSize = 1
+			destFile := filepath.Join(tmpDir, "download")
+			err := newClient(cfg).Download(server.URL, destFile)
+			Expect(err).To(MatchError(ContainSubstring(server.URL + " is larger than max_download_size of 1.0 MB")))
+			Expect(requests.Load()).To(Equal(int32(1)))
+			Expect(destFile).NotTo(BeAnExistingFile())
+		})
+
+		It("logs the progress of the download", func() {
+			server := failingServer(0, http.StatusOK)
+			buffer := new(bytes.Buffer)
+			client := newClient(httpclient.DefaultConfig())
+			client.SetLogger(libbuildpack.NewLogger(buffer))
+			client.SetProgressInterval(0)
+
+			Expect(client.Download(server.URL, filepath.Join(tmpDir, "download"))).To(Succeed())
+			Expect(buffer.String()).To(ContainSubstring("Downloaded 0.0 MB of 0.0 MB from " + server.URL))
+		})
+
+		It("stops when the bound context is canceled", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Length", "1024")
+				w.Write([]byte("partial"))
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+			}))
+			DeferCleanup(server.Close)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer interrupt.Bind(ctx)()
+			time.AfterFunc(50*time.Millisecond, cancel)
+
+			destFile := filepath.Join(tmpDir, "download")
+			Expect(newClient(httpclient.DefaultConfig()).Download(server.URL, destFile)).To(MatchError(context.Canceled))
+			Expect(destFile).NotTo(BeAnExistingFile())
+		})
+	})
+
+	Describe("DownloadVerified", func() {
+		// contentSHA256 is the SHA256 digest of "content"
+		const contentSHA256 = "ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73"
+		const otherSHA256 = "0000000000000000000000000000000000000000000000000000000000000000"
+
+		It("keeps a download with the expected digest", func() {
+			server := failingServer(0, http.StatusOK)
+
+			destFile := filepath.Join(tmpDir, "download")
+			Expect(newClient(httpclient.DefaultConfig()).DownloadVerified(server.URL, destFile, strings.ToUpper(contentSHA256))).To(Succeed())
+			Expect(os.ReadFile(destFile)).To(Equal([]byte("content")))
+		})
+
+		It("removes a download with a different digest", func() {
+			server := failingServer(0, http.StatusOK)
+
+			destFile := filepath.Join(tmpDir, "download")
+			err := newClient(httpclient.DefaultConfig()).DownloadVerified(server.URL, destFile, otherSHA256)
+			Expect(err).To(MatchError(ContainSubstring("SHA256 of " + server.URL + " is " + contentSHA256 + " instead of " + otherSHA256)))
+			Expect(destFile).NotTo(BeAnExistingFile())
+		})
+
+		It("stages a download with a different digest if verification is skipped", func() {
+			server := failingServer(0, http.StatusOK)
+			buffer := new(bytes.Buffer)
+
+			cfg := httpclient.DefaultConfig()
+			cfg.SkipChecksumVerification = true
+			client := newClient(cfg)
+			client.SetLogger(libbuildpack.NewLogger(buffer))
+
+			destFile := filepath.Join(tmpDir, "download")
+			Expect(client.DownloadVerified(server.URL, destFile, otherSHA256)).To(Succeed())
+			Expect(os.ReadFile(destFile)).To(Equal([]byte("content")))
+			Expect(buffer.String()).To(ContainSubstring("staging it because skip_checksum_verification is set"))
+		})
+
+		It("does not verify without an expected digest", func() {
+			server := failingServer(0, http.StatusOK)
+
+			destFile := filepath.Join(tmpDir, "download")
+			Expect(newClient(httpclient.DefaultConfig()).DownloadVerified(server.URL, destFile, "")).To(Succeed())
+			Expect(os.ReadFile(destFile)).To(Equal([]byte("content")))
+		})
+	})
+	Describe("download cache", func() {
+		var (
+			cacheDir string
+			content  map[string][]byte
+			server   *httptest.Server
+		)
+
+		BeforeEach(func() {
+			cacheDir = filepath.Join(tmpDir, "cache")
+			content = map[string][]byte{
+				"/small": []byte("content"),
+				"/large": bytes.Repeat([]byte("a"), 600*1024),
+				"/other": bytes.Repeat([]byte("b"), 600*1024),
+			}
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				w.Write(content[r.URL.Path])
+			}))
+			DeferCleanup(server.Close)
+		})
+
+		checksum := func(path string) string {
+			sum := sha256.Sum256(content[path])
+			return hex.EncodeToString(sum[:])
+		}
+
+		// download fetches path into a new file with a client whose cache holds cacheSize megabytes
+		download := func(cacheSize int, path, expectedSHA256 string) []byte {
+			cfg := httpclient.DefaultConfig()
+			cfg.CacheSize = cacheSize
+			client := newClient(cfg)
+			client.EnableCache(cacheDir)
+
+			destFile := filepath.Join(tmpDir, "download")
+			Expect(client.DownloadVerified(server.URL+path, destFile, expectedSHA256)).To(Succeed())
+			data, err := os.ReadFile(destFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.Remove(destFile)).To(Succeed())
+			return data
+		}
+
+		It("serves a verified download from the cache", func() {
+			Expect(download(1, "/small", checksum("/small"))).To(Equal([]byte("content")))
+			Expect(download(1, "/small", checksum("/small"))).To(Equal([]byte("content")))
+			Expect(requests.Load()).To(Equal(int32(1)))
+		})
+
+		It("downloads again when the checksum changes", func() {
+			download(1, "/small", checksum("/small"))
+			content["/small"] = []byte("changed")
+
+			Expect(download(1, "/small", checksum("/small"))).To(Equal([]byte("changed")))
+			Expect(requests.Load()).To(Equal(int32(2)))
+		})
+
+		It("does not cache downloads without a checksum", func() {
+			download(1, "/small", "")
+			download(1, "/small", "")
+			Expect(requests.Load()).To(Equal(int32(2)))
+		})
+
+		It("replaces a corrupt entry", func() {
+			download(1, "/small", checksum("/small"))
+			entries, err := os.ReadDir(cacheDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+			Expect(os.WriteFile(filepath.Join(cacheDir, entries[0].Name()), []byte("corrupt"), 0644)).To(Succeed())
+
+			Expect(download(1, "/small", checksum("/small"))).To(Equal([]byte("content")))
+			Expect(requests.Load()).To(Equal(int32(2)))
+		})
+
+		It("evicts the least recently used entries beyond the cache size", func() {
+			download(1, "/large", checksum("/large"))
+			download(1, "/other", checksum("/other"))
+
+			entries, err := os.ReadDir(cacheDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+
+			download(1, "/other", checksum("/other"))
+			Expect(requests.Load()).To(Equal(int32(2)))
+			download(1, "/large", checksum("/large"))
+			Expect(requests.Load()).To(Equal(int32(3)))
+		})
+
+		It("is disabled by a cache size of 0", func() {
+			download(0, "/small", checksum("/small"))
+			download(0, "/small", checksum("/small"))
+			Expect(requests.Load()).To(Equal(int32(2)))
+			Expect(cacheDir).NotTo(BeADirectory())
+		})
+	})
+})
@@ -0,0 +1,52 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+// Probe checks that the server of rawURL can be reached from staging: its host name resolves, a TCP connection to it
+// is established and, for https URLs, a TLS handshake trusting the CA bundle and the system certificates succeeds,
+// all within timeout. It returns the addresses the host name resolved to. No request is sent.
+func (c *Client) Probe(rawURL string, timeout time.Duration) ([]string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Hostname() == "" {
+		return nil, fmt.Errorf("invalid endpoint %s", redact(rawURL))
+	}
+
+	port := parsed.Port()
+	if port == "" {
+		port = "80"
+		if parsed.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	addresses, err := net.DefaultResolver.LookupHost(ctx, parsed.Hostname())
+	if err != nil {
+		return nil, fmt.Errorf("DNS lookup of %s failed: %w", parsed.Hostname(), err)
+	}
+
+	address := net.JoinHostPort(parsed.Hostname(), port)
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", address)
+	if err != nil {
+		return addresses, fmt.Errorf("connection to %s failed: %w", address, err)
+	}
+	defer conn.Close()
+
+	if parsed.Scheme == "https" {
+		tlsConfig := c.tlsConfig.Clone()
+		tlsConfig.ServerName = parsed.Hostname()
+		if err := tls.Client(conn, tlsConfig).HandshakeContext(ctx); err != nil {
+			return addresses, fmt.Errorf("TLS handshake with %s failed: %w", address, err)
+		}
+	}
+	return addresses, nil
+}
@@ -0,0 +1,364 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common/interrupt"
+	"github.com/cloudfoundry/libbuildpack"
+)
+
+// DependencyInstaller installs the dependencies listed in the manifest. It extends libbuildpack.Installer, whose
+// download, checksum verification and application cache it uses, with further archive formats, download fallbacks
+// and checks before and after each installation.
+type DependencyInstaller struct {
+	*libbuildpack.Installer
+	manifest     Manifest
+	rootDir      string
+	mirrors      []DependencyMirror
+	licenses     map[string][]License
+	deprecations []libbuildpack.DeprecationDate
+	offline      bool
+	failOnEOL    bool
+	recordFile   string
+	log          *libbuildpack.Logger
+
+	patchFallback bool
+	streamExtract bool
+	disk          *DiskGuard
+}
+
+// NewDependencyInstaller creates an installer for the dependencies listed in manifest
+func NewDependencyInstaller(manifest *libbuildpack.Manifest, logger *libbuildpack.Logger) (*DependencyInstaller, error) {
+	mirrors, err := LoadDependencyMirrors(manifest.RootDir())
+	if err != nil {
+		return nil, err
+	}
+	licenses, err := LoadDependencyLicenses(manifest.RootDir())
+	if err != nil {
+		return nil, err
+	}
+
+	return &DependencyInstaller{
+		Installer:    libbuildpack.NewInstaller(manifest),
+		manifest:     manifest,
+		rootDir:      manifest.RootDir(),
+		mirrors:      mirrors,
+		licenses:     licenses,
+		deprecations: manifest.Deprecations,
+		offline:      isOffline(manifest.RootDir()),
+		failOnEOL:    failOnEndOfLife(),
+		log:          logger,
+	}, nil
+}
+
+// FailOnEndOfLifeEnvVar fails staging ("true") when a dependency is past its date in dependency_deprecation_dates
+const FailOnEndOfLifeEnvVar = "JBP_FAIL_ON_EOL"
+
+// failOnEndOfLife returns true if JBP_FAIL_ON_EOL is true
+func failOnEndOfLife() bool {
+	fail, err := strconv.ParseBool(strings.TrimSpace(os.Getenv(FailOnEndOfLifeEnvVar)))
+	return err == nil && fail
+}
+
+// RecordInstallsTo records every dependency installed from now on in path, usually InstalledDependenciesFile in
+// the deps directory, from which WriteLicenseReport creates the license report
+func (i *DependencyInstaller) RecordInstallsTo(path string) {
+	i.recordFile = path
+}
+
+// InstallDependency installs dep into outputDir
+func (i *DependencyInstaller) InstallDependency(dep libbuildpack.Dependency, outputDir string) error {
+	return i.InstallDependencyWithStrip(dep, outputDir, 0)
+}
+
+// InstallDependencyWithStrip installs dep into outputDir, removing stripComponents leading path components.
+// If the dependency cannot be installed from its URI, the configured mirrors are tried in order; if the artifact is
+// not found and patch_fallback is enabled, the newest other patch version of the same line in the manifest is
+// installed instead.
+func (i *DependencyInstaller) InstallDependencyWithStrip(dep libbuildpack.Dependency, outputDir string, stripComponents int) error {
+	_, err := i.InstallDependencyVersion(dep, outputDir, stripComponents)
+	return err
+}
+
+// InstallDependencyVersion installs dep like InstallDependencyWithStrip and returns the dependency it installed,
+// which is another patch version than dep after a patch version fallback. Dependencies past their date in the
+// manifest's dependency_deprecation_dates fail if JBP_FAIL_ON_EOL is true, and installations are checked against the
+// disk space left for staging (see GuardDiskUsage). Installed dependencies are recorded, with their licenses, for
+// the license report (see RecordInstallsTo).
+func (i *DependencyInstaller) InstallDependencyVersion(dep libbuildpack.Dependency, outputDir string, stripComponents int) (libbuildpack.Dependency, error) {
+	entry, err := i.manifest.GetEntry(dep)
+	if err != nil {
+		return dep, err
+	}
+	if err := i.checkEndOfLife(dep, time.Now()); err != nil {
+		return dep, err
+	}
+	i.preflight(dep, entry)
+
+	err = i.installWithMirrors(dep, entry, outputDir, stripComponents)
+	if err != nil && i.patchFallback && entry.File == "" && !i.offline && i.artifactNotFound(entry.URI) {
+		dep, entry, err = i.installPatchFallback(dep, outputDir, stripComponents, err)
+	}
+	if err != nil {
+		return dep, err
+	}
+	return dep, i.record(dep, entry, outputDir)
+}
+
+// checkEndOfLife returns an error if JBP_FAIL_ON_EOL is true and dep is past the date of its version line in
+// dependency_deprecation_dates. libbuildpack itself only warns about these dates, from 30 days before them.
+func (i *DependencyInstaller) checkEndOfLife(dep libbuildpack.Dependency, now time.Time) error {
+	if !i.failOnEOL {
+		return nil
+	}
+	for _, deprecation := range i.deprecations {
+		if deprecation.Name != dep.Name {
+			continue
+		}
+		if _, err := libbuildpack.FindMatchingVersion(deprecation.VersionLine, []string{dep.Version}); err != nil {
+			continue
+		}
+		date, err := time.Parse(time.DateOnly, deprecation.Date)
+		if err != nil || !now.After(date) {
+			continue
+		}
+
+		see := ""
+		if deprecation.Link != "" {
+			see = "\nSee: " + deprecation.Link
+		}
+		return fmt.Errorf("%s %s reached its end of life on %s and no longer receives security fixes: select a "+
+			"supported version line, or unset %s to stage with it anyway%s", dep.Name, dep.Version, deprecation.Date,
+			FailOnEndOfLifeEnvVar, see)
+	}
+	return nil
+}
+
+// installWithMirrors installs dep from its URI or, failing that, from the mirrors declared in the manifest's
+// dependency_mirrors section. In offline mode (see IsOffline) only dependencies embedded in the buildpack are
+// installed.
+func (i *DependencyInstaller) installWithMirrors(dep libbuildpack.Dependency, entry *libbuildpack.ManifestEntry, outputDir string, stripComponents int) error {
+	if i.offline && entry.File == "" {
+		return OfflineError(fmt.Sprintf("%s %s", dep.Name, dep.Version), entry.URI,
+			fmt.Sprintf("package the buildpack with './scripts/package.sh --cached' without excluding %s, or set %s=false if the staging environment has network access", dep.Name, OfflineEnvVar))
+	}
+
+	err := i.install(dep, entry, outputDir, stripComponents)
+	if err == nil || entry.File != "" {
+		return err
+	}
+
+	uris := mirrorURIs(i.mirrors, entry.URI)
+	if len(uris) == 0 {
+		return err
+	}
+
+	i.log.Warning("Could not install %s %s from %s: %s", dep.Name, dep.Version, RedactURI(entry.URI), err.Error())
+	for _, uri := range uris {
+		i.log.Info("Trying mirror %s", RedactURI(uri))
+		mirrorErr := i.installFromMirror(entry, uri, outputDir, stripComponents)
+		if mirrorErr == nil {
+			return nil
+		}
+		i.log.Warning("Could not install %s %s from mirror %s: %s", dep.Name, dep.Version, RedactURI(uri), mirrorErr.Error())
+	}
+
+	return fmt.Errorf("%w (%d mirrors also failed)", err, len(uris))
+}
+
+// record adds dep to the record of installed dependencies, if RecordInstallsTo was called
+func (i *DependencyInstaller) record(dep libbuildpack.Dependency, entry *libbuildpack.ManifestEntry, outputDir string) error {
+	if i.recordFile == "" {
+		return nil
+	}
+
+	var dir string
+	if rel, ok := relativeTo(filepath.Dir(i.recordFile), outputDir); ok {
+		dir = filepath.ToSlash(rel)
+	}
+	return RecordInstalledDependency(i.recordFile, InstalledDependency{
+		Name:     dep.Name,
+		Version:  dep.Version,
+		URI:      RedactURI(entry.URI),
+		SHA256:   entry.SHA256,
+		Licenses: i.licenses[dep.Name],
+		Dir:      dir,
+	})
+}
+
+// install installs dep from its URI with libbuildpack. Tarballs are extracted while they are downloaded if
+// stream_extract is enabled (see installStreamed). Zstandard-compressed tarballs (.tar.zst, .tzst), which decompress
+// considerably faster than gzip, are extracted with the stack's zstd, and gzip-compressed ones with pigz if the stack
+// provides it (see ExtractTarGz). Other archive formats are installed by libbuildpack unchanged.
+func (i *DependencyInstaller) install(dep libbuildpack.Dependency, entry *libbuildpack.ManifestEntry, outputDir string, stripComponents int) error {
+	if i.streams(entry) {
+		return i.installStreamed(entry, entry.URI, outputDir, stripComponents)
+	}
+	if IsGzipArchive(entry.URI) && parallelGzip() {
+		return i.installGzip(dep, entry, outputDir, stripComponents)
+	}
+	if !IsZstdArchive(entry.URI) {
+		return i.Installer.InstallDependencyWithStrip(dep, outputDir, stripComponents)
+	}
+
+	// libbuildpack copies archives it cannot extract unchanged, after fetching them and verifying
+	// their checksum, so download, caching and version warnings stay in one place
+	tmpDir, err := os.MkdirTemp("", "zstd")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := i.Installer.InstallDependencyWithStrip(dep, tmpDir, 0); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return err
+	}
+	return ExtractTarZst(filepath.Join(tmpDir, filepath.Base(entry.URI)), outputDir, stripComponents)
+}
+
+// installGzip installs a gzip-compressed tarball like libbuildpack, from the buildpack, the application cache or its
+// URI with the manifest checksum verified, and extracts it with ExtractTarGz. libbuildpack only logs its version
+// warnings when it extracts the archive itself, so they are logged by warnVersion.
+func (i *DependencyInstaller) installGzip(dep libbuildpack.Dependency, entry *libbuildpack.ManifestEntry, outputDir string, stripComponents int) error {
+	tmpDir, err := os.MkdirTemp("", "downloads")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	archive := filepath.Join(tmpDir, filepath.Base(entry.URI))
+	if err := i.Installer.FetchDependency(dep, archive); err != nil {
+		return err
+	}
+	i.warnVersion(dep, time.Now())
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return err
+	}
+	return ExtractTarGz(archive, outputDir, stripComponents)
+}
+
+// warnVersion logs the warnings libbuildpack logs when it installs dep: that the manifest has a newer patch version
+// of dep, and that the version line of dep reaches its date in dependency_deprecation_dates within 30 days
+func (i *DependencyInstaller) warnVersion(dep libbuildpack.Dependency, now time.Time) {
+	if parts := strings.SplitN(dep.Version, ".", 3); len(parts) == 3 && !strings.Contains(dep.Version, "-") {
+		line := parts[0] + "." + parts[1] + ".x"
+		if (*i.Installer.GetVersionLine())[dep.Name] == "minor" {
+			line = parts[0] + ".x.x"
+		}
+		if latest, err := libbuildpack.FindMatchingVersion(line, i.manifest.AllDependencyVersions(dep.Name)); err == nil && latest != dep.Version {
+			i.log.Warning("A newer version of %s is available in this buildpack. Please adjust your app to use version %s "+
+				"instead of version %s as soon as possible. Old versions of %s are only provided to assist in migrating to newer versions.",
+				dep.Name, latest, dep.Version, dep.Name)
+		}
+	}
+
+	for _, deprecation := range i.deprecations {
+		if deprecation.Name != dep.Name {
+			continue
+		}
+		if _, err := libbuildpack.FindMatchingVersion(deprecation.VersionLine, []string{dep.Version}); err != nil {
+			continue
+		}
+		date, err := time.Parse(time.DateOnly, deprecation.Date)
+		if err != nil || date.Sub(now) >= 30*24*time.Hour {
+			continue
+		}
+
+		see := ""
+		if deprecation.Link != "" {
+			see = "\nSee: " + deprecation.Link
+		}
+		i.log.Warning("%s %s will no longer be available in new buildpacks released after %s.%s", dep.Name, deprecation.VersionLine, deprecation.Date, see)
+	}
+}
+
+// installFromMirror downloads the dependency from uri, verifies it against the manifest checksum and installs it
+func (i *DependencyInstaller) installFromMirror(entry *libbuildpack.ManifestEntry, uri, outputDir string, stripComponents int) error {
+	if i.streams(entry) {
+		return i.installStreamed(entry, uri, outputDir, stripComponents)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "mirror")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	archive := filepath.Join(tmpDir, filepath.Base(entry.URI))
+	if err := downloadFromMirror(uri, archive); err != nil {
+		return err
+	}
+	if err := libbuildpack.CheckSha256(archive, entry.SHA256); err != nil {
+		return err
+	}
+
+	return extractDependency(archive, entry.URI, outputDir, stripComponents)
+}
+
+// IsZstdArchive returns true if uri names a Zstandard-compressed tarball
+func IsZstdArchive(uri string) bool {
+	return strings.HasSuffix(uri, ".tar.zst") || strings.HasSuffix(uri, ".tzst")
+}
+
+// IsGzipArchive returns true if uri names a gzip-compressed tarball
+func IsGzipArchive(uri string) bool {
+	return strings.HasSuffix(uri, ".tar.gz") || strings.HasSuffix(uri, ".tgz")
+}
+
+// parallelGzip returns true if the stack provides pigz, which decompresses gzip while other threads read the
+// archive, write the output and verify its checksum
+func parallelGzip() bool {
+	_, err := exec.LookPath("pigz")
+	return err == nil
+}
+
+// ExtractTarGz extracts a .tar.gz archive to destDir with the stack's tar and pigz, optionally stripping leading
+// path components like tar's --strip-components flag. Stacks without pigz extract it with libbuildpack.
+func ExtractTarGz(archive, destDir string, stripComponents int) error {
+	if !parallelGzip() {
+		if stripComponents > 0 {
+			return libbuildpack.ExtractTarGzWithStrip(archive, destDir, stripComponents)
+		}
+		return libbuildpack.ExtractTarGz(archive, destDir)
+	}
+	return extractTar(archive, destDir, "pigz", stripComponents)
+}
+
+// ExtractTarZst extracts a .tar.zst archive to destDir with the stack's tar and zstd, optionally
+// stripping leading path components like tar's --strip-components flag
+func ExtractTarZst(archive, destDir string, stripComponents int) error {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		return fmt.Errorf("zstd is required to extract %s but was not found on the stack", filepath.Base(archive))
+	}
+	return extractTar(archive, destDir, "zstd", stripComponents)
+}
+
+// extractTar extracts archive to destDir with the stack's tar, decompressing it with program
+func extractTar(archive, destDir, program string, stripComponents int) error {
+	args := []string{
+		"--extract",
+		"--file", archive,
+		"--directory", destDir,
+		"--use-compress-program", program,
+		"--no-same-owner",
+	}
+	if stripComponents > 0 {
+		args = append(args, "--strip-components", strconv.Itoa(stripComponents))
+	}
+
+	output, err := interrupt.Command("tar", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w: %s", filepath.Base(archive), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
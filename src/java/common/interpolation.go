@@ -0,0 +1,106 @@
+package common
+
+import (
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/cloudfoundry/libbuildpack"
+)
+
+// interpolationPattern matches ${NAME} references and the $${ escape in configuration values
+var interpolationPattern = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// interpolatedKeys are the configuration keys whose values InterpolateConfig resolves. They name the locations that
+// differ between the environments an application is pushed to. Other values, such as java_opts, are left as they
+// are: ${HOME}, ${TMPDIR} or ${MEMORY_LIMIT} in them are expanded when the application starts and must not be
+// replaced by their values in the staging container.
+var interpolatedKeys = map[string]bool{
+	"repository_root":         true,
+	"default_repository_root": true,
+}
+
+// InterpolateConfig resolves ${NAME} references from the staging environment in the repository_root values of the
+// JBP_CONFIG_* and JBP_DEFAULT_* variables, e.g.
+// '{external_configuration: {repository_root: "https://${ARTIFACTORY_HOST}/java"}}', and updates the environment so
+// that components see the resolved value. It must run before MigrateLegacyConfig applies the operator defaults.
+//
+// References to variables that are not set at staging are kept, and $${NAME} is kept as a literal ${NAME}. $NAME
+// without braces is never interpolated. Variables whose value cannot be parsed are left as they are.
+func InterpolateConfig(log *libbuildpack.Logger) {
+	yamlHandler := YamlHandler{}
+
+	for _, prefix := range []string{"JBP_DEFAULT_", "JBP_CONFIG_"} {
+		for _, name := range sortedEnvNames(prefix) {
+			value := os.Getenv(name)
+			if !strings.Contains(value, "${") {
+				continue
+			}
+
+			data, err := NormalizeConfig([]byte(value))
+			if err != nil {
+				continue
+			}
+			var config map[string]interface{}
+			if err := yamlHandler.Unmarshal(data, &config); err != nil {
+				continue
+			}
+
+			if !interpolateLocations(log, name, config) {
+				continue
+			}
+			interpolated, err := yamlHandler.Marshal(config)
+			if err != nil {
+				log.Warning("Failed to interpolate environment variables in %s: %s", name, err.Error())
+				continue
+			}
+			os.Setenv(name, string(interpolated))
+			log.Debug("Interpolated environment variables in %s", name)
+		}
+	}
+}
+
+// interpolateLocations resolves the references in the values of interpolatedKeys in config and its nested mappings,
+// and returns true if any value changed
+func interpolateLocations(log *libbuildpack.Logger, name string, config map[string]interface{}) bool {
+	changed := false
+	for _, key := range sortedKeys(config) {
+		switch value := config[key].(type) {
+		case map[string]interface{}:
+			if interpolateLocations(log, name, value) {
+				changed = true
+			}
+		case string:
+			if !interpolatedKeys[key] {
+				continue
+			}
+			interpolated, unresolved := Interpolate(value, os.LookupEnv)
+			for _, ref := range unresolved {
+				log.Debug("%s references %s in %s which is not set at staging, leaving it as it is", name, ref, key)
+			}
+			if interpolated != value {
+				config[key] = interpolated
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// Interpolate replaces ${NAME} in value with the result of lookup and $${ with a literal ${.
+// References that lookup does not resolve are left in place and returned as unresolved.
+func Interpolate(value string, lookup func(string) (string, bool)) (string, []string) {
+	var unresolved []string
+	result := interpolationPattern.ReplaceAllStringFunc(value, func(match string) string {
+		if match == "$${" {
+			return "${"
+		}
+		name := match[2 : len(match)-1]
+		if resolved, ok := lookup(name); ok {
+			return resolved
+		}
+		unresolved = append(unresolved, name)
+		return match
+	})
+	return result, unresolved
+}
@@ -0,0 +1,99 @@
+package common_test
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/libbuildpack"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("InterpolateConfig", func() {
+	var (
+		buffer *bytes.Buffer
+		logger *libbuildpack.Logger
+	)
+
+	BeforeEach(func() {
+		buffer = new(bytes.Buffer)
+		logger = libbuildpack.NewLogger(buffer)
+		os.Setenv("BP_DEBUG", "true")
+	})
+
+	AfterEach(func() {
+		for _, name := range []string{
+			"BP_DEBUG", "ARTIFACTORY_HOST", "CF_INSTANCE_INDEX", "JBP_CONFIG_TOMCAT", "JBP_DEFAULT_TOMCAT",
+			"JBP_CONFIG_JAVA_MAIN", "JBP_CONFIG_JAVA_OPTS",
+		} {
+			os.Unsetenv(name)
+		}
+	})
+
+	It("resolves references in repository_root from the staging environment", func() {
+		os.Setenv("ARTIFACTORY_HOST", "artifactory.example.com")
+		os.Setenv("JBP_CONFIG_TOMCAT", `{external_configuration: {repository_root: "https://${ARTIFACTORY_HOST}/java"}}`)
+
+		common.InterpolateConfig(logger)
+		Expect(os.Getenv("JBP_CONFIG_TOMCAT")).To(MatchYAML(`{external_configuration: {repository_root: "https://artifactory.example.com/java"}}`))
+	})
+
+	It("resolves references in operator defaults before they are applied", func() {
+		os.Setenv("ARTIFACTORY_HOST", "artifactory.example.com")
+		os.Setenv("JBP_DEFAULT_TOMCAT", `{external_configuration: {repository_root: "https://${ARTIFACTORY_HOST}"}}`)
+
+		common.InterpolateConfig(logger)
+		common.MigrateLegacyConfig(logger)
+		Expect(os.Getenv("JBP_CONFIG_TOMCAT")).To(MatchYAML(`{external_configuration: {repository_root: "https://artifactory.example.com"}}`))
+	})
+
+	It("leaves references in Java options for the application to expand at runtime", func() {
+		os.Setenv("JBP_CONFIG_JAVA_OPTS", `{java_opts: "-Djava.io.tmpdir=${TMPDIR} -Dinstance=${CF_INSTANCE_INDEX}"}`)
+		os.Setenv("CF_INSTANCE_INDEX", "0")
+
+		common.InterpolateConfig(logger)
+		Expect(os.Getenv("JBP_CONFIG_JAVA_OPTS")).To(Equal(`{java_opts: "-Djava.io.tmpdir=${TMPDIR} -Dinstance=${CF_INSTANCE_INDEX}"}`))
+	})
+
+	It("leaves references in values other than locations as they are", func() {
+		os.Setenv("ARTIFACTORY_HOST", "artifactory.example.com")
+		os.Setenv("JBP_CONFIG_JAVA_MAIN", `{arguments: "--host=${ARTIFACTORY_HOST}"}`)
+
+		common.InterpolateConfig(logger)
+		Expect(os.Getenv("JBP_CONFIG_JAVA_MAIN")).To(Equal(`{arguments: "--host=${ARTIFACTORY_HOST}"}`))
+	})
+
+	It("keeps references that are not set at staging", func() {
+		os.Setenv("JBP_CONFIG_TOMCAT", `{external_configuration: {repository_root: "https://${ARTIFACTORY_HOST}/java"}}`)
+
+		common.InterpolateConfig(logger)
+		Expect(os.Getenv("JBP_CONFIG_TOMCAT")).To(Equal(`{external_configuration: {repository_root: "https://${ARTIFACTORY_HOST}/java"}}`))
+		Expect(buffer.String()).To(ContainSubstring("JBP_CONFIG_TOMCAT references ARTIFACTORY_HOST in repository_root which is not set at staging"))
+	})
+})
+
+var _ = Describe("Interpolate", func() {
+	lookup := func(name string) (string, bool) {
+		value, ok := map[string]string{"HOST": "example.com", "EMPTY": ""}[name]
+		return value, ok
+	}
+
+	It("replaces set variables, including empty ones", func() {
+		value, unresolved := common.Interpolate("https://${HOST}/${EMPTY}java", lookup)
+		Expect(value).To(Equal("https://example.com/java"))
+		Expect(unresolved).To(BeEmpty())
+	})
+
+	It("keeps escaped references as literals", func() {
+		value, unresolved := common.Interpolate("$${HOST} ${HOST}", lookup)
+		Expect(value).To(Equal("${HOST} example.com"))
+		Expect(unresolved).To(BeEmpty())
+	})
+
+	It("reports unresolved references", func() {
+		value, unresolved := common.Interpolate("${HOST}:${PORT}", lookup)
+		Expect(value).To(Equal("example.com:${PORT}"))
+		Expect(unresolved).To(ConsistOf("PORT"))
+	})
+})
@@ -0,0 +1,84 @@
+// Package interrupt stops the downloads and commands of a staging step that is abandoned, such as the installation
+// of a framework that exceeds the timeout of framework_supply.
+//
+// The supply phase binds the context of the step with Bind. Until it is unbound, the requests of the HTTP client,
+// the downloads libbuildpack sends through http.DefaultClient, the commands created with Command and the delays
+// before retries end as soon as the context is canceled, so that the step returns instead of running on in the
+// background.
+package interrupt
+
+import (
+	"context"
+	"net/http"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+var (
+	mu    sync.Mutex
+	bound = context.Background()
+
+	installTransport sync.Once
+)
+
+// Bind makes ctx the context of the following downloads and commands. The returned function restores the context
+// bound before.
+func Bind(ctx context.Context) func() {
+	installTransport.Do(func() {
+		base := http.DefaultClient.Transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		http.DefaultClient.Transport = &transport{base: base}
+	})
+
+	mu.Lock()
+	previous := bound
+	bound = ctx
+	mu.Unlock()
+
+	return func() {
+		mu.Lock()
+		bound = previous
+		mu.Unlock()
+	}
+}
+
+// Context returns the bound context, or context.Background if none is bound
+func Context() context.Context {
+	mu.Lock()
+	defer mu.Unlock()
+	return bound
+}
+
+// Command returns an exec.Cmd like exec.Command that is killed when the bound context is canceled
+func Command(name string, args ...string) *exec.Cmd {
+	return exec.CommandContext(Context(), name, args...)
+}
+
+// Sleep waits for d and returns nil, or the error of the bound context as soon as it is canceled
+func Sleep(d time.Duration) error {
+	ctx := Context()
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// transport sends the requests of http.DefaultClient, which carry no context of their own, with the bound context
+type transport struct {
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Context() == context.Background() {
+		req = req.WithContext(Context())
+	}
+	return t.base.RoundTrip(req)
+}
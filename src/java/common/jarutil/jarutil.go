@@ -0,0 +1,114 @@
+// Package jarutil reads JAR manifests so containers and frameworks can detect applications by
+// their declared attributes rather than by file names.
+package jarutil
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ManifestPath is the location of the manifest inside a JAR or an exploded JAR
+const ManifestPath = "META-INF/MANIFEST.MF"
+
+// Manifest holds the main attributes of a JAR manifest. Attribute names are case-insensitive.
+type Manifest map[string]string
+
+// Get returns the value of the named main attribute, or "" if the manifest does not declare it
+func (m Manifest) Get(name string) string {
+	return m[strings.ToLower(name)]
+}
+
+// Has returns true if the manifest declares the named main attribute
+func (m Manifest) Has(name string) bool {
+	_, ok := m[strings.ToLower(name)]
+	return ok
+}
+
+// ParseManifest parses the main section of MANIFEST.MF content following the JAR specification:
+// lines end with CR, LF or CRLF, a line starting with a single space continues the previous one and
+// the main section ends at the first blank line (per-entry sections that follow are ignored).
+func ParseManifest(content []byte) Manifest {
+	text := strings.ReplaceAll(string(content), "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, " ") && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	manifest := Manifest{}
+	for _, line := range lines {
+		name, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		manifest[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(value)
+	}
+	return manifest
+}
+
+// ReadManifest reads META-INF/MANIFEST.MF from the JAR at jarPath.
+// A JAR without a manifest yields an empty Manifest.
+func ReadManifest(jarPath string) (Manifest, error) {
+	r, err := zip.OpenReader(jarPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", jarPath, err)
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		if f.Name != ManifestPath {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s in %s: %w", ManifestPath, jarPath, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s in %s: %w", ManifestPath, jarPath, err)
+		}
+
+		return ParseManifest(data), nil
+	}
+
+	return Manifest{}, nil
+}
+
+// FindEntry returns the name of the first entry of the JAR at jarPath for which match returns true,
+// or "" if there is none
+func FindEntry(jarPath string, match func(name string) bool) (string, error) {
+	r, err := zip.OpenReader(jarPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", jarPath, err)
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		if match(f.Name) {
+			return f.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// ReadManifestFile reads a MANIFEST.MF file from disk, e.g. from an exploded JAR
+func ReadManifestFile(path string) (Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseManifest(data), nil
+}
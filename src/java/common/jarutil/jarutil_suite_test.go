@@ -0,0 +1,13 @@
+package jarutil_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestJarutil(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Jarutil Suite")
+}
@@ -0,0 +1,144 @@
+package jarutil_test
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common/jarutil"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func writeJar(path string, files map[string]string) {
+	f, err := os.Create(path)
+	Expect(err).NotTo(HaveOccurred())
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	for name, content := range files {
+		entry, err := w.Create(name)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = entry.Write([]byte(content))
+		Expect(err).NotTo(HaveOccurred())
+	}
+	Expect(w.Close()).To(Succeed())
+}
+
+var _ = Describe("Jarutil", func() {
+	Describe("ParseManifest", func() {
+		It("reads main attributes", func() {
+			manifest := jarutil.ParseManifest([]byte("Manifest-Version: 1.0\nMain-Class: com.example.Main\n"))
+			Expect(manifest.Get("Manifest-Version")).To(Equal("1.0"))
+			Expect(manifest.Get("Main-Class")).To(Equal("com.example.Main"))
+		})
+
+		It("matches attribute names case-insensitively", func() {
+			manifest := jarutil.ParseManifest([]byte("start-class: com.example.App\n"))
+			Expect(manifest.Has("Start-Class")).To(BeTrue())
+			Expect(manifest.Get("START-CLASS")).To(Equal("com.example.App"))
+		})
+
+		It("joins continuation lines", func() {
+			manifest := jarutil.ParseManifest([]byte("Main-Class: com.example.ve\r\n ry.long.Main\r\nCreated-By: test\r\n"))
+			Expect(manifest.Get("Main-Class")).To(Equal("com.example.very.long.Main"))
+			Expect(manifest.Get("Created-By")).To(Equal("test"))
+		})
+
+		It("accepts CR line endings", func() {
+			manifest := jarutil.ParseManifest([]byte("Main-Class: com.example.Main\rCreated-By: test\r"))
+			Expect(manifest.Get("Main-Class")).To(Equal("com.example.Main"))
+			Expect(manifest.Get("Created-By")).To(Equal("test"))
+		})
+
+		It("ignores per-entry sections", func() {
+			manifest := jarutil.ParseManifest([]byte("Manifest-Version: 1.0\n\nName: com/example/\nStart-Class: com.example.App\n"))
+			Expect(manifest.Has("Start-Class")).To(BeFalse())
+			Expect(manifest.Has("Name")).To(BeFalse())
+		})
+
+		It("returns an empty value for missing attributes", func() {
+			manifest := jarutil.ParseManifest([]byte("Manifest-Version: 1.0\n"))
+			Expect(manifest.Has("Main-Class")).To(BeFalse())
+			Expect(manifest.Get("Main-Class")).To(BeEmpty())
+		})
+	})
+
+	Describe("ReadManifest", func() {
+		var tmpDir string
+
+		BeforeEach(func() {
+			var err error
+			tmpDir, err = os.MkdirTemp("", "jarutil")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(tmpDir)
+		})
+
+		It("reads the manifest from a JAR", func() {
+			jarPath := filepath.Join(tmpDir, "app.jar")
+			writeJar(jarPath, map[string]string{
+				"com/example/App.class": "",
+				"META-INF/MANIFEST.MF":  "Manifest-Version: 1.0\nSpring-Boot-Version: 3.2.0\n",
+			})
+
+			manifest, err := jarutil.ReadManifest(jarPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(manifest.Get("Spring-Boot-Version")).To(Equal("3.2.0"))
+		})
+
+		It("returns an empty manifest for a JAR without one", func() {
+			jarPath := filepath.Join(tmpDir, "lib.jar")
+			writeJar(jarPath, map[string]string{"com/example/Lib.class": ""})
+
+			manifest, err := jarutil.ReadManifest(jarPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(manifest).To(BeEmpty())
+		})
+
+		It("returns an error for a file that is not a JAR", func() {
+			jarPath := filepath.Join(tmpDir, "broken.jar")
+			Expect(os.WriteFile(jarPath, []byte("not a zip"), 0644)).To(Succeed())
+
+			_, err := jarutil.ReadManifest(jarPath)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("FindEntry", func() {
+		It("returns the first matching entry", func() {
+			tmpDir, err := os.MkdirTemp("", "jarutil")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(tmpDir)
+
+			jarPath := filepath.Join(tmpDir, "app.jar")
+			writeJar(jarPath, map[string]string{"BOOT-INF/lib/spring-web-6.1.0.jar": ""})
+
+			entry, err := jarutil.FindEntry(jarPath, func(name string) bool { return strings.HasSuffix(name, ".jar") })
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entry).To(Equal("BOOT-INF/lib/spring-web-6.1.0.jar"))
+
+			entry, err = jarutil.FindEntry(jarPath, func(name string) bool { return strings.HasSuffix(name, ".class") })
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entry).To(BeEmpty())
+		})
+	})
+
+	Describe("ReadManifestFile", func() {
+		It("reads an exploded manifest", func() {
+			tmpDir, err := os.MkdirTemp("", "jarutil")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(tmpDir)
+
+			path := filepath.Join(tmpDir, "MANIFEST.MF")
+			Expect(os.WriteFile(path, []byte("Main-Class: com.example.Main\n"), 0644)).To(Succeed())
+
+			manifest, err := jarutil.ReadManifestFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(manifest.Get("Main-Class")).To(Equal("com.example.Main"))
+		})
+	})
+})
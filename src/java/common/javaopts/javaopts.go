@@ -0,0 +1,412 @@
+// Package javaopts collects the JAVA_OPTS that the JRE, the container and the frameworks contribute and renders the
+// profile.d script that assembles them when the application starts.
+//
+// Every contributor writes its options to its own .opts file in the java_opts directory of the deps directory,
+// named after its priority and name, e.g. 35_new_relic.opts. Supply and finalize run in separate processes, so the
+// files are the record of the contributions. Render orders the contributions by priority and then by name, removes
+// duplicate flags and writes profile.d/00_java_opts.sh, which lists the files in that order.
+package javaopts
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cloudfoundry/libbuildpack"
+)
+
+// Dir is the directory of the deps directory that holds the .opts files
+const Dir = "java_opts"
+
+// ScriptName is the profile.d script that assembles JAVA_OPTS. It sorts before the scripts of the frameworks, except
+// 00_cgroup.sh and 00_dirs_writable.sh, which export CONTAINER_CPU_COUNT and the runtime-writable directories for it.
+const ScriptName = "00_java_opts.sh"
+
+// Priorities of the contributors. Lower priorities come first in JAVA_OPTS; the frameworks follow the order of the
+// Ruby buildpack's config/components.yml, so that, e.g., the Container Security Provider precedes the JRebel agent.
+// Contributions of the same priority are ordered by name.
+const (
+	JRE                       = 5
+	AppCDS                    = 7
+	AppDynamics               = 11
+	AspectJWeaver             = 12
+	AzureApplicationInsights  = 13
+	CheckmarxIAST             = 14
+	PlatformCertificates      = 16
+	ContainerSecurityProvider = 17
+	ContrastSecurity          = 18
+	DatadogJavaagent          = 19
+	ElasticAPM                = 19
+	Debug                     = 20
+	GoogleStackdriverProfiler = 22
+	JaCoCo                    = 26
+	Introscope                = 27
+	JavaMemoryAssistant       = 28
+	JMX                       = 29
+	JProfiler                 = 30
+	JRebel                    = 31
+	LunaSecurityProvider      = 32
+	NewRelic                  = 35
+	OpenTelemetry             = 36
+	RiverbedAppInternals      = 37
+	ProtectAppSecurity        = 38
+	Sealights                 = 39
+	SeekerSecurityProvider    = 40
+	SkyWalking                = 41
+	SplunkOtel                = 42
+	CfMetricsExporter         = 43
+	ServiceMappings           = 43
+	YourKit                   = 45
+	MetricsForwarder          = 47
+	// User is the priority of the application's own JAVA_OPTS, which override those of every other contributor
+	User = 99
+)
+
+// Contribution is the JAVA_OPTS of one contributor
+type Contribution struct {
+	Priority int
+	// Name identifies the contributor, e.g. new_relic. BPL_<NAME>_ENABLED=false drops its options at runtime.
+	Name string
+	// Opts may reference environment variables, e.g. $DEPS_DIR, that are expanded when the application starts
+	Opts string
+}
+
+// FileName returns the name of the .opts file of the contribution, e.g. 35_new_relic.opts
+func (c Contribution) FileName() string {
+	return fmt.Sprintf("%02d_%s.opts", c.Priority, c.Name)
+}
+
+// Write writes the contribution to its .opts file in depDir, replacing the earlier options of the contributor
+func Write(log *libbuildpack.Logger, depDir string, c Contribution) error {
+	return write(log, depDir, c, false)
+}
+
+// Append adds the options of the contribution to those the contributor already wrote, for contributors that
+// are written in several steps, e.g. the JRE and its components
+func Append(log *libbuildpack.Logger, depDir string, c Contribution) error {
+	return write(log, depDir, c, true)
+}
+
+func write(log *libbuildpack.Logger, depDir string, c Contribution, appendOpts bool) error {
+	optsDir := filepath.Join(depDir, Dir)
+	if err := os.MkdirAll(optsDir, 0755); err != nil {
+		return fmt.Errorf("failed to create java_opts directory: %w", err)
+	}
+
+	filename := c.FileName()
+	opts := c.Opts
+	if appendOpts {
+		if existing, err := os.ReadFile(filepath.Join(optsDir, filename)); err == nil {
+			opts = strings.TrimSpace(string(existing)) + " " + opts
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(optsDir, filename), []byte(opts), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+
+	log.Debug("Wrote JAVA_OPTS to %s (priority %d)", filename, c.Priority)
+	return nil
+}
+
+// Read returns the contributions written to depDir, ordered by priority and then by name
+func Read(depDir string) ([]Contribution, error) {
+	files, err := filepath.Glob(filepath.Join(depDir, Dir, "*.opts"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list java_opts files: %w", err)
+	}
+
+	var contributions []Contribution
+	for _, file := range files {
+		prefix, name, ok := strings.Cut(strings.TrimSuffix(filepath.Base(file), ".opts"), "_")
+		priority, err := strconv.Atoi(prefix)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("java_opts file %s is not named <priority>_<name>.opts", filepath.Base(file))
+		}
+		opts, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(file), err)
+		}
+		contributions = append(contributions, Contribution{Priority: priority, Name: name, Opts: string(opts)})
+	}
+
+	sort.SliceStable(contributions, func(i, j int) bool {
+		if contributions[i].Priority != contributions[j].Priority {
+			return contributions[i].Priority < contributions[j].Priority
+		}
+		return contributions[i].Name < contributions[j].Name
+	})
+	return contributions, nil
+}
+
+// Duplicate is a flag that a contribution repeats
+type Duplicate struct {
+	Flag string
+	// Contribution repeats the flag of Original
+	Contribution, Original string
+}
+
+// Conflict is a system property or -XX option that a later contribution sets to another value. The JVM uses the
+// last value, so the conflict is reported but both flags are kept.
+type Conflict struct {
+	Key string
+	// Value and Contribution override the Overridden value of Original
+	Value, Contribution  string
+	Overridden, Original string
+}
+
+// Dedup returns the contributions, in their order, without the flags that an earlier contribution or an earlier
+// flag of the same contribution already passes with the same value, and the system properties and -XX options that
+// later contributions set to another value. Only whitespace-separated flags are compared: contributions with quoted
+// values containing whitespace, command substitutions or escapes are left as they are. Options such as --add-opens
+// are compared together with the argument that follows them.
+//
+// A duplicate flag is kept by the first contribution only, so disabling that contributor at runtime with
+// BPL_<NAME>_ENABLED also drops it for the later ones.
+func Dedup(contributions []Contribution) ([]Contribution, []Duplicate, []Conflict) {
+	type origin struct{ flag, contribution string }
+	seen := map[string]string{}
+	values := map[string]origin{}
+
+	var duplicates []Duplicate
+	var conflicts []Conflict
+	deduped := make([]Contribution, 0, len(contributions))
+	for _, c := range contributions {
+		name := c.FileName()
+		if !comparable(c.Opts) {
+			deduped = append(deduped, c)
+			continue
+		}
+
+		flags := splitFlags(c.Opts)
+		var kept []string
+		for _, flag := range flags {
+			if original, ok := seen[flag]; ok {
+				duplicates = append(duplicates, Duplicate{Flag: flag, Contribution: name, Original: original})
+				continue
+			}
+			seen[flag] = name
+			kept = append(kept, flag)
+
+			key := optionKey(flag)
+			if key == "" {
+				continue
+			}
+			if previous, ok := values[key]; ok && previous.flag != flag {
+				conflicts = append(conflicts, Conflict{Key: key, Value: flag, Contribution: name,
+					Overridden: previous.flag, Original: previous.contribution})
+			}
+			values[key] = origin{flag: flag, contribution: name}
+		}
+
+		if len(kept) < len(flags) {
+			c.Opts = strings.Join(kept, " ")
+		}
+		deduped = append(deduped, c)
+	}
+	return deduped, duplicates, conflicts
+}
+
+// separateArgumentFlags are the options that the JVM reads with their argument in the next word, e.g.
+// --add-opens java.base/java.lang=ALL-UNNAMED
+var separateArgumentFlags = map[string]bool{
+	"--add-exports":  true,
+	"--add-modules":  true,
+	"--add-opens":    true,
+	"--add-reads":    true,
+	"--patch-module": true,
+}
+
+// splitFlags splits opts at whitespace into flags, keeping the options of separateArgumentFlags together with their
+// argument, so that an argument is never removed without its option or vice versa
+func splitFlags(opts string) []string {
+	var flags []string
+	words := strings.Fields(opts)
+	for i := 0; i < len(words); i++ {
+		if separateArgumentFlags[words[i]] && i+1 < len(words) {
+			flags = append(flags, words[i]+" "+words[i+1])
+			i++
+			continue
+		}
+		flags = append(flags, words[i])
+	}
+	return flags
+}
+
+// comparable returns true if splitting opts at whitespace yields the flags that the shell passes to the JVM
+func comparable(opts string) bool {
+	if strings.ContainsAny(opts, "\\`") || strings.Contains(opts, "$(") {
+		return false
+	}
+	for _, flag := range strings.Fields(opts) {
+		if strings.Count(flag, `"`)%2 != 0 || strings.Count(flag, "'")%2 != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// optionKey returns the name that a system property or -XX option sets, e.g. -Dfoo for -Dfoo=bar and -XX:Foo for
+// -XX:+Foo, or "" for other flags
+func optionKey(flag string) string {
+	switch {
+	case strings.HasPrefix(flag, "-D"):
+		key, _, _ := strings.Cut(flag, "=")
+		return key
+	case strings.HasPrefix(flag, "-XX:"):
+		key, _, _ := strings.Cut(strings.TrimLeft(strings.TrimPrefix(flag, "-XX:"), "+-"), "=")
+		return "-XX:" + key
+	}
+	return ""
+}
+
+// Agent is a Java or native agent that a contribution attaches to the JVM with -javaagent, -agentpath or -agentlib
+type Agent struct {
+	// Contribution is the name of the contribution that attaches the agent, e.g. new_relic
+	Contribution string
+	Priority     int
+	// Type is the flag that attaches the agent: javaagent, agentpath or agentlib
+	Type string
+	// Path is the JAR or library of the agent as passed to the JVM, e.g. $DEPS_DIR/0/new_relic_agent/newrelic.jar,
+	// or the name of the library for agentlib
+	Path string
+	// Options are the options passed to the agent after the =, if any
+	Options string
+}
+
+// agentFlags are the prefixes of the flags that attach agents, and the agent types they attach
+var agentFlags = []struct{ prefix, agentType string }{
+	{"-javaagent:", "javaagent"},
+	{"-agentpath:", "agentpath"},
+	{"-agentlib:", "agentlib"},
+}
+
+// Agents returns the agents that the contributions attach, in the order in which the JVM attaches them: the order
+// of the contributions and, within a contribution, the order of its flags
+func Agents(contributions []Contribution) []Agent {
+	var agents []Agent
+	for _, c := range contributions {
+		for _, flag := range strings.Fields(c.Opts) {
+			flag = strings.Trim(flag, `"'`)
+			for _, agentFlag := range agentFlags {
+				if !strings.HasPrefix(flag, agentFlag.prefix) {
+					continue
+				}
+				path, options, _ := strings.Cut(strings.TrimPrefix(flag, agentFlag.prefix), "=")
+				agents = append(agents, Agent{
+					Contribution: c.Name,
+					Priority:     c.Priority,
+					Type:         agentFlag.agentType,
+					Path:         path,
+					Options:      options,
+				})
+			}
+		}
+	}
+	return agents
+}
+
+// Render removes the duplicate flags of the contributions in depDir and writes the profile.d script that assembles
+// them into JAVA_OPTS in their order. It must run after every contributor has written its options.
+func Render(log *libbuildpack.Logger, stager interface {
+	DepDir() string
+	DepsIdx() string
+	WriteProfileD(string, string) error
+}) error {
+	contributions, err := Read(stager.DepDir())
+	if err != nil {
+		return err
+	}
+
+	deduped, duplicates, conflicts := Dedup(contributions)
+	for _, duplicate := range duplicates {
+		log.Debug("Removing %s from %s, %s already passes it", duplicate.Flag, duplicate.Contribution, duplicate.Original)
+	}
+	for _, conflict := range conflicts {
+		log.Warning("%s of %s overrides %s of %s", conflict.Value, conflict.Contribution, conflict.Overridden, conflict.Original)
+	}
+	for i, c := range deduped {
+		if c.Opts == contributions[i].Opts {
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(stager.DepDir(), Dir, c.FileName()), []byte(c.Opts), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", c.FileName(), err)
+		}
+	}
+
+	if err := stager.WriteProfileD(ScriptName, Script(stager.DepsIdx(), deduped)); err != nil {
+		return fmt.Errorf("failed to write %s: %w", ScriptName, err)
+	}
+	log.Debug("Created centralized JAVA_OPTS assembly script: profile.d/%s", ScriptName)
+	return nil
+}
+
+// Script returns the profile.d script that assembles the options of the contributions, in their order, into
+// JAVA_OPTS. The options are read when the application starts, so that runtime variables such as $DEPS_DIR,
+// $HOME and the application's own $JAVA_OPTS are expanded and BPL_<NAME>_ENABLED=false (or 0) can drop the
+// options of a contributor without a restage.
+func Script(depsIdx string, contributions []Contribution) string {
+	files := make([]string, 0, len(contributions))
+	for _, c := range contributions {
+		files = append(files, fmt.Sprintf(`"$DEPS_DIR/%s/%s/%s"`, depsIdx, Dir, c.FileName()))
+	}
+
+	return fmt.Sprintf(`#!/bin/bash
+# Centralized JAVA_OPTS Assembly
+# Reads the .opts files of $DEPS_DIR/%[1]s/java_opts/ in priority order
+# and assembles them into a single JAVA_OPTS environment variable
+# Expands runtime variables like $DEPS_DIR, $HOME, $JAVA_OPTS, and all other environment variables
+
+# Save original JAVA_OPTS from environment (user-provided)
+USER_JAVA_OPTS="$JAVA_OPTS"
+
+# Start building new JAVA_OPTS
+JAVA_OPTS=""
+
+for opts_file in %[2]s; do
+    if [ -f "$opts_file" ]; then
+        # BPL_<NAME>_ENABLED=false drops a component's options without restaging,
+        # e.g. BPL_NEW_RELIC_ENABLED=false for 35_new_relic.opts
+        opts_name=$(basename "$opts_file" .opts)
+        opts_name=${opts_name#*_}
+        enabled_var="BPL_$(echo "$opts_name" | tr '[:lower:]-' '[:upper:]_')_ENABLED"
+        case "${!enabled_var:-}" in
+            false|0)
+                echo "Skipping $opts_name JAVA_OPTS because $enabled_var=${!enabled_var}" >&2
+                continue
+                ;;
+        esac
+
+        # Read content and expand runtime variables
+        opts_content=$(cat "$opts_file")
+
+        # First, expand special variables that need specific handling
+        # Expand $DEPS_DIR variable
+        opts_content=$(echo "$opts_content" | sed "s|\$DEPS_DIR|$DEPS_DIR|g")
+
+        # Expand $HOME variable (for app-provided JARs like AspectJ)
+        opts_content=$(echo "$opts_content" | sed "s|\$HOME|$HOME|g")
+
+        # Expand $JAVA_OPTS to the saved USER_JAVA_OPTS value (not the loop's current JAVA_OPTS)
+        opts_content=$(echo "$opts_content" | sed "s|\$JAVA_OPTS|$USER_JAVA_OPTS|g")
+
+        # Now expand all remaining environment variables using eval with proper escaping
+        # This mimics Ruby buildpack behavior where shell naturally expands variables
+        # Use eval in a subshell to safely expand variables without executing commands
+        opts_content=$(eval "echo \"$opts_content\"")
+
+        if [ -n "$opts_content" ]; then
+            JAVA_OPTS="$JAVA_OPTS $opts_content"
+        fi
+    fi
+done
+
+# Trim leading/trailing whitespace
+JAVA_OPTS=$(echo "$JAVA_OPTS" | sed -e 's/^[[:space:]]*//' -e 's/[[:space:]]*$//')
+
+export JAVA_OPTS
+`, depsIdx, strings.Join(files, " "))
+}
@@ -0,0 +1,13 @@
+package javaopts_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestJavaOpts(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "JavaOpts Suite")
+}
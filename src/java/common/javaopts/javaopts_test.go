@@ -0,0 +1,275 @@
+package javaopts_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
+	"github.com/cloudfoundry/libbuildpack"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("JavaOpts", func() {
+	var (
+		tmpDir  string
+		depsDir string
+		depDir  string
+		buffer  *bytes.Buffer
+		logger  *libbuildpack.Logger
+		stager  *libbuildpack.Stager
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "javaopts")
+		Expect(err).NotTo(HaveOccurred())
+		depsDir = filepath.Join(tmpDir, "deps")
+		depDir = filepath.Join(depsDir, "0")
+		Expect(os.MkdirAll(depDir, 0755)).To(Succeed())
+
+		os.Setenv("BP_DEBUG", "true")
+		buffer = new(bytes.Buffer)
+		logger = libbuildpack.NewLogger(buffer)
+		stager = libbuildpack.NewStager([]string{tmpDir, "", depsDir, "0"}, logger, &libbuildpack.Manifest{})
+	})
+
+	AfterEach(func() {
+		os.Unsetenv("BP_DEBUG")
+		os.RemoveAll(tmpDir)
+	})
+
+	write := func(priority int, name, opts string) {
+		Expect(javaopts.Write(logger, depDir, javaopts.Contribution{Priority: priority, Name: name, Opts: opts})).To(Succeed())
+	}
+
+	// assemble renders the script, sources it and returns the resulting JAVA_OPTS
+	assemble := func(env ...string) string {
+		Expect(javaopts.Render(logger, stager)).To(Succeed())
+
+		cmd := exec.Command("bash", "-c", `source "$DEPS_DIR/0/profile.d/00_java_opts.sh" && printf '%s' "$JAVA_OPTS"`)
+		cmd.Env = append([]string{"DEPS_DIR=" + depsDir, "PATH=" + os.Getenv("PATH")}, env...)
+		output, err := cmd.Output()
+		Expect(err).NotTo(HaveOccurred())
+		return string(output)
+	}
+
+	Describe("Contribution", func() {
+		It("names its file after its priority and name", func() {
+			Expect(javaopts.Contribution{Priority: javaopts.JRE, Name: "jre"}.FileName()).To(Equal("05_jre.opts"))
+			Expect(javaopts.Contribution{Priority: javaopts.NewRelic, Name: "new_relic"}.FileName()).To(Equal("35_new_relic.opts"))
+		})
+	})
+
+	Describe("Write and Append", func() {
+		It("replaces the options of a contributor", func() {
+			write(javaopts.JaCoCo, "jacoco", "-javaagent:a.jar")
+			write(javaopts.JaCoCo, "jacoco", "-javaagent:b.jar")
+
+			content, err := os.ReadFile(filepath.Join(depDir, "java_opts", "26_jacoco.opts"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("-javaagent:b.jar"))
+		})
+
+		It("appends to the options of a contributor", func() {
+			Expect(javaopts.Append(logger, depDir, javaopts.Contribution{Priority: javaopts.JRE, Name: "jre", Opts: "-Xss1M"})).To(Succeed())
+			Expect(javaopts.Append(logger, depDir, javaopts.Contribution{Priority: javaopts.JRE, Name: "jre", Opts: "-XX:+ExitOnOutOfMemoryError"})).To(Succeed())
+
+			content, err := os.ReadFile(filepath.Join(depDir, "java_opts", "05_jre.opts"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("-Xss1M -XX:+ExitOnOutOfMemoryError"))
+		})
+	})
+
+	Describe("Read", func() {
+		It("orders the contributions by priority and then by name", func() {
+			write(javaopts.User, "user_java_opts", "-Duser=1")
+			write(javaopts.NewRelic, "new_relic", "-javaagent:newrelic.jar")
+			write(javaopts.ElasticAPM, "elastic_apm_agent", "-javaagent:elastic-apm.jar")
+			write(javaopts.DatadogJavaagent, "datadog_javaagent", "-javaagent:dd-java-agent.jar")
+			write(javaopts.JRE, "memory_sizes", "-Xmx512M")
+			write(javaopts.JRE, "jre", "-Xss1M")
+
+			contributions, err := javaopts.Read(depDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			var names []string
+			for _, c := range contributions {
+				names = append(names, c.FileName())
+			}
+			Expect(names).To(Equal([]string{"05_jre.opts", "05_memory_sizes.opts", "19_datadog_javaagent.opts",
+				"19_elastic_apm_agent.opts", "35_new_relic.opts", "99_user_java_opts.opts"}))
+		})
+
+		It("returns no contributions if none were written", func() {
+			contributions, err := javaopts.Read(depDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contributions).To(BeEmpty())
+		})
+
+		It("rejects files without a priority", func() {
+			Expect(os.MkdirAll(filepath.Join(depDir, "java_opts"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(depDir, "java_opts", "agent.opts"), []byte("-Dx=1"), 0644)).To(Succeed())
+
+			_, err := javaopts.Read(depDir)
+			Expect(err).To(MatchError(ContainSubstring("agent.opts is not named <priority>_<name>.opts")))
+		})
+	})
+
+	Describe("Dedup", func() {
+		It("keeps the first occurrence of a repeated flag", func() {
+			contributions, duplicates, conflicts := javaopts.Dedup([]javaopts.Contribution{
+				{Priority: javaopts.JRE, Name: "jre", Opts: "-XX:+ExitOnOutOfMemoryError -Djava.io.tmpdir=$TMPDIR"},
+				{Priority: javaopts.JaCoCo, Name: "jacoco", Opts: "-javaagent:jacoco.jar -XX:+ExitOnOutOfMemoryError"},
+				{Priority: javaopts.User, Name: "user_java_opts", Opts: "-Djava.io.tmpdir=$TMPDIR -Xss1M -Xss1M"},
+			})
+
+			Expect(contributions[0].Opts).To(Equal("-XX:+ExitOnOutOfMemoryError -Djava.io.tmpdir=$TMPDIR"))
+			Expect(contributions[1].Opts).To(Equal("-javaagent:jacoco.jar"))
+			Expect(contributions[2].Opts).To(Equal("-Xss1M"))
+			Expect(duplicates).To(ConsistOf(
+				javaopts.Duplicate{Flag: "-XX:+ExitOnOutOfMemoryError", Contribution: "26_jacoco.opts", Original: "05_jre.opts"},
+				javaopts.Duplicate{Flag: "-Djava.io.tmpdir=$TMPDIR", Contribution: "99_user_java_opts.opts", Original: "05_jre.opts"},
+				javaopts.Duplicate{Flag: "-Xss1M", Contribution: "99_user_java_opts.opts", Original: "99_user_java_opts.opts"},
+			))
+			Expect(conflicts).To(BeEmpty())
+		})
+
+		It("reports but keeps system properties and -XX options set to another value", func() {
+			contributions, duplicates, conflicts := javaopts.Dedup([]javaopts.Contribution{
+				{Priority: javaopts.JRE, Name: "jre", Opts: "-XX:+UseG1GC -Dfile.encoding=UTF-8"},
+				{Priority: javaopts.User, Name: "user_java_opts", Opts: "-XX:-UseG1GC -Dfile.encoding=ISO-8859-1"},
+			})
+
+			Expect(contributions[1].Opts).To(Equal("-XX:-UseG1GC -Dfile.encoding=ISO-8859-1"))
+			Expect(duplicates).To(BeEmpty())
+			Expect(conflicts).To(ConsistOf(
+				javaopts.Conflict{Key: "-XX:UseG1GC", Value: "-XX:-UseG1GC", Contribution: "99_user_java_opts.opts",
+					Overridden: "-XX:+UseG1GC", Original: "05_jre.opts"},
+				javaopts.Conflict{Key: "-Dfile.encoding", Value: "-Dfile.encoding=ISO-8859-1", Contribution: "99_user_java_opts.opts",
+					Overridden: "-Dfile.encoding=UTF-8", Original: "05_jre.opts"},
+			))
+		})
+
+		It("keeps options that take their argument in the next word together with it", func() {
+			contributions, duplicates, _ := javaopts.Dedup([]javaopts.Contribution{
+				{Priority: javaopts.JavaMemoryAssistant, Name: "java_memory_assistant",
+					Opts: "--add-opens jdk.management/com.sun.management.internal=ALL-UNNAMED"},
+				{Priority: javaopts.User, Name: "user", Opts: "--add-opens java.base/java.lang=ALL-UNNAMED -Xss1M"},
+			})
+
+			Expect(contributions[1].Opts).To(Equal("--add-opens java.base/java.lang=ALL-UNNAMED -Xss1M"))
+			Expect(duplicates).To(BeEmpty())
+		})
+
+		It("removes an option repeated with the same argument together with its argument", func() {
+			contributions, duplicates, _ := javaopts.Dedup([]javaopts.Contribution{
+				{Priority: javaopts.JavaMemoryAssistant, Name: "java_memory_assistant",
+					Opts: "--add-opens jdk.management/com.sun.management.internal=ALL-UNNAMED"},
+				{Priority: javaopts.User, Name: "user",
+					Opts: "-Xss1M --add-opens jdk.management/com.sun.management.internal=ALL-UNNAMED"},
+			})
+
+			Expect(contributions[1].Opts).To(Equal("-Xss1M"))
+			Expect(duplicates).To(ConsistOf(javaopts.Duplicate{
+				Flag:         "--add-opens jdk.management/com.sun.management.internal=ALL-UNNAMED",
+				Contribution: "99_user.opts",
+				Original:     "28_java_memory_assistant.opts",
+			}))
+		})
+
+		It("leaves options it cannot split at whitespace as they are", func() {
+			contributions, duplicates, _ := javaopts.Dedup([]javaopts.Contribution{
+				{Priority: javaopts.JRE, Name: "jre", Opts: "-Xss1M"},
+				{Priority: javaopts.User, Name: "user_java_opts", Opts: `-Dgreeting="hello world" -Xss1M`},
+			})
+
+			Expect(contributions[1].Opts).To(Equal(`-Dgreeting="hello world" -Xss1M`))
+			Expect(duplicates).To(BeEmpty())
+		})
+	})
+
+	Describe("Agents", func() {
+		It("returns the agents in the order the JVM attaches them", func() {
+			agents := javaopts.Agents([]javaopts.Contribution{
+				{Priority: javaopts.JRE, Name: "jre", Opts: "-agentpath:$DEPS_DIR/0/jre/bin/jvmkill-1.16.0.so=printHeapHistogram=1 -Xss1M"},
+				{Priority: javaopts.JaCoCo, Name: "jacoco", Opts: "'-javaagent:$DEPS_DIR/0/jacoco_agent/jacocoagent.jar=output=tcpclientserver,port=6300'"},
+				{Priority: javaopts.User, Name: "user_java_opts", Opts: "-agentlib:jdwp=transport=dt_socket -javaagent:$HOME/agent.jar"},
+			})
+
+			Expect(agents).To(Equal([]javaopts.Agent{
+				{Contribution: "jre", Priority: javaopts.JRE, Type: "agentpath", Path: "$DEPS_DIR/0/jre/bin/jvmkill-1.16.0.so", Options: "printHeapHistogram=1"},
+				{Contribution: "jacoco", Priority: javaopts.JaCoCo, Type: "javaagent", Path: "$DEPS_DIR/0/jacoco_agent/jacocoagent.jar", Options: "output=tcpclientserver,port=6300"},
+				{Contribution: "user_java_opts", Priority: javaopts.User, Type: "agentlib", Path: "jdwp", Options: "transport=dt_socket"},
+				{Contribution: "user_java_opts", Priority: javaopts.User, Type: "javaagent", Path: "$HOME/agent.jar"},
+			}))
+		})
+
+		It("returns no agents for options without agents", func() {
+			Expect(javaopts.Agents([]javaopts.Contribution{{Priority: javaopts.JRE, Name: "jre", Opts: "-Xss1M"}})).To(BeEmpty())
+		})
+	})
+
+	Describe("Render", func() {
+		It("assembles JAVA_OPTS in priority order", func() {
+			write(javaopts.User, "user_java_opts", "-Dapp=1")
+			write(javaopts.NewRelic, "new_relic", "-javaagent:$DEPS_DIR/0/new_relic/newrelic.jar")
+			write(javaopts.AppDynamics, "app_dynamics", "-javaagent:$DEPS_DIR/0/app_dynamics/javaagent.jar")
+			write(javaopts.JRE, "jre", "-Xss1M")
+			write(javaopts.AppCDS, "app_cds", "-XX:SharedArchiveFile=app.jsa")
+
+			Expect(assemble()).To(Equal("-Xss1M -XX:SharedArchiveFile=app.jsa" +
+				" -javaagent:" + depsDir + "/0/app_dynamics/javaagent.jar" +
+				" -javaagent:" + depsDir + "/0/new_relic/newrelic.jar -Dapp=1"))
+		})
+
+		It("lists the files in priority order in the script", func() {
+			write(javaopts.NewRelic, "new_relic", "-javaagent:newrelic.jar")
+			write(javaopts.JRE, "jre", "-Xss1M")
+			Expect(javaopts.Render(logger, stager)).To(Succeed())
+
+			script, err := os.ReadFile(filepath.Join(depDir, "profile.d", javaopts.ScriptName))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(script)).To(ContainSubstring(
+				`for opts_file in "$DEPS_DIR/0/java_opts/05_jre.opts" "$DEPS_DIR/0/java_opts/35_new_relic.opts"; do`))
+		})
+
+		It("removes duplicate flags from the files", func() {
+			write(javaopts.JRE, "jre", "-XX:+ExitOnOutOfMemoryError")
+			write(javaopts.User, "user_java_opts", "-XX:+ExitOnOutOfMemoryError -Dapp=1")
+
+			Expect(assemble()).To(Equal("-XX:+ExitOnOutOfMemoryError -Dapp=1"))
+			Expect(buffer.String()).To(ContainSubstring(
+				"Removing -XX:+ExitOnOutOfMemoryError from 99_user_java_opts.opts, 05_jre.opts already passes it"))
+		})
+
+		It("warns about options overridden by a later contributor", func() {
+			write(javaopts.JRE, "jre", "-Dfile.encoding=UTF-8")
+			write(javaopts.User, "user_java_opts", "-Dfile.encoding=ISO-8859-1")
+
+			Expect(assemble()).To(Equal("-Dfile.encoding=UTF-8 -Dfile.encoding=ISO-8859-1"))
+			Expect(buffer.String()).To(ContainSubstring(
+				"-Dfile.encoding=ISO-8859-1 of 99_user_java_opts.opts overrides -Dfile.encoding=UTF-8 of 05_jre.opts"))
+		})
+
+		It("expands the application's JAVA_OPTS", func() {
+			write(javaopts.JRE, "jre", "-Xss1M")
+			write(javaopts.User, "user_java_opts", "$JAVA_OPTS")
+
+			Expect(assemble("JAVA_OPTS=-Dfrom=env")).To(Equal("-Xss1M -Dfrom=env"))
+		})
+
+		It("drops the options of a contributor disabled with BPL_<NAME>_ENABLED", func() {
+			write(javaopts.JRE, "jre", "-Xss1M")
+			write(javaopts.NewRelic, "new_relic", "-javaagent:newrelic.jar")
+
+			Expect(assemble("BPL_NEW_RELIC_ENABLED=false")).To(Equal("-Xss1M"))
+		})
+
+		It("renders an empty JAVA_OPTS without contributions", func() {
+			Expect(assemble()).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,150 @@
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// InstalledDependenciesFile records the manifest dependencies installed into the droplet, in the deps directory
+	InstalledDependenciesFile = "installed-dependencies.json"
+	// LicenseReportFile is the license report written to the deps directory by the finalize phase
+	LicenseReportFile = "NOTICE"
+)
+
+// License is an entry of the dependency_licenses section of manifest.yml
+type License struct {
+	Type string `yaml:"type" json:"type"`
+	URI  string `yaml:"uri" json:"uri,omitempty"`
+}
+
+// InstalledDependency is a manifest dependency that was installed into the droplet
+type InstalledDependency struct {
+	Name     string    `json:"name"`
+	Version  string    `json:"version"`
+	URI      string    `json:"uri"`
+	SHA256   string    `json:"sha256,omitempty"`
+	Licenses []License `json:"licenses,omitempty"`
+	// Dir is the slash-separated directory the dependency was installed into, relative to the directory of the
+	// record, e.g. new_relic_agent. It is empty for dependencies installed elsewhere.
+	Dir string `json:"dir,omitempty"`
+}
+
+// LoadDependencyLicenses reads the dependency_licenses section of the buildpack's manifest.yml, which maps
+// dependency names to their licenses
+func LoadDependencyLicenses(manifestRootDir string) (map[string][]License, error) {
+	path := filepath.Join(manifestRootDir, "manifest.yml")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var manifest struct {
+		DependencyLicenses map[string][]License `yaml:"dependency_licenses"`
+	}
+	if err := (YamlHandler{}).Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse dependency_licenses in %s: %w", path, err)
+	}
+	return manifest.DependencyLicenses, nil
+}
+
+// RecordInstalledDependency adds dep to the record at path, replacing an earlier record of the same dependency.
+// The record is kept in a file because supply and finalize run in separate processes.
+func RecordInstalledDependency(path string, dep InstalledDependency) error {
+	installed, err := ReadInstalledDependencies(path)
+	if err != nil {
+		return err
+	}
+
+	recorded := installed[:0]
+	for _, other := range installed {
+		if other.Name != dep.Name || other.Version != dep.Version {
+			recorded = append(recorded, other)
+		}
+	}
+	recorded = append(recorded, dep)
+
+	data, err := json.MarshalIndent(recorded, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// RecordDownload adds dep, which a framework downloaded to file from a source outside the manifest, e.g. a URL of
+// its service binding, to the record in depDir. The checksum of file is recorded if dep has none.
+func RecordDownload(depDir string, dep InstalledDependency, file string) error {
+	if dep.SHA256 == "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		digest := sha256.New()
+		if _, err := io.Copy(digest, f); err != nil {
+			return fmt.Errorf("failed to compute the checksum of %s: %w", file, err)
+		}
+		dep.SHA256 = hex.EncodeToString(digest.Sum(nil))
+	}
+	dep.URI = RedactURI(dep.URI)
+	return RecordInstalledDependency(filepath.Join(depDir, InstalledDependenciesFile), dep)
+}
+
+// ReadInstalledDependencies returns the dependencies recorded at path, or none if nothing was recorded
+func ReadInstalledDependencies(path string) ([]InstalledDependency, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var installed []InstalledDependency
+	if err := json.Unmarshal(data, &installed); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return installed, nil
+}
+
+// LicenseReport returns a NOTICE-style report of the installed third-party components and their licenses,
+// sorted by name
+func LicenseReport(installed []InstalledDependency) string {
+	sorted := append([]InstalledDependency(nil), installed...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	var b strings.Builder
+	b.WriteString("This application was staged with the following third-party components, installed by the Java buildpack.\n")
+	for _, dep := range sorted {
+		fmt.Fprintf(&b, "\n%s %s\n", dep.Name, dep.Version)
+		fmt.Fprintf(&b, "  Source:  %s\n", dep.URI)
+		if len(dep.Licenses) == 0 {
+			b.WriteString("  License: not declared in the buildpack manifest, see the component's distribution terms\n")
+		}
+		for _, license := range dep.Licenses {
+			if license.URI == "" {
+				fmt.Fprintf(&b, "  License: %s\n", license.Type)
+			} else {
+				fmt.Fprintf(&b, "  License: %s (%s)\n", license.Type, license.URI)
+			}
+		}
+	}
+	return b.String()
+}
+
+// WriteLicenseReport writes the LicenseReport of the dependencies recorded in depDir to depDir/NOTICE.
+// Nothing is written if no dependency was installed.
+func WriteLicenseReport(depDir string) error {
+	installed, err := ReadInstalledDependencies(filepath.Join(depDir, InstalledDependenciesFile))
+	if err != nil || len(installed) == 0 {
+		return err
+	}
+	return os.WriteFile(filepath.Join(depDir, LicenseReportFile), []byte(LicenseReport(installed)), 0644)
+}
@@ -0,0 +1,75 @@
+package common_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("License report", func() {
+	var depDir string
+
+	BeforeEach(func() {
+		var err error
+		depDir, err = os.MkdirTemp("", "licenses")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(depDir)
+	})
+
+	It("lists the installed components by name with their licenses", func() {
+		report := common.LicenseReport([]common.InstalledDependency{
+			{Name: "tomcat", Version: "10.1.54", URI: "https://example.com/tomcat.tar.gz",
+				Licenses: []common.License{{Type: "Apache-2.0", URI: "https://www.apache.org/licenses/LICENSE-2.0"}}},
+			{Name: "contrast-security", Version: "6.23.0", URI: "https://example.com/contrast.jar"},
+			{Name: "openjdk", Version: "17.0.13", URI: "https://example.com/openjdk.tar.gz",
+				Licenses: []common.License{{Type: "GPL-2.0-only WITH Classpath-exception-2.0"}}},
+		})
+
+		Expect(report).To(HavePrefix("This application was staged with the following third-party components"))
+		Expect(report).To(ContainSubstring(`
+contrast-security 6.23.0
+  Source:  https://example.com/contrast.jar
+  License: not declared in the buildpack manifest, see the component's distribution terms
+
+openjdk 17.0.13
+  Source:  https://example.com/openjdk.tar.gz
+  License: GPL-2.0-only WITH Classpath-exception-2.0
+
+tomcat 10.1.54
+  Source:  https://example.com/tomcat.tar.gz
+  License: Apache-2.0 (https://www.apache.org/licenses/LICENSE-2.0)
+`))
+	})
+
+	It("writes the report of the recorded dependencies to NOTICE", func() {
+		record := filepath.Join(depDir, common.InstalledDependenciesFile)
+		Expect(common.RecordInstalledDependency(record, common.InstalledDependency{Name: "jvmkill", Version: "1.17.0"})).To(Succeed())
+		Expect(common.RecordInstalledDependency(record, common.InstalledDependency{Name: "openjdk", Version: "17.0.13"})).To(Succeed())
+
+		Expect(common.WriteLicenseReport(depDir)).To(Succeed())
+		content, err := os.ReadFile(filepath.Join(depDir, common.LicenseReportFile))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(ContainSubstring("\njvmkill 1.17.0\n"))
+		Expect(string(content)).To(ContainSubstring("\nopenjdk 17.0.13\n"))
+	})
+
+	It("writes no report if nothing was installed", func() {
+		Expect(common.WriteLicenseReport(depDir)).To(Succeed())
+		Expect(filepath.Join(depDir, common.LicenseReportFile)).NotTo(BeAnExistingFile())
+	})
+
+	It("reads the dependency_licenses section of the manifest", func() {
+		manifest := "---\nlanguage: java\ndependency_licenses:\n  tomcat:\n  - type: Apache-2.0\n"
+		Expect(os.WriteFile(filepath.Join(depDir, "manifest.yml"), []byte(manifest), 0644)).To(Succeed())
+
+		licenses, err := common.LoadDependencyLicenses(depDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(licenses).To(HaveKeyWithValue("tomcat", []common.License{{Type: "Apache-2.0"}}))
+	})
+})
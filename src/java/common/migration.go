@@ -0,0 +1,215 @@
+package common
+
+import (
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/cloudfoundry/libbuildpack"
+)
+
+// legacyRenamedConfigs maps Ruby buildpack configuration variables to the names used by this buildpack
+var legacyRenamedConfigs = map[string]string{
+	"JBP_CONFIG_JREBEL_AGENT":    "JBP_CONFIG_JREBEL",
+	"JBP_CONFIG_SEALIGHTS_AGENT": "JBP_CONFIG_SEALIGHTS",
+}
+
+// legacyRemovedConfigs lists Ruby buildpack configuration variables that have no equivalent,
+// together with the guidance logged when they are found
+var legacyRemovedConfigs = map[string]string{
+	"JBP_CONFIG_TAKIPI_AGENT":                "The Takipi (OverOps) agent is not supported by this buildpack; remove the variable or install the agent from the application",
+	"JBP_CONFIG_JAVA_SECURITY":               "The Java Security framework is not supported; configure security policies at platform level or within the application",
+	"JBP_CONFIG_MULTI_BUILDPACK":             "Multi-buildpack support is built in; remove the variable and list all buildpacks with 'cf push -b'",
+	"JBP_CONFIG_SPRING_INSIGHT":              "Spring Insight is not supported; use one of the supported APM agents instead",
+	"JBP_CONFIG_GOOGLE_STACKDRIVER_DEBUGGER": "Google Stackdriver Debugger is deprecated by Google and not supported; use JBP_CONFIG_GOOGLE_STACKDRIVER_PROFILER instead",
+	"JBP_CONFIG_REPOSITORY":                  "Repository overrides are not supported; dependencies are resolved from manifest.yml (see docs/custom-jre-usage.md)",
+}
+
+// applicationOnlyConfigs lists operator defaults that are not applied, together with the reason logged when they are
+// found, because each application has to enable the behavior itself
+var applicationOnlyConfigs = map[string]string{
+	"JBP_DEFAULT_APP_CDS": "AppCDS training runs the application during staging, so it is enabled by each application with JBP_CONFIG_APP_CDS",
+}
+
+// LegacyConfig returns the variable a Ruby buildpack configuration variable is applied as, if it was renamed, and
+// the migration guidance for it. ok is false if name is not a legacy variable.
+func LegacyConfig(name string) (target, guidance string, ok bool) {
+	if target, renamed := legacyRenamedConfigs[name]; renamed {
+		return target, "rename the variable to " + target, true
+	}
+	if guidance, removed := legacyRemovedConfigs[name]; removed {
+		return "", guidance, true
+	}
+	return "", "", false
+}
+
+// MigrateLegacyConfig recognizes configuration written for the Ruby buildpack and adapts it to this buildpack.
+// Where a legacy setting can be mapped, the environment is updated so components see the new name; where it
+// cannot, a warning with migration guidance is logged. Settings that are already present are never overwritten.
+// It is called once, by the supply phase; finalize applies the same mapping with ApplyLegacyConfig.
+//
+// Handled cases:
+//   - JBP_DEFAULT_<NAME> operator defaults are merged under JBP_CONFIG_<NAME>, the application's keys win, except
+//     for behaviors each application must enable itself
+//   - renamed component variables (e.g. JBP_CONFIG_JREBEL_AGENT -> JBP_CONFIG_JREBEL)
+//   - removed components and repository_root overrides, which are reported only
+func MigrateLegacyConfig(log *libbuildpack.Logger) {
+	for _, name := range sortedEnvNames("JBP_DEFAULT_") {
+		if reason, ok := applicationOnlyConfigs[name]; ok {
+			log.Warning("%s is ignored: %s", name, reason)
+			continue
+		}
+		target := "JBP_CONFIG_" + strings.TrimPrefix(name, "JBP_DEFAULT_")
+		value, exists := os.LookupEnv(target)
+		if !exists {
+			os.Setenv(target, os.Getenv(name))
+			log.Info("Applying operator default %s as %s", name, target)
+			continue
+		}
+
+		merged, err := mergeConfig(os.Getenv(name), value)
+		if err != nil {
+			log.Warning("Ignoring operator default %s, it cannot be merged with %s: %s", name, target, err.Error())
+			continue
+		}
+		os.Setenv(target, merged)
+		log.Info("Merging operator default %s under %s", name, target)
+	}
+
+	for _, legacy := range sortedKeys(legacyRenamedConfigs) {
+		value, exists := os.LookupEnv(legacy)
+		if !exists {
+			continue
+		}
+		target := legacyRenamedConfigs[legacy]
+		if _, set := os.LookupEnv(target); set {
+			log.Warning("%s is a Ruby buildpack setting and is ignored because %s is set; remove %s", legacy, target, legacy)
+			continue
+		}
+		os.Setenv(target, value)
+		log.Warning("%s is a Ruby buildpack setting, applying it as %s; rename the variable to %s", legacy, target, target)
+	}
+
+	for _, name := range sortedKeys(legacyRemovedConfigs) {
+		if _, exists := os.LookupEnv(name); exists {
+			log.Warning("%s is not supported by this buildpack and is ignored: %s", name, legacyRemovedConfigs[name])
+		}
+	}
+
+	for _, name := range sortedEnvNames("JBP_CONFIG_") {
+		if _, removed := legacyRemovedConfigs[name]; removed {
+			continue
+		}
+		for _, path := range findRepositoryRoots(name, os.Getenv(name)) {
+			log.Warning("%s sets %s which is not supported by this buildpack: dependencies are resolved from manifest.yml (see docs/custom-jre-usage.md)", name, path)
+		}
+	}
+}
+
+// ApplyLegacyConfig updates the environment like MigrateLegacyConfig without logging. Finalize runs in a separate
+// process and needs the same environment, but the guidance was already logged by supply.
+func ApplyLegacyConfig() {
+	MigrateLegacyConfig(libbuildpack.NewLogger(io.Discard))
+}
+
+// mergeConfig returns the application's configuration merged over the operator's: nested mappings are merged key
+// by key, scalars and sequences of the application replace those of the operator
+func mergeConfig(operator, application string) (string, error) {
+	yamlHandler := YamlHandler{}
+
+	var values [2]map[string]interface{}
+	for i, value := range []string{operator, application} {
+		data, err := NormalizeConfig([]byte(value))
+		if err != nil {
+			return "", err
+		}
+		if err := yamlHandler.Unmarshal(data, &values[i]); err != nil {
+			return "", err
+		}
+	}
+
+	data, err := yamlHandler.Marshal(mergeMappings(values[0], values[1]))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func mergeMappings(base, override map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(override))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range override {
+		baseChild, baseIsMap := merged[key].(map[string]interface{})
+		child, isMap := value.(map[string]interface{})
+		if baseIsMap && isMap {
+			merged[key] = mergeMappings(baseChild, child)
+			continue
+		}
+		merged[key] = value
+	}
+	return merged
+}
+
+// supportedRepositoryRoots maps JBP_CONFIG_* variables to the repository_root keys their components download from,
+// which are not reported
+var supportedRepositoryRoots = map[string]string{
+	"JBP_CONFIG_TOMCAT":      "external_configuration.repository_root",
+	"JBP_CONFIG_ORACLE_JDBC": "repository_root",
+}
+
+// findRepositoryRoots returns the dotted paths of the unsupported repository_root keys in the value of the
+// JBP_CONFIG_* variable name
+func findRepositoryRoots(name, value string) []string {
+	var config map[string]interface{}
+	if err := (YamlHandler{}).Unmarshal([]byte(value), &config); err != nil {
+		return nil
+	}
+
+	var paths []string
+	var walk func(prefix string, node map[string]interface{})
+	walk = func(prefix string, node map[string]interface{}) {
+		for _, key := range sortedKeys(node) {
+			path := key
+			if prefix != "" {
+				path = prefix + "." + key
+			}
+			if key == "repository_root" || key == "default_repository_root" {
+				if path != supportedRepositoryRoots[name] {
+					paths = append(paths, path)
+				}
+				continue
+			}
+			if child, ok := node[key].(map[string]interface{}); ok {
+				walk(path, child)
+			}
+		}
+	}
+	walk("", config)
+
+	return paths
+}
+
+// sortedEnvNames returns the names of all environment variables with the given prefix, sorted
+func sortedEnvNames(prefix string) []string {
+	var names []string
+	for _, entry := range os.Environ() {
+		name, _, _ := strings.Cut(entry, "=")
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
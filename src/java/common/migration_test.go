@@ -0,0 +1,135 @@
+package common_test
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/libbuildpack"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MigrateLegacyConfig", func() {
+	var (
+		buffer *bytes.Buffer
+		logger *libbuildpack.Logger
+	)
+
+	BeforeEach(func() {
+		buffer = new(bytes.Buffer)
+		logger = libbuildpack.NewLogger(buffer)
+	})
+
+	AfterEach(func() {
+		for _, name := range []string{
+			"JBP_DEFAULT_OPEN_JDK_JRE", "JBP_CONFIG_OPEN_JDK_JRE", "JBP_DEFAULT_APP_CDS", "JBP_CONFIG_APP_CDS",
+			"JBP_CONFIG_JREBEL_AGENT", "JBP_CONFIG_JREBEL",
+			"JBP_CONFIG_JAVA_MAIN", "JAVA_MAIN_CLASS",
+			"JBP_CONFIG_TAKIPI_AGENT", "JBP_CONFIG_ORACLE_JRE", "JBP_CONFIG_TOMCAT", "JBP_CONFIG_ORACLE_JDBC",
+		} {
+			os.Unsetenv(name)
+		}
+	})
+
+	It("applies operator defaults when the application does not override them", func() {
+		os.Setenv("JBP_DEFAULT_OPEN_JDK_JRE", "{jre: {version: 11.+}}")
+		common.MigrateLegacyConfig(logger)
+		Expect(os.Getenv("JBP_CONFIG_OPEN_JDK_JRE")).To(Equal("{jre: {version: 11.+}}"))
+	})
+
+	It("merges application configuration over operator defaults", func() {
+		os.Setenv("JBP_DEFAULT_OPEN_JDK_JRE", "{jre: {version: 11.+}, jvmkill_agent: {enabled: false}}")
+		os.Setenv("JBP_CONFIG_OPEN_JDK_JRE", "'[jre: {version: 21.+}]'")
+		common.MigrateLegacyConfig(logger)
+
+		var merged map[string]interface{}
+		Expect(common.YamlHandler{}.Unmarshal([]byte(os.Getenv("JBP_CONFIG_OPEN_JDK_JRE")), &merged)).To(Succeed())
+		Expect(merged).To(Equal(map[string]interface{}{
+			"jre":           map[string]interface{}{"version": "21.+"},
+			"jvmkill_agent": map[string]interface{}{"enabled": false},
+		}))
+	})
+
+	It("applies the mapping without logging", func() {
+		os.Setenv("JBP_CONFIG_JREBEL_AGENT", "{enabled: false}")
+		common.ApplyLegacyConfig()
+		Expect(os.Getenv("JBP_CONFIG_JREBEL")).To(Equal("{enabled: false}"))
+		Expect(buffer.String()).To(BeEmpty())
+	})
+
+	It("maps renamed component variables", func() {
+		os.Setenv("JBP_CONFIG_JREBEL_AGENT", "{enabled: false}")
+		common.MigrateLegacyConfig(logger)
+		Expect(os.Getenv("JBP_CONFIG_JREBEL")).To(Equal("{enabled: false}"))
+		Expect(buffer.String()).To(ContainSubstring("rename the variable to JBP_CONFIG_JREBEL"))
+	})
+
+	It("does not overwrite the new variable name", func() {
+		os.Setenv("JBP_CONFIG_JREBEL_AGENT", "{enabled: false}")
+		os.Setenv("JBP_CONFIG_JREBEL", "{enabled: true}")
+		common.MigrateLegacyConfig(logger)
+		Expect(os.Getenv("JBP_CONFIG_JREBEL")).To(Equal("{enabled: true}"))
+		Expect(buffer.String()).To(ContainSubstring("is ignored because JBP_CONFIG_JREBEL is set"))
+	})
+
+	It("does not apply operator defaults that each application must enable itself", func() {
+		os.Setenv("JBP_DEFAULT_APP_CDS", "{enabled: true}")
+		common.MigrateLegacyConfig(logger)
+		_, exists := os.LookupEnv("JBP_CONFIG_APP_CDS")
+		Expect(exists).To(BeFalse())
+		Expect(buffer.String()).To(ContainSubstring("JBP_DEFAULT_APP_CDS is ignored"))
+	})
+
+	It("leaves java_main_class to the Java Main container", func() {
+		os.Setenv("JBP_CONFIG_JAVA_MAIN", "{java_main_class: io.pivotal.Main}")
+		common.MigrateLegacyConfig(logger)
+		Expect(os.Getenv("JAVA_MAIN_CLASS")).To(BeEmpty())
+		Expect(buffer.String()).NotTo(ContainSubstring("java_main_class"))
+	})
+
+	It("reports removed components", func() {
+		os.Setenv("JBP_CONFIG_TAKIPI_AGENT", "{enabled: true}")
+		common.MigrateLegacyConfig(logger)
+		Expect(buffer.String()).To(ContainSubstring("JBP_CONFIG_TAKIPI_AGENT is not supported by this buildpack"))
+	})
+
+	It("reports repository_root overrides", func() {
+		os.Setenv("JBP_CONFIG_ORACLE_JRE", `{jre: {repository_root: "https://example.com/oracle"}}`)
+		common.MigrateLegacyConfig(logger)
+		Expect(buffer.String()).To(ContainSubstring("JBP_CONFIG_ORACLE_JRE sets jre.repository_root"))
+	})
+
+	It("does not report Tomcat external configuration repository_root", func() {
+		os.Setenv("JBP_CONFIG_TOMCAT", `{external_configuration: {repository_root: "https://example.com/conf"}}`)
+		common.MigrateLegacyConfig(logger)
+		Expect(buffer.String()).NotTo(ContainSubstring("repository_root"))
+	})
+
+	It("does not report the Oracle JDBC driver repository_root", func() {
+		os.Setenv("JBP_CONFIG_ORACLE_JDBC", `{repository_root: "https://example.com/ojdbc"}`)
+		common.MigrateLegacyConfig(logger)
+		Expect(buffer.String()).NotTo(ContainSubstring("repository_root"))
+	})
+})
+
+var _ = Describe("LegacyConfig", func() {
+	It("returns the new name of renamed variables", func() {
+		target, guidance, ok := common.LegacyConfig("JBP_CONFIG_JREBEL_AGENT")
+		Expect(ok).To(BeTrue())
+		Expect(target).To(Equal("JBP_CONFIG_JREBEL"))
+		Expect(guidance).To(Equal("rename the variable to JBP_CONFIG_JREBEL"))
+	})
+
+	It("returns guidance for removed variables", func() {
+		target, guidance, ok := common.LegacyConfig("JBP_CONFIG_SPRING_INSIGHT")
+		Expect(ok).To(BeTrue())
+		Expect(target).To(BeEmpty())
+		Expect(guidance).To(ContainSubstring("Spring Insight is not supported"))
+	})
+
+	It("does not match current variables", func() {
+		_, _, ok := common.LegacyConfig("JBP_CONFIG_JREBEL")
+		Expect(ok).To(BeFalse())
+	})
+})
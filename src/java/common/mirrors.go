@@ -0,0 +1,112 @@
+package common
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common/httpclient"
+	"github.com/cloudfoundry/libbuildpack"
+)
+
+// DependencyMirror lists alternative base URLs for dependencies whose URI starts with Match.
+// The part of the URI after Match is appended to each mirror, e.g. with
+// match 'https://github.com/' and mirror 'https://mirror.example.com/github/',
+// 'https://github.com/a/b.tar.gz' is also available at 'https://mirror.example.com/github/a/b.tar.gz'.
+type DependencyMirror struct {
+	Match   string   `yaml:"match"`
+	Mirrors []string `yaml:"mirrors"`
+}
+
+// LoadDependencyMirrors reads the dependency_mirrors section of the buildpack's manifest.yml
+func LoadDependencyMirrors(manifestRootDir string) ([]DependencyMirror, error) {
+	path := filepath.Join(manifestRootDir, "manifest.yml")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var manifest struct {
+		DependencyMirrors []DependencyMirror `yaml:"dependency_mirrors"`
+	}
+	if err := (YamlHandler{}).Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse dependency_mirrors in %s: %w", path, err)
+	}
+
+	for _, mirror := range manifest.DependencyMirrors {
+		if mirror.Match == "" {
+			return nil, fmt.Errorf("dependency_mirrors entry without match in %s", path)
+		}
+	}
+	return manifest.DependencyMirrors, nil
+}
+
+// mirrorURIs returns the mirror URIs of uri in the order they are declared
+func mirrorURIs(mirrors []DependencyMirror, uri string) []string {
+	var uris []string
+	for _, mirror := range mirrors {
+		if !strings.HasPrefix(uri, mirror.Match) {
+			continue
+		}
+		path := strings.TrimPrefix(uri, mirror.Match)
+		for _, base := range mirror.Mirrors {
+			uris = append(uris, strings.TrimSuffix(base, "/")+"/"+strings.TrimPrefix(path, "/"))
+		}
+	}
+	return uris
+}
+
+// downloadFromMirror downloads uri to destFile with the shared HTTP client
+func downloadFromMirror(uri, destFile string) error {
+	return httpclient.Default().Download(uri, destFile)
+}
+
+// extractDependency installs a downloaded dependency archive the same way libbuildpack.Installer does,
+// choosing the format from the URI the archive was published under
+func extractDependency(archive, uri, outputDir string, stripComponents int) error {
+	if strings.HasSuffix(uri, ".sh") {
+		return libbuildpack.CopyFile(archive, outputDir)
+	}
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return err
+	}
+
+	switch {
+	case IsZstdArchive(uri):
+		return ExtractTarZst(archive, outputDir, stripComponents)
+	case strings.HasSuffix(uri, ".zip"):
+		if stripComponents > 0 {
+			return libbuildpack.ExtractZipWithStrip(archive, outputDir, stripComponents)
+		}
+		return libbuildpack.ExtractZip(archive, outputDir)
+	case strings.HasSuffix(uri, ".tar.xz"):
+		if stripComponents > 0 {
+			return libbuildpack.ExtractTarXzWithStrip(archive, outputDir, stripComponents)
+		}
+		return libbuildpack.ExtractTarXz(archive, outputDir)
+	case IsGzipArchive(uri):
+		return ExtractTarGz(archive, outputDir, stripComponents)
+	default:
+		return libbuildpack.CopyFile(archive, filepath.Join(outputDir, filepath.Base(uri)))
+	}
+}
+
+// RedactURI removes credentials from uri so it can be logged: the password of its user info and the values of its
+// query string, which often carry access tokens such as pre-signed URL signatures
+func RedactURI(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "<invalid URI>"
+	}
+	if u.RawQuery != "" {
+		query := u.Query()
+		for key := range query {
+			query[key] = []string{"xxxxx"}
+		}
+		u.RawQuery = query.Encode()
+	}
+	return u.Redacted()
+}
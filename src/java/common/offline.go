@@ -0,0 +1,42 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/cloudfoundry/libbuildpack"
+)
+
+// OfflineEnvVar forces offline mode on ("true") or off ("false") regardless of how the buildpack was packaged
+const OfflineEnvVar = "JBP_OFFLINE"
+
+// IsOffline returns true if the buildpack runs without network access: either it was packaged with
+// 'package.sh --cached', which embeds the manifest dependencies in its dependencies directory, or JBP_OFFLINE is true.
+// In offline mode dependencies are installed from the buildpack only and components must not attempt HTTP downloads.
+func IsOffline() bool {
+	buildpackDir, err := libbuildpack.GetBuildpackDir()
+	if err != nil {
+		return false
+	}
+	return isOffline(buildpackDir)
+}
+
+// isOffline returns true if JBP_OFFLINE is true or, if it is not set, the buildpack in buildpackDir embeds its dependencies
+func isOffline(buildpackDir string) bool {
+	if value := strings.TrimSpace(os.Getenv(OfflineEnvVar)); value != "" {
+		offline, err := strconv.ParseBool(value)
+		return err == nil && offline
+	}
+
+	info, err := os.Stat(filepath.Join(buildpackDir, "dependencies"))
+	return err == nil && info.IsDir()
+}
+
+// OfflineError returns the error reported when what would have to be downloaded from url by an offline buildpack,
+// with remedy describing how to make it available without network access
+func OfflineError(what, url, remedy string) error {
+	return fmt.Errorf("%s would be downloaded from %s, but the buildpack runs offline: %s", what, RedactURI(url), remedy)
+}
@@ -0,0 +1,96 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common/httpclient"
+	"github.com/cloudfoundry/libbuildpack"
+)
+
+// InstallerConfig is the JBP_CONFIG_DEPENDENCY_INSTALLER configuration, e.g. '{patch_fallback: true}'.
+// Failed downloads are retried by libbuildpack, and those of mirrors and streamed archives by the HTTP client (see
+// JBP_CONFIG_HTTP_CLIENT), so the installer does not repeat installations itself.
+type InstallerConfig struct {
+	// PatchFallback installs another patch version of the same line from the manifest when the artifact of the
+	// requested version is not found
+	PatchFallback bool `yaml:"patch_fallback"`
+	// StreamExtract extracts downloaded tarballs while they are downloaded instead of extracting a temporary copy,
+	// so that the archive takes no disk space of its own (see installStreamed)
+	StreamExtract bool `yaml:"stream_extract"`
+	// DiskLimit is the staging disk quota, in megabytes, that installations are checked against before they start;
+	// 0 checks them against the free space of the filesystem only
+	DiskLimit int `yaml:"disk_limit"`
+}
+
+// DefaultInstallerConfig returns the built-in installer configuration
+func DefaultInstallerConfig() InstallerConfig {
+	return InstallerConfig{}
+}
+
+// Configure applies cfg to the patch version fallback and the extraction of later installations.
+// DiskLimit applies to the DiskGuard of the supply phase, see GuardDiskUsage.
+func (i *DependencyInstaller) Configure(cfg InstallerConfig) {
+	i.patchFallback = cfg.PatchFallback
+	i.streamExtract = cfg.StreamExtract
+}
+
+// artifactNotFound returns true if the server of uri answers that it has no such artifact. Installation errors do
+// not tell why a download failed, so the artifact is requested again.
+func (i *DependencyInstaller) artifactNotFound(uri string) bool {
+	_, err := httpclient.Default().ContentLength(uri)
+	return httpclient.IsNotFound(err)
+}
+
+// installPatchFallback installs the newest other patch version of dep's version line from the manifest after the
+// artifact of dep was not found. It returns the installed dependency and its manifest entry.
+func (i *DependencyInstaller) installPatchFallback(dep libbuildpack.Dependency, outputDir string, stripComponents int, notFound error) (libbuildpack.Dependency, *libbuildpack.ManifestEntry, error) {
+	candidates := patchVersions(dep.Version, i.manifest.AllDependencyVersions(dep.Name))
+	if len(candidates) == 0 {
+		return dep, nil, notFound
+	}
+
+	for _, version := range candidates {
+		fallback := libbuildpack.Dependency{Name: dep.Name, Version: version}
+		entry, err := i.manifest.GetEntry(fallback)
+		if err != nil {
+			continue
+		}
+
+		i.log.Warning("The artifact of %s %s was not found: %s\n"+
+			"  Installing %s %s instead because patch_fallback is enabled; the application runs a different version than requested",
+			dep.Name, dep.Version, notFound.Error(), dep.Name, version)
+		if err := i.installWithMirrors(fallback, entry, outputDir, stripComponents); err != nil {
+			i.log.Warning("Could not install %s %s: %s", dep.Name, version, err.Error())
+			continue
+		}
+		return fallback, entry, nil
+	}
+
+	return dep, nil, fmt.Errorf("%w (patch versions %s also failed)", notFound, strings.Join(candidates, ", "))
+}
+
+// patchVersions returns the versions of the same major and minor version as version, other than version, newest
+// first
+func patchVersions(version string, versions []string) []string {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 3 {
+		return nil
+	}
+
+	var others []string
+	for _, v := range versions {
+		if v != version {
+			others = append(others, v)
+		}
+	}
+	matching, err := libbuildpack.FindMatchingVersions(parts[0]+"."+parts[1]+".x", others)
+	if err != nil {
+		return nil
+	}
+
+	for left, right := 0, len(matching)-1; left < right; left, right = left+1, right-1 {
+		matching[left], matching[right] = matching[right], matching[left]
+	}
+	return matching
+}
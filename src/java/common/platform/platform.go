@@ -0,0 +1,160 @@
+// Package platform lets droplets run on Kubernetes-based Cloud Foundry implementations, such as Korifi, as they run on
+// Diego cells. Diego sets CF_INSTANCE_INDEX, CF_INSTANCE_GUID and the other CF_INSTANCE_* variables, MEMORY_LIMIT in
+// megabytes, e.g. 1024m, and DEPS_DIR; Kubernetes may leave out any of them and provide the container's limits through
+// the downward API instead, with memory in bytes or as a quantity such as 1Gi.
+//
+// Render writes profile.d/000_platform_compat.sh, which provides the missing variables before any other profile.d
+// script reads them. The compatibility mode is configured with the platform_compat component, e.g.
+//
+//	mode: kubernetes
+//	downward_api_dir: /etc/podinfo
+//
+// The directory the droplet runs from is configured with common.RuntimeRootEnvVar during staging.
+package platform
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/libbuildpack"
+)
+
+// ScriptName is the profile.d script that provides the Diego environment. It sorts before 00_cgroup.sh, which
+// compares MEMORY_LIMIT with the container's cgroup limit.
+const ScriptName = "000_platform_compat.sh"
+
+// Mode decides when the compatibility script provides the Diego environment
+type Mode string
+
+const (
+	// Auto provides the environment when the application runs in a Kubernetes pod, i.e. KUBERNETES_SERVICE_HOST is set
+	Auto Mode = "auto"
+	// Kubernetes always provides the environment
+	Kubernetes Mode = "kubernetes"
+	// CF never provides the environment and writes no script, for droplets that only run on Diego
+	CF Mode = "cf"
+)
+
+// DefaultDownwardAPIDir is the directory that the downward API volume of the pod is expected in
+const DefaultDownwardAPIDir = "/etc/podinfo"
+
+// compatConfig configures the compatibility mode
+type compatConfig struct {
+	Mode Mode `yaml:"mode"`
+	// DownwardAPIDir holds the files of the pod's downward API volume: memory_limit, the container's
+	// limits.memory, and uid, the pod's UID
+	DownwardAPIDir string `yaml:"downward_api_dir"`
+}
+
+func init() {
+	config.RegisterSchema("platform_compat", func() interface{} { return &compatConfig{} })
+}
+
+// Check returns a message if mode is unknown or downward_api_dir is not absolute
+func (c *compatConfig) Check() []string {
+	var problems []string
+	if c.Mode != Auto && c.Mode != Kubernetes && c.Mode != CF {
+		problems = append(problems, fmt.Sprintf("mode must be one of %s, %s, %s, not %q", Auto, Kubernetes, CF, c.Mode))
+	}
+	if !strings.HasPrefix(c.DownwardAPIDir, "/") {
+		problems = append(problems, fmt.Sprintf("downward_api_dir must be an absolute path, not %q", c.DownwardAPIDir))
+	}
+	return problems
+}
+
+// Render writes the profile.d script that provides the Diego environment, unless the mode is cf
+func Render(log *libbuildpack.Logger, stager interface{ WriteProfileD(string, string) error }) error {
+	cfg := compatConfig{Mode: Auto, DownwardAPIDir: DefaultDownwardAPIDir}
+	if err := config.Load(log, "platform_compat", &cfg); err != nil {
+		return err
+	}
+	if problems := cfg.Check(); len(problems) > 0 {
+		return fmt.Errorf("invalid %s: %s", config.EnvVar("platform_compat"), strings.Join(problems, "; "))
+	}
+	if cfg.Mode == CF {
+		log.Debug("Platform compatibility mode is off")
+		return nil
+	}
+
+	if err := stager.WriteProfileD(ScriptName, Script(cfg.Mode, cfg.DownwardAPIDir, common.RuntimeRoot())); err != nil {
+		return fmt.Errorf("failed to write %s: %w", ScriptName, err)
+	}
+	log.Debug("Created platform compatibility script: profile.d/%s (mode %s)", ScriptName, cfg.Mode)
+	return nil
+}
+
+// Script returns the profile.d script that, in mode or on Kubernetes in Auto mode, exports the variables Diego sets
+// and the application lacks:
+//
+//   - DEPS_DIR, below runtimeRoot
+//   - MEMORY_LIMIT, in megabytes, from a MEMORY_LIMIT in bytes or as a Kubernetes quantity, or from the memory_limit
+//     file in downwardAPIDir
+//   - CF_INSTANCE_INDEX, from the ordinal of a StatefulSet pod's name, and otherwise 0
+//   - CF_INSTANCE_GUID, from the pod's UID or name
+//   - CF_INSTANCE_IP, CF_INSTANCE_INTERNAL_IP and CF_INSTANCE_PORT, from the pod's address and PORT
+func Script(mode Mode, downwardAPIDir, runtimeRoot string) string {
+	return fmt.Sprintf(`#!/bin/bash
+# Provides the environment of Diego cells on Kubernetes-based platforms such as Korifi
+
+# compat_memory_mb converts a memory size in bytes, as a Kubernetes quantity, e.g. 1Gi, or as Diego sets it, e.g.
+# 1024m, to megabytes, and prints nothing for anything else
+compat_memory_mb() {
+  local size="$1" number
+  number="${size%%%%[!0-9]*}"
+  if [ -z "$number" ]; then
+    return
+  fi
+  case "${size#"$number"}" in
+    '') echo $(( number / 1048576 )) ;;
+    Ki|[kK]|[kK][bB]) echo $(( number / 1024 )) ;;
+    Mi|[mM]|[mM][bB]) echo "$number" ;;
+    Gi|[gG]|[gG][bB]) echo $(( number * 1024 )) ;;
+    Ti|[tT]|[tT][bB]) echo $(( number * 1048576 )) ;;
+  esac
+}
+
+if [ "%[1]s" = %[4]s ] || [ -n "${KUBERNETES_SERVICE_HOST:-}" ]; then
+  export DEPS_DIR="${DEPS_DIR:-%[3]s/deps}"
+
+  if [ -z "${MEMORY_LIMIT:-}" ] && [ -r "%[2]s/memory_limit" ]; then
+    MEMORY_LIMIT=$(cat "%[2]s/memory_limit")
+  fi
+  if [ -n "${MEMORY_LIMIT:-}" ]; then
+    COMPAT_MEMORY_MB=$(compat_memory_mb "$MEMORY_LIMIT")
+    if [ -n "$COMPAT_MEMORY_MB" ] && [ "$COMPAT_MEMORY_MB" -gt 0 ]; then
+      export MEMORY_LIMIT="${COMPAT_MEMORY_MB}m"
+    else
+      echo "WARNING: Ignoring MEMORY_LIMIT=$MEMORY_LIMIT, which is not a memory size" >&2
+      unset MEMORY_LIMIT
+    fi
+    unset COMPAT_MEMORY_MB
+  fi
+
+  if [ -z "${CF_INSTANCE_INDEX:-}" ]; then
+    CF_INSTANCE_INDEX="${HOSTNAME:-}"
+    CF_INSTANCE_INDEX="${CF_INSTANCE_INDEX##*-}"
+    case "${HOSTNAME:-}:$CF_INSTANCE_INDEX" in
+      *-*:[0-9]*) case "$CF_INSTANCE_INDEX" in *[!0-9]*) CF_INSTANCE_INDEX=0 ;; esac ;;
+      *) CF_INSTANCE_INDEX=0 ;;
+    esac
+    export CF_INSTANCE_INDEX
+  fi
+  if [ -z "${CF_INSTANCE_GUID:-}" ]; then
+    if [ -r "%[2]s/uid" ]; then
+      CF_INSTANCE_GUID=$(cat "%[2]s/uid")
+    fi
+    export CF_INSTANCE_GUID="${CF_INSTANCE_GUID:-${HOSTNAME:-}}"
+  fi
+  if [ -z "${CF_INSTANCE_IP:-}" ]; then
+    CF_INSTANCE_IP="${POD_IP:-$(hostname -i 2>/dev/null)}"
+    export CF_INSTANCE_IP="${CF_INSTANCE_IP%%%% *}"
+  fi
+  export CF_INSTANCE_INTERNAL_IP="${CF_INSTANCE_INTERNAL_IP:-$CF_INSTANCE_IP}"
+  export CF_INSTANCE_PORT="${CF_INSTANCE_PORT:-${PORT:-8080}}"
+fi
+
+unset -f compat_memory_mb
+`, mode, downwardAPIDir, runtimeRoot, Kubernetes)
+}
@@ -0,0 +1,149 @@
+package platform_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/platform"
+	"github.com/cloudfoundry/libbuildpack"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Platform", func() {
+	var (
+		tmpDir     string
+		depDir     string
+		podInfoDir string
+		logger     *libbuildpack.Logger
+		stager     *libbuildpack.Stager
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "platform")
+		Expect(err).NotTo(HaveOccurred())
+		depDir = filepath.Join(tmpDir, "deps", "0")
+		podInfoDir = filepath.Join(tmpDir, "podinfo")
+		Expect(os.MkdirAll(depDir, 0755)).To(Succeed())
+		Expect(os.MkdirAll(podInfoDir, 0755)).To(Succeed())
+
+		logger = libbuildpack.NewLogger(new(bytes.Buffer))
+		stager = libbuildpack.NewStager([]string{filepath.Join(tmpDir, "app"), "", filepath.Join(tmpDir, "deps"), "0"}, logger, &libbuildpack.Manifest{})
+		os.Setenv("JBP_CONFIG_PLATFORM_COMPAT", "{downward_api_dir: "+podInfoDir+"}")
+	})
+
+	AfterEach(func() {
+		os.Unsetenv("JBP_CONFIG_PLATFORM_COMPAT")
+		os.Unsetenv(common.RuntimeRootEnvVar)
+		os.RemoveAll(tmpDir)
+	})
+
+	// source renders the script, sources it with env and returns the values of the Diego variables
+	source := func(env ...string) map[string]string {
+		Expect(platform.Render(logger, stager)).To(Succeed())
+
+		cmd := exec.Command("bash", "-c", `source "$1" && for v in DEPS_DIR MEMORY_LIMIT CF_INSTANCE_INDEX CF_INSTANCE_GUID CF_INSTANCE_IP CF_INSTANCE_INTERNAL_IP CF_INSTANCE_PORT; do echo "$v=${!v:-}"; done`,
+			"bash", filepath.Join(depDir, "profile.d", platform.ScriptName))
+		cmd.Env = append([]string{"PATH=" + os.Getenv("PATH")}, env...)
+		stderr := new(bytes.Buffer)
+		cmd.Stderr = stderr
+		output, err := cmd.Output()
+		Expect(err).NotTo(HaveOccurred(), stderr.String())
+
+		values := map[string]string{}
+		for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+			name, value, _ := strings.Cut(line, "=")
+			values[name] = value
+		}
+		return values
+	}
+
+	It("leaves the environment of Diego cells alone", func() {
+		values := source("MEMORY_LIMIT=1024m", "CF_INSTANCE_INDEX=2", "HOSTNAME=c0ffee")
+		Expect(values).To(HaveKeyWithValue("MEMORY_LIMIT", "1024m"))
+		Expect(values).To(HaveKeyWithValue("CF_INSTANCE_INDEX", "2"))
+		Expect(values).To(HaveKeyWithValue("DEPS_DIR", ""))
+		Expect(values).To(HaveKeyWithValue("CF_INSTANCE_GUID", ""))
+	})
+
+	It("provides the missing Diego variables in a Kubernetes pod", func() {
+		values := source("KUBERNETES_SERVICE_HOST=10.0.0.1", "HOSTNAME=my-app-web-3", "POD_IP=10.1.2.3", "PORT=9090")
+		Expect(values).To(Equal(map[string]string{
+			"DEPS_DIR":                "/home/vcap/deps",
+			"MEMORY_LIMIT":            "",
+			"CF_INSTANCE_INDEX":       "3",
+			"CF_INSTANCE_GUID":        "my-app-web-3",
+			"CF_INSTANCE_IP":          "10.1.2.3",
+			"CF_INSTANCE_INTERNAL_IP": "10.1.2.3",
+			"CF_INSTANCE_PORT":        "9090",
+		}))
+	})
+
+	It("keeps the variables the platform sets", func() {
+		values := source("KUBERNETES_SERVICE_HOST=10.0.0.1", "DEPS_DIR=/workspace/deps", "CF_INSTANCE_INDEX=1", "CF_INSTANCE_GUID=guid", "CF_INSTANCE_IP=10.9.9.9")
+		Expect(values).To(HaveKeyWithValue("DEPS_DIR", "/workspace/deps"))
+		Expect(values).To(HaveKeyWithValue("CF_INSTANCE_INDEX", "1"))
+		Expect(values).To(HaveKeyWithValue("CF_INSTANCE_GUID", "guid"))
+		Expect(values).To(HaveKeyWithValue("CF_INSTANCE_INTERNAL_IP", "10.9.9.9"))
+		Expect(values).To(HaveKeyWithValue("CF_INSTANCE_PORT", "8080"))
+	})
+
+	It("uses instance 0 for pods without an ordinal", func() {
+		Expect(source("KUBERNETES_SERVICE_HOST=10.0.0.1", "HOSTNAME=my-app-7d9f8-xk2p4")).To(HaveKeyWithValue("CF_INSTANCE_INDEX", "0"))
+		Expect(source("KUBERNETES_SERVICE_HOST=10.0.0.1", "HOSTNAME=standalone")).To(HaveKeyWithValue("CF_INSTANCE_INDEX", "0"))
+	})
+
+	DescribeTable("converts MEMORY_LIMIT to megabytes",
+		func(limit, expected string) {
+			Expect(source("KUBERNETES_SERVICE_HOST=10.0.0.1", "MEMORY_LIMIT="+limit)).To(HaveKeyWithValue("MEMORY_LIMIT", expected))
+		},
+		Entry("bytes", "1073741824", "1024m"),
+		Entry("Gi", "2Gi", "2048m"),
+		Entry("Mi", "768Mi", "768m"),
+		Entry("Ki", "524288Ki", "512m"),
+		Entry("Diego", "1024m", "1024m"),
+		Entry("G", "1G", "1024m"),
+		Entry("MB", "512MB", "512m"),
+		Entry("not a size", "lots", ""),
+		Entry("too small", "1024", ""),
+	)
+
+	It("reads the limits and UID of the downward API", func() {
+		Expect(os.WriteFile(filepath.Join(podInfoDir, "memory_limit"), []byte("536870912\n"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(podInfoDir, "uid"), []byte("6a1c2f0e-8d4b-4f7e-9c1a-2b3d4e5f6a7b"), 0644)).To(Succeed())
+
+		values := source("KUBERNETES_SERVICE_HOST=10.0.0.1")
+		Expect(values).To(HaveKeyWithValue("MEMORY_LIMIT", "512m"))
+		Expect(values).To(HaveKeyWithValue("CF_INSTANCE_GUID", "6a1c2f0e-8d4b-4f7e-9c1a-2b3d4e5f6a7b"))
+	})
+
+	It("provides the variables outside Kubernetes in kubernetes mode, below the runtime root", func() {
+		os.Setenv("JBP_CONFIG_PLATFORM_COMPAT", "{mode: kubernetes, downward_api_dir: "+podInfoDir+"}")
+		os.Setenv(common.RuntimeRootEnvVar, "/workspace")
+
+		values := source("MEMORY_LIMIT=1Gi", "HOSTNAME=app-0")
+		Expect(values).To(HaveKeyWithValue("DEPS_DIR", "/workspace/deps"))
+		Expect(values).To(HaveKeyWithValue("MEMORY_LIMIT", "1024m"))
+		Expect(values).To(HaveKeyWithValue("CF_INSTANCE_INDEX", "0"))
+	})
+
+	It("writes no script in cf mode", func() {
+		os.Setenv("JBP_CONFIG_PLATFORM_COMPAT", "{mode: cf}")
+		Expect(platform.Render(logger, stager)).To(Succeed())
+		Expect(filepath.Join(depDir, "profile.d", platform.ScriptName)).NotTo(BeAnExistingFile())
+	})
+
+	It("rejects an unknown mode", func() {
+		os.Setenv("JBP_CONFIG_PLATFORM_COMPAT", "{mode: korifi}")
+		Expect(platform.Render(logger, stager)).To(MatchError(ContainSubstring(`mode must be one of auto, kubernetes, cf, not "korifi"`)))
+	})
+
+	It("sorts before the cgroup script", func() {
+		Expect(platform.ScriptName < "00_cgroup.sh").To(BeTrue())
+	})
+})
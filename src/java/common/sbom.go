@@ -0,0 +1,191 @@
+package common
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// SBOMFile is the CycloneDX software bill of materials written to the deps directory by the finalize phase. It
+	// is not copied to the application, whose files containers such as Tomcat may serve.
+	SBOMFile = "sbom.cdx.json"
+)
+
+// cycloneDXBOM is the subset of the CycloneDX 1.5 JSON format the buildpack writes
+type cycloneDXBOM struct {
+	BOMFormat    string               `json:"bomFormat"`
+	SpecVersion  string               `json:"specVersion"`
+	SerialNumber string               `json:"serialNumber"`
+	Version      int                  `json:"version"`
+	Metadata     cycloneDXMetadata    `json:"metadata"`
+	Components   []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp string `json:"timestamp"`
+	Tools     struct {
+		Components []cycloneDXComponent `json:"components"`
+	} `json:"tools"`
+}
+
+type cycloneDXComponent struct {
+	Type               string               `json:"type"`
+	BOMRef             string               `json:"bom-ref,omitempty"`
+	Name               string               `json:"name"`
+	Version            string               `json:"version,omitempty"`
+	PURL               string               `json:"purl,omitempty"`
+	Hashes             []cycloneDXHash      `json:"hashes,omitempty"`
+	Licenses           []cycloneDXLicense   `json:"licenses,omitempty"`
+	ExternalReferences []cycloneDXReference `json:"externalReferences,omitempty"`
+	Properties         []cycloneDXProperty  `json:"properties,omitempty"`
+}
+
+type cycloneDXHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+// cycloneDXLicense is either a license or an SPDX expression such as "GPL-2.0-only WITH Classpath-exception-2.0"
+type cycloneDXLicense struct {
+	License    *cycloneDXLicenseID `json:"license,omitempty"`
+	Expression string              `json:"expression,omitempty"`
+}
+
+type cycloneDXLicenseID struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+type cycloneDXReference struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type cycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// SBOM returns a CycloneDX JSON software bill of materials of the installed components, sorted by name, with their
+// versions, sources, checksums and licenses. The buildpack of version buildpackVersion is listed as the tool that
+// created it.
+func SBOM(installed []InstalledDependency, buildpackVersion string, timestamp time.Time) ([]byte, error) {
+	serial, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	bom := cycloneDXBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + serial,
+		Version:      1,
+		Metadata:     cycloneDXMetadata{Timestamp: timestamp.UTC().Format(time.RFC3339)},
+		Components:   []cycloneDXComponent{},
+	}
+	bom.Metadata.Tools.Components = []cycloneDXComponent{{Type: "application", Name: "java-buildpack", Version: buildpackVersion}}
+
+	sorted := append([]InstalledDependency(nil), installed...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	for _, dep := range sorted {
+		bom.Components = append(bom.Components, sbomComponent(dep))
+	}
+
+	data, err := json.MarshalIndent(bom, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// sbomComponent describes dep as a CycloneDX component
+func sbomComponent(dep InstalledDependency) cycloneDXComponent {
+	component := cycloneDXComponent{
+		Type:    "library",
+		BOMRef:  dep.Name,
+		Name:    dep.Name,
+		Version: dep.Version,
+	}
+	if dep.Version != "" {
+		component.BOMRef += "@" + dep.Version
+	}
+
+	purl := "pkg:generic/" + url.PathEscape(dep.Name)
+	if dep.Version != "" {
+		purl += "@" + url.PathEscape(dep.Version)
+	}
+	if dep.URI != "" {
+		purl += "?download_url=" + url.QueryEscape(dep.URI)
+		component.ExternalReferences = []cycloneDXReference{{Type: "distribution", URL: dep.URI}}
+	}
+	component.PURL = purl
+
+	if dep.SHA256 != "" {
+		component.Hashes = []cycloneDXHash{{Alg: "SHA-256", Content: dep.SHA256}}
+	}
+
+	for _, license := range dep.Licenses {
+		// CycloneDX allows a single expression instead of licenses
+		if strings.Contains(license.Type, " ") && len(dep.Licenses) == 1 {
+			component.Licenses = []cycloneDXLicense{{Expression: license.Type}}
+			break
+		}
+		id := &cycloneDXLicenseID{ID: license.Type, URL: license.URI}
+		if strings.Contains(license.Type, " ") {
+			id = &cycloneDXLicenseID{Name: license.Type, URL: license.URI}
+		}
+		component.Licenses = append(component.Licenses, cycloneDXLicense{License: id})
+	}
+
+	if dep.Dir != "" {
+		component.Properties = []cycloneDXProperty{{Name: "java-buildpack:dir", Value: dep.Dir}}
+	}
+	return component
+}
+
+// WriteSBOM writes the SBOM of the dependencies recorded in depDir to depDir/sbom.cdx.json. Nothing is written if
+// no dependency was installed.
+func WriteSBOM(depDir string) error {
+	installed, err := ReadInstalledDependencies(filepath.Join(depDir, InstalledDependenciesFile))
+	if err != nil || len(installed) == 0 {
+		return err
+	}
+
+	data, err := SBOM(installed, buildpackVersion(), time.Now())
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(depDir, SBOMFile), data, 0644)
+}
+
+// buildpackVersion returns the content of the buildpack's VERSION file, or an empty string if it has none
+func buildpackVersion() string {
+	buildpackDir := os.Getenv("BUILDPACK_DIR")
+	if buildpackDir == "" {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join(buildpackDir, "VERSION"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// newUUID returns a random version 4 UUID for the serial number of an SBOM
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate SBOM serial number: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
@@ -0,0 +1,99 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/cloudfoundry/libbuildpack"
+)
+
+// Stack returns the stack the application is staged on, e.g. cflinuxfs4, which Cloud Foundry sets in CF_STACK. It is
+// empty outside Cloud Foundry.
+func Stack() string {
+	return os.Getenv("CF_STACK")
+}
+
+// IsWindowsStack returns true for the Windows stacks, e.g. windows and windows2016
+func IsWindowsStack(stack string) bool {
+	return strings.HasPrefix(stack, "windows")
+}
+
+// CheckStack returns an error if the buildpack cannot stage applications on stack. Windows stacks have neither a
+// Linux JRE nor the shell that runs the start command, and other stacks need a manifest with dependencies for them.
+// An empty stack, and a manifest that does not list its entries, e.g. a mock, are accepted.
+func CheckStack(manifest Manifest, stack string) error {
+	if IsWindowsStack(stack) {
+		return fmt.Errorf("the Java buildpack does not support the %s stack: it stages Java applications for Linux "+
+			"stacks only. Push the application with a Linux stack, e.g. cf push -s cflinuxfs4, or use the hwc or binary "+
+			"buildpack for Windows applications", stack)
+	}
+	if stack == "" {
+		return nil
+	}
+
+	stacks := manifestStacks(manifest, "")
+	if len(stacks) == 0 || contains(stacks, stack) {
+		return nil
+	}
+	return fmt.Errorf("the Java buildpack does not support the %s stack: its manifest.yml has dependencies for %s. "+
+		"Push the application with one of these stacks or use a buildpack packaged for %s", stack, strings.Join(stacks, ", "), stack)
+}
+
+// DependencyStackError returns an error if the manifest has entries of the dependency name, but none for the stack
+// the application is staged on, and nil otherwise. libbuildpack only sees the entries of the current stack, so without
+// this check a dependency published for other stacks looks as if it were missing from the manifest.
+func DependencyStackError(manifest Manifest, name string) error {
+	stack := Stack()
+	if stack == "" {
+		return nil
+	}
+
+	stacks := manifestStacks(manifest, name)
+	if len(stacks) == 0 || contains(stacks, stack) {
+		return nil
+	}
+	return fmt.Errorf("%s is not available for the %s stack: manifest.yml has it for %s", name, stack, strings.Join(stacks, ", "))
+}
+
+// manifestStacks returns the sorted stacks of the manifest entries of the dependency name, or of all entries if name
+// is empty. It returns nil if the manifest does not list its entries.
+func manifestStacks(manifest Manifest, name string) []string {
+	m, ok := manifest.(*libbuildpack.Manifest)
+	if !ok {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	for _, entry := range m.ManifestEntries {
+		if name != "" && entry.Dependency.Name != name {
+			continue
+		}
+		// A package built for a single stack records it instead of the cf_stacks of the entries
+		if m.Stack != "" {
+			seen[m.Stack] = true
+			continue
+		}
+		for _, stack := range entry.CFStacks {
+			seen[stack] = true
+		}
+	}
+
+	stacks := make([]string, 0, len(seen))
+	for stack := range seen {
+		stacks = append(stacks, stack)
+	}
+	sort.Strings(stacks)
+	return stacks
+}
+
+// contains returns true if values contains value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package common_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/libbuildpack"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Stack", func() {
+	var (
+		buildpackDir string
+		manifest     *libbuildpack.Manifest
+	)
+
+	BeforeEach(func() {
+		var err error
+		buildpackDir, err = os.MkdirTemp("", "buildpack")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.WriteFile(filepath.Join(buildpackDir, "manifest.yml"), []byte(`---
+language: java
+dependencies:
+- name: openjdk
+  version: 17.0.13
+  uri: https://example.com/openjdk-17.0.13.tar.gz
+  sha256: 6bc007201b97214a3883e2da92dc80b2e5ae29378a7a77ab4077d74ccbfdfdbd
+  cf_stacks:
+  - cflinuxfs4
+- name: agent
+  version: 1.0.0
+  uri: https://example.com/agent-1.0.0.jar
+  sha256: 2d74f026d0d184075ad99de343c6a24bd702eb25d87ce6de5e3ab8df1cd3ef25
+  cf_stacks:
+  - cflinuxfs4
+  - cflinuxfs5
+`), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(buildpackDir, "VERSION"), []byte("1.0.0"), 0644)).To(Succeed())
+
+		manifest, err = libbuildpack.NewManifest(buildpackDir, libbuildpack.NewLogger(GinkgoWriter), time.Now())
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.Unsetenv("CF_STACK")
+		os.RemoveAll(buildpackDir)
+	})
+
+	Describe("CheckStack", func() {
+		It("accepts the stacks of the manifest", func() {
+			Expect(common.CheckStack(manifest, "cflinuxfs4")).To(Succeed())
+			Expect(common.CheckStack(manifest, "cflinuxfs5")).To(Succeed())
+		})
+
+		It("accepts an unknown stack outside Cloud Foundry", func() {
+			Expect(common.CheckStack(manifest, "")).To(Succeed())
+		})
+
+		It("rejects Windows stacks", func() {
+			err := common.CheckStack(manifest, "windows2016")
+			Expect(err).To(MatchError(ContainSubstring("does not support the windows2016 stack")))
+			Expect(err).To(MatchError(ContainSubstring("hwc or binary buildpack")))
+		})
+
+		It("rejects stacks the manifest has no dependencies for", func() {
+			Expect(common.CheckStack(manifest, "cflinuxfs3")).To(MatchError(
+				"the Java buildpack does not support the cflinuxfs3 stack: its manifest.yml has dependencies for cflinuxfs4, cflinuxfs5. " +
+					"Push the application with one of these stacks or use a buildpack packaged for cflinuxfs3"))
+		})
+
+		It("uses the stack of a buildpack packaged for a single stack", func() {
+			manifest.Stack = "cflinuxfs5"
+			Expect(common.CheckStack(manifest, "cflinuxfs4")).To(MatchError(ContainSubstring("has dependencies for cflinuxfs5.")))
+		})
+	})
+
+	Describe("DependencyStackError", func() {
+		It("is nil for a dependency available for the stack", func() {
+			os.Setenv("CF_STACK", "cflinuxfs5")
+			Expect(common.DependencyStackError(manifest, "agent")).To(Succeed())
+		})
+
+		It("names the stacks of a dependency that is not available for the stack", func() {
+			os.Setenv("CF_STACK", "cflinuxfs5")
+			Expect(common.DependencyStackError(manifest, "openjdk")).To(MatchError(
+				"openjdk is not available for the cflinuxfs5 stack: manifest.yml has it for cflinuxfs4"))
+		})
+
+		It("is nil for a dependency that is not in the manifest", func() {
+			os.Setenv("CF_STACK", "cflinuxfs5")
+			Expect(common.DependencyStackError(manifest, "tomcat")).To(Succeed())
+		})
+
+		It("is nil outside Cloud Foundry", func() {
+			Expect(common.DependencyStackError(manifest, "openjdk")).To(Succeed())
+		})
+	})
+})
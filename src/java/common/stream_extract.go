@@ -0,0 +1,169 @@
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common/httpclient"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/interrupt"
+	"github.com/cloudfoundry/libbuildpack"
+)
+
+// GuardDiskUsage checks every later installation against the disk space that guard finds left for staging
+func (i *DependencyInstaller) GuardDiskUsage(guard *DiskGuard) {
+	i.disk = guard
+}
+
+// preflight estimates the disk space that installing dep needs and warns if it is likely to exceed the space left
+func (i *DependencyInstaller) preflight(dep libbuildpack.Dependency, entry *libbuildpack.ManifestEntry) {
+	if i.disk == nil {
+		return
+	}
+	what := fmt.Sprintf("%s %s", dep.Name, dep.Version)
+	streamed := i.streams(entry)
+	suggestStreaming := !streamed && entry.File == "" && streamableArchive(entry.URI)
+	i.disk.Preflight(what, EstimateInstallSize(i.archiveSize(entry), entry.URI, streamed), suggestStreaming)
+}
+
+// archiveSize returns the size of the archive of entry, from the buildpack if it is cached there and otherwise as
+// announced by its server, or -1 if it is unknown
+func (i *DependencyInstaller) archiveSize(entry *libbuildpack.ManifestEntry) int64 {
+	if entry.File != "" {
+		info, err := os.Stat(filepath.Join(i.rootDir, entry.File))
+		if err != nil {
+			return -1
+		}
+		return info.Size()
+	}
+	if i.offline {
+		return -1
+	}
+
+	size, err := httpclient.Default().ContentLength(entry.URI)
+	if err != nil {
+		i.log.Debug("Could not determine the size of %s: %s", RedactURI(entry.URI), err.Error())
+		return -1
+	}
+	return size
+}
+
+// streams returns true if entry is installed with installStreamed: stream_extract is enabled and entry is a tarball
+// that is downloaded, not cached in the buildpack
+func (i *DependencyInstaller) streams(entry *libbuildpack.ManifestEntry) bool {
+	return i.streamExtract && entry.File == "" && streamableArchive(entry.URI)
+}
+
+// streamableArchive returns true if uri names a tarball that tar can extract from a stream. Zip archives list their
+// content at the end, so they cannot be.
+func streamableArchive(uri string) bool {
+	return IsGzipArchive(uri) || strings.HasSuffix(uri, ".tar.xz") || IsZstdArchive(uri)
+}
+
+// installStreamed downloads the tarball of entry from uri and extracts it with the stack's tar while it is
+// downloaded, so the archive never takes disk space. The content is extracted next to outputDir and only moved into
+// it once the whole download matches the manifest checksum. Streamed dependencies bypass the application cache and
+// are downloaded on every staging.
+func (i *DependencyInstaller) installStreamed(entry *libbuildpack.ManifestEntry, uri, outputDir string, stripComponents int) error {
+	args := []string{"--extract", "--no-same-owner"}
+	switch {
+	case IsZstdArchive(uri):
+		if _, err := exec.LookPath("zstd"); err != nil {
+			return fmt.Errorf("zstd is required to extract %s but was not found on the stack", filepath.Base(uri))
+		}
+		args = append(args, "--use-compress-program", "zstd")
+	case strings.HasSuffix(uri, ".tar.xz"):
+		args = append(args, "--xz")
+	case parallelGzip():
+		args = append(args, "--use-compress-program", "pigz")
+	default:
+		args = append(args, "--gzip")
+	}
+	if stripComponents > 0 {
+		args = append(args, "--strip-components", strconv.Itoa(stripComponents))
+	}
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return err
+	}
+	partialDir, err := os.MkdirTemp(filepath.Dir(outputDir), "."+filepath.Base(outputDir)+"-partial")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(partialDir)
+
+	i.log.Debug("Download and extract [%s]", RedactURI(uri))
+	resp, err := httpclient.Default().Get(uri)
+	if err != nil {
+		return fmt.Errorf("could not download: %w", err)
+	}
+	defer resp.Body.Close()
+
+	digest := sha256.New()
+	body := &recordingReader{r: io.TeeReader(resp.Body, digest)}
+	cmd := interrupt.Command("tar", append(args, "--directory", partialDir)...)
+	cmd.Stdin = body
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		// tar stops at the end-of-archive marker, the checksum covers the whole download
+		_, err = io.Copy(io.Discard, body)
+	}
+	if body.err != nil {
+		return fmt.Errorf("could not download: %w", body.err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w: %s", filepath.Base(uri), err, strings.TrimSpace(string(output)))
+	}
+
+	if actual := hex.EncodeToString(digest.Sum(nil)); entry.SHA256 != "" && actual != entry.SHA256 {
+		return fmt.Errorf("dependency sha256 mismatch: expected sha256 %s, actual sha256 %s", entry.SHA256, actual)
+	}
+	return moveInto(partialDir, outputDir)
+}
+
+// recordingReader remembers the first error other than io.EOF reading r. tar reports a broken download as a
+// truncated archive, the recorded error tells why it broke.
+type recordingReader struct {
+	r   io.Reader
+	err error
+}
+
+func (r *recordingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && err != io.EOF && r.err == nil {
+		r.err = err
+	}
+	return n, err
+}
+
+// moveInto moves the content of srcDir into dstDir, merging directories that exist in both and replacing files
+func moveInto(srcDir, dstDir string) error {
+	entries, err := os.ReadDir(srcDir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		src := filepath.Join(srcDir, entry.Name())
+		dst := filepath.Join(dstDir, entry.Name())
+		if info, err := os.Lstat(dst); err == nil && info.IsDir() && entry.IsDir() {
+			if err := moveInto(src, dst); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.RemoveAll(dst); err != nil {
+			return err
+		}
+		if err := os.Rename(src, dst); err != nil {
+			return err
+		}
+	}
+	return nil
+}
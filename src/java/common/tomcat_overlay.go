@@ -0,0 +1,14 @@
+package common
+
+import "path/filepath"
+
+// TomcatOverlaysDir is the directory in the deps directory that holds the Tomcat overlays of all frameworks
+const TomcatOverlaysDir = "tomcat_overlays"
+
+// TomcatOverlayDir returns the directory in which the framework name stages files for Tomcat. Its lib, conf and
+// other subdirectories mirror CATALINA_BASE: when the application starts, the Tomcat container links every file
+// into CATALINA_BASE unless a file with the same path exists already, so that frameworks do not overwrite each
+// other's files or Tomcat's own.
+func TomcatOverlayDir(stager Stager, name string) string {
+	return filepath.Join(stager.DepDir(), TomcatOverlaysDir, name)
+}
@@ -0,0 +1,262 @@
+// Package writable keeps the directories that frameworks write to at runtime, e.g. agent logs, writable on
+// platforms that mount the droplet read-only, such as Korifi.
+//
+// A framework registers such a directory during staging with Register and passes the variable Register returns, e.g.
+// $JBP_WRITABLE_SEALIGHTS_LOGS, to its agent instead of the path. Supply and finalize run in separate processes, so
+// the registrations are recorded in the deps directory. Render writes profile.d/00_dirs_writable.sh, which exports
+// each variable when the application starts: the directory itself if it can be written, and otherwise a directory in
+// $TMPDIR that the directory's staged files are linked or copied into.
+//
+// The strategy of a framework's directories can be configured with the writable_dirs component, e.g.
+//
+//	frameworks:
+//	  sealights_agent: copy
+package writable
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/libbuildpack"
+)
+
+// File records the registered directories, in the deps directory
+const File = "writable_dirs.json"
+
+// ScriptName is the profile.d script that exports the runtime paths of the directories. It sorts before
+// 00_java_opts.sh, which expands the variables in the options of the frameworks.
+const ScriptName = "00_dirs_writable.sh"
+
+// RelocateEnvVar forces the relocation of every directory when set to true, to try an application on a writable
+// platform as it would run with a read-only droplet
+const RelocateEnvVar = "BPL_RELOCATE_WRITABLE_DIRS"
+
+// Strategy is how a directory that cannot be written at runtime is relocated to $TMPDIR
+type Strategy string
+
+const (
+	// Link links the staged files of the directory into the relocated directory, so that new files can be written
+	// next to them. Staged files stay read-only.
+	Link Strategy = "link"
+	// Copy copies the staged files, for directories whose files are changed at runtime
+	Copy Strategy = "copy"
+	// None leaves the directory in place, e.g. for an agent that tolerates a read-only directory
+	None Strategy = "none"
+)
+
+// strategies are the valid strategies
+var strategies = []Strategy{Link, Copy, None}
+
+// Dir is a directory that a framework writes to at runtime
+type Dir struct {
+	// Framework is the name of the framework that registered the directory, e.g. sealights_agent
+	Framework string `json:"framework"`
+	// Path is the runtime path of the directory, e.g. $DEPS_DIR/0/sealights_logs
+	Path string `json:"path"`
+	// Variable is the environment variable that holds the path of the directory at runtime
+	Variable string `json:"variable"`
+	// Strategy is the framework's default strategy
+	Strategy Strategy `json:"strategy"`
+}
+
+// writableDirsConfig configures the strategies of the frameworks' directories
+type writableDirsConfig struct {
+	// Frameworks overrides the default strategy of the directories of the named frameworks
+	Frameworks map[string]Strategy `yaml:"frameworks"`
+}
+
+func init() {
+	config.RegisterSchema("writable_dirs", func() interface{} { return &writableDirsConfig{} })
+}
+
+// Check returns a message for every unknown strategy
+func (c *writableDirsConfig) Check() []string {
+	var problems []string
+	for _, framework := range sortedKeys(c.Frameworks) {
+		if !valid(c.Frameworks[framework]) {
+			problems = append(problems, fmt.Sprintf("frameworks.%s must be one of %s, not %q", framework, strategyNames(), c.Frameworks[framework]))
+		}
+	}
+	return problems
+}
+
+// Register records the directory at stagingPath, in the build directory or a deps directory, that framework writes
+// to at runtime and returns the variable that holds its runtime path, e.g. $JBP_WRITABLE_SEALIGHTS_LOGS for
+// <deps>/0/sealights_logs. Options and scripts expanded at runtime must use the variable instead of the path.
+func Register(ctx *common.Context, framework, stagingPath string, strategy Strategy) (string, error) {
+	runtimePath, err := ctx.Droplet().RuntimePath(stagingPath)
+	if err != nil {
+		return "", err
+	}
+	dir := Dir{Framework: framework, Path: runtimePath, Variable: variable(runtimePath), Strategy: strategy}
+
+	file := filepath.Join(ctx.Stager.DepDir(), File)
+	dirs, err := Read(ctx.Stager.DepDir())
+	if err != nil {
+		return "", err
+	}
+	recorded := dirs[:0]
+	for _, other := range dirs {
+		if other.Path != dir.Path {
+			recorded = append(recorded, other)
+		}
+	}
+	recorded = append(recorded, dir)
+
+	data, err := json.MarshalIndent(recorded, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", File, err)
+	}
+	return "$" + dir.Variable, nil
+}
+
+// Read returns the directories registered in depDir, or none if nothing was registered
+func Read(depDir string) ([]Dir, error) {
+	data, err := os.ReadFile(filepath.Join(depDir, File))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var dirs []Dir
+	if err := json.Unmarshal(data, &dirs); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", File, err)
+	}
+	return dirs, nil
+}
+
+// Render writes the profile.d script that exports the runtime paths of the registered directories, applying the
+// configured strategies. It must run after every framework has registered its directories.
+func Render(log *libbuildpack.Logger, stager interface {
+	DepDir() string
+	WriteProfileD(string, string) error
+}) error {
+	dirs, err := Read(stager.DepDir())
+	if err != nil || len(dirs) == 0 {
+		return err
+	}
+
+	cfg := writableDirsConfig{Frameworks: map[string]Strategy{}}
+	if err := config.Load(log, "writable_dirs", &cfg); err != nil {
+		return err
+	}
+	if problems := cfg.Check(); len(problems) > 0 {
+		return fmt.Errorf("invalid %s: %s", config.EnvVar("writable_dirs"), strings.Join(problems, "; "))
+	}
+	for i, dir := range dirs {
+		if strategy, ok := cfg.Frameworks[dir.Framework]; ok {
+			dirs[i].Strategy = strategy
+		}
+	}
+
+	if err := stager.WriteProfileD(ScriptName, Script(dirs)); err != nil {
+		return fmt.Errorf("failed to write %s: %w", ScriptName, err)
+	}
+	log.Debug("Created runtime-writable directories script: profile.d/%s", ScriptName)
+	return nil
+}
+
+// Script returns the profile.d script that exports the variables of dirs. A directory that the application can write,
+// or create, is used in place. Any other directory, or every directory if BPL_RELOCATE_WRITABLE_DIRS=true, is
+// relocated to $TMPDIR/java-buildpack with its strategy.
+func Script(dirs []Dir) string {
+	var calls strings.Builder
+	for _, dir := range dirs {
+		fmt.Fprintf(&calls, "writable_dir %s \"%s\" \"%s\" %s\n", dir.Variable, dir.Path, relocatedPath(dir.Path), dir.Strategy)
+	}
+
+	return fmt.Sprintf(`#!/bin/bash
+# Exports the runtime paths of the directories that frameworks write to, relocating those that cannot be written,
+# e.g. because the platform mounts the droplet read-only, to $TMPDIR
+
+writable_dir() {
+  local variable="$1" dir="$2" target="${TMPDIR:-/tmp}/java-buildpack/$3" strategy="$4"
+
+  if [ "$strategy" = none ] || { [ "${%[1]s:-}" != true ] &&
+     { [ -w "$dir" ] || { [ ! -e "$dir" ] && mkdir -p "$dir" 2>/dev/null; }; }; }; then
+    export "$variable=$dir"
+    return
+  fi
+
+  mkdir -p "$target"
+  if [ -d "$dir" ]; then
+    if [ "$strategy" = copy ]; then
+      cp -Rn "$dir/." "$target/"
+    else
+      (cd "$dir" && find . -mindepth 1 -type d) | while read -r sub; do mkdir -p "$target/$sub"; done
+      (cd "$dir" && find . ! -type d) | while read -r file; do
+        [ -e "$target/$file" ] || [ -L "$target/$file" ] || ln -s "$dir/${file#./}" "$target/$file"
+      done
+    fi
+  fi
+  echo "[Java Buildpack] Using $target for $dir, which is read-only" >&2
+  export "$variable=$target"
+}
+
+%[2]s
+unset -f writable_dir
+`, RelocateEnvVar, calls.String())
+}
+
+// variablePattern matches the characters that are not allowed in environment variable names
+var variablePattern = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// variable returns the environment variable of the directory at runtimePath, e.g. JBP_WRITABLE_SEALIGHTS_LOGS for
+// $DEPS_DIR/0/sealights_logs and JBP_WRITABLE_DYNATRACE_LOG for $HOME/dynatrace/log
+func variable(runtimePath string) string {
+	path := strings.TrimPrefix(strings.TrimPrefix(runtimePath, "$HOME"), "$DEPS_DIR")
+	if parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2); strings.HasPrefix(runtimePath, "$DEPS_DIR") && len(parts) == 2 {
+		path = parts[1]
+	}
+	return "JBP_WRITABLE_" + strings.Trim(variablePattern.ReplaceAllString(strings.ToUpper(path), "_"), "_")
+}
+
+// relocatedPath returns the path of the relocated directory relative to $TMPDIR/java-buildpack, e.g. deps/0/htl for
+// $DEPS_DIR/0/htl and app/logs for $HOME/logs
+func relocatedPath(runtimePath string) string {
+	if rel, ok := strings.CutPrefix(runtimePath, "$DEPS_DIR/"); ok {
+		return "deps/" + rel
+	}
+	return "app/" + strings.TrimPrefix(strings.TrimPrefix(runtimePath, "$HOME"), "/")
+}
+
+// valid returns true for the known strategies
+func valid(strategy Strategy) bool {
+	for _, s := range strategies {
+		if s == strategy {
+			return true
+		}
+	}
+	return false
+}
+
+// strategyNames returns the known strategies separated by commas
+func strategyNames() string {
+	names := make([]string, len(strategies))
+	for i, s := range strategies {
+		names[i] = string(s)
+	}
+	return strings.Join(names, ", ")
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string]Strategy) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,13 @@
+package writable_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestWritable(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Writable Suite")
+}
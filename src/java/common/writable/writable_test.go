@@ -0,0 +1,160 @@
+package writable_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/writable"
+	"github.com/cloudfoundry/libbuildpack"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Writable", func() {
+	var (
+		tmpDir   string
+		buildDir string
+		depsDir  string
+		depDir   string
+		logger   *libbuildpack.Logger
+		ctx      *common.Context
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "writable")
+		Expect(err).NotTo(HaveOccurred())
+		buildDir = filepath.Join(tmpDir, "app")
+		depsDir = filepath.Join(tmpDir, "deps")
+		depDir = filepath.Join(depsDir, "0")
+		Expect(os.MkdirAll(buildDir, 0755)).To(Succeed())
+		Expect(os.MkdirAll(depDir, 0755)).To(Succeed())
+
+		logger = libbuildpack.NewLogger(new(bytes.Buffer))
+		stager := libbuildpack.NewStager([]string{buildDir, "", depsDir, "0"}, logger, &libbuildpack.Manifest{})
+		ctx = &common.Context{Stager: stager, Log: logger}
+	})
+
+	AfterEach(func() {
+		os.Unsetenv("JBP_CONFIG_WRITABLE_DIRS")
+		os.RemoveAll(tmpDir)
+	})
+
+	register := func(framework, stagingPath string, strategy writable.Strategy) string {
+		variable, err := writable.Register(ctx, framework, stagingPath, strategy)
+		Expect(err).NotTo(HaveOccurred())
+		return variable
+	}
+
+	// source renders the script, sources it and returns the value of variable and the standard error
+	source := func(variable string, env ...string) (string, string) {
+		Expect(writable.Render(logger, ctx.Stager)).To(Succeed())
+
+		cmd := exec.Command("bash", "-c", `source "$DEPS_DIR/0/profile.d/00_dirs_writable.sh" && printf '%s' "${!1}"`, "bash", variable)
+		cmd.Env = append([]string{"DEPS_DIR=" + depsDir, "HOME=" + buildDir, "TMPDIR=" + filepath.Join(tmpDir, "tmp"), "PATH=" + os.Getenv("PATH")}, env...)
+		stderr := new(bytes.Buffer)
+		cmd.Stderr = stderr
+		output, err := cmd.Output()
+		Expect(err).NotTo(HaveOccurred(), stderr.String())
+		return string(output), stderr.String()
+	}
+
+	Describe("Register", func() {
+		It("records the directory with its runtime path and returns its variable", func() {
+			Expect(register("sealights_agent", filepath.Join(depDir, "sealights_logs"), writable.Link)).To(Equal("$JBP_WRITABLE_SEALIGHTS_LOGS"))
+			Expect(register("dynatrace", filepath.Join(buildDir, "dynatrace", "log"), writable.Copy)).To(Equal("$JBP_WRITABLE_DYNATRACE_LOG"))
+
+			Expect(writable.Read(depDir)).To(Equal([]writable.Dir{
+				{Framework: "sealights_agent", Path: "$DEPS_DIR/0/sealights_logs", Variable: "JBP_WRITABLE_SEALIGHTS_LOGS", Strategy: writable.Link},
+				{Framework: "dynatrace", Path: "$HOME/dynatrace/log", Variable: "JBP_WRITABLE_DYNATRACE_LOG", Strategy: writable.Copy},
+			}))
+		})
+
+		It("records a directory once", func() {
+			register("sealights_agent", filepath.Join(depDir, "sealights_logs"), writable.Link)
+			register("sealights_agent", filepath.Join(depDir, "sealights_logs"), writable.Copy)
+
+			dirs, err := writable.Read(depDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dirs).To(HaveLen(1))
+			Expect(dirs[0].Strategy).To(Equal(writable.Copy))
+		})
+
+		It("rejects directories outside the droplet", func() {
+			_, err := writable.Register(ctx, "sealights_agent", filepath.Join(tmpDir, "elsewhere"), writable.Link)
+			Expect(err).To(MatchError(ContainSubstring("is neither in the build directory nor in a deps directory")))
+		})
+	})
+
+	Describe("Render", func() {
+		var logs string
+
+		BeforeEach(func() {
+			logs = filepath.Join(depDir, "agent", "logs")
+			Expect(os.MkdirAll(filepath.Join(logs, "archive"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(logs, "agent.properties"), []byte("level=info"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(logs, "archive", "old.log"), []byte("old"), 0644)).To(Succeed())
+		})
+
+		It("writes no script without registered directories", func() {
+			Expect(writable.Render(logger, ctx.Stager)).To(Succeed())
+			Expect(filepath.Join(depDir, "profile.d", writable.ScriptName)).NotTo(BeAnExistingFile())
+		})
+
+		It("uses a directory that can be written in place", func() {
+			register("agent", logs, writable.Link)
+
+			path, stderr := source("JBP_WRITABLE_AGENT_LOGS")
+			Expect(path).To(Equal(logs))
+			Expect(stderr).To(BeEmpty())
+		})
+
+		It("creates a missing directory in place if it can", func() {
+			register("agent", filepath.Join(depDir, "agent", "cache"), writable.Link)
+
+			path, _ := source("JBP_WRITABLE_AGENT_CACHE")
+			Expect(path).To(Equal(filepath.Join(depDir, "agent", "cache")))
+			Expect(path).To(BeADirectory())
+		})
+
+		It("links the staged files into $TMPDIR when the directory is relocated", func() {
+			register("agent", logs, writable.Link)
+
+			path, stderr := source("JBP_WRITABLE_AGENT_LOGS", "BPL_RELOCATE_WRITABLE_DIRS=true")
+			Expect(path).To(Equal(filepath.Join(tmpDir, "tmp", "java-buildpack", "deps", "0", "agent", "logs")))
+			Expect(stderr).To(ContainSubstring("Using " + path + " for " + logs + ", which is read-only"))
+
+			Expect(os.Readlink(filepath.Join(path, "agent.properties"))).To(Equal(filepath.Join(logs, "agent.properties")))
+			Expect(filepath.Join(path, "archive")).To(BeADirectory())
+			Expect(os.Readlink(filepath.Join(path, "archive", "old.log"))).To(Equal(filepath.Join(logs, "archive", "old.log")))
+		})
+
+		It("copies the staged files with the copy strategy configured for the framework", func() {
+			register("agent", logs, writable.Link)
+			os.Setenv("JBP_CONFIG_WRITABLE_DIRS", "{frameworks: {agent: copy}}")
+
+			path, _ := source("JBP_WRITABLE_AGENT_LOGS", "BPL_RELOCATE_WRITABLE_DIRS=true")
+			info, err := os.Lstat(filepath.Join(path, "agent.properties"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().IsRegular()).To(BeTrue())
+			Expect(filepath.Join(path, "archive", "old.log")).To(BeARegularFile())
+		})
+
+		It("keeps the directory in place with the none strategy", func() {
+			register("agent", logs, writable.None)
+
+			path, _ := source("JBP_WRITABLE_AGENT_LOGS", "BPL_RELOCATE_WRITABLE_DIRS=true")
+			Expect(path).To(Equal(logs))
+		})
+
+		It("rejects unknown strategies", func() {
+			register("agent", logs, writable.Link)
+			os.Setenv("JBP_CONFIG_WRITABLE_DIRS", "{frameworks: {agent: move}}")
+
+			Expect(writable.Render(logger, ctx.Stager)).To(MatchError(ContainSubstring(`frameworks.agent must be one of link, copy, none, not "move"`)))
+		})
+	})
+})
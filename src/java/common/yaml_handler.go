@@ -2,9 +2,19 @@ package common
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cloudfoundry/libbuildpack"
 	"go.yaml.in/yaml/v3"
 )
 
+// StrictConfigEnvVar enables strict validation of JBP_CONFIG_* values.
+// When set to "true", unknown configuration keys fail staging instead of only logging a warning.
+const StrictConfigEnvVar = "JBP_STRICT_CONFIG"
+
 // YamlHandler provides a thin wrapper around yaml.v3's Marshal and Unmarshal.
 type YamlHandler struct{}
 
@@ -24,3 +34,99 @@ func (h YamlHandler) ValidateFields(data []byte, out interface{}) error {
 	dec.KnownFields(true)
 	return dec.Decode(out)
 }
+
+// ValidateConfig checks a JBP_CONFIG_* value for keys that are not part of the component schema (out).
+// Unknown keys are logged as a warning. In strict mode (JBP_STRICT_CONFIG=true) they are returned
+// as an *UnknownConfigKeysError so that staging fails on misconfiguration.
+// Type mismatches are ignored here, they are reported by the subsequent Unmarshal.
+func (h YamlHandler) ValidateConfig(log *libbuildpack.Logger, envVar string, data []byte, out interface{}) error {
+	unknown := unknownFields(h.ValidateFields(data, out))
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	if IsStrictConfig() {
+		return &UnknownConfigKeysError{EnvVar: envVar, Fields: unknown}
+	}
+
+	log.Warning("Unknown user config values in %s: %s", envVar, strings.Join(unknown, "; "))
+	log.Warning("Set %s=true to fail staging on unknown configuration keys", StrictConfigEnvVar)
+	return nil
+}
+
+// IsStrictConfig returns true if strict JBP_CONFIG_* validation is enabled
+func IsStrictConfig() bool {
+	value := strings.ToLower(strings.TrimSpace(os.Getenv(StrictConfigEnvVar)))
+	return value == "true" || value == "1"
+}
+
+// UnknownConfigKeysError reports keys in a JBP_CONFIG_* value that the component does not understand
+type UnknownConfigKeysError struct {
+	EnvVar string
+	Fields []string
+}
+
+func (e *UnknownConfigKeysError) Error() string {
+	return fmt.Sprintf("unknown configuration keys in %s: %s", e.EnvVar, strings.Join(e.Fields, "; "))
+}
+
+// IsUnknownConfigKeysError returns true if err (or any error it wraps) is an *UnknownConfigKeysError
+func IsUnknownConfigKeysError(err error) bool {
+	var target *UnknownConfigKeysError
+	return errors.As(err, &target)
+}
+
+// unknownFields extracts the "field X not found in type Y" messages from a yaml decode error
+func unknownFields(err error) []string {
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) {
+		return nil
+	}
+
+	var fields []string
+	for _, msg := range typeErr.Errors {
+		if strings.Contains(msg, "not found in type") {
+			fields = append(fields, strings.TrimSpace(msg))
+		}
+	}
+	return fields
+}
+
+// NormalizeConfig converts the JBP_CONFIG_* formats accepted by the Ruby buildpack into a single YAML mapping:
+// a flow or block mapping, a mapping quoted as a YAML string, or the legacy sequence of single-key mappings
+func NormalizeConfig(data []byte) ([]byte, error) {
+	return normalizeConfig(data, true)
+}
+
+func normalizeConfig(data []byte, unquote bool) ([]byte, error) {
+	yamlHandler := YamlHandler{}
+
+	var content interface{}
+	if err := yamlHandler.Unmarshal(data, &content); err != nil {
+		return nil, err
+	}
+
+	switch v := content.(type) {
+	case nil, map[string]interface{}:
+		return data, nil
+	case string:
+		if !unquote {
+			return nil, fmt.Errorf("expected a mapping, found %q", v)
+		}
+		return normalizeConfig([]byte(v), false)
+	case []interface{}:
+		merged := make(map[string]interface{})
+		for _, item := range v {
+			entry, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("expected a mapping, found %v", item)
+			}
+			for key, value := range entry {
+				merged[key] = value
+			}
+		}
+		return yamlHandler.Marshal(merged)
+	default:
+		return nil, fmt.Errorf("expected a mapping, found %v", v)
+	}
+}
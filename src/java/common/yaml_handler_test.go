@@ -0,0 +1,71 @@
+package common_test
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/libbuildpack"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("YamlHandler", func() {
+	type nested struct {
+		AccessLogging string `yaml:"access_logging"`
+	}
+	type testConfig struct {
+		Enabled bool   `yaml:"enabled"`
+		Nested  nested `yaml:"nested"`
+	}
+
+	var (
+		handler common.YamlHandler
+		buffer  *bytes.Buffer
+		logger  *libbuildpack.Logger
+	)
+
+	BeforeEach(func() {
+		handler = common.YamlHandler{}
+		buffer = new(bytes.Buffer)
+		logger = libbuildpack.NewLogger(buffer)
+	})
+
+	AfterEach(func() {
+		os.Unsetenv("JBP_STRICT_CONFIG")
+	})
+
+	Describe("ValidateConfig", func() {
+		It("accepts known keys", func() {
+			cfg := testConfig{}
+			err := handler.ValidateConfig(logger, "JBP_CONFIG_TEST", []byte("{enabled: true, nested: {access_logging: enabled}}"), &cfg)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(buffer.String()).To(BeEmpty())
+		})
+
+		It("warns about unknown nested keys by default", func() {
+			cfg := testConfig{}
+			err := handler.ValidateConfig(logger, "JBP_CONFIG_TEST", []byte("{nested: {acess_logging: enabled}}"), &cfg)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(buffer.String()).To(ContainSubstring("Unknown user config values in JBP_CONFIG_TEST"))
+			Expect(buffer.String()).To(ContainSubstring("acess_logging"))
+		})
+
+		It("returns an UnknownConfigKeysError in strict mode", func() {
+			os.Setenv("JBP_STRICT_CONFIG", "true")
+			cfg := testConfig{}
+			err := handler.ValidateConfig(logger, "JBP_CONFIG_TEST", []byte("{enabld: true}"), &cfg)
+			Expect(err).To(HaveOccurred())
+			Expect(common.IsUnknownConfigKeysError(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("unknown configuration keys in JBP_CONFIG_TEST"))
+			Expect(err.Error()).To(ContainSubstring("enabld"))
+		})
+
+		It("ignores type errors, leaving them to Unmarshal", func() {
+			os.Setenv("JBP_STRICT_CONFIG", "true")
+			cfg := testConfig{}
+			err := handler.ValidateConfig(logger, "JBP_CONFIG_TEST", []byte("{enabled: notabool}"), &cfg)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+})
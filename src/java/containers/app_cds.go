@@ -0,0 +1,352 @@
+package containers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/features"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/jarutil"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
+	"github.com/cloudfoundry/java-buildpack/src/java/jres"
+)
+
+// appCDSMinJavaVersion is the first LTS release that accepts a dynamic archive when the application and deps
+// directories have moved together, as they do from /tmp/app and /tmp/deps during staging to /home/vcap/app and
+// /home/vcap/deps at runtime
+const appCDSMinJavaVersion = 21
+
+// appCDSSpringBootMinVersion is the first Spring Boot release that stops after the application context is refreshed
+// with -Dspring.context.exit=onRefresh, so the training run does not serve requests or run application tasks, and
+// that extracts the application with -Djarmode=tools
+var appCDSSpringBootMinVersion = [2]int{3, 3}
+
+// appCDSDir is the directory of the application the extracted JAR is written to. The JVM does not archive classes
+// loaded from directories or from the nested JARs of a Spring Boot JAR, so the application is trained and started
+// from the extracted JAR and its lib directory.
+var appCDSDir = filepath.Join(".java-buildpack", "app_cds")
+
+// appCDSTrainingEnv lists the variables the training run inherits from staging. VCAP_SERVICES and other variables
+// that may carry credentials are left out, so the application cannot reach bound services during staging.
+// JAVA_OPTS is the application's own, which the JAVA_OPTS assembly adds as at runtime.
+var appCDSTrainingEnv = []string{"PATH", "LANG", "LC_ALL", "TZ", "TMPDIR", "JAVA_HOME", "JAVA_OPTS"}
+
+// appCDSProfileScripts lists the profile.d scripts of the deps directory that the training run sources besides the
+// JAVA_OPTS assembly, because they change the class path of the start command. The other scripts, e.g.
+// zzz_classpath_symlinks.sh, may change the droplet and only run at runtime.
+var appCDSProfileScripts = []string{"container_security_provider.sh"}
+
+// appCDSConfig enables an application class data sharing (AppCDS) archive, which is created by a training run
+// during staging and shortens the startup of large Spring Boot applications, e.g.
+//
+//	JBP_CONFIG_APP_CDS='{enabled: true}'
+type appCDSConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// TrainingTimeout is the time in seconds the training run may take before the application is stopped
+	TrainingTimeout int `yaml:"training_timeout"`
+}
+
+// trainAppCDS extracts the Spring Boot JAR, or the JAR repacked from the exploded application, with
+// -Djarmode=tools, runs the start command of the extracted JAR once with the runtime JAVA_OPTS,
+// -XX:ArchiveClassesAtExit and -Dspring.context.exit=onRefresh, and adds the resulting archive to JAVA_OPTS.
+// jarFile is the runtime path of the Spring Boot JAR, or "" for an exploded application.
+//
+// It only runs for Spring Boot 3.3 and later, and only when the application enables it with JBP_CONFIG_APP_CDS and
+// the app_cds feature flag is enabled for its organization and space. Failed runs are reported and skipped, as the
+// application starts without the archive as well.
+func trainAppCDS(ctx *common.Context, springBootVersion, jarFile string) error {
+	cfg := appCDSConfig{TrainingTimeout: 120}
+	if err := config.Load(ctx.Log, "app_cds", &cfg); err != nil {
+		return err
+	}
+	if !cfg.Enabled {
+		return nil
+	}
+	if !features.Enabled(ctx.Log, features.AppCDS) {
+		ctx.Log.Info("Skipping AppCDS training, the app_cds feature flag is not enabled for this organization or space")
+		return nil
+	}
+	if !springBootAtLeast(springBootVersion, appCDSSpringBootMinVersion) {
+		ctx.Log.Warning("Skipping AppCDS training, it requires Spring Boot %d.%d or later, found %q",
+			appCDSSpringBootMinVersion[0], appCDSSpringBootMinVersion[1], springBootVersion)
+		return nil
+	}
+
+	javaHome := os.Getenv("JAVA_HOME")
+	if javaHome == "" {
+		ctx.Log.Warning("Skipping AppCDS training, JAVA_HOME is not set")
+		return nil
+	}
+	if release, err := os.ReadFile(filepath.Join(javaHome, "release")); err == nil && strings.Contains(strings.ToLower(string(release)), "openj9") {
+		ctx.Log.Info("Skipping AppCDS training, OpenJ9 uses its shared class cache instead")
+		return nil
+	}
+	if version, err := common.DetermineJavaVersion(javaHome); err != nil || version < appCDSMinJavaVersion {
+		ctx.Log.Warning("Skipping AppCDS training, it requires Java %d or later", appCDSMinJavaVersion)
+		return nil
+	}
+
+	buildDir := ctx.Stager.BuildDir()
+	source := filepath.Join(buildDir, filepath.Base(jarFile))
+	if jarFile == "" {
+		if _, err := os.Stat(filepath.Join(buildDir, "BOOT-INF")); err != nil {
+			ctx.Log.Info("Skipping AppCDS training, it requires a Spring Boot JAR or an exploded Spring Boot JAR")
+			return nil
+		}
+
+		tmpDir, err := os.MkdirTemp("", "app-cds")
+		if err != nil {
+			return fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		defer os.RemoveAll(tmpDir)
+
+		source = filepath.Join(tmpDir, "application.jar")
+		if err := repackExplodedJar(buildDir, source); err != nil {
+			return fmt.Errorf("failed to repack the exploded application: %w", err)
+		}
+	}
+
+	ctx.Log.BeginStep("Creating AppCDS archive")
+	archive := filepath.Join(ctx.Stager.DepDir(), "app_cds", "application.jsa")
+	if err := os.MkdirAll(filepath.Dir(archive), 0755); err != nil {
+		return fmt.Errorf("failed to create app_cds directory: %w", err)
+	}
+	os.Remove(archive)
+
+	extracted := filepath.Join(buildDir, appCDSDir)
+	if err := os.RemoveAll(extracted); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", appCDSDir, err)
+	}
+
+	var output bytes.Buffer
+	extract := exec.Command(filepath.Join(javaHome, "bin", "java"), "-Djarmode=tools", "-jar", source, "extract", "--destination", extracted)
+	extract.Dir = buildDir
+	extract.Env = appCDSEnv(buildDir)
+	extract.Stdout, extract.Stderr = &output, &output
+	if err := extract.Run(); err != nil {
+		ctx.Log.Warning("Could not extract the application for AppCDS (%v), starting without an archive", err)
+		ctx.Log.Debug("Extraction output:\n%s", output.String())
+		os.RemoveAll(extracted)
+		return nil
+	}
+
+	command, err := appCDSStartCommand(ctx)
+	if err != nil {
+		ctx.Log.Warning("Could not start the extracted application for AppCDS (%v), starting without an archive", err)
+		os.RemoveAll(extracted)
+		return nil
+	}
+
+	script, err := appCDSTrainingScript(ctx, command, archive)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(script)
+
+	runCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.TrainingTimeout)*time.Second)
+	defer cancel()
+
+	// The start command runs as at runtime, with $HOME pointing at the application, but without service bindings
+	cmd := exec.CommandContext(runCtx, "bash", script)
+	cmd.Dir = buildDir
+	cmd.Env = append(appCDSEnv(buildDir),
+		"DEPS_DIR="+filepath.Dir(ctx.Stager.DepDir()),
+		"PORT=0",
+		"SERVER_PORT=0",
+	)
+	// SIGTERM lets the JVM run its shutdown sequence, which writes the archive
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = 30 * time.Second
+
+	output.Reset()
+	cmd.Stdout, cmd.Stderr = &output, &output
+	err = cmd.Run()
+	if runCtx.Err() != nil {
+		ctx.Log.Info("Stopped the AppCDS training run after %ds", cfg.TrainingTimeout)
+	}
+
+	if _, statErr := os.Stat(archive); statErr != nil {
+		ctx.Log.Warning("AppCDS training run did not create an archive (%v), starting without it", err)
+		ctx.Log.Debug("AppCDS training output:\n%s", output.String())
+		os.RemoveAll(extracted)
+		return nil
+	}
+
+	opts := "-XX:SharedArchiveFile=" + ctx.Droplet().AbsoluteDep("app_cds", "application.jsa")
+	if err := jres.WriteJavaOptsWithPriority(ctx, javaopts.AppCDS, "app_cds", opts); err != nil {
+		return fmt.Errorf("failed to add AppCDS archive to JAVA_OPTS: %w", err)
+	}
+	ctx.Log.Info("Created AppCDS archive %s", filepath.Base(archive))
+	return nil
+}
+
+// appCDSEnv returns the environment of the extraction and the training run: the variables of appCDSTrainingEnv,
+// the BPL_ variables that drop JAVA_OPTS contributions, and $HOME and $PWD pointing at the application
+func appCDSEnv(buildDir string) []string {
+	var env []string
+	for _, name := range appCDSTrainingEnv {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	for _, variable := range os.Environ() {
+		if strings.HasPrefix(variable, "BPL_") {
+			env = append(env, variable)
+		}
+	}
+	return append(env, "HOME="+buildDir, "PWD="+buildDir)
+}
+
+// appCDSTrainingScript writes the script of the training run: it assembles JAVA_OPTS from the .opts files written
+// so far, as profile.d/00_java_opts.sh does at runtime, sources the appCDSProfileScripts and runs command with the
+// options that create the archive. The caller removes the script.
+func appCDSTrainingScript(ctx *common.Context, command, archive string) (string, error) {
+	contributions, err := javaopts.Read(ctx.Stager.DepDir())
+	if err != nil {
+		return "", err
+	}
+	deduped, _, _ := javaopts.Dedup(contributions)
+
+	var script strings.Builder
+	script.WriteString(javaopts.Script(ctx.Stager.DepsIdx(), deduped))
+	for _, name := range appCDSProfileScripts {
+		if _, err := os.Stat(filepath.Join(ctx.Stager.DepDir(), "profile.d", name)); err == nil {
+			fmt.Fprintf(&script, "source \"$DEPS_DIR/%s/profile.d/%s\"\n", ctx.Stager.DepsIdx(), name)
+		}
+	}
+	fmt.Fprintf(&script, "JAVA_OPTS=\"$JAVA_OPTS -XX:ArchiveClassesAtExit=%s -Dspring.context.exit=onRefresh\"\n%s\n", archive, command)
+
+	file, err := os.CreateTemp("", "app-cds-*.sh")
+	if err != nil {
+		return "", fmt.Errorf("failed to create AppCDS training script: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(script.String()); err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write AppCDS training script: %w", err)
+	}
+	return file.Name(), nil
+}
+
+// appCDSStartCommand returns the command that starts the application from the JAR extracted by trainAppCDS. The
+// class path is absolute, like the one of the training run, so that the JVM relocates the archive's class path
+// together with the application and deps directories. The Container Security Provider is appended to it.
+func appCDSStartCommand(ctx *common.Context) (string, error) {
+	jars, err := filepath.Glob(filepath.Join(ctx.Stager.BuildDir(), appCDSDir, "*.jar"))
+	if err != nil || len(jars) != 1 {
+		return "", fmt.Errorf("expected one JAR in %s, found %d", appCDSDir, len(jars))
+	}
+	manifest, err := jarutil.ReadManifest(jars[0])
+	if err != nil {
+		return "", err
+	}
+	mainClass := manifest.Get("Main-Class")
+	if mainClass == "" {
+		return "", fmt.Errorf("%s has no Main-Class", filepath.Base(jars[0]))
+	}
+
+	// Use eval to properly handle backslash-escaped values in $JAVA_OPTS (Ruby buildpack parity)
+	return fmt.Sprintf("eval exec $JAVA_HOME/bin/java $JAVA_OPTS -cp %s${CONTAINER_SECURITY_PROVIDER:+:$CONTAINER_SECURITY_PROVIDER} %s",
+		ctx.Droplet().App(appCDSDir, filepath.Base(jars[0])), mainClass), nil
+}
+
+// appCDSArchived returns true if trainAppCDS created an archive, so the application starts from the extracted JAR
+func appCDSArchived(ctx *common.Context) bool {
+	_, err := os.Stat(filepath.Join(ctx.Stager.DepDir(), "app_cds", "application.jsa"))
+	return err == nil
+}
+
+// repackExplodedJar writes the exploded Spring Boot JAR in buildDir to jarPath, which -Djarmode=tools extracts.
+// The manifest comes first and the nested JARs of BOOT-INF/lib are stored uncompressed, as Spring Boot requires.
+// Hidden entries of buildDir, e.g. .java-buildpack and .profile.d, are left out.
+func repackExplodedJar(buildDir, jarPath string) error {
+	file, err := os.Create(jarPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := zip.NewWriter(file)
+	add := func(name, path string) error {
+		method := zip.Deflate
+		if strings.HasPrefix(name, "BOOT-INF/lib/") {
+			method = zip.Store
+		}
+		entry, err := w.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+		if err != nil {
+			return err
+		}
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		_, err = io.Copy(entry, in)
+		return err
+	}
+
+	manifest := filepath.Join(buildDir, filepath.FromSlash(jarutil.ManifestPath))
+	if err := add(jarutil.ManifestPath, manifest); err != nil {
+		return err
+	}
+
+	err = filepath.WalkDir(buildDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(buildDir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && filepath.Dir(rel) == "." {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		name := filepath.ToSlash(rel)
+		if d.IsDir() {
+			_, err := w.Create(name + "/")
+			return err
+		}
+		if !d.Type().IsRegular() || name == jarutil.ManifestPath {
+			return nil
+		}
+		return add(name, path)
+	})
+	if err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// springBootAtLeast returns true if version, e.g. 3.4.1, is min or later
+func springBootAtLeast(version string, min [2]int) bool {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false
+	}
+	return major > min[0] || (major == min[0] && minor >= min[1])
+}
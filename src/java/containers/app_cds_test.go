@@ -0,0 +1,257 @@
+package containers_test
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/containers"
+	"github.com/cloudfoundry/libbuildpack"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AppCDS", func() {
+	var (
+		ctx      *common.Context
+		buildDir string
+		depsDir  string
+		javaHome string
+	)
+
+	// fakeJava records its arguments and, like a JVM, writes the archive named by -XX:ArchiveClassesAtExit. With
+	// -Djarmode=tools it copies the JAR to the destination, like extract does with the application classes.
+	const fakeJava = `#!/bin/bash
+if [ "$1" = "-Djarmode=tools" ]; then
+  echo "$@" > "$HOME/extract-args"
+  mkdir -p "$6/lib" && cp "$3" "$6/"
+  exit
+fi
+echo "$@" > "$HOME/java-args"
+env > "$HOME/java-env"
+for arg in "$@"; do
+  case "$arg" in
+    -XX:ArchiveClassesAtExit=*) [ ! -e "$HOME/no-archive" ] && echo archive > "${arg#*=}" ;;
+  esac
+done
+`
+
+	BeforeEach(func() {
+		var err error
+		buildDir, err = os.MkdirTemp("", "build")
+		Expect(err).NotTo(HaveOccurred())
+		depsDir, err = os.MkdirTemp("", "deps")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.MkdirAll(filepath.Join(depsDir, "0"), 0755)).To(Succeed())
+
+		javaHome = filepath.Join(depsDir, "0", "jre")
+		Expect(os.MkdirAll(filepath.Join(javaHome, "bin"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(javaHome, "bin", "java"), []byte(fakeJava), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(javaHome, "release"), []byte("JAVA_VERSION=\"21.0.5\"\n"), 0644)).To(Succeed())
+		os.Setenv("JAVA_HOME", javaHome)
+
+		logger := libbuildpack.NewLogger(GinkgoWriter)
+		manifest := &libbuildpack.Manifest{}
+		ctx = &common.Context{
+			Stager:   libbuildpack.NewStager([]string{buildDir, "", depsDir, "0"}, logger, manifest),
+			Manifest: manifest,
+			Log:      logger,
+		}
+
+		Expect(createJar(filepath.Join(buildDir, "app.jar"), "Manifest-Version: 1.0\nMain-Class: com.example.Main\nStart-Class: com.example.App\nSpring-Boot-Version: 3.4.1\n")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(buildDir)
+		os.RemoveAll(depsDir)
+		os.Unsetenv("JAVA_HOME")
+		os.Unsetenv("JBP_CONFIG_APP_CDS")
+		os.Unsetenv("JBP_DEFAULT_FEATURE_FLAGS")
+		os.Unsetenv("JAVA_OPTS")
+	})
+
+	archive := func() string {
+		return filepath.Join(depsDir, "0", "app_cds", "application.jsa")
+	}
+
+	readOpts := func() string {
+		opts, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "07_app_cds.opts"))
+		Expect(err).NotTo(HaveOccurred())
+		return string(opts)
+	}
+
+	It("is disabled by default", func() {
+		container := containers.NewSpringBootContainer(ctx)
+		Expect(container.Detect()).To(Equal("Spring Boot"))
+		Expect(container.Finalize()).To(Succeed())
+
+		Expect(archive()).NotTo(BeAnExistingFile())
+		Expect(filepath.Join(buildDir, "java-args")).NotTo(BeAnExistingFile())
+	})
+
+	It("is not enabled by the app_cds feature flag alone", func() {
+		os.Setenv("JBP_DEFAULT_FEATURE_FLAGS", "{app_cds: true}")
+
+		container := containers.NewSpringBootContainer(ctx)
+		Expect(container.Detect()).To(Equal("Spring Boot"))
+		Expect(container.Finalize()).To(Succeed())
+		Expect(filepath.Join(buildDir, "java-args")).NotTo(BeAnExistingFile())
+	})
+
+	It("is not enabled by the application while the app_cds feature flag is disabled", func() {
+		os.Setenv("JBP_CONFIG_APP_CDS", "{enabled: true}")
+
+		container := containers.NewSpringBootContainer(ctx)
+		Expect(container.Detect()).To(Equal("Spring Boot"))
+		Expect(container.Finalize()).To(Succeed())
+		Expect(filepath.Join(buildDir, "java-args")).NotTo(BeAnExistingFile())
+	})
+
+	Context("when enabled", func() {
+		BeforeEach(func() {
+			os.Setenv("JBP_CONFIG_APP_CDS", "{enabled: true}")
+			os.Setenv("JBP_DEFAULT_FEATURE_FLAGS", "{app_cds: true}")
+		})
+
+		It("trains a Spring Boot application from the extracted JAR and adds the archive to JAVA_OPTS", func() {
+			container := containers.NewSpringBootContainer(ctx)
+			Expect(container.Detect()).To(Equal("Spring Boot"))
+			Expect(container.Finalize()).To(Succeed())
+
+			extracted := filepath.Join(buildDir, ".java-buildpack", "app_cds")
+			extractArgs, err := os.ReadFile(filepath.Join(buildDir, "extract-args"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(extractArgs)).To(Equal("-Djarmode=tools -jar " + filepath.Join(buildDir, "app.jar") + " extract --destination " + extracted + "\n"))
+
+			Expect(archive()).To(BeAnExistingFile())
+			args, err := os.ReadFile(filepath.Join(buildDir, "java-args"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(args)).To(ContainSubstring("-XX:ArchiveClassesAtExit=" + archive() + " -Dspring.context.exit=onRefresh"))
+			Expect(string(args)).To(ContainSubstring("-cp " + filepath.Join(extracted, "app.jar") + " com.example.Main"))
+			Expect(readOpts()).To(Equal("-XX:SharedArchiveFile=/home/vcap/deps/0/app_cds/application.jsa"))
+
+			command, err := container.Release()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(command).To(Equal("eval exec $JAVA_HOME/bin/java $JAVA_OPTS -cp $HOME/.java-buildpack/app_cds/app.jar${CONTAINER_SECURITY_PROVIDER:+:$CONTAINER_SECURITY_PROVIDER} com.example.Main"))
+		})
+
+		It("trains with the JAVA_OPTS the application starts with", func() {
+			Expect(os.MkdirAll(filepath.Join(depsDir, "0", "java_opts"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(depsDir, "0", "java_opts", "05_jre.opts"), []byte("-XX:+UseG1GC -Dagent.home=$DEPS_DIR/0/agent"), 0644)).To(Succeed())
+			os.Setenv("JAVA_OPTS", "-Dapp.option=true")
+
+			container := containers.NewSpringBootContainer(ctx)
+			Expect(container.Detect()).To(Equal("Spring Boot"))
+			Expect(container.Finalize()).To(Succeed())
+
+			args, err := os.ReadFile(filepath.Join(buildDir, "java-args"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(args)).To(HavePrefix("-XX:+UseG1GC -Dagent.home=" + filepath.Join(depsDir, "0", "agent") + " -XX:ArchiveClassesAtExit="))
+			Expect(string(args)).NotTo(ContainSubstring("-Dapp.option=true"))
+
+			Expect(os.WriteFile(filepath.Join(depsDir, "0", "java_opts", "99_user_java_opts.opts"), []byte("$JAVA_OPTS"), 0644)).To(Succeed())
+			Expect(container.Finalize()).To(Succeed())
+			args, err = os.ReadFile(filepath.Join(buildDir, "java-args"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(args)).To(ContainSubstring("-Dapp.option=true -XX:ArchiveClassesAtExit="))
+		})
+
+		It("repacks an exploded JAR before extracting it", func() {
+			Expect(os.Remove(filepath.Join(buildDir, "app.jar"))).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(buildDir, "META-INF"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(buildDir, "META-INF", "MANIFEST.MF"), []byte("Manifest-Version: 1.0\nMain-Class: org.springframework.boot.loader.launch.JarLauncher\nStart-Class: com.example.App\nSpring-Boot-Version: 3.4.1\n"), 0644)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(buildDir, "BOOT-INF", "classes", "com", "example"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(buildDir, "BOOT-INF", "classes", "com", "example", "App.class"), []byte("class"), 0644)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(buildDir, "BOOT-INF", "lib"), 0755)).To(Succeed())
+			Expect(createJar(filepath.Join(buildDir, "BOOT-INF", "lib", "dependency.jar"), "Manifest-Version: 1.0\n")).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(buildDir, "org", "springframework", "boot", "loader", "launch"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(buildDir, "org", "springframework", "boot", "loader", "launch", "JarLauncher.class"), []byte("class"), 0644)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(buildDir, ".profile.d"), 0755)).To(Succeed())
+
+			container := containers.NewSpringBootContainer(ctx)
+			Expect(container.Detect()).To(Equal("Spring Boot"))
+			Expect(container.Finalize()).To(Succeed())
+
+			repacked, err := zip.OpenReader(filepath.Join(buildDir, ".java-buildpack", "app_cds", "application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			defer repacked.Close()
+
+			methods := map[string]uint16{}
+			for _, f := range repacked.File {
+				methods[f.Name] = f.Method
+			}
+			Expect(repacked.File[0].Name).To(Equal("META-INF/MANIFEST.MF"))
+			Expect(methods).To(HaveKeyWithValue("BOOT-INF/lib/dependency.jar", zip.Store))
+			Expect(methods).To(HaveKey("BOOT-INF/classes/com/example/App.class"))
+			Expect(methods).To(HaveKey("org/springframework/boot/loader/launch/JarLauncher.class"))
+			Expect(methods).NotTo(HaveKey(".profile.d/"))
+
+			args, err := os.ReadFile(filepath.Join(buildDir, "java-args"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(args)).To(ContainSubstring("-cp " + filepath.Join(buildDir, ".java-buildpack", "app_cds", "application.jar") + " org.springframework.boot.loader.launch.JarLauncher"))
+			Expect(string(args)).NotTo(ContainSubstring("$PWD/."))
+		})
+
+		It("trains without service bindings", func() {
+			os.Setenv("VCAP_SERVICES", `{"p.mysql": [{"credentials": {"password": "secret"}}]}`)
+			os.Setenv("DATABASE_PASSWORD", "secret")
+			defer os.Unsetenv("VCAP_SERVICES")
+			defer os.Unsetenv("DATABASE_PASSWORD")
+
+			container := containers.NewSpringBootContainer(ctx)
+			Expect(container.Detect()).To(Equal("Spring Boot"))
+			Expect(container.Finalize()).To(Succeed())
+
+			env, err := os.ReadFile(filepath.Join(buildDir, "java-env"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(env)).To(ContainSubstring("JAVA_HOME=" + javaHome))
+			Expect(string(env)).NotTo(ContainSubstring("secret"))
+		})
+
+		It("skips Spring Boot versions that do not exit after the context refresh", func() {
+			Expect(createJar(filepath.Join(buildDir, "app.jar"), "Manifest-Version: 1.0\nMain-Class: com.example.Main\nStart-Class: com.example.App\nSpring-Boot-Version: 3.2.5\n")).To(Succeed())
+
+			container := containers.NewSpringBootContainer(ctx)
+			Expect(container.Detect()).To(Equal("Spring Boot"))
+			Expect(container.Finalize()).To(Succeed())
+
+			Expect(filepath.Join(buildDir, "java-args")).NotTo(BeAnExistingFile())
+		})
+
+		It("does not train Java Main applications", func() {
+			os.Setenv("JBP_CONFIG_JAVA_MAIN", "{java_main_class: com.example.Main}")
+			defer os.Unsetenv("JBP_CONFIG_JAVA_MAIN")
+
+			container := containers.NewJavaMainContainer(ctx)
+			Expect(container.Detect()).To(Equal("Java Main"))
+			Expect(container.Finalize()).To(Succeed())
+
+			Expect(filepath.Join(buildDir, "java-args")).NotTo(BeAnExistingFile())
+		})
+
+		It("starts without an archive when the training run does not create one", func() {
+			Expect(os.WriteFile(filepath.Join(buildDir, "no-archive"), nil, 0644)).To(Succeed())
+
+			container := containers.NewSpringBootContainer(ctx)
+			Expect(container.Detect()).To(Equal("Spring Boot"))
+			Expect(container.Finalize()).To(Succeed())
+
+			Expect(filepath.Join(depsDir, "0", "java_opts", "07_app_cds.opts")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(buildDir, ".java-buildpack", "app_cds")).NotTo(BeADirectory())
+			command, err := container.Release()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(command).To(ContainSubstring("-jar $HOME/app.jar"))
+		})
+
+		It("skips Java versions that cannot relocate the archive", func() {
+			Expect(os.WriteFile(filepath.Join(javaHome, "release"), []byte("JAVA_VERSION=\"17.0.13\"\n"), 0644)).To(Succeed())
+
+			container := containers.NewSpringBootContainer(ctx)
+			Expect(container.Detect()).To(Equal("Spring Boot"))
+			Expect(container.Finalize()).To(Succeed())
+
+			Expect(filepath.Join(buildDir, "java-args")).NotTo(BeAnExistingFile())
+		})
+	})
+})
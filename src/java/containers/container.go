@@ -2,6 +2,7 @@ package containers
 
 import (
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/components"
 )
 
 // Container represents a Java application container (Tomcat, Spring Boot, etc.)
@@ -20,9 +21,32 @@ type Container interface {
 	Release() (string, error)
 }
 
+// RequiredArtifactsProvider optionally lists the files the start command of a container needs, e.g. $HOME/app.jar.
+// Paths may use variables that are set at runtime; the finalize phase checks them before the application starts.
+type RequiredArtifactsProvider interface {
+	RequiredArtifacts() []string
+}
+
+// PortBinding describes how the port assigned by the platform, $PORT, is passed to the application
+type PortBinding struct {
+	// SystemProperty is the JVM system property set to $PORT in JAVA_OPTS, e.g. http.port
+	SystemProperty string
+	// EnvironmentVariable is exported with the value of $PORT, e.g. SERVER_PORT
+	EnvironmentVariable string
+	// Argument is appended to the start command and contains $PORT, e.g. "-p $PORT"
+	Argument string
+}
+
+// PortConfigurer optionally declares how a container conveys $PORT to the application. The finalize phase writes
+// the system property and environment variable to profile.d/port.sh and appends the argument to the start command.
+type PortConfigurer interface {
+	PortBinding() PortBinding
+}
+
 // Registry manages available containers
 type Registry struct {
 	containers []Container
+	names      map[Container]string
 	context    *common.Context
 }
 
@@ -30,18 +54,32 @@ type Registry struct {
 func NewRegistry(ctx *common.Context) *Registry {
 	return &Registry{
 		containers: []Container{},
+		names:      map[Container]string{},
 		context:    ctx,
 	}
 }
 
-// Register adds a container to the registry
+// Register adds a container to the registry. JBP_CONFIG_COMPONENTS cannot exclude it.
 func (r *Registry) Register(c Container) {
 	r.containers = append(r.containers, c)
 }
 
+// RegisterAs adds a container that JBP_CONFIG_COMPONENTS includes or excludes by the given name, the class name of
+// the container in the Ruby buildpack, e.g. SpringBoot
+func (r *Registry) RegisterAs(name string, c Container) {
+	r.Register(c)
+	r.names[c] = name
+}
+
 // Detect finds the first container that can handle the application
+// Containers that JBP_CONFIG_COMPONENTS excludes, or does not include if it includes any, are not detected
 func (r *Registry) Detect() (Container, string, error) {
-	for _, container := range r.containers {
+	allowed, err := r.allowed()
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, container := range allowed {
 		name, err := container.Detect()
 		if err != nil {
 			// Propagate errors (e.g., validation failures)
@@ -59,7 +97,12 @@ func (r *Registry) DetectAll() ([]Container, []string, error) {
 	var matched []Container
 	var names []string
 
-	for _, container := range r.containers {
+	allowed, err := r.allowed()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, container := range allowed {
 		name, err := container.Detect()
 		if err != nil {
 			// Propagate errors (e.g., validation failures)
@@ -74,6 +117,53 @@ func (r *Registry) DetectAll() ([]Container, []string, error) {
 	return matched, names, nil
 }
 
+// Candidate is a registered container together with the name JBP_CONFIG_COMPONENTS lists it by
+type Candidate struct {
+	// Component is the name JBP_CONFIG_COMPONENTS lists the container by, empty for containers it cannot exclude
+	Component string
+	Container Container
+	// Allowed is false if JBP_CONFIG_COMPONENTS excludes the container, or does not include it if it includes any
+	Allowed bool
+}
+
+// Candidates returns the registered containers in detection order, whether JBP_CONFIG_COMPONENTS allows them or not
+func (r *Registry) Candidates() ([]Candidate, error) {
+	cfg, err := components.Load(r.context.Log)
+	if err != nil {
+		return nil, err
+	}
+	listed := components.NewSelection("container", cfg.Containers)
+
+	var known []string
+	candidates := make([]Candidate, 0, len(r.containers))
+	for _, container := range r.containers {
+		name, ok := r.names[container]
+		if ok {
+			known = append(known, name)
+		}
+		candidates = append(candidates, Candidate{Component: name, Container: container, Allowed: !ok || listed.Allows(name)})
+	}
+	return candidates, listed.Validate(known)
+}
+
+// allowed returns the registered containers that the containers list of JBP_CONFIG_COMPONENTS allows
+func (r *Registry) allowed() ([]Container, error) {
+	candidates, err := r.Candidates()
+	if err != nil {
+		return nil, err
+	}
+
+	var allowed []Container
+	for _, candidate := range candidates {
+		if !candidate.Allowed {
+			r.context.Log.Debug("Container %s is not detected: JBP_CONFIG_COMPONENTS does not allow it", candidate.Component)
+			continue
+		}
+		allowed = append(allowed, candidate.Container)
+	}
+	return allowed, nil
+}
+
 // Get returns the container whose Detect() returns the given name, or nil if not found.
 // Used by the finalize phase to resolve a container by the name stored in config.yml.
 func (r *Registry) Get(name string) Container {
@@ -90,6 +180,7 @@ func (r *Registry) Get(name string) Container {
 // This ensures Supply and Finalize phases use the same detection order.
 // IMPORTANT: The order matters! Containers are checked in registration order.
 // More specific containers (with stricter detection rules) must come before generic ones.
+// Containers are registered with their Ruby buildpack class names, which JBP_CONFIG_COMPONENTS lists.
 func (r *Registry) RegisterStandardContainers() {
 	// Priority order (most specific to least specific):
 	// 1. Spring Boot - checks for BOOT-INF or Spring Boot JAR markers
@@ -99,13 +190,28 @@ func (r *Registry) RegisterStandardContainers() {
 	// 5. Play - checks for Play Framework structure
 	// 6. DistZip - checks for bin/ and lib/ directories
 	// 7. JavaMain - checks for executable JAR with Main-Class manifest entry
-	r.Register(NewSpringBootContainer(r.context))
-	r.Register(NewSpringBootCLIContainer(r.context))
-	r.Register(NewTomcatContainer(r.context))
-	r.Register(NewGroovyContainer(r.context))
-	r.Register(NewPlayContainer(r.context))
-	r.Register(NewDistZipContainer(r.context))
-	r.Register(NewJavaMainContainer(r.context))
+	r.RegisterAs("SpringBoot", NewSpringBootContainer(r.context))
+	r.RegisterAs("SpringBootCLI", NewSpringBootCLIContainer(r.context))
+	r.RegisterAs("Tomcat", NewTomcatContainer(r.context))
+	r.RegisterAs("Groovy", NewGroovyContainer(r.context))
+	r.RegisterAs("PlayFramework", NewPlayContainer(r.context))
+	r.RegisterAs("DistZip", NewDistZipContainer(r.context))
+	r.RegisterAs("JavaMain", NewJavaMainContainer(r.context))
+}
+
+// runtimeClasspath converts the staging paths of libraries, e.g. /tmp/deps/1/new_relic_agent/newrelic.jar, to their
+// runtime paths, e.g. $DEPS_DIR/1/new_relic_agent/newrelic.jar. Paths outside the droplet are used as they are.
+func runtimeClasspath(ctx *common.Context, libs []string) []string {
+	classpath := make([]string, 0, len(libs))
+	for _, lib := range libs {
+		runtimePath, err := ctx.Droplet().RuntimePath(lib)
+		if err != nil {
+			ctx.Log.Warning("Library path %s doesn't match deps or build directory, using as-is", lib)
+			runtimePath = lib
+		}
+		classpath = append(classpath, runtimePath)
+	}
+	return classpath
 }
 
 // This script is used to process the CLASSPATH assembled from various framework scripts sourced from profile.d
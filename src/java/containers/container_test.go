@@ -125,4 +125,38 @@ var _ = Describe("Container Registry", func() {
 		})
 	})
 
+	Describe("JBP_CONFIG_COMPONENTS", func() {
+		BeforeEach(func() {
+			os.WriteFile(filepath.Join(buildDir, "app.groovy"), []byte("println 'hello'"), 0644)
+			os.MkdirAll(filepath.Join(buildDir, "WEB-INF"), 0755)
+			registry.RegisterStandardContainers()
+		})
+
+		AfterEach(func() {
+			os.Unsetenv("JBP_CONFIG_COMPONENTS")
+		})
+
+		It("does not detect excluded containers", func() {
+			os.Setenv("JBP_CONFIG_COMPONENTS", `{containers: ["-JavaBuildpack::Container::Tomcat"]}`)
+
+			_, name, err := registry.Detect()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("Groovy"))
+		})
+
+		It("detects only the included containers if any are listed", func() {
+			os.Setenv("JBP_CONFIG_COMPONENTS", `{containers: ["Groovy"]}`)
+
+			_, names, err := registry.DetectAll()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(names).To(Equal([]string{"Groovy"}))
+		})
+
+		It("rejects unknown containers", func() {
+			os.Setenv("JBP_CONFIG_COMPONENTS", `{containers: ["Jetty"]}`)
+
+			_, _, err := registry.Detect()
+			Expect(err).To(MatchError(ContainSubstring(`unknown container component "Jetty" in JBP_CONFIG_COMPONENTS`)))
+		})
+	})
 })
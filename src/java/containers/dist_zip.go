@@ -30,6 +30,7 @@ func (d *DistZipContainer) Detect() (string, error) {
 	}
 
 	if len(matches) == 0 {
+		d.context.Log.Debug("Dist ZIP: no bin/ directory with a start script next to a lib/ directory")
 		return "", nil
 	}
 
@@ -125,6 +126,7 @@ func (d *DistZipContainer) isPlayFramework(libDir string) bool {
 	}
 
 	// Check for Play Framework JAR patterns:
+	// - org.playframework.play_*.jar (Play 3.0+)
 	// - com.typesafe.play.play_*.jar (Play 2.2+)
 	// - play.play_*.jar (Play 2.0)
 	// - play_*.jar (Play 2.1)
@@ -133,7 +135,8 @@ func (d *DistZipContainer) isPlayFramework(libDir string) bool {
 			continue
 		}
 		name := entry.Name()
-		if strings.Contains(name, "com.typesafe.play.play_") ||
+		if strings.Contains(name, "org.playframework.play_") ||
+			strings.Contains(name, "com.typesafe.play.play_") ||
 			strings.HasPrefix(name, "play.play_") ||
 			(strings.HasPrefix(name, "play_") && strings.HasSuffix(name, ".jar")) {
 			return true
@@ -212,24 +215,24 @@ func (d *DistZipContainer) Finalize() error {
 
 	// Build CLASSPATH from additional libraries
 	// Convert staging paths to runtime paths
-	classpathParts := d.buildRuntimeClasspath(additionalLibs)
+	classpathParts := runtimeClasspath(d.context, additionalLibs)
 
 	// Write profile.d script that sets up environment variables
 	// This follows the immutable BuildDir pattern: configure via environment, don't modify files
-	envContent := fmt.Sprintf(`export DEPS_DIR=${DEPS_DIR:-/home/vcap/deps}
+	envContent := fmt.Sprintf(`export DEPS_DIR=${DEPS_DIR:-%s}
 export DIST_ZIP_HOME=$HOME
-export DIST_ZIP_BIN=$HOME/%s
+export DIST_ZIP_BIN=%s
 export PATH=$DIST_ZIP_BIN:$PATH
 
 # Prepend additional libraries to CLASSPATH
 # Most distZip scripts respect CLASSPATH environment variable
 # This includes JVMKill agent, framework JARs, JDBC drivers, etc.
-`, scriptDir)
+`, common.RuntimeDepsDir(), d.context.Droplet().App(scriptDir))
 
 	// Add CLASSPATH if we have additional libraries
 	if len(classpathParts) > 0 {
 		classpathValue := strings.Join(classpathParts, ":")
-		envContent += fmt.Sprintf("export CLASSPATH=\"%s:${CLASSPATH:-}\"\n", classpathValue)
+		envContent += fmt.Sprintf("export CLASSPATH=\"%s${CLASSPATH:+:$CLASSPATH}\"\n", classpathValue)
 		d.context.Log.Info("Configured CLASSPATH with %d additional libraries", len(classpathParts))
 	}
 
@@ -246,8 +249,9 @@ export PATH=$DIST_ZIP_BIN:$PATH
 		"-XX:+ExitOnOutOfMemoryError",
 	}
 
-	// Most distZip scripts respect JAVA_OPTS environment variable
-	javaOptsScript := fmt.Sprintf("export JAVA_OPTS=\"%s\"\n", strings.Join(javaOpts, " "))
+	// Most distZip scripts respect JAVA_OPTS environment variable. Append to the value assembled by
+	// 00_java_opts.sh so framework and user options are kept
+	javaOptsScript := fmt.Sprintf("export JAVA_OPTS=\"${JAVA_OPTS:+$JAVA_OPTS }%s\"\n", strings.Join(javaOpts, " "))
 	if err := d.context.Stager.WriteProfileD("dist_zip_java_opts.sh", javaOptsScript); err != nil {
 		return fmt.Errorf("failed to write JAVA_OPTS profile.d script: %w", err)
 	}
@@ -256,46 +260,6 @@ export PATH=$DIST_ZIP_BIN:$PATH
 	return nil
 }
 
-// buildRuntimeClasspath converts staging library paths to runtime paths for CLASSPATH
-func (d *DistZipContainer) buildRuntimeClasspath(libs []string) []string {
-	depsDir := d.context.Stager.DepDir()
-	buildDir := d.context.Stager.BuildDir()
-	depsIdx := d.context.Stager.DepsIdx()
-	var classpathParts []string
-
-	for _, lib := range libs {
-		var runtimePath string
-
-		// Check if library is in deps directory (e.g., framework JARs, agents)
-		if strings.HasPrefix(lib, depsDir) {
-			// Convert staging absolute path to runtime path
-			// Staging: /tmp/staging/deps/<idx>/new_relic_agent/newrelic.jar
-			// Runtime: $DEPS_DIR/<idx>/new_relic_agent/newrelic.jar
-			relPath := strings.TrimPrefix(lib, depsDir)
-			relPath = strings.TrimPrefix(relPath, "/") // Remove leading slash
-			relPath = filepath.ToSlash(relPath)        // Normalize slashes
-			runtimePath = fmt.Sprintf("$DEPS_DIR/%s/%s", depsIdx, relPath)
-		} else if strings.HasPrefix(lib, buildDir) {
-			// Library is in build directory (unlikely for additional libs, but handle it)
-			relPath, err := filepath.Rel(buildDir, lib)
-			if err != nil {
-				d.context.Log.Warning("Could not calculate relative path for %s: %s", lib, err.Error())
-				continue
-			}
-			relPath = filepath.ToSlash(relPath)
-			runtimePath = fmt.Sprintf("$HOME/%s", relPath)
-		} else {
-			// Fallback: library path doesn't match expected patterns
-			d.context.Log.Warning("Library path %s doesn't match deps or build directory, using as-is", lib)
-			runtimePath = lib
-		}
-
-		classpathParts = append(classpathParts, runtimePath)
-	}
-
-	return classpathParts
-}
-
 // collectAdditionalLibraries gathers all additional libraries that should be added to CLASSPATH
 // This includes framework-provided JAR libraries installed during supply phase
 func (d *DistZipContainer) collectAdditionalLibraries() []string {
@@ -339,6 +303,14 @@ func (d *DistZipContainer) collectAdditionalLibraries() []string {
 	return libs
 }
 
+// RequiredArtifacts returns the start script of the distribution
+func (d *DistZipContainer) RequiredArtifacts() []string {
+	if d.startScript == "" {
+		return nil
+	}
+	return []string{d.context.Droplet().App(d.startScript)}
+}
+
 // Release returns the Dist ZIP startup command
 // Uses absolute path to ensure script is found at runtime
 func (d *DistZipContainer) Release() (string, error) {
@@ -350,8 +322,5 @@ func (d *DistZipContainer) Release() (string, error) {
 		}
 	}
 
-	scriptPath := filepath.ToSlash(d.startScript)
-	cmd := fmt.Sprintf("$HOME/%s", scriptPath)
-
-	return cmd, nil
+	return d.context.Droplet().App(d.startScript), nil
 }
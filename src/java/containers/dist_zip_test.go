@@ -211,5 +211,24 @@ var _ = Describe("Dist ZIP Container", func() {
 			Expect(string(content)).To(ContainSubstring("export JAVA_OPTS="))
 			Expect(string(content)).To(ContainSubstring("$TMPDIR"))
 		})
+
+		It("appends to JAVA_OPTS instead of replacing it", func() {
+			Expect(container.Finalize()).To(Succeed())
+			content, err := os.ReadFile(filepath.Join(depsDir, "0", "profile.d", "dist_zip_java_opts.sh"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(HavePrefix(`export JAVA_OPTS="${JAVA_OPTS:+$JAVA_OPTS }`))
+		})
+
+		It("adds JDBC drivers installed by frameworks to CLASSPATH", func() {
+			driverDir := filepath.Join(depsDir, "0", "oracle_jdbc")
+			Expect(os.MkdirAll(driverDir, 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(driverDir, "ojdbc11.jar"), []byte("jar"), 0644)).To(Succeed())
+
+			Expect(container.Finalize()).To(Succeed())
+			content, err := os.ReadFile(filepath.Join(depsDir, "0", "profile.d", "dist_zip.sh"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring(
+				`export CLASSPATH="$DEPS_DIR/0/oracle_jdbc/ojdbc11.jar${CLASSPATH:+:$CLASSPATH}"`))
+		})
 	})
 })
@@ -37,6 +37,7 @@ func (g *GroovyContainer) Detect() (string, error) {
 		return "Groovy", nil
 	}
 
+	g.context.Log.Debug("Groovy: no .groovy files")
 	return "", nil
 }
 
@@ -63,8 +64,7 @@ func (g *GroovyContainer) Supply() error {
 	g.context.Log.Info("Installed Groovy version %s", dep.Version)
 
 	// Write profile.d script to set GROOVY_HOME at runtime
-	depsIdx := g.context.Stager.DepsIdx()
-	groovyPath := fmt.Sprintf("$DEPS_DIR/%s/groovy", depsIdx)
+	groovyPath := g.context.Droplet().Dep("groovy")
 
 	envContent := fmt.Sprintf("export GROOVY_HOME=%s\n", groovyPath)
 	if err := g.context.Stager.WriteProfileD("groovy.sh", envContent); err != nil {
@@ -89,6 +89,11 @@ func (g *GroovyContainer) Finalize() error {
 	return nil
 }
 
+// RequiredArtifacts returns the Groovy launcher
+func (g *GroovyContainer) RequiredArtifacts() []string {
+	return []string{"$GROOVY_HOME/bin/groovy"}
+}
+
 // Release returns the Groovy startup command
 func (g *GroovyContainer) Release() (string, error) {
 	// Determine which script to run
@@ -148,7 +153,7 @@ func (g *GroovyContainer) buildClasspath() string {
 		if strings.HasSuffix(info.Name(), ".jar") {
 			rel, relErr := filepath.Rel(buildDir, path)
 			if relErr == nil {
-				jarPaths = append(jarPaths, "$HOME/"+filepath.ToSlash(rel))
+				jarPaths = append(jarPaths, g.context.Droplet().App(rel))
 			}
 		}
 		return nil
@@ -15,7 +15,10 @@ var (
 	pogoPattern       = regexp.MustCompile(`class\s+\w+[\s\w]*\{`)
 	shebangPattern    = regexp.MustCompile(`^#!`)
 	beansPattern      = regexp.MustCompile(`beans\s*\{`)
-	logbackPattern    = regexp.MustCompile(`ch/qos/logback/.*\.groovy$`)
+	// springPattern matches the annotations Spring Boot CLI scripts are written with and @Grab of Spring Boot
+	// starters, e.g. @Grab('spring-boot-starter-actuator')
+	springPattern  = regexp.MustCompile(`@(RestController|Controller|Component|Service|Repository|Configuration|SpringBootApplication|EnableAutoConfiguration)\b|@Grab\s*\([^)]*spring-boot`)
+	logbackPattern = regexp.MustCompile(`ch/qos/logback/.*\.groovy$`)
 )
 
 // GroovyUtils struct provides instance methods for SpringBootCLI compatibility
@@ -77,6 +80,15 @@ func (g *GroovyUtils) IsBeans(filePath string) bool {
 	return beansPattern.Match(content)
 }
 
+// UsesSpring checks if a Groovy file uses Spring annotations or grabs a Spring Boot dependency
+func (g *GroovyUtils) UsesSpring(filePath string) bool {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return false
+	}
+	return springPattern.Match(content)
+}
+
 // isValidGroovyFile checks if a file is a valid, readable Groovy script
 // Filters out binary files, empty files, and files with invalid content
 func isValidGroovyFile(filePath string) bool {
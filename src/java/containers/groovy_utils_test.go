@@ -112,6 +112,23 @@ println 'Hello'`, false),
 			Entry("no beans block", `class Alpha {}`, false),
 		)
 	})
+
+	Describe("UsesSpring", func() {
+		DescribeTable("detecting Spring Boot CLI scripts",
+			func(content string, expected bool) {
+				Expect(g.UsesSpring(groovyFile(content))).To(Equal(expected))
+			},
+			Entry("has a Spring annotation", `@RestController
+class Hello {}`, true),
+			Entry("grabs a Spring Boot starter", `@Grab('spring-boot-starter-actuator')
+import groovy.transform.Field`, true),
+			Entry("grabs a Spring Boot starter by coordinates", `@Grab(group = 'org.springframework.boot', module = 'spring-boot-starter-web')
+import groovy.transform.Field`, true),
+			Entry("grabs another dependency", `@Grab('org.apache.commons:commons-lang3:3.14.0')
+println 'Hello'`, false),
+			Entry("plain script", `println 'Hello'`, false),
+		)
+	})
 })
 
 var _ = Describe("FindMainGroovyScript", func() {
@@ -1,14 +1,15 @@
 package containers
 
 import (
-	"archive/zip"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"unicode"
 
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/jarutil"
 )
 
 // JavaMainContainer handles standalone JAR applications with a main class
@@ -18,6 +19,16 @@ type JavaMainContainer struct {
 	jarFile   string
 }
 
+// javaMainConfig is the java_main configuration in config/java_main.yml and JBP_CONFIG_JAVA_MAIN
+type javaMainConfig struct {
+	// JavaMainClass overrides the Main-Class of the application's manifest
+	JavaMainClass string `yaml:"java_main_class"`
+	// Arguments are passed to the main class, interpreted with shell quoting rules, e.g. --port $PORT --name 'a b'
+	Arguments string `yaml:"arguments"`
+	// MainArtifact names the JAR to run when the application root contains several, e.g. app.jar
+	MainArtifact string `yaml:"main_artifact"`
+}
+
 // NewJavaMainContainer creates a new Java Main container
 func NewJavaMainContainer(ctx *common.Context) *JavaMainContainer {
 	return &JavaMainContainer{
@@ -29,9 +40,17 @@ func NewJavaMainContainer(ctx *common.Context) *JavaMainContainer {
 func (j *JavaMainContainer) Detect() (string, error) {
 	buildDir := j.context.Stager.BuildDir()
 
+	cfg, err := j.loadConfig()
+	if err != nil {
+		return "", err
+	}
+
 	// Look for JAR files with Main-Class manifest
-	mainClass, jarFile := j.findMainClass(buildDir)
-	if mainClass != "" {
+	mainClass, jarFile, err := j.findMainClass(buildDir, cfg)
+	if err != nil {
+		return "", err
+	}
+	if jarFile != "" {
 		j.mainClass = mainClass
 		j.jarFile = jarFile
 		j.context.Log.Debug("Detected Java Main application: %s (main: %s)", jarFile, mainClass)
@@ -56,101 +75,57 @@ func (j *JavaMainContainer) Detect() (string, error) {
 		return "Java Main", nil
 	}
 
+	// Check for a main class configured in JBP_CONFIG_JAVA_MAIN
+	if cfg.JavaMainClass != "" {
+		j.context.Log.Debug("Detected Java Main application via java_main_class: %s", cfg.JavaMainClass)
+		return "Java Main", nil
+	}
+
+	j.context.Log.Debug("Java Main: no JAR or META-INF/MANIFEST.MF with a Main-Class, no .class files and no java_main_class in JBP_CONFIG_JAVA_MAIN")
 	return "", nil
 }
 
-// findMainClass searches for a JAR in buildDir whose META-INF/MANIFEST.MF
-// contains a Main-Class entry. Returns the main class name and the path to
-// the JAR (relative to $HOME) if found, or empty strings if none qualify.
-func (j *JavaMainContainer) findMainClass(buildDir string) (string, string) {
-	entries, err := os.ReadDir(buildDir)
-	if err != nil {
-		return "", ""
+// loadConfig overlays config/java_main.yml and JBP_CONFIG_JAVA_MAIN
+func (j *JavaMainContainer) loadConfig() (javaMainConfig, error) {
+	cfg := javaMainConfig{}
+	if err := config.Load(j.context.Log, "java_main", &cfg); err != nil {
+		return javaMainConfig{}, err
 	}
-
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-
-		name := entry.Name()
-		if !strings.HasSuffix(name, ".jar") {
-			continue
-		}
-
-		jarPath := filepath.Join(buildDir, name)
-		if mainClass := readMainClassFromJar(jarPath); mainClass != "" {
-			return mainClass, filepath.Join("$HOME", name)
-		}
+	if strings.IndexFunc(cfg.JavaMainClass, unicode.IsSpace) != -1 {
+		return javaMainConfig{}, fmt.Errorf("java_main_class %q must not contain whitespace", cfg.JavaMainClass)
 	}
+	return cfg, nil
+}
 
-	return "", ""
+// findMainClass selects the JAR in buildDir whose META-INF/MANIFEST.MF contains a Main-Class entry, see
+// selectMainArtifact. A main_artifact without Main-Class is run with the configured java_main_class.
+// Returns the main class name and the path to the JAR (relative to $HOME), or empty strings if none qualify.
+func (j *JavaMainContainer) findMainClass(buildDir string, cfg javaMainConfig) (string, string, error) {
+	jarFile, err := selectMainArtifact(j.context, buildDir, cfg.MainArtifact, func(jarPath string) bool {
+		return readMainClassFromJar(jarPath) != "" || (cfg.MainArtifact != "" && cfg.JavaMainClass != "")
+	})
+	if err != nil || jarFile == "" {
+		return "", "", err
+	}
+	return readMainClassFromJar(filepath.Join(buildDir, filepath.Base(jarFile))), jarFile, nil
 }
 
-// readMainClassFromJar opens a JAR (zip) file and reads the Main-Class
-// attribute from META-INF/MANIFEST.MF, returning "" if not present or on error.
+// readMainClassFromJar reads the Main-Class attribute of the JAR's manifest, returning "" if not present or on error
 func readMainClassFromJar(jarPath string) string {
-	r, err := zip.OpenReader(jarPath)
+	manifest, err := jarutil.ReadManifest(jarPath)
 	if err != nil {
 		return ""
 	}
-	defer r.Close()
-
-	for _, f := range r.File {
-		if f.Name != "META-INF/MANIFEST.MF" {
-			continue
-		}
-
-		rc, err := f.Open()
-		if err != nil {
-			return ""
-		}
-
-		data, err := io.ReadAll(rc)
-		rc.Close()
-		if err != nil {
-			return ""
-		}
-
-		return parseMainClass(string(data))
-	}
-
-	return ""
+	return manifest.Get("Main-Class")
 }
 
 // readMainClassFromManifest reads the Main-Class from a manifest file
 func (j *JavaMainContainer) readMainClassFromManifest(manifestPath string) string {
-	data, err := os.ReadFile(manifestPath)
+	manifest, err := jarutil.ReadManifestFile(manifestPath)
 	if err != nil {
 		return ""
 	}
-
-	return parseMainClass(string(data))
-}
-
-// parseMainClass extracts the Main-Class value from MANIFEST.MF content.
-// Handles line continuations (lines starting with a space are folded onto the previous line).
-func parseMainClass(content string) string {
-	// Unfold continuation lines (space at start of line means continuation)
-	content = strings.ReplaceAll(content, "\r\n", "\n")
-	var unfolded strings.Builder
-	for _, line := range strings.Split(content, "\n") {
-		if strings.HasPrefix(line, " ") {
-			unfolded.WriteString(strings.TrimPrefix(line, " "))
-		} else {
-			unfolded.WriteString("\n")
-			unfolded.WriteString(line)
-		}
-	}
-
-	for _, line := range strings.Split(unfolded.String(), "\n") {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "Main-Class:") {
-			return strings.TrimSpace(strings.TrimPrefix(line, "Main-Class:"))
-		}
-	}
-
-	return ""
+	return manifest.Get("Main-Class")
 }
 
 // Supply installs Java Main dependencies
@@ -203,12 +178,12 @@ func (j *JavaMainContainer) buildClasspath() (string, error) {
 	// Even if it's not a Spring Boot app, we need to include these paths
 	bootInfClasses := filepath.Join(buildDir, "BOOT-INF", "classes")
 	if _, err := os.Stat(bootInfClasses); err == nil {
-		classpathEntries = append(classpathEntries, "$HOME/BOOT-INF/classes")
+		classpathEntries = append(classpathEntries, j.context.Droplet().App("BOOT-INF", "classes"))
 	}
 
 	bootInfLib := filepath.Join(buildDir, "BOOT-INF", "lib")
 	if _, err := os.Stat(bootInfLib); err == nil {
-		classpathEntries = append(classpathEntries, "$HOME/BOOT-INF/lib/*")
+		classpathEntries = append(classpathEntries, j.context.Droplet().App("BOOT-INF", "lib", "*"))
 	}
 
 	// Add all JARs in the build directory
@@ -222,30 +197,76 @@ func (j *JavaMainContainer) buildClasspath() (string, error) {
 	// Add lib directory if it exists
 	libDir := filepath.Join(buildDir, "lib")
 	if _, err := os.Stat(libDir); err == nil {
-		classpathEntries = append(classpathEntries, "$HOME/lib/*")
+		classpathEntries = append(classpathEntries, j.context.Droplet().App("lib", "*"))
 	}
 
 	return strings.Join(classpathEntries, ":"), nil
 }
 
+// RequiredArtifacts returns the JAR that runs the application, if it is run with java -jar or on its classpath
+func (j *JavaMainContainer) RequiredArtifacts() []string {
+	if j.jarFile == "" {
+		return nil
+	}
+	return []string{j.jarFile}
+}
+
 // Release returns the Java Main startup command
 func (j *JavaMainContainer) Release() (string, error) {
-	if j.jarFile != "" {
+	cfg, err := j.loadConfig()
+	if err != nil {
+		return "", err
+	}
+
+	var command string
+	if j.jarFile != "" && cfg.JavaMainClass == "" {
 		// JAR has its own Main-Class in the manifest — java -jar handles it
 		// Use eval to properly handle backslash-escaped values in $JAVA_OPTS (Ruby buildpack parity)
-		return fmt.Sprintf("eval exec $JAVA_HOME/bin/java $JAVA_OPTS -jar %s", j.jarFile), nil
+		command = fmt.Sprintf("eval exec $JAVA_HOME/bin/java $JAVA_OPTS -jar %s", j.jarFile)
+	} else {
+		// Classpath mode: need an explicit main class
+		mainClass := cfg.JavaMainClass
+		if mainClass != "" {
+			j.context.Log.Debug("Main Class %s found in java_main_class", mainClass)
+		} else if mainClass = j.mainClass; mainClass == "" {
+			mainClass = os.Getenv("JAVA_MAIN_CLASS")
+			if mainClass == "" {
+				return "", fmt.Errorf("no main class specified (set JAVA_MAIN_CLASS)")
+			}
+			j.context.Log.Debug("Main Class %s found in JAVA_MAIN_CLASS", mainClass)
+		}
+
+		// A configured main class may live in the detected JAR
+		classpath := "${CLASSPATH}${CONTAINER_SECURITY_PROVIDER:+:$CONTAINER_SECURITY_PROVIDER}"
+		if j.jarFile != "" {
+			classpath = j.jarFile + ":" + classpath
+		}
+
+		// Use eval to properly handle backslash-escaped values in $JAVA_OPTS (Ruby buildpack parity)
+		command = fmt.Sprintf("eval exec $JAVA_HOME/bin/java $JAVA_OPTS -cp %s %s", classpath, mainClass)
+	}
+
+	if cfg.Arguments != "" {
+		command += " " + escapeForEval(cfg.Arguments)
 	}
+	return command, nil
+}
 
-	// Classpath mode: need an explicit main class
-	mainClass := j.mainClass
-	if mainClass == "" {
-		mainClass = os.Getenv("JAVA_MAIN_CLASS")
-		if mainClass == "" {
-			return "", fmt.Errorf("no main class specified (set JAVA_MAIN_CLASS)")
+// escapeForEval backslash-escapes the shell syntax in s, so that the start command passes it unchanged to eval,
+// which then splits and expands it like a shell command line, e.g. --name 'a b' is the two arguments --name and a b
+func escapeForEval(s string) string {
+	var escaped strings.Builder
+	for _, r := range strings.ReplaceAll(s, "\n", " ") {
+		if !isEvalSafeChar(r) {
+			escaped.WriteRune('\\')
 		}
-		j.context.Log.Debug("Main Class %s found in JAVA_MAIN_CLASS", mainClass)
+		escaped.WriteRune(r)
 	}
+	return escaped.String()
+}
 
-	// Use eval to properly handle backslash-escaped values in $JAVA_OPTS (Ruby buildpack parity)
-	return fmt.Sprintf("eval exec $JAVA_HOME/bin/java $JAVA_OPTS -cp ${CLASSPATH}${CONTAINER_SECURITY_PROVIDER:+:$CONTAINER_SECURITY_PROVIDER} %s", mainClass), nil
+// isEvalSafeChar returns true if r has no special meaning to the shell
+func isEvalSafeChar(r rune) bool {
+	return (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') ||
+		strings.ContainsRune("_-.,:/@=+%", r)
 }
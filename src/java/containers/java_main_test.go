@@ -4,7 +4,9 @@ import (
 	"archive/zip"
 	"bytes"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
 	"github.com/cloudfoundry/java-buildpack/src/java/containers"
@@ -151,6 +153,128 @@ var _ = Describe("Java Main Container", func() {
 				Expect(name).To(BeEmpty())
 			})
 		})
+
+		Context("with JAR declaring Main-Class only in a per-entry section", func() {
+			BeforeEach(func() {
+				Expect(createJar(
+					filepath.Join(buildDir, "lib.jar"),
+					"Manifest-Version: 1.0\r\n\r\nName: com/example/\r\nMain-Class: com.example.Main\r\n",
+				)).To(Succeed())
+			})
+
+			It("does not detect", func() {
+				name, err := container.Detect()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(name).To(BeEmpty())
+			})
+		})
+
+		Context("with several runnable JARs", func() {
+			BeforeEach(func() {
+				Expect(createJar(filepath.Join(buildDir, "admin.jar"),
+					"Manifest-Version: 1.0\nMain-Class: com.example.Admin\nImplementation-Title: admin\n")).To(Succeed())
+				Expect(createJar(filepath.Join(buildDir, "orders.jar"),
+					"Manifest-Version: 1.0\nMain-Class: com.example.Orders\nImplementation-Title: Orders\n")).To(Succeed())
+			})
+
+			AfterEach(func() {
+				os.Unsetenv("JBP_CONFIG_JAVA_MAIN")
+				os.Unsetenv("VCAP_APPLICATION")
+			})
+
+			release := func() string {
+				name, err := container.Detect()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(name).To(Equal("Java Main"))
+				command, err := container.Release()
+				Expect(err).NotTo(HaveOccurred())
+				return command
+			}
+
+			It("uses the first JAR by name", func() {
+				Expect(release()).To(HaveSuffix("-jar $HOME/admin.jar"))
+			})
+
+			It("uses the JAR whose Implementation-Title matches the application name", func() {
+				os.Setenv("VCAP_APPLICATION", `{"application_name": "orders"}`)
+				Expect(release()).To(HaveSuffix("-jar $HOME/orders.jar"))
+			})
+
+			It("uses the configured main_artifact", func() {
+				os.Setenv("VCAP_APPLICATION", `{"application_name": "orders"}`)
+				os.Setenv("JBP_CONFIG_JAVA_MAIN", "{main_artifact: admin.jar}")
+				Expect(release()).To(HaveSuffix("-jar $HOME/admin.jar"))
+			})
+
+			It("runs a main_artifact without Main-Class with the configured java_main_class", func() {
+				Expect(createJar(filepath.Join(buildDir, "tools.jar"), "Manifest-Version: 1.0\n")).To(Succeed())
+				os.Setenv("JBP_CONFIG_JAVA_MAIN", "{main_artifact: tools.jar, java_main_class: com.example.Tool}")
+				Expect(release()).To(ContainSubstring("-cp $HOME/tools.jar:${CLASSPATH}"))
+			})
+
+			It("fails when the main_artifact does not exist", func() {
+				os.Setenv("JBP_CONFIG_JAVA_MAIN", "{main_artifact: missing.jar}")
+				_, err := container.Detect()
+				Expect(err).To(MatchError(ContainSubstring(`main_artifact "missing.jar" not found`)))
+			})
+		})
+	})
+
+	Describe("MANIFEST.MF parsing", func() {
+		Context("with Main-Class wrapped onto continuation lines", func() {
+			BeforeEach(func() {
+				metaInfDir := filepath.Join(buildDir, "META-INF")
+				Expect(os.MkdirAll(metaInfDir, 0755)).To(Succeed())
+				manifest := "Manifest-Version: 1.0\r\nMain-Class: com.example.some.very.long.packa\r\n ge.name.Application\r\nCreated-By: test\r\n"
+				Expect(os.WriteFile(filepath.Join(metaInfDir, "MANIFEST.MF"), []byte(manifest), 0644)).To(Succeed())
+			})
+
+			It("uses the unfolded class name in the start command", func() {
+				_, err := container.Detect()
+				Expect(err).NotTo(HaveOccurred())
+
+				cmd, err := container.Release()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cmd).To(HaveSuffix(" com.example.some.very.long.package.name.Application"))
+			})
+		})
+
+		Context("with a lower case attribute name", func() {
+			BeforeEach(func() {
+				metaInfDir := filepath.Join(buildDir, "META-INF")
+				Expect(os.MkdirAll(metaInfDir, 0755)).To(Succeed())
+				manifest := "Manifest-Version: 1.0\nmain-class: com.example.Main\n"
+				Expect(os.WriteFile(filepath.Join(metaInfDir, "MANIFEST.MF"), []byte(manifest), 0644)).To(Succeed())
+			})
+
+			It("matches the attribute name case-insensitively", func() {
+				_, err := container.Detect()
+				Expect(err).NotTo(HaveOccurred())
+
+				cmd, err := container.Release()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cmd).To(HaveSuffix(" com.example.Main"))
+			})
+		})
+
+		Context("with a JAR whose Main-Class uses continuation lines", func() {
+			BeforeEach(func() {
+				Expect(createJar(
+					filepath.Join(buildDir, "app.jar"),
+					"Manifest-Version: 1.0\nMain-Class: com.example.Ma\n in\n",
+				)).To(Succeed())
+			})
+
+			It("launches the JAR that declares it", func() {
+				name, err := container.Detect()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(name).To(Equal("Java Main"))
+
+				cmd, err := container.Release()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cmd).To(ContainSubstring("-jar $HOME/app.jar"))
+			})
+		})
 	})
 
 	Describe("Release", func() {
@@ -197,6 +321,58 @@ var _ = Describe("Java Main Container", func() {
 			})
 		})
 
+		Context("with JBP_CONFIG_JAVA_MAIN", func() {
+			BeforeEach(func() {
+				Expect(createJar(
+					filepath.Join(buildDir, "app.jar"),
+					"Manifest-Version: 1.0\nMain-Class: com.example.Main\n",
+				)).To(Succeed())
+				container.Detect()
+			})
+
+			AfterEach(func() {
+				os.Unsetenv("JBP_CONFIG_JAVA_MAIN")
+			})
+
+			It("appends the arguments escaped for eval", func() {
+				os.Setenv("JBP_CONFIG_JAVA_MAIN", `{arguments: "--name 'a b' --port $PORT"}`)
+
+				cmd, err := container.Release()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cmd).To(Equal(`eval exec $JAVA_HOME/bin/java $JAVA_OPTS -jar $HOME/app.jar --name\ \'a\ b\'\ --port\ \$PORT`))
+
+				arguments := strings.TrimPrefix(cmd, "eval exec $JAVA_HOME/bin/java $JAVA_OPTS -jar $HOME/app.jar ")
+				output, err := exec.Command("bash", "-c", "PORT=8080; eval printf '[%s]' "+arguments).CombinedOutput()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(output)).To(Equal("[--name][a b][--port][8080]"))
+			})
+
+			It("launches the configured main class from the JAR's classpath", func() {
+				os.Setenv("JBP_CONFIG_JAVA_MAIN", "{java_main_class: com.example.Other, arguments: serve}")
+
+				cmd, err := container.Release()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cmd).To(ContainSubstring("-cp $HOME/app.jar:${CLASSPATH}"))
+				Expect(cmd).To(HaveSuffix(" com.example.Other serve"))
+			})
+
+			It("rejects a main class with whitespace", func() {
+				os.Setenv("JBP_CONFIG_JAVA_MAIN", "{java_main_class: 'com.example.Main -x'}")
+
+				_, err := container.Release()
+				Expect(err).To(MatchError(ContainSubstring("must not contain whitespace")))
+			})
+
+			It("detects an application with a configured main class", func() {
+				Expect(os.Remove(filepath.Join(buildDir, "app.jar"))).To(Succeed())
+				os.Setenv("JBP_CONFIG_JAVA_MAIN", "{java_main_class: com.example.Main}")
+
+				name, err := containers.NewJavaMainContainer(ctx).Detect()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(name).To(Equal("Java Main"))
+			})
+		})
+
 		Context("without main class or JAR", func() {
 			It("returns error", func() {
 				_, err := container.Release()
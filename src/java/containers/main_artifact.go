@@ -0,0 +1,98 @@
+package containers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/jarutil"
+)
+
+// configuredMainArtifact returns the main_artifact of the java_main configuration, which also selects the JAR
+// of a Spring Boot application. It is decoded without validation, which Java Main's loadConfig performs.
+func configuredMainArtifact() (string, error) {
+	cfg := struct {
+		MainArtifact string `yaml:"main_artifact"`
+	}{}
+	if err := config.Decode("java_main", &cfg); err != nil {
+		return "", err
+	}
+	return cfg.MainArtifact, nil
+}
+
+// selectMainArtifact chooses the JAR in buildDir that runs the application among the runnable JARs that
+// isCandidate accepts. A configured main_artifact wins; it returns "" if that JAR is not a candidate, so
+// that another container can run it. Otherwise a single candidate is used, or the candidate whose
+// Implementation-Title matches the application name. Remaining ties go to the first JAR by name, with
+// a warning. The result is relative to $HOME, e.g. $HOME/app.jar.
+func selectMainArtifact(ctx *common.Context, buildDir, mainArtifact string, isCandidate func(jarPath string) bool) (string, error) {
+	if mainArtifact != "" {
+		if strings.ContainsRune(mainArtifact, '/') {
+			return "", fmt.Errorf("main_artifact %q must be the name of a JAR in the application root", mainArtifact)
+		}
+		jarPath := filepath.Join(buildDir, mainArtifact)
+		if _, err := os.Stat(jarPath); err != nil {
+			return "", fmt.Errorf("main_artifact %q not found in the application root", mainArtifact)
+		}
+		if !isCandidate(jarPath) {
+			return "", nil
+		}
+		return ctx.Droplet().App(mainArtifact), nil
+	}
+
+	entries, err := os.ReadDir(buildDir)
+	if err != nil {
+		return "", err
+	}
+
+	// os.ReadDir sorts by name, so the candidates are in a deterministic order
+	var candidates []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".jar") {
+			continue
+		}
+		if isCandidate(filepath.Join(buildDir, entry.Name())) {
+			candidates = append(candidates, entry.Name())
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return "", nil
+	case 1:
+		return ctx.Droplet().App(candidates[0]), nil
+	}
+
+	if appName := applicationName(); appName != "" {
+		var matches []string
+		for _, candidate := range candidates {
+			manifest, err := jarutil.ReadManifest(filepath.Join(buildDir, candidate))
+			if err == nil && strings.EqualFold(strings.TrimSpace(manifest.Get("Implementation-Title")), appName) {
+				matches = append(matches, candidate)
+			}
+		}
+		if len(matches) == 1 {
+			ctx.Log.Debug("Selected %s, its Implementation-Title matches the application name %s", matches[0], appName)
+			return ctx.Droplet().App(matches[0]), nil
+		}
+	}
+
+	ctx.Log.Warning("Found several runnable JARs (%s), using %s. Set main_artifact in %s to choose another one.",
+		strings.Join(candidates, ", "), candidates[0], config.EnvVar("java_main"))
+	return ctx.Droplet().App(candidates[0]), nil
+}
+
+// applicationName returns the application name from VCAP_APPLICATION, or "" outside Cloud Foundry
+func applicationName() string {
+	vcapApp := struct {
+		ApplicationName string `json:"application_name"`
+	}{}
+	if err := json.Unmarshal([]byte(os.Getenv("VCAP_APPLICATION")), &vcapApp); err != nil {
+		return ""
+	}
+	return vcapApp.ApplicationName
+}
@@ -88,6 +88,7 @@ func (p *PlayContainer) Validate() error {
 
 // detectPost22Dist detects Play 2.2+ distributed applications
 // Structure: application-root/bin/<script>, application-root/lib/com.typesafe.play.play_*.jar
+// (org.playframework.play_*.jar for Play 3.0+)
 func (p *PlayContainer) detectPost22Dist(buildDir string) bool {
 	// Check for application-root/bin/ directory
 	binDir := filepath.Join(buildDir, "application-root", "bin")
@@ -103,7 +104,7 @@ func (p *PlayContainer) detectPost22Dist(buildDir string) bool {
 		return false
 	}
 
-	// Find Play JAR in lib/ (com.typesafe.play.play_*.jar)
+	// Find Play JAR in lib/ (com.typesafe.play.play_*.jar or org.playframework.play_*.jar)
 	playJar, version := p.findPlayJar(libDir)
 	if playJar == "" {
 		return false
@@ -129,7 +130,7 @@ func (p *PlayContainer) detectPost22Dist(buildDir string) bool {
 }
 
 // detectPost22Staged detects Play 2.2+ staged applications
-// Structure: lib/com.typesafe.play.play_*.jar (may or may not have bin/ with script)
+// Structure: lib/com.typesafe.play.play_*.jar or lib/org.playframework.play_*.jar (may or may not have bin/ with script)
 func (p *PlayContainer) detectPost22Staged(buildDir string) bool {
 	// Check for lib/ directory at root
 	libDir := filepath.Join(buildDir, "lib")
@@ -267,10 +268,11 @@ func (p *PlayContainer) findPlayJar(libDir string) (string, string) {
 	}
 
 	// Match patterns:
+	// - org.playframework.play_2.13-3.0.1.jar (Play 3.0+, Pekko based)
 	// - com.typesafe.play.play_2.10-2.2.0.jar (Play 2.2+)
 	// - play.play_2.9.1-2.0.jar (Play 2.0)
 	// - play_2.10-2.1.4.jar (Play 2.1)
-	playJarPattern := regexp.MustCompile(`^(?:com\.typesafe\.)?play(?:\.play)?_.*-(.+)\.jar$`)
+	playJarPattern := regexp.MustCompile(`^(?:com\.typesafe\.|org\.playframework\.)?play(?:\.play)?_.*-(.+)\.jar$`)
 
 	for _, entry := range entries {
 		if entry.IsDir() {
@@ -334,6 +336,14 @@ func (p *PlayContainer) isPost22Version(version string) bool {
 	return majorInt > 2
 }
 
+// isPlay3 checks if the detected version is 3.0 or higher. Play 3 is based on Pekko instead of Akka and starts with
+// play.core.server.ProdServerStart.
+func (p *PlayContainer) isPlay3() bool {
+	majorInt := 0
+	fmt.Sscanf(p.playVersion, "%d", &majorInt)
+	return majorInt >= 3
+}
+
 // Supply installs and configures the Play Framework application
 func (p *PlayContainer) Supply() error {
 	p.context.Log.BeginStep("Installing Play Framework %s (%s)", p.playVersion, p.playType)
@@ -371,7 +381,7 @@ func (p *PlayContainer) Finalize() error {
 
 	// Build CLASSPATH from additional libraries
 	// Convert staging paths to runtime paths
-	classpathParts := p.buildRuntimeClasspath(additionalLibs)
+	classpathParts := runtimeClasspath(p.context, additionalLibs)
 
 	// Determine the script directory based on Play type
 	var scriptDir string
@@ -390,20 +400,20 @@ func (p *PlayContainer) Finalize() error {
 
 	// Write profile.d script that sets up environment variables
 	// This follows the immutable BuildDir pattern: configure via environment, don't modify files
-	envContent := fmt.Sprintf(`export DEPS_DIR=${DEPS_DIR:-/home/vcap/deps}
+	envContent := fmt.Sprintf(`export DEPS_DIR=${DEPS_DIR:-%s}
 export PLAY_HOME=$HOME
-export PLAY_BIN=$HOME/%s
+export PLAY_BIN=%s
 export PATH=$PLAY_BIN:$PATH
 
 # Prepend additional libraries to CLASSPATH
 # Play start scripts respect CLASSPATH environment variable
 # This includes JVMKill agent, framework JARs, JDBC drivers, etc.
-`, scriptDir)
+`, common.RuntimeDepsDir(), p.context.Droplet().App(scriptDir))
 
 	// Add CLASSPATH if we have additional libraries
 	if len(classpathParts) > 0 {
 		classpathValue := strings.Join(classpathParts, ":")
-		envContent += fmt.Sprintf("export CLASSPATH=\"%s:${CLASSPATH:-}\"\n", classpathValue)
+		envContent += fmt.Sprintf("export CLASSPATH=\"%s${CLASSPATH:+:$CLASSPATH}\"\n", classpathValue)
 		p.context.Log.Info("Configured CLASSPATH with %d additional libraries", len(classpathParts))
 	}
 
@@ -414,16 +424,21 @@ export PATH=$PLAY_BIN:$PATH
 	}
 
 	// Configure JAVA_OPTS to be picked up by Play startup scripts
-	// Play uses -Dhttp.port system property to configure the HTTP port
+	// The HTTP port is added by the finalize phase, see PortBinding
 	// Note: JVMKill agent is configured by the JRE component via .profile.d/java_opts.sh
 	javaOpts := []string{
-		"-Dhttp.port=$PORT",
 		"-Djava.io.tmpdir=$TMPDIR",
 		"-XX:+ExitOnOutOfMemoryError",
 	}
+	if p.isPlay3() {
+		// Play 3 refuses to start if a RUNNING_PID file is left in the application root, e.g. pushed from a
+		// developer machine. The container is the only instance, so no PID file is needed.
+		javaOpts = append(javaOpts, "-Dpidfile.path=/dev/null")
+	}
 
-	// Play start scripts respect JAVA_OPTS environment variable
-	javaOptsScript := fmt.Sprintf("export JAVA_OPTS=\"%s\"\n", strings.Join(javaOpts, " "))
+	// Play start scripts respect JAVA_OPTS environment variable. Append to the value assembled by
+	// 00_java_opts.sh so framework and user options are kept
+	javaOptsScript := fmt.Sprintf("export JAVA_OPTS=\"${JAVA_OPTS:+$JAVA_OPTS }%s\"\n", strings.Join(javaOpts, " "))
 	if err := p.context.Stager.WriteProfileD("play_java_opts.sh", javaOptsScript); err != nil {
 		return fmt.Errorf("failed to write JAVA_OPTS profile.d script: %w", err)
 	}
@@ -483,43 +498,17 @@ func (p *PlayContainer) collectAdditionalLibraries() []string {
 	return libs
 }
 
-// buildRuntimeClasspath converts staging-time library paths to runtime paths.
-// At staging time, libraries are in $DEPS_DIR/<idx>/<framework>/*.jar
-// At runtime, they'll be in /home/vcap/deps/<idx>/<framework>/*.jar
-func (p *PlayContainer) buildRuntimeClasspath(libs []string) []string {
-	var classpathParts []string
-	allDepsDir := filepath.Dir(p.context.Stager.DepDir())
-	buildDir := p.context.Stager.BuildDir()
-
-	for _, lib := range libs {
-		var runtimePath string
-
-		if strings.HasPrefix(lib, allDepsDir) {
-			// e.g. /tmp/deps/1/new_relic_agent/newrelic.jar
-			// → relPath = 1/new_relic_agent/newrelic.jar
-			// → $DEPS_DIR/1/new_relic_agent/newrelic.jar
-			relPath, err := filepath.Rel(allDepsDir, lib)
-			if err != nil {
-				p.context.Log.Warning("Could not calculate relative path for %s: %s", lib, err.Error())
-				continue
-			}
-			runtimePath = fmt.Sprintf("$DEPS_DIR/%s", filepath.ToSlash(relPath))
-		} else if strings.HasPrefix(lib, buildDir) {
-			relPath, err := filepath.Rel(buildDir, lib)
-			if err != nil {
-				p.context.Log.Warning("Could not calculate relative path for %s: %s", lib, err.Error())
-				continue
-			}
-			runtimePath = fmt.Sprintf("$HOME/%s", filepath.ToSlash(relPath))
-		} else {
-			p.context.Log.Warning("Library path %s doesn't match deps or build directory, using as-is", lib)
-			runtimePath = lib
-		}
+// PortBinding passes $PORT to Play as the http.port system property
+func (p *PlayContainer) PortBinding() PortBinding {
+	return PortBinding{SystemProperty: "http.port"}
+}
 
-		classpathParts = append(classpathParts, runtimePath)
+// RequiredArtifacts returns the start script of the application, if it has one
+func (p *PlayContainer) RequiredArtifacts() []string {
+	if p.startScript == "" {
+		return nil
 	}
-
-	return classpathParts
+	return []string{p.context.Droplet().App(p.startScript)}
 }
 
 // Release returns the command to start the Play Framework application
@@ -536,7 +525,7 @@ func (p *PlayContainer) Release() (string, error) {
 	if p.startScript != "" {
 		// Use absolute path with $HOME prefix to ensure the script can be found at runtime
 		// Cloud Foundry sets $HOME to the application root directory
-		cmd = fmt.Sprintf("$HOME/%s", p.startScript)
+		cmd = p.context.Droplet().App(p.startScript)
 	} else {
 		// No start script - use java command with NettyServer
 		// This is for staged apps without start scripts
@@ -548,8 +537,13 @@ func (p *PlayContainer) Release() (string, error) {
 				libPath = filepath.ToSlash(relPath)
 			}
 		}
+		// ProdServerStart is the production entry point of Play 3 and starts the configured Pekko HTTP or Netty server
+		mainClass := "play.core.server.NettyServer"
+		if p.isPlay3() {
+			mainClass = "play.core.server.ProdServerStart"
+		}
 		// Use eval to properly handle backslash-escaped values in $JAVA_OPTS (Ruby buildpack parity)
-		cmd = fmt.Sprintf("eval exec java $JAVA_OPTS -cp $HOME/%s/* play.core.server.NettyServer $HOME", libPath)
+		cmd = fmt.Sprintf("eval exec java $JAVA_OPTS -cp %s %s $HOME", p.context.Droplet().App(libPath, "*"), mainClass)
 	}
 
 	p.context.Log.Debug("Play Framework release command: %s", cmd)
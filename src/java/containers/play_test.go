@@ -130,6 +130,41 @@ var _ = Describe("Play Container", func() {
 			})
 		})
 
+		Context("with Play 3 dist application (org.playframework path)", func() {
+			BeforeEach(func() {
+				os.MkdirAll(filepath.Join(buildDir, "application-root", "bin"), 0755)
+				os.WriteFile(filepath.Join(buildDir, "application-root", "bin", "myapp"), []byte("#!/bin/sh"), 0755)
+				os.MkdirAll(filepath.Join(buildDir, "application-root", "lib"), 0755)
+				os.WriteFile(filepath.Join(buildDir, "application-root", "lib", "org.playframework.play_3-3.0.5.jar"), []byte("fake"), 0644)
+				os.WriteFile(filepath.Join(buildDir, "application-root", "lib", "org.playframework.play-pekko-http-server_3-3.0.5.jar"), []byte("fake"), 0644)
+			})
+
+			It("detects as Play", func() {
+				name, err := container.Detect()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(name).To(Equal("Play"))
+			})
+
+			It("starts with the start script", func() {
+				_, err := container.Detect()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(container.Release()).To(Equal("$HOME/application-root/bin/myapp"))
+			})
+		})
+
+		Context("with Play 3 staged application (lib directory)", func() {
+			BeforeEach(func() {
+				os.MkdirAll(filepath.Join(buildDir, "lib"), 0755)
+				os.WriteFile(filepath.Join(buildDir, "lib", "org.playframework.play_2.13-3.0.5.jar"), []byte("fake"), 0644)
+			})
+
+			It("detects as Play", func() {
+				name, err := container.Detect()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(name).To(Equal("Play"))
+			})
+		})
+
 		Context("with non-Play application", func() {
 			BeforeEach(func() {
 				os.WriteFile(filepath.Join(buildDir, "app.jar"), []byte("fake"), 0644)
@@ -206,6 +241,21 @@ var _ = Describe("Play Container", func() {
 			})
 		})
 
+		Context("with Play 3 staged application", func() {
+			BeforeEach(func() {
+				os.MkdirAll(filepath.Join(buildDir, "lib"), 0755)
+				os.WriteFile(filepath.Join(buildDir, "lib", "org.playframework.play_3-3.0.5.jar"), []byte("fake"), 0644)
+				_, err := container.Detect()
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("starts ProdServerStart", func() {
+				cmd, err := container.Release()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cmd).To(Equal("eval exec java $JAVA_OPTS -cp $HOME/lib/* play.core.server.ProdServerStart $HOME"))
+			})
+		})
+
 		Context("when not detected", func() {
 			It("returns error", func() {
 				_, err := container.Release()
@@ -231,16 +281,49 @@ var _ = Describe("Play Container", func() {
 				Expect(err).NotTo(HaveOccurred())
 			})
 
-			It("writes profile.d script that exports JAVA_OPTS with $PORT and $TMPDIR", func() {
+			It("writes profile.d script that exports JAVA_OPTS with $TMPDIR", func() {
 				Expect(container.Finalize()).To(Succeed())
 				scriptPath := filepath.Join(depsDir, "0", "profile.d", "play_java_opts.sh")
 				Expect(scriptPath).To(BeAnExistingFile())
 				content, err := os.ReadFile(scriptPath)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(string(content)).To(ContainSubstring("export JAVA_OPTS="))
-				Expect(string(content)).To(ContainSubstring("$PORT"))
 				Expect(string(content)).To(ContainSubstring("$TMPDIR"))
 			})
+
+			It("binds to $PORT through the http.port system property", func() {
+				Expect(container.PortBinding()).To(Equal(containers.PortBinding{SystemProperty: "http.port"}))
+			})
+
+			It("appends to JAVA_OPTS instead of replacing it", func() {
+				Expect(container.Finalize()).To(Succeed())
+				content, err := os.ReadFile(filepath.Join(depsDir, "0", "profile.d", "play_java_opts.sh"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(HavePrefix(`export JAVA_OPTS="${JAVA_OPTS:+$JAVA_OPTS }`))
+			})
+
+			It("keeps the PID file of Play 2", func() {
+				Expect(container.Finalize()).To(Succeed())
+				content, err := os.ReadFile(filepath.Join(depsDir, "0", "profile.d", "play_java_opts.sh"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).NotTo(ContainSubstring("pidfile.path"))
+			})
+		})
+
+		Context("with detected Play 3 application", func() {
+			BeforeEach(func() {
+				os.MkdirAll(filepath.Join(buildDir, "lib"), 0755)
+				os.WriteFile(filepath.Join(buildDir, "lib", "org.playframework.play_3-3.0.5.jar"), []byte("fake"), 0644)
+				_, err := container.Detect()
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("disables the PID file", func() {
+				Expect(container.Finalize()).To(Succeed())
+				content, err := os.ReadFile(filepath.Join(depsDir, "0", "profile.d", "play_java_opts.sh"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(ContainSubstring(" -Dpidfile.path=/dev/null\""))
+			})
 		})
 	})
 })
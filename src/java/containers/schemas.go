@@ -0,0 +1,21 @@
+package containers
+
+import "github.com/cloudfoundry/java-buildpack/src/java/common/config"
+
+func init() {
+	config.RegisterSchema("app_cds", func() interface{} { return &appCDSConfig{} })
+	config.RegisterSchema("java_main", func() interface{} { return &javaMainConfig{} })
+	config.RegisterSchema("tomcat", func() interface{} { return &tomcatConfig{} })
+}
+
+// Check reports logging levels and client authentication settings that Supply rejects
+func (c *tomcatConfig) Check() []string {
+	var problems []string
+	if err := c.Logging.Validate(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if err := c.ClientAuth.Validate(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	return problems
+}
@@ -2,10 +2,12 @@ package containers
 
 import (
 	"fmt"
-	"github.com/cloudfoundry/java-buildpack/src/java/common"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/jarutil"
 )
 
 // SpringBootContainer handles Spring Boot JAR applications
@@ -40,7 +42,10 @@ func (s *SpringBootContainer) Detect() (string, error) {
 
 	// Check for Spring Boot JAR in root directory
 	jarFile, err := s.findSpringBootJar(buildDir)
-	if err == nil && jarFile != "" {
+	if err != nil {
+		return "", err
+	}
+	if jarFile != "" {
 		s.jarFile = jarFile
 		s.context.Log.Debug("Detected Spring Boot JAR: %s", jarFile)
 		return "Spring Boot", nil
@@ -58,42 +63,39 @@ func (s *SpringBootContainer) Detect() (string, error) {
 		}
 	}
 
+	s.context.Log.Debug("Spring Boot: no BOOT-INF directory with Spring Boot manifest markers, no Spring Boot JAR and no staged application with spring-boot-*.jar in lib/")
 	return "", nil
 }
 
-// findSpringBootJar looks for a Spring Boot JAR in the build directory
+// findSpringBootJar selects the Spring Boot JAR in the build directory, see selectMainArtifact
 func (s *SpringBootContainer) findSpringBootJar(buildDir string) (string, error) {
-	entries, err := os.ReadDir(buildDir)
+	mainArtifact, err := configuredMainArtifact()
 	if err != nil {
 		return "", err
 	}
+	return selectMainArtifact(s.context, buildDir, mainArtifact, s.isSpringBootJar)
+}
 
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-
-		name := entry.Name()
-		if strings.HasSuffix(name, ".jar") {
-			// Check if JAR has Spring Boot manifest
-			jarPath := filepath.Join(buildDir, name)
-			if s.isSpringBootJar(jarPath) {
-				return filepath.Join("$HOME", name), nil
-			}
-		}
+// isSpringBootJar checks if a JAR is a Spring Boot JAR by inspecting its MANIFEST.MF
+func (s *SpringBootContainer) isSpringBootJar(jarPath string) bool {
+	manifest, err := jarutil.ReadManifest(jarPath)
+	if err != nil {
+		s.context.Log.Debug("Could not read manifest of %s: %s", filepath.Base(jarPath), err.Error())
+		return false
 	}
-
-	return "", nil
+	return isSpringBootManifest(manifest)
 }
 
-// isSpringBootJar checks if a JAR is a Spring Boot JAR
-func (s *SpringBootContainer) isSpringBootJar(jarPath string) bool {
-	// TODO: In full implementation, we'd extract and check MANIFEST.MF
-	// For now, check file name patterns
-	name := filepath.Base(jarPath)
-	return strings.Contains(name, "spring") ||
-		strings.Contains(name, "boot") ||
-		strings.Contains(name, "BOOT-INF")
+// isSpringBootManifest checks for Spring Boot-specific manifest attributes:
+//   - Start-Class: The actual main class (Spring Boot specific)
+//   - Spring-Boot-Version: Spring Boot version
+//   - Spring-Boot-Classes: BOOT-INF/classes
+//   - Spring-Boot-Lib: BOOT-INF/lib
+func isSpringBootManifest(manifest jarutil.Manifest) bool {
+	return manifest.Has("Start-Class") ||
+		manifest.Has("Spring-Boot-Version") ||
+		manifest.Has("Spring-Boot-Classes") ||
+		manifest.Has("Spring-Boot-Lib")
 }
 
 // hasSpringBootInLib checks for staged Spring Boot applications (bin/ + lib/ with spring-boot-*.jar)
@@ -233,16 +235,105 @@ func (s *SpringBootContainer) Finalize() error {
 		return fmt.Errorf("failed to write JAVA_OPTS: %w", err)
 	}
 
-	// Ensure the app binds to CF's assigned port, overriding any server.port set in
-	// application.yml or other Spring config. Without this, apps with a hardcoded
-	// server.port will either bind to the wrong port (health check fails) or crash
-	// with java.net.BindException: Permission denied for privileged ports (< 1024).
-	// Uses WriteProfileD (not WriteEnvFile) so that $PORT is shell-expanded at runtime.
-	// Mirrors Ruby buildpack: lib/java_buildpack/container/spring_boot.rb release()
-	if err := s.context.Stager.WriteProfileD("spring_boot_server_port.sh", "export SERVER_PORT=$PORT\n"); err != nil {
-		return fmt.Errorf("failed to write SERVER_PORT profile.d script: %w", err)
+	s.logHealthCheckHint(buildDir)
+
+	return trainAppCDS(s.context, s.springBootVersion(buildDir), s.jarFile)
+}
+
+// springBootVersion returns the Spring-Boot-Version of the JAR's or the exploded application's MANIFEST.MF or, for
+// staged applications, the version of spring-boot-<version>.jar in lib/. It is empty if the version cannot be determined.
+func (s *SpringBootContainer) springBootVersion(buildDir string) string {
+	manifest, err := s.readManifestFile(buildDir)
+	if s.jarFile != "" {
+		manifest, err = jarutil.ReadManifest(filepath.Join(buildDir, filepath.Base(s.jarFile)))
+	}
+	if err == nil && manifest.Get("Spring-Boot-Version") != "" {
+		return manifest.Get("Spring-Boot-Version")
+	}
+	if jar, ok := s.context.Classpath().Find("spring-boot-[0-9]*.jar"); ok {
+		return strings.TrimSuffix(strings.TrimPrefix(filepath.Base(jar), "spring-boot-"), ".jar")
+	}
+	return ""
+}
+
+// logHealthCheckHint suggests HTTP health checks against the actuator health endpoint when the
+// application ships Spring Boot Actuator. The release output only carries process types, so the
+// hint is logged as manifest attributes the user can adopt.
+func (s *SpringBootContainer) logHealthCheckHint(buildDir string) {
+	actuatorJar := s.findActuatorJar(buildDir)
+	if actuatorJar == "" {
+		return
+	}
+
+	// Spring Boot 1.x serves actuator endpoints at the root path
+	healthPath := "/actuator/health"
+	if strings.HasPrefix(strings.TrimPrefix(filepath.Base(actuatorJar), "spring-boot-actuator-"), "1.") {
+		healthPath = "/health"
+	}
+
+	s.context.Log.Info("Spring Boot Actuator detected (%s). Suggested health checks for the application manifest:", filepath.Base(actuatorJar))
+	s.context.Log.Info("  health-check-type: http")
+	s.context.Log.Info("  health-check-http-endpoint: %s", healthPath)
+	if healthPath == "/actuator/health" {
+		s.context.Log.Info("  readiness-health-check-type: http")
+		s.context.Log.Info("  readiness-health-check-http-endpoint: /actuator/health/readiness")
+		s.context.Log.Info("The readiness endpoint requires management.endpoint.health.probes.enabled=true")
+	}
+}
+
+// findActuatorJar returns the spring-boot-actuator JAR shipped by the application, or "" if there is none
+func (s *SpringBootContainer) findActuatorJar(buildDir string) string {
+	isActuator := func(name string) bool {
+		base := filepath.Base(name)
+		return strings.HasPrefix(base, "spring-boot-actuator-") && !strings.HasPrefix(base, "spring-boot-actuator-autoconfigure-") && strings.HasSuffix(base, ".jar")
+	}
+
+	for _, libDir := range []string{filepath.Join("BOOT-INF", "lib"), "lib"} {
+		entries, err := os.ReadDir(filepath.Join(buildDir, libDir))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if isActuator(entry.Name()) {
+				return entry.Name()
+			}
+		}
 	}
 
+	if s.jarFile != "" {
+		jarPath := filepath.Join(buildDir, filepath.Base(s.jarFile))
+		entry, err := jarutil.FindEntry(jarPath, func(name string) bool {
+			return strings.HasPrefix(name, "BOOT-INF/lib/") && isActuator(name)
+		})
+		if err != nil {
+			s.context.Log.Debug("Could not inspect %s for Spring Boot Actuator: %s", filepath.Base(jarPath), err.Error())
+		}
+		return entry
+	}
+
+	return ""
+}
+
+// PortBinding exports $PORT as SERVER_PORT, which overrides any server.port set in application.yml or other Spring
+// config. Without it, apps with a hardcoded server.port either bind to the wrong port (health check fails) or crash
+// with java.net.BindException: Permission denied for privileged ports (< 1024).
+// Mirrors Ruby buildpack: lib/java_buildpack/container/spring_boot.rb release()
+func (s *SpringBootContainer) PortBinding() PortBinding {
+	return PortBinding{EnvironmentVariable: "SERVER_PORT"}
+}
+
+// RequiredArtifacts returns the exploded BOOT-INF directory, the start script of a staged application or the
+// Spring Boot JAR, in the order Release chooses between them
+func (s *SpringBootContainer) RequiredArtifacts() []string {
+	if _, err := os.Stat(filepath.Join(s.context.Stager.BuildDir(), "BOOT-INF")); err == nil {
+		return []string{s.context.Droplet().App("BOOT-INF")}
+	}
+	if s.startScript != "" {
+		return []string{s.context.Droplet().App("bin", s.startScript)}
+	}
+	if s.jarFile != "" {
+		return []string{s.jarFile}
+	}
 	return nil
 }
 
@@ -250,6 +341,13 @@ func (s *SpringBootContainer) Finalize() error {
 func (s *SpringBootContainer) Release() (string, error) {
 	buildDir := s.context.Stager.BuildDir()
 
+	// An application trained for AppCDS starts from the JAR extracted for the training run
+	if appCDSArchived(s.context) {
+		if command, err := appCDSStartCommand(s.context); err == nil {
+			return command, nil
+		}
+	}
+
 	// Check if we have an exploded JAR (BOOT-INF directory)
 	bootInf := filepath.Join(buildDir, "BOOT-INF")
 	if _, err := os.Stat(bootInf); err == nil {
@@ -278,8 +376,7 @@ func (s *SpringBootContainer) Release() (string, error) {
 
 	// Check for staged Spring Boot app with startup script
 	if s.startScript != "" {
-		cmd := fmt.Sprintf("$HOME/bin/%s", s.startScript)
-		return cmd, nil
+		return s.context.Droplet().App("bin", s.startScript), nil
 	}
 
 	// Find the Spring Boot JAR
@@ -300,68 +397,37 @@ func (s *SpringBootContainer) Release() (string, error) {
 // isSpringBootExplodedJar checks if an exploded JAR is actually a Spring Boot application
 // by looking for Spring Boot-specific markers in MANIFEST.MF
 func (s *SpringBootContainer) isSpringBootExplodedJar(buildDir string) bool {
-	manifestData, err := s.readManifestFile(buildDir)
+	manifest, err := s.readManifestFile(buildDir)
 	if err != nil {
-		s.context.Log.Debug("Could not read MANIFEST.MF: %s", err.Error())
 		return false
 	}
 
-	// Parse MANIFEST.MF and look for Spring Boot markers
-	lines := strings.Split(manifestData, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-
-		// Check for Spring Boot-specific entries:
-		// - Start-Class: The actual main class (Spring Boot specific)
-		// - Spring-Boot-Version: Spring Boot version
-		// - Spring-Boot-Classes: BOOT-INF/classes
-		// - Spring-Boot-Lib: BOOT-INF/lib
-		if strings.HasPrefix(line, "Start-Class:") ||
-			strings.HasPrefix(line, "Spring-Boot-Version:") ||
-			strings.HasPrefix(line, "Spring-Boot-Classes:") ||
-			strings.HasPrefix(line, "Spring-Boot-Lib:") {
-			s.context.Log.Debug("Found Spring Boot marker in MANIFEST.MF: %s", line)
-			return true
-		}
+	if isSpringBootManifest(manifest) {
+		s.context.Log.Debug("Found Spring Boot markers in MANIFEST.MF")
+		return true
 	}
 
 	s.context.Log.Debug("No Spring Boot markers found in MANIFEST.MF - this is a plain exploded JAR")
 	return false
 }
 
-func (s *SpringBootContainer) readManifestFile(buildDir string) (string, error) {
-	manifestPath := filepath.Join(buildDir, "META-INF", "MANIFEST.MF")
-	data, err := os.ReadFile(manifestPath)
+func (s *SpringBootContainer) readManifestFile(buildDir string) (jarutil.Manifest, error) {
+	manifest, err := jarutil.ReadManifestFile(filepath.Join(buildDir, filepath.FromSlash(jarutil.ManifestPath)))
 	if err != nil {
 		s.context.Log.Debug("Could not read MANIFEST.MF: %s", err.Error())
-		return "", err
-	}
-	return string(data), nil
-}
-
-func (s *SpringBootContainer) readManifestField(manifest, field string) string {
-	// Parse MANIFEST.MF file (simple line-by-line parsing)
-	lines := strings.Split(manifest, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, field) {
-			fieldValue := strings.TrimSpace(strings.TrimPrefix(line, field))
-			s.context.Log.Debug("Found %s in MANIFEST.MF with value: %s", field, fieldValue)
-			return fieldValue
-		}
+		return nil, err
 	}
-
-	return ""
+	return manifest, nil
 }
 
 // readMainClassFromManifest reads the Main-Class entry from MANIFEST.MF
 func (s *SpringBootContainer) readMainClassFromManifest(buildDir string) (string, error) {
-	manifestData, err := s.readManifestFile(buildDir)
+	manifest, err := s.readManifestFile(buildDir)
 	if err != nil {
 		return "", err
 	}
 
-	return s.readManifestField(manifestData, "Main-Class:"), nil
+	return manifest.Get("Main-Class"), nil
 }
 
 // getLauncherClass returns the launcher class from manifest. If missing tries to determine correct JarLauncher class name
@@ -369,7 +435,7 @@ func (s *SpringBootContainer) readMainClassFromManifest(buildDir string) (string
 // Spring Boot 2.x uses: org.springframework.boot.loader.JarLauncher
 // Spring Boot 3.x uses: org.springframework.boot.loader.launch.JarLauncher
 func (s *SpringBootContainer) getLauncherClass(buildDir string) string {
-	manifestData, err := s.readManifestFile(buildDir)
+	manifest, err := s.readManifestFile(buildDir)
 	if err != nil {
 		s.context.Log.Debug("Could not read MANIFEST.MF for version detection: %s", err.Error())
 		// Default to Spring Boot 3.x (newer) launcher
@@ -377,12 +443,12 @@ func (s *SpringBootContainer) getLauncherClass(buildDir string) string {
 	}
 
 	// Return launcher class from manifest. If missing try using JarLauncher based on Spring-Boot-Version
-	mainClass := s.readManifestField(manifestData, "Main-Class:")
+	mainClass := manifest.Get("Main-Class")
 	if mainClass != "" {
 		return mainClass
 	}
 
-	springBootVersion := s.readManifestField(manifestData, "Spring-Boot-Version:")
+	springBootVersion := manifest.Get("Spring-Boot-Version")
 	if strings.HasPrefix(springBootVersion, "3.") {
 		return "org.springframework.boot.loader.launch.JarLauncher"
 	}
@@ -27,6 +27,12 @@ func NewSpringBootCLIContainer(ctx *common.Context) *SpringBootCLIContainer {
 func (s *SpringBootCLIContainer) Detect() (string, error) {
 	buildDir := s.context.Stager.BuildDir()
 
+	// Groovy files of a web application belong to Tomcat
+	if _, err := os.Stat(filepath.Join(buildDir, "WEB-INF")); err == nil {
+		s.context.Log.Debug("Spring Boot CLI: the application has a WEB-INF directory, Tomcat stages it")
+		return "", nil
+	}
+
 	// Find all Groovy files (excluding logback config files)
 	allGroovyFiles, err := s.groovyUtils.FindGroovyFiles(buildDir)
 	if err != nil {
@@ -42,21 +48,25 @@ func (s *SpringBootCLIContainer) Detect() (string, error) {
 
 	// Must have at least one Groovy file
 	if len(groovyFiles) == 0 {
+		s.context.Log.Debug("Spring Boot CLI: no .groovy files")
 		return "", nil
 	}
 
-	// All Groovy files must be POGO or beans configuration
+	// All Groovy files must be POGO, beans configuration or Spring scripts
 	if !s.allPOGOOrConfiguration(groovyFiles) {
+		s.context.Log.Debug("Spring Boot CLI: not all .groovy files are POGOs, beans configurations or Spring scripts")
 		return "", nil
 	}
 
 	// No Groovy file should have a main() method
 	if !s.noMainMethod(groovyFiles) {
+		s.context.Log.Debug("Spring Boot CLI: a .groovy file has a main method")
 		return "", nil
 	}
 
 	// No Groovy file should have a shebang
 	if !s.noShebang(groovyFiles) {
+		s.context.Log.Debug("Spring Boot CLI: a .groovy file has a shebang")
 		return "", nil
 	}
 
@@ -88,10 +98,7 @@ func (s *SpringBootCLIContainer) Supply() error {
 	s.context.Log.Info("Installed Spring Boot CLI version %s", dep.Version)
 
 	// Write profile.d script to set SPRING_BOOT_CLI_HOME at runtime
-	// At runtime, CF sets $DEPS_DIR (e.g., /home/vcap/deps) and makes dependencies available at $DEPS_DIR/<idx>/
-	depsIdx := s.context.Stager.DepsIdx()
-	envContent := fmt.Sprintf(`export SPRING_BOOT_CLI_HOME=$DEPS_DIR/%s/spring-boot-cli
-`, depsIdx)
+	envContent := fmt.Sprintf("export SPRING_BOOT_CLI_HOME=%s\n", s.context.Droplet().Dep("spring-boot-cli"))
 
 	if err := s.context.Stager.WriteProfileD("spring-boot-cli.sh", envContent); err != nil {
 		s.context.Log.Warning("Could not write spring-boot-cli.sh profile.d script: %s", err.Error())
@@ -106,19 +113,25 @@ func (s *SpringBootCLIContainer) Supply() error {
 func (s *SpringBootCLIContainer) Finalize() error {
 	s.context.Log.BeginStep("Finalizing Spring Boot CLI")
 
-	// $JAVA_OPTS and $PORT are runtime variables — WriteProfileD ensures they are
-	// expanded at container startup rather than stored as literal strings.
+	// $JAVA_OPTS is a runtime variable — WriteProfileD ensures it is
+	// expanded at container startup rather than stored as a literal string.
 	if err := s.context.Stager.WriteProfileD("spring_boot_cli_java_opts.sh", "export JAVA_OPTS=$JAVA_OPTS\n"); err != nil {
 		return fmt.Errorf("failed to write JAVA_OPTS profile.d script: %w", err)
 	}
 
-	if err := s.context.Stager.WriteProfileD("spring_boot_cli_server_port.sh", "export SERVER_PORT=$PORT\n"); err != nil {
-		return fmt.Errorf("failed to write SERVER_PORT profile.d script: %w", err)
-	}
-
 	return nil
 }
 
+// PortBinding exports $PORT as SERVER_PORT, which the Spring Boot application started by the CLI binds to
+func (s *SpringBootCLIContainer) PortBinding() PortBinding {
+	return PortBinding{EnvironmentVariable: "SERVER_PORT"}
+}
+
+// RequiredArtifacts returns the Spring Boot CLI launcher
+func (s *SpringBootCLIContainer) RequiredArtifacts() []string {
+	return []string{"$SPRING_BOOT_CLI_HOME/bin/spring"}
+}
+
 // Release returns the Spring Boot CLI startup command
 func (s *SpringBootCLIContainer) Release() (string, error) {
 	buildDir := s.context.Stager.BuildDir()
@@ -132,13 +145,13 @@ func (s *SpringBootCLIContainer) Release() (string, error) {
 	// Add additional libraries (if any)
 	additionalLibs := filepath.Join(buildDir, ".additional_libs")
 	if info, err := os.Stat(additionalLibs); err == nil && info.IsDir() {
-		classpathParts = append(classpathParts, "$HOME/.additional_libs/*")
+		classpathParts = append(classpathParts, s.context.Droplet().App(".additional_libs", "*"))
 	}
 
 	// Add root libraries (lib/ directory)
 	rootLibs := filepath.Join(buildDir, "lib")
 	if info, err := os.Stat(rootLibs); err == nil && info.IsDir() {
-		classpathParts = append(classpathParts, "$HOME/lib/*")
+		classpathParts = append(classpathParts, s.context.Droplet().App("lib", "*"))
 	}
 
 	classpath := "${CLASSPATH}${CONTAINER_SECURITY_PROVIDER:+:$CONTAINER_SECURITY_PROVIDER}"
@@ -178,11 +191,12 @@ func (s *SpringBootCLIContainer) Release() (string, error) {
 
 // Helper methods
 
-// allPOGOOrConfiguration checks if all Groovy files are POGO or beans configuration
+// allPOGOOrConfiguration checks if all Groovy files are POGO, beans configuration or scripts using Spring, which
+// Spring Boot CLI compiles into classes and grabs the dependencies of
 func (s *SpringBootCLIContainer) allPOGOOrConfiguration(files []string) bool {
 	for _, file := range files {
-		if !s.groovyUtils.IsPOGO(file) && !s.groovyUtils.IsBeans(file) {
-			s.context.Log.Debug("File %s is neither POGO nor beans configuration", file)
+		if !s.groovyUtils.IsPOGO(file) && !s.groovyUtils.IsBeans(file) && !s.groovyUtils.UsesSpring(file) {
+			s.context.Log.Debug("File %s is neither POGO, beans configuration nor a Spring script", file)
 			return false
 		}
 	}
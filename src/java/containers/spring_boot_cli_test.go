@@ -1,11 +1,8 @@
 package containers_test
 
 import (
-	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"strings"
 
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
 	"github.com/cloudfoundry/java-buildpack/src/java/containers"
@@ -60,23 +57,45 @@ var _ = Describe("Spring Boot CLI Container", func() {
 		os.RemoveAll(cacheDir)
 	})
 
+	writeGroovy := func(name, content string) {
+		Expect(os.MkdirAll(filepath.Dir(filepath.Join(buildDir, name)), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(buildDir, name), []byte(content), 0644)).To(Succeed())
+	}
+
+	Describe("Detect", func() {
+		It("detects classes using Spring annotations", func() {
+			writeGroovy("app.groovy", "@RestController\nclass Hello {\n  @RequestMapping('/')\n  String home() { 'Hello' }\n}\n")
+
+			Expect(container.Detect()).To(Equal("Spring Boot CLI"))
+		})
+
+		It("detects scripts grabbing Spring Boot starters", func() {
+			writeGroovy("app.groovy", "@Grab('spring-boot-starter-actuator')\nimport groovy.transform.Field\n")
+			writeGroovy("config/beans.groovy", "beans {\n  greeting(String, 'Hello')\n}\n")
+
+			Expect(container.Detect()).To(Equal("Spring Boot CLI"))
+			Expect(container.Release()).To(Equal("$SPRING_BOOT_CLI_HOME/bin/spring run -cp ${CLASSPATH}${CONTAINER_SECURITY_PROVIDER:+:$CONTAINER_SECURITY_PROVIDER} app.groovy config/beans.groovy"))
+		})
+
+		It("leaves plain scripts and scripts with a main method to the Groovy container", func() {
+			writeGroovy("app.groovy", "println 'Hello'\n")
+			Expect(container.Detect()).To(BeEmpty())
+
+			writeGroovy("app.groovy", "@RestController\nclass Hello {\n  static void main(String[] args) {}\n}\n")
+			Expect(container.Detect()).To(BeEmpty())
+		})
+
+		It("leaves web applications to Tomcat", func() {
+			writeGroovy("app.groovy", "@RestController\nclass Hello {}\n")
+			Expect(os.MkdirAll(filepath.Join(buildDir, "WEB-INF"), 0755)).To(Succeed())
+
+			Expect(container.Detect()).To(BeEmpty())
+		})
+	})
+
 	Describe("Finalize", func() {
-		It("writes a profile.d script that exports SERVER_PORT=$PORT so the variable is shell-expanded at runtime", func() {
-			err := container.Finalize()
-			Expect(err).NotTo(HaveOccurred())
-
-			profileScript := filepath.Join(depsDir, "0", "profile.d", "spring_boot_cli_server_port.sh")
-			data, err := os.ReadFile(profileScript)
-			Expect(err).NotTo(HaveOccurred())
-			Expect(string(data)).To(Equal("export SERVER_PORT=$PORT\n"))
-
-			// Verify $PORT is actually shell-expanded at runtime (not left as literal "$PORT").
-			// Simulates what CF's launcher does: source the profile.d script with PORT set in env.
-			cmd := exec.Command("bash", "-c", fmt.Sprintf("PORT=8080 . %s && echo $SERVER_PORT", profileScript))
-			out, bashErr := cmd.Output()
-			Expect(bashErr).NotTo(HaveOccurred())
-			Expect(strings.TrimSpace(string(out))).To(Equal("8080"),
-				"SERVER_PORT should be the expanded value of $PORT, not the literal string \"$PORT\"")
+		It("binds to $PORT through the SERVER_PORT environment variable", func() {
+			Expect(container.PortBinding()).To(Equal(containers.PortBinding{EnvironmentVariable: "SERVER_PORT"}))
 		})
 	})
 })
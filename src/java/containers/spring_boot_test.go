@@ -1,11 +1,11 @@
 package containers_test
 
 import (
+	"archive/zip"
+	"bytes"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"strings"
 
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
 	"github.com/cloudfoundry/java-buildpack/src/java/containers"
@@ -84,9 +84,12 @@ var _ = Describe("Spring Boot Container", func() {
 			})
 		})
 
-		Context("with spring-boot.jar", func() {
+		Context("with a JAR declaring Spring-Boot-Version", func() {
 			BeforeEach(func() {
-				os.WriteFile(filepath.Join(buildDir, "spring-boot.jar"), []byte("fake jar"), 0644)
+				Expect(createJar(
+					filepath.Join(buildDir, "app.jar"),
+					"Manifest-Version: 1.0\nMain-Class: org.springframework.boot.loader.launch.JarLauncher\nSpring-Boot-Version: 3.2.0\n",
+				)).To(Succeed())
 			})
 
 			It("detects as Spring Boot", func() {
@@ -96,9 +99,12 @@ var _ = Describe("Spring Boot Container", func() {
 			})
 		})
 
-		Context("with myapp-boot.jar", func() {
+		Context("with a JAR declaring only Start-Class", func() {
 			BeforeEach(func() {
-				os.WriteFile(filepath.Join(buildDir, "myapp-boot.jar"), []byte("fake jar"), 0644)
+				Expect(createJar(
+					filepath.Join(buildDir, "service.jar"),
+					"Manifest-Version: 1.0\nStart-Class: com.example.App\n",
+				)).To(Succeed())
 			})
 
 			It("detects as Spring Boot", func() {
@@ -108,6 +114,58 @@ var _ = Describe("Spring Boot Container", func() {
 			})
 		})
 
+		Context("with several Spring Boot JARs", func() {
+			BeforeEach(func() {
+				Expect(createJar(filepath.Join(buildDir, "gateway.jar"), "Manifest-Version: 1.0\nStart-Class: com.example.Gateway\n")).To(Succeed())
+				Expect(createJar(filepath.Join(buildDir, "service.jar"), "Manifest-Version: 1.0\nStart-Class: com.example.App\n")).To(Succeed())
+				Expect(createJar(filepath.Join(buildDir, "tool.jar"), "Manifest-Version: 1.0\nMain-Class: com.example.Tool\n")).To(Succeed())
+			})
+
+			AfterEach(func() {
+				os.Unsetenv("JBP_CONFIG_JAVA_MAIN")
+			})
+
+			It("runs the main_artifact of JBP_CONFIG_JAVA_MAIN", func() {
+				os.Setenv("JBP_CONFIG_JAVA_MAIN", "{main_artifact: service.jar}")
+
+				Expect(container.Detect()).To(Equal("Spring Boot"))
+				Expect(container.Release()).To(HaveSuffix("-jar $HOME/service.jar"))
+			})
+
+			It("leaves a main_artifact that is not a Spring Boot JAR to other containers", func() {
+				os.Setenv("JBP_CONFIG_JAVA_MAIN", "{main_artifact: tool.jar}")
+
+				Expect(container.Detect()).To(BeEmpty())
+			})
+		})
+
+		Context("with a plain JAR whose name contains boot", func() {
+			BeforeEach(func() {
+				Expect(createJar(
+					filepath.Join(buildDir, "reboot-service.jar"),
+					"Manifest-Version: 1.0\nMain-Class: com.example.Reboot\n",
+				)).To(Succeed())
+			})
+
+			It("does not detect as Spring Boot", func() {
+				name, err := container.Detect()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(name).To(BeEmpty())
+			})
+		})
+
+		Context("with a file named like a Spring Boot JAR that is not a JAR", func() {
+			BeforeEach(func() {
+				os.WriteFile(filepath.Join(buildDir, "spring-boot.jar"), []byte("fake jar"), 0644)
+			})
+
+			It("does not detect as Spring Boot", func() {
+				name, err := container.Detect()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(name).To(BeEmpty())
+			})
+		})
+
 		Context("with non-Spring Boot JAR", func() {
 			BeforeEach(func() {
 				os.WriteFile(filepath.Join(buildDir, "regular.jar"), []byte("fake jar"), 0644)
@@ -140,8 +198,10 @@ var _ = Describe("Spring Boot Container", func() {
 
 		Context("with Spring Boot JAR", func() {
 			BeforeEach(func() {
-				jarPath := filepath.Join(buildDir, "app-boot.jar")
-				os.WriteFile(jarPath, []byte("fake jar content"), 0644)
+				Expect(createJar(
+					filepath.Join(buildDir, "app-boot.jar"),
+					"Manifest-Version: 1.0\nSpring-Boot-Version: 3.2.0\n",
+				)).To(Succeed())
 				container.Detect()
 			})
 
@@ -164,7 +224,10 @@ var _ = Describe("Spring Boot Container", func() {
 
 	Describe("Finalize", func() {
 		BeforeEach(func() {
-			os.WriteFile(filepath.Join(buildDir, "spring-boot.jar"), []byte("fake"), 0644)
+			Expect(createJar(
+				filepath.Join(buildDir, "app.jar"),
+				"Manifest-Version: 1.0\nSpring-Boot-Version: 3.2.0\n",
+			)).To(Succeed())
 			container.Detect()
 		})
 
@@ -173,22 +236,83 @@ var _ = Describe("Spring Boot Container", func() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 
-		It("writes a profile.d script that exports SERVER_PORT=$PORT so the variable is shell-expanded at runtime", func() {
-			err := container.Finalize()
+		It("links the CLASSPATH of frameworks, such as JDBC drivers, into BOOT-INF/lib of an exploded app", func() {
+			Expect(os.MkdirAll(filepath.Join(buildDir, "BOOT-INF", "lib"), 0755)).To(Succeed())
+
+			Expect(container.Finalize()).To(Succeed())
+			script, err := os.ReadFile(filepath.Join(depsDir, "0", "profile.d", "zzz_classpath_symlinks.sh"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(script)).To(ContainSubstring(`TARGET_DIR="$PWD/BOOT-INF/lib"`))
+		})
+
+		It("binds to $PORT through the SERVER_PORT environment variable", func() {
+			Expect(container.PortBinding()).To(Equal(containers.PortBinding{EnvironmentVariable: "SERVER_PORT"}))
+		})
+	})
+
+	Describe("Actuator health check hint", func() {
+		var logs *bytes.Buffer
+
+		BeforeEach(func() {
+			logs = new(bytes.Buffer)
+			ctx.Log = libbuildpack.NewLogger(logs)
+		})
+
+		writeExplodedApp := func(bootVersion string, libs ...string) {
+			Expect(os.MkdirAll(filepath.Join(buildDir, "BOOT-INF", "lib"), 0755)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(buildDir, "META-INF"), 0755)).To(Succeed())
+			manifest := fmt.Sprintf("Manifest-Version: 1.0\nStart-Class: com.example.App\nSpring-Boot-Version: %s\n", bootVersion)
+			Expect(os.WriteFile(filepath.Join(buildDir, "META-INF", "MANIFEST.MF"), []byte(manifest), 0644)).To(Succeed())
+			for _, lib := range libs {
+				Expect(os.WriteFile(filepath.Join(buildDir, "BOOT-INF", "lib", lib), []byte("jar"), 0644)).To(Succeed())
+			}
+		}
+
+		It("suggests liveness and readiness endpoints for an exploded app with actuator", func() {
+			writeExplodedApp("3.2.0", "spring-boot-actuator-autoconfigure-3.2.0.jar", "spring-boot-actuator-3.2.0.jar")
+			Expect(container.Finalize()).To(Succeed())
+
+			Expect(logs.String()).To(ContainSubstring("Spring Boot Actuator detected (spring-boot-actuator-3.2.0.jar)"))
+			Expect(logs.String()).To(ContainSubstring("health-check-http-endpoint: /actuator/health"))
+			Expect(logs.String()).To(ContainSubstring("readiness-health-check-http-endpoint: /actuator/health/readiness"))
+		})
+
+		It("suggests the root health endpoint for Spring Boot 1.x actuator", func() {
+			writeExplodedApp("1.5.22.RELEASE", "spring-boot-actuator-1.5.22.RELEASE.jar")
+			Expect(container.Finalize()).To(Succeed())
+
+			Expect(logs.String()).To(ContainSubstring("health-check-http-endpoint: /health"))
+			Expect(logs.String()).NotTo(ContainSubstring("readiness"))
+		})
+
+		It("inspects BOOT-INF/lib inside a Spring Boot JAR", func() {
+			f, err := os.Create(filepath.Join(buildDir, "app.jar"))
 			Expect(err).NotTo(HaveOccurred())
+			w := zip.NewWriter(f)
+			for name, content := range map[string]string{
+				"META-INF/MANIFEST.MF":                        "Manifest-Version: 1.0\nSpring-Boot-Version: 3.2.0\n",
+				"BOOT-INF/lib/spring-boot-actuator-3.2.0.jar": "jar",
+			} {
+				entry, err := w.Create(name)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = entry.Write([]byte(content))
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(w.Close()).To(Succeed())
+			Expect(f.Close()).To(Succeed())
 
-			profileScript := filepath.Join(depsDir, "0", "profile.d", "spring_boot_server_port.sh")
-			data, err := os.ReadFile(profileScript)
+			_, err = container.Detect()
 			Expect(err).NotTo(HaveOccurred())
-			Expect(string(data)).To(Equal("export SERVER_PORT=$PORT\n"))
-
-			// Verify $PORT is actually shell-expanded at runtime (not left as literal "$PORT").
-			// Simulates what CF's launcher does: source the profile.d script with PORT set in env.
-			cmd := exec.Command("bash", "-c", fmt.Sprintf("PORT=8080 . %s && echo $SERVER_PORT", profileScript))
-			out, bashErr := cmd.Output()
-			Expect(bashErr).NotTo(HaveOccurred())
-			Expect(strings.TrimSpace(string(out))).To(Equal("8080"),
-				"SERVER_PORT should be the expanded value of $PORT, not the literal string \"$PORT\"")
+			Expect(container.Finalize()).To(Succeed())
+
+			Expect(logs.String()).To(ContainSubstring("health-check-http-endpoint: /actuator/health"))
+		})
+
+		It("does not suggest health checks without actuator", func() {
+			writeExplodedApp("3.2.0", "spring-boot-actuator-autoconfigure-3.2.0.jar", "spring-web-6.1.0.jar")
+			Expect(container.Finalize()).To(Succeed())
+
+			Expect(logs.String()).NotTo(ContainSubstring("health-check"))
 		})
 	})
 })
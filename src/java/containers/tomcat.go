@@ -3,12 +3,14 @@ package containers
 import (
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/httpclient"
 	"github.com/cloudfoundry/java-buildpack/src/java/resources"
 	"github.com/cloudfoundry/libbuildpack"
 )
@@ -44,6 +46,7 @@ func (t *TomcatContainer) Detect() (string, error) {
 		return "Tomcat", nil
 	}
 
+	t.context.Log.Debug("Tomcat: no WEB-INF directory and no .war file")
 	return "", nil
 }
 
@@ -52,7 +55,7 @@ func (t *TomcatContainer) Supply() error {
 	t.context.Log.BeginStep("Supplying Tomcat")
 
 	// Determine Java version to select appropriate Tomcat version
-	// Tomcat 10.x requires Java 11+, Tomcat 9.x supports Java 8-22
+	// Tomcat 9.x supports Java 8+, Tomcat 10.x requires Java 11+, Tomcat 11.x requires Java 17+
 	javaHome := os.Getenv("JAVA_HOME")
 	var dep libbuildpack.Dependency
 	var err error
@@ -62,47 +65,51 @@ func (t *TomcatContainer) Supply() error {
 		return fmt.Errorf("failed to load tomcat config: %w", err)
 	}
 
+	// Tomcat is published per stack; report a stack without entries instead of a missing version
+	if err := common.DependencyStackError(t.context.Manifest, "tomcat"); err != nil {
+		return err
+	}
+
+	javaMajorVersion := 0
 	if javaHome != "" {
-		javaMajorVersion, versionErr := common.DetermineJavaVersion(javaHome)
-		if versionErr == nil {
-			tomcatVersion := DetermineTomcatVersion(t.config.Tomcat.Version)
+		javaMajorVersion, err = common.DetermineJavaVersion(javaHome)
+		if err != nil {
+			t.context.Log.Warning("Unable to determine Java version: %s", err.Error())
+			javaMajorVersion = 0
+		} else {
 			t.context.Log.Debug("Detected Java major version: %d", javaMajorVersion)
+		}
+	}
 
-			// Select Tomcat version pattern based on Java version
-			var versionPattern string
-			if tomcatVersion == "" {
-				t.context.Log.Info("Tomcat version not specified")
-				if javaMajorVersion >= 11 {
-					// Java 11+: Use Tomcat 10.x (Jakarta EE 9+)
-					versionPattern = "10.x"
-					t.context.Log.Info("Using Tomcat 10.x for Java %d", javaMajorVersion)
-				} else {
-					// Java 8-10: Use Tomcat 9.x (Java EE 8)
-					versionPattern = "9.x"
-					t.context.Log.Info("Using Tomcat 9.x for Java %d", javaMajorVersion)
-				}
-			} else {
-				versionPattern = tomcatVersion
-				t.context.Log.Info("Using Tomcat %s for Java %d", versionPattern, javaMajorVersion)
-			}
-
-			if strings.HasPrefix(versionPattern, "10.") && javaMajorVersion < 11 {
-				return fmt.Errorf("Tomcat 10.x requires Java 11+, but Java %d detected", javaMajorVersion)
-			}
-
-			// Resolve the version pattern to actual version using libbuildpack
-			allVersions := t.context.Manifest.AllDependencyVersions("tomcat")
-			resolvedVersion, err := libbuildpack.FindMatchingVersion(versionPattern, allVersions)
-			if err != nil {
-				return fmt.Errorf("tomcat version resolution error for pattern %q: %w", versionPattern, err)
-			}
+	versionPattern := DetermineTomcatVersion(t.config.Tomcat.Version)
+	if versionPattern == "" && javaMajorVersion > 0 {
+		t.context.Log.Info("Tomcat version not specified")
+		if javaMajorVersion >= 11 {
+			// Java 11+: Use Tomcat 10.x (Jakarta EE 9+)
+			versionPattern = "10.x"
+		} else {
+			// Java 8-10: Use Tomcat 9.x (Java EE 8)
+			versionPattern = "9.x"
+		}
+	}
 
-			dep.Name = "tomcat"
-			dep.Version = resolvedVersion
-			t.context.Log.Debug("Resolved Tomcat version pattern '%s' to %s", versionPattern, resolvedVersion)
+	if versionPattern != "" {
+		if javaMajorVersion > 0 {
+			t.context.Log.Info("Using Tomcat %s for Java %d", versionPattern, javaMajorVersion)
 		} else {
-			t.context.Log.Warning("Unable to determine Java version: %s", versionErr.Error())
+			t.context.Log.Info("Using Tomcat %s", versionPattern)
+		}
+
+		// Resolve the version pattern to actual version using libbuildpack
+		allVersions := t.context.Manifest.AllDependencyVersions("tomcat")
+		resolvedVersion, err := ResolveTomcatVersion(versionPattern, javaMajorVersion, allVersions)
+		if err != nil {
+			return err
 		}
+
+		dep.Name = "tomcat"
+		dep.Version = resolvedVersion
+		t.context.Log.Debug("Resolved Tomcat version pattern '%s' to %s", versionPattern, resolvedVersion)
 	}
 
 	// Fallback to default version if we couldn't determine Java version
@@ -125,18 +132,17 @@ func (t *TomcatContainer) Supply() error {
 	// Get buildpack index for multi-buildpack support
 	depsIdx := t.context.Stager.DepsIdx()
 	// Write profile.d script to set CATALINA_HOME, CATALINA_BASE, and JAVA_OPTS at runtime
-	tomcatPath := fmt.Sprintf("$DEPS_DIR/%s/tomcat", depsIdx)
+	tomcatPath := t.context.Droplet().Dep("tomcat")
 
 	// Determine access logging configuration (default: disabled, matching Ruby buildpack)
 	// Can be enabled via: JBP_CONFIG_TOMCAT='{access_logging_support: {access_logging: enabled}}'
 	accessLoggingEnabled := t.isAccessLoggingEnabled()
 
-	// Add http.port system property to JAVA_OPTS so Tomcat uses $PORT for the HTTP connector
 	// Add access.logging.enabled to control CloudFoundryAccessLoggingValve
-	// These are required for Cloud Foundry where the platform assigns a dynamic port
+	// The http.port system property for the HTTP connector is added by the finalize phase, see PortBinding
 	envContent := fmt.Sprintf(`export CATALINA_HOME=%s
 export CATALINA_BASE=%s
-export JAVA_OPTS="${JAVA_OPTS:+$JAVA_OPTS }-Dhttp.port=$PORT -Daccess.logging.enabled=%s"
+export JAVA_OPTS="${JAVA_OPTS:+$JAVA_OPTS }-Daccess.logging.enabled=%s"
 `, tomcatPath, tomcatPath, accessLoggingEnabled)
 
 	if err := t.context.Stager.WriteProfileD("tomcat.sh", envContent); err != nil {
@@ -145,6 +151,11 @@ export JAVA_OPTS="${JAVA_OPTS:+$JAVA_OPTS }-Dhttp.port=$PORT -Daccess.logging.en
 		t.context.Log.Debug("Created profile.d script: tomcat.sh")
 	}
 
+	// Merge the files that frameworks contribute to CATALINA_BASE at runtime, after tomcat.sh has set it
+	if err := t.context.Stager.WriteProfileD("tomcat_overlays.sh", TomcatOverlayScript(depsIdx)); err != nil {
+		return fmt.Errorf("failed to write tomcat_overlays.sh profile.d script: %w", err)
+	}
+
 	// Install Tomcat support libraries (lifecycle, access-logging, and logging)
 	// These are ALWAYS required for proper Tomcat initialization with Cloud Foundry
 	if err := t.installTomcatLifecycleSupport(); err != nil {
@@ -177,6 +188,14 @@ export JAVA_OPTS="${JAVA_OPTS:+$JAVA_OPTS }-Dhttp.port=$PORT -Daccess.logging.en
 		return fmt.Errorf("failed to install external Tomcat configuration: %w", err)
 	}
 
+	if err := t.configureLogging(tomcatDir); err != nil {
+		return err
+	}
+
+	if err := t.configureClientAuth(tomcatDir); err != nil {
+		return fmt.Errorf("failed to configure client authentication connector: %w", err)
+	}
+
 	// JVMKill agent is installed and configured by JRE component
 
 	return nil
@@ -266,21 +285,13 @@ func (t *TomcatContainer) installTomcatLoggingSupport() (string, error) {
 }
 
 // createSetenvScript creates a setenv.sh script in tomcat/bin to add logging support JAR to CLASSPATH
+// and to move JVM options from JAVA_OPTS to CATALINA_OPTS
 // Tomcat's catalina.sh automatically sources setenv.sh if it exists
 func (t *TomcatContainer) createSetenvScript(tomcatDir, loggingSupportJar string) error {
 	binDir := filepath.Join(tomcatDir, "bin")
 	setenvPath := filepath.Join(binDir, "setenv.sh")
 
-	jarPath := "$CATALINA_HOME/bin/" + loggingSupportJar
-	// Note that Tomcat builds its own CLASSPATH env before starting. It ensures that any user defined CLASSPATH variables
-	// are not used on startup, as can be seen in the catalina.sh script. That is why even we have something already
-	// sourced in CLASSPATH env from profile.d scripts it is disregarded on Tomcat startup and fresh CLASSPATH env is
-	// built here in the setenv.sh script.
-	setenvContent := fmt.Sprintf(`#!/bin/sh
-CLASSPATH="%s${CONTAINER_SECURITY_PROVIDER:+:$CONTAINER_SECURITY_PROVIDER}"
-`, jarPath)
-
-	if err := os.WriteFile(setenvPath, []byte(setenvContent), 0755); err != nil {
+	if err := os.WriteFile(setenvPath, []byte(TomcatSetenvScript(loggingSupportJar)), 0755); err != nil {
 		return fmt.Errorf("failed to write setenv.sh: %w", err)
 	}
 
@@ -288,6 +299,53 @@ CLASSPATH="%s${CONTAINER_SECURITY_PROVIDER:+:$CONTAINER_SECURITY_PROVIDER}"
 	return nil
 }
 
+// TomcatSetenvScript returns the content of tomcat/bin/setenv.sh, which catalina.sh sources for every command.
+//
+// Note that Tomcat builds its own CLASSPATH env before starting. It ensures that any user defined CLASSPATH variables
+// are not used on startup, as can be seen in the catalina.sh script. That is why even we have something already
+// sourced in CLASSPATH env from profile.d scripts it is disregarded on Tomcat startup and fresh CLASSPATH env is
+// built here in the setenv.sh script.
+//
+// catalina.sh passes JAVA_OPTS to every JVM it starts, including the one for "catalina.sh stop", while CATALINA_OPTS
+// is only used for the server. The JVM options assembled by the buildpack (memory settings, agents, debug and JMX
+// ports, user JAVA_OPTS) are therefore moved to CATALINA_OPTS, ahead of any CATALINA_OPTS set by the user.
+func TomcatSetenvScript(loggingSupportJar string) string {
+	return fmt.Sprintf(`#!/bin/sh
+CLASSPATH="$CATALINA_HOME/bin/%s${CONTAINER_SECURITY_PROVIDER:+:$CONTAINER_SECURITY_PROVIDER}"
+
+if [ -n "$JAVA_OPTS" ]; then
+  CATALINA_OPTS="$JAVA_OPTS${CATALINA_OPTS:+ $CATALINA_OPTS}"
+  JAVA_OPTS=""
+fi
+`, loggingSupportJar)
+}
+
+// TomcatOverlayScript returns the profile.d script that merges the Tomcat overlays of frameworks into CATALINA_BASE.
+// Frameworks stage files in common.TomcatOverlayDir, e.g. a session manager JAR in tomcat_overlays/<name>/lib, and
+// the script links each of them to the same path in CATALINA_BASE on every start. A file that exists already, from
+// Tomcat, the external configuration or another overlay, is kept and reported, so overlays never clobber each other.
+// Overlays are merged in the order of their names.
+func TomcatOverlayScript(depsIdx string) string {
+	return fmt.Sprintf(`(
+overlays="$DEPS_DIR/%s/%s"
+for overlay in "$overlays"/*; do
+  [ -d "$overlay" ] || continue
+  find "$overlay" -type f | sort | while read -r source; do
+    file="${source#$overlay/}"
+    target="$CATALINA_BASE/$file"
+    if [ -L "$target" ] && [ "$(readlink "$target")" = "$source" ]; then
+      continue
+    elif [ -e "$target" ]; then
+      echo "[Java Buildpack] Tomcat overlay ${overlay##*/} does not replace existing $file" >&2
+      continue
+    fi
+    mkdir -p "$(dirname "$target")" && ln -s "$source" "$target"
+  done
+done
+)
+`, depsIdx, common.TomcatOverlaysDir)
+}
+
 // installExternalConfiguration installs external Tomcat configuration if enabled
 func (t *TomcatContainer) installExternalConfiguration(tomcatDir string) error {
 	// Check if external configuration is enabled
@@ -319,6 +377,10 @@ func (t *TomcatContainer) installExternalConfiguration(tomcatDir string) error {
 	dep, err := t.context.Manifest.DefaultVersion("tomcat-external-configuration")
 	if err != nil {
 		// Manifest entry not found - download directly from repository_root
+		if common.IsOffline() {
+			return common.OfflineError("External Tomcat configuration", repositoryRoot,
+				"add a tomcat-external-configuration dependency to manifest.yml and package the buildpack with './scripts/package.sh --cached'")
+		}
 		t.context.Log.Info("External configuration not in manifest, downloading directly from repository")
 		return t.downloadExternalConfiguration(repositoryRoot, version, tomcatDir)
 	}
@@ -343,60 +405,40 @@ func (t *TomcatContainer) downloadExternalConfiguration(repositoryRoot, version,
 	indexURL := fmt.Sprintf("%s/index.yml", repositoryRoot)
 	t.context.Log.Info("Fetching external configuration index from: %s", indexURL)
 
-	indexResp, err := http.Get(indexURL)
+	indexResp, err := httpclient.Default().Get(indexURL)
 	if err != nil {
 		return fmt.Errorf("failed to download index.yml: %w", err)
 	}
 	defer indexResp.Body.Close()
 
-	if indexResp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to download index.yml: HTTP %d", indexResp.StatusCode)
-	}
-
 	// Read and parse index.yml
 	indexData, err := io.ReadAll(indexResp.Body)
 	if err != nil {
 		return fmt.Errorf("failed to read index.yml: %w", err)
 	}
 
-	// Parse YAML as map[string]string (version -> URL)
-	var index map[string]string
-	yamlHandler := common.YamlHandler{}
-	if err := yamlHandler.Unmarshal(indexData, &index); err != nil {
-		return fmt.Errorf("failed to parse index.yml: %w", err)
-	}
-
-	// Step 2: Look up the download URL for the requested version
-	downloadURL, found := index[version]
-	if !found {
-		return fmt.Errorf("version %s not found in index.yml (available versions: %v)", version, getKeys(index))
+	// Step 2: Look up the download URL and checksum for the requested version
+	downloadURL, sha256, err := LookupExternalConfiguration(indexData, version)
+	if err != nil {
+		return err
 	}
 
 	t.context.Log.Info("Found version %s in index, downloading from: %s", version, downloadURL)
+	if sha256 == "" {
+		t.context.Log.Warning("index.yml has no sha256 for version %s, the external configuration is not verified", version)
+	}
 
-	// Step 3: Download the configuration archive
+	// Step 3: Download the configuration archive and verify its checksum before extracting it
 	tmpFile, err := os.CreateTemp("", "tomcat-external-config-*.tar.gz")
 	if err != nil {
 		return fmt.Errorf("failed to create temp file: %w", err)
 	}
 	defer os.Remove(tmpFile.Name())
-	defer tmpFile.Close()
+	tmpFile.Close()
 
-	resp, err := http.Get(downloadURL)
-	if err != nil {
+	if err := httpclient.Default().DownloadVerified(downloadURL, tmpFile.Name(), sha256); err != nil {
 		return fmt.Errorf("failed to download external configuration: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to download external configuration: HTTP %d", resp.StatusCode)
-	}
-
-	// Write response to temp file
-	if _, err := io.Copy(tmpFile, resp.Body); err != nil {
-		return fmt.Errorf("failed to write external configuration to temp file: %w", err)
-	}
-	tmpFile.Close()
 
 	// Step 4: Extract the archive to tomcatDir with strip=0
 	// The external config archive has structure: ./conf/...
@@ -453,8 +495,38 @@ func (t *TomcatContainer) installDefaultConfiguration(tomcatDir string) error {
 	return nil
 }
 
+// LookupExternalConfiguration returns the download URL and SHA256 checksum of version in an external configuration
+// index.yml. A version maps either to the URL of its archive or to a mapping with uri and sha256 keys; the checksum
+// is empty for the former.
+func LookupExternalConfiguration(indexData []byte, version string) (string, string, error) {
+	var index map[string]interface{}
+	yamlHandler := common.YamlHandler{}
+	if err := yamlHandler.Unmarshal(indexData, &index); err != nil {
+		return "", "", fmt.Errorf("failed to parse index.yml: %w", err)
+	}
+
+	entry, found := index[version]
+	if !found {
+		return "", "", fmt.Errorf("version %s not found in index.yml (available versions: %v)", version, getKeys(index))
+	}
+
+	switch entry := entry.(type) {
+	case string:
+		return entry, "", nil
+	case map[string]interface{}:
+		uri, _ := entry["uri"].(string)
+		if uri == "" {
+			return "", "", fmt.Errorf("version %s in index.yml has no uri", version)
+		}
+		sha256, _ := entry["sha256"].(string)
+		return uri, sha256, nil
+	default:
+		return "", "", fmt.Errorf("version %s in index.yml must be a URL or a mapping with uri and sha256", version)
+	}
+}
+
 // getKeys returns the keys of a map as a slice (for error messages)
-func getKeys(m map[string]string) []string {
+func getKeys(m map[string]interface{}) []string {
 	keys := make([]string, 0, len(m))
 	for k := range m {
 		keys = append(keys, k)
@@ -473,6 +545,43 @@ func DetermineTomcatVersion(version string) string {
 	return strings.ReplaceAll(version, "+", "*")
 }
 
+// tomcatMinimumJavaVersions maps Tomcat major versions to the oldest Java major version they run on
+var tomcatMinimumJavaVersions = map[string]int{
+	"9":  8,
+	"10": 11,
+	"11": 17,
+}
+
+// ResolveTomcatVersion resolves a Tomcat version pattern (e.g. "9.0.*", "10.x") to the newest matching
+// version in versions that runs on the given Java major version. A javaMajorVersion of 0 means the
+// Java version is unknown and skips the compatibility check.
+func ResolveTomcatVersion(pattern string, javaMajorVersion int, versions []string) (string, error) {
+	compatible := versions
+	if javaMajorVersion > 0 {
+		compatible = nil
+		for _, v := range versions {
+			major := strings.SplitN(v, ".", 2)[0]
+			if minimum, ok := tomcatMinimumJavaVersions[major]; ok && javaMajorVersion < minimum {
+				continue
+			}
+			compatible = append(compatible, v)
+		}
+	}
+
+	resolved, err := libbuildpack.FindMatchingVersion(pattern, compatible)
+	if err == nil {
+		return resolved, nil
+	}
+
+	// Distinguish a Tomcat line the buildpack does not ship from one the detected Java cannot run
+	if newest, allErr := libbuildpack.FindMatchingVersion(pattern, versions); allErr == nil {
+		major := strings.SplitN(newest, ".", 2)[0]
+		return "", fmt.Errorf("Tomcat %s requires Java %d+, but Java %d detected", newest, tomcatMinimumJavaVersions[major], javaMajorVersion)
+	}
+
+	return "", fmt.Errorf("no Tomcat version matching %q is available (available versions: %s)", pattern, strings.Join(versions, ", "))
+}
+
 // isAccessLoggingEnabled checks if access logging is enabled in configuration
 // Returns: "true" or "false" as a string (for use in JAVA_OPTS)
 // Default: "false" (disabled, matching Ruby buildpack behavior)
@@ -566,33 +675,137 @@ func (t *TomcatContainer) Finalize() error {
 			return fmt.Errorf("failed to create context directory: %w", err)
 		}
 
-		appContextXML := filepath.Join(buildDir, "META-INF", "context.xml")
-		var contextContent string
-
-		if _, err := os.Stat(appContextXML); err == nil {
-			xmlBytes, err := os.ReadFile(appContextXML)
-			if err != nil {
-				return fmt.Errorf("failed to read META-INF/context.xml: %w", err)
-			}
-
-			xmlStr := string(xmlBytes)
-			xmlStr = strings.TrimSpace(xmlStr)
-
-			contextContent = injectDocBase(xmlStr, "${user.home}/app")
+		contextContent, merged, err := renderContextXML(filepath.Join(buildDir, "META-INF", "context.xml"), "${user.home}/app")
+		if err != nil {
+			return err
+		}
+		if merged {
 			t.context.Log.Info("Merged META-INF/context.xml with ROOT.xml - realm and resource configurations preserved")
 		} else {
-			contextContent = fmt.Sprintf("<Context docBase=\"${user.home}/app\" reloadable=\"false\">\n</Context>\n")
 			t.context.Log.Info("Created ROOT.xml with docBase pointing to application directory")
 		}
 
 		if err := os.WriteFile(contextXMLPath, []byte(contextContent), 0644); err != nil {
 			return fmt.Errorf("failed to write ROOT.xml: %w", err)
 		}
+		return nil
+	}
+
+	wars, err := filepath.Glob(filepath.Join(buildDir, "*.war"))
+	if err != nil {
+		return err
+	}
+	if len(wars) > 0 {
+		return t.deployWARs(wars)
+	}
+
+	return nil
+}
+
+// renderContextXML returns a context descriptor for docBase, merging the application's META-INF/context.xml
+// if it exists. merged reports whether it did.
+func renderContextXML(appContextXML, docBase string) (content string, merged bool, err error) {
+	xmlBytes, err := os.ReadFile(appContextXML)
+	if os.IsNotExist(err) {
+		return fmt.Sprintf("<Context docBase=\"%s\" reloadable=\"false\">\n</Context>\n", docBase), false, nil
+	} else if err != nil {
+		return "", false, fmt.Errorf("failed to read META-INF/context.xml: %w", err)
+	}
+
+	return injectDocBase(strings.TrimSpace(string(xmlBytes)), docBase), true, nil
+}
+
+// deployWARs explodes each WAR file in the application directory into its own directory and deploys it
+// as a separate Tomcat context. The context path is taken from tomcat's context_path_map, keyed by WAR
+// file name, and otherwise derived from the file name; a single WAR, or one named ROOT.war, is served at /.
+func (t *TomcatContainer) deployWARs(wars []string) error {
+	tConfig, err := t.loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load tomcat config: %w", err)
+	}
+
+	buildDir := t.context.Stager.BuildDir()
+	contextDir := filepath.Join(t.tomcatDir(), "conf", "Catalina", "localhost")
+	if err := os.MkdirAll(contextDir, 0755); err != nil {
+		return fmt.Errorf("failed to create context directory: %w", err)
+	}
+
+	deployed := map[string]string{}
+	for _, war := range wars {
+		warName := filepath.Base(war)
+		name := strings.TrimSuffix(warName, ".war")
+
+		contextPath := tConfig.contextPathFor(warName, len(wars))
+		descriptor := contextDescriptorName(contextPath)
+		if other, exists := deployed[descriptor]; exists {
+			return fmt.Errorf("%s and %s are both mapped to context path %q", other, warName, contextPath)
+		}
+		deployed[descriptor] = warName
+
+		appDir := filepath.Join(tomcatWebappsDir, name)
+		if err := libbuildpack.ExtractZip(war, filepath.Join(buildDir, appDir)); err != nil {
+			return fmt.Errorf("failed to explode %s: %w", warName, err)
+		}
+		if err := os.Remove(war); err != nil {
+			return fmt.Errorf("failed to remove %s after exploding it: %w", warName, err)
+		}
+
+		content, _, err := renderContextXML(filepath.Join(buildDir, appDir, "META-INF", "context.xml"), "${user.home}/app/"+appDir)
+		if err != nil {
+			return fmt.Errorf("%s: %w", warName, err)
+		}
+		if err := os.WriteFile(filepath.Join(contextDir, descriptor+".xml"), []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s.xml: %w", descriptor, err)
+		}
+
+		if err := t.context.Stager.WriteProfileD("zzz_classpath_symlinks_"+name+".sh", fmt.Sprintf(symlinkScript, filepath.Join(appDir, "WEB-INF", "lib"))); err != nil {
+			return fmt.Errorf("failed to write zzz_classpath_symlinks_%s.sh: %w", name, err)
+		}
+
+		t.context.Log.Info("Deployed %s at context path %s", warName, contextPath)
 	}
 
 	return nil
 }
 
+// tomcatWebappsDir is the directory, relative to the application directory, that WAR files are exploded into
+const tomcatWebappsDir = ".java-buildpack/tomcat_webapps"
+
+// contextPathFor returns the context path of the WAR file warName when warCount WARs are deployed
+func (c *tomcatConfig) contextPathFor(warName string, warCount int) string {
+	name := strings.TrimSuffix(warName, ".war")
+	for _, key := range []string{warName, name} {
+		if path, ok := c.ContextPathMap[key]; ok {
+			return "/" + strings.Trim(path, "/")
+		}
+	}
+
+	if name == "ROOT" || warCount == 1 {
+		return "/"
+	}
+	return "/" + name
+}
+
+// contextDescriptorName returns the base name of the conf/Catalina/localhost descriptor that Tomcat maps to
+// contextPath, e.g. "/" -> "ROOT" and "/shop/api" -> "shop#api"
+func contextDescriptorName(contextPath string) string {
+	name := strings.ReplaceAll(strings.Trim(contextPath, "/"), "/", "#")
+	if name == "" {
+		return "ROOT"
+	}
+	return name
+}
+
+// PortBinding passes $PORT as the http.port system property, which the HTTP connector in server.xml listens on
+func (t *TomcatContainer) PortBinding() PortBinding {
+	return PortBinding{SystemProperty: "http.port"}
+}
+
+// RequiredArtifacts returns the Tomcat launcher
+func (t *TomcatContainer) RequiredArtifacts() []string {
+	return []string{"$CATALINA_HOME/bin/catalina.sh"}
+}
+
 // Release returns the Tomcat startup command
 func (t *TomcatContainer) Release() (string, error) {
 	// Use $CATALINA_HOME environment variable set by profile.d script
@@ -619,14 +832,20 @@ func (t *TomcatContainer) loadConfig() (*tomcatConfig, error) {
 		AccessLoggingSupport: AccessLoggingSupport{
 			AccessLogging: "disabled",
 		},
+		ClientAuth: ClientAuth{
+			Port: 8443,
+			Mode: "need",
+		},
 	}
-	config := os.Getenv("JBP_CONFIG_TOMCAT")
-	if config != "" {
-		yamlHandler := common.YamlHandler{}
-		// overlay JBP_CONFIG_TOMCAT over default values
-		if err := yamlHandler.Unmarshal([]byte(config), &tConfig); err != nil {
-			return nil, fmt.Errorf("failed to parse JBP_CONFIG_TOMCAT: %w", err)
-		}
+	// overlay buildpack defaults and JBP_CONFIG_TOMCAT over default values
+	if err := config.Load(t.context.Log, "tomcat", &tConfig); err != nil {
+		return nil, err
+	}
+	if err := tConfig.Logging.Validate(); err != nil {
+		return nil, err
+	}
+	if err := tConfig.ClientAuth.Validate(); err != nil {
+		return nil, err
 	}
 	return &tConfig, nil
 }
@@ -635,6 +854,10 @@ type tomcatConfig struct {
 	Tomcat                Tomcat                `yaml:"tomcat"`
 	ExternalConfiguration ExternalConfiguration `yaml:"external_configuration"`
 	AccessLoggingSupport  AccessLoggingSupport  `yaml:"access_logging_support"`
+	Logging               Logging               `yaml:"logging"`
+	ClientAuth            ClientAuth            `yaml:"client_auth"`
+	// ContextPathMap maps WAR file names to the context paths they are deployed at
+	ContextPathMap map[string]string `yaml:"context_path_map"`
 }
 
 type Tomcat struct {
@@ -650,3 +873,131 @@ type ExternalConfiguration struct {
 type AccessLoggingSupport struct {
 	AccessLogging string `yaml:"access_logging"`
 }
+
+// Logging customizes the embedded conf/logging.properties, e.g.
+// JBP_CONFIG_TOMCAT='{logging: {level: INFO, levels: {org.apache.catalina.realm: FINEST}}}'
+type Logging struct {
+	// Level is the level of the root logger
+	Level string `yaml:"level"`
+	// Levels maps logger categories, e.g. org.apache.catalina.realm, to their levels
+	Levels map[string]string `yaml:"levels"`
+	// Handlers maps JULI handler classes to their properties, e.g.
+	// {org.cloudfoundry.tomcat.logging.CloudFoundryConsoleHandler: {level: FINEST}}
+	Handlers map[string]map[string]string `yaml:"handlers"`
+}
+
+// tomcatConsoleHandler is the handler of the embedded logging.properties that writes to stdout
+const tomcatConsoleHandler = "org.cloudfoundry.tomcat.logging.CloudFoundryConsoleHandler"
+
+// julLevels are the java.util.logging levels, from the least to the most verbose
+var julLevels = []string{"OFF", "SEVERE", "WARNING", "INFO", "CONFIG", "FINE", "FINER", "FINEST", "ALL"}
+
+// Validate returns an error if a level is not a java.util.logging level
+func (l Logging) Validate() error {
+	levels := map[string]string{}
+	if l.Level != "" {
+		levels["logging.level"] = l.Level
+	}
+	for category, level := range l.Levels {
+		levels["logging.levels."+category] = level
+	}
+	for handler, properties := range l.Handlers {
+		if level, ok := properties["level"]; ok {
+			levels["logging.handlers."+handler+".level"] = level
+		}
+	}
+
+	keys := make([]string, 0, len(levels))
+	for key := range levels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if julLevelIndex(levels[key]) < 0 {
+			return fmt.Errorf("invalid level %q for %s, expected one of %s", levels[key], key, strings.Join(julLevels, ", "))
+		}
+	}
+	return nil
+}
+
+// julLevelIndex returns the verbosity of a java.util.logging level, or -1 if level is not one
+func julLevelIndex(level string) int {
+	for i, l := range julLevels {
+		if strings.EqualFold(level, l) {
+			return i
+		}
+	}
+	return -1
+}
+
+// TomcatLoggingProperties appends the settings of logging to the content of a logging.properties file. Later
+// properties replace earlier ones, so the settings override those of the file. Unless the handlers set the level
+// of the console handler, it is lowered to the most verbose configured level so that the messages reach stdout.
+func TomcatLoggingProperties(properties string, logging Logging) string {
+	var lines []string
+	finest := -1
+	if logging.Level != "" {
+		lines = append(lines, ".level: "+strings.ToUpper(logging.Level))
+		finest = julLevelIndex(logging.Level)
+	}
+
+	categories := make([]string, 0, len(logging.Levels))
+	for category := range logging.Levels {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	for _, category := range categories {
+		level := logging.Levels[category]
+		lines = append(lines, fmt.Sprintf("%s.level: %s", category, strings.ToUpper(level)))
+		finest = max(finest, julLevelIndex(level))
+	}
+
+	handlers := make([]string, 0, len(logging.Handlers))
+	for handler := range logging.Handlers {
+		handlers = append(handlers, handler)
+	}
+	sort.Strings(handlers)
+	for _, handler := range handlers {
+		names := make([]string, 0, len(logging.Handlers[handler]))
+		for name := range logging.Handlers[handler] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			lines = append(lines, fmt.Sprintf("%s.%s: %s", handler, name, logging.Handlers[handler][name]))
+		}
+	}
+
+	// The embedded file logs FINE and above to the console
+	if _, ok := logging.Handlers[tomcatConsoleHandler]["level"]; !ok && finest > julLevelIndex("FINE") {
+		lines = append(lines, fmt.Sprintf("%s.level: %s", tomcatConsoleHandler, julLevels[finest]))
+	}
+
+	if len(lines) == 0 {
+		return properties
+	}
+	if properties != "" && !strings.HasSuffix(properties, "\n") {
+		properties += "\n"
+	}
+	return properties + "\n# JBP_CONFIG_TOMCAT logging\n" + strings.Join(lines, "\n") + "\n"
+}
+
+// configureLogging applies the logging configuration to conf/logging.properties, after the default and external
+// configurations are installed
+func (t *TomcatContainer) configureLogging(tomcatDir string) error {
+	path := filepath.Join(tomcatDir, "conf", "logging.properties")
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read logging.properties: %w", err)
+	}
+
+	properties := TomcatLoggingProperties(string(data), t.config.Logging)
+	if properties == string(data) {
+		return nil
+	}
+	if err := os.WriteFile(path, []byte(properties), 0644); err != nil {
+		return fmt.Errorf("failed to write logging.properties: %w", err)
+	}
+	t.context.Log.Info("Applied logging configuration of JBP_CONFIG_TOMCAT to logging.properties")
+	return nil
+}
@@ -0,0 +1,247 @@
+package containers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+)
+
+// ClientAuth configures a second HTTPS connector that requests or requires client certificates, for applications
+// that terminate mutual TLS themselves rather than at the router, e.g. behind a TCP route:
+// JBP_CONFIG_TOMCAT='{client_auth: {enabled: true, port: 8443, mode: need}}'
+type ClientAuth struct {
+	Enabled bool `yaml:"enabled"`
+	// Port is the container port of the connector, which must be mapped to a route
+	Port int `yaml:"port"`
+	// Mode is want to request a client certificate or need to reject clients without one
+	Mode string `yaml:"mode"`
+	// Service names a bound service whose certificate, private_key and optional ca_certificate credentials the
+	// connector uses. Without it the connector presents the instance identity certificate of the container.
+	Service string `yaml:"service"`
+}
+
+// clientAuthModes maps the modes of ClientAuth to the certificateVerification of Tomcat's SSLHostConfig
+var clientAuthModes = map[string]string{"want": "optional", "need": "required"}
+
+// clientAuthModeVerbs describe the modes of ClientAuth in the staging output
+var clientAuthModeVerbs = map[string]string{"want": "requests", "need": "requires"}
+
+// Validate returns an error if the mode or the port of an enabled connector is invalid
+func (c ClientAuth) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if _, ok := clientAuthModes[c.Mode]; !ok {
+		return fmt.Errorf("invalid client_auth.mode %q, expected want or need", c.Mode)
+	}
+	if c.Port < 1 || c.Port > 65535 {
+		return fmt.Errorf("invalid client_auth.port %d, expected a port between 1 and 65535", c.Port)
+	}
+	if c.Port == 8080 {
+		return fmt.Errorf("invalid client_auth.port 8080, which is the port of the HTTP connector")
+	}
+	return nil
+}
+
+// tomcatClientAuthDir is the directory of CATALINA_BASE that holds the key and trust stores of the connector
+const tomcatClientAuthDir = "conf/client_auth"
+
+// TomcatClientAuthConnector returns the Connector element of the client authentication connector, whose key store,
+// and trust store if withTrustStore is true, are PKCS12 files in conf/client_auth protected by password. Without a
+// trust store, client certificates are verified against the default trust store of the JVM.
+func TomcatClientAuthConnector(clientAuth ClientAuth, withTrustStore bool, password string) string {
+	trustStore := ""
+	if withTrustStore {
+		trustStore = fmt.Sprintf("\n                           truststoreFile='${catalina.base}/%s/truststore.p12' "+
+			"truststoreType='PKCS12' truststorePassword='%s'", tomcatClientAuthDir, password)
+	}
+
+	return fmt.Sprintf(`        <Connector port='%d' protocol='org.apache.coyote.http11.Http11NioProtocol' SSLEnabled='true'
+                   scheme='https' secure='true' bindOnInit='false' connectionTimeout='20000'>
+            <SSLHostConfig certificateVerification='%s'%s>
+                <Certificate certificateKeystoreFile='${catalina.base}/%s/keystore.p12'
+                             certificateKeystoreType='PKCS12' certificateKeystorePassword='%s'/>
+            </SSLHostConfig>
+        </Connector>
+`, clientAuth.Port, clientAuthModes[clientAuth.Mode], trustStore, tomcatClientAuthDir, password)
+}
+
+// InsertTomcatConnector adds connector to the Catalina service of serverXML, before its Engine
+func InsertTomcatConnector(serverXML, connector string) (string, error) {
+	idx := strings.Index(serverXML, "<Engine")
+	if idx < 0 {
+		return "", fmt.Errorf("server.xml has no Engine element to add the client authentication connector before")
+	}
+	lineStart := strings.LastIndex(serverXML[:idx], "\n") + 1
+	return serverXML[:lineStart] + connector + "\n" + serverXML[lineStart:], nil
+}
+
+// TomcatClientAuthScript returns the profile.d script that writes the instance identity certificate and key of the
+// container to the key store of the client authentication connector when the application starts. Cloud Foundry
+// provides them at runtime only, in the files named by CF_INSTANCE_CERT and CF_INSTANCE_KEY. The CA certificates
+// that follow the instance certificate in CF_INSTANCE_CERT, which also issue the instance identity certificates of
+// other applications, make up the trust store.
+func TomcatClientAuthScript(password string) string {
+	return fmt.Sprintf(`if [ -n "${CF_INSTANCE_CERT:-}" ] && [ -n "${CF_INSTANCE_KEY:-}" ]; then
+  openssl pkcs12 -export -in "$CF_INSTANCE_CERT" -inkey "$CF_INSTANCE_KEY" -name tomcat \
+    -out "$CATALINA_BASE/%[1]s/keystore.p12" -passout pass:%[2]s \
+    || echo "WARNING: Unable to create the key store of the client authentication connector" >&2
+
+  rm -f "$CATALINA_BASE/%[1]s/truststore.p12" "$CATALINA_BASE/%[1]s"/ca-*.pem
+  awk -v dir="$CATALINA_BASE/%[1]s" '/-----BEGIN CERTIFICATE-----/ { n++ } n > 1 { print > (dir "/ca-" (n - 1) ".pem") }' \
+    "$CF_INSTANCE_CERT"
+  for ca in "$CATALINA_BASE/%[1]s"/ca-*.pem; do
+    [ -f "$ca" ] || continue
+    "$JAVA_HOME/bin/keytool" -importcert -noprompt -alias "$(basename "$ca" .pem)" -file "$ca" \
+      -keystore "$CATALINA_BASE/%[1]s/truststore.p12" -storetype PKCS12 -storepass %[2]s > /dev/null \
+      || echo "WARNING: Unable to add $(basename "$ca") of CF_INSTANCE_CERT to the client authentication trust store" >&2
+    rm -f "$ca"
+  done
+  [ -f "$CATALINA_BASE/%[1]s/truststore.p12" ] \
+    || echo "WARNING: CF_INSTANCE_CERT contains no CA certificate, the client authentication connector cannot verify clients" >&2
+else
+  echo "WARNING: CF_INSTANCE_CERT and CF_INSTANCE_KEY are not set, the client authentication connector has no certificate" >&2
+fi
+`, tomcatClientAuthDir, password)
+}
+
+// configureClientAuth adds the client authentication connector to conf/server.xml, after the default and external
+// configurations are installed, and creates its key store, or the script that creates it at runtime
+func (t *TomcatContainer) configureClientAuth(tomcatDir string) error {
+	clientAuth := t.config.ClientAuth
+	if !clientAuth.Enabled {
+		return nil
+	}
+
+	storeDir := filepath.Join(tomcatDir, filepath.FromSlash(tomcatClientAuthDir))
+	if err := os.MkdirAll(storeDir, 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", tomcatClientAuthDir, err)
+	}
+	password, err := randomPassword()
+	if err != nil {
+		return err
+	}
+
+	// The profile.d script creates the trust store of the instance identity from the CA certificates of the container
+	withTrustStore := true
+	if clientAuth.Service != "" {
+		if withTrustStore, err = t.createClientAuthStores(clientAuth.Service, storeDir, password); err != nil {
+			return err
+		}
+	} else if err := t.context.Stager.WriteProfileD("tomcat_client_auth.sh", TomcatClientAuthScript(password)); err != nil {
+		return fmt.Errorf("failed to write tomcat_client_auth.sh profile.d script: %w", err)
+	}
+
+	serverXMLPath := filepath.Join(tomcatDir, "conf", "server.xml")
+	serverXML, err := os.ReadFile(serverXMLPath)
+	if err != nil {
+		return fmt.Errorf("failed to read server.xml: %w", err)
+	}
+	content, err := InsertTomcatConnector(string(serverXML), TomcatClientAuthConnector(clientAuth, withTrustStore, password))
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(serverXMLPath, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write server.xml: %w", err)
+	}
+
+	source := "the instance identity certificate"
+	if clientAuth.Service != "" {
+		source = "the certificate of service " + clientAuth.Service
+	}
+	t.context.Log.Info("Added HTTPS connector on port %d that %s client certificates, presenting %s",
+		clientAuth.Port, clientAuthModeVerbs[clientAuth.Mode], source)
+	return nil
+}
+
+// createClientAuthStores creates the key store from the certificate and private_key credentials of the named service
+// and, if it has a ca_certificate credential, the trust store. It returns whether it created a trust store.
+func (t *TomcatContainer) createClientAuthStores(name, storeDir, password string) (bool, error) {
+	credentials, err := clientAuthCredentials(name)
+	if err != nil {
+		return false, err
+	}
+
+	certFile := filepath.Join(storeDir, "certificate.pem")
+	keyFile := filepath.Join(storeDir, "private_key.pem")
+	defer os.Remove(certFile)
+	defer os.Remove(keyFile)
+	if err := os.WriteFile(certFile, []byte(credentials["certificate"]+"\n"), 0600); err != nil {
+		return false, fmt.Errorf("failed to write client authentication certificate: %w", err)
+	}
+	if err := os.WriteFile(keyFile, []byte(credentials["private_key"]+"\n"), 0600); err != nil {
+		return false, fmt.Errorf("failed to write client authentication private key: %w", err)
+	}
+
+	output := new(strings.Builder)
+	if err := t.context.Command.Execute(storeDir, output, output, "openssl", "pkcs12", "-export",
+		"-in", certFile, "-inkey", keyFile, "-name", "tomcat",
+		"-out", filepath.Join(storeDir, "keystore.p12"), "-passout", "pass:"+password); err != nil {
+		return false, fmt.Errorf("failed to create the client authentication key store from service %s: %w, output: %s",
+			name, err, output.String())
+	}
+
+	rest := []byte(credentials["ca_certificate"])
+	for i := 0; ; i++ {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			return i > 0, nil
+		}
+
+		caFile := filepath.Join(storeDir, fmt.Sprintf("ca-%d.pem", i))
+		if err := os.WriteFile(caFile, pem.EncodeToMemory(block), 0600); err != nil {
+			return false, fmt.Errorf("failed to write client authentication CA certificate: %w", err)
+		}
+		output.Reset()
+		err := t.context.Command.Execute(storeDir, output, output, filepath.Join(os.Getenv("JAVA_HOME"), "bin", "keytool"),
+			"-importcert", "-noprompt", "-alias", fmt.Sprintf("ca-%d", i), "-file", caFile,
+			"-keystore", filepath.Join(storeDir, "truststore.p12"), "-storetype", "PKCS12", "-storepass", password)
+		os.Remove(caFile)
+		if err != nil {
+			return false, fmt.Errorf("failed to add the CA certificates of service %s to the client authentication trust "+
+				"store: %w, output: %s", name, err, output.String())
+		}
+	}
+}
+
+// clientAuthCredentials returns the certificate, private_key and ca_certificate credentials of the named service
+func clientAuthCredentials(name string) (map[string]string, error) {
+	services, err := common.GetVCAPServices()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse VCAP_SERVICES: %w", err)
+	}
+
+	for _, service := range services.Sorted() {
+		if service.Name != name {
+			continue
+		}
+		credentials := map[string]string{}
+		for _, key := range []string{"certificate", "private_key", "ca_certificate"} {
+			credentials[key], _ = service.Credentials[key].(string)
+		}
+		for _, key := range []string{"certificate", "private_key"} {
+			if credentials[key] == "" {
+				return nil, fmt.Errorf("service %s of client_auth.service has no %s credential", name, key)
+			}
+		}
+		return credentials, nil
+	}
+	return nil, fmt.Errorf("service %s of client_auth.service is not bound to the application", name)
+}
+
+// randomPassword returns a random password for the key and trust stores. It is stored in plaintext in server.xml,
+// and in the profile.d script of the instance identity, so it only keeps the stores from sharing a well-known
+// password; it does not protect them from anyone who can read the droplet.
+func randomPassword() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate key store password: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -1,11 +1,15 @@
 package containers_test
 
 import (
+	"archive/zip"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
 	"github.com/cloudfoundry/java-buildpack/src/java/containers"
+	"github.com/cloudfoundry/java-buildpack/src/java/resources"
 	"github.com/cloudfoundry/libbuildpack"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -109,6 +113,10 @@ var _ = Describe("Tomcat Container", func() {
 			Expect(cmd).To(ContainSubstring("CATALINA_HOME"))
 			Expect(cmd).To(ContainSubstring("catalina.sh run"))
 		})
+
+		It("binds to $PORT through the http.port system property", func() {
+			Expect(container.PortBinding()).To(Equal(containers.PortBinding{SystemProperty: "http.port"}))
+		})
 	})
 
 	Describe("Finalize", func() {
@@ -197,6 +205,396 @@ var _ = Describe("Tomcat Container", func() {
 		})
 	})
 
+	Describe("Finalize with WAR files", func() {
+		var contextDir string
+
+		// writeWar creates a WAR with a WEB-INF/web.xml and the given extra entries
+		writeWar := func(name string, entries map[string]string) {
+			f, err := os.Create(filepath.Join(buildDir, name))
+			Expect(err).NotTo(HaveOccurred())
+			defer f.Close()
+
+			w := zip.NewWriter(f)
+			entries["WEB-INF/web.xml"] = "<web-app/>"
+			for entry, content := range entries {
+				e, err := w.Create(entry)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = e.Write([]byte(content))
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(w.Close()).To(Succeed())
+		}
+
+		BeforeEach(func() {
+			contextDir = filepath.Join(depsDir, "0", "tomcat", "conf", "Catalina", "localhost")
+			Expect(os.MkdirAll(filepath.Join(depsDir, "0", "tomcat", "conf"), 0755)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			os.Unsetenv("JBP_CONFIG_TOMCAT")
+		})
+
+		It("deploys each WAR to a context named after its file", func() {
+			writeWar("shop.war", map[string]string{})
+			writeWar("admin.war", map[string]string{
+				"META-INF/context.xml": `<Context><Resource name="jdbc/Admin" type="javax.sql.DataSource"/></Context>`,
+			})
+
+			Expect(container.Finalize()).To(Succeed())
+
+			shop, err := os.ReadFile(filepath.Join(contextDir, "shop.xml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(shop)).To(ContainSubstring(`docBase="${user.home}/app/.java-buildpack/tomcat_webapps/shop"`))
+
+			admin, err := os.ReadFile(filepath.Join(contextDir, "admin.xml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(admin)).To(ContainSubstring(`docBase="${user.home}/app/.java-buildpack/tomcat_webapps/admin"`))
+			Expect(string(admin)).To(ContainSubstring("jdbc/Admin"))
+
+			Expect(filepath.Join(buildDir, ".java-buildpack", "tomcat_webapps", "shop", "WEB-INF", "web.xml")).To(BeARegularFile())
+			Expect(filepath.Join(buildDir, "shop.war")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(contextDir, "ROOT.xml")).NotTo(BeAnExistingFile())
+
+			script, err := os.ReadFile(filepath.Join(depsDir, "0", "profile.d", "zzz_classpath_symlinks_shop.sh"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(script)).To(ContainSubstring(`TARGET_DIR="$PWD/.java-buildpack/tomcat_webapps/shop/WEB-INF/lib"`))
+		})
+
+		It("serves ROOT.war and a single WAR at /", func() {
+			writeWar("ROOT.war", map[string]string{})
+			writeWar("api.war", map[string]string{})
+
+			Expect(container.Finalize()).To(Succeed())
+			Expect(filepath.Join(contextDir, "ROOT.xml")).To(BeAnExistingFile())
+			Expect(filepath.Join(contextDir, "api.xml")).To(BeAnExistingFile())
+		})
+
+		It("serves a single WAR at /", func() {
+			writeWar("petclinic.war", map[string]string{})
+
+			Expect(container.Finalize()).To(Succeed())
+
+			content, err := os.ReadFile(filepath.Join(contextDir, "ROOT.xml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring("tomcat_webapps/petclinic"))
+		})
+
+		It("uses context paths from context_path_map", func() {
+			os.Setenv("JBP_CONFIG_TOMCAT", `{context_path_map: {shop.war: /store/v2, admin: /}}`)
+			writeWar("shop.war", map[string]string{})
+			writeWar("admin.war", map[string]string{})
+
+			Expect(container.Finalize()).To(Succeed())
+			Expect(filepath.Join(contextDir, "store#v2.xml")).To(BeAnExistingFile())
+			Expect(filepath.Join(contextDir, "ROOT.xml")).To(BeAnExistingFile())
+		})
+
+		It("rejects two WARs mapped to the same context path", func() {
+			os.Setenv("JBP_CONFIG_TOMCAT", `{context_path_map: {shop: /app, admin: /app}}`)
+			writeWar("shop.war", map[string]string{})
+			writeWar("admin.war", map[string]string{})
+
+			Expect(container.Finalize()).To(MatchError(ContainSubstring(`are both mapped to context path "/app"`)))
+		})
+	})
+
+	Describe("Supply with unknown JBP_CONFIG_TOMCAT keys", func() {
+		BeforeEach(func() {
+			os.Setenv("JBP_CONFIG_TOMCAT", "{access_logging_support: {acess_logging: enabled}}")
+			os.Setenv("JBP_STRICT_CONFIG", "true")
+		})
+
+		AfterEach(func() {
+			os.Unsetenv("JBP_CONFIG_TOMCAT")
+			os.Unsetenv("JBP_STRICT_CONFIG")
+		})
+
+		It("fails staging in strict config mode", func() {
+			err := container.Supply()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unknown configuration keys in JBP_CONFIG_TOMCAT"))
+			Expect(err.Error()).To(ContainSubstring("acess_logging"))
+		})
+	})
+
+	Describe("Supply with invalid JBP_CONFIG_TOMCAT logging levels", func() {
+		BeforeEach(func() {
+			os.Setenv("JBP_CONFIG_TOMCAT", "{logging: {levels: {org.apache.catalina.realm: VERBOSE}}}")
+		})
+
+		AfterEach(func() {
+			os.Unsetenv("JBP_CONFIG_TOMCAT")
+		})
+
+		It("fails staging", func() {
+			err := container.Supply()
+			Expect(err).To(MatchError(ContainSubstring(`invalid level "VERBOSE" for logging.levels.org.apache.catalina.realm`)))
+		})
+	})
+
+	Describe("TomcatLoggingProperties", func() {
+		const properties = "handlers: org.cloudfoundry.tomcat.logging.CloudFoundryConsoleHandler\n" +
+			"org.cloudfoundry.tomcat.logging.CloudFoundryConsoleHandler.level: FINE\n"
+
+		It("leaves the properties unchanged without logging configuration", func() {
+			Expect(containers.TomcatLoggingProperties(properties, containers.Logging{})).To(Equal(properties))
+		})
+
+		It("appends the root level and the category levels", func() {
+			Expect(containers.TomcatLoggingProperties(properties, containers.Logging{
+				Level:  "warning",
+				Levels: map[string]string{"org.apache.coyote": "INFO", "org.apache.catalina.realm": "fine"},
+			})).To(Equal(properties + "\n# JBP_CONFIG_TOMCAT logging\n" +
+				".level: WARNING\n" +
+				"org.apache.catalina.realm.level: FINE\n" +
+				"org.apache.coyote.level: INFO\n"))
+		})
+
+		It("lowers the console handler level to the most verbose category level", func() {
+			Expect(containers.TomcatLoggingProperties(properties, containers.Logging{
+				Levels: map[string]string{"org.apache.catalina.realm": "FINEST"},
+			})).To(HaveSuffix("org.apache.catalina.realm.level: FINEST\n" +
+				"org.cloudfoundry.tomcat.logging.CloudFoundryConsoleHandler.level: FINEST\n"))
+		})
+
+		It("sets the properties of handlers", func() {
+			Expect(containers.TomcatLoggingProperties(properties, containers.Logging{
+				Levels: map[string]string{"org.apache.catalina.realm": "ALL"},
+				Handlers: map[string]map[string]string{
+					"org.cloudfoundry.tomcat.logging.CloudFoundryConsoleHandler": {"level": "INFO", "encoding": "UTF-8"},
+				},
+			})).To(HaveSuffix("org.apache.catalina.realm.level: ALL\n" +
+				"org.cloudfoundry.tomcat.logging.CloudFoundryConsoleHandler.encoding: UTF-8\n" +
+				"org.cloudfoundry.tomcat.logging.CloudFoundryConsoleHandler.level: INFO\n"))
+		})
+	})
+
+	Describe("Logging.Validate", func() {
+		It("accepts java.util.logging levels in any case", func() {
+			Expect(containers.Logging{Level: "off", Levels: map[string]string{"a.b": "Finer"}}.Validate()).To(Succeed())
+		})
+
+		It("rejects other levels", func() {
+			Expect(containers.Logging{Level: "DEBUG"}.Validate()).To(MatchError(ContainSubstring(`invalid level "DEBUG" for logging.level`)))
+			Expect(containers.Logging{Handlers: map[string]map[string]string{"h": {"level": "TRACE"}}}.Validate()).To(
+				MatchError(ContainSubstring("logging.handlers.h.level")))
+		})
+	})
+
+	Describe("Supply with an invalid JBP_CONFIG_TOMCAT client_auth mode", func() {
+		BeforeEach(func() {
+			os.Setenv("JBP_CONFIG_TOMCAT", "{client_auth: {enabled: true, mode: require}}")
+		})
+
+		AfterEach(func() {
+			os.Unsetenv("JBP_CONFIG_TOMCAT")
+		})
+
+		It("fails staging", func() {
+			err := container.Supply()
+			Expect(err).To(MatchError(ContainSubstring(`invalid client_auth.mode "require", expected want or need`)))
+		})
+	})
+
+	Describe("ClientAuth.Validate", func() {
+		It("accepts want and need", func() {
+			Expect(containers.ClientAuth{Enabled: true, Port: 8443, Mode: "want"}.Validate()).To(Succeed())
+			Expect(containers.ClientAuth{Enabled: true, Port: 9443, Mode: "need"}.Validate()).To(Succeed())
+		})
+
+		It("rejects ports that the connector cannot listen on", func() {
+			Expect(containers.ClientAuth{Enabled: true, Port: 70000, Mode: "need"}.Validate()).To(
+				MatchError(ContainSubstring("invalid client_auth.port 70000")))
+			Expect(containers.ClientAuth{Enabled: true, Port: 8080, Mode: "need"}.Validate()).To(
+				MatchError(ContainSubstring("port of the HTTP connector")))
+		})
+
+		It("does not check a disabled connector", func() {
+			Expect(containers.ClientAuth{Mode: "require"}.Validate()).To(Succeed())
+		})
+	})
+
+	Describe("TomcatClientAuthConnector", func() {
+		It("requires client certificates verified against the JVM trust store", func() {
+			connector := containers.TomcatClientAuthConnector(containers.ClientAuth{Port: 8443, Mode: "need"}, false, "secret")
+
+			Expect(connector).To(ContainSubstring("<Connector port='8443'"))
+			Expect(connector).To(ContainSubstring("SSLEnabled='true'"))
+			Expect(connector).To(ContainSubstring("<SSLHostConfig certificateVerification='required'>"))
+			Expect(connector).To(ContainSubstring("certificateKeystoreFile='${catalina.base}/conf/client_auth/keystore.p12'"))
+			Expect(connector).To(ContainSubstring("certificateKeystorePassword='secret'"))
+			Expect(connector).NotTo(ContainSubstring("truststoreFile"))
+		})
+
+		It("requests client certificates verified against the trust store of the service", func() {
+			connector := containers.TomcatClientAuthConnector(containers.ClientAuth{Port: 9443, Mode: "want"}, true, "secret")
+
+			Expect(connector).To(ContainSubstring("certificateVerification='optional'"))
+			Expect(connector).To(ContainSubstring("truststoreFile='${catalina.base}/conf/client_auth/truststore.p12'"))
+			Expect(connector).To(ContainSubstring("truststorePassword='secret'"))
+		})
+	})
+
+	Describe("InsertTomcatConnector", func() {
+		It("adds the connector to the Catalina service before the Engine", func() {
+			serverXML, err := resources.GetResource("tomcat/conf/server.xml")
+			Expect(err).NotTo(HaveOccurred())
+
+			content, err := containers.InsertTomcatConnector(string(serverXML), "        <Connector port='8443'/>\n")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(content).To(MatchRegexp(`(?s)<Connector port='\$\{http.port\}'.*</Connector>\n\n        <Connector port='8443'/>\n\n        <Engine `))
+		})
+
+		It("rejects a server.xml without Engine", func() {
+			_, err := containers.InsertTomcatConnector("<Server/>", "<Connector/>")
+			Expect(err).To(MatchError(ContainSubstring("server.xml has no Engine element")))
+		})
+	})
+
+	Describe("TomcatClientAuthScript", func() {
+		It("creates the key store from the instance identity certificate", func() {
+			script := containers.TomcatClientAuthScript("secret")
+
+			Expect(script).To(ContainSubstring(`openssl pkcs12 -export -in "$CF_INSTANCE_CERT" -inkey "$CF_INSTANCE_KEY"`))
+			Expect(script).To(ContainSubstring(`-out "$CATALINA_BASE/conf/client_auth/keystore.p12" -passout pass:secret`))
+		})
+
+		It("creates the trust store from the CA certificates of the instance identity certificate", func() {
+			storeDir := filepath.Join(buildDir, "conf", "client_auth")
+			javaHome := filepath.Join(buildDir, "jre")
+			Expect(os.MkdirAll(storeDir, 0755)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(javaHome, "bin"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(javaHome, "bin", "keytool"), []byte("#!/bin/sh\n"+
+				`echo "$@" >> "$CATALINA_BASE/keytool.log"; touch "$CATALINA_BASE/conf/client_auth/truststore.p12"`+"\n"), 0755)).To(Succeed())
+
+			var chain strings.Builder
+			for _, name := range []string{"instance", "intermediate", "root"} {
+				chain.WriteString("-----BEGIN CERTIFICATE-----\n" + name + "\n-----END CERTIFICATE-----\n")
+			}
+			Expect(os.WriteFile(filepath.Join(buildDir, "instance.crt"), []byte(chain.String()), 0600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(buildDir, "instance.key"), []byte("key"), 0600)).To(Succeed())
+
+			cmd := exec.Command("sh", "-c", containers.TomcatClientAuthScript("secret"))
+			cmd.Env = []string{"PATH=" + os.Getenv("PATH"), "CATALINA_BASE=" + buildDir, "JAVA_HOME=" + javaHome,
+				"CF_INSTANCE_CERT=" + filepath.Join(buildDir, "instance.crt"), "CF_INSTANCE_KEY=" + filepath.Join(buildDir, "instance.key")}
+			output, err := cmd.CombinedOutput()
+			Expect(err).NotTo(HaveOccurred(), string(output))
+
+			log, err := os.ReadFile(filepath.Join(buildDir, "keytool.log"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(log)).To(ContainSubstring("-alias ca-1 -file " + filepath.Join(storeDir, "ca-1.pem")))
+			Expect(string(log)).To(ContainSubstring("-alias ca-2 -file " + filepath.Join(storeDir, "ca-2.pem")))
+			Expect(string(log)).NotTo(ContainSubstring("ca-3"))
+			Expect(string(log)).To(ContainSubstring("-storepass secret"))
+			Expect(filepath.Join(storeDir, "ca-1.pem")).NotTo(BeAnExistingFile())
+			Expect(string(output)).NotTo(ContainSubstring("contains no CA certificate"))
+		})
+
+		It("warns when the instance identity certificate is not available", func() {
+			cmd := exec.Command("sh", "-c", containers.TomcatClientAuthScript("secret"))
+			cmd.Env = []string{"PATH=" + os.Getenv("PATH")}
+			output, err := cmd.CombinedOutput()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(output)).To(ContainSubstring("CF_INSTANCE_CERT and CF_INSTANCE_KEY are not set"))
+		})
+	})
+
+	Describe("TomcatSetenvScript", func() {
+		source := func(env ...string) string {
+			setenv := filepath.Join(buildDir, "setenv.sh")
+			Expect(os.WriteFile(setenv, []byte(containers.TomcatSetenvScript("tomcat-logging-support.jar")), 0755)).To(Succeed())
+
+			cmd := exec.Command("sh", "-c", `. "$0"; echo "JAVA_OPTS=$JAVA_OPTS"; echo "CATALINA_OPTS=$CATALINA_OPTS"; echo "CLASSPATH=$CLASSPATH"`, setenv)
+			cmd.Env = append([]string{"PATH=" + os.Getenv("PATH"), "CATALINA_HOME=/home/vcap/deps/0/tomcat"}, env...)
+			output, err := cmd.CombinedOutput()
+			Expect(err).NotTo(HaveOccurred(), string(output))
+			return string(output)
+		}
+
+		It("moves JVM options from JAVA_OPTS to CATALINA_OPTS", func() {
+			output := source("JAVA_OPTS=-javaagent:/deps/agent.jar -Xmx512M")
+			Expect(output).To(ContainSubstring("JAVA_OPTS=\n"))
+			Expect(output).To(ContainSubstring("CATALINA_OPTS=-javaagent:/deps/agent.jar -Xmx512M\n"))
+		})
+
+		It("keeps user CATALINA_OPTS after the buildpack options", func() {
+			output := source("JAVA_OPTS=-Xmx512M", "CATALINA_OPTS=-Xmx768M")
+			Expect(output).To(ContainSubstring("CATALINA_OPTS=-Xmx512M -Xmx768M\n"))
+		})
+
+		It("leaves CATALINA_OPTS untouched when JAVA_OPTS is empty", func() {
+			output := source("CATALINA_OPTS=-Duser=true")
+			Expect(output).To(ContainSubstring("CATALINA_OPTS=-Duser=true\n"))
+		})
+
+		It("puts the logging support JAR on the CLASSPATH", func() {
+			output := source("CONTAINER_SECURITY_PROVIDER=/deps/csp.jar")
+			Expect(output).To(ContainSubstring("CLASSPATH=/home/vcap/deps/0/tomcat/bin/tomcat-logging-support.jar:/deps/csp.jar\n"))
+		})
+	})
+
+	Describe("TomcatOverlayScript", func() {
+		var depsDir, catalinaBase string
+
+		BeforeEach(func() {
+			depsDir = filepath.Join(buildDir, "deps")
+			catalinaBase = filepath.Join(depsDir, "0", "tomcat")
+			Expect(os.MkdirAll(filepath.Join(catalinaBase, "conf"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(catalinaBase, "conf", "context.xml"), []byte("tomcat"), 0644)).To(Succeed())
+		})
+
+		overlay := func(name, file, content string) {
+			path := filepath.Join(depsDir, "0", "tomcat_overlays", name, file)
+			Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+			Expect(os.WriteFile(path, []byte(content), 0644)).To(Succeed())
+		}
+
+		source := func() string {
+			script := filepath.Join(buildDir, "tomcat_overlays.sh")
+			Expect(os.WriteFile(script, []byte(containers.TomcatOverlayScript("0")), 0755)).To(Succeed())
+
+			cmd := exec.Command("bash", "-c", `. "$0"`, script)
+			cmd.Env = []string{"PATH=" + os.Getenv("PATH"), "DEPS_DIR=" + depsDir, "CATALINA_BASE=" + catalinaBase}
+			output, err := cmd.CombinedOutput()
+			Expect(err).NotTo(HaveOccurred(), string(output))
+			return string(output)
+		}
+
+		It("links the files of every overlay into CATALINA_BASE", func() {
+			overlay("jndi", "lib/jndi-factory.jar", "jndi")
+			overlay("session", "lib/session-manager.jar", "session")
+			overlay("session", "shared/session.properties", "properties")
+
+			Expect(source()).To(BeEmpty())
+			Expect(os.ReadFile(filepath.Join(catalinaBase, "lib", "jndi-factory.jar"))).To(Equal([]byte("jndi")))
+			Expect(os.ReadFile(filepath.Join(catalinaBase, "lib", "session-manager.jar"))).To(Equal([]byte("session")))
+			Expect(os.ReadFile(filepath.Join(catalinaBase, "shared", "session.properties"))).To(Equal([]byte("properties")))
+		})
+
+		It("keeps existing files", func() {
+			overlay("a", "lib/common.jar", "a")
+			overlay("b", "lib/common.jar", "b")
+			overlay("b", "conf/context.xml", "b")
+
+			output := source()
+			Expect(output).To(ContainSubstring("Tomcat overlay b does not replace existing lib/common.jar"))
+			Expect(output).To(ContainSubstring("Tomcat overlay b does not replace existing conf/context.xml"))
+			Expect(os.ReadFile(filepath.Join(catalinaBase, "lib", "common.jar"))).To(Equal([]byte("a")))
+			Expect(os.ReadFile(filepath.Join(catalinaBase, "conf", "context.xml"))).To(Equal([]byte("tomcat")))
+		})
+
+		It("can run again on restart", func() {
+			overlay("session", "lib/session-manager.jar", "session")
+
+			Expect(source()).To(BeEmpty())
+			Expect(source()).To(BeEmpty())
+		})
+
+		It("does nothing without overlays", func() {
+			Expect(source()).To(BeEmpty())
+		})
+	})
+
 	Describe("determineTomcatVersion", func() {
 		It("returns empty string when JBP_CONFIG_TOMCAT is empty", func() {
 			v := containers.DetermineTomcatVersion("")
@@ -221,4 +619,70 @@ var _ = Describe("Tomcat Container", func() {
 			Expect(v).To(Equal("10.1.*"))
 		})
 	})
+
+	Describe("ResolveTomcatVersion", func() {
+		versions := []string{"9.0.113", "10.1.54", "11.0.21"}
+
+		It("resolves a pinned Tomcat line", func() {
+			v, err := containers.ResolveTomcatVersion(containers.DetermineTomcatVersion("9.0.+"), 17, versions)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(v).To(Equal("9.0.113"))
+		})
+
+		It("picks the newest version that runs on the detected Java", func() {
+			v, err := containers.ResolveTomcatVersion("*", 11, versions)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(v).To(Equal("10.1.54"))
+		})
+
+		It("skips the Java check when the Java version is unknown", func() {
+			v, err := containers.ResolveTomcatVersion("11.*", 0, versions)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(v).To(Equal("11.0.21"))
+		})
+
+		It("reports a Tomcat line that needs a newer Java", func() {
+			_, err := containers.ResolveTomcatVersion("10.*", 8, versions)
+			Expect(err).To(MatchError("Tomcat 10.1.54 requires Java 11+, but Java 8 detected"))
+		})
+
+		It("lists the available versions when the line is not in the manifest", func() {
+			_, err := containers.ResolveTomcatVersion("8.5.*", 17, versions)
+			Expect(err).To(MatchError(ContainSubstring(`no Tomcat version matching "8.5.*" is available`)))
+			Expect(err).To(MatchError(ContainSubstring("9.0.113, 10.1.54, 11.0.21")))
+		})
+	})
+	Describe("LookupExternalConfiguration", func() {
+		index := []byte(`1.3.0: https://repo.example.com/tomcat-config-1.3.0.tar.gz
+1.4.0:
+  uri: https://repo.example.com/tomcat-config-1.4.0.tar.gz
+  sha256: 1f2e3d4c
+1.5.0:
+  sha256: 1f2e3d4c
+`)
+
+		It("returns the URL of a version without checksum", func() {
+			uri, sha256, err := containers.LookupExternalConfiguration(index, "1.3.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(uri).To(Equal("https://repo.example.com/tomcat-config-1.3.0.tar.gz"))
+			Expect(sha256).To(BeEmpty())
+		})
+
+		It("returns the URL and checksum of a version with metadata", func() {
+			uri, sha256, err := containers.LookupExternalConfiguration(index, "1.4.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(uri).To(Equal("https://repo.example.com/tomcat-config-1.4.0.tar.gz"))
+			Expect(sha256).To(Equal("1f2e3d4c"))
+		})
+
+		It("rejects a version without uri", func() {
+			_, _, err := containers.LookupExternalConfiguration(index, "1.5.0")
+			Expect(err).To(MatchError("version 1.5.0 in index.yml has no uri"))
+		})
+
+		It("reports a missing version", func() {
+			_, _, err := containers.LookupExternalConfiguration(index, "2.0.0")
+			Expect(err).To(MatchError(ContainSubstring("version 2.0.0 not found in index.yml")))
+		})
+	})
 })
@@ -0,0 +1,45 @@
+package containers
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// windowsMarkers are files of Windows applications that the hwc buildpack runs
+var windowsMarkers = []string{"Web.config", "web.config"}
+
+// WindowsArtifacts returns the files that mark the application in buildDir as a Windows application, relative to
+// buildDir: the .bat start scripts of a bin directory without a Linux start script, as in a distribution whose Linux
+// scripts were left out, and the Web.config of an HWC application. Containers ignore .bat scripts, so such an
+// application is not detected and these files explain why.
+func WindowsArtifacts(buildDir string) []string {
+	var artifacts []string
+	for _, binDir := range []string{"bin", filepath.Join("application-root", "bin")} {
+		entries, err := os.ReadDir(filepath.Join(buildDir, binDir))
+		if err != nil {
+			continue
+		}
+
+		var scripts []string
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			if !strings.EqualFold(filepath.Ext(entry.Name()), ".bat") {
+				scripts = nil
+				break
+			}
+			scripts = append(scripts, filepath.ToSlash(filepath.Join(binDir, entry.Name())))
+		}
+		artifacts = append(artifacts, scripts...)
+	}
+
+	for _, marker := range windowsMarkers {
+		if _, err := os.Stat(filepath.Join(buildDir, marker)); err == nil {
+			artifacts = append(artifacts, marker)
+			break
+		}
+	}
+	return artifacts
+}
@@ -0,0 +1,47 @@
+package containers_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/containers"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WindowsArtifacts", func() {
+	var buildDir string
+
+	BeforeEach(func() {
+		var err error
+		buildDir, err = os.MkdirTemp("", "build")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(buildDir)
+	})
+
+	writeFile := func(name string) {
+		path := filepath.Join(buildDir, name)
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(""), 0644)).To(Succeed())
+	}
+
+	It("returns nothing for a Linux application", func() {
+		writeFile(filepath.Join("bin", "app"))
+		writeFile(filepath.Join("bin", "app.bat"))
+		Expect(containers.WindowsArtifacts(buildDir)).To(BeEmpty())
+	})
+
+	It("returns the .bat scripts of a distribution without Linux start scripts", func() {
+		writeFile(filepath.Join("bin", "app.bat"))
+		writeFile(filepath.Join("application-root", "bin", "server.BAT"))
+		Expect(containers.WindowsArtifacts(buildDir)).To(Equal([]string{"bin/app.bat", "application-root/bin/server.BAT"}))
+	})
+
+	It("returns the Web.config of an HWC application", func() {
+		writeFile("Web.config")
+		Expect(containers.WindowsArtifacts(buildDir)).To(Equal([]string{"Web.config"}))
+	})
+})
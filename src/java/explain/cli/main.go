@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/explain"
+	"github.com/cloudfoundry/libbuildpack"
+)
+
+// Usage: explain [--json] <build-dir>, usually run as bin/detect --explain [--json] <build-dir>
+func main() {
+	logger := libbuildpack.NewLogger(os.Stderr)
+
+	flags := flag.NewFlagSet("explain", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: bin/detect --explain [--json] <build-dir>")
+	}
+	if err := flags.Parse(os.Args[1:]); err != nil || flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	buildDir, err := filepath.Abs(flags.Arg(0))
+	if err != nil {
+		logger.Error("Unable to determine build directory: %s", err.Error())
+		os.Exit(9)
+	}
+
+	buildpackDir, err := libbuildpack.GetBuildpackDir()
+	if err != nil {
+		logger.Error("Unable to determine buildpack directory: %s", err.Error())
+		os.Exit(9)
+	}
+	// Configuration defaults are read from $BUILDPACK_DIR
+	if os.Getenv("BUILDPACK_DIR") == "" {
+		os.Setenv("BUILDPACK_DIR", buildpackDir)
+	}
+
+	manifest, err := libbuildpack.NewManifest(buildpackDir, logger, time.Now())
+	if err != nil {
+		logger.Error("Unable to load buildpack manifest: %s", err.Error())
+		os.Exit(10)
+	}
+
+	// Detection may link files into the deps directory, so it runs against temporary deps and cache directories
+	workDir, err := os.MkdirTemp("", "java-buildpack-explain")
+	if err != nil {
+		logger.Error("Unable to create temporary directory: %s", err.Error())
+		os.Exit(8)
+	}
+	defer os.RemoveAll(workDir)
+	depsDir, cacheDir := filepath.Join(workDir, "deps"), filepath.Join(workDir, "cache")
+	for _, dir := range []string{filepath.Join(depsDir, "0"), cacheDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			logger.Error("Could not create directory: %s", err.Error())
+			os.Exit(8)
+		}
+	}
+
+	stager := libbuildpack.NewStager([]string{buildDir, cacheDir, depsDir, "0"}, logger, manifest)
+	report, err := explain.Run(stager, manifest, libbuildpack.NewInstaller(manifest))
+	if err != nil {
+		logger.Error("Detection failed: %s", err.Error())
+		os.RemoveAll(workDir)
+		os.Exit(14)
+	}
+
+	if *asJSON {
+		err = report.WriteJSON(os.Stdout)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		logger.Error("Unable to write report: %s", err.Error())
+		os.RemoveAll(workDir)
+		os.Exit(14)
+	}
+}
@@ -0,0 +1,190 @@
+// Package explain implements bin/detect --explain, which reports the containers and frameworks the buildpack
+// detects for an application and why it does not detect the others, without installing anything. It helps users
+// find out why an agent is not installed, e.g. because its service binding lacks a credential.
+//
+// Components log why they detect or reject an application at debug level; Run records these messages for each
+// component and reports the last one as the reason.
+package explain
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/containers"
+	"github.com/cloudfoundry/java-buildpack/src/java/frameworks"
+	"github.com/cloudfoundry/libbuildpack"
+)
+
+// Result is the detection result of a container or framework
+type Result struct {
+	// Component is the name JBP_CONFIG_COMPONENTS lists the component by, e.g. NewRelicAgent
+	Component string `json:"component"`
+	// Detected is the name the component was detected as, e.g. New Relic Agent
+	Detected string `json:"detected,omitempty"`
+	Matched  bool   `json:"matched"`
+	// Reason explains why the component was detected or not
+	Reason string `json:"reason"`
+	// Log are the messages the component logged during detection
+	Log []string `json:"log,omitempty"`
+}
+
+// Report is the detection result of all containers and frameworks
+type Report struct {
+	// Container is the container that stages the application, the first one detected; empty if none is
+	Container  string   `json:"container"`
+	Containers []Result `json:"containers"`
+	Frameworks []Result `json:"frameworks"`
+}
+
+// notAllowed is the reason for components that JBP_CONFIG_COMPONENTS does not allow
+const notAllowed = "JBP_CONFIG_COMPONENTS does not allow it"
+
+// noReason is the reason for components that do not log why they are not detected
+const noReason = "its detection criteria are not met, see its documentation"
+
+// Run detects the containers and frameworks for the application staged by stager, with debug messages enabled to
+// capture why. Detection may link files into the deps directory of the stager, so it should be a temporary one.
+func Run(stager common.Stager, manifest common.Manifest, installer common.Installer) (Report, error) {
+	if previous, ok := os.LookupEnv("BP_DEBUG"); ok {
+		defer os.Setenv("BP_DEBUG", previous)
+	} else {
+		defer os.Unsetenv("BP_DEBUG")
+	}
+	os.Setenv("BP_DEBUG", "true")
+
+	recorder := new(bytes.Buffer)
+	ctx := &common.Context{
+		Stager:    stager,
+		Manifest:  manifest,
+		Installer: installer,
+		Log:       libbuildpack.NewLogger(recorder),
+		Command:   &libbuildpack.Command{},
+	}
+
+	var report Report
+
+	containerRegistry := containers.NewRegistry(ctx)
+	containerRegistry.RegisterStandardContainers()
+	containerCandidates, err := containerRegistry.Candidates()
+	if err != nil {
+		return Report{}, err
+	}
+	for _, candidate := range containerCandidates {
+		result := detect(candidate.Component, candidate.Allowed, candidate.Container.Detect, recorder)
+		if result.Matched && report.Container == "" {
+			report.Container = result.Detected
+		} else if result.Matched {
+			result.Reason = fmt.Sprintf("%s; not used, the application is staged with %s", result.Reason, report.Container)
+		}
+		report.Containers = append(report.Containers, result)
+	}
+
+	frameworkRegistry := frameworks.NewRegistry(ctx)
+	frameworkRegistry.RegisterStandardFrameworks()
+	frameworkCandidates, err := frameworkRegistry.Candidates()
+	if err != nil {
+		return Report{}, err
+	}
+	for _, candidate := range frameworkCandidates {
+		report.Frameworks = append(report.Frameworks, detect(candidate.Component, candidate.Allowed, candidate.Framework.Detect, recorder))
+	}
+
+	return report, nil
+}
+
+// detect runs the Detect method of a component and explains its result with the messages it logs to recorder
+func detect(component string, allowed bool, detector func() (string, error), recorder *bytes.Buffer) Result {
+	result := Result{Component: component}
+	if !allowed {
+		result.Reason = notAllowed
+		return result
+	}
+
+	recorder.Reset()
+	name, err := detector()
+	result.Log = messages(recorder.String())
+
+	switch {
+	case err != nil:
+		result.Reason = "detection failed: " + err.Error()
+	case name != "":
+		result.Matched = true
+		result.Detected = name
+		result.Reason = "detected"
+		if len(result.Log) > 0 {
+			result.Reason = result.Log[len(result.Log)-1]
+		}
+	case len(result.Log) > 0:
+		result.Reason = result.Log[len(result.Log)-1]
+	default:
+		result.Reason = noReason
+	}
+	return result
+}
+
+// ansiEscape matches the color codes of the libbuildpack logger
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// messages returns the messages in the output of a libbuildpack logger without their headers
+func messages(output string) []string {
+	var messages []string
+	for _, line := range strings.Split(ansiEscape.ReplaceAllString(output, ""), "\n") {
+		line = strings.TrimSpace(line)
+		for _, header := range []string{"DEBUG:", "**WARNING**", "**ERROR**", "----->"} {
+			line = strings.TrimSpace(strings.TrimPrefix(line, header))
+		}
+		if line != "" {
+			messages = append(messages, line)
+		}
+	}
+	return messages
+}
+
+// WriteJSON writes the report as JSON
+func (r Report) WriteJSON(w io.Writer) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// WriteText writes the report as a table for humans, marking detected components with + and the others with -
+func (r Report) WriteText(w io.Writer) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	if r.Container != "" {
+		fmt.Fprintf(table, "Container: %s\n", r.Container)
+	} else {
+		fmt.Fprintln(table, "Container: none, the buildpack cannot stage the application")
+	}
+	fmt.Fprintln(table, "\nContainers, in detection order:")
+	writeResults(table, r.Containers)
+	fmt.Fprintln(table, "\nFrameworks:")
+	writeResults(table, r.Frameworks)
+
+	return table.Flush()
+}
+
+// writeResults writes a line for each result
+func writeResults(w io.Writer, results []Result) {
+	for _, result := range results {
+		mark := "-"
+		if result.Matched {
+			mark = "+"
+		}
+		component := result.Component
+		if component == "" {
+			component = result.Detected
+		}
+		fmt.Fprintf(w, "  %s %s\t%s\n", mark, component, result.Reason)
+	}
+}
@@ -0,0 +1,142 @@
+package explain_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/explain"
+	"github.com/cloudfoundry/libbuildpack"
+)
+
+var _ = Describe("Explain", func() {
+	var (
+		buildDir string
+		cacheDir string
+		depsDir  string
+		stager   *libbuildpack.Stager
+		manifest *libbuildpack.Manifest
+	)
+
+	BeforeEach(func() {
+		var err error
+		buildDir, err = os.MkdirTemp("", "explain-build")
+		Expect(err).NotTo(HaveOccurred())
+		cacheDir, err = os.MkdirTemp("", "explain-cache")
+		Expect(err).NotTo(HaveOccurred())
+		depsDir, err = os.MkdirTemp("", "explain-deps")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.MkdirAll(filepath.Join(depsDir, "0"), 0755)).To(Succeed())
+
+		manifest = &libbuildpack.Manifest{}
+		stager = libbuildpack.NewStager([]string{buildDir, cacheDir, depsDir, "0"}, libbuildpack.NewLogger(GinkgoWriter), manifest)
+		Expect(os.MkdirAll(filepath.Join(buildDir, "WEB-INF"), 0755)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(buildDir)
+		os.RemoveAll(cacheDir)
+		os.RemoveAll(depsDir)
+		os.Unsetenv("VCAP_SERVICES")
+		os.Unsetenv("JBP_CONFIG_COMPONENTS")
+		os.Unsetenv("BP_DEBUG")
+	})
+
+	result := func(results []explain.Result, component string) explain.Result {
+		for _, result := range results {
+			if result.Component == component {
+				return result
+			}
+		}
+		Fail("no result for " + component)
+		return explain.Result{}
+	}
+
+	It("reports the container that stages the application and why the others do not", func() {
+		report, err := explain.Run(stager, manifest, &libbuildpack.Installer{})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(report.Container).To(Equal("Tomcat"))
+		Expect(result(report.Containers, "Tomcat")).To(Equal(explain.Result{
+			Component: "Tomcat",
+			Detected:  "Tomcat",
+			Matched:   true,
+			Reason:    "Detected WAR application via WEB-INF directory",
+			Log:       []string{"Detected WAR application via WEB-INF directory"},
+		}))
+		springBoot := result(report.Containers, "SpringBoot")
+		Expect(springBoot.Matched).To(BeFalse())
+		Expect(springBoot.Reason).To(ContainSubstring("Spring Boot: no BOOT-INF directory"))
+	})
+
+	It("reports frameworks whose service binding is missing or lacks credentials", func() {
+		os.Setenv("VCAP_SERVICES", `{"user-provided": [{"name": "my-seeker", "label": "user-provided", "credentials": {}}]}`)
+
+		report, err := explain.Run(stager, manifest, &libbuildpack.Installer{})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(result(report.Frameworks, "SeekerSecurityProvider").Reason).To(Equal("Seeker: the seeker service has no seeker_server_url credential"))
+		Expect(result(report.Frameworks, "AppDynamicsAgent").Reason).To(Equal("AppDynamics: no appdynamics service bound"))
+		Expect(result(report.Frameworks, "JavaOpts").Matched).To(BeTrue())
+	})
+
+	It("reports components that JBP_CONFIG_COMPONENTS does not allow without detecting them", func() {
+		os.Setenv("JBP_CONFIG_COMPONENTS", `{containers: ["-Tomcat"], frameworks: ["-JavaOpts"]}`)
+
+		report, err := explain.Run(stager, manifest, &libbuildpack.Installer{})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(report.Container).To(BeEmpty())
+		Expect(result(report.Containers, "Tomcat")).To(Equal(explain.Result{Component: "Tomcat", Reason: "JBP_CONFIG_COMPONENTS does not allow it"}))
+		Expect(result(report.Frameworks, "JavaOpts").Reason).To(Equal("JBP_CONFIG_COMPONENTS does not allow it"))
+	})
+
+	It("enables debug messages only while it runs", func() {
+		_, err := explain.Run(stager, manifest, &libbuildpack.Installer{})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, set := os.LookupEnv("BP_DEBUG")
+		Expect(set).To(BeFalse())
+	})
+
+	Describe("WriteText and WriteJSON", func() {
+		var report explain.Report
+
+		BeforeEach(func() {
+			report = explain.Report{
+				Container: "Tomcat",
+				Containers: []explain.Result{
+					{Component: "SpringBoot", Reason: "Spring Boot: no BOOT-INF directory"},
+					{Component: "Tomcat", Detected: "Tomcat", Matched: true, Reason: "Detected WAR file: app.war"},
+				},
+				Frameworks: []explain.Result{
+					{Component: "NewRelicAgent", Reason: "New Relic: no newrelic service bound"},
+				},
+			}
+		})
+
+		It("writes a table marking detected components", func() {
+			out := new(bytes.Buffer)
+			Expect(report.WriteText(out)).To(Succeed())
+
+			Expect(out.String()).To(ContainSubstring("Container: Tomcat\n"))
+			Expect(out.String()).To(MatchRegexp(`  - SpringBoot +Spring Boot: no BOOT-INF directory\n`))
+			Expect(out.String()).To(MatchRegexp(`  \+ Tomcat +Detected WAR file: app.war\n`))
+			Expect(out.String()).To(MatchRegexp(`Frameworks:\n  - NewRelicAgent +New Relic: no newrelic service bound\n`))
+		})
+
+		It("writes JSON", func() {
+			out := new(bytes.Buffer)
+			Expect(report.WriteJSON(out)).To(Succeed())
+
+			var decoded explain.Report
+			Expect(json.Unmarshal(out.Bytes(), &decoded)).To(Succeed())
+			Expect(decoded).To(Equal(report))
+			Expect(out.String()).To(ContainSubstring(`"matched": false`))
+		})
+	})
+})
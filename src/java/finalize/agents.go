@@ -0,0 +1,106 @@
+package finalize
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
+)
+
+// AgentsFile lists the agents attached to the JVM in the order the JVM attaches them, in the deps directory
+const AgentsFile = "agents.json"
+
+// agentsManifest is the content of AgentsFile
+type agentsManifest struct {
+	Agents []agentEntry `json:"agents"`
+}
+
+// agentEntry is an agent attached to the JVM. Contribution names the .opts file that attaches it, so that the
+// agent can be dropped at runtime with BPL_<CONTRIBUTION>_ENABLED=false.
+type agentEntry struct {
+	Order        int    `json:"order"`
+	Contribution string `json:"contribution"`
+	Priority     int    `json:"priority"`
+	Type         string `json:"type"`
+	Path         string `json:"path"`
+	Options      string `json:"options,omitempty"`
+	Dependency   string `json:"dependency,omitempty"`
+	Version      string `json:"version,omitempty"`
+}
+
+// writeAgentsManifest records the agents of the assembled JAVA_OPTS, with the versions of the dependencies they
+// were installed from, so that the instrumentation of a running application can be read without decoding JAVA_OPTS.
+// It must run after assembleJavaOpts has removed the duplicate flags.
+func (f *Finalizer) writeAgentsManifest(ctx *common.Context) error {
+	depDir := f.Stager.DepDir()
+	contributions, err := javaopts.Read(depDir)
+	if err != nil {
+		return err
+	}
+	installed, err := common.ReadInstalledDependencies(filepath.Join(depDir, common.InstalledDependenciesFile))
+	if err != nil {
+		return err
+	}
+
+	droplet := ctx.Droplet()
+	manifest := agentsManifest{Agents: []agentEntry{}}
+	for i, agent := range javaopts.Agents(contributions) {
+		entry := agentEntry{
+			Order:        i + 1,
+			Contribution: agent.Contribution,
+			Priority:     agent.Priority,
+			Type:         agent.Type,
+			Path:         agent.Path,
+			Options:      agent.Options,
+		}
+		if agent.Type != "agentlib" {
+			entry.Path = droplet.Absolute(agent.Path)
+		}
+		if dep, ok := agentDependency(installed, droplet.AbsoluteDep(), entry.Path); ok {
+			entry.Dependency = dep.Name
+			entry.Version = dep.Version
+		}
+		manifest.Agents = append(manifest.Agents, entry)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(depDir, AgentsFile), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", AgentsFile, err)
+	}
+
+	for _, entry := range manifest.Agents {
+		version := ""
+		if entry.Version != "" {
+			version = " " + entry.Version
+		}
+		f.Log.Debug("Agent %d: %s%s (%s) from %s", entry.Order, entry.Path, version, entry.Type, entry.Contribution)
+	}
+	return nil
+}
+
+// agentDependency returns the installed dependency whose directory contains the agent at path, a path in depDir,
+// e.g. /home/vcap/deps/0/new_relic_agent/newrelic.jar. The deepest directory wins when dependencies are nested.
+func agentDependency(installed []common.InstalledDependency, depDir, path string) (common.InstalledDependency, bool) {
+	rel, ok := strings.CutPrefix(path, depDir+"/")
+	if !ok {
+		return common.InstalledDependency{}, false
+	}
+
+	var found common.InstalledDependency
+	for _, dep := range installed {
+		if dep.Dir == "" || dep.Dir == "." || len(dep.Dir) <= len(found.Dir) {
+			continue
+		}
+		if rel == dep.Dir || strings.HasPrefix(rel, dep.Dir+"/") {
+			found = dep
+		}
+	}
+	return found, found.Name != ""
+}
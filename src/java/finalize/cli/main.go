@@ -3,8 +3,10 @@ package main
 import (
 	"io"
 	"os"
+	"path/filepath"
 	"time"
 
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
 	"github.com/cloudfoundry/java-buildpack/src/java/finalize"
 	_ "github.com/cloudfoundry/java-buildpack/src/java/hooks" // Register hooks (Dynatrace)
 	"github.com/cloudfoundry/libbuildpack"
@@ -34,8 +36,13 @@ func main() {
 		os.Exit(10)
 	}
 
-	installer := libbuildpack.NewInstaller(manifest)
+	installer, err := common.NewDependencyInstaller(manifest, logger)
+	if err != nil {
+		logger.Error("Unable to load dependency mirrors: %s", err.Error())
+		os.Exit(10)
+	}
 	stager := libbuildpack.NewStager(os.Args[1:], logger, manifest)
+	installer.RecordInstallsTo(filepath.Join(stager.DepDir(), common.InstalledDependenciesFile))
 
 	if err = manifest.ApplyOverride(stager.DepsDir()); err != nil {
 		logger.Error("Unable to apply override.yml files: %s", err)
@@ -0,0 +1,206 @@
+package finalize
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+)
+
+// dropletConfig selects application files that are removed from the droplet, e.g.
+//
+//	exclude: [/src/, /docs/, "*.map"]
+//	include: [/docs/licenses/]
+//
+// Patterns follow .gitignore conventions: a leading / anchors the pattern to the application root, otherwise it
+// matches at any depth, and a trailing / only matches directories. Paths matching include are always kept.
+type dropletConfig struct {
+	Exclude []string `yaml:"exclude"`
+	Include []string `yaml:"include"`
+}
+
+func init() {
+	config.RegisterSchema("droplet", func() interface{} { return &dropletConfig{} })
+}
+
+// dropletPattern is a parsed exclude or include pattern
+type dropletPattern struct {
+	glob     string
+	anchored bool
+	dirOnly  bool
+}
+
+// slimDroplet removes the excluded files from the build directory and reports the bytes saved
+func (f *Finalizer) slimDroplet() error {
+	cfg := dropletConfig{}
+	if err := config.Load(f.Log, "droplet", &cfg); err != nil {
+		return err
+	}
+	if len(cfg.Exclude) == 0 {
+		return nil
+	}
+
+	exclude, err := parseDropletPatterns(cfg.Exclude)
+	if err != nil {
+		return err
+	}
+	include, err := parseDropletPatterns(cfg.Include)
+	if err != nil {
+		return err
+	}
+
+	f.Log.BeginStep("Removing excluded files from the droplet")
+	s := &dropletSlimmer{f: f, exclude: exclude, include: include}
+	if err := s.slim(f.Stager.BuildDir(), "", false); err != nil {
+		return fmt.Errorf("failed to remove excluded files: %w", err)
+	}
+
+	f.Log.Info("Removed %d files (%s) from the droplet", s.files, formatBytes(s.bytes))
+	return nil
+}
+
+// parseDropletPatterns parses patterns and rejects malformed globs
+func parseDropletPatterns(patterns []string) ([]dropletPattern, error) {
+	var parsed []dropletPattern
+	for _, p := range patterns {
+		pattern := dropletPattern{glob: strings.TrimSpace(p)}
+		if strings.HasSuffix(pattern.glob, "/") {
+			pattern.dirOnly = true
+			pattern.glob = strings.TrimRight(pattern.glob, "/")
+		}
+		if strings.HasPrefix(pattern.glob, "/") {
+			pattern.anchored = true
+			pattern.glob = strings.TrimLeft(pattern.glob, "/")
+		}
+		// Like .gitignore, a pattern with a slash in the middle is relative to the application root
+		if strings.Contains(pattern.glob, "/") {
+			pattern.anchored = true
+		}
+		if pattern.glob == "" {
+			return nil, fmt.Errorf("invalid droplet pattern %q: matches the application root", p)
+		}
+		if _, err := path.Match(pattern.glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid droplet pattern %q: %w", p, err)
+		}
+		parsed = append(parsed, pattern)
+	}
+	return parsed, nil
+}
+
+// matches returns true if the slash-separated path rel, relative to the application root, matches the pattern
+func (p dropletPattern) matches(rel string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+	if p.anchored {
+		ok, _ := path.Match(p.glob, rel)
+		return ok
+	}
+	ok, _ := path.Match(p.glob, path.Base(rel))
+	return ok
+}
+
+// dropletSlimmer walks the build directory and counts what it removes
+type dropletSlimmer struct {
+	f       *Finalizer
+	exclude []dropletPattern
+	include []dropletPattern
+	files   int
+	bytes   int64
+}
+
+// slim removes the excluded entries of dir. Inside an excluded directory, entries matching include are kept along
+// with the directories leading to them.
+func (s *dropletSlimmer) slim(dir, rel string, excluded bool) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		entryPath := filepath.Join(dir, entry.Name())
+		entryRel := path.Join(rel, entry.Name())
+		isDir := entry.IsDir()
+
+		if matchesAny(s.include, entryRel, isDir) {
+			continue
+		}
+		entryExcluded := excluded || matchesAny(s.exclude, entryRel, isDir)
+
+		if isDir {
+			if entryExcluded && len(s.include) == 0 {
+				if err := s.remove(entryPath, entryRel); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := s.slim(entryPath, entryRel, entryExcluded); err != nil {
+				return err
+			}
+			if entryExcluded {
+				// Fails while included entries remain, which keeps the directory
+				_ = os.Remove(entryPath)
+			}
+			continue
+		}
+
+		if entryExcluded {
+			if err := s.remove(entryPath, entryRel); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// remove deletes path, a file or a whole directory, and counts its files and bytes
+func (s *dropletSlimmer) remove(entryPath, rel string) error {
+	err := filepath.WalkDir(entryPath, func(_ string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		s.files++
+		s.bytes += info.Size()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.f.Log.Debug("Removing %s", rel)
+	return os.RemoveAll(entryPath)
+}
+
+// matchesAny returns true if any of patterns matches rel
+func matchesAny(patterns []dropletPattern, rel string, isDir bool) bool {
+	for _, p := range patterns {
+		if p.matches(rel, isDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatBytes formats a size with binary units, e.g. 1536 -> "1.5 KiB"
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -7,6 +7,9 @@ import (
 	"strings"
 
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/platform"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/writable"
 
 	"github.com/cloudfoundry/java-buildpack/src/java/containers"
 	"github.com/cloudfoundry/java-buildpack/src/java/frameworks"
@@ -14,6 +17,10 @@ import (
 	"github.com/cloudfoundry/libbuildpack"
 )
 
+// MaxReleaseCommandLength is the longest start command Cloud Controller accepts for a process.
+// Longer commands are moved into a generated launcher script.
+const MaxReleaseCommandLength = 4096
+
 type Finalizer struct {
 	Stager        common.Stager
 	Manifest      common.Manifest
@@ -24,6 +31,15 @@ type Finalizer struct {
 	JRE           jres.JRE
 	ContainerName string
 	JREName       string
+
+	// SkippedFrameworks are not finalized because supply failed to install them
+	SkippedFrameworks []string
+	// StrictFrameworks fail staging if they cannot be finalized, see framework_supply
+	StrictFrameworks []string
+
+	heapDump *jres.HeapDump
+	// deprecated are the detected deprecated frameworks, listed in the release metadata
+	deprecated []frameworks.DeprecatedComponent
 }
 
 // SupplyConfig holds the values written to config.yml by the supply phase.
@@ -32,6 +48,10 @@ type SupplyConfig struct {
 	JRE        string `yaml:"jre"`
 	JREVersion string `yaml:"jre_version"`
 	JavaHome   string `yaml:"java_home"`
+	// SkippedFrameworks lists, comma-separated, the frameworks that supply failed to install
+	SkippedFrameworks string `yaml:"skipped_frameworks"`
+	// StrictFrameworks lists, comma-separated, the installed frameworks that framework_supply makes strict
+	StrictFrameworks string `yaml:"strict_frameworks"`
 }
 
 // NewFinalizer creates a Finalizer by reading the config.yml written by the supply phase.
@@ -55,14 +75,24 @@ func NewFinalizer(stager common.Stager, manifest common.Manifest,
 
 	logger.Info("Loaded supply config: container=%s jre=%s version=%s", cfg.Container, cfg.JRE, cfg.JREVersion)
 
+	var skipped, strict []string
+	if cfg.SkippedFrameworks != "" {
+		skipped = strings.Split(cfg.SkippedFrameworks, ",")
+	}
+	if cfg.StrictFrameworks != "" {
+		strict = strings.Split(cfg.StrictFrameworks, ",")
+	}
+
 	return &Finalizer{
-		Stager:        stager,
-		Manifest:      manifest,
-		Installer:     installer,
-		Log:           logger,
-		Command:       command,
-		ContainerName: cfg.Container,
-		JREName:       cfg.JRE,
+		Stager:            stager,
+		Manifest:          manifest,
+		Installer:         installer,
+		Log:               logger,
+		Command:           command,
+		ContainerName:     cfg.Container,
+		JREName:           cfg.JRE,
+		SkippedFrameworks: skipped,
+		StrictFrameworks:  strict,
 	}, nil
 }
 
@@ -70,6 +100,11 @@ func NewFinalizer(stager common.Stager, manifest common.Manifest,
 func Run(f *Finalizer) error {
 	f.Log.BeginStep("Finalizing Java")
 
+	// Finalize runs in a separate process, so the interpolation and legacy configuration mapping are applied again;
+	// the migration guidance was already logged by supply
+	common.InterpolateConfig(f.Log)
+	common.ApplyLegacyConfig()
+
 	ctx := &common.Context{
 		Stager:    f.Stager,
 		Manifest:  f.Manifest,
@@ -95,8 +130,14 @@ func Run(f *Finalizer) error {
 	}
 	f.JRE = jre
 
+	// Remove excluded application files before the buildpack adds its own
+	if err := f.slimDroplet(); err != nil {
+		f.Log.Error("Failed to slim droplet: %s", err.Error())
+		return err
+	}
+
 	// Finalize JRE (memory calculator, jvmkill, etc.)
-	if err := f.finalizeJRE(); err != nil {
+	if err := f.finalizeJRE(ctx); err != nil {
 		f.Log.Error("Failed to finalize JRE: %s", err.Error())
 		return err
 	}
@@ -113,12 +154,49 @@ func Run(f *Finalizer) error {
 		return err
 	}
 
+	// Korifi and other Kubernetes-based platforms lack parts of the environment that Diego cells provide
+	if err := platform.Render(f.Log, f.Stager); err != nil {
+		f.Log.Error("Could not configure platform compatibility: %s", err.Error())
+		return err
+	}
+
+	// Directories that frameworks write to at runtime are relocated if the droplet is read-only
+	if err := writable.Render(f.Log, f.Stager); err != nil {
+		f.Log.Error("Could not configure runtime-writable directories: %s", err.Error())
+		return err
+	}
+
+	f.assembleJavaOpts(ctx)
+
+	// The attach order of the agents, for incident responders who cannot read it from JAVA_OPTS
+	if err := f.writeAgentsManifest(ctx); err != nil {
+		f.Log.Warning("Could not write %s: %s", AgentsFile, err.Error())
+	}
+
 	// Write release YAML configuration
 	if err := f.writeReleaseYaml(container); err != nil {
 		f.Log.Error("Failed to write release YAML: %s", err.Error())
 		return err
 	}
 
+	// Adds the components loaded by finalize to the file supply wrote; only those supply did not log are logged
+	if err := config.WriteEffective(f.Log, filepath.Join(f.Stager.DepDir(), config.EffectiveConfigFile)); err != nil {
+		f.Log.Error("Could not write effective configuration: %s", err.Error())
+		return err
+	}
+
+	// Distribution notices for the third-party components installed by supply and finalize
+	if err := common.WriteLicenseReport(f.Stager.DepDir()); err != nil {
+		f.Log.Error("Could not write license report: %s", err.Error())
+		return err
+	}
+
+	// The bill of materials of the components installed into the droplet, for security teams that track them
+	if err := common.WriteSBOM(f.Stager.DepDir()); err != nil {
+		f.Log.Error("Could not write SBOM: %s", err.Error())
+		return err
+	}
+
 	f.Log.Info("Java buildpack finalization complete")
 	return nil
 }
@@ -146,13 +224,19 @@ func resolveJRE(ctx *common.Context, name string) (jres.JRE, error) {
 }
 
 // finalizeJRE finalizes the JRE configuration (memory calculator, jvmkill, etc.)
-func (f *Finalizer) finalizeJRE() error {
+func (f *Finalizer) finalizeJRE(ctx *common.Context) error {
 	f.Log.BeginStep("Finalizing JRE: %s", f.JREName)
 
 	if err := f.JRE.Finalize(); err != nil {
 		return fmt.Errorf("failed to finalize JRE %s: %w", f.JREName, err)
 	}
 
+	// Heap dumps apply to every JRE, so they are configured here rather than by each provider
+	f.heapDump = jres.NewHeapDump(ctx)
+	if err := f.heapDump.Finalize(); err != nil {
+		return fmt.Errorf("failed to configure heap dumps: %w", err)
+	}
+
 	f.Log.Debug("JRE finalization complete")
 	return nil
 }
@@ -163,11 +247,31 @@ func (f *Finalizer) finalizeFrameworks(ctx *common.Context) error {
 	registry.RegisterStandardFrameworks()
 
 	detectedFrameworks, frameworkNames, err := registry.DetectAll()
+	if common.IsUnknownConfigKeysError(err) {
+		return err
+	}
+	if err != nil && len(f.StrictFrameworks) > 0 {
+		return fmt.Errorf("failed to detect frameworks, strict frameworks %s cannot be finalized: %w", strings.Join(f.StrictFrameworks, ", "), err)
+	}
 	if err != nil {
 		f.Log.Warning("Failed to detect frameworks: %s", err.Error())
 		return nil // Don't fail the build if framework detection fails
 	}
 
+	// Frameworks that supply staged the application without have nothing to configure
+	for i := len(detectedFrameworks) - 1; i >= 0; i-- {
+		for _, skipped := range f.SkippedFrameworks {
+			if frameworkNames[i] == skipped {
+				f.Log.Warning("Skipping framework %s, its installation failed during supply", skipped)
+				detectedFrameworks = append(detectedFrameworks[:i], detectedFrameworks[i+1:]...)
+				frameworkNames = append(frameworkNames[:i], frameworkNames[i+1:]...)
+				break
+			}
+		}
+	}
+
+	f.deprecated = registry.Deprecated(detectedFrameworks, frameworkNames)
+
 	if len(detectedFrameworks) == 0 {
 		f.Log.Info("No frameworks to finalize")
 		return nil
@@ -178,17 +282,44 @@ func (f *Finalizer) finalizeFrameworks(ctx *common.Context) error {
 	for i, framework := range detectedFrameworks {
 		f.Log.Debug("Finalizing framework: %s", frameworkNames[i])
 		if err := framework.Finalize(); err != nil {
+			if common.IsUnknownConfigKeysError(err) {
+				return fmt.Errorf("failed to finalize framework %s: %w", frameworkNames[i], err)
+			}
+			if f.isStrict(frameworkNames[i]) {
+				return fmt.Errorf("framework %s is strict and could not be finalized: %w", frameworkNames[i], err)
+			}
 			f.Log.Warning("Failed to finalize framework %s: %s", frameworkNames[i], err.Error())
 			// Continue with other frameworks even if one fails
 		}
 	}
 
-	// After all frameworks have written their .opts files, create the centralized assembly script
+	return nil
+}
+
+// isStrict returns true if supply installed the framework named name as a strict framework
+func (f *Finalizer) isStrict(name string) bool {
+	for _, strict := range f.StrictFrameworks {
+		if name == strict {
+			return true
+		}
+	}
+	return false
+}
+
+// assembleJavaOpts creates the centralized JAVA_OPTS assembly script once the JRE, the frameworks and the container,
+// e.g. its AppCDS archive, have written their .opts files
+func (f *Finalizer) assembleJavaOpts(ctx *common.Context) {
+	// Options that the installed JRE would refuse to start with are corrected before they are assembled
+	if err := frameworks.CheckClassLoaderOptions(ctx); err != nil {
+		f.Log.Warning("Failed to check class loader options: %s", err.Error())
+	}
+	if err := frameworks.CheckVersionedOptions(ctx); err != nil {
+		f.Log.Warning("Failed to check versioned options: %s", err.Error())
+	}
+
 	if err := frameworks.CreateJavaOptsAssemblyScript(ctx); err != nil {
 		f.Log.Warning("Failed to create JAVA_OPTS assembly script: %s", err.Error())
 	}
-
-	return nil
 }
 
 // writeReleaseYaml writes the release configuration to a YAML file
@@ -200,6 +331,14 @@ func (f *Finalizer) writeReleaseYaml(container containers.Container) error {
 		return fmt.Errorf("failed to get container command: %w", err)
 	}
 
+	portArgument, err := f.writePortBinding(container)
+	if err != nil {
+		return err
+	}
+	if portArgument != "" {
+		containerCommand += " " + portArgument
+	}
+
 	var fullCommand string
 	if f.JRE != nil {
 		memCalcCmd := f.JRE.MemoryCalculatorCommand()
@@ -213,16 +352,51 @@ func (f *Finalizer) writeReleaseYaml(container containers.Container) error {
 		fullCommand = containerCommand
 	}
 
+	if f.heapDump != nil {
+		fullCommand, err = f.heapDump.WrapCommand(fullCommand)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The droplet is checked first, so that missing files are reported before anything else runs
+	verifyCommand, err := f.writeVerifyScript(container)
+	if err != nil {
+		return fmt.Errorf("failed to write verify script: %w", err)
+	}
+	fullCommand = verifyCommand + " && " + fullCommand
+
+	if len(fullCommand) > MaxReleaseCommandLength {
+		launcherCommand, err := f.writeLauncherScript(fullCommand)
+		if err != nil {
+			return fmt.Errorf("failed to write launcher script: %w", err)
+		}
+		f.Log.Warning("Start command is %d characters long (limit %d), starting the application via %s", len(fullCommand), MaxReleaseCommandLength, launcherCommand)
+		fullCommand = launcherCommand
+	}
+
 	tmpDir := filepath.Join(f.Stager.BuildDir(), "tmp")
 	if err := os.MkdirAll(tmpDir, 0755); err != nil {
 		return fmt.Errorf("failed to create tmp directory: %w", err)
 	}
 
 	releaseYamlPath := filepath.Join(tmpDir, "java-buildpack-release-step.yml")
+	// Single quotes in the command, e.g. from escaped java_main arguments, are doubled in the single-quoted YAML scalar
 	yamlContent := fmt.Sprintf(`---
 default_process_types:
   web: '%s'
-`, fullCommand)
+`, strings.ReplaceAll(fullCommand, "'", "''"))
+
+	// Deprecated frameworks are listed for fleet reporting; Cloud Foundry only reads default_process_types
+	if len(f.deprecated) > 0 {
+		deprecated, err := (common.YamlHandler{}).Marshal(map[string][]frameworks.DeprecatedComponent{
+			"deprecated_components": f.deprecated,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write deprecated components to release YAML: %w", err)
+		}
+		yamlContent += string(deprecated)
+	}
 
 	if err := os.WriteFile(releaseYamlPath, []byte(yamlContent), 0644); err != nil {
 		return fmt.Errorf("failed to write release YAML: %w", err)
@@ -232,3 +406,25 @@ default_process_types:
 	f.Log.Info("Web process command: %s", fullCommand)
 	return nil
 }
+
+// writeLauncherScript writes the start command to bin/start.sh in the buildpack's deps directory and returns the
+// command that runs it. Used when the start command exceeds MaxReleaseCommandLength. The script is kept out of the
+// application directory, which containers such as Tomcat may serve.
+func (f *Finalizer) writeLauncherScript(command string) (string, error) {
+	binDir := filepath.Join(f.Stager.DepDir(), "bin")
+	if err := os.MkdirAll(binDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create bin directory: %w", err)
+	}
+
+	// profile.d scripts are sourced before the start command runs, so the script sees the same environment
+	content := fmt.Sprintf(`#!/bin/bash
+# Generated by the Java buildpack: the start command exceeds the platform command length limit
+%s
+`, command)
+
+	if err := os.WriteFile(filepath.Join(binDir, "start.sh"), []byte(content), 0755); err != nil {
+		return "", fmt.Errorf("failed to write start.sh: %w", err)
+	}
+
+	return common.NewDroplet(f.Stager).Dep("bin", "start.sh"), nil
+}
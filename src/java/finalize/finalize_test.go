@@ -1,6 +1,10 @@
 package finalize_test
 
 import (
+	"fmt"
+	"os/exec"
+	"strings"
+
 	"github.com/cloudfoundry/java-buildpack/src/internal/mocks"
 	"github.com/golang/mock/gomock"
 	"os"
@@ -134,6 +138,484 @@ dependencies: []
 		})
 	})
 
+	Describe("Strict frameworks", func() {
+		BeforeEach(func() {
+			finalizer.JREName = "OpenJDK"
+			finalizer.ContainerName = "Groovy"
+			Expect(os.WriteFile(filepath.Join(buildDir, "app.groovy"), []byte("println 'hello'"), 0644)).To(Succeed())
+			// The Contrast Security Agent is detected, but cannot be finalized without its agent JAR
+			os.Setenv("VCAP_SERVICES", `{"contrast-security": [{"name": "contrast", "credentials": {}}]}`)
+		})
+
+		AfterEach(func() {
+			os.Unsetenv("VCAP_SERVICES")
+		})
+
+		It("warns about a framework that cannot be finalized", func() {
+			Expect(finalize.Run(finalizer)).To(Succeed())
+		})
+
+		It("fails on a strict framework that cannot be finalized", func() {
+			finalizer.StrictFrameworks = []string{"contrast-security"}
+
+			Expect(finalize.Run(finalizer)).To(MatchError(ContainSubstring(
+				"framework contrast-security is strict and could not be finalized: failed to locate contrast security agent JAR")))
+		})
+
+		Context("when the frameworks cannot be detected", func() {
+			BeforeEach(func() {
+				os.Setenv("JBP_CONFIG_COMPONENTS", "{frameworks: [no_such_framework]}")
+			})
+
+			AfterEach(func() {
+				os.Unsetenv("JBP_CONFIG_COMPONENTS")
+			})
+
+			It("warns if no framework is strict", func() {
+				Expect(finalize.Run(finalizer)).To(Succeed())
+			})
+
+			It("fails if a framework is strict", func() {
+				finalizer.StrictFrameworks = []string{"contrast-security"}
+
+				Expect(finalize.Run(finalizer)).To(MatchError(ContainSubstring(
+					"failed to detect frameworks, strict frameworks contrast-security cannot be finalized")))
+			})
+		})
+	})
+
+	Describe("Release command length", func() {
+		BeforeEach(func() {
+			finalizer.JREName = "OpenJDK"
+			finalizer.ContainerName = "Groovy"
+			Expect(os.WriteFile(filepath.Join(buildDir, "app.groovy"), []byte("println 'hello'"), 0644)).To(Succeed())
+		})
+
+		readReleaseYaml := func() string {
+			content, err := os.ReadFile(filepath.Join(buildDir, "tmp", "java-buildpack-release-step.yml"))
+			Expect(err).NotTo(HaveOccurred())
+			return string(content)
+		}
+
+		It("keeps a short start command inline", func() {
+			Expect(finalize.Run(finalizer)).To(Succeed())
+			Expect(readReleaseYaml()).To(ContainSubstring("groovy"))
+			Expect(filepath.Join(depsDir, depsIdx, "bin", "start.sh")).NotTo(BeAnExistingFile())
+		})
+
+		Context("with a classpath longer than the command length limit", func() {
+			BeforeEach(func() {
+				libDir := filepath.Join(buildDir, "lib")
+				Expect(os.MkdirAll(libDir, 0755)).To(Succeed())
+				for i := 0; i < 150; i++ {
+					jar := filepath.Join(libDir, fmt.Sprintf("vendor-observability-agent-bootstrap-extension-%03d.jar", i))
+					Expect(os.WriteFile(jar, []byte("fake"), 0644)).To(Succeed())
+				}
+			})
+
+			It("falls back to a generated launcher script", func() {
+				Expect(finalize.Run(finalizer)).To(Succeed())
+
+				releaseYaml := readReleaseYaml()
+				Expect(releaseYaml).To(ContainSubstring("web: '$DEPS_DIR/0/bin/start.sh'"))
+				Expect(len(releaseYaml)).To(BeNumerically("<", finalize.MaxReleaseCommandLength))
+
+				startScript := filepath.Join(depsDir, depsIdx, "bin", "start.sh")
+				content, err := os.ReadFile(startScript)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(HavePrefix("#!/bin/bash"))
+				Expect(string(content)).To(ContainSubstring("vendor-observability-agent-bootstrap-extension-149.jar"))
+				Expect(string(content)).To(ContainSubstring("app.groovy"))
+
+				info, err := os.Stat(startScript)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(info.Mode().Perm() & 0100).NotTo(BeZero())
+			})
+
+			It("keeps the launcher script out of the application directory", func() {
+				Expect(finalize.Run(finalizer)).To(Succeed())
+
+				Expect(filepath.Join(buildDir, ".java-buildpack", "start.sh")).NotTo(BeAnExistingFile())
+			})
+		})
+	})
+
+	Describe("Deprecated frameworks", func() {
+		BeforeEach(func() {
+			finalizer.JREName = "OpenJDK"
+			finalizer.ContainerName = "Groovy"
+			Expect(os.WriteFile(filepath.Join(buildDir, "app.groovy"), []byte("println 'hello'"), 0644)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			os.Unsetenv("JBP_CONFIG_SPRING_AUTO_RECONFIGURATION")
+		})
+
+		readReleaseYaml := func() string {
+			content, err := os.ReadFile(filepath.Join(buildDir, "tmp", "java-buildpack-release-step.yml"))
+			Expect(err).NotTo(HaveOccurred())
+			return string(content)
+		}
+
+		It("lists the detected deprecated frameworks in the release metadata", func() {
+			Expect(os.MkdirAll(filepath.Join(buildDir, "lib"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(buildDir, "lib", "spring-core-5.3.39.jar"), []byte("fake"), 0644)).To(Succeed())
+			os.Setenv("JBP_CONFIG_SPRING_AUTO_RECONFIGURATION", "{enabled: true}")
+
+			Expect(finalize.Run(finalizer)).To(Succeed())
+			Expect(readReleaseYaml()).To(HaveSuffix("\ndeprecated_components:\n" +
+				"    - name: Spring Auto-reconfiguration\n" +
+				"      component: SpringAutoReconfiguration\n" +
+				"      sunset: \"2026-12-31\"\n" +
+				"      replacement: java-cfenv\n"))
+		})
+
+		It("lists no deprecated components without deprecated frameworks", func() {
+			Expect(finalize.Run(finalizer)).To(Succeed())
+			Expect(readReleaseYaml()).NotTo(ContainSubstring("deprecated_components"))
+		})
+	})
+
+	Describe("Droplet verification", func() {
+		var javaHome string
+
+		BeforeEach(func() {
+			finalizer.JREName = "OpenJDK"
+			finalizer.ContainerName = "Tomcat"
+			Expect(os.MkdirAll(filepath.Join(buildDir, "WEB-INF"), 0755)).To(Succeed())
+
+			javaHome = filepath.Join(depsDir, depsIdx, "jre")
+			Expect(os.MkdirAll(filepath.Join(javaHome, "bin"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(javaHome, "bin", "java"), []byte("#!/bin/sh\n"), 0755)).To(Succeed())
+		})
+
+		// verify runs the generated script with the given environment and returns its output and exit status
+		verify := func(env ...string) (string, error) {
+			cmd := exec.Command("bash", filepath.Join(depsDir, depsIdx, "bin", "verify.sh"))
+			cmd.Env = append([]string{"PATH=" + os.Getenv("PATH")}, env...)
+			output, err := cmd.CombinedOutput()
+			return string(output), err
+		}
+
+		It("runs the verify script before the start command", func() {
+			Expect(finalize.Run(finalizer)).To(Succeed())
+
+			content, err := os.ReadFile(filepath.Join(buildDir, "tmp", "java-buildpack-release-step.yml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring("web: '$DEPS_DIR/0/bin/verify.sh && "))
+		})
+
+		It("passes for a complete droplet", func() {
+			Expect(finalize.Run(finalizer)).To(Succeed())
+
+			catalinaHome := filepath.Join(depsDir, depsIdx, "tomcat")
+			Expect(os.MkdirAll(filepath.Join(catalinaHome, "bin"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(catalinaHome, "bin", "catalina.sh"), []byte("#!/bin/sh\n"), 0755)).To(Succeed())
+			agent := filepath.Join(depsDir, depsIdx, "agent.jar")
+			Expect(os.WriteFile(agent, []byte("fake"), 0644)).To(Succeed())
+
+			output, err := verify("JAVA_HOME="+javaHome, "CATALINA_HOME="+catalinaHome, "JAVA_OPTS=-Xss1M -javaagent:"+agent+"=key=value")
+			Expect(err).NotTo(HaveOccurred(), output)
+		})
+
+		It("reports every missing file", func() {
+			Expect(finalize.Run(finalizer)).To(Succeed())
+
+			output, err := verify("JAVA_HOME="+javaHome, "CATALINA_HOME=/missing/tomcat", "JAVA_OPTS=-agentpath:/missing/libagent.so=port=8000")
+			Expect(err).To(HaveOccurred())
+			Expect(output).To(ContainSubstring("/missing/tomcat/bin/catalina.sh is missing"))
+			Expect(output).To(ContainSubstring("/missing/libagent.so is missing"))
+			Expect(output).To(ContainSubstring("The droplet is incomplete"))
+		})
+
+		It("reports a missing JAVA_HOME and can be skipped", func() {
+			Expect(finalize.Run(finalizer)).To(Succeed())
+
+			output, err := verify("CATALINA_HOME=/missing/tomcat")
+			Expect(err).To(HaveOccurred())
+			Expect(output).To(ContainSubstring("JAVA_HOME is not set"))
+
+			output, err = verify("BPL_VERIFY_ENABLED=false")
+			Expect(err).NotTo(HaveOccurred(), output)
+		})
+	})
+
+	Describe("Port binding", func() {
+		BeforeEach(func() {
+			finalizer.JREName = "OpenJDK"
+		})
+
+		// source runs profile.d/port.sh with PORT=8080 and returns JAVA_OPTS and SERVER_PORT
+		source := func() string {
+			cmd := exec.Command("bash", "-c", `. "$0"; echo "JAVA_OPTS=$JAVA_OPTS"; echo "SERVER_PORT=$SERVER_PORT"`,
+				filepath.Join(depsDir, depsIdx, "profile.d", "port.sh"))
+			cmd.Env = []string{"PATH=" + os.Getenv("PATH"), "PORT=8080", "JAVA_OPTS=-Xss1M"}
+			output, err := cmd.CombinedOutput()
+			Expect(err).NotTo(HaveOccurred(), string(output))
+			return string(output)
+		}
+
+		It("passes $PORT to Tomcat as the http.port system property", func() {
+			finalizer.ContainerName = "Tomcat"
+			Expect(os.MkdirAll(filepath.Join(buildDir, "WEB-INF"), 0755)).To(Succeed())
+
+			Expect(finalize.Run(finalizer)).To(Succeed())
+			Expect(source()).To(Equal("JAVA_OPTS=-Xss1M -Dhttp.port=8080\nSERVER_PORT=\n"))
+		})
+
+		It("exports $PORT as SERVER_PORT for Spring Boot", func() {
+			finalizer.ContainerName = "Spring Boot"
+			Expect(os.MkdirAll(filepath.Join(buildDir, "BOOT-INF"), 0755)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(buildDir, "META-INF"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(buildDir, "META-INF", "MANIFEST.MF"), []byte("Spring-Boot-Version: 3.x.x"), 0644)).To(Succeed())
+
+			Expect(finalize.Run(finalizer)).To(Succeed())
+			Expect(source()).To(Equal("JAVA_OPTS=-Xss1M\nSERVER_PORT=8080\n"))
+		})
+
+		It("leaves containers without a port binding alone", func() {
+			finalizer.ContainerName = "Groovy"
+			Expect(os.WriteFile(filepath.Join(buildDir, "app.groovy"), []byte("println 'hello'"), 0644)).To(Succeed())
+
+			Expect(finalize.Run(finalizer)).To(Succeed())
+			Expect(filepath.Join(depsDir, depsIdx, "profile.d", "port.sh")).NotTo(BeAnExistingFile())
+		})
+	})
+
+	Describe("Agents manifest", func() {
+		BeforeEach(func() {
+			finalizer.JREName = "OpenJDK"
+			finalizer.ContainerName = "Groovy"
+			Expect(os.WriteFile(filepath.Join(buildDir, "app.groovy"), []byte("println 'hello'"), 0644)).To(Succeed())
+
+			optsDir := filepath.Join(depsDir, depsIdx, "java_opts")
+			Expect(os.MkdirAll(optsDir, 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(optsDir, "35_new_relic.opts"),
+				[]byte("-javaagent:$DEPS_DIR/0/new_relic_agent/newrelic.jar -Dnewrelic.home=$DEPS_DIR/0/new_relic_agent"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(optsDir, "21_jacoco.opts"),
+				[]byte("-javaagent:$DEPS_DIR/0/jacoco/jacocoagent.jar=address=localhost,port=6300"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(optsDir, "40_debug.opts"),
+				[]byte("-agentlib:jdwp=transport=dt_socket,server=y,suspend=n"), 0644)).To(Succeed())
+
+			Expect(os.WriteFile(filepath.Join(depsDir, depsIdx, "installed-dependencies.json"), []byte(`[
+  {"name": "jacoco", "version": "0.8.13", "uri": "https://example.com/jacoco.jar", "dir": "jacoco"},
+  {"name": "new-relic", "version": "8.20.0", "uri": "https://example.com/newrelic.jar", "dir": "new_relic_agent"}
+]`), 0644)).To(Succeed())
+		})
+
+		It("records the agents in attach order with the versions they were installed with", func() {
+			Expect(finalize.Run(finalizer)).To(Succeed())
+
+			data, err := os.ReadFile(filepath.Join(depsDir, depsIdx, finalize.AgentsFile))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(MatchJSON(`{"agents": [
+  {"order": 1, "contribution": "jacoco", "priority": 21, "type": "javaagent",
+   "path": "/home/vcap/deps/0/jacoco/jacocoagent.jar", "options": "address=localhost,port=6300",
+   "dependency": "jacoco", "version": "0.8.13"},
+  {"order": 2, "contribution": "new_relic", "priority": 35, "type": "javaagent",
+   "path": "/home/vcap/deps/0/new_relic_agent/newrelic.jar", "dependency": "new-relic", "version": "8.20.0"},
+  {"order": 3, "contribution": "debug", "priority": 40, "type": "agentlib",
+   "path": "jdwp", "options": "transport=dt_socket,server=y,suspend=n"}
+]}`))
+		})
+
+		It("records an agent once when two contributions attach it", func() {
+			Expect(os.WriteFile(filepath.Join(depsDir, depsIdx, "java_opts", "36_new_relic_copy.opts"),
+				[]byte("-javaagent:$DEPS_DIR/0/new_relic_agent/newrelic.jar"), 0644)).To(Succeed())
+
+			Expect(finalize.Run(finalizer)).To(Succeed())
+
+			data, err := os.ReadFile(filepath.Join(depsDir, depsIdx, finalize.AgentsFile))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(strings.Count(string(data), "newrelic.jar")).To(Equal(1))
+		})
+	})
+
+	Describe("Runtime-writable directories", func() {
+		BeforeEach(func() {
+			finalizer.JREName = "OpenJDK"
+			finalizer.ContainerName = "Groovy"
+			Expect(os.WriteFile(filepath.Join(buildDir, "app.groovy"), []byte("println 'hello'"), 0644)).To(Succeed())
+		})
+
+		It("exports the directories registered by frameworks", func() {
+			Expect(os.MkdirAll(filepath.Join(depsDir, depsIdx), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(depsDir, depsIdx, "writable_dirs.json"), []byte(`[
+  {"framework": "sealights_agent", "path": "$DEPS_DIR/0/sealights_logs", "variable": "JBP_WRITABLE_SEALIGHTS_LOGS", "strategy": "link"}
+]`), 0644)).To(Succeed())
+
+			Expect(finalize.Run(finalizer)).To(Succeed())
+
+			script, err := os.ReadFile(filepath.Join(depsDir, depsIdx, "profile.d", "00_dirs_writable.sh"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(script)).To(ContainSubstring(`writable_dir JBP_WRITABLE_SEALIGHTS_LOGS "$DEPS_DIR/0/sealights_logs" "deps/0/sealights_logs" link`))
+		})
+
+		It("writes no script without registered directories", func() {
+			Expect(finalize.Run(finalizer)).To(Succeed())
+			Expect(filepath.Join(depsDir, depsIdx, "profile.d", "00_dirs_writable.sh")).NotTo(BeAnExistingFile())
+		})
+	})
+
+	Describe("Platform compatibility", func() {
+		BeforeEach(func() {
+			finalizer.JREName = "OpenJDK"
+			finalizer.ContainerName = "Groovy"
+			Expect(os.WriteFile(filepath.Join(buildDir, "app.groovy"), []byte("println 'hello'"), 0644)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			os.Unsetenv("JBP_CONFIG_PLATFORM_COMPAT")
+		})
+
+		It("writes the compatibility script that sorts before the other profile.d scripts", func() {
+			Expect(finalize.Run(finalizer)).To(Succeed())
+
+			scripts, err := filepath.Glob(filepath.Join(depsDir, depsIdx, "profile.d", "*.sh"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(scripts).NotTo(BeEmpty())
+			Expect(filepath.Base(scripts[0])).To(Equal("000_platform_compat.sh"))
+		})
+
+		It("writes no script in cf mode", func() {
+			os.Setenv("JBP_CONFIG_PLATFORM_COMPAT", "{mode: cf}")
+			Expect(finalize.Run(finalizer)).To(Succeed())
+			Expect(filepath.Join(depsDir, depsIdx, "profile.d", "000_platform_compat.sh")).NotTo(BeAnExistingFile())
+		})
+	})
+
+	Describe("Droplet slimming", func() {
+		BeforeEach(func() {
+			finalizer.JREName = "OpenJDK"
+			finalizer.ContainerName = "Tomcat"
+			for _, file := range []string{
+				"WEB-INF/web.xml",
+				"WEB-INF/classes/app.js.map",
+				"src/main/java/App.java",
+				"docs/guide.html",
+				"docs/licenses/LICENSE",
+				"static/app.js",
+				"static/app.js.map",
+			} {
+				Expect(os.MkdirAll(filepath.Join(buildDir, filepath.Dir(file)), 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(buildDir, file), []byte("content"), 0644)).To(Succeed())
+			}
+		})
+
+		AfterEach(func() {
+			os.Unsetenv("JBP_CONFIG_DROPLET")
+		})
+
+		It("keeps every file by default", func() {
+			Expect(finalize.Run(finalizer)).To(Succeed())
+			Expect(filepath.Join(buildDir, "src", "main", "java", "App.java")).To(BeAnExistingFile())
+		})
+
+		It("removes excluded files but keeps included ones", func() {
+			os.Setenv("JBP_CONFIG_DROPLET", "{exclude: [/src/, /docs/, '*.map'], include: [/docs/licenses/]}")
+
+			Expect(finalize.Run(finalizer)).To(Succeed())
+
+			Expect(filepath.Join(buildDir, "src")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(buildDir, "docs", "guide.html")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(buildDir, "docs", "licenses", "LICENSE")).To(BeAnExistingFile())
+			Expect(filepath.Join(buildDir, "static", "app.js.map")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(buildDir, "WEB-INF", "classes", "app.js.map")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(buildDir, "static", "app.js")).To(BeAnExistingFile())
+			Expect(filepath.Join(buildDir, "WEB-INF", "web.xml")).To(BeAnExistingFile())
+		})
+
+		It("only matches directories with a trailing slash", func() {
+			Expect(os.WriteFile(filepath.Join(buildDir, "static", "src"), []byte("content"), 0644)).To(Succeed())
+			os.Setenv("JBP_CONFIG_DROPLET", "{exclude: [src/]}")
+
+			Expect(finalize.Run(finalizer)).To(Succeed())
+
+			Expect(filepath.Join(buildDir, "src")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(buildDir, "static", "src")).To(BeAnExistingFile())
+		})
+
+		It("rejects malformed patterns", func() {
+			os.Setenv("JBP_CONFIG_DROPLET", "{exclude: ['[src']}")
+
+			Expect(finalize.Run(finalizer)).To(MatchError(ContainSubstring(`invalid droplet pattern "[src"`)))
+		})
+	})
+
+	// Cloud Foundry sources the profile.d scripts and then the application's .profile before the start
+	// command. Buildpack scripts must only extend JAVA_OPTS, CATALINA_OPTS and CLASSPATH, so values set
+	// in the manifest survive and the application's .profile has the last word.
+	Describe("Runtime environment ordering", func() {
+		runtimeEnv := func() []string {
+			script := `
+for script in "$DEPS_DIR"/0/profile.d/*.sh; do source "$script"; done
+echo "JAVA_OPTS=$JAVA_OPTS"
+echo "CATALINA_OPTS=$CATALINA_OPTS"
+echo "CLASSPATH=$CLASSPATH"
+[ -f "$HOME/.profile" ] && source "$HOME/.profile"
+echo "PROFILE_JAVA_OPTS=$JAVA_OPTS"
+`
+			cmd := exec.Command("bash", "-c", script)
+			cmd.Dir = buildDir
+			cmd.Env = []string{
+				"PATH=" + os.Getenv("PATH"),
+				"HOME=" + buildDir,
+				"DEPS_DIR=" + depsDir,
+				"TMPDIR=" + os.TempDir(),
+				"PORT=8080",
+				"JAVA_OPTS=-Dmanifest.opt=true",
+				"CATALINA_OPTS=-Dmanifest.catalina=true",
+				"CLASSPATH=/home/vcap/app/user.jar",
+			}
+			output, err := cmd.CombinedOutput()
+			Expect(err).NotTo(HaveOccurred(), string(output))
+			return strings.Split(strings.TrimSpace(string(output)), "\n")
+		}
+
+		ItKeepsManifestValues := func() {
+			It("keeps JAVA_OPTS, CATALINA_OPTS and CLASSPATH from the manifest", func() {
+				Expect(finalize.Run(finalizer)).To(Succeed())
+
+				env := runtimeEnv()
+				Expect(env).To(ContainElement(ContainSubstring("-Dmanifest.opt=true")))
+				Expect(env).To(ContainElement("CATALINA_OPTS=-Dmanifest.catalina=true"))
+				Expect(env).To(ContainElement(MatchRegexp(`^CLASSPATH=(.*:)?/home/vcap/app/user.jar$`)))
+			})
+
+			It("lets the application's .profile override JAVA_OPTS", func() {
+				Expect(os.WriteFile(filepath.Join(buildDir, ".profile"), []byte("export JAVA_OPTS=-Dprofile.opt=true\n"), 0644)).To(Succeed())
+				Expect(finalize.Run(finalizer)).To(Succeed())
+
+				Expect(runtimeEnv()).To(ContainElement("PROFILE_JAVA_OPTS=-Dprofile.opt=true"))
+			})
+		}
+
+		BeforeEach(func() {
+			finalizer.JREName = "OpenJDK"
+		})
+
+		Context("with a Tomcat application", func() {
+			BeforeEach(func() {
+				Expect(os.MkdirAll(filepath.Join(buildDir, "WEB-INF"), 0755)).To(Succeed())
+				finalizer.ContainerName = "Tomcat"
+			})
+
+			ItKeepsManifestValues()
+		})
+
+		Context("with a Dist ZIP application", func() {
+			BeforeEach(func() {
+				Expect(os.MkdirAll(filepath.Join(buildDir, "bin"), 0755)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(buildDir, "lib"), 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(buildDir, "bin", "application"), []byte("#!/bin/sh"), 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(buildDir, "lib", "application.jar"), []byte("fake"), 0644)).To(Succeed())
+				finalizer.ContainerName = "Dist ZIP"
+			})
+
+			ItKeepsManifestValues()
+		})
+	})
+
 	Describe("Startup Script Generation", func() {
 		It("creates .java-buildpack directory", func() {
 			javaBuildpackDir := filepath.Join(buildDir, ".java-buildpack")
@@ -204,6 +686,31 @@ dependencies: []
 			Expect(err).NotTo(HaveOccurred())
 			Expect(f.ContainerName).To(Equal("spring-boot"))
 			Expect(f.JREName).To(Equal("OpenJDK"))
+			Expect(f.SkippedFrameworks).To(BeEmpty())
+		})
+
+		It("NewFinalizer reads the frameworks that supply failed to install", func() {
+			Expect(stager.WriteConfigYml(map[string]string{
+				"container":          "spring-boot",
+				"jre":                "OpenJDK",
+				"skipped_frameworks": "New Relic Agent,Seeker Security Provider",
+			})).To(Succeed())
+
+			f, err := finalize.NewFinalizer(stager, mockManifest, mockInstaller, logger, &libbuildpack.Command{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(f.SkippedFrameworks).To(Equal([]string{"New Relic Agent", "Seeker Security Provider"}))
+		})
+
+		It("NewFinalizer reads the frameworks that supply installed as strict", func() {
+			Expect(stager.WriteConfigYml(map[string]string{
+				"container":         "spring-boot",
+				"jre":               "OpenJDK",
+				"strict_frameworks": "contrast-security",
+			})).To(Succeed())
+
+			f, err := finalize.NewFinalizer(stager, mockManifest, mockInstaller, logger, &libbuildpack.Command{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(f.StrictFrameworks).To(Equal([]string{"contrast-security"}))
 		})
 
 		It("NewFinalizer fails when config.yml is missing", func() {
@@ -0,0 +1,34 @@
+package finalize
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/containers"
+)
+
+// writePortBinding conveys $PORT to the application the way the container declares through
+// containers.PortConfigurer. The system property and environment variable are written to profile.d/port.sh, so that
+// $PORT is expanded at runtime; the returned argument, if any, is appended to the start command.
+func (f *Finalizer) writePortBinding(container containers.Container) (string, error) {
+	configurer, ok := container.(containers.PortConfigurer)
+	if !ok {
+		return "", nil
+	}
+	binding := configurer.PortBinding()
+
+	var script strings.Builder
+	if binding.SystemProperty != "" {
+		fmt.Fprintf(&script, "export JAVA_OPTS=\"${JAVA_OPTS:+$JAVA_OPTS }-D%s=$PORT\"\n", binding.SystemProperty)
+	}
+	if binding.EnvironmentVariable != "" {
+		fmt.Fprintf(&script, "export %s=$PORT\n", binding.EnvironmentVariable)
+	}
+
+	if script.Len() > 0 {
+		if err := f.Stager.WriteProfileD("port.sh", script.String()); err != nil {
+			return "", fmt.Errorf("failed to write port.sh profile.d script: %w", err)
+		}
+	}
+	return binding.Argument, nil
+}
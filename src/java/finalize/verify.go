@@ -0,0 +1,76 @@
+package finalize
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/containers"
+)
+
+// verifyScriptTemplate checks the droplet before the start command runs. %[1]s is the list of artifacts the
+// container needs, each quoted so that runtime variables such as $HOME and $CATALINA_HOME are expanded.
+const verifyScriptTemplate = `#!/bin/bash
+# Generated by the Java buildpack: checks that the droplet is complete before the application starts.
+# Set BPL_VERIFY_ENABLED=false to skip the checks.
+case "${BPL_VERIFY_ENABLED:-}" in
+  false|0) exit 0 ;;
+esac
+
+failed=0
+fail() {
+  echo "[Java Buildpack] $*" >&2
+  failed=1
+}
+
+if [ -z "${JAVA_HOME:-}" ]; then
+  fail "JAVA_HOME is not set: the JRE's .profile.d script did not run or was removed"
+elif [ ! -x "$JAVA_HOME/bin/java" ]; then
+  fail "$JAVA_HOME/bin/java is missing: the JRE is not in the droplet"
+fi
+
+for artifact in %[1]s; do
+  [ -e "$artifact" ] || fail "$artifact is missing: the application or its container is not in the droplet"
+done
+
+for opt in ${JAVA_OPTS:-}; do
+  case "$opt" in
+    -javaagent:*) agent=${opt#-javaagent:} ;;
+    -agentpath:*) agent=${opt#-agentpath:} ;;
+    *) continue ;;
+  esac
+  agent=${agent%%%%=*}
+  [ -e "$agent" ] || fail "$agent is missing: the agent set by $opt in JAVA_OPTS is not in the droplet"
+done
+
+if [ "$failed" -ne 0 ]; then
+  echo "[Java Buildpack] The droplet is incomplete, restage the application with BP_DEBUG=true to see what was installed" >&2
+  exit 1
+fi
+`
+
+// writeVerifyScript writes bin/verify.sh to the buildpack's deps directory and returns the command that runs it.
+// The script checks JAVA_HOME, the artifacts the container reports through containers.RequiredArtifactsProvider
+// and the agents in JAVA_OPTS, so that an incomplete droplet fails with a diagnosis instead of a Java stack trace.
+func (f *Finalizer) writeVerifyScript(container containers.Container) (string, error) {
+	var artifacts []string
+	if provider, ok := container.(containers.RequiredArtifactsProvider); ok {
+		for _, artifact := range provider.RequiredArtifacts() {
+			artifacts = append(artifacts, `"`+artifact+`"`)
+		}
+	}
+
+	binDir := filepath.Join(f.Stager.DepDir(), "bin")
+	if err := os.MkdirAll(binDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create bin directory: %w", err)
+	}
+
+	content := fmt.Sprintf(verifyScriptTemplate, strings.Join(artifacts, " "))
+	if err := os.WriteFile(filepath.Join(binDir, "verify.sh"), []byte(content), 0755); err != nil {
+		return "", fmt.Errorf("failed to write verify.sh: %w", err)
+	}
+
+	return common.NewDroplet(f.Stager).Dep("bin", "verify.sh"), nil
+}
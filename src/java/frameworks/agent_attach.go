@@ -0,0 +1,135 @@
+package frameworks
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Attach mechanisms of agents that are shipped both as a Java agent and as a native agent, selected with the attach
+// key of their configuration
+const (
+	// attachAuto uses the mechanism the vendor recommends and falls back to the other one if the archive has no
+	// artifact for it, e.g. no native agent for the architecture of the container
+	attachAuto = "auto"
+	// attachJavaagent loads the Java agent with -javaagent
+	attachJavaagent = "javaagent"
+	// attachAgentpath loads the native agent for the architecture of the container with -agentpath
+	attachAgentpath = "agentpath"
+)
+
+// agentLayout lists the artifacts of one layout of an agent archive, relative to the directory it is extracted to
+type agentLayout struct {
+	// jar is the Java agent, empty if the layout has none
+	jar string
+	// native are the native agents by architecture (GOARCH); architectures without one are missing
+	native map[string]string
+}
+
+// dualAgent describes an agent that is shipped both as a Java agent and as native agents
+type dualAgent struct {
+	// name is the name of the agent in messages
+	name string
+	// preferred is the mechanism attachAuto uses if the archive has an artifact for it
+	preferred string
+	// layouts are the layouts of the archives of different versions, the current one first
+	layouts []agentLayout
+}
+
+// agentAttachment is the artifact an agent is attached with
+type agentAttachment struct {
+	mechanism string
+	path      string
+}
+
+// option returns the JVM option that attaches the artifact at runtimePath
+func (a agentAttachment) option(runtimePath string) string {
+	return fmt.Sprintf("-%s:%s", a.mechanism, runtimePath)
+}
+
+// validateAttach returns an error if attach is not one of the attach mechanisms
+func validateAttach(name, attach string) error {
+	switch attach {
+	case attachAuto, attachJavaagent, attachAgentpath:
+		return nil
+	}
+	return fmt.Errorf("invalid attach %q for %s: must be %s, %s or %s", attach, name, attachAuto, attachJavaagent, attachAgentpath)
+}
+
+// resolve returns the artifact in installDir that attaches the agent with the mechanism attach selects. Only the exact
+// paths of the layout of the extracted archive are considered, so that a native agent for a different architecture is
+// never picked; if the artifact is missing, the error names the file that was expected.
+func (d dualAgent) resolve(installDir, attach string) (agentAttachment, error) {
+	if err := validateAttach(d.name, attach); err != nil {
+		return agentAttachment{}, err
+	}
+
+	layout := d.layout(installDir)
+	mechanisms := []string{attach}
+	if attach == attachAuto {
+		mechanisms = []string{d.preferred, attachJavaagent}
+		if d.preferred == attachJavaagent {
+			mechanisms[1] = attachAgentpath
+		}
+	}
+
+	var missing []string
+	for _, mechanism := range mechanisms {
+		artifact, err := layout.artifact(mechanism, d.name)
+		if err != nil {
+			if attach != attachAuto {
+				return agentAttachment{}, err
+			}
+			continue
+		}
+		path := filepath.Join(installDir, artifact)
+		if _, err := os.Stat(path); err == nil {
+			return agentAttachment{mechanism: mechanism, path: path}, nil
+		}
+		missing = append(missing, path)
+	}
+
+	if len(missing) == 0 {
+		return agentAttachment{}, fmt.Errorf("%s has neither a Java agent nor a native agent for %s", d.name, runtime.GOARCH)
+	}
+	return agentAttachment{}, fmt.Errorf("%s not found: expected %s", d.name, strings.Join(missing, " or "))
+}
+
+// layout returns the layout of the archive extracted to installDir: the first one any artifact of which exists, or the
+// current one if none does
+func (d dualAgent) layout(installDir string) agentLayout {
+	for _, layout := range d.layouts {
+		artifacts := []string{layout.jar}
+		for _, native := range layout.native {
+			artifacts = append(artifacts, native)
+		}
+		for _, artifact := range artifacts {
+			if artifact == "" {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(installDir, artifact)); err == nil {
+				return layout
+			}
+		}
+	}
+	return d.layouts[0]
+}
+
+// artifact returns the artifact of the layout that attaches the agent with mechanism, or an error if the layout has
+// none, e.g. no native agent for the architecture of the container
+func (l agentLayout) artifact(mechanism, name string) (string, error) {
+	if mechanism == attachJavaagent {
+		if l.jar == "" {
+			return "", fmt.Errorf("%s has no Java agent: set attach: %s", name, attachAgentpath)
+		}
+		return l.jar, nil
+	}
+
+	native, ok := l.native[runtime.GOARCH]
+	if !ok {
+		return "", fmt.Errorf("%s has no native agent for %s: set attach: %s", name, runtime.GOARCH, attachJavaagent)
+	}
+	return native, nil
+}
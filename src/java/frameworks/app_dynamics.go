@@ -3,9 +3,13 @@ package frameworks
 import (
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/appname"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
 	"github.com/cloudfoundry/java-buildpack/src/java/resources"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/cloudfoundry/libbuildpack"
 )
@@ -37,6 +41,7 @@ func (a *AppDynamicsFramework) Detect() (string, error) {
 		return "AppDynamics Agent", nil
 	}
 
+	a.context.Log.Debug("AppDynamics: no appdynamics service bound")
 	return "", nil
 }
 
@@ -66,12 +71,13 @@ func (a *AppDynamicsFramework) Supply() error {
 	}
 
 	// Install default configuration from embedded resources
+	var causes []error
 	if err := a.installDefaultConfiguration(agentDir); err != nil {
-		a.context.Log.Warning("Could not install default AppDynamics configuration: %s", err.Error())
+		causes = append(causes, fmt.Errorf("could not install default AppDynamics configuration: %w", err))
 	}
 
 	a.context.Log.Debug("Installed AppDynamics Agent version %s", dep.Version)
-	return nil
+	return incomplete(causes...)
 }
 
 // installDefaultConfiguration installs the default app-agent-config.xml from embedded resources
@@ -110,7 +116,6 @@ func (a *AppDynamicsFramework) installDefaultConfiguration(agentDir string) erro
 // Finalize configures AppDynamics agent for runtime
 func (a *AppDynamicsFramework) Finalize() error {
 	// Get buildpack index for multi-buildpack support
-	depsIdx := a.context.Stager.DepsIdx()
 
 	// Find the actual AppDynamics agent jar at staging time
 	agentDir := filepath.Join(a.context.Stager.DepDir(), "app_dynamics_agent")
@@ -125,7 +130,7 @@ func (a *AppDynamicsFramework) Finalize() error {
 	if err != nil {
 		return fmt.Errorf("failed to compute relative path: %w", err)
 	}
-	runtimeAgentPath := filepath.Join(fmt.Sprintf("$DEPS_DIR/%s", depsIdx), relPath)
+	runtimeAgentPath := a.context.Droplet().Dep(relPath)
 
 	// Get AppDynamics configuration from service binding
 	vcapServices, _ := GetVCAPServices()
@@ -165,24 +170,44 @@ func (a *AppDynamicsFramework) Finalize() error {
 			javaOpts += fmt.Sprintf(" -Dappdynamics.agent.accountAccessKey=%s", accessKey)
 		}
 
-		// Add application name
-		if appName, ok := service.Credentials["application-name"].(string); ok && appName != "" {
-			javaOpts += fmt.Sprintf(" -Dappdynamics.agent.applicationName=%s", appName)
-		}
+	}
 
-		// Add tier name (use app name from VCAP_APPLICATION if not provided)
-		if tierName, ok := service.Credentials["tier-name"].(string); ok && tierName != "" {
-			javaOpts += fmt.Sprintf(" -Dappdynamics.agent.tierName=%s", tierName)
+	// Application, tier and node names: credentials, then JBP_CONFIG_APP_DYNAMICS_AGENT, then the built-in defaults,
+	// of which the application name follows JBP_CONFIG_APPLICATION_NAME
+	cfg, err := a.loadConfig()
+	if err != nil {
+		return err
+	}
+	applicationName, err := appname.Template(a.context.Log, "{space_name}:{application_name}")
+	if err != nil {
+		return err
+	}
+	var credentials map[string]interface{}
+	if service != nil {
+		credentials = service.Credentials
+	}
+	names := []struct {
+		property, credential, configured, fallback string
+	}{
+		{"applicationName", "application-name", cfg.DefaultApplicationName, applicationName},
+		{"tierName", "tier-name", cfg.DefaultTierName, "{application_name}"},
+		{"nodeName", "node-name", cfg.DefaultNodeName, "{application_name}:{instance_index}"},
+	}
+	for _, n := range names {
+		name := credentialString(credentials, n.credential)
+		if name == "" {
+			name = n.configured
 		}
-
-		// Add node name (use instance index if available)
-		if nodeName, ok := service.Credentials["node-name"].(string); ok && nodeName != "" {
-			javaOpts += fmt.Sprintf(" -Dappdynamics.agent.nodeName=%s", nodeName)
+		if name == "" {
+			name = n.fallback
+		}
+		if name = appDynamicsName(name); name != "" {
+			javaOpts += fmt.Sprintf(" -Dappdynamics.agent.%s=%s", n.property, name)
 		}
 	}
 
 	// Write JAVA_OPTS to .opts file with priority 11 (Ruby buildpack line 45)
-	if err := writeJavaOptsFile(a.context, 11, "app_dynamics", javaOpts); err != nil {
+	if err := writeJavaOptsFile(a.context, javaopts.AppDynamics, "app_dynamics", javaOpts); err != nil {
 		return fmt.Errorf("failed to write java_opts file: %w", err)
 	}
 
@@ -190,6 +215,45 @@ func (a *AppDynamicsFramework) Finalize() error {
 	return nil
 }
 
+// appDynamicsConfig is the JBP_CONFIG_APP_DYNAMICS_AGENT configuration, e.g.
+//
+//	default_application_name: "{organization_name}-{space_name}"
+//	default_tier_name: "{application_name}"
+//	default_node_name: "{application_name}-{instance_index}"
+//
+// Names may reference the fields of VCAP_APPLICATION listed in appDynamicsName and contain shell expressions,
+// which are evaluated when the application starts.
+type appDynamicsConfig struct {
+	DefaultApplicationName string `yaml:"default_application_name"`
+	DefaultTierName        string `yaml:"default_tier_name"`
+	DefaultNodeName        string `yaml:"default_node_name"`
+}
+
+func (a *AppDynamicsFramework) loadConfig() (*appDynamicsConfig, error) {
+	adConfig := appDynamicsConfig{}
+	if err := config.Load(a.context.Log, "app_dynamics_agent", &adConfig); err != nil {
+		return nil, err
+	}
+	return &adConfig, nil
+}
+
+// appDynamicsName resolves the references in an application, tier or node name: the placeholders of
+// appname.Placeholders, e.g. {application_name} or {space}, are replaced from VCAP_APPLICATION at staging,
+// {instance_index} becomes $CF_INSTANCE_INDEX so that every instance reports as its own node. Returns "" if a name
+// references a field that VCAP_APPLICATION does not provide, so that the agent falls back to its own configuration.
+func appDynamicsName(name string) string {
+	for placeholder, value := range appname.Placeholders() {
+		if !strings.Contains(name, placeholder) {
+			continue
+		}
+		if value == "" {
+			return ""
+		}
+		name = strings.ReplaceAll(name, placeholder, escapeValue(value))
+	}
+	return strings.ReplaceAll(name, "{instance_index}", "$CF_INSTANCE_INDEX")
+}
+
 func (a *AppDynamicsFramework) DependencyIdentifier() string {
 	return "appdynamics"
 }
@@ -376,6 +376,85 @@ var _ = Describe("AppDynamics Agent", func() {
 			})
 		})
 
+		Context("naming", func() {
+			readOpts := func() string {
+				content, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "11_app_dynamics.opts"))
+				Expect(err).NotTo(HaveOccurred())
+				return string(content)
+			}
+
+			BeforeEach(func() {
+				installAppDynamicsAgent(depsDir)
+				os.Setenv("VCAP_SERVICES", appdVCAPServices("appdynamics", "my-appd", nil, ""))
+				os.Setenv("VCAP_APPLICATION", `{"application_name":"shop","space_name":"prod","organization_name":"acme"}`)
+			})
+
+			AfterEach(func() {
+				os.Unsetenv("VCAP_APPLICATION")
+				os.Unsetenv("JBP_CONFIG_APP_DYNAMICS_AGENT")
+				os.Unsetenv("JBP_CONFIG_APPLICATION_NAME")
+			})
+
+			It("names the application space:app, the tier after the application and the node per instance", func() {
+				Expect(fw.Finalize()).To(Succeed())
+				opts := readOpts()
+				Expect(opts).To(ContainSubstring(" -Dappdynamics.agent.applicationName=prod:shop"))
+				Expect(opts).To(ContainSubstring(" -Dappdynamics.agent.tierName=shop"))
+				Expect(opts).To(ContainSubstring(" -Dappdynamics.agent.nodeName=shop:$CF_INSTANCE_INDEX"))
+			})
+
+			It("uses the configured names and resolves their references", func() {
+				os.Setenv("JBP_CONFIG_APP_DYNAMICS_AGENT", `{default_application_name: "{organization_name}-{space_name}", `+
+					`default_tier_name: web, default_node_name: "node-{instance_index}"}`)
+
+				Expect(fw.Finalize()).To(Succeed())
+				opts := readOpts()
+				Expect(opts).To(ContainSubstring(" -Dappdynamics.agent.applicationName=acme-prod"))
+				Expect(opts).To(ContainSubstring(" -Dappdynamics.agent.tierName=web"))
+				Expect(opts).To(ContainSubstring(" -Dappdynamics.agent.nodeName=node-$CF_INSTANCE_INDEX"))
+			})
+
+			It("names the application after the application name template of all agents", func() {
+				os.Setenv("JBP_CONFIG_APPLICATION_NAME", `{template: "{org}-{space}-{app}"}`)
+
+				Expect(fw.Finalize()).To(Succeed())
+				opts := readOpts()
+				Expect(opts).To(ContainSubstring(" -Dappdynamics.agent.applicationName=acme-prod-shop"))
+				Expect(opts).To(ContainSubstring(" -Dappdynamics.agent.tierName=shop"))
+			})
+
+			It("prefers its own configured application name over the template", func() {
+				os.Setenv("JBP_CONFIG_APPLICATION_NAME", `{template: "{org}-{space}-{app}"}`)
+				os.Setenv("JBP_CONFIG_APP_DYNAMICS_AGENT", `{default_application_name: "{space_name}"}`)
+
+				Expect(fw.Finalize()).To(Succeed())
+				Expect(readOpts()).To(ContainSubstring(" -Dappdynamics.agent.applicationName=prod "))
+			})
+
+			It("prefers the names in the credentials", func() {
+				os.Setenv("JBP_CONFIG_APP_DYNAMICS_AGENT", `{default_tier_name: web}`)
+				os.Setenv("VCAP_SERVICES", appdVCAPServices("appdynamics", "my-appd", nil, `"tier-name":"api"`))
+
+				Expect(fw.Finalize()).To(Succeed())
+				Expect(readOpts()).To(ContainSubstring(" -Dappdynamics.agent.tierName=api"))
+			})
+
+			It("escapes names for the shell", func() {
+				os.Setenv("VCAP_APPLICATION", `{"application_name":"shop (eu)","space_name":"prod"}`)
+
+				Expect(fw.Finalize()).To(Succeed())
+				Expect(readOpts()).To(ContainSubstring(` -Dappdynamics.agent.tierName=shop\ \(eu\)`))
+			})
+
+			It("leaves names that reference missing VCAP_APPLICATION fields to the agent", func() {
+				os.Unsetenv("VCAP_APPLICATION")
+
+				Expect(fw.Finalize()).To(Succeed())
+				Expect(readOpts()).NotTo(ContainSubstring("appdynamics.agent.applicationName"))
+				Expect(readOpts()).NotTo(ContainSubstring("appdynamics.agent.nodeName"))
+			})
+		})
+
 		Context("when javaagent.jar is not present", func() {
 			It("returns an error", func() {
 				err := fw.Finalize()
@@ -3,9 +3,10 @@ package frameworks
 import (
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
 	"os"
 	"path/filepath"
-	"strings"
 )
 
 // AspectJWeaverAgentFramework represents the AspectJ Weaver Agent framework
@@ -24,16 +25,21 @@ func NewAspectJWeaverAgentFramework(ctx *common.Context) *AspectJWeaverAgentFram
 func (a *AspectJWeaverAgentFramework) Detect() (string, error) {
 	config, err := a.loadConfig()
 	if err != nil {
+		if common.IsUnknownConfigKeysError(err) {
+			return "", err
+		}
 		a.context.Log.Warning("Failed to load aspectj weaver agent config: %s", err.Error())
 		return "", nil // Don't fail the build
 	}
 
 	if !config.isEnabled() {
+		a.context.Log.Debug("AspectJ Weaver: disabled by configuration")
 		return "", nil
 	}
 	// Look for aspectjweaver-*.jar in the application
 	aspectjJar, err := a.findAspectJWeaver()
 	if err != nil || aspectjJar == "" {
+		a.context.Log.Debug("AspectJ Weaver: no aspectjweaver-*.jar in the application")
 		return "", nil
 	}
 
@@ -55,6 +61,7 @@ func (a *AspectJWeaverAgentFramework) Detect() (string, error) {
 		return "aspectj-weaver", nil
 	}
 
+	a.context.Log.Debug("AspectJ Weaver: no META-INF/aop.xml or WEB-INF/classes/META-INF/aop.xml")
 	return "", nil
 }
 
@@ -89,13 +96,13 @@ func (a *AspectJWeaverAgentFramework) Finalize() error {
 	if err != nil {
 		return fmt.Errorf("failed to compute relative path: %w", err)
 	}
-	runtimeJarPath := filepath.Join("$HOME", relPath)
+	runtimeJarPath := a.context.Droplet().App(relPath)
 
 	// Build JAVA_OPTS with javaagent using runtime path
 	javaOpts := fmt.Sprintf("-javaagent:%s", runtimeJarPath)
 
 	// Write JAVA_OPTS to .opts file with priority 12 (Ruby buildpack line 46)
-	if err := writeJavaOptsFile(a.context, 12, "aspectj_weaver", javaOpts); err != nil {
+	if err := writeJavaOptsFile(a.context, javaopts.AspectJWeaver, "aspectj_weaver", javaOpts); err != nil {
 		return fmt.Errorf("failed to write java_opts file: %w", err)
 	}
 
@@ -105,36 +112,14 @@ func (a *AspectJWeaverAgentFramework) Finalize() error {
 
 // findAspectJWeaver searches for aspectjweaver-*.jar in the application
 func (a *AspectJWeaverAgentFramework) findAspectJWeaver() (string, error) {
-	buildDir := a.context.Stager.BuildDir()
-
-	// Common locations to check for AspectJ Weaver JAR
-	searchDirs := []string{
-		filepath.Join(buildDir, "WEB-INF", "lib"),
-		filepath.Join(buildDir, "lib"),
-		filepath.Join(buildDir, "BOOT-INF", "lib"), // Spring Boot
-		buildDir, // Root directory
-	}
-
-	for _, dir := range searchDirs {
-		if _, err := os.Stat(dir); err != nil {
-			continue // Directory doesn't exist, skip
-		}
-
-		entries, err := os.ReadDir(dir)
-		if err != nil {
-			continue
-		}
-
-		for _, entry := range entries {
-			if !entry.IsDir() && strings.HasPrefix(entry.Name(), "aspectjweaver-") && strings.HasSuffix(entry.Name(), ".jar") {
-				jarPath := filepath.Join(dir, entry.Name())
-				a.context.Log.Debug("Found AspectJ Weaver JAR: %s", jarPath)
-				return jarPath, nil
-			}
-		}
+	jar, ok := a.context.Classpath().Find("aspectjweaver-*.jar")
+	if !ok {
+		return "", nil
 	}
 
-	return "", nil
+	jarPath := filepath.Join(a.context.Stager.BuildDir(), jar)
+	a.context.Log.Debug("Found AspectJ Weaver JAR: %s", jarPath)
+	return jarPath, nil
 }
 
 func (a *AspectJWeaverAgentFramework) loadConfig() (*aspectjWeaverConfig, error) {
@@ -142,17 +127,9 @@ func (a *AspectJWeaverAgentFramework) loadConfig() (*aspectjWeaverConfig, error)
 	ajwConfig := aspectjWeaverConfig{
 		Enabled: true,
 	}
-	config := os.Getenv("JBP_CONFIG_ASPECTJ_WEAVER_AGENT")
-	if config != "" {
-		yamlHandler := common.YamlHandler{}
-		err := yamlHandler.ValidateFields([]byte(config), &ajwConfig)
-		if err != nil {
-			a.context.Log.Warning("Unknown user config values: %s", err.Error())
-		}
-		// overlay JBP_CONFIG_ASPECTJ_WEAVER_AGENT over default values
-		if err = yamlHandler.Unmarshal([]byte(config), &ajwConfig); err != nil {
-			return nil, fmt.Errorf("failed to parse JBP_CONFIG_ASPECTJ_WEAVER_AGENT: %w", err)
-		}
+	// overlay buildpack defaults and JBP_CONFIG_ASPECTJ_WEAVER_AGENT over default values
+	if err := config.Load(a.context.Log, "aspectj_weaver_agent", &ajwConfig); err != nil {
+		return nil, err
 	}
 	return &ajwConfig, nil
 }
@@ -19,6 +19,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/appname"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
 	"github.com/cloudfoundry/java-buildpack/src/java/resources"
 	"os"
 	"path/filepath"
@@ -97,8 +99,9 @@ func (a *AzureApplicationInsightsAgentFramework) Supply() error {
 	}
 
 	// Install default configuration from embedded resources
+	var causes []error
 	if err := a.installDefaultConfiguration(agentDir); err != nil {
-		a.context.Log.Warning("Could not install default Azure Application Insights configuration: %s", err.Error())
+		causes = append(causes, fmt.Errorf("could not install default Azure Application Insights configuration: %w", err))
 	}
 
 	// constructJarPath can be skipped here and do it only in finalize, but it can be left as a double check
@@ -109,7 +112,7 @@ func (a *AzureApplicationInsightsAgentFramework) Supply() error {
 	}
 
 	a.context.Log.Info("Azure Application Insights agent %s installed", dep.Version)
-	return nil
+	return incomplete(causes...)
 }
 
 // installDefaultConfiguration installs the default AI-Agent.xml from embedded resources
@@ -150,14 +153,13 @@ func (a *AzureApplicationInsightsAgentFramework) Finalize() error {
 	a.context.Log.BeginStep("Configuring Azure Application Insights agent")
 
 	// Get buildpack index for multi-buildpack support
-	depsIdx := a.context.Stager.DepsIdx()
 
 	// Convert staging path to runtime path
 	relPath, err := filepath.Rel(a.context.Stager.DepDir(), a.jarPath)
 	if err != nil {
 		return fmt.Errorf("failed to determine relative path for Azure Application Insights agent: %w", err)
 	}
-	runtimeJarPath := filepath.Join(fmt.Sprintf("$DEPS_DIR/%s", depsIdx), relPath)
+	runtimeJarPath := a.context.Droplet().Dep(relPath)
 
 	// Build all JAVA_OPTS options
 	var opts []string
@@ -175,13 +177,17 @@ func (a *AzureApplicationInsightsAgentFramework) Finalize() error {
 	}
 
 	// Set cloud role name (application name)
-	if appName := a.getApplicationName(); appName != "" {
+	appName, err := a.getApplicationName()
+	if err != nil {
+		return err
+	}
+	if appName != "" {
 		opts = append(opts, fmt.Sprintf("-Dapplicationinsights.role.name=%s", appName))
 	}
 
 	// Write all options to .opts file
 	javaOpts := strings.Join(opts, " ")
-	if err := writeJavaOptsFile(a.context, 13, "azure_application_insights_agent", javaOpts); err != nil {
+	if err := writeJavaOptsFile(a.context, javaopts.AzureApplicationInsights, "azure_application_insights_agent", javaOpts); err != nil {
 		return fmt.Errorf("failed to write JAVA_OPTS for Azure Application Insights: %w", err)
 	}
 
@@ -257,23 +263,9 @@ func (a *AzureApplicationInsightsAgentFramework) getCredentials() AzureCredentia
 	return creds
 }
 
-// getApplicationName returns the application name from VCAP_APPLICATION
-func (a *AzureApplicationInsightsAgentFramework) getApplicationName() string {
-	vcapApp := os.Getenv("VCAP_APPLICATION")
-	if vcapApp == "" {
-		return ""
-	}
-
-	var appData map[string]interface{}
-	if err := json.Unmarshal([]byte(vcapApp), &appData); err != nil {
-		return ""
-	}
-
-	if name, ok := appData["application_name"].(string); ok {
-		return name
-	}
-
-	return ""
+// getApplicationName returns the name the application is reported as, see appname.Name
+func (a *AzureApplicationInsightsAgentFramework) getApplicationName() (string, error) {
+	return appname.Name(a.context.Log, appname.App)
 }
 
 func (a *AzureApplicationInsightsAgentFramework) constructJarPath(agentDir string) error {
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
 	"github.com/cloudfoundry/libbuildpack"
 )
 
@@ -29,6 +30,7 @@ func (f *CfMetricsExporterFramework) Detect() (string, error) {
 		}
 		return "CF Metrics Exporter", nil
 	}
+	f.context.Log.Debug("CF Metrics Exporter: disabled (default), enable it with CF_METRICS_EXPORTER_ENABLED=true")
 	return "", nil
 }
 
@@ -97,8 +99,7 @@ func (f *CfMetricsExporterFramework) Finalize() error {
 	}
 
 	jarName := fmt.Sprintf("cf-metrics-exporter-%s.jar", dep.Version)
-	depsIdx := f.context.Stager.DepsIdx()
-	agentPath := fmt.Sprintf("$DEPS_DIR/%s/cf_metrics_exporter/%s", depsIdx, jarName)
+	agentPath := f.context.Droplet().Dep("cf_metrics_exporter", jarName)
 
 	props := os.Getenv("CF_METRICS_EXPORTER_PROPS")
 	var javaOpt string
@@ -109,5 +110,5 @@ func (f *CfMetricsExporterFramework) Finalize() error {
 	}
 
 	// Priority 43: after SkyWalking (41), Splunk OTEL (42)
-	return writeJavaOptsFile(f.context, 43, cfMetricsExporterDirName, javaOpt)
+	return writeJavaOptsFile(f.context, javaopts.CfMetricsExporter, cfMetricsExporterDirName, javaOpt)
 }
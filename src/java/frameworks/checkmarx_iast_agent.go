@@ -18,8 +18,8 @@ package frameworks
 import (
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
-	"io"
-	"net/http"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/httpclient"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
 	"os"
 	"path/filepath"
 	"strings"
@@ -77,11 +77,16 @@ func (c *CheckmarxIASTAgentFramework) Supply() error {
 	}
 
 	jarPath := filepath.Join(agentDir, "cx-agent.jar")
-	if err := c.downloadAgent(credentials.URL, jarPath); err != nil {
+	if err := c.downloadAgent(credentials.URL, jarPath, credentials.Checksum); err != nil {
 		return fmt.Errorf("failed to download Checkmarx IAST agent: %w", err)
 	}
 
 	c.jarPath = jarPath
+	if err := common.RecordDownload(c.context.Stager.DepDir(), common.InstalledDependency{
+		Name: "checkmarx-iast-agent", URI: credentials.URL, Dir: "checkmarx_iast_agent",
+	}, jarPath); err != nil {
+		c.context.Log.Warning("Could not record the Checkmarx IAST agent for the SBOM: %s", err.Error())
+	}
 	c.context.Log.Info("Checkmarx IAST agent installed from %s", credentials.URL)
 	return nil
 }
@@ -94,14 +99,13 @@ func (c *CheckmarxIASTAgentFramework) Finalize() error {
 	c.context.Log.BeginStep("Configuring Checkmarx IAST agent")
 
 	// Get buildpack index for multi-buildpack support
-	depsIdx := c.context.Stager.DepsIdx()
 
 	// Convert staging path to runtime path
 	relPath, err := filepath.Rel(c.context.Stager.DepDir(), c.jarPath)
 	if err != nil {
 		return fmt.Errorf("failed to determine relative path for Checkmarx IAST agent: %w", err)
 	}
-	runtimeJarPath := filepath.Join(fmt.Sprintf("$DEPS_DIR/%s", depsIdx), relPath)
+	runtimeJarPath := c.context.Droplet().Dep(relPath)
 
 	// Build all JAVA_OPTS options
 	var opts []string
@@ -122,7 +126,7 @@ func (c *CheckmarxIASTAgentFramework) Finalize() error {
 
 	// Write all options to .opts file
 	javaOpts := strings.Join(opts, " ")
-	if err := writeJavaOptsFile(c.context, 14, "checkmarx_iast_agent", javaOpts); err != nil {
+	if err := writeJavaOptsFile(c.context, javaopts.CheckmarxIAST, "checkmarx_iast_agent", javaOpts); err != nil {
 		return fmt.Errorf("failed to write JAVA_OPTS for Checkmarx IAST: %w", err)
 	}
 
@@ -135,6 +139,7 @@ type CheckmarxCredentials struct {
 	URL        string // Agent download URL
 	ManagerURL string // Checkmarx manager URL
 	APIKey     string // API key for authentication
+	Checksum   string // SHA256 of the agent JAR
 }
 
 // getCredentials retrieves Checkmarx IAST credentials from service binding
@@ -182,32 +187,20 @@ func (c *CheckmarxIASTAgentFramework) getCredentials() CheckmarxCredentials {
 		creds.APIKey = apiKey
 	}
 
+	if checksum, ok := service.Credentials["checksum"].(string); ok {
+		creds.Checksum = checksum
+	}
+
 	return creds
 }
 
-// downloadAgent downloads the agent JAR from the given URL
-func (c *CheckmarxIASTAgentFramework) downloadAgent(url, destPath string) error {
+// downloadAgent downloads the agent JAR from the given URL and verifies it against checksum, if one is given
+func (c *CheckmarxIASTAgentFramework) downloadAgent(url, destPath, checksum string) error {
 	c.context.Log.Debug("Downloading Checkmarx IAST agent from %s", url)
 
-	resp, err := http.Get(url)
-	if err != nil {
+	if err := httpclient.Default().DownloadVerified(url, destPath, checksum); err != nil {
 		return fmt.Errorf("failed to download agent: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to download agent: HTTP %d", resp.StatusCode)
-	}
-
-	outFile, err := os.Create(destPath)
-	if err != nil {
-		return fmt.Errorf("failed to create destination file: %w", err)
-	}
-	defer outFile.Close()
-
-	if _, err := io.Copy(outFile, resp.Body); err != nil {
-		return fmt.Errorf("failed to write agent file: %w", err)
-	}
 
 	return nil
 }
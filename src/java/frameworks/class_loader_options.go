@@ -0,0 +1,188 @@
+package frameworks
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+)
+
+// CheckClassLoaderOptions checks the class loader options that frameworks and the user wrote to the .opts files
+// against the major version of the installed JRE. Java 9 removed the extension and endorsed mechanisms and the
+// boot class path options other than -Xbootclasspath/a, and the JVM refuses to start with them; Java 8 does not
+// know --patch-module. Such options are replaced with their equivalent for the installed version, or removed with
+// a warning that names the alternative.
+//
+// This must run after all frameworks are finalized and before the JAVA_OPTS assembly script is written.
+func CheckClassLoaderOptions(ctx *common.Context) error {
+	javaVersion, err := common.GetJavaMajorVersion()
+	if err != nil {
+		ctx.Log.Debug("Unable to detect Java version, class loader options are not checked: %s", err.Error())
+		return nil
+	}
+
+	return rewriteJavaOptsFiles(ctx, func(source, opts string) (string, bool) {
+		checker := classLoaderOptionsChecker{context: ctx, javaVersion: javaVersion, source: source}
+		return checker.check(opts)
+	})
+}
+
+// rewriteJavaOptsFiles replaces the options of each .opts file with the result of check, if it changed them. source
+// is the name of the file, e.g. 32_luna_security_provider.
+func rewriteJavaOptsFiles(ctx *common.Context, check func(source, opts string) (string, bool)) error {
+	files, err := filepath.Glob(filepath.Join(ctx.Stager.DepDir(), "java_opts", "*.opts"))
+	if err != nil {
+		return fmt.Errorf("failed to list java_opts files: %w", err)
+	}
+
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", filepath.Base(file), err)
+		}
+
+		opts, changed := check(strings.TrimSuffix(filepath.Base(file), ".opts"), string(content))
+		if !changed {
+			continue
+		}
+		if err := os.WriteFile(file, []byte(opts), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", filepath.Base(file), err)
+		}
+	}
+	return nil
+}
+
+// classLoaderOptionsChecker checks the options of a single .opts file
+type classLoaderOptionsChecker struct {
+	context     *common.Context
+	javaVersion int
+	// source is the name of the .opts file, e.g. 32_luna_security_provider
+	source string
+}
+
+// check returns opts with the class loader options the JVM would reject replaced or removed, and whether it changed
+// any
+func (c classLoaderOptionsChecker) check(opts string) (string, bool) {
+	tokens := strings.Fields(opts)
+	checked := make([]string, 0, len(tokens))
+	changed := false
+
+	for i := 0; i < len(tokens); i++ {
+		opt := tokens[i]
+
+		if c.javaVersion <= 8 {
+			// --patch-module takes its value either after = or as the next option
+			if opt == "--patch-module" && i+1 < len(tokens) {
+				i++
+				opt += "=" + tokens[i]
+			}
+			if value, ok := strings.CutPrefix(opt, "--patch-module="); ok {
+				checked = append(checked, c.patchModule(value)...)
+				changed = true
+				continue
+			}
+			checked = append(checked, opt)
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(opt, "-Djava.ext.dirs="):
+			checked = append(checked, c.extensionDirs(strings.TrimPrefix(opt, "-Djava.ext.dirs="))...)
+			changed = true
+		case strings.HasPrefix(opt, "-Djava.endorsed.dirs="):
+			c.context.Log.Warning("Removing %s from %s: Java %d does not support endorsed directories and would not start.\n"+
+				"  Put the JARs on the classpath, or replace upgradeable modules with --upgrade-module-path",
+				opt, c.source, c.javaVersion)
+			changed = true
+		case strings.HasPrefix(opt, "-Xbootclasspath/p:"):
+			c.context.Log.Warning("Removing %s from %s: Java %d does not support prepending to the boot class path and would not start.\n"+
+				"  Use --patch-module <module>=%s to override classes of a module, or -Xbootclasspath/a to add classes",
+				opt, c.source, c.javaVersion, strings.TrimPrefix(opt, "-Xbootclasspath/p:"))
+			changed = true
+		case strings.HasPrefix(opt, "-Xbootclasspath:"):
+			c.context.Log.Warning("Removing %s from %s: Java %d loads the platform classes from modules, does not support "+
+				"replacing the boot class path and would not start.\n  Use -Xbootclasspath/a to add classes",
+				opt, c.source, c.javaVersion)
+			changed = true
+		case strings.HasPrefix(opt, "-Xbootclasspath/a:"):
+			c.context.Log.Debug("%s adds %s to the boot class path; on Java %d its classes can only use modules of the "+
+				"boot class loader, not java.sql or other modules of the platform class loader",
+				c.source, strings.TrimPrefix(opt, "-Xbootclasspath/a:"), c.javaVersion)
+			checked = append(checked, opt)
+		default:
+			checked = append(checked, opt)
+		}
+	}
+
+	if !changed {
+		return opts, false
+	}
+	return strings.Join(checked, " "), true
+}
+
+// extensionDirs returns the -Xbootclasspath/a option that puts the JARs of the extension directories dirs on the boot
+// class path, which like the extension class loader is searched before the application's classpath. The JARs of the
+// directories in the droplet are listed at staging time; the JDK's own lib/ext directories no longer exist.
+func (c classLoaderOptionsChecker) extensionDirs(dirs string) []string {
+	var jars, unknown []string
+	for _, dir := range strings.Split(dirs, ":") {
+		if dir == "" || strings.HasPrefix(dir, "$JAVA_HOME") {
+			continue
+		}
+
+		stagingDir, ok := c.stagingPath(dir)
+		if !ok {
+			unknown = append(unknown, dir)
+			continue
+		}
+		matches, _ := filepath.Glob(filepath.Join(stagingDir, "*.jar"))
+		for _, match := range matches {
+			jars = append(jars, dir+"/"+filepath.Base(match))
+		}
+	}
+
+	message := fmt.Sprintf("Replacing -Djava.ext.dirs=%s in %s: Java %d has no extension class loader and would not start", dirs,
+		c.source, c.javaVersion)
+	if len(unknown) > 0 {
+		message += fmt.Sprintf(".\n  The JARs of %s cannot be listed at staging and must be put on the classpath",
+			strings.Join(unknown, ", "))
+	}
+	if len(jars) == 0 {
+		c.context.Log.Warning("%s; no JARs of the extension directories are added to the boot class path", message)
+		return nil
+	}
+
+	bootClassPath := "-Xbootclasspath/a:" + strings.Join(jars, ":")
+	c.context.Log.Warning("%s; using %s instead", message, bootClassPath)
+	return []string{bootClassPath}
+}
+
+// patchModule returns the -Xbootclasspath/p option that prepends the path of the --patch-module value
+// module=path to the boot class path, which is how Java 8 overrides platform classes
+func (c classLoaderOptionsChecker) patchModule(value string) []string {
+	module, path, ok := strings.Cut(value, "=")
+	if !ok || path == "" {
+		c.context.Log.Warning("Removing --patch-module %s from %s: Java %d does not recognize it and would not start",
+			value, c.source, c.javaVersion)
+		return nil
+	}
+
+	c.context.Log.Warning("Replacing --patch-module %s in %s: Java %d has no module system and would not start; "+
+		"using -Xbootclasspath/p:%s instead to override the classes of %s", value, c.source, c.javaVersion, path, module)
+	return []string{"-Xbootclasspath/p:" + path}
+}
+
+// stagingPath returns the staging directory of a runtime path in the droplet, which starts with this buildpack's
+// $DEPS_DIR/<index> or with $HOME
+func (c classLoaderOptionsChecker) stagingPath(runtimePath string) (string, bool) {
+	depsPrefix := c.context.Droplet().Dep()
+	if rest, ok := strings.CutPrefix(runtimePath, depsPrefix); ok && (rest == "" || strings.HasPrefix(rest, "/")) {
+		return filepath.Join(c.context.Stager.DepDir(), filepath.FromSlash(rest)), true
+	}
+	if rest, ok := strings.CutPrefix(runtimePath, c.context.Droplet().App()); ok && (rest == "" || strings.HasPrefix(rest, "/")) {
+		return filepath.Join(c.context.Stager.BuildDir(), filepath.FromSlash(rest)), true
+	}
+	return "", false
+}
@@ -0,0 +1,179 @@
+package frameworks_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/frameworks"
+	"github.com/cloudfoundry/libbuildpack"
+)
+
+var _ = Describe("CheckClassLoaderOptions", func() {
+	var (
+		ctx      *common.Context
+		logs     *bytes.Buffer
+		buildDir string
+		cacheDir string
+		depsDir  string
+		javaHome string
+	)
+
+	setJavaVersion := func(version string) {
+		Expect(os.WriteFile(filepath.Join(javaHome, "release"), []byte(`JAVA_VERSION="`+version+`"`), 0644)).To(Succeed())
+	}
+
+	writeOpts := func(name, opts string) {
+		Expect(os.MkdirAll(filepath.Join(depsDir, "0", "java_opts"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(depsDir, "0", "java_opts", name+".opts"), []byte(opts), 0644)).To(Succeed())
+	}
+
+	readOpts := func(name string) string {
+		content, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", name+".opts"))
+		Expect(err).NotTo(HaveOccurred())
+		return string(content)
+	}
+
+	BeforeEach(func() {
+		var err error
+		buildDir, err = os.MkdirTemp("", "class-loader-build")
+		Expect(err).NotTo(HaveOccurred())
+		cacheDir, err = os.MkdirTemp("", "class-loader-cache")
+		Expect(err).NotTo(HaveOccurred())
+		depsDir, err = os.MkdirTemp("", "class-loader-deps")
+		Expect(err).NotTo(HaveOccurred())
+		javaHome, err = os.MkdirTemp("", "class-loader-java")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.MkdirAll(filepath.Join(depsDir, "0"), 0755)).To(Succeed())
+		os.Setenv("JAVA_HOME", javaHome)
+
+		logs = new(bytes.Buffer)
+		ctx = newMariaDBContext(buildDir, cacheDir, depsDir)
+		ctx.Log = libbuildpack.NewLogger(logs)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(buildDir)
+		os.RemoveAll(cacheDir)
+		os.RemoveAll(depsDir)
+		os.RemoveAll(javaHome)
+		os.Unsetenv("JAVA_HOME")
+	})
+
+	Context("on Java 17", func() {
+		BeforeEach(func() {
+			setJavaVersion("17.0.13")
+		})
+
+		It("puts the JARs of extension directories on the boot class path", func() {
+			extDir := filepath.Join(depsDir, "0", "luna_security_provider", "ext")
+			Expect(os.MkdirAll(extDir, 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(extDir, "LunaProvider.jar"), []byte("jar"), 0644)).To(Succeed())
+			writeOpts("32_luna_security_provider",
+				"-Djava.ext.dirs=$DEPS_DIR/0/luna_security_provider/ext:$JAVA_HOME/jre/lib/ext:$JAVA_HOME/lib/ext")
+
+			Expect(frameworks.CheckClassLoaderOptions(ctx)).To(Succeed())
+
+			Expect(readOpts("32_luna_security_provider")).To(Equal(
+				"-Xbootclasspath/a:$DEPS_DIR/0/luna_security_provider/ext/LunaProvider.jar"))
+			Expect(logs.String()).To(ContainSubstring("Replacing -Djava.ext.dirs=$DEPS_DIR/0/luna_security_provider/ext"))
+			Expect(logs.String()).To(ContainSubstring("Java 17 has no extension class loader"))
+		})
+
+		It("keeps the other options of the file", func() {
+			writeOpts("17_container_security_provider",
+				"-Djava.ext.dirs=$DEPS_DIR/0/container_security_provider:$JAVA_HOME/lib/ext "+
+					"-Djava.security.properties=$DEPS_DIR/0/container_security_provider/java.security")
+
+			Expect(frameworks.CheckClassLoaderOptions(ctx)).To(Succeed())
+
+			Expect(readOpts("17_container_security_provider")).To(Equal(
+				"-Djava.security.properties=$DEPS_DIR/0/container_security_provider/java.security"))
+			Expect(logs.String()).To(ContainSubstring("no JARs of the extension directories are added"))
+		})
+
+		It("names extension directories outside the droplet", func() {
+			writeOpts("99_user_java_opts", "-Djava.ext.dirs=/opt/ext")
+
+			Expect(frameworks.CheckClassLoaderOptions(ctx)).To(Succeed())
+
+			Expect(readOpts("99_user_java_opts")).To(BeEmpty())
+			Expect(logs.String()).To(ContainSubstring("The JARs of /opt/ext cannot be listed at staging"))
+		})
+
+		DescribeTable("removes the options Java 9 and later reject",
+			func(opt, alternative string) {
+				writeOpts("99_user_java_opts", "-Xmx512M "+opt+" -Dfoo=bar")
+
+				Expect(frameworks.CheckClassLoaderOptions(ctx)).To(Succeed())
+
+				Expect(readOpts("99_user_java_opts")).To(Equal("-Xmx512M -Dfoo=bar"))
+				Expect(logs.String()).To(ContainSubstring("Removing " + opt + " from 99_user_java_opts"))
+				Expect(logs.String()).To(ContainSubstring(alternative))
+			},
+			Entry("endorsed directories", "-Djava.endorsed.dirs=$HOME/endorsed", "--upgrade-module-path"),
+			Entry("prepending to the boot class path", "-Xbootclasspath/p:$HOME/patch.jar",
+				"--patch-module <module>=$HOME/patch.jar"),
+			Entry("replacing the boot class path", "-Xbootclasspath:$HOME/rt.jar", "Use -Xbootclasspath/a"),
+		)
+
+		It("keeps appending to the boot class path", func() {
+			opts := "-Xbootclasspath/a:$DEPS_DIR/0/protect_app_security_provider/ext/IngrianNAE-8.12.0.000.jar"
+			writeOpts("38_protect_app_security_provider", opts)
+
+			Expect(frameworks.CheckClassLoaderOptions(ctx)).To(Succeed())
+
+			Expect(readOpts("38_protect_app_security_provider")).To(Equal(opts))
+			Expect(logs.String()).NotTo(ContainSubstring("WARNING"))
+		})
+
+		It("keeps --patch-module", func() {
+			writeOpts("99_user_java_opts", "--patch-module java.base=$HOME/patch.jar")
+
+			Expect(frameworks.CheckClassLoaderOptions(ctx)).To(Succeed())
+
+			Expect(readOpts("99_user_java_opts")).To(Equal("--patch-module java.base=$HOME/patch.jar"))
+		})
+	})
+
+	Context("on Java 8", func() {
+		BeforeEach(func() {
+			setJavaVersion("1.8.0_422")
+		})
+
+		It("keeps extension directories", func() {
+			opts := "-Djava.ext.dirs=$DEPS_DIR/0/luna_security_provider/ext:$JAVA_HOME/jre/lib/ext:$JAVA_HOME/lib/ext"
+			writeOpts("32_luna_security_provider", opts)
+
+			Expect(frameworks.CheckClassLoaderOptions(ctx)).To(Succeed())
+
+			Expect(readOpts("32_luna_security_provider")).To(Equal(opts))
+		})
+
+		DescribeTable("prepends patched modules to the boot class path",
+			func(opts string) {
+				writeOpts("99_user_java_opts", opts+" -Dfoo=bar")
+
+				Expect(frameworks.CheckClassLoaderOptions(ctx)).To(Succeed())
+
+				Expect(readOpts("99_user_java_opts")).To(Equal("-Xbootclasspath/p:$HOME/patch.jar -Dfoo=bar"))
+				Expect(logs.String()).To(ContainSubstring("Java 8 has no module system"))
+			},
+			Entry("with the value as the next option", "--patch-module java.base=$HOME/patch.jar"),
+			Entry("with the value after =", "--patch-module=java.base=$HOME/patch.jar"),
+		)
+	})
+
+	It("does not check the options without JAVA_HOME", func() {
+		os.Unsetenv("JAVA_HOME")
+		writeOpts("99_user_java_opts", "-Djava.ext.dirs=/opt/ext")
+
+		Expect(frameworks.CheckClassLoaderOptions(ctx)).To(Succeed())
+
+		Expect(readOpts("99_user_java_opts")).To(Equal("-Djava.ext.dirs=/opt/ext"))
+	})
+})
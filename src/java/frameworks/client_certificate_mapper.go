@@ -3,13 +3,16 @@ package frameworks
 import (
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
 	"os"
 	"path/filepath"
 )
 
 // ClientCertificateMapperFramework implements mTLS client certificate mapper support
 // This framework provides automatic mapping of Cloud Foundry client certificates
-// for mutual TLS (mTLS) authentication in Java applications
+// for mutual TLS (mTLS) authentication in Java applications. For applications that run in Tomcat, its servlet filter
+// is installed as a Tomcat overlay, which the Tomcat container links into tomcat/lib at start-up; other applications,
+// e.g. Spring Boot applications, get it on their CLASSPATH.
 type ClientCertificateMapperFramework struct {
 	context *common.Context
 }
@@ -25,6 +28,9 @@ func (c *ClientCertificateMapperFramework) Detect() (string, error) {
 	// Check if explicitly disabled via configuration
 	config, err := c.loadConfig()
 	if err != nil {
+		if common.IsUnknownConfigKeysError(err) {
+			return "", err
+		}
 		c.context.Log.Warning("Failed to load client certificate mapper config: %s", err.Error())
 		return "", nil // Don't fail the build
 	}
@@ -37,7 +43,8 @@ func (c *ClientCertificateMapperFramework) Detect() (string, error) {
 	return "Client Certificate Mapper", nil
 }
 
-// Supply installs the client certificate mapper JAR
+// Supply installs the client certificate mapper JAR, into the lib directory of its Tomcat overlay for servlet
+// applications
 func (c *ClientCertificateMapperFramework) Supply() error {
 	c.context.Log.Debug("Installing Client Certificate Mapper")
 
@@ -47,9 +54,7 @@ func (c *ClientCertificateMapperFramework) Supply() error {
 		return fmt.Errorf("unable to determine Client Certificate Mapper version: %w", err)
 	}
 
-	// Install client certificate mapper JAR
-	mapperDir := filepath.Join(c.context.Stager.DepDir(), "client_certificate_mapper")
-	if err := c.context.Installer.InstallDependency(dep, mapperDir); err != nil {
+	if err := c.context.Installer.InstallDependency(dep, c.installDir()); err != nil {
 		return fmt.Errorf("failed to install Client Certificate Mapper: %w", err)
 	}
 
@@ -57,8 +62,14 @@ func (c *ClientCertificateMapperFramework) Supply() error {
 	return nil
 }
 
-// Finalize adds the client certificate mapper JAR to the application classpath
+// Finalize adds the client certificate mapper JAR to the application classpath. For servlet applications the
+// Tomcat container links the JAR into tomcat/lib instead, where Tomcat loads the servlet filter for every web
+// application.
 func (c *ClientCertificateMapperFramework) Finalize() error {
+	if c.isServletApplication() {
+		return nil
+	}
+
 	// Find the installed JAR
 	mapperDir := filepath.Join(c.context.Stager.DepDir(), "client_certificate_mapper")
 	jarPattern := filepath.Join(mapperDir, "client-certificate-mapper-*.jar")
@@ -69,8 +80,7 @@ func (c *ClientCertificateMapperFramework) Finalize() error {
 		return nil
 	}
 
-	depsIdx := c.context.Stager.DepsIdx()
-	runtimePath := fmt.Sprintf("$DEPS_DIR/%s/client_certificate_mapper/%s", depsIdx, filepath.Base(matches[0]))
+	runtimePath := c.context.Droplet().Dep("client_certificate_mapper", filepath.Base(matches[0]))
 
 	profileScript := fmt.Sprintf("export CLASSPATH=\"%s${CLASSPATH:+:$CLASSPATH}\"\n", runtimePath)
 
@@ -83,22 +93,37 @@ func (c *ClientCertificateMapperFramework) Finalize() error {
 	return nil
 }
 
+// isServletApplication returns true if the application runs in the Tomcat container: an exploded WAR or a WAR file
+// that is not a Spring Boot application, which embeds its own servlet container
+func (c *ClientCertificateMapperFramework) isServletApplication() bool {
+	buildDir := c.context.Stager.BuildDir()
+	if _, err := os.Stat(filepath.Join(buildDir, "BOOT-INF")); err == nil {
+		return false
+	}
+	if _, err := os.Stat(filepath.Join(buildDir, "WEB-INF")); err == nil {
+		return true
+	}
+	wars, err := filepath.Glob(filepath.Join(buildDir, "*.war"))
+	return err == nil && len(wars) > 0
+}
+
+// installDir returns the lib directory of the Tomcat overlay for servlet applications, and the framework's own
+// directory for all other applications
+func (c *ClientCertificateMapperFramework) installDir() string {
+	if c.isServletApplication() {
+		return filepath.Join(common.TomcatOverlayDir(c.context.Stager, "client_certificate_mapper"), "lib")
+	}
+	return filepath.Join(c.context.Stager.DepDir(), "client_certificate_mapper")
+}
+
 func (c *ClientCertificateMapperFramework) loadConfig() (*clientCertificateMapperConfig, error) {
 	// initialize default values
 	mapperConfig := clientCertificateMapperConfig{
 		Enabled: true,
 	}
-	config := os.Getenv("JBP_CONFIG_CLIENT_CERTIFICATE_MAPPER")
-	if config != "" {
-		yamlHandler := common.YamlHandler{}
-		err := yamlHandler.ValidateFields([]byte(config), &mapperConfig)
-		if err != nil {
-			c.context.Log.Warning("Unknown user config values: %s", err.Error())
-		}
-		// overlay JBP_CONFIG_CLIENT_CERTIFICATE_MAPPER over default values
-		if err = yamlHandler.Unmarshal([]byte(config), &mapperConfig); err != nil {
-			return nil, fmt.Errorf("failed to parse JBP_CONFIG_CLIENT_CERTIFICATE_MAPPER: %w", err)
-		}
+	// overlay buildpack defaults and JBP_CONFIG_CLIENT_CERTIFICATE_MAPPER over default values
+	if err := config.Load(c.context.Log, "client_certificate_mapper", &mapperConfig); err != nil {
+		return nil, err
 	}
 	return &mapperConfig, nil
 }
@@ -6,6 +6,9 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/golang/mock/gomock"
+
+	"github.com/cloudfoundry/java-buildpack/src/internal/mocks"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
 	"github.com/cloudfoundry/java-buildpack/src/java/frameworks"
 	"github.com/cloudfoundry/libbuildpack"
@@ -115,6 +118,18 @@ var _ = Describe("Client Certificate Mapper", func() {
 				})
 			})
 
+			Context("with a Spring Boot application, which embeds its servlet container", func() {
+				BeforeEach(func() {
+					Expect(os.MkdirAll(filepath.Join(buildDir, "BOOT-INF"), 0755)).To(Succeed())
+				})
+
+				It("returns 'Client Certificate Mapper'", func() {
+					name, err := fw.Detect()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(name).To(Equal("Client Certificate Mapper"))
+				})
+			})
+
 			Context("with an unrelated key in config", func() {
 				BeforeEach(func() {
 					os.Setenv("JBP_CONFIG_CLIENT_CERTIFICATE_MAPPER", "some_other_key: value")
@@ -128,6 +143,49 @@ var _ = Describe("Client Certificate Mapper", func() {
 			})
 		})
 
+		Describe("Supply", func() {
+			var (
+				manifest  *mocks.MockManifest
+				installer *mocks.MockInstaller
+				dep       libbuildpack.Dependency
+			)
+
+			BeforeEach(func() {
+				ctrl := gomock.NewController(GinkgoT())
+				manifest = mocks.NewMockManifest(ctrl)
+				installer = mocks.NewMockInstaller(ctrl)
+				ctx := newCCMContext(buildDir, cacheDir, depsDir)
+				ctx.Manifest = manifest
+				ctx.Installer = installer
+				fw = frameworks.NewClientCertificateMapperFramework(ctx)
+
+				dep = libbuildpack.Dependency{Name: "client-certificate-mapper", Version: "2.0.1"}
+				manifest.EXPECT().DefaultVersion("client-certificate-mapper").Return(dep, nil)
+			})
+
+			It("installs the JAR into the lib directory of its Tomcat overlay for a servlet application", func() {
+				Expect(os.MkdirAll(filepath.Join(buildDir, "WEB-INF"), 0755)).To(Succeed())
+				installer.EXPECT().InstallDependency(dep, filepath.Join(depsDir, "0", "tomcat_overlays", "client_certificate_mapper", "lib")).Return(nil)
+
+				Expect(fw.Supply()).To(Succeed())
+			})
+
+			It("installs the JAR into a WAR file's Tomcat overlay", func() {
+				Expect(os.WriteFile(filepath.Join(buildDir, "app.war"), []byte("war"), 0644)).To(Succeed())
+				installer.EXPECT().InstallDependency(dep, filepath.Join(depsDir, "0", "tomcat_overlays", "client_certificate_mapper", "lib")).Return(nil)
+
+				Expect(fw.Supply()).To(Succeed())
+			})
+
+			It("installs the JAR into its own directory for a Spring Boot application", func() {
+				Expect(os.MkdirAll(filepath.Join(buildDir, "WEB-INF"), 0755)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(buildDir, "BOOT-INF"), 0755)).To(Succeed())
+				installer.EXPECT().InstallDependency(dep, filepath.Join(depsDir, "0", "client_certificate_mapper")).Return(nil)
+
+				Expect(fw.Supply()).To(Succeed())
+			})
+		})
+
 		Describe("Finalize", func() {
 			Context("when the JAR is present in the dep dir", func() {
 				BeforeEach(func() {
@@ -172,18 +230,17 @@ var _ = Describe("Client Certificate Mapper", func() {
 				})
 			})
 
-			Context("when multiple JAR versions exist", func() {
+			Context("with a servlet application", func() {
 				BeforeEach(func() {
+					Expect(os.MkdirAll(filepath.Join(buildDir, "WEB-INF"), 0755)).To(Succeed())
 					mapperDir := filepath.Join(depsDir, "0", "client_certificate_mapper")
 					Expect(os.MkdirAll(mapperDir, 0755)).To(Succeed())
 					Expect(os.WriteFile(filepath.Join(mapperDir, "client-certificate-mapper-2.0.1.jar"), []byte("jar"), 0644)).To(Succeed())
 				})
 
-				It("references the found JAR in the profile.d script", func() {
+				It("leaves the CLASSPATH to Tomcat", func() {
 					Expect(fw.Finalize()).To(Succeed())
-					content, err := os.ReadFile(filepath.Join(depsDir, "0", "profile.d", "client_certificate_mapper.sh"))
-					Expect(err).NotTo(HaveOccurred())
-					Expect(string(content)).To(ContainSubstring("client-certificate-mapper-2.0.1.jar"))
+					Expect(filepath.Join(depsDir, "0", "profile.d", "client_certificate_mapper.sh")).NotTo(BeAnExistingFile())
 				})
 			})
 		})
@@ -36,45 +36,19 @@ func (c *ContainerCustomizerFramework) Detect() (string, error) {
 		bootInfErr == nil && bootInfStat.IsDir() {
 
 		// Verify Spring Boot by checking for spring-boot-*.jar in lib directories
-		if c.hasSpringBootJars(buildDir) {
+		if c.hasSpringBootJars() {
 			c.context.Log.Debug("Detected Spring Boot WAR application for Container Customizer")
 			return "Container Customizer", nil
 		}
 	}
 
+	c.context.Log.Debug("Container Customizer: not a Spring Boot WAR application with WEB-INF, BOOT-INF and spring-boot-*.jar")
 	return "", nil
 }
 
 // hasSpringBootJars checks if Spring Boot JARs exist in lib directories
-func (c *ContainerCustomizerFramework) hasSpringBootJars(buildDir string) bool {
-	libDirs := []string{
-		filepath.Join(buildDir, "WEB-INF", "lib"),
-		filepath.Join(buildDir, "BOOT-INF", "lib"),
-	}
-
-	for _, libDir := range libDirs {
-		if _, err := os.Stat(libDir); err != nil {
-			continue
-		}
-
-		entries, err := os.ReadDir(libDir)
-		if err != nil {
-			continue
-		}
-
-		for _, entry := range entries {
-			if entry.IsDir() {
-				continue
-			}
-			name := entry.Name()
-			// Look for spring-boot-*.jar files
-			if filepath.Ext(name) == ".jar" && contains(name, "spring-boot-") {
-				return true
-			}
-		}
-	}
-
-	return false
+func (c *ContainerCustomizerFramework) hasSpringBootJars() bool {
+	return c.context.Classpath().Contains("*spring-boot-*.jar")
 }
 
 // Supply installs the Container Customizer library
@@ -111,18 +85,17 @@ func (c *ContainerCustomizerFramework) Finalize() error {
 	}
 
 	// Get buildpack index for multi-buildpack support
-	depsIdx := c.context.Stager.DepsIdx()
 
 	// Convert staging path to runtime path for CLASSPATH
 	// Staging: /tmp/staging/deps/<idx>/container_customizer/container-customizer-2.0.0.jar
 	// Runtime: $DEPS_DIR/<idx>/container_customizer/container-customizer-2.0.0.jar
 	relPath := filepath.Base(matches[0])
-	runtimePath := fmt.Sprintf("$DEPS_DIR/%s/container_customizer/%s", depsIdx, relPath)
+	runtimePath := c.context.Droplet().Dep("container_customizer", relPath)
 
 	// Write profile.d script to add Container Customizer JAR to classpath
 	// This ensures it's available to the embedded Tomcat at startup
 	profileScript := fmt.Sprintf(`# Container Customizer Framework
-export CLASSPATH="%s:${CLASSPATH:-}"
+export CLASSPATH="%s${CLASSPATH:+:$CLASSPATH}"
 `, runtimePath)
 
 	if err := c.context.Stager.WriteProfileD("container_customizer.sh", profileScript); err != nil {
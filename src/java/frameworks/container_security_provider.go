@@ -3,11 +3,19 @@ package frameworks
 import (
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
 	"os"
 	"path/filepath"
 	"strings"
 )
 
+// Properties that configure the KeyManager and TrustManager of CloudFoundryContainerProvider
+const (
+	keyManagerEnabledProperty   = "org.cloudfoundry.security.keymanager.enabled"
+	trustManagerEnabledProperty = "org.cloudfoundry.security.trustmanager.enabled"
+)
+
 // ContainerSecurityProviderFramework implements container-based security provider support
 // This framework provides CloudFoundryContainerProvider for Java security integration
 type ContainerSecurityProviderFramework struct {
@@ -70,12 +78,11 @@ func (c *ContainerSecurityProviderFramework) Finalize() error {
 	}
 
 	// Get buildpack index for multi-buildpack support
-	depsIdx := c.context.Stager.DepsIdx()
 
 	// Build JAVA_OPTS with runtime paths using $DEPS_DIR
 	var javaOpts string
 	if javaVersion >= 9 {
-		runtimeJarPath := fmt.Sprintf("$DEPS_DIR/%s/container_security_provider/%s", depsIdx, jarFilename)
+		runtimeJarPath := c.context.Droplet().Dep("container_security_provider", jarFilename)
 
 		profileScript := fmt.Sprintf("export CONTAINER_SECURITY_PROVIDER=\"%s\"\n", runtimeJarPath)
 
@@ -84,39 +91,47 @@ func (c *ContainerSecurityProviderFramework) Finalize() error {
 		}
 	} else {
 		// Java 8: Use extension directory
-		runtimeProviderDir := fmt.Sprintf("$DEPS_DIR/%s/container_security_provider", depsIdx)
+		runtimeProviderDir := c.context.Droplet().Dep("container_security_provider")
 		javaOpts = fmt.Sprintf("-Djava.ext.dirs=%s:$JAVA_HOME/jre/lib/ext:$JAVA_HOME/lib/ext", runtimeProviderDir)
 	}
 
 	// Add security provider to java.security.properties
 	// Insert at position 1 (after default providers)
-	runtimeSecurityFile := fmt.Sprintf("$DEPS_DIR/%s/container_security_provider/java.security", depsIdx)
+	runtimeSecurityFile := c.context.Droplet().Dep("container_security_provider", "java.security")
 	securityProvider := fmt.Sprintf("-Djava.security.properties=%s", runtimeSecurityFile)
 	javaOpts += " " + securityProvider
 
-	// Write security properties file
-	if err := c.writeSecurityProperties(); err != nil {
-		return fmt.Errorf("failed to write security properties: %w", err)
-	}
-
 	config, err := c.loadConfig()
 	if err != nil {
+		if common.IsUnknownConfigKeysError(err) {
+			return err
+		}
 		c.context.Log.Warning("Failed to load container security provider config: %s", err.Error())
+		config = &containerSecurityProviderConfig{}
+	}
+	if err := config.validate(); err != nil {
+		return err
 	}
+
+	// Write security properties file
+	if err := c.writeSecurityProperties(config); err != nil {
+		return fmt.Errorf("failed to write security properties: %w", err)
+	}
+
 	// Add key manager and trust manager configuration if specified
 	keyManagerEnabled := config.getKeyManagerEnabled()
 	if keyManagerEnabled != "" {
-		javaOpts += fmt.Sprintf(" -Dorg.cloudfoundry.security.keymanager.enabled=%s", keyManagerEnabled)
+		javaOpts += fmt.Sprintf(" -D%s=%s", keyManagerEnabledProperty, keyManagerEnabled)
 	}
 
 	trustManagerEnabled := config.getTrustManagerEnabled()
 	if trustManagerEnabled != "" {
-		javaOpts += fmt.Sprintf(" -Dorg.cloudfoundry.security.trustmanager.enabled=%s", trustManagerEnabled)
+		javaOpts += fmt.Sprintf(" -D%s=%s", trustManagerEnabledProperty, trustManagerEnabled)
 	}
 
 	// Write JAVA_OPTS to .opts file with priority 17 (Ruby buildpack line 51)
 	// This ensures Container Security Provider runs BEFORE JRebel (priority 31)
-	if err := writeJavaOptsFile(c.context, 17, "container_security", javaOpts); err != nil {
+	if err := writeJavaOptsFile(c.context, javaopts.ContainerSecurityProvider, "container_security", javaOpts); err != nil {
 		return fmt.Errorf("failed to write java_opts file: %w", err)
 	}
 
@@ -125,8 +140,9 @@ func (c *ContainerSecurityProviderFramework) Finalize() error {
 }
 
 // writeSecurityProperties writes the java.security properties file with CloudFoundryContainerProvider
-// It reads existing security providers from the JRE and inserts CloudFoundryContainerProvider at position 1
-func (c *ContainerSecurityProviderFramework) writeSecurityProperties() error {
+// It reads existing security providers from the JRE and inserts CloudFoundryContainerProvider at position 1,
+// followed by the key manager and trust manager properties that are configured
+func (c *ContainerSecurityProviderFramework) writeSecurityProperties(config *containerSecurityProviderConfig) error {
 	providerDir := filepath.Join(c.context.Stager.DepDir(), "container_security_provider")
 	securityFile := filepath.Join(providerDir, "java.security")
 
@@ -154,6 +170,13 @@ func (c *ContainerSecurityProviderFramework) writeSecurityProperties() error {
 	content += "networkaddress.cache.ttl=0\n"
 	content += "networkaddress.cache.negative.ttl=0\n"
 
+	if properties := config.securityProperties(); len(properties) > 0 {
+		content += "\n# Container Security Provider key store and trust store\n"
+		for _, property := range properties {
+			content += property + "\n"
+		}
+	}
+
 	if err := os.WriteFile(securityFile, []byte(content), 0644); err != nil {
 		return fmt.Errorf("failed to write security properties file: %w", err)
 	}
@@ -228,17 +251,9 @@ func (c *ContainerSecurityProviderFramework) loadConfig() (*containerSecurityPro
 		KeyManagerEnabled:   "",
 		TrustManagerEnabled: "",
 	}
-	config := os.Getenv("JBP_CONFIG_CONTAINER_SECURITY_PROVIDER")
-	if config != "" {
-		yamlHandler := common.YamlHandler{}
-		err := yamlHandler.ValidateFields([]byte(config), &secConfig)
-		if err != nil {
-			c.context.Log.Warning("Unknown user config values: %s", err.Error())
-		}
-		// overlay JBP_CONFIG_CONTAINER_SECURITY_PROVIDER over default values
-		if err = yamlHandler.Unmarshal([]byte(config), &secConfig); err != nil {
-			return nil, fmt.Errorf("failed to parse JBP_CONFIG_CONTAINER_SECURITY_PROVIDER: %w", err)
-		}
+	// overlay buildpack defaults and JBP_CONFIG_CONTAINER_SECURITY_PROVIDER over default values
+	if err := config.Load(c.context.Log, "container_security_provider", &secConfig); err != nil {
+		return nil, err
 	}
 	return &secConfig, nil
 }
@@ -253,6 +268,39 @@ func (c *containerSecurityProviderConfig) getTrustManagerEnabled() string {
 	return c.TrustManagerEnabled
 }
 
+// validate returns an error if key_manager_enabled or trust_manager_enabled is neither true nor false
+func (c *containerSecurityProviderConfig) validate() error {
+	for _, option := range []struct{ key, value string }{
+		{"key_manager_enabled", c.KeyManagerEnabled},
+		{"trust_manager_enabled", c.TrustManagerEnabled},
+	} {
+		if option.value != "" && option.value != "true" && option.value != "false" {
+			return fmt.Errorf("JBP_CONFIG_CONTAINER_SECURITY_PROVIDER: %s must be true or false, not %q", option.key, option.value)
+		}
+	}
+	return nil
+}
+
+// securityProperties returns the java.security properties of the configured options, so that the provider finds
+// them as security properties
+func (c *containerSecurityProviderConfig) securityProperties() []string {
+	var properties []string
+	for _, property := range []struct{ name, value string }{
+		{keyManagerEnabledProperty, c.KeyManagerEnabled},
+		{trustManagerEnabledProperty, c.TrustManagerEnabled},
+	} {
+		if property.value != "" {
+			properties = append(properties, property.name+"="+escapeProperty(property.value))
+		}
+	}
+	return properties
+}
+
+// escapeProperty escapes value for a Java properties file, such as java.security
+func escapeProperty(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`).Replace(value)
+}
+
 type containerSecurityProviderConfig struct {
 	KeyManagerEnabled   string `yaml:"key_manager_enabled"`
 	TrustManagerEnabled string `yaml:"trust_manager_enabled"`
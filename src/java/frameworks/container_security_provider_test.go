@@ -166,6 +166,46 @@ var _ = Describe("Container Security Provider", func() {
 				})
 			})
 
+			Context("with an instance identity at staging", func() {
+				BeforeEach(func() {
+					javaHome, err := os.MkdirTemp("", "java-home")
+					Expect(err).NotTo(HaveOccurred())
+					writeJavaReleaseFile(javaHome, "17.0.13")
+					os.Setenv("JAVA_HOME", javaHome)
+
+					providerDir := filepath.Join(depsDir, "0", "container_security_provider")
+					Expect(os.MkdirAll(providerDir, 0755)).To(Succeed())
+					Expect(os.WriteFile(filepath.Join(providerDir, "container-security-provider-1.20.0-RELEASE.jar"), []byte("fake jar"), 0644)).To(Succeed())
+
+					identityDir := filepath.Join(buildDir, "instance-identity")
+					Expect(os.MkdirAll(identityDir, 0755)).To(Succeed())
+					Expect(os.WriteFile(filepath.Join(identityDir, "instance.crt"), []byte("STAGING CERTIFICATE"), 0644)).To(Succeed())
+					Expect(os.WriteFile(filepath.Join(identityDir, "instance.key"), []byte("STAGING KEY"), 0600)).To(Succeed())
+					os.Setenv("CF_INSTANCE_CERT", filepath.Join(identityDir, "instance.crt"))
+					os.Setenv("CF_INSTANCE_KEY", filepath.Join(identityDir, "instance.key"))
+					DeferCleanup(os.Unsetenv, "CF_INSTANCE_CERT")
+					DeferCleanup(os.Unsetenv, "CF_INSTANCE_KEY")
+				})
+
+				// The provider's KeyManager reads $CF_INSTANCE_CERT and $CF_INSTANCE_KEY when the application starts
+				// and watches them for the certificates Diego rotates, so nothing of the identity may be staged
+				It("leaves the identity for the provider to read and reload at runtime", func() {
+					Expect(fw.Finalize()).To(Succeed())
+
+					Expect(filepath.Walk(filepath.Join(depsDir, "0"), func(path string, info os.FileInfo, err error) error {
+						if err != nil || info.IsDir() {
+							return err
+						}
+						content, err := os.ReadFile(path)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(string(content)).NotTo(ContainSubstring("STAGING"), path)
+						Expect(string(content)).NotTo(ContainSubstring("instance-identity"), path)
+						Expect(string(content)).NotTo(ContainSubstring("keymanager.enabled"), path)
+						return nil
+					})).To(Succeed())
+				})
+			})
+
 			Context("when the JAR is present (Java 8)", func() {
 				BeforeEach(func() {
 					javaHome, err := os.MkdirTemp("", "java-home")
@@ -261,6 +301,49 @@ var _ = Describe("Container Security Provider", func() {
 				})
 			})
 
+			Context("key store and trust store configuration", func() {
+				var securityFile string
+
+				BeforeEach(func() {
+					javaHome, err := os.MkdirTemp("", "java-home")
+					Expect(err).NotTo(HaveOccurred())
+					writeJavaReleaseFile(javaHome, "17.0.13")
+					os.Setenv("JAVA_HOME", javaHome)
+
+					providerDir := filepath.Join(depsDir, "0", "container_security_provider")
+					Expect(os.MkdirAll(providerDir, 0755)).To(Succeed())
+					Expect(os.WriteFile(
+						filepath.Join(providerDir, "container-security-provider-1.20.0-RELEASE.jar"),
+						[]byte("fake jar"),
+						0644,
+					)).To(Succeed())
+					securityFile = filepath.Join(providerDir, "java.security")
+				})
+
+				It("writes the enabled flags to java.security", func() {
+					os.Setenv("JBP_CONFIG_CONTAINER_SECURITY_PROVIDER", "{key_manager_enabled: false, trust_manager_enabled: true}")
+					Expect(fw.Finalize()).To(Succeed())
+
+					content, err := os.ReadFile(securityFile)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(content)).To(ContainSubstring("org.cloudfoundry.security.keymanager.enabled=false\n" +
+						"org.cloudfoundry.security.trustmanager.enabled=true\n"))
+				})
+
+				It("writes no enabled flags when none are configured", func() {
+					Expect(fw.Finalize()).To(Succeed())
+
+					content, err := os.ReadFile(securityFile)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(content)).NotTo(ContainSubstring("manager.enabled"))
+				})
+
+				It("rejects an enabled flag that is neither true nor false", func() {
+					os.Setenv("JBP_CONFIG_CONTAINER_SECURITY_PROVIDER", "{key_manager_enabled: maybe}")
+					Expect(fw.Finalize()).To(MatchError(ContainSubstring("key_manager_enabled must be true or false")))
+				})
+			})
+
 			Context("when JAVA_HOME points to a JDK with existing security providers", func() {
 				BeforeEach(func() {
 					javaHome, err := os.MkdirTemp("", "java-home")
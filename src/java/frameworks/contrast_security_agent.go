@@ -3,6 +3,7 @@ package frameworks
 import (
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
 	"os"
 	"path/filepath"
 )
@@ -35,6 +36,7 @@ func (c *ContrastSecurityAgentFramework) Detect() (string, error) {
 		return "contrast-security", nil
 	}
 
+	c.context.Log.Debug("Contrast Security: no contrast-security service bound")
 	return "", nil
 }
 
@@ -113,8 +115,7 @@ func (c *ContrastSecurityAgentFramework) Finalize() error {
 	if c.credentials == nil {
 		vcapServices, err := GetVCAPServices()
 		if err != nil {
-			c.context.Log.Warning("Failed to parse VCAP_SERVICES: %s", err)
-			return nil
+			return fmt.Errorf("failed to parse VCAP_SERVICES: %w", err)
 		}
 
 		service := c.findContrastService(vcapServices)
@@ -128,32 +129,30 @@ func (c *ContrastSecurityAgentFramework) Finalize() error {
 		configPath := filepath.Join(filepath.Dir(c.agentJarPath), "contrast.config")
 		c.configPath = configPath
 		if err := c.writeConfiguration(configPath); err != nil {
-			c.context.Log.Warning("Failed to write Contrast Security configuration: %s", err)
-			return nil
+			return fmt.Errorf("failed to write Contrast Security configuration: %w", err)
 		}
 	}
 
 	// Get buildpack index for multi-buildpack support
-	depsIdx := c.context.Stager.DepsIdx()
 
 	// Convert staging paths to runtime paths using $DEPS_DIR
 	agentRelPath, err := filepath.Rel(c.context.Stager.DepDir(), c.agentJarPath)
 	if err != nil {
 		return fmt.Errorf("failed to compute relative path for agent jar: %w", err)
 	}
-	runtimeAgentPath := filepath.Join(fmt.Sprintf("$DEPS_DIR/%s", depsIdx), agentRelPath)
+	runtimeAgentPath := c.context.Droplet().Dep(agentRelPath)
 
 	configRelPath, err := filepath.Rel(c.context.Stager.DepDir(), c.configPath)
 	if err != nil {
 		return fmt.Errorf("failed to compute relative path for config: %w", err)
 	}
-	runtimeConfigPath := filepath.Join(fmt.Sprintf("$DEPS_DIR/%s", depsIdx), configRelPath)
+	runtimeConfigPath := c.context.Droplet().Dep(configRelPath)
 
 	// Build JAVA_OPTS with javaagent and system properties using runtime paths
 	javaOpts := fmt.Sprintf("-javaagent:%s=%s -Dcontrast.dir=$TMPDIR", runtimeAgentPath, runtimeConfigPath)
 
 	// Write JAVA_OPTS to .opts file with priority 18 (Ruby buildpack line 52)
-	if err := writeJavaOptsFile(c.context, 18, "contrast_security", javaOpts); err != nil {
+	if err := writeJavaOptsFile(c.context, javaopts.ContrastSecurity, "contrast_security", javaOpts); err != nil {
 		return fmt.Errorf("failed to write java_opts file: %w", err)
 	}
 
@@ -179,12 +178,10 @@ func (c *ContrastSecurityAgentFramework) findContrastService(vcapServices VCAPSe
 	}
 
 	// Check all services for tags
-	for _, serviceList := range vcapServices {
-		for _, svc := range serviceList {
-			for _, tag := range svc.Tags {
-				if common.ContainsIgnoreCase(tag, "contrast-security") || common.ContainsIgnoreCase(tag, "contrast") {
-					return &svc
-				}
+	for _, svc := range vcapServices.Sorted() {
+		for _, tag := range svc.Tags {
+			if common.ContainsIgnoreCase(tag, "contrast-security") || common.ContainsIgnoreCase(tag, "contrast") {
+				return &svc
 			}
 		}
 	}
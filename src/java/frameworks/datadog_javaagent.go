@@ -20,6 +20,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/appname"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -118,14 +120,13 @@ func (d *DatadogJavaagentFramework) Finalize() error {
 	d.context.Log.BeginStep("Configuring Datadog Java agent")
 
 	// Get buildpack index for multi-buildpack support
-	depsIdx := d.context.Stager.DepsIdx()
 
 	// Convert staging path to runtime path
 	relPath, err := filepath.Rel(d.context.Stager.DepDir(), d.jarPath)
 	if err != nil {
 		return fmt.Errorf("failed to determine relative path for Datadog agent: %w", err)
 	}
-	runtimeJarPath := filepath.Join(fmt.Sprintf("$DEPS_DIR/%s", depsIdx), relPath)
+	runtimeJarPath := d.context.Droplet().Dep(relPath)
 
 	// Build all JAVA_OPTS options
 	var opts []string
@@ -134,7 +135,10 @@ func (d *DatadogJavaagentFramework) Finalize() error {
 	// Set dd.service if DD_SERVICE not set
 	if os.Getenv("DD_SERVICE") == "" {
 		// Get application name from VCAP_APPLICATION
-		appName := GetApplicationName(false)
+		appName, err := appname.Name(d.context.Log, appname.App)
+		if err != nil {
+			return err
+		}
 		if appName != "" {
 			opts = append(opts, fmt.Sprintf("-Ddd.service=\"%s\"", appName))
 		}
@@ -146,9 +150,22 @@ func (d *DatadogJavaagentFramework) Finalize() error {
 		opts = append(opts, fmt.Sprintf("-Ddd.version=%s", appVersion))
 	}
 
+	// Set dd.tags from the resource tags, followed by DD_TAGS so that these take precedence
+	tags, err := resourceTags(d.context)
+	if err != nil {
+		return err
+	}
+	if len(tags) > 0 {
+		ddTags := joinResourceTags(tags, ":", ",", false)
+		if userTags := os.Getenv("DD_TAGS"); userTags != "" {
+			ddTags += "," + userTags
+		}
+		opts = append(opts, fmt.Sprintf("-Ddd.tags=%s", escapeValue(ddTags)))
+	}
+
 	// Write all options to .opts file
 	javaOpts := strings.Join(opts, " ")
-	if err := writeJavaOptsFile(d.context, 19, "datadog_javaagent", javaOpts); err != nil {
+	if err := writeJavaOptsFile(d.context, javaopts.DatadogJavaagent, "datadog_javaagent", javaOpts); err != nil {
 		return fmt.Errorf("failed to write JAVA_OPTS for Datadog: %w", err)
 	}
 
@@ -363,6 +363,28 @@ var _ = Describe("Datadog JavaAgent", func() {
 			})
 		})
 
+		Context("with resource tags enabled", func() {
+			BeforeEach(func() {
+				installDatadogAgent(depsDir, "1.28.0", false)
+				os.Setenv("JBP_CONFIG_RESOURCE_TAGS", "{enabled: true, foundation: east, cloud_provider: gcp, region: us-east1}")
+				os.Setenv("VCAP_APPLICATION", `{"application_name":"my-cf-app","organization_name":"shop","space_name":"prod"}`)
+				os.Setenv("DD_TAGS", "team:payments")
+			})
+
+			AfterEach(func() {
+				os.Unsetenv("JBP_CONFIG_RESOURCE_TAGS")
+				os.Unsetenv("DD_TAGS")
+			})
+
+			It("opts file contains -Ddd.tags with the resource tags followed by DD_TAGS", func() {
+				Expect(fw.Finalize()).To(Succeed())
+				content, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "19_datadog_javaagent.opts"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(ContainSubstring("-Ddd.tags=foundation:east,org:shop,space:prod,cloud_provider:gcp," +
+					"region:us-east1,team:payments"))
+			})
+		})
+
 		Context("when the agent JAR is not present", func() {
 			It("returns an error", func() {
 				err := fw.Finalize()
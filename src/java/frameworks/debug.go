@@ -6,6 +6,8 @@ import (
 	"strconv"
 
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
 )
 
 // DebugFramework implements Java remote debugging support
@@ -24,10 +26,14 @@ func (d *DebugFramework) Detect() (string, error) {
 	// Check if debug is enabled in configuration
 	config, err := d.loadConfig()
 	if err != nil {
+		if common.IsUnknownConfigKeysError(err) {
+			return "", err
+		}
 		d.context.Log.Warning("Failed to load debug config: %s", err.Error())
 		return "", nil // Don't fail the build
 	}
 	if !config.isEnabled() {
+		d.context.Log.Debug("Debug: disabled (default), enable it with JBP_CONFIG_DEBUG='{enabled: true}'")
 		return "", nil
 	}
 
@@ -38,8 +44,7 @@ func (d *DebugFramework) Detect() (string, error) {
 func (d *DebugFramework) Supply() error {
 	config, err := d.loadConfig()
 	if err != nil {
-		d.context.Log.Warning("Failed to load debug config: %s", err.Error())
-		return nil // Don't fail the build
+		return incomplete(fmt.Errorf("failed to load debug config: %w", err))
 	}
 	if !config.isEnabled() {
 		return nil
@@ -80,7 +85,7 @@ func (d *DebugFramework) Finalize() error {
 	debugOpts := fmt.Sprintf("-agentlib:jdwp=transport=dt_socket,server=y,address=%d,suspend=%s", port, suspendValue)
 
 	// Write JAVA_OPTS to .opts file with priority 20 (Ruby buildpack line 54)
-	if err := writeJavaOptsFile(d.context, 20, "debug", debugOpts); err != nil {
+	if err := writeJavaOptsFile(d.context, javaopts.Debug, "debug", debugOpts); err != nil {
 		return fmt.Errorf("failed to write java_opts file: %w", err)
 	}
 
@@ -132,17 +137,9 @@ func (d *DebugFramework) loadConfig() (*debugConfig, error) {
 		Port:    8000,
 		Suspend: false,
 	}
-	config := os.Getenv("JBP_CONFIG_DEBUG")
-	if config != "" {
-		yamlHandler := common.YamlHandler{}
-		err := yamlHandler.ValidateFields([]byte(config), &dbgConfig)
-		if err != nil {
-			d.context.Log.Warning("Unknown user config values: %s", err.Error())
-		}
-		// overlay JBP_CONFIG_DEBUG over default values
-		if err = yamlHandler.Unmarshal([]byte(config), &dbgConfig); err != nil {
-			return nil, fmt.Errorf("failed to parse JBP_CONFIG_DEBUG: %w", err)
-		}
+	// overlay buildpack defaults and JBP_CONFIG_DEBUG over default values
+	if err := config.Load(d.context.Log, "debug", &dbgConfig); err != nil {
+		return nil, err
 	}
 	return &dbgConfig, nil
 }
@@ -16,10 +16,10 @@
 package frameworks
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
-	"os"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/appname"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -99,18 +99,20 @@ func (e *ElasticApmAgentFramework) Finalize() error {
 	e.context.Log.BeginStep("Configuring Elastic APM agent")
 
 	// Get buildpack index for multi-buildpack support
-	depsIdx := e.context.Stager.DepsIdx()
 
 	// Convert staging paths to runtime paths
 	relJarPath, err := filepath.Rel(e.context.Stager.DepDir(), e.jarPath)
 	if err != nil {
 		return fmt.Errorf("failed to determine relative path for Elastic APM agent: %w", err)
 	}
-	runtimeJarPath := filepath.Join(fmt.Sprintf("$DEPS_DIR/%s", depsIdx), relJarPath)
-	runtimeHomeDir := fmt.Sprintf("$DEPS_DIR/%s/elastic_apm_agent", depsIdx)
+	runtimeJarPath := e.context.Droplet().Dep(relJarPath)
+	runtimeHomeDir := e.context.Droplet().Dep("elastic_apm_agent")
 
 	// Build configuration map
-	config := e.buildConfiguration()
+	config, err := e.buildConfiguration()
+	if err != nil {
+		return err
+	}
 
 	// Build all JAVA_OPTS options
 	var opts []string
@@ -129,7 +131,7 @@ func (e *ElasticApmAgentFramework) Finalize() error {
 
 	// Write all options to .opts file
 	javaOpts := strings.Join(opts, " ")
-	if err := writeJavaOptsFile(e.context, 19, "elastic_apm_agent", javaOpts); err != nil {
+	if err := writeJavaOptsFile(e.context, javaopts.ElasticAPM, "elastic_apm_agent", javaOpts); err != nil {
 		return fmt.Errorf("failed to write JAVA_OPTS for Elastic APM: %w", err)
 	}
 
@@ -153,17 +155,15 @@ func (e *ElasticApmAgentFramework) findElasticApmService() *VCAPService {
 		return service
 	}
 
-	for _, services := range vcapServices {
-		for _, service := range services {
-			for _, tag := range service.Tags {
-				if common.ContainsIgnoreCase(tag, "elastic-apm") || common.ContainsIgnoreCase(tag, "elastic") {
-					return &service
-				}
-			}
-			if common.ContainsIgnoreCase(service.Name, "elastic-apm") || common.ContainsIgnoreCase(service.Name, "elastic") {
+	for _, service := range vcapServices.Sorted() {
+		for _, tag := range service.Tags {
+			if common.ContainsIgnoreCase(tag, "elastic-apm") || common.ContainsIgnoreCase(tag, "elastic") {
 				return &service
 			}
 		}
+		if common.ContainsIgnoreCase(service.Name, "elastic-apm") || common.ContainsIgnoreCase(service.Name, "elastic") {
+			return &service
+		}
 	}
 
 	return nil
@@ -184,7 +184,7 @@ func (e *ElasticApmAgentFramework) hasRequiredCredentials(service *VCAPService)
 }
 
 // buildConfiguration builds the Elastic APM configuration map
-func (e *ElasticApmAgentFramework) buildConfiguration() map[string]string {
+func (e *ElasticApmAgentFramework) buildConfiguration() (map[string]string, error) {
 	config := make(map[string]string)
 
 	// Default configuration
@@ -201,7 +201,10 @@ func (e *ElasticApmAgentFramework) buildConfiguration() map[string]string {
 	}
 
 	// Add service name from application name
-	appName := e.getApplicationName()
+	appName, err := appname.Name(e.context.Log, appname.App)
+	if err != nil {
+		return nil, err
+	}
 	if appName != "" {
 		config["service_name"] = appName
 	}
@@ -213,7 +216,7 @@ func (e *ElasticApmAgentFramework) buildConfiguration() map[string]string {
 		}
 	}
 
-	return config
+	return config, nil
 }
 
 // formatSystemProperty formats a key-value pair as a -Delastic.apm.key=value system property
@@ -243,25 +246,6 @@ func shellEscape(s string) string {
 	return fmt.Sprintf("'%s'", escaped)
 }
 
-// getApplicationName returns the application name from VCAP_APPLICATION
-func (e *ElasticApmAgentFramework) getApplicationName() string {
-	vcapApp := os.Getenv("VCAP_APPLICATION")
-	if vcapApp == "" {
-		return ""
-	}
-
-	var appData map[string]interface{}
-	if err := json.Unmarshal([]byte(vcapApp), &appData); err != nil {
-		return ""
-	}
-
-	if name, ok := appData["application_name"].(string); ok {
-		return name
-	}
-
-	return ""
-}
-
 func (e *ElasticApmAgentFramework) constructJarPath(elasticDir string) error {
 	// Find the installed JAR
 	jarPattern := filepath.Join(elasticDir, "elastic-apm-agent*.jar")
@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/components"
 	"os"
 	"path/filepath"
+	"sort"
 )
 
 // Framework represents a cross-cutting concern (APM agents, security providers, etc.)
@@ -15,6 +17,50 @@ type DependencyIdentifierProvider interface {
 	DependencyIdentifier() string
 }
 
+// EndpointProvider optionally exposes the URLs of the backends an agent connects to at runtime,
+// which the supply phase probes when endpoint_check is enabled
+type EndpointProvider interface {
+	Endpoints() []string
+}
+
+// DeprecationProvider optionally marks a framework as deprecated. The supply phase warns when it detects a
+// deprecated framework and the finalize phase lists it in the release metadata.
+type DeprecationProvider interface {
+	Deprecation() Deprecation
+}
+
+// Deprecation is the deprecation metadata of a framework
+type Deprecation struct {
+	Deprecated bool
+	// Sunset is the date, as YYYY-MM-DD, after which releases of the buildpack may no longer include the framework
+	Sunset string
+	// Replacement names the framework or library that applications should migrate to
+	Replacement string
+}
+
+// DeprecatedComponent is a detected deprecated framework, as listed in the deprecated_components of the release
+// metadata
+type DeprecatedComponent struct {
+	// Name is the name the framework was detected as, e.g. Spring Auto-reconfiguration
+	Name string `yaml:"name"`
+	// Component is the name JBP_CONFIG_COMPONENTS lists the framework by, e.g. SpringAutoReconfiguration
+	Component   string `yaml:"component,omitempty"`
+	Sunset      string `yaml:"sunset,omitempty"`
+	Replacement string `yaml:"replacement,omitempty"`
+}
+
+// Warning returns the staging log warning about the deprecated framework
+func (d DeprecatedComponent) Warning() string {
+	warning := d.Name + " is deprecated"
+	if d.Sunset != "" {
+		warning += " and may be removed from the buildpack after " + d.Sunset
+	}
+	if d.Replacement != "" {
+		warning += ", migrate to " + d.Replacement
+	}
+	return warning
+}
+
 type Framework interface {
 	// Detect returns true if this framework should be included
 	// Returns the framework name and version if detected
@@ -30,6 +76,7 @@ type Framework interface {
 // Registry manages available frameworks
 type Registry struct {
 	frameworks []Framework
+	names      map[Framework]string
 	context    *common.Context
 }
 
@@ -37,88 +84,118 @@ type Registry struct {
 func NewRegistry(ctx *common.Context) *Registry {
 	return &Registry{
 		frameworks: []Framework{},
+		names:      map[Framework]string{},
 		context:    ctx,
 	}
 }
 
-// Register adds a framework to the registry
+// Register adds a framework to the registry. JBP_CONFIG_COMPONENTS cannot exclude it.
 func (r *Registry) Register(f Framework) {
 	r.frameworks = append(r.frameworks, f)
 }
 
+// RegisterAs adds a framework that JBP_CONFIG_COMPONENTS includes or excludes by the given name, the class name of
+// the framework in the Ruby buildpack, e.g. NewRelicAgent
+func (r *Registry) RegisterAs(name string, f Framework) {
+	r.Register(f)
+	r.names[f] = name
+}
+
 // RegisterStandardFrameworks registers all standard frameworks in the correct priority order.
 // This ensures Supply and Finalize phases use the same detection order.
 // IMPORTANT: The order matters! Frameworks are checked in registration order.
+// Frameworks are registered with their Ruby buildpack class names, which JBP_CONFIG_COMPONENTS lists; frameworks
+// the Ruby buildpack does not have are named after their type.
 func (r *Registry) RegisterStandardFrameworks() {
 	// APM Agents (Priority 1)
-	r.Register(NewNewRelicFramework(r.context))
-	r.Register(NewAppDynamicsFramework(r.context))
-	r.Register(NewDatadogJavaagentFramework(r.context))
-	r.Register(NewElasticApmAgentFramework(r.context))
+	r.RegisterAs("NewRelicAgent", NewNewRelicFramework(r.context))
+	r.RegisterAs("AppDynamicsAgent", NewAppDynamicsFramework(r.context))
+	r.RegisterAs("DatadogJavaagent", NewDatadogJavaagentFramework(r.context))
+	r.RegisterAs("ElasticApmAgent", NewElasticApmAgentFramework(r.context))
 
 	// Spring Service Bindings (Priority 1)
 	// Note: order matters, Java Cf Env should be registered before StringAutoReconfiguration
-	r.Register(NewJavaCfEnvFramework(r.context))
-	r.Register(NewSpringAutoReconfigurationFramework(r.context))
+	r.RegisterAs("JavaCfEnv", NewJavaCfEnvFramework(r.context))
+	r.RegisterAs("SpringAutoReconfiguration", NewSpringAutoReconfigurationFramework(r.context))
+	r.RegisterAs("ServiceMappings", NewServiceMappingsFramework(r.context))
+	r.RegisterAs("SpringApplicationJson", NewSpringApplicationJSONFramework(r.context))
 
 	// JDBC Drivers (Priority 1)
-	r.Register(NewPostgresqlJdbcFramework(r.context))
-	r.Register(NewMariaDBJDBCFramework(r.context))
+	r.RegisterAs("PostgresqlJDBC", NewPostgresqlJdbcFramework(r.context))
+	r.RegisterAs("MariaDbJDBC", NewMariaDBJDBCFramework(r.context))
+	r.RegisterAs("OracleJDBC", NewOracleJdbcFramework(r.context))
 
 	// mTLS Support (Priority 1)
-	r.Register(NewClientCertificateMapperFramework(r.context))
+	r.RegisterAs("ClientCertificateMapper", NewClientCertificateMapperFramework(r.context))
 
 	// Security Providers (Priority 1)
-	r.Register(NewContainerSecurityProviderFramework(r.context))
-	r.Register(NewLunaSecurityProviderFramework(r.context))
-	r.Register(NewProtectAppSecurityProviderFramework(r.context))
-	r.Register(NewSeekerSecurityProviderFramework(r.context))
+	r.RegisterAs("ContainerSecurityProvider", NewContainerSecurityProviderFramework(r.context))
+	r.RegisterAs("LunaSecurityProvider", NewLunaSecurityProviderFramework(r.context))
+	r.RegisterAs("ProtectAppSecurityProvider", NewProtectAppSecurityProviderFramework(r.context))
+	r.RegisterAs("SeekerSecurityProvider", NewSeekerSecurityProviderFramework(r.context))
+	r.RegisterAs("PlatformCertificates", NewPlatformCertificatesFramework(r.context))
 
 	// Container & Runtime Support (Priority 1)
-	r.Register(NewContainerCustomizerFramework(r.context))
-	r.Register(NewJavaMemoryAssistantFramework(r.context))
+	r.RegisterAs("ContainerCustomizer", NewContainerCustomizerFramework(r.context))
+	r.RegisterAs("JavaMemoryAssistant", NewJavaMemoryAssistantFramework(r.context))
 
 	// Metrics & Observability (Priority 1)
-	r.Register(NewMetricWriterFramework(r.context))
+	r.RegisterAs("MetricWriter", NewMetricWriterFramework(r.context))
 	// Register cf-metrics-exporter agent (agent mode)
-	r.Register(NewCfMetricsExporterFramework(r.context))
+	r.RegisterAs("CfMetricsExporter", NewCfMetricsExporterFramework(r.context))
+	r.RegisterAs("MetricsForwarder", NewMetricsForwarderFramework(r.context))
 
 	// Development Tools (Priority 1)
-	r.Register(NewDebugFramework(r.context))
-	r.Register(NewJmxFramework(r.context))
-	r.Register(NewJavaOptsFramework(r.context))
+	r.RegisterAs("Debug", NewDebugFramework(r.context))
+	r.RegisterAs("Jmx", NewJmxFramework(r.context))
+	r.RegisterAs("JavaOpts", NewJavaOptsFramework(r.context))
 
 	// APM Agents (Priority 2)
-	r.Register(NewAzureApplicationInsightsAgentFramework(r.context))
-	r.Register(NewCheckmarxIASTAgentFramework(r.context))
+	r.RegisterAs("AzureApplicationInsightsAgent", NewAzureApplicationInsightsAgentFramework(r.context))
+	r.RegisterAs("CheckmarxIastAgent", NewCheckmarxIASTAgentFramework(r.context))
 	// NOTE: Google Stackdriver Debugger has been removed - it's deprecated by Google
 	// and shares the same binary as Profiler. Use Profiler instead.
-	r.Register(NewGoogleStackdriverProfilerFramework(r.context))
-	r.Register(NewIntroscopeAgentFramework(r.context))
-	r.Register(NewOpenTelemetryJavaagentFramework(r.context))
-	r.Register(NewRiverbedAppInternalsAgentFramework(r.context))
-	r.Register(NewSkyWalkingAgentFramework(r.context))
-	r.Register(NewSplunkOtelJavaAgentFramework(r.context))
+	r.RegisterAs("GoogleStackdriverProfiler", NewGoogleStackdriverProfilerFramework(r.context))
+	r.RegisterAs("IntroscopeAgent", NewIntroscopeAgentFramework(r.context))
+	r.RegisterAs("OpenTelemetryJavaagent", NewOpenTelemetryJavaagentFramework(r.context))
+	r.RegisterAs("RiverbedAppinternalsAgent", NewRiverbedAppInternalsAgentFramework(r.context))
+	r.RegisterAs("SkyWalkingAgent", NewSkyWalkingAgentFramework(r.context))
+	r.RegisterAs("SplunkOtelJavaAgent", NewSplunkOtelJavaAgentFramework(r.context))
 
 	// Testing & Code Coverage (Priority 3)
-	r.Register(NewJacocoAgentFramework(r.context))
+	r.RegisterAs("JacocoAgent", NewJacocoAgentFramework(r.context))
 
 	// Code Instrumentation & Additional Development Tools (Priority 3)
-	r.Register(NewJRebelAgentFramework(r.context))
-	r.Register(NewContrastSecurityAgentFramework(r.context))
-	r.Register(NewAspectJWeaverAgentFramework(r.context))
-	r.Register(NewYourKitProfilerFramework(r.context))
-	r.Register(NewJProfilerProfilerFramework(r.context))
-	r.Register(NewSealightsAgentFramework(r.context))
+	r.RegisterAs("JrebelAgent", NewJRebelAgentFramework(r.context))
+	r.RegisterAs("ContrastSecurityAgent", NewContrastSecurityAgentFramework(r.context))
+	r.RegisterAs("AspectjWeaverAgent", NewAspectJWeaverAgentFramework(r.context))
+	r.RegisterAs("YourKitProfiler", NewYourKitProfilerFramework(r.context))
+	r.RegisterAs("JprofilerProfiler", NewJProfilerProfilerFramework(r.context))
+	r.RegisterAs("SealightsAgent", NewSealightsAgentFramework(r.context))
 }
 
 // DetectAll returns all frameworks that should be included
+// Frameworks that JBP_CONFIG_COMPONENTS excludes, or does not include if it includes any, are not detected
+// Detection errors are ignored, except for unknown configuration keys in strict config mode
 func (r *Registry) DetectAll() ([]Framework, []string, error) {
 	var matched []Framework
 	var names []string
 
+	listed, err := r.frameworkComponents()
+	if err != nil {
+		return nil, nil, err
+	}
+
 	for _, framework := range r.frameworks {
-		if name, err := framework.Detect(); err == nil && name != "" {
+		if component, ok := r.names[framework]; ok && !listed.Allows(component) {
+			r.context.Log.Debug("Framework %s is not detected: JBP_CONFIG_COMPONENTS does not allow it", component)
+			continue
+		}
+		name, err := framework.Detect()
+		if common.IsUnknownConfigKeysError(err) {
+			return nil, nil, err
+		}
+		if err == nil && name != "" {
 			matched = append(matched, framework)
 			names = append(names, name)
 		}
@@ -127,6 +204,67 @@ func (r *Registry) DetectAll() ([]Framework, []string, error) {
 	return matched, names, nil
 }
 
+// Candidate is a registered framework together with the name JBP_CONFIG_COMPONENTS lists it by
+type Candidate struct {
+	// Component is the name JBP_CONFIG_COMPONENTS lists the framework by, empty for frameworks it cannot exclude
+	Component string
+	Framework Framework
+	// Allowed is false if JBP_CONFIG_COMPONENTS excludes the framework, or does not include it if it includes any
+	Allowed bool
+}
+
+// Candidates returns the registered frameworks in detection order, whether JBP_CONFIG_COMPONENTS allows them or not
+func (r *Registry) Candidates() ([]Candidate, error) {
+	listed, err := r.frameworkComponents()
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]Candidate, 0, len(r.frameworks))
+	for _, framework := range r.frameworks {
+		component, ok := r.names[framework]
+		candidates = append(candidates, Candidate{Component: component, Framework: framework, Allowed: !ok || listed.Allows(component)})
+	}
+	return candidates, nil
+}
+
+// Deprecated returns the deprecated frameworks among the detected frameworks, which DetectAll named names
+func (r *Registry) Deprecated(detected []Framework, names []string) []DeprecatedComponent {
+	var deprecated []DeprecatedComponent
+	for i, framework := range detected {
+		provider, ok := framework.(DeprecationProvider)
+		if !ok {
+			continue
+		}
+		if deprecation := provider.Deprecation(); deprecation.Deprecated {
+			deprecated = append(deprecated, DeprecatedComponent{
+				Name:        names[i],
+				Component:   r.names[framework],
+				Sunset:      deprecation.Sunset,
+				Replacement: deprecation.Replacement,
+			})
+		}
+	}
+	return deprecated
+}
+
+// frameworkComponents returns the frameworks list of JBP_CONFIG_COMPONENTS
+func (r *Registry) frameworkComponents() (components.Selection, error) {
+	cfg, err := components.Load(r.context.Log)
+	if err != nil {
+		return components.Selection{}, err
+	}
+	listed := components.NewSelection("framework", cfg.Frameworks)
+
+	known := make([]string, 0, len(r.names))
+	for _, framework := range r.frameworks {
+		if name, ok := r.names[framework]; ok {
+			known = append(known, name)
+		}
+	}
+	return listed, listed.Validate(known)
+}
+
 // Type aliases for backward compatibility
 // All VCAP types and functions are now in common package
 type VCAPServices = common.VCAPServices
@@ -137,6 +275,29 @@ func GetVCAPServices() (common.VCAPServices, error) {
 	return common.GetVCAPServices()
 }
 
+// sortedLabels returns the service labels of a parsed VCAP_SERVICES, sorted, for lookups that pick the first
+// matching service; see common.VCAPServices.Sorted
+func sortedLabels[V any](services map[string]V) []string {
+	labels := make([]string, 0, len(services))
+	for label := range services {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// credentialString returns a string or numeric credential as a string
+func credentialString(credentials map[string]interface{}, key string) string {
+	switch v := credentials[key].(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	default:
+		return ""
+	}
+}
+
 // GetApplicationName returns the application name from VCAP_APPLICATION.
 // If includeSpace is true, returns "space_name:application_name" format,
 // falling back to just "application_name" if space is not available.
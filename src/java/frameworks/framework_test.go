@@ -289,6 +289,77 @@ var _ = Describe("Framework Registry", func() {
 			Expect(detected).To(HaveLen(2))
 			Expect(names).To(ContainElements("New Relic Agent", "AppDynamics Agent"))
 		})
+
+		It("lists the deprecated frameworks among the detected ones", func() {
+			registry.RegisterAs("NewRelicAgent", frameworks.NewNewRelicFramework(ctx))
+			registry.RegisterAs("RiverbedAppinternalsAgent", frameworks.NewRiverbedAppInternalsAgentFramework(ctx))
+			os.Setenv("VCAP_SERVICES", `{
+				"newrelic": [{"name": "newrelic-service", "label": "newrelic", "credentials": {"licenseKey": "test-key"}}],
+				"appinternals": [{"name": "appinternals", "label": "appinternals", "credentials": {}}]
+			}`)
+
+			detected, names, err := registry.DetectAll()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(names).To(HaveLen(2))
+
+			deprecated := registry.Deprecated(detected, names)
+			Expect(deprecated).To(Equal([]frameworks.DeprecatedComponent{{
+				Name:        "riverbed-appinternals-agent",
+				Component:   "RiverbedAppinternalsAgent",
+				Sunset:      "2027-06-30",
+				Replacement: "the OpenTelemetry Javaagent framework",
+			}}))
+			Expect(deprecated[0].Warning()).To(Equal("riverbed-appinternals-agent is deprecated and may be removed from " +
+				"the buildpack after 2027-06-30, migrate to the OpenTelemetry Javaagent framework"))
+		})
+
+		Context("with JBP_CONFIG_COMPONENTS", func() {
+			BeforeEach(func() {
+				registry.RegisterAs("NewRelicAgent", frameworks.NewNewRelicFramework(ctx))
+				registry.RegisterAs("AppDynamicsAgent", frameworks.NewAppDynamicsFramework(ctx))
+				os.Setenv("VCAP_SERVICES", `{
+					"newrelic": [{"name": "newrelic-service", "label": "newrelic", "credentials": {"licenseKey": "test-key"}}],
+					"appdynamics": [{"name": "appdynamics-service", "label": "appdynamics", "credentials": {"account-access-key": "test-key"}}]
+				}`)
+			})
+
+			AfterEach(func() {
+				os.Unsetenv("JBP_CONFIG_COMPONENTS")
+			})
+
+			It("does not detect excluded frameworks", func() {
+				os.Setenv("JBP_CONFIG_COMPONENTS", `{frameworks: ["-JavaBuildpack::Framework::AppDynamicsAgent"]}`)
+
+				_, names, err := registry.DetectAll()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(names).To(Equal([]string{"New Relic Agent"}))
+			})
+
+			It("detects only the included frameworks if any are listed", func() {
+				os.Setenv("JBP_CONFIG_COMPONENTS", `{frameworks: ["AppDynamicsAgent"]}`)
+
+				_, names, err := registry.DetectAll()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(names).To(Equal([]string{"AppDynamics Agent"}))
+			})
+
+			It("rejects unknown frameworks", func() {
+				os.Setenv("JBP_CONFIG_COMPONENTS", `{frameworks: ["-NewRelic"]}`)
+
+				_, _, err := registry.DetectAll()
+				Expect(err).To(MatchError(ContainSubstring(`unknown framework component "NewRelic" in JBP_CONFIG_COMPONENTS`)))
+			})
+
+			It("names the standard frameworks after the Ruby buildpack", func() {
+				standard := frameworks.NewRegistry(ctx)
+				standard.RegisterStandardFrameworks()
+				os.Setenv("JBP_CONFIG_COMPONENTS", `{frameworks: ["NewRelicAgent", "-DatadogJavaagent"]}`)
+
+				_, names, err := standard.DetectAll()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(names).To(Equal([]string{"New Relic Agent"}))
+			})
+		})
 	})
 })
 
@@ -19,6 +19,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/appname"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
 	"os"
 	"path/filepath"
 	"strings"
@@ -106,14 +109,13 @@ func (g *GoogleStackdriverProfilerFramework) Finalize() error {
 	g.context.Log.BeginStep("Configuring Google Stackdriver Profiler")
 
 	// Get buildpack index for multi-buildpack support
-	depsIdx := g.context.Stager.DepsIdx()
 
 	// Convert staging path to runtime path
 	relPath, err := filepath.Rel(g.context.Stager.DepDir(), g.agentPath)
 	if err != nil {
 		return fmt.Errorf("failed to determine relative path for Google Stackdriver Profiler: %w", err)
 	}
-	runtimeAgentPath := filepath.Join(fmt.Sprintf("$DEPS_DIR/%s", depsIdx), relPath)
+	runtimeAgentPath := g.context.Droplet().Dep(relPath)
 
 	// Get credentials
 	credentials := g.getCredentials()
@@ -123,12 +125,19 @@ func (g *GoogleStackdriverProfilerFramework) Finalize() error {
 
 	err = g.loadConfig()
 	if err != nil {
+		if common.IsUnknownConfigKeysError(err) {
+			return err
+		}
 		g.context.Log.Warning("Failed to load google stack driver profiler config: %s", err.Error())
 		return nil // Do not fail the build
 	}
 
 	// Add service name (application name)
-	if appName := g.getApplicationName(); appName != "" {
+	appName, err := g.getApplicationName()
+	if err != nil {
+		return err
+	}
+	if appName != "" {
 		agentArgs = append(agentArgs, fmt.Sprintf("-cprof_service=%s", appName))
 	}
 
@@ -151,7 +160,7 @@ func (g *GoogleStackdriverProfilerFramework) Finalize() error {
 	}
 
 	// Write to .opts file
-	if err := writeJavaOptsFile(g.context, 22, "google_stackdriver_profiler", agentOpt); err != nil {
+	if err := writeJavaOptsFile(g.context, javaopts.GoogleStackdriverProfiler, "google_stackdriver_profiler", agentOpt); err != nil {
 		return fmt.Errorf("failed to write JAVA_OPTS for Google Stackdriver Profiler: %w", err)
 	}
 
@@ -205,26 +214,13 @@ func (g *GoogleStackdriverProfilerFramework) getCredentials() GoogleProfilerCred
 	return creds
 }
 
-// getApplicationName returns the application name
-func (g *GoogleStackdriverProfilerFramework) getApplicationName() string {
+// getApplicationName returns the configured application name or the name the application is reported as, see
+// appname.Name
+func (g *GoogleStackdriverProfilerFramework) getApplicationName() (string, error) {
 	if g.config.ApplicationName != "" {
-		return g.config.ApplicationName
-	}
-	vcapApp := os.Getenv("VCAP_APPLICATION")
-	if vcapApp == "" {
-		return ""
-	}
-
-	var appData map[string]interface{}
-	if err := json.Unmarshal([]byte(vcapApp), &appData); err != nil {
-		return ""
-	}
-
-	if name, ok := appData["application_name"].(string); ok {
-		return name
+		return g.config.ApplicationName, nil
 	}
-
-	return ""
+	return appname.Name(g.context.Log, appname.App)
 }
 
 // getApplicationVersion returns the application version
@@ -265,17 +261,9 @@ func (g *GoogleStackdriverProfilerFramework) loadConfig() error {
 		ApplicationName:    "",
 		ApplicationVersion: "",
 	}
-	config := os.Getenv("JBP_CONFIG_GOOGLE_STACKDRIVER_PROFILER")
-	if config != "" {
-		yamlHandler := common.YamlHandler{}
-		err := yamlHandler.ValidateFields([]byte(config), &gsdConfig)
-		if err != nil {
-			g.context.Log.Warning("Unknown user config values: %s", err.Error())
-		}
-		// overlay JBP_CONFIG_GOOGLE_STACKDRIVER_PROFILER over default values
-		if err = yamlHandler.Unmarshal([]byte(config), &gsdConfig); err != nil {
-			return fmt.Errorf("failed to parse JBP_CONFIG_GOOGLE_STACKDRIVER_PROFILER: %w", err)
-		}
+	// overlay buildpack defaults and JBP_CONFIG_GOOGLE_STACKDRIVER_PROFILER over default values
+	if err := config.Load(g.context.Log, "google_stackdriver_profiler", &gsdConfig); err != nil {
+		return err
 	}
 	g.config = &gsdConfig
 	return nil
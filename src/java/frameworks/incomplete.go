@@ -0,0 +1,41 @@
+package frameworks
+
+import (
+	"errors"
+	"strings"
+)
+
+// IncompleteError is returned by Supply when a framework could not complete its installation but the application can
+// be staged with what was installed, e.g. an agent without its default configuration, or without the agent because
+// its service sets skiperrors. The supply phase logs it as a warning and finalizes the framework as usual, unless
+// framework_supply makes the framework strict, in which case it fails staging.
+type IncompleteError struct {
+	Causes []error
+}
+
+func (e *IncompleteError) Error() string {
+	messages := make([]string, len(e.Causes))
+	for i, cause := range e.Causes {
+		messages[i] = cause.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *IncompleteError) Unwrap() []error {
+	return e.Causes
+}
+
+// IsIncompleteError returns true if err (or any error it wraps) is an *IncompleteError
+func IsIncompleteError(err error) bool {
+	var target *IncompleteError
+	return errors.As(err, &target)
+}
+
+// incomplete returns an *IncompleteError for causes, or nil if there are none, so that Supply can collect the steps
+// that failed and return incomplete(causes...) once it has completed the others
+func incomplete(causes ...error) error {
+	if len(causes) == 0 {
+		return nil
+	}
+	return &IncompleteError{Causes: causes}
+}
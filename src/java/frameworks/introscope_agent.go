@@ -16,14 +16,29 @@
 package frameworks
 
 import (
-	"encoding/json"
+	"bufio"
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/appname"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
+// introscopeProfileName is the name of the agent profile, both the one the buildpack generates and the one an
+// application may supply to override it
+const introscopeProfileName = "IntroscopeAgent.profile"
+
+// introscopeAppProfileLocations are the locations, relative to the application root, searched for a profile
+// supplied by the application
+var introscopeAppProfileLocations = []string{
+	introscopeProfileName,
+	filepath.Join("BOOT-INF", "classes", introscopeProfileName),
+	filepath.Join("WEB-INF", "classes", introscopeProfileName),
+}
+
 // IntroscopeAgentFramework represents the CA APM Introscope agent framework
 type IntroscopeAgentFramework struct {
 	context   *common.Context
@@ -85,44 +100,31 @@ func (i *IntroscopeAgentFramework) Finalize() error {
 	i.context.Log.BeginStep("Configuring Introscope agent")
 
 	// Get buildpack index for multi-buildpack support
-	depsIdx := i.context.Stager.DepsIdx()
 
 	// Convert staging path to runtime path
 	relPath, err := filepath.Rel(i.context.Stager.DepDir(), i.agentPath)
 	if err != nil {
 		return fmt.Errorf("failed to determine relative path for Introscope agent: %w", err)
 	}
-	runtimeJarPath := filepath.Join(fmt.Sprintf("$DEPS_DIR/%s", depsIdx), relPath)
+	runtimeJarPath := i.context.Droplet().Dep(relPath)
 
-	// Get credentials from service binding
-	credentials := i.getCredentials()
-
-	// Build all JAVA_OPTS options
-	var opts []string
-	opts = append(opts, fmt.Sprintf("-javaagent:%s", runtimeJarPath))
-
-	// Configure agent name (default to application name)
-	agentName := credentials.AgentName
-	if agentName == "" {
-		agentName = i.getApplicationName()
-	}
-	if agentName != "" {
-		opts = append(opts, fmt.Sprintf("-Dcom.wily.introscope.agentProfile.agent.name=%s", agentName))
+	profile, err := i.buildProfile(agentDir)
+	if err != nil {
+		return err
 	}
-
-	// Configure Enterprise Manager host
-	if credentials.EMHost != "" {
-		opts = append(opts, fmt.Sprintf("-Dcom.wily.introscope.agentProfile.agent.enterpriseManager.host=%s", credentials.EMHost))
+	profilePath := filepath.Join(agentDir, introscopeProfileName)
+	if err := os.WriteFile(profilePath, []byte(profile.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", introscopeProfileName, err)
 	}
 
-	// Configure Enterprise Manager port
-	if credentials.EMPort != "" {
-		opts = append(opts, fmt.Sprintf("-Dcom.wily.introscope.agentProfile.agent.enterpriseManager.port=%s", credentials.EMPort))
+	opts := []string{
+		fmt.Sprintf("-javaagent:%s", runtimeJarPath),
+		"-Dcom.wily.introscope.agentProfile=" + i.context.Droplet().Dep("introscope_agent", introscopeProfileName),
 	}
 
 	// Write all options to .opts file
 	javaOpts := strings.Join(opts, " ")
-	if err := writeJavaOptsFile(i.context, 27, "introscope_agent", javaOpts); err != nil {
+	if err := writeJavaOptsFile(i.context, javaopts.Introscope, "introscope_agent", javaOpts); err != nil {
 		return fmt.Errorf("failed to write JAVA_OPTS for Introscope: %w", err)
 	}
 
@@ -167,34 +169,7 @@ type IntroscopeCredentials struct {
 func (i *IntroscopeAgentFramework) getCredentials() IntroscopeCredentials {
 	creds := IntroscopeCredentials{}
 
-	vcapServices, err := GetVCAPServices()
-	if err != nil {
-		return creds
-	}
-
-	// Find Introscope service using standard helpers
-	var service *VCAPService
-
-	// Try exact service labels first
-	if svc := vcapServices.GetService("introscope"); svc != nil {
-		service = svc
-	} else if svc := vcapServices.GetService("ca-apm"); svc != nil {
-		service = svc
-	} else if svc := vcapServices.GetService("ca-wily"); svc != nil {
-		service = svc
-	} else if svc := vcapServices.GetService("wily-introscope"); svc != nil {
-		service = svc
-	} else {
-		// Try user-provided services with introscope/ca-apm/wily in the name
-		if svc := vcapServices.GetServiceByNamePattern("introscope"); svc != nil {
-			service = svc
-		} else if svc := vcapServices.GetServiceByNamePattern("ca-apm"); svc != nil {
-			service = svc
-		} else if svc := vcapServices.GetServiceByNamePattern("wily"); svc != nil {
-			service = svc
-		}
-	}
-
+	service := i.findService()
 	if service == nil {
 		return creds
 	}
@@ -225,23 +200,33 @@ func (i *IntroscopeAgentFramework) getCredentials() IntroscopeCredentials {
 	return creds
 }
 
-// getApplicationName returns the application name from VCAP_APPLICATION
-func (i *IntroscopeAgentFramework) getApplicationName() string {
-	vcapApp := os.Getenv("VCAP_APPLICATION")
-	if vcapApp == "" {
-		return ""
+// findService returns the bound Introscope service, or nil if there is none
+func (i *IntroscopeAgentFramework) findService() *VCAPService {
+	vcapServices, err := GetVCAPServices()
+	if err != nil {
+		return nil
 	}
 
-	var appData map[string]interface{}
-	if err := json.Unmarshal([]byte(vcapApp), &appData); err != nil {
-		return ""
+	// Try exact service labels first
+	for _, label := range []string{"introscope", "ca-apm", "ca-wily", "wily-introscope"} {
+		if svc := vcapServices.GetService(label); svc != nil {
+			return svc
+		}
 	}
 
-	if name, ok := appData["application_name"].(string); ok {
-		return name
+	for _, service := range vcapServices.Sorted() {
+		if service.HasTag("introscope") || service.HasTag("ca-apm") || service.HasTag("wily") {
+			return &service
+		}
 	}
 
-	return ""
+	// Try user-provided services with introscope/ca-apm/wily in the name
+	for _, pattern := range []string{"introscope", "ca-apm", "wily"} {
+		if svc := vcapServices.GetServiceByNamePattern(pattern); svc != nil {
+			return svc
+		}
+	}
+	return nil
 }
 
 func (i *IntroscopeAgentFramework) constructAgentPath(agentDir string) error {
@@ -257,3 +242,167 @@ func (i *IntroscopeAgentFramework) constructAgentPath(agentDir string) error {
 func (i *IntroscopeAgentFramework) DependencyIdentifier() string {
 	return "introscope-agent"
 }
+
+// buildProfile generates the agent profile. It starts from the default profile of the agent bundle, sets the agent
+// name and the Enterprise Manager connection from the service binding, copies credentials named like agent
+// properties, e.g. introscope.agent.deep.trace.enabled, and finally applies the profile supplied by the application.
+func (i *IntroscopeAgentFramework) buildProfile(agentDir string) (*introscopeProfile, error) {
+	profile := newIntroscopeProfile()
+	for _, dir := range []string{filepath.Join("core", "config"), filepath.Join("wily", "core", "config")} {
+		path := filepath.Join(agentDir, dir, introscopeProfileName)
+		if _, err := os.Stat(path); err == nil {
+			if err := profile.load(path); err != nil {
+				return nil, fmt.Errorf("failed to read the default Introscope profile: %w", err)
+			}
+			break
+		}
+	}
+
+	var credentials map[string]interface{}
+	if service := i.findService(); service != nil {
+		credentials = service.Credentials
+	}
+	creds := i.getCredentials()
+
+	agentName := creds.AgentName
+	if agentName == "" {
+		name, err := appname.Name(i.context.Log, appname.SpaceApp)
+		if err != nil {
+			return nil, err
+		}
+		agentName = name
+	}
+	if agentName != "" {
+		profile.set("introscope.agent.agentAutoNamingEnabled", "false")
+		profile.set("introscope.agent.agentName", agentName)
+	}
+
+	if url := introscopeManagerURL(credentials, creds); url != "" {
+		profile.set("agentManager.url.1", url)
+	}
+	if credential := introscopeCredential(credentials, "agent_manager_credential", "credential"); credential != "" {
+		profile.set("agentManager.credential", credential)
+	}
+	if trustStore := credentialString(credentials, "ssl_truststore"); trustStore != "" {
+		profile.set("agentManager.trustStore.1", trustStore)
+	}
+	if password := credentialString(credentials, "ssl_truststore_password"); password != "" {
+		profile.set("agentManager.trustStorePassword.1", password)
+	}
+
+	var properties []string
+	for key := range credentials {
+		if strings.Contains(key, ".") {
+			properties = append(properties, key)
+		}
+	}
+	sort.Strings(properties)
+	for _, key := range properties {
+		profile.set(key, introscopeCredential(credentials, key))
+	}
+
+	for _, location := range introscopeAppProfileLocations {
+		path := filepath.Join(i.context.Stager.BuildDir(), location)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if err := profile.load(path); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", location, err)
+		}
+		i.context.Log.Info("Applying Introscope agent settings from %s", location)
+		break
+	}
+
+	return profile, nil
+}
+
+// introscopeManagerURL returns the Enterprise Manager URL of the binding: agentManager_url_1 or agent_manager_url
+// as they are, or else em_host and em_port, over SSL if the ssl credential is true
+func introscopeManagerURL(credentials map[string]interface{}, creds IntroscopeCredentials) string {
+	if url := introscopeCredential(credentials, "agentManager_url_1", "agent_manager_url"); url != "" {
+		return url
+	}
+	if creds.EMHost == "" {
+		return ""
+	}
+
+	ssl := introscopeCredential(credentials, "ssl") == "true"
+	port := creds.EMPort
+	if port == "" {
+		port = "5001"
+		if ssl {
+			port = "5443"
+		}
+	}
+	if ssl {
+		return fmt.Sprintf("ssl://%s:%s", creds.EMHost, port)
+	}
+	return fmt.Sprintf("%s:%s", creds.EMHost, port)
+}
+
+// introscopeCredential returns the first of keys set in credentials, with booleans as true or false
+func introscopeCredential(credentials map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if value, ok := credentials[key].(bool); ok {
+			return fmt.Sprintf("%t", value)
+		}
+		if value := credentialString(credentials, key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// introscopeProfile is an agent profile, a Java properties file, kept in the order its properties were first set
+type introscopeProfile struct {
+	keys   []string
+	values map[string]string
+}
+
+func newIntroscopeProfile() *introscopeProfile {
+	return &introscopeProfile{values: map[string]string{}}
+}
+
+func (p *introscopeProfile) set(key, value string) {
+	if _, ok := p.values[key]; !ok {
+		p.keys = append(p.keys, key)
+	}
+	p.values[key] = value
+}
+
+// load sets the properties of the profile at path, overriding the ones already set. Comments are dropped and
+// values continued with a trailing backslash are joined.
+func (p *introscopeProfile) load(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	var line string
+	for scanner.Scan() {
+		line += strings.TrimSpace(scanner.Text())
+		if strings.HasSuffix(line, "\\") {
+			line = strings.TrimSuffix(line, "\\")
+			continue
+		}
+
+		if line != "" && !strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "!") {
+			if separator := strings.IndexAny(line, "=:"); separator > 0 {
+				p.set(strings.TrimSpace(line[:separator]), strings.TrimSpace(line[separator+1:]))
+			}
+		}
+		line = ""
+	}
+	return scanner.Err()
+}
+
+func (p *introscopeProfile) String() string {
+	var b strings.Builder
+	b.WriteString("# Generated by the Java buildpack from the Introscope service binding\n")
+	for _, key := range p.keys {
+		fmt.Fprintf(&b, "%s=%s\n", key, p.values[key])
+	}
+	return b.String()
+}
@@ -64,6 +64,13 @@ func installIntroscopeAgent(depsDir string) {
 	Expect(os.WriteFile(filepath.Join(agentDir, "Agent.jar"), []byte("fake jar"), 0644)).To(Succeed())
 }
 
+// readIntroscopeProfile returns the agent profile generated under depsDir.
+func readIntroscopeProfile(depsDir string) string {
+	content, err := os.ReadFile(filepath.Join(depsDir, "0", "introscope_agent", "IntroscopeAgent.profile"))
+	Expect(err).NotTo(HaveOccurred())
+	return string(content)
+}
+
 var _ = Describe("Introscope Agent", func() {
 	var (
 		fw       *frameworks.IntroscopeAgentFramework
@@ -282,13 +289,18 @@ var _ = Describe("Introscope Agent", func() {
 				Expect(entries[0].Name()).To(Equal("27_introscope_agent.opts"))
 			})
 
-			It("opts file contains no agent name, EM host, or EM port flags when absent", func() {
+			It("opts file points the agent at the generated profile", func() {
 				Expect(fw.Finalize()).To(Succeed())
 				content, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "27_introscope_agent.opts"))
 				Expect(err).NotTo(HaveOccurred())
-				Expect(string(content)).NotTo(ContainSubstring("agent.name"))
-				Expect(string(content)).NotTo(ContainSubstring("enterpriseManager.host"))
-				Expect(string(content)).NotTo(ContainSubstring("enterpriseManager.port"))
+				Expect(string(content)).To(HaveSuffix(" -Dcom.wily.introscope.agentProfile=$DEPS_DIR/0/introscope_agent/IntroscopeAgent.profile"))
+			})
+
+			It("profile contains no agent name or Enterprise Manager URL when absent", func() {
+				Expect(fw.Finalize()).To(Succeed())
+				profile := readIntroscopeProfile(depsDir)
+				Expect(profile).NotTo(ContainSubstring("introscope.agent.agentName"))
+				Expect(profile).NotTo(ContainSubstring("agentManager.url.1"))
 			})
 		})
 
@@ -299,11 +311,10 @@ var _ = Describe("Introscope Agent", func() {
 					`"agent_name":"MyApp"`))
 			})
 
-			It("opts file contains the agent name property", func() {
+			It("profile contains the agent name property", func() {
 				Expect(fw.Finalize()).To(Succeed())
-				content, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "27_introscope_agent.opts"))
-				Expect(err).NotTo(HaveOccurred())
-				Expect(string(content)).To(ContainSubstring("-Dcom.wily.introscope.agentProfile.agent.name=MyApp"))
+				content := readIntroscopeProfile(depsDir)
+				Expect(content).To(ContainSubstring("introscope.agent.agentName=MyApp\n"))
 			})
 		})
 
@@ -314,11 +325,10 @@ var _ = Describe("Introscope Agent", func() {
 					`"agentName":"CamelApp"`))
 			})
 
-			It("opts file contains the agent name property from camelCase key", func() {
+			It("profile contains the agent name property from camelCase key", func() {
 				Expect(fw.Finalize()).To(Succeed())
-				content, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "27_introscope_agent.opts"))
-				Expect(err).NotTo(HaveOccurred())
-				Expect(string(content)).To(ContainSubstring("-Dcom.wily.introscope.agentProfile.agent.name=CamelApp"))
+				content := readIntroscopeProfile(depsDir)
+				Expect(content).To(ContainSubstring("introscope.agent.agentName=CamelApp\n"))
 			})
 		})
 
@@ -328,11 +338,10 @@ var _ = Describe("Introscope Agent", func() {
 				os.Setenv("VCAP_APPLICATION", `{"application_name":"vcap-app"}`)
 			})
 
-			It("opts file contains the agent name from VCAP_APPLICATION", func() {
+			It("profile contains the agent name from VCAP_APPLICATION", func() {
 				Expect(fw.Finalize()).To(Succeed())
-				content, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "27_introscope_agent.opts"))
-				Expect(err).NotTo(HaveOccurred())
-				Expect(string(content)).To(ContainSubstring("-Dcom.wily.introscope.agentProfile.agent.name=vcap-app"))
+				content := readIntroscopeProfile(depsDir)
+				Expect(content).To(ContainSubstring("introscope.agent.agentName=vcap-app\n"))
 			})
 		})
 
@@ -344,12 +353,11 @@ var _ = Describe("Introscope Agent", func() {
 					`"agent_name":"binding-name"`))
 			})
 
-			It("opts file uses the binding agent name", func() {
+			It("profile uses the binding agent name", func() {
 				Expect(fw.Finalize()).To(Succeed())
-				content, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "27_introscope_agent.opts"))
-				Expect(err).NotTo(HaveOccurred())
-				Expect(string(content)).To(ContainSubstring("-Dcom.wily.introscope.agentProfile.agent.name=binding-name"))
-				Expect(string(content)).NotTo(ContainSubstring("vcap-app"))
+				content := readIntroscopeProfile(depsDir)
+				Expect(content).To(ContainSubstring("introscope.agent.agentName=binding-name\n"))
+				Expect(content).NotTo(ContainSubstring("vcap-app"))
 			})
 		})
 
@@ -360,11 +368,10 @@ var _ = Describe("Introscope Agent", func() {
 					`"em_host":"em.example.com"`))
 			})
 
-			It("opts file contains the EM host property", func() {
+			It("profile contains the Enterprise Manager URL with the default port", func() {
 				Expect(fw.Finalize()).To(Succeed())
-				content, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "27_introscope_agent.opts"))
-				Expect(err).NotTo(HaveOccurred())
-				Expect(string(content)).To(ContainSubstring("-Dcom.wily.introscope.agentProfile.agent.enterpriseManager.host=em.example.com"))
+				content := readIntroscopeProfile(depsDir)
+				Expect(content).To(ContainSubstring("agentManager.url.1=em.example.com:5001\n"))
 			})
 		})
 
@@ -375,11 +382,10 @@ var _ = Describe("Introscope Agent", func() {
 					`"emHost":"em-camel.example.com"`))
 			})
 
-			It("opts file contains the EM host property from camelCase key", func() {
+			It("profile contains the Enterprise Manager URL from camelCase key", func() {
 				Expect(fw.Finalize()).To(Succeed())
-				content, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "27_introscope_agent.opts"))
-				Expect(err).NotTo(HaveOccurred())
-				Expect(string(content)).To(ContainSubstring("-Dcom.wily.introscope.agentProfile.agent.enterpriseManager.host=em-camel.example.com"))
+				content := readIntroscopeProfile(depsDir)
+				Expect(content).To(ContainSubstring("agentManager.url.1=em-camel.example.com:5001\n"))
 			})
 		})
 
@@ -387,14 +393,13 @@ var _ = Describe("Introscope Agent", func() {
 			BeforeEach(func() {
 				installIntroscopeAgent(depsDir)
 				os.Setenv("VCAP_SERVICES", introscopeVCAPServices("introscope", "my-introscope", nil,
-					`"em_port":"5001"`))
+					`"em_host":"em.example.com","em_port":"5001"`))
 			})
 
-			It("opts file contains the EM port property", func() {
+			It("profile contains the Enterprise Manager URL with the port", func() {
 				Expect(fw.Finalize()).To(Succeed())
-				content, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "27_introscope_agent.opts"))
-				Expect(err).NotTo(HaveOccurred())
-				Expect(string(content)).To(ContainSubstring("-Dcom.wily.introscope.agentProfile.agent.enterpriseManager.port=5001"))
+				content := readIntroscopeProfile(depsDir)
+				Expect(content).To(ContainSubstring("agentManager.url.1=em.example.com:5001\n"))
 			})
 		})
 
@@ -402,14 +407,13 @@ var _ = Describe("Introscope Agent", func() {
 			BeforeEach(func() {
 				installIntroscopeAgent(depsDir)
 				os.Setenv("VCAP_SERVICES", introscopeVCAPServices("introscope", "my-introscope", nil,
-					`"emPort":5002`))
+					`"emHost":"em.example.com","emPort":5002`))
 			})
 
-			It("opts file contains the EM port property from numeric camelCase key", func() {
+			It("profile contains the Enterprise Manager URL with the numeric camelCase port", func() {
 				Expect(fw.Finalize()).To(Succeed())
-				content, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "27_introscope_agent.opts"))
-				Expect(err).NotTo(HaveOccurred())
-				Expect(string(content)).To(ContainSubstring("-Dcom.wily.introscope.agentProfile.agent.enterpriseManager.port=5002"))
+				content := readIntroscopeProfile(depsDir)
+				Expect(content).To(ContainSubstring("agentManager.url.1=em.example.com:5002\n"))
 			})
 		})
 
@@ -420,13 +424,11 @@ var _ = Describe("Introscope Agent", func() {
 					`"agent_name":"FullApp","em_host":"em.example.com","em_port":"5001"`))
 			})
 
-			It("opts file contains all three agent properties", func() {
+			It("profile contains the agent name and the Enterprise Manager URL", func() {
 				Expect(fw.Finalize()).To(Succeed())
-				content, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "27_introscope_agent.opts"))
-				Expect(err).NotTo(HaveOccurred())
-				Expect(string(content)).To(ContainSubstring("-Dcom.wily.introscope.agentProfile.agent.name=FullApp"))
-				Expect(string(content)).To(ContainSubstring("-Dcom.wily.introscope.agentProfile.agent.enterpriseManager.host=em.example.com"))
-				Expect(string(content)).To(ContainSubstring("-Dcom.wily.introscope.agentProfile.agent.enterpriseManager.port=5001"))
+				content := readIntroscopeProfile(depsDir)
+				Expect(content).To(ContainSubstring("introscope.agent.agentName=FullApp\n"))
+				Expect(content).To(ContainSubstring("agentManager.url.1=em.example.com:5001\n"))
 			})
 		})
 
@@ -437,11 +439,10 @@ var _ = Describe("Introscope Agent", func() {
 					`"em_host":"em.example.com"`))
 			})
 
-			It("opts file contains the credential from the ca-apm binding", func() {
+			It("profile contains the credential from the ca-apm binding", func() {
 				Expect(fw.Finalize()).To(Succeed())
-				content, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "27_introscope_agent.opts"))
-				Expect(err).NotTo(HaveOccurred())
-				Expect(string(content)).To(ContainSubstring("-Dcom.wily.introscope.agentProfile.agent.enterpriseManager.host=em.example.com"))
+				content := readIntroscopeProfile(depsDir)
+				Expect(content).To(ContainSubstring("agentManager.url.1=em.example.com:5001\n"))
 			})
 		})
 
@@ -452,11 +453,94 @@ var _ = Describe("Introscope Agent", func() {
 					`"em_host":"wily.example.com"`))
 			})
 
-			It("opts file contains the credential from the wily user-provided binding", func() {
+			It("profile contains the credential from the wily user-provided binding", func() {
 				Expect(fw.Finalize()).To(Succeed())
-				content, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "27_introscope_agent.opts"))
-				Expect(err).NotTo(HaveOccurred())
-				Expect(string(content)).To(ContainSubstring("-Dcom.wily.introscope.agentProfile.agent.enterpriseManager.host=wily.example.com"))
+				content := readIntroscopeProfile(depsDir)
+				Expect(content).To(ContainSubstring("agentManager.url.1=wily.example.com:5001\n"))
+			})
+		})
+
+		Context("with VCAP_APPLICATION including the space", func() {
+			BeforeEach(func() {
+				installIntroscopeAgent(depsDir)
+				os.Setenv("VCAP_APPLICATION", `{"application_name":"orders","space_name":"prod"}`)
+			})
+
+			It("profile names the agent space:app and disables auto naming", func() {
+				Expect(fw.Finalize()).To(Succeed())
+				content := readIntroscopeProfile(depsDir)
+				Expect(content).To(ContainSubstring("introscope.agent.agentAutoNamingEnabled=false\n"))
+				Expect(content).To(ContainSubstring("introscope.agent.agentName=prod:orders\n"))
+			})
+		})
+
+		Context("with SSL enabled", func() {
+			BeforeEach(func() {
+				installIntroscopeAgent(depsDir)
+				os.Setenv("VCAP_SERVICES", introscopeVCAPServices("introscope", "my-introscope", nil,
+					`"em_host":"em.example.com","ssl":true,"ssl_truststore":"/home/vcap/app/truststore.jks","ssl_truststore_password":"secret"`))
+			})
+
+			It("profile connects over SSL with the default SSL port and trust store", func() {
+				Expect(fw.Finalize()).To(Succeed())
+				content := readIntroscopeProfile(depsDir)
+				Expect(content).To(ContainSubstring("agentManager.url.1=ssl://em.example.com:5443\n"))
+				Expect(content).To(ContainSubstring("agentManager.trustStore.1=/home/vcap/app/truststore.jks\n"))
+				Expect(content).To(ContainSubstring("agentManager.trustStorePassword.1=secret\n"))
+			})
+		})
+
+		Context("with an agent manager URL, credential and agent properties", func() {
+			BeforeEach(func() {
+				installIntroscopeAgent(depsDir)
+				os.Setenv("VCAP_SERVICES", introscopeVCAPServices("introscope", "my-introscope", nil,
+					`"agentManager_url_1":"https://apm.example.com:443","agent_manager_credential":"token",`+
+						`"em_host":"ignored.example.com","introscope.agent.deep.trace.enabled":true,`+
+						`"introscope.agent.transactiontracer.sampling.perinterval.count":"2"`))
+			})
+
+			It("profile uses the URL as it is and copies the agent properties", func() {
+				Expect(fw.Finalize()).To(Succeed())
+				content := readIntroscopeProfile(depsDir)
+				Expect(content).To(ContainSubstring("agentManager.url.1=https://apm.example.com:443\n"))
+				Expect(content).NotTo(ContainSubstring("ignored.example.com"))
+				Expect(content).To(ContainSubstring("agentManager.credential=token\n"))
+				Expect(content).To(ContainSubstring("introscope.agent.deep.trace.enabled=true\n"))
+				Expect(content).To(ContainSubstring("introscope.agent.transactiontracer.sampling.perinterval.count=2\n"))
+			})
+		})
+
+		Context("with a default profile in the agent bundle and a profile in the application", func() {
+			BeforeEach(func() {
+				installIntroscopeAgent(depsDir)
+				os.Setenv("VCAP_SERVICES", introscopeVCAPServices("introscope", "my-introscope", nil,
+					`"agent_name":"binding-name","em_host":"em.example.com"`))
+
+				configDir := filepath.Join(depsDir, "0", "introscope_agent", "core", "config")
+				Expect(os.MkdirAll(configDir, 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(configDir, "IntroscopeAgent.profile"), []byte(
+					"# Bundle defaults\n"+
+						"introscope.autoprobe.directivesFile=default-typical.pbl,\\\n"+
+						"    hotdeploy\n"+
+						"introscope.agent.agentName=DefaultAgent\n"+
+						"introscope.agent.log.level=INFO\n"), 0644)).To(Succeed())
+
+				classesDir := filepath.Join(buildDir, "BOOT-INF", "classes")
+				Expect(os.MkdirAll(classesDir, 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(classesDir, "IntroscopeAgent.profile"), []byte(
+					"introscope.agent.agentName = app-name\n"+
+						"introscope.agent.log.level: DEBUG\n"), 0644)).To(Succeed())
+			})
+
+			It("profile merges the bundle defaults, the binding and the application overrides in order", func() {
+				Expect(fw.Finalize()).To(Succeed())
+				Expect(readIntroscopeProfile(depsDir)).To(Equal(
+					"# Generated by the Java buildpack from the Introscope service binding\n" +
+						"introscope.autoprobe.directivesFile=default-typical.pbl,hotdeploy\n" +
+						"introscope.agent.agentName=app-name\n" +
+						"introscope.agent.log.level=DEBUG\n" +
+						"introscope.agent.agentAutoNamingEnabled=false\n" +
+						"agentManager.url.1=em.example.com:5001\n"))
 			})
 		})
 
@@ -3,6 +3,7 @@ package frameworks
 import (
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
 	"path/filepath"
 
 	"github.com/cloudfoundry/libbuildpack"
@@ -45,9 +46,11 @@ func (j *JacocoAgentFramework) Detect() (string, error) {
 				return "JaCoCo Agent", nil
 			}
 		}
+		j.context.Log.Debug("JaCoCo: the jacoco service has no address credential")
+		return "", nil
 	}
 
-	j.context.Log.Debug("JaCoCo not detected")
+	j.context.Log.Debug("JaCoCo: no jacoco service bound")
 	return "", nil
 }
 
@@ -133,7 +136,6 @@ func (j *JacocoAgentFramework) Finalize() error {
 	}
 
 	// Get buildpack index for multi-buildpack support
-	depsIdx := j.context.Stager.DepsIdx()
 
 	// Find jacocoagent.jar at staging time to determine relative path
 	agentDir := filepath.Join(j.context.Stager.DepDir(), "jacoco_agent")
@@ -148,7 +150,7 @@ func (j *JacocoAgentFramework) Finalize() error {
 	if err != nil {
 		return fmt.Errorf("failed to compute relative path: %w", err)
 	}
-	runtimeAgentPath := filepath.Join(fmt.Sprintf("$DEPS_DIR/%s", depsIdx), relPath)
+	runtimeAgentPath := j.context.Droplet().Dep(relPath)
 
 	// Build javaagent option with runtime path
 	javaagentOpts := fmt.Sprintf("-javaagent:%s", runtimeAgentPath)
@@ -165,7 +167,7 @@ func (j *JacocoAgentFramework) Finalize() error {
 	}
 
 	// Write to .opts file using priority 26
-	if err := writeJavaOptsFile(j.context, 26, "jacoco", javaagentOpts); err != nil {
+	if err := writeJavaOptsFile(j.context, javaopts.JaCoCo, "jacoco", javaagentOpts); err != nil {
 		return fmt.Errorf("failed to write java_opts file: %w", err)
 	}
 
@@ -2,11 +2,12 @@ package frameworks
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/jarutil"
 
 	"github.com/cloudfoundry/libbuildpack"
 )
@@ -26,13 +27,18 @@ func NewJavaCfEnvFramework(ctx *common.Context) *JavaCfEnvFramework {
 // Detect checks if java-cfenv should be included
 func (j *JavaCfEnvFramework) Detect() (string, error) {
 	// Check if enabled in configuration
-	enabled := j.isEnabled()
+	enabled, err := j.isEnabled()
+	if err != nil {
+		return "", err
+	}
 	if !enabled {
+		j.context.Log.Debug("Java CF Env: disabled by configuration")
 		return "", nil
 	}
 
 	// Check if Spring Boot 3.x/4.x is present
 	if !j.isSpringBootMajor(4) && !j.isSpringBootMajor(3) {
+		j.context.Log.Debug("Java CF Env: not a Spring Boot 3 or 4 application")
 		return "", nil
 	}
 
@@ -94,8 +100,7 @@ func (j *JavaCfEnvFramework) Finalize() error {
 		return nil
 	}
 
-	depsIdx := j.context.Stager.DepsIdx()
-	runtimePath := fmt.Sprintf("$DEPS_DIR/%s/java_cf_env/%s", depsIdx, filepath.Base(matches[0]))
+	runtimePath := j.context.Droplet().Dep("java_cf_env", filepath.Base(matches[0]))
 
 	profileScript := fmt.Sprintf("export CLASSPATH=\"%s${CLASSPATH:+:$CLASSPATH}\"\n", runtimePath)
 	if err := j.context.Stager.WriteProfileD("java_cf_env.sh", profileScript); err != nil {
@@ -108,49 +113,19 @@ func (j *JavaCfEnvFramework) Finalize() error {
 }
 
 // isEnabled checks if java-cfenv is enabled in configuration
-func (j *JavaCfEnvFramework) isEnabled() bool {
-	// Check JBP_CONFIG_JAVA_CF_ENV environment variable
-	yamlHandler := common.YamlHandler{}
-	configOverride := os.Getenv("JBP_CONFIG_JAVA_CF_ENV")
-	if configOverride != "" {
-		// Parse YAML configuration
-		var yamlContent interface{}
-		if err := yamlHandler.Unmarshal([]byte(configOverride), &yamlContent); err != nil {
-			j.context.Log.Warning("Failed to parse JBP_CONFIG_JAVA_CF_ENV, treating as enabled: %s", err)
-			return true
-		}
-
-		// Handle both direct map and string-encoded YAML
-		var configData []byte
-		switch v := yamlContent.(type) {
-		case string:
-			configData = []byte(v)
-		case map[string]interface{}:
-			var err error
-			configData, err = yamlHandler.Marshal(v)
-			if err != nil {
-				j.context.Log.Warning("Failed to marshal config, treating as enabled: %s", err)
-				return true
-			}
-		default:
-			j.context.Log.Warning("Unexpected YAML type, treating as enabled: %T", v)
-			return true
-		}
+// Unknown configuration keys are only returned as an error in strict mode
+func (j *JavaCfEnvFramework) isEnabled() (bool, error) {
+	// Default to enabled
+	cfEnvConfig := enabledConfig{Enabled: true}
 
-		// Parse into config structure
-		var config struct {
-			Enabled bool `yaml:"enabled"`
-		}
-		if err := yamlHandler.Unmarshal(configData, &config); err != nil {
-			j.context.Log.Warning("Failed to parse config structure, treating as enabled: %s", err)
-			return true
+	if err := config.Load(j.context.Log, "java_cf_env", &cfEnvConfig); err != nil {
+		if common.IsUnknownConfigKeysError(err) {
+			return false, err
 		}
-
-		return config.Enabled
+		j.context.Log.Warning("Failed to parse JBP_CONFIG_JAVA_CF_ENV, treating as enabled: %s", err)
+		return true, nil
 	}
-
-	// Default to enabled
-	return true
+	return cfEnvConfig.Enabled, nil
 }
 
 // isSpringBootMajor checks if the application is Spring Boot <major>.x
@@ -171,10 +146,9 @@ func (j *JavaCfEnvFramework) isSpringBootMajor(major int) bool {
 	}
 
 	// Also check META-INF/MANIFEST.MF for Spring-Boot-Version
-	manifestPath := filepath.Join(j.context.Stager.BuildDir(), "META-INF", "MANIFEST.MF")
-	if content, err := os.ReadFile(manifestPath); err == nil {
-		manifest := string(content)
-		if strings.Contains(manifest, fmt.Sprintf("Spring-Boot-Version: %d.", major)) {
+	manifestPath := filepath.Join(j.context.Stager.BuildDir(), filepath.FromSlash(jarutil.ManifestPath))
+	if manifest, err := jarutil.ReadManifestFile(manifestPath); err == nil {
+		if strings.HasPrefix(manifest.Get("Spring-Boot-Version"), fmt.Sprintf("%d.", major)) {
 			return true
 		}
 	}
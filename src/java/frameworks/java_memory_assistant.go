@@ -3,6 +3,8 @@ package frameworks
 import (
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
 	"os"
 	"path/filepath"
 	"strings"
@@ -25,6 +27,9 @@ func (j *JavaMemoryAssistantFramework) Detect() (string, error) {
 	// Check if explicitly enabled via configuration
 	config, err := j.loadConfig()
 	if err != nil {
+		if common.IsUnknownConfigKeysError(err) {
+			return "", err
+		}
 		j.context.Log.Warning("Failed to load java memory assistant config: %s", err.Error())
 		return "", nil // Don't fail the build
 	}
@@ -60,10 +65,9 @@ func (j *JavaMemoryAssistantFramework) Supply() error {
 	if err == nil {
 		cleanupDir := filepath.Join(j.context.Stager.DepDir(), "java_memory_assistant_cleanup")
 		if err := j.context.Installer.InstallDependency(cleanupDep, cleanupDir); err != nil {
-			j.context.Log.Warning("Failed to install Java Memory Assistant cleanup utility: %s", err.Error())
-		} else {
-			j.context.Log.Debug("Installed Java Memory Assistant cleanup utility version %s", cleanupDep.Version)
+			return incomplete(fmt.Errorf("failed to install Java Memory Assistant cleanup utility: %w", err))
 		}
+		j.context.Log.Debug("Installed Java Memory Assistant cleanup utility version %s", cleanupDep.Version)
 	}
 
 	return nil
@@ -81,12 +85,11 @@ func (j *JavaMemoryAssistantFramework) Finalize() error {
 	}
 
 	// Get buildpack index for multi-buildpack support
-	depsIdx := j.context.Stager.DepsIdx()
 
 	// Convert staging path to runtime path
 	// Runtime: $DEPS_DIR/<idx>/java_memory_assistant/java-memory-assistant-x.x.x.jar
 	relPath := filepath.Base(matches[0])
-	runtimeAgentPath := fmt.Sprintf("$DEPS_DIR/%s/java_memory_assistant/%s", depsIdx, relPath)
+	runtimeAgentPath := j.context.Droplet().Dep("java_memory_assistant", relPath)
 
 	// Build agent configuration
 	agentConfig := j.buildAgentConfig()
@@ -107,7 +110,7 @@ func (j *JavaMemoryAssistantFramework) Finalize() error {
 	}
 
 	// Write to .opts file using priority 28
-	if err := writeJavaOptsFile(j.context, 28, "java_memory_assistant", javaagentArg); err != nil {
+	if err := writeJavaOptsFile(j.context, javaopts.JavaMemoryAssistant, "java_memory_assistant", javaagentArg); err != nil {
 		return fmt.Errorf("failed to write java_opts file: %w", err)
 	}
 
@@ -218,17 +221,9 @@ func (j *JavaMemoryAssistantFramework) loadConfig() (*javaMemoryAssistantConfig,
 			MaxDumpCount: 1,
 		},
 	}
-	config := os.Getenv("JBP_CONFIG_JAVA_MEMORY_ASSISTANT")
-	if config != "" {
-		yamlHandler := common.YamlHandler{}
-		err := yamlHandler.ValidateFields([]byte(config), &jConfig)
-		if err != nil {
-			j.context.Log.Warning("Unknown user config values: %s", err.Error())
-		}
-		// overlay JBP_CONFIG_JAVA_MEMORY_ASSISTANT over default values
-		if err = yamlHandler.Unmarshal([]byte(config), &jConfig); err != nil {
-			return nil, fmt.Errorf("failed to parse JBP_CONFIG_JAVA_MEMORY_ASSISTANT: %w", err)
-		}
+	// overlay buildpack defaults and JBP_CONFIG_JAVA_MEMORY_ASSISTANT over default values
+	if err := config.Load(j.context.Log, "java_memory_assistant", &jConfig); err != nil {
+		return nil, err
 	}
 	return &jConfig, nil
 }
@@ -3,7 +3,8 @@ package frameworks
 import (
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
-	"os"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
 	"strings"
 	"unicode"
 )
@@ -19,6 +20,13 @@ type JavaOptsConfig struct {
 	JavaOpts        []string `yaml:"java_opts"`
 }
 
+// javaOptsRawConfig is JBP_CONFIG_JAVA_OPTS as written by the application, where java_opts may be a YAML sequence
+// or a legacy space-separated string
+type javaOptsRawConfig struct {
+	FromEnvironment bool        `yaml:"from_environment"`
+	JavaOpts        interface{} `yaml:"java_opts"`
+}
+
 // NewJavaOptsFramework creates a new Java Opts framework instance
 func NewJavaOptsFramework(ctx *common.Context) *JavaOptsFramework {
 	return &JavaOptsFramework{context: ctx}
@@ -29,6 +37,9 @@ func (j *JavaOptsFramework) Detect() (string, error) {
 	// Check if there's any configuration to apply
 	config, err := j.loadConfig()
 	if err != nil {
+		if common.IsUnknownConfigKeysError(err) {
+			return "", err
+		}
 		// if detect "fails" Finalize() is not called so log parse failures as warning
 		j.context.Log.Warning("Failed to load java_opts config: %s", err.Error())
 		return "", nil
@@ -102,7 +113,7 @@ func (j *JavaOptsFramework) Finalize() error {
 
 	// Write to .opts file (priority 99 = always last)
 	if finalOpts != "" {
-		if err := writeJavaOptsFile(j.context, 99, "user_java_opts", finalOpts); err != nil {
+		if err := writeJavaOptsFile(j.context, javaopts.User, "user_java_opts", finalOpts); err != nil {
 			return fmt.Errorf("failed to write java_opts file: %w", err)
 		}
 	}
@@ -243,92 +254,40 @@ func isRubySafeChar(ch rune) bool {
 
 // loadConfig loads the java_opts.yml configuration
 func (j *JavaOptsFramework) loadConfig() (*JavaOptsConfig, error) {
-	config := &JavaOptsConfig{
+	rawConfig := javaOptsRawConfig{
 		FromEnvironment: true, // Default to true (matches config file)
-		JavaOpts:        []string{},
 	}
-	yamlHandler := common.YamlHandler{}
-
-	// Check for JBP_CONFIG_JAVA_OPTS override
-	configOverride := os.Getenv("JBP_CONFIG_JAVA_OPTS")
-	if configOverride != "" {
-		// First, parse the outer YAML string (handles single-quoted format like '{...}')
-		var yamlContent interface{}
-		if err := yamlHandler.Unmarshal([]byte(configOverride), &yamlContent); err != nil {
-			return nil, fmt.Errorf("failed to parse JBP_CONFIG_JAVA_OPTS: %w", err)
-		}
 
-		// Handle different YAML formats for backward compatibility
-		var configData []byte
-		switch v := yamlContent.(type) {
-		case string:
-			// It's a YAML string literal - parse the content
-			configData = []byte(v)
-		case map[string]interface{}:
-			// It's already a parsed YAML structure - marshal it back to bytes
-			var err error
-			configData, err = yamlHandler.Marshal(v)
-			if err != nil {
-				return nil, fmt.Errorf("failed to marshal config map: %w", err)
-			}
-		case []interface{}:
-			// Handle legacy format: [from_environment: false, java_opts: ...]
-			// This parses as an array of maps, so we need to merge them
-			mergedMap := make(map[string]interface{})
-			for _, item := range v {
-				if m, ok := item.(map[string]interface{}); ok {
-					for k, val := range m {
-						mergedMap[k] = val
-					}
-				}
-			}
-			var err error
-			configData, err = yamlHandler.Marshal(mergedMap)
-			if err != nil {
-				return nil, fmt.Errorf("failed to marshal merged config map: %w", err)
-			}
-		default:
-			return nil, fmt.Errorf("unexpected YAML type: %T", v)
-		}
+	// overlay buildpack defaults and JBP_CONFIG_JAVA_OPTS over default values
+	if err := config.Load(j.context.Log, "java_opts", &rawConfig); err != nil {
+		return nil, err
+	}
 
-		// Parse into a generic map first to handle both string and array formats for java_opts
-		var rawConfig map[string]interface{}
-		if err := yamlHandler.Unmarshal(configData, &rawConfig); err != nil {
-			return nil, fmt.Errorf("failed to parse JBP_CONFIG_JAVA_OPTS structure: %w", err)
-		}
+	joConfig := &JavaOptsConfig{
+		FromEnvironment: rawConfig.FromEnvironment,
+		JavaOpts:        []string{},
+	}
 
-		// Handle from_environment field
-		if fromEnv, ok := rawConfig["from_environment"].(bool); ok {
-			config.FromEnvironment = fromEnv
+	// Handle java_opts field - support both string and array formats
+	switch opts := rawConfig.JavaOpts.(type) {
+	case []interface{}:
+		// Already an array
+		for _, opt := range opts {
+			if optStr, ok := opt.(string); ok {
+				joConfig.JavaOpts = append(joConfig.JavaOpts, optStr)
+			}
 		}
-
-		// Handle java_opts field - support both string and array formats
-		if javaOptsRaw, ok := rawConfig["java_opts"]; ok {
-			switch opts := javaOptsRaw.(type) {
-			case []interface{}:
-				// Already an array
-				for _, opt := range opts {
-					if optStr, ok := opt.(string); ok {
-						config.JavaOpts = append(config.JavaOpts, optStr)
-					}
-				}
-			case string:
-				// Legacy format: space-separated string
-				// Split on spaces but preserve quoted strings (like Ruby's shellsplit)
-				if opts != "" {
-					tokens, err := shellSplit(opts)
-					if err != nil {
-						return nil, fmt.Errorf("failed to parse java_opts string: %w", err)
-					}
-					config.JavaOpts = tokens
-				}
+	case string:
+		// Legacy format: space-separated string
+		// Split on spaces but preserve quoted strings (like Ruby's shellsplit)
+		if opts != "" {
+			tokens, err := shellSplit(opts)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse java_opts string: %w", err)
 			}
+			joConfig.JavaOpts = tokens
 		}
-
-		return config, nil
 	}
 
-	// No config file - use built-in defaults
-	// (The Ruby buildpack's config/java_opts.yml only contained these same defaults)
-	return config, nil
+	return joConfig, nil
 }
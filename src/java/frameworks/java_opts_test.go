@@ -4,6 +4,8 @@ import (
 	"os"
 	"strings"
 
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/libbuildpack"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -90,7 +92,7 @@ var _ = Describe("JavaOpts", func() {
 	})
 
 	Describe("loadConfig", func() {
-		framework := &JavaOptsFramework{context: nil}
+		framework := &JavaOptsFramework{context: &common.Context{Log: libbuildpack.NewLogger(GinkgoWriter)}}
 
 		AfterEach(func() {
 			os.Unsetenv("JBP_CONFIG_JAVA_OPTS")
@@ -133,5 +135,13 @@ var _ = Describe("JavaOpts", func() {
 			Expect(config.FromEnvironment).To(BeFalse())
 			Expect(config.JavaOpts).To(Equal([]string{"-Xmx256m"}))
 		})
+
+		It("parses block style YAML with nested quoting", func() {
+			os.Setenv("JBP_CONFIG_JAVA_OPTS", "from_environment: false\njava_opts:\n  - '-Dkey=value: with {braces}'\n")
+			config, err := framework.loadConfig()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.FromEnvironment).To(BeFalse())
+			Expect(config.JavaOpts).To(Equal([]string{"-Dkey=value: with {braces}"}))
+		})
 	})
 })
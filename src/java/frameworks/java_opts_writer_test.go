@@ -2,7 +2,12 @@ package frameworks_test
 
 import (
 	"os"
+	"os/exec"
+	"path/filepath"
 
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/frameworks"
+	"github.com/cloudfoundry/libbuildpack"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -20,4 +25,56 @@ var _ = Describe("Java Opts Writer", func() {
 			Expect(os.Getenv("JAVA_OPTS")).To(Equal(javaOpts))
 		})
 	})
+
+	Describe("CreateJavaOptsAssemblyScript", func() {
+		var (
+			tmpDir  string
+			depsDir string
+		)
+
+		BeforeEach(func() {
+			var err error
+			tmpDir, err = os.MkdirTemp("", "java-opts")
+			Expect(err).NotTo(HaveOccurred())
+			depsDir = filepath.Join(tmpDir, "deps")
+
+			optsDir := filepath.Join(depsDir, "0", "java_opts")
+			Expect(os.MkdirAll(optsDir, 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(optsDir, "19_datadog_javaagent.opts"), []byte("-javaagent:$DEPS_DIR/0/datadog/dd.jar"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(optsDir, "35_new_relic.opts"), []byte("-javaagent:$DEPS_DIR/0/new_relic/newrelic.jar -Dnewrelic.home=x"), 0644)).To(Succeed())
+
+			logger := libbuildpack.NewLogger(GinkgoWriter)
+			manifest := &libbuildpack.Manifest{}
+			ctx := &common.Context{
+				Stager: libbuildpack.NewStager([]string{tmpDir, "", depsDir, "0"}, logger, manifest),
+				Log:    logger,
+			}
+			Expect(frameworks.CreateJavaOptsAssemblyScript(ctx)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(tmpDir)
+		})
+
+		// assemble sources the script with the given environment and returns the resulting JAVA_OPTS
+		assemble := func(env ...string) string {
+			cmd := exec.Command("bash", "-c", `source "$DEPS_DIR/0/profile.d/00_java_opts.sh" && printf '%s' "$JAVA_OPTS"`)
+			cmd.Env = append([]string{"DEPS_DIR=" + depsDir, "PATH=" + os.Getenv("PATH")}, env...)
+			output, err := cmd.Output()
+			Expect(err).NotTo(HaveOccurred())
+			return string(output)
+		}
+
+		It("assembles the options of every component", func() {
+			Expect(assemble()).To(Equal("-javaagent:" + depsDir + "/0/datadog/dd.jar -javaagent:" + depsDir + "/0/new_relic/newrelic.jar -Dnewrelic.home=x"))
+		})
+
+		It("drops the options of a component disabled with BPL_<NAME>_ENABLED", func() {
+			opts := assemble("BPL_NEW_RELIC_ENABLED=false")
+			Expect(opts).To(Equal("-javaagent:" + depsDir + "/0/datadog/dd.jar"))
+
+			Expect(assemble("BPL_DATADOG_JAVAAGENT_ENABLED=0")).NotTo(ContainSubstring("dd.jar"))
+			Expect(assemble("BPL_NEW_RELIC_ENABLED=true")).To(ContainSubstring("newrelic.jar"))
+		})
+	})
 })
@@ -6,6 +6,8 @@ import (
 	"strconv"
 
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
 )
 
 // JmxFramework implements JMX (Java Management Extensions) support
@@ -24,10 +26,14 @@ func (j *JmxFramework) Detect() (string, error) {
 	// Check if JMX is enabled in configuration
 	config, err := j.loadConfig()
 	if err != nil {
+		if common.IsUnknownConfigKeysError(err) {
+			return "", err
+		}
 		j.context.Log.Warning("Failed to load jmx config: %s", err.Error())
 		return "", nil // Don't fail the build
 	}
 	if !config.isEnabled() {
+		j.context.Log.Debug("JMX: disabled (default), enable it with JBP_CONFIG_JMX='{enabled: true}'")
 		return "", nil
 	}
 
@@ -38,8 +44,7 @@ func (j *JmxFramework) Detect() (string, error) {
 func (j *JmxFramework) Supply() error {
 	config, err := j.loadConfig()
 	if err != nil {
-		j.context.Log.Warning("Failed to load debug config: %s", err.Error())
-		return nil // Don't fail the build
+		return incomplete(fmt.Errorf("failed to load jmx config: %w", err))
 	}
 
 	port := config.getPort()
@@ -68,7 +73,7 @@ func (j *JmxFramework) Finalize() error {
 	)
 
 	// Write JAVA_OPTS to .opts file with priority 29 (Ruby buildpack line 63)
-	if err := writeJavaOptsFile(j.context, 29, "jmx", jmxOpts); err != nil {
+	if err := writeJavaOptsFile(j.context, javaopts.JMX, "jmx", jmxOpts); err != nil {
 		return fmt.Errorf("failed to write java_opts file: %w", err)
 	}
 
@@ -81,17 +86,9 @@ func (j *JmxFramework) loadConfig() (*jmxConfig, error) {
 		Enabled: false,
 		Port:    5000,
 	}
-	config := os.Getenv("JBP_CONFIG_JMX")
-	if config != "" {
-		yamlHandler := common.YamlHandler{}
-		err := yamlHandler.ValidateFields([]byte(config), &jConfig)
-		if err != nil {
-			j.context.Log.Warning("Unknown user config values: %s", err.Error())
-		}
-		// overlay JBP_CONFIG_JMX over default values
-		if err = yamlHandler.Unmarshal([]byte(config), &jConfig); err != nil {
-			return nil, fmt.Errorf("failed to parse JBP_CONFIG_JMX: %w", err)
-		}
+	// overlay buildpack defaults and JBP_CONFIG_JMX over default values
+	if err := config.Load(j.context.Log, "jmx", &jConfig); err != nil {
+		return nil, err
 	}
 	return &jConfig, nil
 }
@@ -55,6 +55,7 @@ var _ = Describe("JMX", func() {
 		os.Unsetenv("BPL_JMX_ENABLED")
 		os.Unsetenv("BPL_JMX_PORT")
 		os.Unsetenv("JBP_CONFIG_JMX")
+		os.Unsetenv("JBP_STRICT_CONFIG")
 	})
 
 	Describe("Detect", func() {
@@ -163,6 +164,27 @@ var _ = Describe("JMX", func() {
 				Expect(name).To(BeEmpty())
 			})
 		})
+
+		Context("with an unknown key in JBP_CONFIG_JMX", func() {
+			BeforeEach(func() {
+				os.Setenv("JBP_CONFIG_JMX", "{enabled: true, prot: 5001}")
+			})
+
+			It("warns and still detects when strict config is disabled", func() {
+				name, err := fw.Detect()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(name).To(Equal("JMX"))
+			})
+
+			It("fails detection when JBP_STRICT_CONFIG=true", func() {
+				os.Setenv("JBP_STRICT_CONFIG", "true")
+				_, err := fw.Detect()
+				Expect(err).To(HaveOccurred())
+				Expect(common.IsUnknownConfigKeysError(err)).To(BeTrue())
+				Expect(err.Error()).To(ContainSubstring("JBP_CONFIG_JMX"))
+				Expect(err.Error()).To(ContainSubstring("prot"))
+			})
+		})
 	})
 
 	Describe("Finalize", func() {
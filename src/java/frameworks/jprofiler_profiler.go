@@ -18,7 +18,8 @@ package frameworks
 import (
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
-	"os"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
 	"path/filepath"
 
 	"github.com/cloudfoundry/libbuildpack"
@@ -40,6 +41,9 @@ func (f *JProfilerProfilerFramework) Detect() (string, error) {
 	// Check for JBP_CONFIG_JPROFILER_PROFILER='{enabled: true}'
 	config, err := f.loadConfig()
 	if err != nil {
+		if common.IsUnknownConfigKeysError(err) {
+			return "", err
+		}
 		f.context.Log.Warning("Failed to load jprofile profiler config: %s", err.Error())
 		return "", nil // Don't fail the build
 	}
@@ -47,6 +51,7 @@ func (f *JProfilerProfilerFramework) Detect() (string, error) {
 		return "JProfiler Profiler", nil
 	}
 
+	f.context.Log.Debug("JProfiler Profiler: disabled (default), enable it with JBP_CONFIG_JPROFILER_PROFILER='{enabled: true}'")
 	return "", nil
 }
 
@@ -93,7 +98,6 @@ func (f *JProfilerProfilerFramework) Finalize() error {
 	f.context.Log.Debug("JProfiler Profiler Finalize phase")
 
 	// Get buildpack index for multi-buildpack support
-	depsIdx := f.context.Stager.DepsIdx()
 
 	installDir := filepath.Join(f.context.Stager.DepDir(), "jprofiler_profiler")
 
@@ -109,7 +113,7 @@ func (f *JProfilerProfilerFramework) Finalize() error {
 	if err != nil {
 		return fmt.Errorf("failed to compute relative path: %w", err)
 	}
-	runtimeAgentPath := filepath.Join(fmt.Sprintf("$DEPS_DIR/%s", depsIdx), relPath)
+	runtimeAgentPath := f.context.Droplet().Dep(relPath)
 
 	config, err := f.loadConfig()
 	if err != nil {
@@ -133,7 +137,7 @@ func (f *JProfilerProfilerFramework) Finalize() error {
 
 	f.context.Log.Info("JProfiler Profiler java agent options: %s", javaAgent)
 	// Write to .opts file using priority 30
-	if err := writeJavaOptsFile(f.context, 30, "jprofiler_profiler", javaAgent); err != nil {
+	if err := writeJavaOptsFile(f.context, javaopts.JProfiler, "jprofiler_profiler", javaAgent); err != nil {
 		return fmt.Errorf("failed to write java_opts file: %w", err)
 	}
 
@@ -148,17 +152,9 @@ func (f *JProfilerProfilerFramework) loadConfig() (*jProfilerConfig, error) {
 		NoWait:  true,
 		Port:    8849,
 	}
-	config := os.Getenv("JBP_CONFIG_JPROFILER_PROFILER")
-	if config != "" {
-		yamlHandler := common.YamlHandler{}
-		err := yamlHandler.ValidateFields([]byte(config), &jpConfig)
-		if err != nil {
-			f.context.Log.Warning("Unknown user config values: %s", err.Error())
-		}
-		// overlay JBP_CONFIG_JPROFILER_PROFILER over default values
-		if err = yamlHandler.Unmarshal([]byte(config), &jpConfig); err != nil {
-			return nil, fmt.Errorf("failed to parse JBP_CONFIG_JPROFILER_PROFILER: %w", err)
-		}
+	// overlay buildpack defaults and JBP_CONFIG_JPROFILER_PROFILER over default values
+	if err := config.Load(f.context.Log, "jprofiler_profiler", &jpConfig); err != nil {
+		return nil, err
 	}
 	return &jpConfig, nil
 }
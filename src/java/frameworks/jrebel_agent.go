@@ -3,14 +3,29 @@ package frameworks
 import (
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
 	"os"
 	"path/filepath"
 )
 
 // JRebelAgentFramework represents the JRebel Agent framework
 type JRebelAgentFramework struct {
-	context      *common.Context
-	agentLibPath string
+	context *common.Context
+}
+
+// jrebelAgent are the artifacts of the JRebel archive: the native agent is loaded by default, jrebel.jar where
+// there is no native agent for the architecture
+var jrebelAgent = dualAgent{
+	name:      "JRebel agent",
+	preferred: attachAgentpath,
+	layouts: []agentLayout{
+		// The ZIP contains a nested jrebel/ directory structure
+		{jar: "jrebel/jrebel.jar", native: map[string]string{"amd64": "jrebel/lib/libjrebel64.so"}},
+		// Flat paths of older versions
+		{jar: "jrebel.jar", native: map[string]string{"amd64": "lib/libjrebel64.so"}},
+		{native: map[string]string{"amd64": "libjrebel64.so"}},
+	},
 }
 
 // NewJRebelAgentFramework creates a new instance of JRebelAgentFramework
@@ -23,11 +38,15 @@ func (j *JRebelAgentFramework) Detect() (string, error) {
 	// Check if explicitly disabled via configuration
 	config, err := j.loadConfig()
 	if err != nil {
+		if common.IsUnknownConfigKeysError(err) {
+			return "", err
+		}
 		j.context.Log.Warning("Failed to load jrebel config: %s", err.Error())
 		return "", nil // Don't fail the build
 	}
 
 	if !config.isEnabled() {
+		j.context.Log.Debug("JRebel: disabled by configuration")
 		return "", nil
 	}
 	// Check for rebel-remote.xml configuration file in the app
@@ -44,6 +63,7 @@ func (j *JRebelAgentFramework) Detect() (string, error) {
 		return "jrebel", nil
 	}
 
+	j.context.Log.Debug("JRebel: no rebel-remote.xml or WEB-INF/rebel-remote.xml")
 	return "", nil
 }
 
@@ -66,15 +86,9 @@ func (j *JRebelAgentFramework) Supply() error {
 		return fmt.Errorf("failed to install jrebel agent: %w", err)
 	}
 
-	// Find libjrebel64.so in the extracted files
-	// The ZIP contains a nested jrebel/ directory structure
-	j.agentLibPath = filepath.Join(frameworkDir, "jrebel", "lib", "libjrebel64.so")
-	if _, err := os.Stat(j.agentLibPath); err != nil {
-		// Try flat path (older versions)
-		j.agentLibPath = filepath.Join(frameworkDir, "lib", "libjrebel64.so")
-		if _, err := os.Stat(j.agentLibPath); err != nil {
-			j.agentLibPath = filepath.Join(frameworkDir, "libjrebel64.so")
-		}
+	// Validate that the archive contains the agent the attach policy selects
+	if _, err := j.attachment(); err != nil {
+		return err
 	}
 
 	j.context.Log.Info("JRebel Agent installed successfully")
@@ -85,43 +99,24 @@ func (j *JRebelAgentFramework) Supply() error {
 func (j *JRebelAgentFramework) Finalize() error {
 	j.context.Log.Info("Configuring JRebel Agent")
 
-	// Reconstruct path if not set (separate finalize instance)
-	if j.agentLibPath == "" {
-		frameworkDir := filepath.Join(j.context.Stager.DepDir(), "jrebel")
-		// Try nested path first (current versions)
-		j.agentLibPath = filepath.Join(frameworkDir, "jrebel", "lib", "libjrebel64.so")
-		if _, err := os.Stat(j.agentLibPath); err != nil {
-			// Try flat path (older versions)
-			j.agentLibPath = filepath.Join(frameworkDir, "lib", "libjrebel64.so")
-			if _, err := os.Stat(j.agentLibPath); err != nil {
-				j.agentLibPath = filepath.Join(frameworkDir, "libjrebel64.so")
-			}
-		}
-	}
-
-	// Verify agent library exists at staging time
-	if _, err := os.Stat(j.agentLibPath); err != nil {
-		j.context.Log.Warning("JRebel agent library not found: %s", j.agentLibPath)
-		return nil
+	attachment, err := j.attachment()
+	if err != nil {
+		return err
 	}
 
-	// Get buildpack index for multi-buildpack support
-	depsIdx := j.context.Stager.DepsIdx()
-
 	// Convert staging path to runtime path using $DEPS_DIR
-	// Extract the relative path from the absolute staging path
 	frameworkDir := filepath.Join(j.context.Stager.DepDir(), "jrebel")
-	relPath, err := filepath.Rel(frameworkDir, j.agentLibPath)
+	relPath, err := filepath.Rel(frameworkDir, attachment.path)
 	if err != nil {
 		j.context.Log.Warning("Failed to determine relative path for JRebel agent: %s", err)
 		return nil
 	}
-	runtimeAgentPath := fmt.Sprintf("$DEPS_DIR/%s/jrebel/%s", depsIdx, relPath)
+	runtimeAgentPath := j.context.Droplet().Dep("jrebel", relPath)
 
 	// Write JAVA_OPTS to .opts file with priority 31 (Ruby buildpack line 65)
 	// This ensures JRebel runs AFTER Container Security Provider (priority 17)
-	javaOpts := fmt.Sprintf("-agentpath:%s", runtimeAgentPath)
-	if err := writeJavaOptsFile(j.context, 31, "jrebel", javaOpts); err != nil {
+	javaOpts := attachment.option(runtimeAgentPath)
+	if err := writeJavaOptsFile(j.context, javaopts.JRebel, "jrebel", javaOpts); err != nil {
 		return fmt.Errorf("failed to write java_opts file: %w", err)
 	}
 
@@ -129,28 +124,31 @@ func (j *JRebelAgentFramework) Finalize() error {
 	return nil
 }
 
+// attachment returns the installed artifact that attaches JRebel according to the attach configuration
+func (j *JRebelAgentFramework) attachment() (agentAttachment, error) {
+	config, err := j.loadConfig()
+	if err != nil {
+		return agentAttachment{}, err
+	}
+	return jrebelAgent.resolve(filepath.Join(j.context.Stager.DepDir(), "jrebel"), config.Attach)
+}
+
 func (j *JRebelAgentFramework) loadConfig() (*jrebelConfig, error) {
 	// initialize default values
 	jrConfig := jrebelConfig{
 		Enabled: true,
+		Attach:  attachAuto,
 	}
-	config := os.Getenv("JBP_CONFIG_JREBEL")
-	if config != "" {
-		yamlHandler := common.YamlHandler{}
-		err := yamlHandler.ValidateFields([]byte(config), &jrConfig)
-		if err != nil {
-			j.context.Log.Warning("Unknown user config values: %s", err.Error())
-		}
-		// overlay JBP_CONFIG_JREBEL over default values
-		if err = yamlHandler.Unmarshal([]byte(config), &jrConfig); err != nil {
-			return nil, fmt.Errorf("failed to parse JBP_CONFIG_JREBEL: %w", err)
-		}
+	// overlay buildpack defaults and JBP_CONFIG_JREBEL over default values
+	if err := config.Load(j.context.Log, "jrebel", &jrConfig); err != nil {
+		return nil, err
 	}
 	return &jrConfig, nil
 }
 
 type jrebelConfig struct {
-	Enabled bool `yaml:"enabled"`
+	Enabled bool   `yaml:"enabled"`
+	Attach  string `yaml:"attach"`
 }
 
 // isEnabled checks if jrebel is enabled
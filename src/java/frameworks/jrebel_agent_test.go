@@ -3,6 +3,7 @@ package frameworks_test
 import (
 	"os"
 	"path/filepath"
+	"runtime"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -185,12 +186,59 @@ var _ = Describe("JRebel Agent", func() {
 		})
 
 		Context("when the agent library is not present", func() {
-			It("succeeds without writing an opts file", func() {
-				Expect(fw.Finalize()).To(Succeed())
+			It("returns an error naming the expected files without writing an opts file", func() {
+				if runtime.GOARCH != "amd64" {
+					Skip("the JRebel native agent is only available for amd64")
+				}
+				err := fw.Finalize()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("JRebel agent not found: expected " +
+					filepath.Join(depsDir, "0", "jrebel", "jrebel", "lib", "libjrebel64.so") + " or " +
+					filepath.Join(depsDir, "0", "jrebel", "jrebel", "jrebel.jar")))
 				Expect(filepath.Join(depsDir, "0", "java_opts", "31_jrebel.opts")).NotTo(BeAnExistingFile())
 			})
 		})
 
+		Context("with attach: javaagent in JBP_CONFIG_JREBEL", func() {
+			BeforeEach(func() {
+				libPath := filepath.Join(depsDir, "0", "jrebel", "jrebel", "lib")
+				Expect(os.MkdirAll(libPath, 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(libPath, "libjrebel64.so"), []byte("fake"), 0644)).To(Succeed())
+				os.Setenv("JBP_CONFIG_JREBEL", "{attach: javaagent}")
+			})
+
+			It("attaches jrebel.jar with -javaagent", func() {
+				Expect(os.WriteFile(filepath.Join(depsDir, "0", "jrebel", "jrebel", "jrebel.jar"), []byte("fake"), 0644)).To(Succeed())
+
+				Expect(fw.Finalize()).To(Succeed())
+				content, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "31_jrebel.opts"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(ContainSubstring("-javaagent:$DEPS_DIR/0/jrebel/jrebel/jrebel.jar"))
+				Expect(string(content)).NotTo(ContainSubstring("-agentpath"))
+			})
+
+			It("returns an error naming jrebel.jar instead of falling back to the native agent", func() {
+				err := fw.Finalize()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("expected " + filepath.Join(depsDir, "0", "jrebel", "jrebel", "jrebel.jar")))
+			})
+		})
+
+		Context("with only jrebel.jar in the archive and the default attach mechanism", func() {
+			BeforeEach(func() {
+				jrebelDir := filepath.Join(depsDir, "0", "jrebel", "jrebel")
+				Expect(os.MkdirAll(jrebelDir, 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(jrebelDir, "jrebel.jar"), []byte("fake"), 0644)).To(Succeed())
+			})
+
+			It("falls back to -javaagent", func() {
+				Expect(fw.Finalize()).To(Succeed())
+				content, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "31_jrebel.opts"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(ContainSubstring("-javaagent:$DEPS_DIR/0/jrebel/jrebel/jrebel.jar"))
+			})
+		})
+
 		Context("opts file naming and priority", func() {
 			BeforeEach(func() {
 				libPath := filepath.Join(depsDir, "0", "jrebel", "lib")
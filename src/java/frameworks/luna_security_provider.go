@@ -7,6 +7,9 @@ import (
 	"strings"
 
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/writable"
 	"github.com/cloudfoundry/java-buildpack/src/java/resources"
 )
 
@@ -38,6 +41,7 @@ func (l *LunaSecurityProviderFramework) Detect() (string, error) {
 		return "Luna Security Provider", nil
 	}
 
+	l.context.Log.Debug("Luna Security Provider: no luna service bound")
 	return "", nil
 }
 
@@ -67,16 +71,17 @@ func (l *LunaSecurityProviderFramework) Supply() error {
 	lunaProviderJar := filepath.Join(lunaDir, "jsp", "LunaProvider.jar")
 	lunaAPIso := filepath.Join(lunaDir, "jsp", "64", "libLunaAPI.so")
 
+	var causes []error
 	if err := l.createSymlink(lunaProviderJar, filepath.Join(extDir, "LunaProvider.jar")); err != nil {
-		l.context.Log.Warning("Failed to create LunaProvider.jar symlink: %s", err.Error())
+		causes = append(causes, fmt.Errorf("failed to create LunaProvider.jar symlink: %w", err))
 	}
 	if err := l.createSymlink(lunaAPIso, filepath.Join(extDir, "libLunaAPI.so")); err != nil {
-		l.context.Log.Warning("Failed to create libLunaAPI.so symlink: %s", err.Error())
+		causes = append(causes, fmt.Errorf("failed to create libLunaAPI.so symlink: %w", err))
 	}
 
 	// Install default configuration from embedded resources
 	if err := l.installDefaultConfiguration(lunaDir); err != nil {
-		l.context.Log.Warning("Could not install default Luna configuration: %s", err.Error())
+		causes = append(causes, fmt.Errorf("could not install default Luna configuration: %w", err))
 	}
 
 	// Write credentials from VCAP_SERVICES
@@ -85,9 +90,20 @@ func (l *LunaSecurityProviderFramework) Supply() error {
 	}
 
 	l.context.Log.Debug("Installed Luna Security Provider version %s", dep.Version)
-	return nil
+	return incomplete(causes...)
 }
 
+// lunaRelocatedHtlScript points ChrystokiConfigurationPath to a copy of Chrystoki.conf that names the relocated HTL
+// directory, if the droplet is read-only and the directory was relocated to $TMPDIR
+const lunaRelocatedHtlScript = `if [ "%[1]s" != "%[2]s" ] && [ -f "$ChrystokiConfigurationPath/Chrystoki.conf" ]; then
+  luna_config="${TMPDIR:-/tmp}/java-buildpack/luna_security_provider"
+  mkdir -p "$luna_config"
+  sed "s|^\\([[:space:]]*HtlDir[[:space:]]*=\\).*;|\\1 %[1]s;|" "$ChrystokiConfigurationPath/Chrystoki.conf" > "$luna_config/Chrystoki.conf"
+  export ChrystokiConfigurationPath="$luna_config"
+  unset luna_config
+fi
+`
+
 // installDefaultConfiguration installs the default Chrystoki.conf from embedded resources
 func (l *LunaSecurityProviderFramework) installDefaultConfiguration(lunaDir string) error {
 	configPath := filepath.Join(lunaDir, "Chrystoki.conf")
@@ -120,11 +136,18 @@ func (l *LunaSecurityProviderFramework) Finalize() error {
 	// Set ChrystokiConfigurationPath and (for Java 9+) LD_LIBRARY_PATH via profile.d.
 	// $DEPS_DIR is a runtime variable — WriteProfileD ensures it is expanded at
 	// container startup rather than stored as a literal string.
-	depsIdx := l.context.Stager.DepsIdx()
-	lunaRuntimeDir := fmt.Sprintf("$DEPS_DIR/%s/luna_security_provider", depsIdx)
+	lunaRuntimeDir := l.context.Droplet().Dep("luna_security_provider")
 
 	profileScript := fmt.Sprintf("export ChrystokiConfigurationPath=%s\n", lunaRuntimeDir)
 
+	// The client writes the host trust links of HtlDir at runtime
+	htlDir := filepath.Join(l.context.Stager.DepDir(), "luna_security_provider", "htl")
+	htlVar, err := writable.Register(l.context, "luna_security_provider", htlDir, writable.Link)
+	if err != nil {
+		return fmt.Errorf("failed to register HTL directory: %w", err)
+	}
+	profileScript += fmt.Sprintf(lunaRelocatedHtlScript, htlVar, l.context.Droplet().Dep("luna_security_provider", "htl"))
+
 	// Detect Java version to determine extension mechanism
 	javaVersion, err := common.GetJavaMajorVersion()
 	if err != nil {
@@ -135,8 +158,8 @@ func (l *LunaSecurityProviderFramework) Finalize() error {
 	var javaOpts string
 	if javaVersion >= 9 {
 		// Java 9+: Add to bootstrap classpath and set LD_LIBRARY_PATH
-		lunaProviderJar := fmt.Sprintf("$DEPS_DIR/%s/luna_security_provider/jsp/LunaProvider.jar", depsIdx)
-		ldLibPath := fmt.Sprintf("$DEPS_DIR/%s/luna_security_provider/jsp/64", depsIdx)
+		lunaProviderJar := l.context.Droplet().Dep("luna_security_provider", "jsp", "LunaProvider.jar")
+		ldLibPath := l.context.Droplet().Dep("luna_security_provider", "jsp", "64")
 
 		// Build JAVA_OPTS with runtime path
 		javaOpts = fmt.Sprintf("-Xbootclasspath/a:%s", lunaProviderJar)
@@ -145,7 +168,7 @@ func (l *LunaSecurityProviderFramework) Finalize() error {
 		profileScript += fmt.Sprintf("export LD_LIBRARY_PATH=%s${LD_LIBRARY_PATH:+:$LD_LIBRARY_PATH}\n", ldLibPath)
 	} else {
 		// Java 8: Use extension directory
-		extDir := fmt.Sprintf("$DEPS_DIR/%s/luna_security_provider/ext", depsIdx)
+		extDir := l.context.Droplet().Dep("luna_security_provider", "ext")
 		javaOpts = fmt.Sprintf("-Djava.ext.dirs=%s:$JAVA_HOME/jre/lib/ext:$JAVA_HOME/lib/ext", extDir)
 	}
 
@@ -154,7 +177,7 @@ func (l *LunaSecurityProviderFramework) Finalize() error {
 	}
 
 	// Write to .opts file using priority 32
-	if err := writeJavaOptsFile(l.context, 32, "luna_security_provider", javaOpts); err != nil {
+	if err := writeJavaOptsFile(l.context, javaopts.LunaSecurityProvider, "luna_security_provider", javaOpts); err != nil {
 		return fmt.Errorf("failed to write java_opts file: %w", err)
 	}
 
@@ -173,8 +196,8 @@ func (l *LunaSecurityProviderFramework) writeCredentials() error {
 	// Find Luna service (try multiple lookup patterns)
 	var credentials map[string]interface{}
 
-	for label, services := range vcapServices {
-		for _, service := range services {
+	for _, label := range sortedLabels(vcapServices) {
+		for _, service := range vcapServices[label] {
 			if common.ContainsIgnoreCase(service.Name, "luna") ||
 				common.ContainsIgnoreCase(label, "luna") {
 				credentials = service.Credentials
@@ -453,17 +476,9 @@ func (l *LunaSecurityProviderFramework) loadConfig() (*lunaSecurityProviderConfi
 		LoggingEnabled:      false,
 		TCPKeepAliveEnabled: false,
 	}
-	config := os.Getenv("JBP_CONFIG_LUNA_SECURITY_PROVIDER")
-	if config != "" {
-		yamlHandler := common.YamlHandler{}
-		err := yamlHandler.ValidateFields([]byte(config), &lspConfig)
-		if err != nil {
-			l.context.Log.Warning("Unknown user config values: %s", err.Error())
-		}
-		// overlay JBP_CONFIG_LUNA_SECURITY_PROVIDER over default values
-		if err = yamlHandler.Unmarshal([]byte(config), &lspConfig); err != nil {
-			return nil, fmt.Errorf("failed to parse JBP_CONFIG_LUNA_SECURITY_PROVIDER: %w", err)
-		}
+	// overlay buildpack defaults and JBP_CONFIG_LUNA_SECURITY_PROVIDER over default values
+	if err := config.Load(l.context.Log, "luna_security_provider", &lspConfig); err != nil {
+		return nil, err
 	}
 	return &lspConfig, nil
 }
@@ -6,9 +6,11 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	"github.com/cloudfoundry/java-buildpack/src/internal/mocks"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
 	"github.com/cloudfoundry/java-buildpack/src/java/frameworks"
 	"github.com/cloudfoundry/java-buildpack/src/java/resources"
@@ -57,6 +59,31 @@ func installLunaProvider(depsDir string) string {
 	return lunaDir
 }
 
+// expectGoldenFile compares the file at path to testdata/security_providers/<golden> after applying the old, new
+// replacement pairs, which keep temporary staging directories and server URLs out of golden files.
+// UPDATE_GOLDEN=true rewrites the golden file from the actual output instead.
+func expectGoldenFile(path, golden string, replacements ...string) {
+	actual, err := os.ReadFile(path)
+	Expect(err).NotTo(HaveOccurred())
+	normalized := strings.NewReplacer(replacements...).Replace(string(actual))
+
+	goldenPath := filepath.Join("testdata", "security_providers", golden)
+	if os.Getenv("UPDATE_GOLDEN") == "true" {
+		Expect(os.WriteFile(goldenPath, []byte(normalized), 0644)).To(Succeed())
+	}
+
+	expected, err := os.ReadFile(goldenPath)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(normalized).To(Equal(string(expected)), "%s differs from %s", filepath.Base(path), goldenPath)
+}
+
+// readFixture returns the content of testdata/security_providers/<name>
+func readFixture(name string) string {
+	content, err := os.ReadFile(filepath.Join("testdata", "security_providers", name))
+	Expect(err).NotTo(HaveOccurred())
+	return string(content)
+}
+
 var _ = Describe("LunaSecurityProvider", func() {
 	var embeddedPath string
 
@@ -564,4 +591,90 @@ var _ = Describe("LunaSecurityProvider", func() {
 			})
 		})
 	})
+
+	Describe("staging against a fake HSM", func() {
+		var (
+			fw       *frameworks.LunaSecurityProviderFramework
+			mockCtrl *gomock.Controller
+			buildDir string
+			cacheDir string
+			depsDir  string
+			javaHome string
+			depDir   string
+		)
+
+		BeforeEach(func() {
+			var err error
+			buildDir, err = os.MkdirTemp("", "luna-build")
+			Expect(err).NotTo(HaveOccurred())
+			cacheDir, err = os.MkdirTemp("", "luna-cache")
+			Expect(err).NotTo(HaveOccurred())
+			depsDir, err = os.MkdirTemp("", "luna-deps")
+			Expect(err).NotTo(HaveOccurred())
+			javaHome, err = os.MkdirTemp("", "java-home")
+			Expect(err).NotTo(HaveOccurred())
+			depDir = filepath.Join(depsDir, "0")
+			Expect(os.MkdirAll(depDir, 0755)).To(Succeed())
+			writeJavaReleaseFile(javaHome, "17.0.13")
+			os.Setenv("JAVA_HOME", javaHome)
+			os.Setenv("VCAP_SERVICES", readFixture("luna/vcap_services.json"))
+
+			// The installer lays out a stub provider in place of the Luna client tarball
+			dep := libbuildpack.Dependency{Name: "luna-security-provider", Version: "7.4.0"}
+			mockCtrl = gomock.NewController(GinkgoT())
+			manifest := mocks.NewMockManifest(mockCtrl)
+			manifest.EXPECT().DefaultVersion("luna-security-provider").Return(dep, nil).AnyTimes()
+			installer := mocks.NewMockInstaller(mockCtrl)
+			installer.EXPECT().InstallDependency(dep, filepath.Join(depDir, "luna_security_provider")).DoAndReturn(
+				func(_ libbuildpack.Dependency, _ string) error {
+					installLunaProvider(depsDir)
+					return nil
+				})
+
+			logger := libbuildpack.NewLogger(GinkgoWriter)
+			fw = frameworks.NewLunaSecurityProviderFramework(&common.Context{
+				Stager:    libbuildpack.NewStager([]string{buildDir, cacheDir, depsDir, "0"}, logger, &libbuildpack.Manifest{}),
+				Manifest:  manifest,
+				Installer: installer,
+				Log:       logger,
+				Command:   &libbuildpack.Command{},
+			})
+		})
+
+		AfterEach(func() {
+			mockCtrl.Finish()
+			os.RemoveAll(buildDir)
+			os.RemoveAll(cacheDir)
+			os.RemoveAll(depsDir)
+			os.RemoveAll(javaHome)
+			os.Unsetenv("VCAP_SERVICES")
+			os.Unsetenv("JBP_CONFIG_LUNA_SECURITY_PROVIDER")
+			os.Unsetenv("JAVA_HOME")
+		})
+
+		It("generates the HA client configuration", func() {
+			Expect(fw.Supply()).To(Succeed())
+			Expect(fw.Finalize()).To(Succeed())
+
+			lunaDir := filepath.Join(depDir, "luna_security_provider")
+			expectGoldenFile(filepath.Join(lunaDir, "Chrystoki.conf"), "luna/Chrystoki.conf", depDir, "$DIR")
+			expectGoldenFile(filepath.Join(lunaDir, "client-certificate.pem"), "luna/client-certificate.pem", depDir, "$DIR")
+			expectGoldenFile(filepath.Join(lunaDir, "server-certificates.pem"), "luna/server-certificates.pem", depDir, "$DIR")
+			expectGoldenFile(filepath.Join(depDir, "java_opts", "32_luna_security_provider.opts"), "luna/32_luna_security_provider.opts", depDir, "$DIR")
+			expectGoldenFile(filepath.Join(depDir, "profile.d", "luna_security_provider.sh"), "luna/luna_security_provider.sh", depDir, "$DIR")
+
+			info, err := os.Stat(filepath.Join(lunaDir, "client-private-key.pem"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+			Expect(os.Readlink(filepath.Join(lunaDir, "ext", "LunaProvider.jar"))).To(Equal("../jsp/LunaProvider.jar"))
+		})
+
+		It("generates client logging and TCP keep-alive settings", func() {
+			os.Setenv("JBP_CONFIG_LUNA_SECURITY_PROVIDER", "{logging_enabled: true, tcp_keep_alive_enabled: true, ha_logging_enabled: false}")
+
+			Expect(fw.Supply()).To(Succeed())
+
+			expectGoldenFile(filepath.Join(depDir, "luna_security_provider", "Chrystoki.conf"), "luna/Chrystoki-logging.conf", depDir, "$DIR")
+		})
+	})
 })
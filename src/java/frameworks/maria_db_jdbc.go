@@ -18,10 +18,17 @@ package frameworks
 import (
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/applibs"
 	"path/filepath"
-	"strings"
 )
 
+// mariaDBDriverPatterns match the MySQL and MariaDB JDBC drivers an application may already contain
+var mariaDBDriverPatterns = []string{
+	"mariadb-java-client*.jar",
+	"mysql-connector-j*.jar",
+	"aws-mysql-jdbc*.jar",
+}
+
 // MariaDBJDBCFramework represents the MariaDB JDBC framework
 type MariaDBJDBCFramework struct {
 	context *common.Context
@@ -42,8 +49,8 @@ func (f *MariaDBJDBCFramework) Detect() (string, error) {
 	}
 
 	// Check if driver already exists in app
-	if f.hasExistingDriver() {
-		f.context.Log.Debug("MariaDB JDBC: Driver already present in application")
+	if driver, ok := applibs.Find(f.context.Stager.BuildDir(), mariaDBDriverPatterns...); ok {
+		f.context.Log.Info("MariaDB JDBC: driver %s found in application, not installing the buildpack's driver", driver)
 		return "", nil
 	}
 
@@ -88,8 +95,7 @@ func (f *MariaDBJDBCFramework) Finalize() error {
 
 	f.context.Log.BeginStep("Configuring MariaDB JDBC driver")
 
-	depsIdx := f.context.Stager.DepsIdx()
-	runtimePath := fmt.Sprintf("$DEPS_DIR/%s/mariadb_jdbc/%s", depsIdx, filepath.Base(f.jarPath))
+	runtimePath := f.context.Droplet().Dep("mariadb_jdbc", filepath.Base(f.jarPath))
 
 	profileScript := fmt.Sprintf("export CLASSPATH=\"%s${CLASSPATH:+:$CLASSPATH}\"\n", runtimePath)
 	if err := f.context.Stager.WriteProfileD("mariadb_jdbc.sh", profileScript); err != nil {
@@ -147,52 +153,6 @@ func (f *MariaDBJDBCFramework) hasMariaDBService() bool {
 	return false
 }
 
-// hasExistingDriver checks if a MySQL/MariaDB JDBC driver already exists in the app
-func (f *MariaDBJDBCFramework) hasExistingDriver() bool {
-	// Check for various MySQL/MariaDB driver patterns
-	driverPatterns := []string{
-		"mariadb-java-client*.jar",
-		"mysql-connector-j*.jar",
-		"aws-mysql-jdbc*.jar",
-	}
-
-	for _, pattern := range driverPatterns {
-		matches, err := filepath.Glob(filepath.Join(f.context.Stager.BuildDir(), "**", pattern))
-		if err != nil {
-			f.context.Log.Debug("Error globbing for %s: %s", pattern, err)
-			continue
-		}
-		if len(matches) > 0 {
-			f.context.Log.Debug("Found existing driver: %s", matches[0])
-			return true
-		}
-	}
-
-	// Also check common locations
-	commonLocations := []string{
-		filepath.Join(f.context.Stager.BuildDir(), "WEB-INF", "lib"),
-		filepath.Join(f.context.Stager.BuildDir(), "lib"),
-		filepath.Join(f.context.Stager.BuildDir(), "BOOT-INF", "lib"),
-	}
-
-	for _, location := range commonLocations {
-		for _, pattern := range driverPatterns {
-			// Use simpler pattern for filepath.Glob (no ** recursive)
-			simplePattern := strings.Replace(pattern, "*", "", 1) // Remove first *
-			matches, err := filepath.Glob(filepath.Join(location, simplePattern+"*"))
-			if err != nil {
-				continue
-			}
-			if len(matches) > 0 {
-				f.context.Log.Debug("Found existing driver in %s: %s", location, matches[0])
-				return true
-			}
-		}
-	}
-
-	return false
-}
-
 func (f *MariaDBJDBCFramework) constructJarPath(mariadbDir string) error {
 	jarPattern := filepath.Join(mariadbDir, "mariadb-jdbc-*.jar")
 	matches, err := filepath.Glob(jarPattern)
@@ -177,6 +177,21 @@ var _ = Describe("MariaDBJDBC", func() {
 				Expect(name).To(BeEmpty())
 			})
 		})
+
+		Context("with a mysql service but mysql-connector-j already in BOOT-INF/lib", func() {
+			BeforeEach(func() {
+				os.Setenv("VCAP_SERVICES", vcapServices("mysql", "my-mysql", nil))
+				libDir := filepath.Join(buildDir, "BOOT-INF", "lib")
+				Expect(os.MkdirAll(libDir, 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(libDir, "mysql-connector-j-8.4.0.jar"), []byte("fake"), 0644)).To(Succeed())
+			})
+
+			It("returns empty string (driver already present)", func() {
+				name, err := fw.Detect()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(name).To(BeEmpty())
+			})
+		})
 	})
 
 	Describe("Finalize", func() {
@@ -3,7 +3,7 @@ package frameworks
 import (
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
-	"os"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
 	"path/filepath"
 	"strings"
 )
@@ -25,6 +25,9 @@ func (m *MetricWriterFramework) Detect() (string, error) {
 	// Check if explicitly enabled via configuration
 	config, err := m.loadConfig()
 	if err != nil {
+		if common.IsUnknownConfigKeysError(err) {
+			return "", err
+		}
 		m.context.Log.Warning("Failed to load metric writer config: %s", err.Error())
 		return "", nil // Don't fail the build
 	}
@@ -45,39 +48,11 @@ func (m *MetricWriterFramework) Detect() (string, error) {
 
 // hasMicrometer checks if the application uses Micrometer
 func (m *MetricWriterFramework) hasMicrometer() bool {
-	buildDir := m.context.Stager.BuildDir()
-
-	// Check common locations for micrometer-core JAR
-	libDirs := []string{
-		filepath.Join(buildDir, "lib"),
-		filepath.Join(buildDir, "WEB-INF", "lib"),
-		filepath.Join(buildDir, "BOOT-INF", "lib"),
-	}
-
-	for _, libDir := range libDirs {
-		if _, err := os.Stat(libDir); err != nil {
-			continue
-		}
-
-		entries, err := os.ReadDir(libDir)
-		if err != nil {
-			continue
-		}
-
-		for _, entry := range entries {
-			if entry.IsDir() {
-				continue
-			}
-			name := entry.Name()
-			// Look for micrometer-core-*.jar files
-			if strings.HasPrefix(name, "micrometer-core-") && strings.HasSuffix(name, ".jar") {
-				m.context.Log.Debug("Found Micrometer in %s: %s", libDir, name)
-				return true
-			}
-		}
+	jar, ok := m.context.Classpath().Find("micrometer-core-*.jar")
+	if ok {
+		m.context.Log.Debug("Found Micrometer: %s", jar)
 	}
-
-	return false
+	return ok
 }
 
 // Supply installs the Metric Writer library
@@ -113,18 +88,17 @@ func (m *MetricWriterFramework) Finalize() error {
 	}
 
 	// Get buildpack index for multi-buildpack support
-	depsIdx := m.context.Stager.DepsIdx()
 
 	// Convert staging path to runtime path for CLASSPATH
 	relPath := filepath.Base(matches[0])
-	runtimePath := fmt.Sprintf("$DEPS_DIR/%s/metric_writer/%s", depsIdx, relPath)
+	runtimePath := m.context.Droplet().Dep("metric_writer", relPath)
 
 	// Build CloudFoundry tag environment variables
 	cfTags := m.buildCFTagEnvVars()
 
 	// Write profile.d script to add Metric Writer JAR to classpath and set CF tags
 	profileScript := fmt.Sprintf(`# Metric Writer Framework - CloudFoundry Micrometer Tags
-export CLASSPATH="%s:${CLASSPATH:-}"
+export CLASSPATH="%s${CLASSPATH:+:$CLASSPATH}"
 
 # CloudFoundry-specific Micrometer tags
 %s
@@ -177,17 +151,9 @@ func (m *MetricWriterFramework) loadConfig() (*metricWriterConfig, error) {
 	mwConfig := metricWriterConfig{
 		Enabled: false,
 	}
-	config := os.Getenv("JBP_CONFIG_METRIC_WRITER")
-	if config != "" {
-		yamlHandler := common.YamlHandler{}
-		err := yamlHandler.ValidateFields([]byte(config), &mwConfig)
-		if err != nil {
-			m.context.Log.Warning("Unknown user config values: %s", err.Error())
-		}
-		// overlay JBP_CONFIG_METRIC_WRITER over default values
-		if err = yamlHandler.Unmarshal([]byte(config), &mwConfig); err != nil {
-			return nil, fmt.Errorf("failed to parse JBP_CONFIG_METRIC_WRITER: %w", err)
-		}
+	// overlay buildpack defaults and JBP_CONFIG_METRIC_WRITER over default values
+	if err := config.Load(m.context.Log, "metric_writer", &mwConfig); err != nil {
+		return nil, err
 	}
 	return &mwConfig, nil
 }
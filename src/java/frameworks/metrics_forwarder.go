@@ -0,0 +1,302 @@
+package frameworks
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/appname"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
+)
+
+const metricsForwarderDependencyName = "metrics-forwarder-agent"
+
+// metricsForwarderServiceFilters match the label, name or tags of the services the agent forwards metrics to: the
+// Metrics Forwarder service of the platform and StatsD servers
+var metricsForwarderServiceFilters = []string{"metrics-forwarder", "statsd"}
+
+// metricsForwarderDefaultPort is the port of a StatsD service that has a host credential but no port
+const metricsForwarderDefaultPort = "8125"
+
+// metricsForwarderPrefixInvalid matches the characters a metric name prefix may not contain
+var metricsForwarderPrefixInvalid = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// metricsForwarderConfig is the JBP_CONFIG_METRICS_FORWARDER configuration, e.g.
+// '{prefix: orders, instance_tags: false, tags: {team: payments}}'
+type metricsForwarderConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Prefix is prepended to the name of every metric. It defaults to the prefix credential of the service or, if
+	// the service has none, the application name of JBP_CONFIG_APPLICATION_NAME.
+	Prefix string `yaml:"prefix"`
+	// InstanceTags tags every metric with the index and GUID of the application instance that reports it
+	InstanceTags bool `yaml:"instance_tags"`
+	// Tags are added to every metric and override the tags of the application, space and org
+	Tags map[string]string `yaml:"tags"`
+}
+
+// MetricsForwarderFramework forwards the JVM and application metrics of the application to a bound Metrics
+// Forwarder or StatsD service. Unlike the Metric Writer, which tags the Micrometer metrics the application publishes
+// itself, it installs a metrics javaagent, so the application needs no metrics library.
+type MetricsForwarderFramework struct {
+	context *common.Context
+}
+
+// NewMetricsForwarderFramework creates a new Metrics Forwarder framework instance
+func NewMetricsForwarderFramework(ctx *common.Context) *MetricsForwarderFramework {
+	return &MetricsForwarderFramework{context: ctx}
+}
+
+// Detect checks if a Metrics Forwarder or StatsD service with an endpoint is bound
+func (m *MetricsForwarderFramework) Detect() (string, error) {
+	if _, ok := m.findService(); !ok {
+		m.context.Log.Debug("Metrics Forwarder: no %s service with an endpoint bound",
+			strings.Join(metricsForwarderServiceFilters, " or "))
+		return "", nil
+	}
+
+	cfg, err := m.loadConfig()
+	if err != nil {
+		return "", err
+	}
+	if !cfg.Enabled {
+		m.context.Log.Debug("Metrics Forwarder: disabled by configuration")
+		return "", nil
+	}
+
+	if _, err := m.context.Manifest.DefaultVersion(metricsForwarderDependencyName); err != nil {
+		m.context.Log.Warning("A metrics service is bound but %s is not in the buildpack manifest; metrics are not forwarded",
+			metricsForwarderDependencyName)
+		return "", nil
+	}
+
+	m.context.Log.Debug("Metrics Forwarder framework detected")
+	return "Metrics Forwarder", nil
+}
+
+// Supply installs the metrics javaagent
+func (m *MetricsForwarderFramework) Supply() error {
+	dep, err := m.context.Manifest.DefaultVersion(metricsForwarderDependencyName)
+	if err != nil {
+		return fmt.Errorf("unable to determine Metrics Forwarder agent version: %w", err)
+	}
+
+	if err := m.context.Installer.InstallDependency(dep, m.agentDir()); err != nil {
+		return fmt.Errorf("failed to install Metrics Forwarder agent: %w", err)
+	}
+
+	m.context.Log.Info("Metrics Forwarder agent %s installed", dep.Version)
+	return nil
+}
+
+// Finalize adds the agent to JAVA_OPTS and exports the endpoint, token, prefix and tags it forwards the metrics with
+// through a profile.d script, so that the token does not appear on the command line
+func (m *MetricsForwarderFramework) Finalize() error {
+	m.context.Log.BeginStep("Configuring Metrics Forwarder agent")
+
+	service, ok := m.findService()
+	if !ok {
+		return fmt.Errorf("no %s service with an endpoint bound", strings.Join(metricsForwarderServiceFilters, " or "))
+	}
+	cfg, err := m.loadConfig()
+	if err != nil {
+		return err
+	}
+
+	matches, err := filepath.Glob(filepath.Join(m.agentDir(), "*.jar"))
+	if err != nil || len(matches) == 0 {
+		return fmt.Errorf("no Metrics Forwarder agent JAR found in %s", m.agentDir())
+	}
+	runtimeJarPath := m.context.Droplet().Dep("metrics_forwarder", filepath.Base(matches[0]))
+
+	if err := writeJavaOptsFile(m.context, javaopts.MetricsForwarder, "metrics_forwarder", "-javaagent:"+runtimeJarPath); err != nil {
+		return fmt.Errorf("failed to write JAVA_OPTS for Metrics Forwarder agent: %w", err)
+	}
+
+	exports, err := m.environment(service, cfg)
+	if err != nil {
+		return err
+	}
+	if err := m.context.Stager.WriteProfileD("metrics_forwarder.sh", strings.Join(exports, "\n")+"\n"); err != nil {
+		return fmt.Errorf("failed to write metrics_forwarder.sh profile.d script: %w", err)
+	}
+
+	m.context.Log.Debug("Metrics Forwarder agent configured for service %s", service.Name)
+	return nil
+}
+
+// DependencyIdentifier returns the manifest dependency of the metrics javaagent
+func (m *MetricsForwarderFramework) DependencyIdentifier() string {
+	return metricsForwarderDependencyName
+}
+
+// Endpoints returns the endpoint of the bound service if it is an HTTP endpoint. StatsD endpoints are UDP and
+// cannot be probed.
+func (m *MetricsForwarderFramework) Endpoints() []string {
+	service, ok := m.findService()
+	if !ok {
+		return nil
+	}
+
+	endpoint := metricsForwarderEndpoint(service.Credentials)
+	if u, err := url.Parse(endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil
+	}
+	return []string{endpoint}
+}
+
+// environment returns the profile.d exports that configure the agent:
+//   - METRICS_FORWARDER_ENDPOINT with the endpoint, uri or host and port credential of the service
+//   - METRICS_FORWARDER_TOKEN with the access_key or token credential, if the service has one
+//   - METRICS_FORWARDER_PREFIX with the configured prefix, the prefix credential or the application name
+//   - METRICS_FORWARDER_TAGS with the application, space and org, the resource tags, the configured tags
+//     and, at runtime, the instance index and GUID, as comma-separated key:value pairs
+//
+// Variables set in the application's environment take precedence, and tags of its METRICS_FORWARDER_TAGS are
+// appended so that they override the generated ones.
+func (m *MetricsForwarderFramework) environment(service VCAPService, cfg *metricsForwarderConfig) ([]string, error) {
+	var app struct {
+		ApplicationName  string `json:"application_name"`
+		SpaceName        string `json:"space_name"`
+		OrganizationName string `json:"organization_name"`
+	}
+	_ = json.Unmarshal([]byte(os.Getenv("VCAP_APPLICATION")), &app)
+
+	prefix := cfg.Prefix
+	if prefix == "" {
+		prefix = credentialString(service.Credentials, "prefix")
+	}
+	if prefix == "" {
+		name, err := appname.Name(m.context.Log, appname.App)
+		if err != nil {
+			return nil, err
+		}
+		prefix = name
+	}
+	prefix = strings.Trim(metricsForwarderPrefixInvalid.ReplaceAllString(prefix, "_"), "._")
+
+	variables := map[string]string{
+		"METRICS_FORWARDER_ENDPOINT": metricsForwarderEndpoint(service.Credentials),
+		"METRICS_FORWARDER_PREFIX":   prefix,
+	}
+	for _, key := range []string{"access_key", "token"} {
+		if token := credentialString(service.Credentials, key); token != "" {
+			variables["METRICS_FORWARDER_TOKEN"] = token
+			break
+		}
+	}
+
+	names := make([]string, 0, len(variables))
+	for name, value := range variables {
+		if value != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	exports := make([]string, 0, len(names)+1)
+	for _, name := range names {
+		exports = append(exports, fmt.Sprintf(`[ -n "${%s:-}" ] || export %s=%s`, name, name, shellEscape(variables[name])))
+	}
+
+	tags := map[string]string{
+		"application": app.ApplicationName,
+		"space":       app.SpaceName,
+		"org":         app.OrganizationName,
+	}
+	resourceTags, err := resourceTags(m.context)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range resourceTags {
+		tags[tag.name] = tag.value
+	}
+	for key, value := range cfg.Tags {
+		tags[key] = value
+	}
+
+	keys := make([]string, 0, len(tags))
+	for key, value := range tags {
+		if value != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+":"+tags[key])
+	}
+
+	var segments []string
+	if len(pairs) > 0 {
+		segments = append(segments, shellEscape(strings.Join(pairs, ",")))
+	}
+	if cfg.InstanceTags {
+		segments = append(segments, `"instance_index:${CF_INSTANCE_INDEX:-0},instance_id:${CF_INSTANCE_GUID:-}"`)
+	}
+	applicationTags := `"${METRICS_FORWARDER_TAGS:-}"`
+	if len(segments) > 0 {
+		applicationTags = `"${METRICS_FORWARDER_TAGS:+,$METRICS_FORWARDER_TAGS}"`
+	}
+	exports = append(exports, "export METRICS_FORWARDER_TAGS="+strings.Join(segments, ",")+applicationTags)
+	return exports, nil
+}
+
+func (m *MetricsForwarderFramework) agentDir() string {
+	return filepath.Join(m.context.Stager.DepDir(), "metrics_forwarder")
+}
+
+func (m *MetricsForwarderFramework) loadConfig() (*metricsForwarderConfig, error) {
+	cfg := metricsForwarderConfig{Enabled: true, InstanceTags: true}
+	if err := config.Load(m.context.Log, "metrics_forwarder", &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// findService returns the first service, in sorted label order, whose label, name or tags contain
+// "metrics-forwarder" or "statsd" and that has an endpoint
+func (m *MetricsForwarderFramework) findService() (VCAPService, bool) {
+	vcapServices, err := GetVCAPServices()
+	if err != nil {
+		m.context.Log.Warning("Failed to parse VCAP_SERVICES: %s", err.Error())
+		return VCAPService{}, false
+	}
+
+	for _, service := range vcapServices.Sorted() {
+		for _, filter := range metricsForwarderServiceFilters {
+			if (common.ContainsIgnoreCase(service.Label, filter) ||
+				common.ContainsIgnoreCase(service.Name, filter) ||
+				service.HasTag(filter)) &&
+				metricsForwarderEndpoint(service.Credentials) != "" {
+				return service, true
+			}
+		}
+	}
+	return VCAPService{}, false
+}
+
+// metricsForwarderEndpoint returns the endpoint or uri credential or, for StatsD services that name a host, the
+// host and port
+func metricsForwarderEndpoint(credentials map[string]interface{}) string {
+	for _, key := range []string{"endpoint", "uri"} {
+		if endpoint := credentialString(credentials, key); endpoint != "" {
+			return endpoint
+		}
+	}
+	for _, key := range []string{"host", "hostname"} {
+		if host := credentialString(credentials, key); host != "" {
+			port := credentialString(credentials, "port")
+			if port == "" {
+				port = metricsForwarderDefaultPort
+			}
+			return host + ":" + port
+		}
+	}
+	return ""
+}
@@ -0,0 +1,209 @@
+package frameworks_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/frameworks"
+	"github.com/cloudfoundry/libbuildpack"
+)
+
+var _ = Describe("MetricsForwarder", func() {
+	const forwarderService = `{"metrics-forwarder":[{"name":"metrics","label":"metrics-forwarder","tags":[],` +
+		`"credentials":{"endpoint":"https://metrics-forwarder.example.com/v1/metrics","access_key":"s3cr3t"}}]}`
+
+	var (
+		fw           *frameworks.MetricsForwarderFramework
+		buildDir     string
+		cacheDir     string
+		depsDir      string
+		buildpackDir string
+	)
+
+	// newContext returns a context whose manifest lists the given dependencies
+	newContext := func(defaultVersions, dependencies string) *common.Context {
+		manifestYml := "---\nlanguage: java\ndefault_versions:\n" + defaultVersions + "dependencies:\n" + dependencies
+		Expect(os.WriteFile(filepath.Join(buildpackDir, "manifest.yml"), []byte(manifestYml), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(buildpackDir, "VERSION"), []byte("1.0.0"), 0644)).To(Succeed())
+
+		logger := libbuildpack.NewLogger(GinkgoWriter)
+		manifest, err := libbuildpack.NewManifest(buildpackDir, logger, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+
+		stager := libbuildpack.NewStager([]string{buildDir, cacheDir, depsDir, "0"}, logger, manifest)
+		return &common.Context{
+			Stager:    stager,
+			Manifest:  manifest,
+			Installer: libbuildpack.NewInstaller(manifest),
+			Log:       logger,
+			Command:   &libbuildpack.Command{},
+		}
+	}
+
+	agentDefault := "- name: metrics-forwarder-agent\n  version: 1.x\n"
+	agentDependency := "- name: metrics-forwarder-agent\n  version: 1.4.0\n  uri: https://example.com/metrics-forwarder-agent-1.4.0.jar\n" +
+		"  sha256: 0000000000000000000000000000000000000000000000000000000000000000\n  cf_stacks:\n  - cflinuxfs4\n"
+
+	BeforeEach(func() {
+		var err error
+		buildDir, err = os.MkdirTemp("", "metrics-forwarder-build")
+		Expect(err).NotTo(HaveOccurred())
+		cacheDir, err = os.MkdirTemp("", "metrics-forwarder-cache")
+		Expect(err).NotTo(HaveOccurred())
+		depsDir, err = os.MkdirTemp("", "metrics-forwarder-deps")
+		Expect(err).NotTo(HaveOccurred())
+		buildpackDir, err = os.MkdirTemp("", "metrics-forwarder-buildpack")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.MkdirAll(filepath.Join(depsDir, "0"), 0755)).To(Succeed())
+		os.Setenv("CF_STACK", "cflinuxfs4")
+
+		fw = frameworks.NewMetricsForwarderFramework(newContext(agentDefault, agentDependency))
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(buildDir)
+		os.RemoveAll(cacheDir)
+		os.RemoveAll(depsDir)
+		os.RemoveAll(buildpackDir)
+		os.Unsetenv("VCAP_SERVICES")
+		os.Unsetenv("VCAP_APPLICATION")
+		os.Unsetenv("CF_STACK")
+		os.Unsetenv("JBP_CONFIG_METRICS_FORWARDER")
+	})
+
+	Describe("Detect", func() {
+		It("is not detected without VCAP_SERVICES", func() {
+			name, err := fw.Detect()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(BeEmpty())
+		})
+
+		DescribeTable("detects metrics services with an endpoint",
+			func(services string) {
+				os.Setenv("VCAP_SERVICES", services)
+
+				name, err := fw.Detect()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(name).To(Equal("Metrics Forwarder"))
+			},
+			Entry("by metrics-forwarder label", forwarderService),
+			Entry("by statsd tag", `{"user-provided":[{"name":"metrics","label":"user-provided","tags":["statsd"],`+
+				`"credentials":{"host":"statsd.example.com","port":8125}}]}`),
+			Entry("by name", `{"user-provided":[{"name":"orders-statsd","label":"user-provided","tags":[],`+
+				`"credentials":{"uri":"udp://statsd.example.com:8125"}}]}`),
+		)
+
+		It("ignores services without an endpoint", func() {
+			os.Setenv("VCAP_SERVICES", `{"statsd":[{"name":"metrics","label":"statsd","tags":[],"credentials":{}}]}`)
+
+			name, err := fw.Detect()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(BeEmpty())
+		})
+
+		It("is not detected when disabled", func() {
+			os.Setenv("VCAP_SERVICES", forwarderService)
+			os.Setenv("JBP_CONFIG_METRICS_FORWARDER", "{enabled: false}")
+
+			name, err := fw.Detect()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(BeEmpty())
+		})
+
+		It("is not detected when the manifest has no agent", func() {
+			os.Setenv("VCAP_SERVICES", forwarderService)
+			fw = frameworks.NewMetricsForwarderFramework(newContext("", ""))
+
+			name, err := fw.Detect()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(BeEmpty())
+		})
+	})
+
+	Describe("Endpoints", func() {
+		It("returns an HTTP endpoint", func() {
+			os.Setenv("VCAP_SERVICES", forwarderService)
+
+			Expect(fw.Endpoints()).To(ConsistOf("https://metrics-forwarder.example.com/v1/metrics"))
+		})
+
+		It("does not return StatsD endpoints", func() {
+			os.Setenv("VCAP_SERVICES", `{"statsd":[{"name":"metrics","label":"statsd","tags":[],`+
+				`"credentials":{"host":"statsd.example.com"}}]}`)
+
+			Expect(fw.Endpoints()).To(BeEmpty())
+		})
+	})
+
+	Describe("Finalize", func() {
+		readScript := func() string {
+			content, err := os.ReadFile(filepath.Join(depsDir, "0", "profile.d", "metrics_forwarder.sh"))
+			Expect(err).NotTo(HaveOccurred())
+			return string(content)
+		}
+
+		BeforeEach(func() {
+			os.Setenv("VCAP_SERVICES", forwarderService)
+			os.Setenv("VCAP_APPLICATION", `{"application_name":"orders api","space_name":"staging","organization_name":"acme"}`)
+
+			agentDir := filepath.Join(depsDir, "0", "metrics_forwarder")
+			Expect(os.MkdirAll(agentDir, 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(agentDir, "metrics-forwarder-agent-1.4.0.jar"), []byte("jar"), 0644)).To(Succeed())
+		})
+
+		It("adds the agent to JAVA_OPTS", func() {
+			Expect(fw.Finalize()).To(Succeed())
+
+			content, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "47_metrics_forwarder.opts"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("-javaagent:$DEPS_DIR/0/metrics_forwarder/metrics-forwarder-agent-1.4.0.jar"))
+		})
+
+		It("exports the endpoint, token, prefix and tags unless they are set", func() {
+			Expect(fw.Finalize()).To(Succeed())
+
+			Expect(readScript()).To(Equal(
+				`[ -n "${METRICS_FORWARDER_ENDPOINT:-}" ] || export METRICS_FORWARDER_ENDPOINT=https://metrics-forwarder.example.com/v1/metrics` + "\n" +
+					`[ -n "${METRICS_FORWARDER_PREFIX:-}" ] || export METRICS_FORWARDER_PREFIX=orders_api` + "\n" +
+					`[ -n "${METRICS_FORWARDER_TOKEN:-}" ] || export METRICS_FORWARDER_TOKEN=s3cr3t` + "\n" +
+					`export METRICS_FORWARDER_TAGS='application:orders api,org:acme,space:staging',` +
+					`"instance_index:${CF_INSTANCE_INDEX:-0},instance_id:${CF_INSTANCE_GUID:-}"` +
+					`"${METRICS_FORWARDER_TAGS:+,$METRICS_FORWARDER_TAGS}"` + "\n"))
+		})
+
+		It("uses the configured prefix and tags", func() {
+			os.Setenv("JBP_CONFIG_METRICS_FORWARDER", "{prefix: payments.orders, instance_tags: false, tags: {team: payments, space: prod}}")
+
+			Expect(fw.Finalize()).To(Succeed())
+
+			script := readScript()
+			Expect(script).To(ContainSubstring("export METRICS_FORWARDER_PREFIX=payments.orders\n"))
+			Expect(script).To(ContainSubstring(`export METRICS_FORWARDER_TAGS='application:orders api,org:acme,space:prod,team:payments'` +
+				`"${METRICS_FORWARDER_TAGS:+,$METRICS_FORWARDER_TAGS}"`))
+			Expect(script).NotTo(ContainSubstring("instance_index"))
+		})
+
+		It("uses the host and port of a StatsD service and its prefix credential", func() {
+			os.Setenv("VCAP_SERVICES", `{"user-provided":[{"name":"statsd","label":"user-provided","tags":[],`+
+				`"credentials":{"hostname":"statsd.example.com","prefix":"apps.orders"}}]}`)
+
+			Expect(fw.Finalize()).To(Succeed())
+
+			script := readScript()
+			Expect(script).To(ContainSubstring("export METRICS_FORWARDER_ENDPOINT=statsd.example.com:8125\n"))
+			Expect(script).To(ContainSubstring("export METRICS_FORWARDER_PREFIX=apps.orders\n"))
+			Expect(script).NotTo(ContainSubstring("METRICS_FORWARDER_TOKEN"))
+		})
+
+		It("fails without the agent JAR", func() {
+			Expect(os.RemoveAll(filepath.Join(depsDir, "0", "metrics_forwarder"))).To(Succeed())
+
+			Expect(fw.Finalize()).To(MatchError(ContainSubstring("no Metrics Forwarder agent JAR found")))
+		})
+	})
+})
@@ -1,11 +1,16 @@
 package frameworks
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/appname"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
 	"github.com/cloudfoundry/java-buildpack/src/java/resources"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/cloudfoundry/libbuildpack"
@@ -44,7 +49,7 @@ func (n *NewRelicFramework) Detect() (string, error) {
 		return "New Relic Agent", nil
 	}
 
-	n.context.Log.Debug("New Relic not detected")
+	n.context.Log.Debug("New Relic: no newrelic service bound and no .new-relic-credentials directory")
 	return "", nil
 }
 
@@ -73,57 +78,148 @@ func (n *NewRelicFramework) Supply() error {
 		return fmt.Errorf("failed to install New Relic agent: %w", err)
 	}
 
-	// Install default newrelic.yml configuration from embedded resources
-	if err := n.installDefaultConfiguration(agentDir); err != nil {
-		n.context.Log.Warning("Could not install default New Relic configuration: %s", err.Error())
-	}
-
 	n.context.Log.Debug("Installed New Relic Agent version %s", dep.Version)
 	return nil
 }
 
-// installDefaultConfiguration installs the default newrelic.yml from embedded resources
-func (n *NewRelicFramework) installDefaultConfiguration(agentDir string) error {
-	configPath := filepath.Join(agentDir, "newrelic.yml")
+// service returns the bound New Relic service, or nil
+func (n *NewRelicFramework) service() *VCAPService {
+	vcapServices, _ := GetVCAPServices()
+	service := vcapServices.GetService("newrelic")
+
+	// If not found by label, try user-provided services (Docker platform)
+	if service == nil {
+		service = vcapServices.GetServiceByNamePattern("newrelic")
+	}
+	return service
+}
 
-	// Check if configuration already exists (user-provided or from external config)
+// Endpoints returns the proxy configured in the service's credentials or, without one, the collector the agent
+// reports to: the host credential, the EU collector for EU license keys, or collector.newrelic.com
+func (n *NewRelicFramework) Endpoints() []string {
+	service := n.service()
+	if service == nil {
+		return nil
+	}
+
+	if proxyHost := credentialString(service.Credentials, "proxy_host"); proxyHost != "" {
+		scheme := credentialString(service.Credentials, "proxy_scheme")
+		if scheme == "" {
+			scheme = "http"
+		}
+		port := credentialString(service.Credentials, "proxy_port")
+		if port == "" {
+			port = "8080"
+		}
+		return []string{fmt.Sprintf("%s://%s:%s", scheme, proxyHost, port)}
+	}
+
+	host := credentialString(service.Credentials, "host")
+	if host == "" {
+		licenseKey := credentialString(service.Credentials, "license_key")
+		if licenseKey == "" {
+			licenseKey = credentialString(service.Credentials, "licenseKey")
+		}
+		host = "collector.newrelic.com"
+		if strings.HasPrefix(licenseKey, "eu01x") {
+			host = "collector.eu01.nr-data.net"
+		}
+	}
+	return []string{"https://" + host}
+}
+
+// newRelicConfigurationKeys are the credentials written to newrelic.yml instead of being passed as system properties
+var newRelicConfigurationKeys = map[string]bool{
+	"license_key":         true,
+	"licenseKey":          true,
+	"proxy_host":          true,
+	"proxy_port":          true,
+	"proxy_user":          true,
+	"proxy_password":      true,
+	"proxy_scheme":        true,
+	"distributed_tracing": true,
+}
+
+// writeConfiguration writes newrelic.yml to agentDir from the embedded template, populated with the license key,
+// proxy settings and distributed tracing flag of the service's credentials and the application's name.
+// A newrelic.yml that already exists in agentDir is kept.
+func (n *NewRelicFramework) writeConfiguration(agentDir string, service *VCAPService) error {
+	configPath := filepath.Join(agentDir, "newrelic.yml")
 	if _, err := os.Stat(configPath); err == nil {
-		n.context.Log.Debug("newrelic.yml already exists, skipping default configuration")
+		n.context.Log.Debug("newrelic.yml already exists, skipping generated configuration")
 		return nil
 	}
 
-	// Read embedded newrelic.yml template
-	embeddedPath := "new_relic_agent/newrelic.yml"
-	configData, err := resources.GetResource(embeddedPath)
+	template, err := resources.GetResource("new_relic_agent/newrelic.yml")
 	if err != nil {
 		return fmt.Errorf("failed to read embedded newrelic.yml: %w", err)
 	}
 
-	// Process ERB-style template placeholders
-	configStr := string(configData)
+	credentials := map[string]interface{}{}
+	serviceName := ""
+	if service != nil {
+		credentials = service.Credentials
+		serviceName = service.Name
+	}
+
+	licenseKey := credentialString(credentials, "license_key")
+	if licenseKey == "" {
+		licenseKey = credentialString(credentials, "licenseKey")
+	}
+	if licenseKey == "" {
+		n.context.Log.Warning("New Relic service has no license_key credential, the agent will not report data")
+	}
+
+	appName, err := appname.Name(n.context.Log, appname.App)
+	if err != nil {
+		return err
+	}
+	if appName == "" {
+		appName = serviceName
+	}
 
-	// Replace <%= generated_for_user %> with buildpack info
-	configStr = strings.ReplaceAll(configStr, "<%= generated_for_user %>",
-		"This configuration file was generated by the Cloud Foundry Java Buildpack")
+	var proxy strings.Builder
+	for _, key := range []string{"proxy_host", "proxy_port", "proxy_user", "proxy_password", "proxy_scheme"} {
+		value := credentialString(credentials, key)
+		if value == "" {
+			continue
+		}
+		if _, err := strconv.Atoi(value); key != "proxy_port" || err != nil {
+			value = yamlString(value)
+		}
+		fmt.Fprintf(&proxy, "  %s: %s\n", key, value)
+	}
+
+	distributedTracing, ok := credentials["distributed_tracing"].(bool)
+	if !ok {
+		distributedTracing, _ = strconv.ParseBool(credentialString(credentials, "distributed_tracing"))
+	}
 
-	// Replace <%= license_key %> with placeholder (will be set via JAVA_OPTS at runtime)
-	// The license key will be provided through service bindings in the Finalize phase
-	configStr = strings.ReplaceAll(configStr, "<%= license_key %>", "YOUR_LICENSE_KEY_HERE")
+	config := strings.NewReplacer(
+		"<%= generated_for_user %>", "This configuration file was generated by the Cloud Foundry Java Buildpack",
+		"<%= license_key %>", yamlString(licenseKey),
+		"<%= app_name %>", yamlString(appName),
+		"<%= proxy_settings %>\n", proxy.String(),
+		"<%= distributed_tracing %>", strconv.FormatBool(distributedTracing),
+	).Replace(string(template))
 
-	// Write configuration file
-	if err := os.WriteFile(configPath, []byte(configStr), 0644); err != nil {
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
 		return fmt.Errorf("failed to write newrelic.yml: %w", err)
 	}
 
-	n.context.Log.Debug("Installed default New Relic configuration")
-	n.context.Log.Debug("  - newrelic.yml (license key and app name will be configured via JAVA_OPTS)")
+	n.context.Log.Debug("Generated newrelic.yml for application %s", appName)
 	return nil
 }
 
+// yamlString quotes value as a YAML double-quoted scalar, which JSON string syntax is a subset of
+func yamlString(value string) string {
+	quoted, _ := json.Marshal(value)
+	return string(quoted)
+}
+
 // Finalize performs final New Relic configuration
 func (n *NewRelicFramework) Finalize() error {
 	// Get buildpack index for multi-buildpack support
-	depsIdx := n.context.Stager.DepsIdx()
 
 	// Find the actual New Relic agent jar at staging time
 	agentDir := filepath.Join(n.context.Stager.DepDir(), "new_relic_agent")
@@ -137,34 +233,63 @@ func (n *NewRelicFramework) Finalize() error {
 	if err != nil {
 		return fmt.Errorf("failed to compute relative path: %w", err)
 	}
-	runtimeAgentPath := filepath.Join(fmt.Sprintf("$DEPS_DIR/%s", depsIdx), relPath)
+	runtimeAgentPath := n.context.Droplet().Dep(relPath)
 
-	// Add javaagent to JAVA_OPTS
-	javaOpts := fmt.Sprintf("-javaagent:%s", runtimeAgentPath)
+	service := n.service()
+	if err := n.writeConfiguration(agentDir, service); err != nil {
+		return err
+	}
 
-	// Get New Relic configuration from service binding
-	vcapServices, _ := GetVCAPServices()
-	service := vcapServices.GetService("newrelic")
+	// Add javaagent and its configuration file to JAVA_OPTS
+	javaOpts := fmt.Sprintf("-javaagent:%s -Dnewrelic.config.file=%s",
+		runtimeAgentPath, n.context.Droplet().Dep("new_relic_agent", "newrelic.yml"))
 
-	// If not found by label, try user-provided services (Docker platform)
-	if service == nil {
-		service = vcapServices.GetServiceByNamePattern("newrelic")
+	// Resource tags become labels, followed by the labels of the service so that these take precedence
+	tags, err := resourceTags(n.context)
+	if err != nil {
+		return err
+	}
+	var labels string
+	if len(tags) > 0 {
+		labels = joinResourceTags(tags, ":", ";", false)
+		if service != nil {
+			if serviceLabels := credentialString(service.Credentials, "labels"); serviceLabels != "" {
+				labels += ";" + serviceLabels
+			}
+		}
 	}
 
+	// Any other credentials configure the agent through system properties, which take precedence over newrelic.yml
 	if service != nil {
-		// Add license key from service credentials
-		if licenseKey, ok := service.Credentials["licenseKey"].(string); ok && licenseKey != "" {
-			javaOpts += fmt.Sprintf(" -Dnewrelic.config.license_key=%s", licenseKey)
+		keys := make([]string, 0, len(service.Credentials))
+		for key := range service.Credentials {
+			if !newRelicConfigurationKeys[key] && (key != "labels" || labels == "") {
+				keys = append(keys, key)
+			}
 		}
-
-		// Add app name from service name
-		if service.Name != "" {
-			javaOpts += fmt.Sprintf(" -Dnewrelic.config.app_name='%s'", service.Name)
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			var value string
+			switch v := service.Credentials[key].(type) {
+			case string:
+				value = v
+			case float64:
+				value = strconv.FormatFloat(v, 'f', -1, 64)
+			case bool:
+				value = strconv.FormatBool(v)
+			default:
+				continue
+			}
+			javaOpts += fmt.Sprintf(" -Dnewrelic.config.%s=%s", key, escapeValue(value))
 		}
 	}
+	if labels != "" {
+		javaOpts += " -Dnewrelic.config.labels=" + escapeValue(labels)
+	}
 
 	// Write to .opts file using priority 35
-	if err := writeJavaOptsFile(n.context, 35, "new_relic", javaOpts); err != nil {
+	if err := writeJavaOptsFile(n.context, javaopts.NewRelic, "new_relic", javaOpts); err != nil {
 		return fmt.Errorf("failed to write java_opts file: %w", err)
 	}
 
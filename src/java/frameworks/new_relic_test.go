@@ -8,6 +8,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	"github.com/cloudfoundry/java-buildpack/src/java/frameworks"
 	"github.com/cloudfoundry/java-buildpack/src/java/resources"
 )
 
@@ -31,6 +32,9 @@ var _ = Describe("NewRelicAgent", func() {
 
 		Expect(configStr).To(ContainSubstring("<%= generated_for_user %>"))
 		Expect(configStr).To(ContainSubstring("<%= license_key %>"))
+		Expect(configStr).To(ContainSubstring("<%= app_name %>"))
+		Expect(configStr).To(ContainSubstring("<%= proxy_settings %>"))
+		Expect(configStr).To(ContainSubstring("<%= distributed_tracing %>"))
 		Expect(configStr).To(ContainSubstring("common: &default_settings"))
 
 		expectedSections := []string{
@@ -47,83 +51,168 @@ var _ = Describe("NewRelicAgent", func() {
 		}
 	})
 
-	It("should correctly replace ERB placeholders", func() {
-		configData, err := resources.GetResource(embeddedPath)
-		Expect(err).NotTo(HaveOccurred())
+	Describe("Finalize", func() {
+		var (
+			fw       *frameworks.NewRelicFramework
+			buildDir string
+			cacheDir string
+			depsDir  string
+		)
 
-		configStr := string(configData)
-		configStr = strings.ReplaceAll(configStr, "<%= generated_for_user %>",
-			"This configuration file was generated by the Cloud Foundry Java Buildpack")
-		configStr = strings.ReplaceAll(configStr, "<%= license_key %>", "YOUR_LICENSE_KEY_HERE")
-
-		Expect(configStr).NotTo(ContainSubstring("<%= generated_for_user %>"))
-		Expect(configStr).NotTo(ContainSubstring("<%= license_key %>"))
-		Expect(configStr).To(ContainSubstring("This configuration file was generated by the Cloud Foundry Java Buildpack"))
-		Expect(configStr).To(ContainSubstring("license_key: 'YOUR_LICENSE_KEY_HERE'"))
-		Expect(configStr).To(ContainSubstring("common: &default_settings"))
-	})
+		readConfig := func() string {
+			data, err := os.ReadFile(filepath.Join(depsDir, "0", "new_relic_agent", "newrelic.yml"))
+			Expect(err).NotTo(HaveOccurred())
+			return string(data)
+		}
 
-	Context("config file creation", func() {
-		var tmpDir string
-		var agentDir string
+		readOpts := func() string {
+			data, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "35_new_relic.opts"))
+			Expect(err).NotTo(HaveOccurred())
+			return string(data)
+		}
 
 		BeforeEach(func() {
 			var err error
-			tmpDir, err = os.MkdirTemp("", "newrelic-test-*")
+			buildDir, err = os.MkdirTemp("", "newrelic-build")
+			Expect(err).NotTo(HaveOccurred())
+			cacheDir, err = os.MkdirTemp("", "newrelic-cache")
 			Expect(err).NotTo(HaveOccurred())
-			agentDir = filepath.Join(tmpDir, "new_relic_agent")
+			depsDir, err = os.MkdirTemp("", "newrelic-deps")
+			Expect(err).NotTo(HaveOccurred())
+
+			jarDir := filepath.Join(depsDir, "0", "new_relic_agent", "newrelic")
+			Expect(os.MkdirAll(jarDir, 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(jarDir, "newrelic.jar"), []byte("fake jar"), 0644)).To(Succeed())
+
+			os.Setenv("VCAP_APPLICATION", `{"application_name":"orders","space_name":"prod"}`)
+			fw = frameworks.NewNewRelicFramework(newElasticContext(buildDir, cacheDir, depsDir))
 		})
 
 		AfterEach(func() {
-			os.RemoveAll(tmpDir)
+			os.RemoveAll(buildDir)
+			os.RemoveAll(cacheDir)
+			os.RemoveAll(depsDir)
+			os.Unsetenv("VCAP_SERVICES")
+			os.Unsetenv("VCAP_APPLICATION")
 		})
 
-		It("should create config file from embedded resource", func() {
-			err := os.MkdirAll(agentDir, 0755)
-			Expect(err).NotTo(HaveOccurred())
+		It("generates newrelic.yml from the service binding and points the agent at it", func() {
+			os.Setenv("VCAP_SERVICES", `{"newrelic":[{"name":"my-newrelic","label":"newrelic","tags":[],`+
+				`"credentials":{"licenseKey":"abc123","proxy_host":"proxy.example.com","proxy_port":3128,`+
+				`"proxy_password":"p@ss 'word'","distributed_tracing":true}}]}`)
 
-			configData, err := resources.GetResource(embeddedPath)
-			Expect(err).NotTo(HaveOccurred())
+			Expect(fw.Finalize()).To(Succeed())
 
-			configStr := string(configData)
-			configStr = strings.ReplaceAll(configStr, "<%= generated_for_user %>",
-				"This configuration file was generated by the Cloud Foundry Java Buildpack")
-			configStr = strings.ReplaceAll(configStr, "<%= license_key %>", "YOUR_LICENSE_KEY_HERE")
+			config := readConfig()
+			Expect(config).NotTo(ContainSubstring("<%="))
+			Expect(config).To(ContainSubstring(`license_key: "abc123"`))
+			Expect(config).To(ContainSubstring(`app_name: "orders"`))
+			Expect(config).To(ContainSubstring("  proxy_host: \"proxy.example.com\"\n  proxy_port: 3128\n" +
+				`  proxy_password: "p@ss 'word'"`))
+			Expect(config).To(ContainSubstring("distributed_tracing:\n    enabled: true"))
 
-			configPath := filepath.Join(agentDir, "newrelic.yml")
-			err = os.WriteFile(configPath, []byte(configStr), 0644)
-			Expect(err).NotTo(HaveOccurred())
+			Expect(readOpts()).To(Equal("-javaagent:$DEPS_DIR/0/new_relic_agent/newrelic/newrelic.jar " +
+				"-Dnewrelic.config.file=$DEPS_DIR/0/new_relic_agent/newrelic.yml"))
+		})
 
-			Expect(configPath).To(BeARegularFile())
+		It("prefers license_key over licenseKey", func() {
+			os.Setenv("VCAP_SERVICES", `{"newrelic":[{"name":"my-newrelic","label":"newrelic","tags":[],`+
+				`"credentials":{"license_key":"winner","licenseKey":"loser"}}]}`)
 
-			writtenData, err := os.ReadFile(configPath)
-			Expect(err).NotTo(HaveOccurred())
+			Expect(fw.Finalize()).To(Succeed())
+			Expect(readConfig()).To(ContainSubstring(`license_key: "winner"`))
+		})
 
-			writtenStr := string(writtenData)
-			Expect(writtenStr).To(ContainSubstring("common: &default_settings"))
-			Expect(writtenStr).To(ContainSubstring("This configuration file was generated by the Cloud Foundry Java Buildpack"))
-			Expect(writtenStr).To(ContainSubstring("license_key: 'YOUR_LICENSE_KEY_HERE'"))
-			Expect(writtenStr).NotTo(ContainSubstring("<%="))
+		It("names the application after the service without VCAP_APPLICATION", func() {
+			os.Unsetenv("VCAP_APPLICATION")
+			os.Setenv("VCAP_SERVICES", `{"user-provided":[{"name":"newrelic-prod","label":"user-provided","tags":[],`+
+				`"credentials":{"license_key":"abc123"}}]}`)
+
+			Expect(fw.Finalize()).To(Succeed())
+
+			config := readConfig()
+			Expect(config).To(ContainSubstring(`app_name: "newrelic-prod"`))
+			Expect(config).To(ContainSubstring("distributed_tracing:\n    enabled: false"))
+			Expect(config).NotTo(MatchRegexp(`(?m)^  proxy_host:`))
 		})
 
-		It("should not overwrite existing config", func() {
-			err := os.MkdirAll(agentDir, 0755)
-			Expect(err).NotTo(HaveOccurred())
+		It("passes other credentials as system properties", func() {
+			os.Setenv("VCAP_SERVICES", `{"newrelic":[{"name":"my-newrelic","label":"newrelic","tags":[],`+
+				`"credentials":{"license_key":"abc123","log_level":"fine","transaction_tracer.transaction_threshold":0.5}}]}`)
 
-			configPath := filepath.Join(agentDir, "newrelic.yml")
-			userConfig := "# User-provided configuration\nlicense_key: 'my-custom-key'\n"
-			err = os.WriteFile(configPath, []byte(userConfig), 0644)
-			Expect(err).NotTo(HaveOccurred())
+			Expect(fw.Finalize()).To(Succeed())
 
-			_, err = os.Stat(configPath)
-			Expect(err).NotTo(HaveOccurred(), "Should have detected existing config file")
+			opts := readOpts()
+			Expect(opts).To(ContainSubstring(" -Dnewrelic.config.log_level=fine"))
+			Expect(opts).To(ContainSubstring(" -Dnewrelic.config.transaction_tracer.transaction_threshold=0.5"))
+			Expect(opts).NotTo(ContainSubstring("abc123"))
+		})
 
-			existingData, err := os.ReadFile(configPath)
-			Expect(err).NotTo(HaveOccurred())
+		Describe("Endpoints", func() {
+			It("reports the US collector by default", func() {
+				os.Setenv("VCAP_SERVICES", `{"newrelic":[{"name":"my-newrelic","label":"newrelic","tags":[],`+
+					`"credentials":{"license_key":"abc123"}}]}`)
+				Expect(fw.Endpoints()).To(Equal([]string{"https://collector.newrelic.com"}))
+			})
+
+			It("reports the EU collector for EU license keys", func() {
+				os.Setenv("VCAP_SERVICES", `{"newrelic":[{"name":"my-newrelic","label":"newrelic","tags":[],`+
+					`"credentials":{"licenseKey":"eu01xxabc123"}}]}`)
+				Expect(fw.Endpoints()).To(Equal([]string{"https://collector.eu01.nr-data.net"}))
+			})
+
+			It("reports the proxy the agent connects through", func() {
+				os.Setenv("VCAP_SERVICES", `{"newrelic":[{"name":"my-newrelic","label":"newrelic","tags":[],`+
+					`"credentials":{"license_key":"abc123","proxy_host":"proxy.example.com","proxy_port":3128}}]}`)
+				Expect(fw.Endpoints()).To(Equal([]string{"http://proxy.example.com:3128"}))
+			})
+
+			It("reports nothing without a service", func() {
+				Expect(fw.Endpoints()).To(BeEmpty())
+			})
+		})
+
+		Context("with resource tags enabled", func() {
+			BeforeEach(func() {
+				os.Setenv("JBP_CONFIG_RESOURCE_TAGS", "{enabled: true, cloud_provider: aws, region: eu-west-1}")
+				os.Setenv("VCAP_APPLICATION", `{"application_name":"orders","cf_api":"https://api.sys.example.com",`+
+					`"organization_name":"shop","space_name":"prod"}`)
+			})
+
+			AfterEach(func() {
+				os.Unsetenv("JBP_CONFIG_RESOURCE_TAGS")
+			})
+
+			It("labels the application with the foundation, org, space and region", func() {
+				os.Setenv("VCAP_SERVICES", `{"newrelic":[{"name":"my-newrelic","label":"newrelic","tags":[],`+
+					`"credentials":{"license_key":"abc123"}}]}`)
+
+				Expect(fw.Finalize()).To(Succeed())
+				Expect(readOpts()).To(HaveSuffix(` -Dnewrelic.config.labels=foundation:sys.example.com\;org:shop\;space:prod` +
+					`\;cloud_provider:aws\;region:eu-west-1`))
+			})
+
+			It("appends the labels of the service", func() {
+				os.Setenv("VCAP_SERVICES", `{"newrelic":[{"name":"my-newrelic","label":"newrelic","tags":[],`+
+					`"credentials":{"license_key":"abc123","labels":"team:payments"}}]}`)
+
+				Expect(fw.Finalize()).To(Succeed())
+
+				opts := readOpts()
+				Expect(opts).To(HaveSuffix(`\;region:eu-west-1\;team:payments`))
+				Expect(strings.Count(opts, "-Dnewrelic.config.labels=")).To(Equal(1))
+			})
+		})
+
+		It("keeps an existing newrelic.yml", func() {
+			os.Setenv("VCAP_SERVICES", `{"newrelic":[{"name":"my-newrelic","label":"newrelic","tags":[],`+
+				`"credentials":{"license_key":"abc123"}}]}`)
+			userConfig := "# User-provided configuration\nlicense_key: 'my-custom-key'\n"
+			Expect(os.WriteFile(filepath.Join(depsDir, "0", "new_relic_agent", "newrelic.yml"), []byte(userConfig), 0644)).To(Succeed())
 
-			existingStr := string(existingData)
-			Expect(existingStr).To(ContainSubstring("# User-provided configuration"))
-			Expect(existingStr).To(ContainSubstring("my-custom-key"))
+			Expect(fw.Finalize()).To(Succeed())
+			Expect(readConfig()).To(Equal(userConfig))
+			Expect(readOpts()).To(ContainSubstring("-Dnewrelic.config.file=$DEPS_DIR/0/new_relic_agent/newrelic.yml"))
 		})
 	})
 })
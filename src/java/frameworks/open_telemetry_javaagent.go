@@ -1,13 +1,36 @@
 package frameworks
 
 import (
+	"encoding/json"
 	"fmt"
-	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"net/url"
+	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/appname"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
 	"github.com/cloudfoundry/libbuildpack"
 )
 
+// openTelemetryLogAppender is an OpenTelemetry appender for a logging library the application may use
+type openTelemetryLogAppender struct {
+	// dependency is the appender's name in the buildpack manifest
+	dependency string
+	// libraryPrefix is the file name prefix of the logging library JAR that enables the appender
+	libraryPrefix string
+	// instrumentation is the javaagent instrumentation that bridges the library to the appender
+	instrumentation string
+}
+
+var openTelemetryLogAppenders = []openTelemetryLogAppender{
+	{dependency: "opentelemetry-logback-appender", libraryPrefix: "logback-classic-", instrumentation: "logback-appender"},
+	{dependency: "opentelemetry-log4j-appender", libraryPrefix: "log4j-core-", instrumentation: "log4j-appender"},
+}
+
 // OpenTelemetryJavaagentFramework implements OpenTelemetry instrumentation support
 type OpenTelemetryJavaagentFramework struct {
 	context *common.Context
@@ -42,7 +65,7 @@ func (o *OpenTelemetryJavaagentFramework) Detect() (string, error) {
 		return "OpenTelemetry Javaagent", nil
 	}
 
-	o.context.Log.Debug("OpenTelemetry not detected")
+	o.context.Log.Debug("OpenTelemetry: no otel-collector, opentelemetry or otel service bound")
 	return "", nil
 }
 
@@ -67,24 +90,94 @@ func (o *OpenTelemetryJavaagentFramework) Supply() error {
 	}
 
 	o.context.Log.Debug("Installed OpenTelemetry Javaagent version %s", dep.Version)
+
+	otelConfig, err := o.loadConfig()
+	if err != nil {
+		return err
+	}
+	if otelConfig.Logs.Enabled {
+		return o.installLogAppenders()
+	}
+	return nil
+}
+
+// installLogAppenders installs the appenders of the logging libraries the application ships into the lib directory
+// of the library, e.g. BOOT-INF/lib, which every container already puts on the application's classpath.
+// Appenders that are not in the manifest are skipped: the javaagent bridges the libraries on its own.
+func (o *OpenTelemetryJavaagentFramework) installLogAppenders() error {
+	for _, appender := range openTelemetryLogAppenders {
+		library, ok := o.context.Classpath().Find(appender.libraryPrefix + "*.jar")
+		if !ok {
+			continue
+		}
+		libDir := filepath.Dir(library)
+		if libDir == "." {
+			o.context.Log.Debug("%s is not in a lib directory, relying on the javaagent's %s instrumentation", library, appender.instrumentation)
+			continue
+		}
+		if o.context.Classpath().Contains(appender.dependency + "-*.jar") {
+			o.context.Log.Debug("Application provides %s", appender.dependency)
+			continue
+		}
+
+		dep, err := o.context.Manifest.DefaultVersion(appender.dependency)
+		if err != nil {
+			o.context.Log.Debug("%s is not in the manifest, relying on the javaagent's %s instrumentation", appender.dependency, appender.instrumentation)
+			continue
+		}
+		if err := o.context.Installer.InstallDependency(dep, filepath.Join(o.context.Stager.BuildDir(), libDir)); err != nil {
+			return fmt.Errorf("failed to install %s: %w", appender.dependency, err)
+		}
+		o.context.Log.Info("Installed OpenTelemetry log appender %s %s into %s", appender.dependency, dep.Version, libDir)
+	}
 	return nil
 }
 
 // Finalize performs final OpenTelemetry configuration
 func (o *OpenTelemetryJavaagentFramework) Finalize() error {
 	// Get buildpack index for multi-buildpack support
-	depsIdx := o.context.Stager.DepsIdx()
 
 	// Build runtime agent path
-	agentJar := fmt.Sprintf("$DEPS_DIR/%s/open_telemetry_javaagent/opentelemetry-javaagent.jar", depsIdx)
+	agentJar := o.context.Droplet().Dep("open_telemetry_javaagent", "opentelemetry-javaagent.jar")
 
 	// Add javaagent to JAVA_OPTS
 	javaOpts := fmt.Sprintf("-javaagent:%s", agentJar)
 
-	// Get OpenTelemetry configuration from service binding
+	service := o.findService()
+
+	environment, err := o.environment(service)
+	if err != nil {
+		return err
+	}
+	if err := o.context.Stager.WriteProfileD("open_telemetry_javaagent.sh", strings.Join(environment, "\n")+"\n"); err != nil {
+		return fmt.Errorf("failed to write open_telemetry_javaagent.sh profile.d script: %w", err)
+	}
+
+	otelConfig, err := o.loadConfig()
+	if err != nil {
+		return err
+	}
+	if otelConfig.Logs.Enabled {
+		logOpts, err := o.configureLogExport(otelConfig.Logs, service)
+		if err != nil {
+			return err
+		}
+		javaOpts += logOpts
+	}
+
+	// Write to .opts file using priority 36
+	if err := writeJavaOptsFile(o.context, javaopts.OpenTelemetry, "open_telemetry_javaagent", javaOpts); err != nil {
+		return fmt.Errorf("failed to write java_opts file: %w", err)
+	}
+
+	o.context.Log.Debug("OpenTelemetry Javaagent configured (priority 36)")
+	return nil
+}
+
+// findService returns the bound OpenTelemetry collector service, or nil if there is none
+func (o *OpenTelemetryJavaagentFramework) findService() *VCAPService {
 	vcapServices, _ := GetVCAPServices()
 
-	// Try to find service by various patterns
 	service := vcapServices.GetService("otel-collector")
 	if service == nil {
 		service = vcapServices.GetService("opentelemetry")
@@ -95,32 +188,187 @@ func (o *OpenTelemetryJavaagentFramework) Finalize() error {
 	if service == nil {
 		service = vcapServices.GetServiceByNamePattern("otel")
 	}
+	return service
+}
 
-	// Add all otel.* credentials from the service bind as JVM system properties
-	if service != nil && service.Credentials != nil {
-		for key, value := range service.Credentials {
-			// Only add properties that start with "otel."
-			if len(key) >= 5 && key[:5] == "otel." {
-				javaOpts += fmt.Sprintf(" -D%s=%v", key, value)
-			}
+// environment returns the profile.d exports that configure the javaagent through its OTEL_* environment variables,
+// so that credentials such as exporter headers do not appear on the command line:
+//   - every otel.* credential as the matching variable, e.g. otel.exporter.otlp.endpoint as
+//     OTEL_EXPORTER_OTLP_ENDPOINT
+//   - the endpoint, protocol, headers and authorization credentials as the OTLP exporter variables, unless the
+//     otel.* credentials set them
+//   - OTEL_RESOURCE_ATTRIBUTES with the application, instance, space and org of VCAP_APPLICATION, the resource
+//     tags and the service's otel.resource.attributes
+//
+// Variables set in the application's environment take precedence, and attributes of its OTEL_RESOURCE_ATTRIBUTES
+// are appended so that they override the generated ones.
+func (o *OpenTelemetryJavaagentFramework) environment(service *VCAPService) ([]string, error) {
+	var credentials map[string]interface{}
+	if service != nil {
+		credentials = service.Credentials
+	}
+
+	variables := map[string]string{}
+	for key, value := range credentials {
+		if strings.HasPrefix(key, "otel.") && key != "otel.resource.attributes" {
+			variables[openTelemetryVariable(key)] = fmt.Sprint(value)
 		}
+	}
+	for key, variable := range map[string]string{"endpoint": "OTEL_EXPORTER_OTLP_ENDPOINT", "protocol": "OTEL_EXPORTER_OTLP_PROTOCOL"} {
+		if value := credentialString(credentials, key); value != "" && variables[variable] == "" {
+			variables[variable] = value
+		}
+	}
+	if headers := openTelemetryHeaders(credentials); headers != "" && variables["OTEL_EXPORTER_OTLP_HEADERS"] == "" {
+		variables["OTEL_EXPORTER_OTLP_HEADERS"] = headers
+	}
+
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	exports := make([]string, 0, len(names)+1)
+	for _, name := range names {
+		exports = append(exports, fmt.Sprintf(`[ -n "${%s:-}" ] || export %s=%s`, name, name, shellEscape(variables[name])))
+	}
+
+	attributes, err := o.resourceAttributes(credentials)
+	if err != nil {
+		return nil, err
+	}
+	resourceAttributes := `"cloudfoundry.app.instance.id=${CF_INSTANCE_INDEX:-0}"`
+	if attributes != "" {
+		resourceAttributes += `,` + shellEscape(attributes)
+	}
+	exports = append(exports, fmt.Sprintf(`export OTEL_RESOURCE_ATTRIBUTES=%s"${OTEL_RESOURCE_ATTRIBUTES:+,$OTEL_RESOURCE_ATTRIBUTES}"`, resourceAttributes))
+	return exports, nil
+}
+
+// resourceAttributes returns the resource attributes of the application, the resource tags and the service's
+// otel.resource.attributes, in increasing precedence. The instance index is added at runtime.
+func (o *OpenTelemetryJavaagentFramework) resourceAttributes(credentials map[string]interface{}) (string, error) {
+	var app struct {
+		ApplicationID    string `json:"application_id"`
+		ApplicationName  string `json:"application_name"`
+		OrganizationID   string `json:"organization_id"`
+		OrganizationName string `json:"organization_name"`
+		SpaceID          string `json:"space_id"`
+		SpaceName        string `json:"space_name"`
+	}
+	_ = json.Unmarshal([]byte(os.Getenv("VCAP_APPLICATION")), &app)
 
-		// Set otel.service.name to the application name if not specified in credentials
-		if _, hasServiceName := service.Credentials["otel.service.name"]; !hasServiceName {
-			// Use the build directory name as the application name
-			if appName := GetApplicationName(false); appName != "" {
-				javaOpts += fmt.Sprintf(" -Dotel.service.name=%s", appName)
-			}
+	var attributes []string
+	seen := map[string]bool{}
+	add := func(key, value string) {
+		if value != "" && !seen[key] {
+			seen[key] = true
+			attributes = append(attributes, key+"="+openTelemetryEscape(value))
 		}
 	}
+	serviceName, err := appname.Name(o.context.Log, appname.App)
+	if err != nil {
+		return "", err
+	}
+	add("service.name", serviceName)
+	add("cloudfoundry.app.id", app.ApplicationID)
+	add("cloudfoundry.app.name", app.ApplicationName)
+	add("cloudfoundry.space.id", app.SpaceID)
+	add("cloudfoundry.space.name", app.SpaceName)
+	add("cloudfoundry.org.id", app.OrganizationID)
+	add("cloudfoundry.org.name", app.OrganizationName)
 
-	// Write to .opts file using priority 36
-	if err := writeJavaOptsFile(o.context, 36, "open_telemetry_javaagent", javaOpts); err != nil {
-		return fmt.Errorf("failed to write java_opts file: %w", err)
+	tags, err := resourceTags(o.context)
+	if err != nil {
+		return "", err
+	}
+	for _, tag := range tags {
+		add(tag.attribute, tag.value)
 	}
 
-	o.context.Log.Debug("OpenTelemetry Javaagent configured (priority 36)")
-	return nil
+	if serviceAttributes := credentialString(credentials, "otel.resource.attributes"); serviceAttributes != "" {
+		attributes = append(attributes, serviceAttributes)
+	}
+	return strings.Join(attributes, ","), nil
+}
+
+// openTelemetryVariable returns the environment variable of a javaagent system property, e.g.
+// OTEL_TRACES_SAMPLER for otel.traces.sampler
+func openTelemetryVariable(property string) string {
+	return strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(property))
+}
+
+// openTelemetryHeaders returns the OTLP exporter headers of the headers credential, either a map or a list of
+// key=value pairs, and of the authorization credential, which is sent as the Authorization header. Header values
+// of the map and the authorization are URL-encoded as OTEL_EXPORTER_OTLP_HEADERS requires.
+func openTelemetryHeaders(credentials map[string]interface{}) string {
+	var headers []string
+	switch value := credentials["headers"].(type) {
+	case string:
+		headers = append(headers, value)
+	case map[string]interface{}:
+		names := make([]string, 0, len(value))
+		for name := range value {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			headers = append(headers, name+"="+openTelemetryEscape(fmt.Sprint(value[name])))
+		}
+	}
+	if authorization := credentialString(credentials, "authorization"); authorization != "" {
+		headers = append(headers, "Authorization="+openTelemetryEscape(authorization))
+	}
+	return strings.Join(headers, ",")
+}
+
+// openTelemetryEscape percent-encodes a value of OTEL_RESOURCE_ATTRIBUTES or OTEL_EXPORTER_OTLP_HEADERS. The agent
+// decodes them with URLDecoder, which reads "+" as a space, so spaces are encoded as %20 and "+" as %2B.
+func openTelemetryEscape(value string) string {
+	return strings.ReplaceAll(url.PathEscape(value), "+", "%2B")
+}
+
+// configureLogExport returns the JAVA_OPTS that export application logs through OpenTelemetry
+func (o *OpenTelemetryJavaagentFramework) configureLogExport(logs openTelemetryLogsConfig, service *VCAPService) (string, error) {
+	var opts string
+	exporter := logs.Exporter
+	if service != nil && service.Credentials["otel.logs.exporter"] != nil {
+		// already exported as OTEL_LOGS_EXPORTER with the other otel.* credentials
+		exporter = fmt.Sprint(service.Credentials["otel.logs.exporter"])
+	} else {
+		opts += fmt.Sprintf(" -Dotel.logs.exporter=%s", exporter)
+	}
+	for _, appender := range openTelemetryLogAppenders {
+		opts += fmt.Sprintf(" -Dotel.instrumentation.%s.enabled=true", appender.instrumentation)
+	}
+
+	o.context.Log.Info("Exporting application logs through OpenTelemetry (otel.logs.exporter=%s)", exporter)
+	return opts, nil
+}
+
+func (o *OpenTelemetryJavaagentFramework) loadConfig() (*openTelemetryConfig, error) {
+	otelConfig := openTelemetryConfig{
+		Logs: openTelemetryLogsConfig{
+			Enabled:  false,
+			Exporter: "otlp",
+		},
+	}
+	// overlay buildpack defaults and JBP_CONFIG_OPEN_TELEMETRY_JAVAAGENT over default values
+	if err := config.Load(o.context.Log, "open_telemetry_javaagent", &otelConfig); err != nil {
+		return nil, err
+	}
+	return &otelConfig, nil
+}
+
+type openTelemetryConfig struct {
+	Logs openTelemetryLogsConfig `yaml:"logs"`
+}
+
+type openTelemetryLogsConfig struct {
+	// Enabled exports application logs alongside traces and metrics
+	Enabled bool `yaml:"enabled"`
+	// Exporter is the otel.logs.exporter value, unless the service binding sets one
+	Exporter string `yaml:"exporter"`
 }
 
 func (o *OpenTelemetryJavaagentFramework) DependencyIdentifier() string {
@@ -1,12 +1,17 @@
 package frameworks_test
 
 import (
+	"errors"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 
+	"github.com/cloudfoundry/java-buildpack/src/internal/mocks"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
 	"github.com/cloudfoundry/java-buildpack/src/java/frameworks"
 	"github.com/cloudfoundry/libbuildpack"
+	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -44,6 +49,7 @@ var _ = Describe("OpenTelemetryJavaagentFramework", func() {
 	AfterEach(func() {
 		os.RemoveAll(tmpDir)
 		os.Unsetenv("VCAP_SERVICES")
+		os.Unsetenv("VCAP_APPLICATION")
 	})
 
 	Describe("Detect", func() {
@@ -193,13 +199,77 @@ var _ = Describe("OpenTelemetryJavaagentFramework", func() {
 		})
 	})
 
+	Describe("Supply with log export enabled", func() {
+		var (
+			mockCtrl      *gomock.Controller
+			mockManifest  *mocks.MockManifest
+			mockInstaller *mocks.MockInstaller
+		)
+
+		BeforeEach(func() {
+			os.Setenv("JBP_CONFIG_OPEN_TELEMETRY_JAVAAGENT", "{logs: {enabled: true}}")
+			mockCtrl = gomock.NewController(GinkgoT())
+			mockManifest = mocks.NewMockManifest(mockCtrl)
+			mockInstaller = mocks.NewMockInstaller(mockCtrl)
+			ctx.Manifest = mockManifest
+			ctx.Installer = mockInstaller
+
+			agent := libbuildpack.Dependency{Name: "open-telemetry-javaagent", Version: "2.10.0"}
+			mockManifest.EXPECT().DefaultVersion("open-telemetry-javaagent").Return(agent, nil)
+			mockInstaller.EXPECT().InstallDependency(agent, filepath.Join(depsDir, "0", "open_telemetry_javaagent")).Return(nil)
+
+			Expect(os.MkdirAll(filepath.Join(tmpDir, "BOOT-INF", "lib"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(tmpDir, "BOOT-INF", "lib", "logback-classic-1.5.6.jar"), []byte("jar"), 0644)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			os.Unsetenv("JBP_CONFIG_OPEN_TELEMETRY_JAVAAGENT")
+			mockCtrl.Finish()
+		})
+
+		It("installs the appender next to the logging library the application uses", func() {
+			appender := libbuildpack.Dependency{Name: "opentelemetry-logback-appender", Version: "2.10.0"}
+			mockManifest.EXPECT().DefaultVersion("opentelemetry-logback-appender").Return(appender, nil)
+			mockInstaller.EXPECT().InstallDependency(appender, filepath.Join(tmpDir, "BOOT-INF", "lib")).Return(nil)
+
+			Expect(framework.Supply()).To(Succeed())
+		})
+
+		It("keeps the appender the application provides", func() {
+			Expect(os.WriteFile(filepath.Join(tmpDir, "BOOT-INF", "lib", "opentelemetry-logback-appender-1.0-2.10.0-alpha.jar"), []byte("jar"), 0644)).To(Succeed())
+
+			Expect(framework.Supply()).To(Succeed())
+		})
+
+		It("relies on the javaagent when the appender is not in the manifest", func() {
+			mockManifest.EXPECT().DefaultVersion("opentelemetry-logback-appender").Return(libbuildpack.Dependency{}, errors.New("not found"))
+
+			Expect(framework.Supply()).To(Succeed())
+		})
+	})
+
 	Describe("Finalize", func() {
 		otelOptsFile := func() string {
 			return filepath.Join(depsDir, "0", "java_opts", "36_open_telemetry_javaagent.opts")
 		}
 
+		otelScript := func() string {
+			data, err := os.ReadFile(filepath.Join(depsDir, "0", "profile.d", "open_telemetry_javaagent.sh"))
+			Expect(err).NotTo(HaveOccurred())
+			return string(data)
+		}
+
+		// runtimeEnv sources the profile.d script with the given environment and returns the value of name
+		runtimeEnv := func(name string, env ...string) string {
+			cmd := exec.Command("bash", "-c", `source "$DEPS_DIR/0/profile.d/open_telemetry_javaagent.sh"; echo "${`+name+`}"`)
+			cmd.Env = append([]string{"DEPS_DIR=" + depsDir}, env...)
+			output, err := cmd.CombinedOutput()
+			Expect(err).NotTo(HaveOccurred(), string(output))
+			return strings.TrimSpace(string(output))
+		}
+
 		Context("with otel-collector service and otel.* credentials", func() {
-			It("writes javaagent and otel.* properties to opts file", func() {
+			It("writes the javaagent to the opts file and exports the otel.* credentials", func() {
 				os.Setenv("VCAP_SERVICES", `{
 					"otel-collector": [{
 						"name": "my-otel",
@@ -219,14 +289,17 @@ var _ = Describe("OpenTelemetryJavaagentFramework", func() {
 				Expect(err).NotTo(HaveOccurred())
 				opts := string(data)
 
-				Expect(opts).To(ContainSubstring("-javaagent:$DEPS_DIR/0/open_telemetry_javaagent/opentelemetry-javaagent.jar"))
-				Expect(opts).To(ContainSubstring("-Dotel.exporter.otlp.endpoint=http://collector:4318"))
-				Expect(opts).To(ContainSubstring("-Dotel.traces.sampler=always_on"))
+				Expect(opts).To(Equal("-javaagent:$DEPS_DIR/0/open_telemetry_javaagent/opentelemetry-javaagent.jar"))
+
+				script := otelScript()
+				Expect(script).To(ContainSubstring(`[ -n "${OTEL_EXPORTER_OTLP_ENDPOINT:-}" ] || export OTEL_EXPORTER_OTLP_ENDPOINT=http://collector:4318` + "\n"))
+				Expect(script).To(ContainSubstring(`[ -n "${OTEL_TRACES_SAMPLER:-}" ] || export OTEL_TRACES_SAMPLER=always_on` + "\n"))
+				Expect(runtimeEnv("OTEL_TRACES_SAMPLER", "OTEL_TRACES_SAMPLER=parentbased_always_off")).To(Equal("parentbased_always_off"))
 			})
 		})
 
 		Context("with credentials that do not start with otel.", func() {
-			It("does not include non-otel credentials as JVM properties", func() {
+			It("does not export non-otel credentials", func() {
 				os.Setenv("VCAP_SERVICES", `{
 					"otel-collector": [{
 						"name": "my-otel",
@@ -247,9 +320,10 @@ var _ = Describe("OpenTelemetryJavaagentFramework", func() {
 				Expect(err).NotTo(HaveOccurred())
 				opts := string(data)
 
-				Expect(opts).NotTo(ContainSubstring("-Dusername="))
-				Expect(opts).NotTo(ContainSubstring("-Dpassword="))
-				Expect(opts).To(ContainSubstring("-Dotel.exporter.otlp.endpoint=http://collector:4318"))
+				Expect(opts).NotTo(ContainSubstring("username"))
+				Expect(otelScript()).NotTo(ContainSubstring("USERNAME"))
+				Expect(otelScript()).NotTo(ContainSubstring("secret"))
+				Expect(otelScript()).To(ContainSubstring("export OTEL_EXPORTER_OTLP_ENDPOINT=http://collector:4318"))
 			})
 		})
 
@@ -268,7 +342,7 @@ var _ = Describe("OpenTelemetryJavaagentFramework", func() {
 		})
 
 		Context("with otel.service.name already set in credentials", func() {
-			It("does not add a second otel.service.name from the app name", func() {
+			It("exports the service name of the credentials", func() {
 				os.Setenv("VCAP_SERVICES", `{
 					"otel-collector": [{
 						"name": "my-otel",
@@ -279,17 +353,145 @@ var _ = Describe("OpenTelemetryJavaagentFramework", func() {
 						}
 					}]
 				}`)
+				os.Setenv("VCAP_APPLICATION", `{"application_name":"orders"}`)
 
-				err := framework.Finalize()
+				Expect(framework.Finalize()).To(Succeed())
+
+				Expect(otelScript()).To(ContainSubstring("export OTEL_SERVICE_NAME=explicit-service-name\n"))
+				// OTEL_SERVICE_NAME takes precedence over the service.name resource attribute
+				Expect(runtimeEnv("OTEL_SERVICE_NAME")).To(Equal("explicit-service-name"))
+			})
+		})
+
+		Context("with resource tags enabled", func() {
+			BeforeEach(func() {
+				os.Setenv("JBP_CONFIG_RESOURCE_TAGS", "{enabled: true, cloud_provider: azure, region: westeurope}")
+				os.Setenv("VCAP_APPLICATION", `{"application_name":"orders","cf_api":"https://api.sys.example.com",`+
+					`"organization_name":"shop","space_name":"prod"}`)
+			})
+
+			AfterEach(func() {
+				os.Unsetenv("JBP_CONFIG_RESOURCE_TAGS")
+				os.Unsetenv("VCAP_APPLICATION")
+			})
+
+			It("sets the resource attributes followed by those of the service", func() {
+				os.Setenv("VCAP_SERVICES", `{
+					"otel-collector": [{
+						"name": "my-otel",
+						"label": "otel-collector",
+						"tags": [],
+						"credentials": {
+							"otel.resource.attributes": "deployment.environment=production"
+						}
+					}]
+				}`)
+
+				Expect(framework.Finalize()).To(Succeed())
+
+				Expect(runtimeEnv("OTEL_RESOURCE_ATTRIBUTES", "CF_INSTANCE_INDEX=3", "OTEL_RESOURCE_ATTRIBUTES=team=checkout")).To(Equal(
+					"cloudfoundry.app.instance.id=3,service.name=orders,cloudfoundry.app.name=orders," +
+						"cloudfoundry.space.name=prod,cloudfoundry.org.name=shop,cloudfoundry.foundation=sys.example.com," +
+						"cloud.provider=azure,cloud.region=westeurope,deployment.environment=production,team=checkout"))
+
+				data, err := os.ReadFile(otelOptsFile())
 				Expect(err).NotTo(HaveOccurred())
+				Expect(string(data)).NotTo(ContainSubstring("otel.resource.attributes"))
+			})
+		})
+
+		Context("with VCAP_APPLICATION", func() {
+			BeforeEach(func() {
+				os.Setenv("VCAP_APPLICATION", `{"application_id":"a1b2","application_name":"orders api",`+
+					`"organization_id":"o1","organization_name":"shop","space_id":"s1","space_name":"prod"}`)
+			})
+
+			It("sets the resource attributes of the application and its instance", func() {
+				Expect(framework.Finalize()).To(Succeed())
+
+				Expect(runtimeEnv("OTEL_RESOURCE_ATTRIBUTES", "CF_INSTANCE_INDEX=1")).To(Equal(
+					"cloudfoundry.app.instance.id=1,service.name=orders%20api,cloudfoundry.app.id=a1b2,cloudfoundry.app.name=orders%20api," +
+						"cloudfoundry.space.id=s1,cloudfoundry.space.name=prod,cloudfoundry.org.id=o1,cloudfoundry.org.name=shop"))
+			})
+		})
+
+		Context("without VCAP_APPLICATION", func() {
+			It("sets only the instance resource attribute", func() {
+				Expect(framework.Finalize()).To(Succeed())
+
+				Expect(runtimeEnv("OTEL_RESOURCE_ATTRIBUTES")).To(Equal("cloudfoundry.app.instance.id=0"))
+			})
+		})
+
+		Context("with endpoint and headers-based authentication credentials", func() {
+			It("exports the OTLP exporter endpoint, protocol and URL-encoded headers", func() {
+				os.Setenv("VCAP_SERVICES", `{"user-provided": [{"name": "otel-collector", "label": "user-provided", "tags": [],
+					"credentials": {"endpoint": "https://collector.example.com:4317", "protocol": "grpc",
+						"headers": {"x-api-key": "k+y=1", "x-tenant": "shop"}, "authorization": "Bearer abc"}}]}`)
+
+				Expect(framework.Finalize()).To(Succeed())
+
+				Expect(runtimeEnv("OTEL_EXPORTER_OTLP_ENDPOINT")).To(Equal("https://collector.example.com:4317"))
+				Expect(runtimeEnv("OTEL_EXPORTER_OTLP_PROTOCOL")).To(Equal("grpc"))
+				Expect(runtimeEnv("OTEL_EXPORTER_OTLP_HEADERS")).To(Equal("x-api-key=k%2By=1,x-tenant=shop,Authorization=Bearer%20abc"))
 
 				data, err := os.ReadFile(otelOptsFile())
 				Expect(err).NotTo(HaveOccurred())
-				opts := string(data)
+				Expect(string(data)).NotTo(ContainSubstring("abc"))
+			})
+
+			It("prefers the otel.* credentials", func() {
+				os.Setenv("VCAP_SERVICES", `{"otel-collector": [{"name": "my-otel", "label": "otel-collector", "tags": [],
+					"credentials": {"endpoint": "https://ignored.example.com", "headers": "x-api-key=ignored",
+						"otel.exporter.otlp.endpoint": "https://collector.example.com", "otel.exporter.otlp.headers": "x-api-key=secret"}}]}`)
+
+				Expect(framework.Finalize()).To(Succeed())
+
+				Expect(otelScript()).NotTo(ContainSubstring("ignored"))
+				Expect(runtimeEnv("OTEL_EXPORTER_OTLP_ENDPOINT")).To(Equal("https://collector.example.com"))
+				Expect(runtimeEnv("OTEL_EXPORTER_OTLP_HEADERS")).To(Equal("x-api-key=secret"))
+			})
+		})
 
-				Expect(opts).To(ContainSubstring("-Dotel.service.name=explicit-service-name"))
-				// Only one occurrence of otel.service.name
-				Expect(countOccurrences(opts, "-Dotel.service.name=")).To(Equal(1))
+		Context("with log export enabled", func() {
+			BeforeEach(func() {
+				os.Setenv("JBP_CONFIG_OPEN_TELEMETRY_JAVAAGENT", "{logs: {enabled: true}}")
+			})
+
+			AfterEach(func() {
+				os.Unsetenv("JBP_CONFIG_OPEN_TELEMETRY_JAVAAGENT")
+			})
+
+			It("sets the logs exporter and enables the appender instrumentations", func() {
+				Expect(framework.Finalize()).To(Succeed())
+
+				data, err := os.ReadFile(otelOptsFile())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(data)).To(ContainSubstring("-Dotel.logs.exporter=otlp"))
+				Expect(string(data)).To(ContainSubstring("-Dotel.instrumentation.logback-appender.enabled=true"))
+				Expect(string(data)).To(ContainSubstring("-Dotel.instrumentation.log4j-appender.enabled=true"))
+			})
+
+			It("keeps the logs exporter from the service binding", func() {
+				os.Setenv("VCAP_SERVICES", `{"otel-collector": [{"name": "my-otel", "label": "otel-collector", "tags": [],
+					"credentials": {"otel.logs.exporter": "console"}}]}`)
+
+				Expect(framework.Finalize()).To(Succeed())
+
+				data, err := os.ReadFile(otelOptsFile())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(data)).NotTo(ContainSubstring("-Dotel.logs.exporter="))
+				Expect(runtimeEnv("OTEL_LOGS_EXPORTER")).To(Equal("console"))
+			})
+		})
+
+		Context("with log export disabled", func() {
+			It("does not configure log export", func() {
+				Expect(framework.Finalize()).To(Succeed())
+
+				data, err := os.ReadFile(otelOptsFile())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(data)).NotTo(ContainSubstring("otel.logs.exporter"))
 			})
 		})
 
@@ -306,17 +508,3 @@ var _ = Describe("OpenTelemetryJavaagentFramework", func() {
 		})
 	})
 })
-
-// countOccurrences counts non-overlapping occurrences of substr in s.
-func countOccurrences(s, substr string) int {
-	count := 0
-	for i := 0; i <= len(s)-len(substr); {
-		if s[i:i+len(substr)] == substr {
-			count++
-			i += len(substr)
-		} else {
-			i++
-		}
-	}
-	return count
-}
@@ -0,0 +1,260 @@
+package frameworks
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/applibs"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/httpclient"
+)
+
+const oracleServiceFilter = "oracle"
+
+// oracleDriverPatterns match the Oracle JDBC drivers an application may already contain
+var oracleDriverPatterns = []string{"ojdbc*.jar"}
+
+// oracleJdbcConfig is the JBP_CONFIG_ORACLE_JDBC configuration, e.g.
+// '{repository_root: "https://repo.example.com/ojdbc", version: 23.+}'
+type oracleJdbcConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// RepositoryRoot is the URL of a repository whose index.yml maps driver versions to the URI and SHA256 of
+	// their ojdbc JAR. The driver may not be redistributed, so the buildpack has no repository of its own.
+	RepositoryRoot string `yaml:"repository_root"`
+	// Version is an exact version, a version prefix followed by .+, e.g. 23.+, or empty for the newest version
+	Version string `yaml:"version"`
+}
+
+// OracleJdbcFramework places the Oracle JDBC driver on the classpath of applications bound to an Oracle service
+// that do not contain a driver themselves. The driver is downloaded from a repository the operator or the user
+// provides.
+type OracleJdbcFramework struct {
+	context *common.Context
+}
+
+// NewOracleJdbcFramework creates a new Oracle JDBC framework instance
+func NewOracleJdbcFramework(ctx *common.Context) *OracleJdbcFramework {
+	return &OracleJdbcFramework{context: ctx}
+}
+
+// Detect checks if an Oracle service is bound and the application has no Oracle JDBC driver
+func (o *OracleJdbcFramework) Detect() (string, error) {
+	if _, ok := o.findService(); !ok {
+		o.context.Log.Debug("Oracle JDBC: no %s service bound", oracleServiceFilter)
+		return "", nil
+	}
+
+	if driver, ok := applibs.Find(o.context.Stager.BuildDir(), oracleDriverPatterns...); ok {
+		o.context.Log.Info("Oracle JDBC: driver %s found in application, not installing a driver", driver)
+		return "", nil
+	}
+
+	cfg, err := o.loadConfig()
+	if err != nil {
+		return "", err
+	}
+	if !cfg.Enabled {
+		o.context.Log.Debug("Oracle JDBC: disabled by configuration")
+		return "", nil
+	}
+
+	o.context.Log.Debug("Oracle JDBC framework detected")
+	return "oracle-jdbc", nil
+}
+
+// Supply downloads the driver of the configured version from the repository and verifies its checksum
+func (o *OracleJdbcFramework) Supply() error {
+	cfg, err := o.loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if cfg.RepositoryRoot == "" {
+		return fmt.Errorf("an %s service is bound but the Oracle JDBC driver cannot be installed: it may not be "+
+			"redistributed with the buildpack and no repository is configured. Set %s='{repository_root: "+
+			"\"https://<host>/<path>\"}' to a repository whose index.yml lists the ojdbc JARs, add the driver to the "+
+			"application, or set %s='{enabled: false}'", oracleServiceFilter, config.EnvVar("oracle_jdbc"),
+			config.EnvVar("oracle_jdbc"))
+	}
+	repositoryRoot := strings.TrimSuffix(cfg.RepositoryRoot, "/")
+
+	if common.IsOffline() {
+		return common.OfflineError("The Oracle JDBC driver", repositoryRoot, "add the driver to the application")
+	}
+
+	indexURL := repositoryRoot + "/index.yml"
+	redactedIndexURL := common.RedactURI(indexURL)
+	index, err := o.downloadIndex(indexURL)
+	if err != nil {
+		return err
+	}
+
+	version, err := resolveOracleJdbcVersion(cfg.Version, index)
+	if err != nil {
+		return fmt.Errorf("%w in %s", err, redactedIndexURL)
+	}
+	entry := index[version]
+	if entry.SHA256 == "" {
+		return fmt.Errorf("Oracle JDBC %s in %s has no sha256: the driver is only installed if its checksum can be "+
+			"verified, list it as '%s: {uri: <uri>, sha256: <sha256>}'", version, redactedIndexURL, version)
+	}
+
+	if err := os.MkdirAll(o.driverDir(), 0755); err != nil {
+		return fmt.Errorf("failed to create Oracle JDBC directory: %w", err)
+	}
+	jar := filepath.Join(o.driverDir(), fmt.Sprintf("ojdbc-%s.jar", version))
+	o.context.Log.Info("Downloading Oracle JDBC %s from %s", version, common.RedactURI(repositoryRoot))
+	if err := httpclient.Default().DownloadVerified(entry.URI, jar, entry.SHA256); err != nil {
+		return fmt.Errorf("failed to download Oracle JDBC %s: %w", version, err)
+	}
+
+	if err := common.RecordDownload(o.context.Stager.DepDir(), common.InstalledDependency{
+		Name: "ojdbc", Version: version, URI: entry.URI, Dir: filepath.Base(o.driverDir()),
+	}, jar); err != nil {
+		o.context.Log.Warning("Could not record Oracle JDBC %s for the SBOM: %s", version, err.Error())
+	}
+	o.context.Log.Info("Oracle JDBC %s installed", version)
+	return nil
+}
+
+// Finalize adds the driver to CLASSPATH, from which the containers make it available to the application
+func (o *OracleJdbcFramework) Finalize() error {
+	matches, err := filepath.Glob(filepath.Join(o.driverDir(), "ojdbc-*.jar"))
+	if err != nil || len(matches) == 0 {
+		return fmt.Errorf("jdbc jar not found in %s", o.driverDir())
+	}
+
+	o.context.Log.BeginStep("Configuring Oracle JDBC driver")
+
+	runtimePath := o.context.Droplet().Dep("oracle_jdbc", filepath.Base(matches[0]))
+	profileScript := fmt.Sprintf("export CLASSPATH=\"%s${CLASSPATH:+:$CLASSPATH}\"\n", runtimePath)
+	if err := o.context.Stager.WriteProfileD("oracle_jdbc.sh", profileScript); err != nil {
+		return fmt.Errorf("failed to write oracle_jdbc.sh profile.d script: %w", err)
+	}
+
+	o.context.Log.Debug("Oracle JDBC will be added to classpath at runtime: %s", runtimePath)
+	return nil
+}
+
+func (o *OracleJdbcFramework) driverDir() string {
+	return filepath.Join(o.context.Stager.DepDir(), "oracle_jdbc")
+}
+
+func (o *OracleJdbcFramework) loadConfig() (*oracleJdbcConfig, error) {
+	cfg := oracleJdbcConfig{Enabled: true}
+	if err := config.Load(o.context.Log, "oracle_jdbc", &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// oracleJdbcArtifact is a driver version listed in the index.yml of a repository
+type oracleJdbcArtifact struct {
+	URI    string
+	SHA256 string
+}
+
+// downloadIndex downloads and parses the index.yml of the repository. A version maps either to the URI of its JAR
+// or to a mapping with uri and sha256 keys.
+func (o *OracleJdbcFramework) downloadIndex(indexURL string) (map[string]oracleJdbcArtifact, error) {
+	resp, err := httpclient.Default().Get(indexURL)
+	indexURL = common.RedactURI(indexURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download the Oracle JDBC repository index %s: %w", indexURL, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", indexURL, err)
+	}
+
+	var raw map[string]interface{}
+	if err := (common.YamlHandler{}).Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", indexURL, err)
+	}
+
+	index := make(map[string]oracleJdbcArtifact, len(raw))
+	for version, entry := range raw {
+		switch entry := entry.(type) {
+		case string:
+			index[version] = oracleJdbcArtifact{URI: entry}
+		case map[string]interface{}:
+			uri, _ := entry["uri"].(string)
+			sha256, _ := entry["sha256"].(string)
+			if uri == "" {
+				return nil, fmt.Errorf("Oracle JDBC %s in %s has no uri", version, indexURL)
+			}
+			index[version] = oracleJdbcArtifact{URI: uri, SHA256: sha256}
+		default:
+			return nil, fmt.Errorf("Oracle JDBC %s in %s must be a URI or a mapping with uri and sha256", version, indexURL)
+		}
+	}
+	return index, nil
+}
+
+// findService returns the first service, in sorted label order, whose label, name or tags contain "oracle" and that
+// has a uri, jdbcUrl or hostname credential
+func (o *OracleJdbcFramework) findService() (VCAPService, bool) {
+	vcapServices, err := GetVCAPServices()
+	if err != nil {
+		o.context.Log.Warning("Failed to parse VCAP_SERVICES: %s", err.Error())
+		return VCAPService{}, false
+	}
+
+	for _, service := range vcapServices.Sorted() {
+		if !common.ContainsIgnoreCase(service.Label, oracleServiceFilter) &&
+			!common.ContainsIgnoreCase(service.Name, oracleServiceFilter) &&
+			!service.HasTag(oracleServiceFilter) {
+			continue
+		}
+		for _, credential := range []string{"uri", "jdbcUrl", "hostname"} {
+			if credentialString(service.Credentials, credential) != "" {
+				return service, true
+			}
+		}
+	}
+	return VCAPService{}, false
+}
+
+// resolveOracleJdbcVersion returns the newest version of index that matches pattern: an exact version, a prefix
+// followed by .+, or empty for any version. Oracle numbers its drivers with up to five parts, e.g. 23.5.0.24.07,
+// which are compared numerically.
+func resolveOracleJdbcVersion(pattern string, index map[string]oracleJdbcArtifact) (string, error) {
+	versions := make([]string, 0, len(index))
+	for version := range index {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return compareDottedVersions(versions[i], versions[j]) > 0 })
+
+	prefix, wildcard := strings.CutSuffix(pattern, "+")
+	for _, version := range versions {
+		if pattern == "" || (wildcard && strings.HasPrefix(version, prefix)) || version == pattern {
+			return version, nil
+		}
+	}
+	return "", fmt.Errorf("no Oracle JDBC version matches %q (available versions: %s)", pattern, strings.Join(versions, ", "))
+}
+
+// compareDottedVersions compares the dot-separated parts of a and b numerically, or as strings if they are not
+// numbers, and returns a negative number, zero or a positive number if a is older than, equal to or newer than b
+func compareDottedVersions(a, b string) int {
+	aParts, bParts := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		aNumber, aErr := strconv.Atoi(aParts[i])
+		bNumber, bErr := strconv.Atoi(bParts[i])
+		switch {
+		case aErr == nil && bErr == nil && aNumber != bNumber:
+			return aNumber - bNumber
+		case (aErr != nil || bErr != nil) && aParts[i] != bParts[i]:
+			return strings.Compare(aParts[i], bParts[i])
+		}
+	}
+	return len(aParts) - len(bParts)
+}
@@ -0,0 +1,235 @@
+package frameworks_test
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/java-buildpack/src/internal/fakevendors"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/httpclient"
+	"github.com/cloudfoundry/java-buildpack/src/java/frameworks"
+)
+
+var _ = Describe("OracleJdbc", func() {
+	const oracleService = `{"oracle-db":[{"name":"orders-db","label":"oracle-db","tags":["oracle"],` +
+		`"credentials":{"jdbcUrl":"jdbc:oracle:thin:@//orders.example.com:1521/ORDERS"}}]}`
+
+	var (
+		fw       *frameworks.OracleJdbcFramework
+		buildDir string
+		cacheDir string
+		depsDir  string
+	)
+
+	BeforeEach(func() {
+		var err error
+		buildDir, err = os.MkdirTemp("", "oracle-build")
+		Expect(err).NotTo(HaveOccurred())
+		cacheDir, err = os.MkdirTemp("", "oracle-cache")
+		Expect(err).NotTo(HaveOccurred())
+		depsDir, err = os.MkdirTemp("", "oracle-deps")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.MkdirAll(filepath.Join(depsDir, "0"), 0755)).To(Succeed())
+
+		fw = frameworks.NewOracleJdbcFramework(newMariaDBContext(buildDir, cacheDir, depsDir))
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(buildDir)
+		os.RemoveAll(cacheDir)
+		os.RemoveAll(depsDir)
+		os.Unsetenv("VCAP_SERVICES")
+		os.Unsetenv("JBP_CONFIG_ORACLE_JDBC")
+		os.Unsetenv("JBP_OFFLINE")
+	})
+
+	Describe("Detect", func() {
+		It("is not detected without VCAP_SERVICES", func() {
+			name, err := fw.Detect()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(BeEmpty())
+		})
+
+		DescribeTable("detects Oracle services",
+			func(services string) {
+				os.Setenv("VCAP_SERVICES", services)
+
+				name, err := fw.Detect()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(name).To(Equal("oracle-jdbc"))
+			},
+			Entry("by label", oracleService),
+			Entry("by oracle tag", `{"user-provided":[{"name":"db","label":"user-provided","tags":["oracle"],`+
+				`"credentials":{"hostname":"db.example.com"}}]}`),
+			Entry("by name", `{"user-provided":[{"name":"orders-oracle","label":"user-provided","tags":[],`+
+				`"credentials":{"uri":"oracle://db.example.com:1521/ORDERS"}}]}`),
+		)
+
+		It("ignores Oracle services without connection credentials", func() {
+			os.Setenv("VCAP_SERVICES", `{"oracle":[{"name":"db","label":"oracle","tags":[],"credentials":{}}]}`)
+
+			name, err := fw.Detect()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(BeEmpty())
+		})
+
+		It("is not detected when disabled", func() {
+			os.Setenv("VCAP_SERVICES", oracleService)
+			os.Setenv("JBP_CONFIG_ORACLE_JDBC", "{enabled: false}")
+
+			name, err := fw.Detect()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(BeEmpty())
+		})
+
+		DescribeTable("is not detected when the application contains a driver",
+			func(driver string) {
+				os.Setenv("VCAP_SERVICES", oracleService)
+				Expect(os.MkdirAll(filepath.Join(buildDir, filepath.Dir(driver)), 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(buildDir, driver), []byte("jar"), 0644)).To(Succeed())
+
+				name, err := fw.Detect()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(name).To(BeEmpty())
+			},
+			Entry("in WEB-INF/lib", "WEB-INF/lib/ojdbc11.jar"),
+			Entry("in BOOT-INF/lib", "BOOT-INF/lib/ojdbc11-23.5.0.24.07.jar"),
+			Entry("in the lib directory of a distribution", "orders-1.0/lib/ojdbc8.jar"),
+		)
+	})
+
+	Describe("Supply", func() {
+		var (
+			repository *fakevendors.Repository
+			jar        = []byte("ojdbc jar")
+		)
+
+		BeforeEach(func() {
+			repository = fakevendors.NewRepository()
+			os.Setenv("JBP_OFFLINE", "false")
+			os.Setenv("JBP_CONFIG_ORACLE_JDBC", fmt.Sprintf("{repository_root: %s/}", repository.RepositoryRoot()))
+			httpclient.Default().SetRetryInterval(time.Millisecond)
+		})
+
+		AfterEach(func() {
+			repository.Close()
+			httpclient.Default().SetRetryInterval(time.Second)
+		})
+
+		installed := func() []string {
+			matches, err := filepath.Glob(filepath.Join(depsDir, "0", "oracle_jdbc", "*.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			return matches
+		}
+
+		It("fails with instructions when no repository is configured", func() {
+			os.Unsetenv("JBP_CONFIG_ORACLE_JDBC")
+
+			err := fw.Supply()
+			Expect(err).To(MatchError(ContainSubstring("no repository is configured")))
+			Expect(err).To(MatchError(ContainSubstring("JBP_CONFIG_ORACLE_JDBC='{repository_root:")))
+			Expect(err).To(MatchError(ContainSubstring("{enabled: false}")))
+		})
+
+		It("installs the newest version after verifying its checksum", func() {
+			repository.AddArtifact("23.4.0.24.05", "ojdbc11-23.4.0.24.05.jar", jar)
+			repository.AddArtifact("23.5.0.24.07", "ojdbc11-23.5.0.24.07.jar", jar)
+			repository.AddArtifact("21.15.0.0", "ojdbc11-21.15.0.0.jar", jar)
+
+			Expect(fw.Supply()).To(Succeed())
+
+			Expect(installed()).To(ConsistOf(filepath.Join(depsDir, "0", "oracle_jdbc", "ojdbc-23.5.0.24.07.jar")))
+		})
+
+		It("installs the newest version matching the configured version", func() {
+			os.Setenv("JBP_CONFIG_ORACLE_JDBC", fmt.Sprintf("{repository_root: %s, version: 21.+}", repository.RepositoryRoot()))
+			repository.AddArtifact("23.5.0.24.07", "ojdbc11-23.5.0.24.07.jar", jar)
+			repository.AddArtifact("21.15.0.0", "ojdbc11-21.15.0.0.jar", jar)
+
+			Expect(fw.Supply()).To(Succeed())
+
+			Expect(installed()).To(ConsistOf(filepath.Join(depsDir, "0", "oracle_jdbc", "ojdbc-21.15.0.0.jar")))
+		})
+
+		It("fails when no version matches", func() {
+			os.Setenv("JBP_CONFIG_ORACLE_JDBC", fmt.Sprintf("{repository_root: %s, version: 19.+}", repository.RepositoryRoot()))
+			repository.AddArtifact("23.5.0.24.07", "ojdbc11-23.5.0.24.07.jar", jar)
+
+			Expect(fw.Supply()).To(MatchError(ContainSubstring(`no Oracle JDBC version matches "19.+"`)))
+		})
+
+		It("refuses versions without a checksum", func() {
+			repository.AddUnverifiedArtifact("23.5.0.24.07", "ojdbc11-23.5.0.24.07.jar", jar)
+
+			Expect(fw.Supply()).To(MatchError(ContainSubstring("has no sha256")))
+			Expect(installed()).To(BeEmpty())
+		})
+
+		It("fails when the checksum does not match", func() {
+			repository.AddArtifact("23.5.0.24.07", "ojdbc11-23.5.0.24.07.jar", jar)
+			repository.SetIndexEntry("23.5.0.24.07", fmt.Sprintf("{uri: %s/ojdbc11-23.5.0.24.07.jar, sha256: '%064d'}",
+				repository.RepositoryRoot(), 0))
+
+			Expect(fw.Supply()).To(MatchError(ContainSubstring("failed to download Oracle JDBC 23.5.0.24.07")))
+			Expect(installed()).To(BeEmpty())
+		})
+
+		It("retries transient failures of the repository", func() {
+			repository.AddArtifact("23.5.0.24.07", "ojdbc11-23.5.0.24.07.jar", jar)
+			repository.FailNext(repository.IndexPath(), 1, http.StatusServiceUnavailable)
+			repository.FailNext(repository.ArtifactPath("ojdbc11-23.5.0.24.07.jar"), 2, http.StatusBadGateway)
+
+			Expect(fw.Supply()).To(Succeed())
+
+			Expect(installed()).To(ConsistOf(filepath.Join(depsDir, "0", "oracle_jdbc", "ojdbc-23.5.0.24.07.jar")))
+			Expect(repository.RequestCount(repository.IndexPath())).To(Equal(2))
+			Expect(repository.RequestCount(repository.ArtifactPath("ojdbc11-23.5.0.24.07.jar"))).To(Equal(3))
+		})
+
+		It("fails when the repository keeps failing", func() {
+			repository.AddArtifact("23.5.0.24.07", "ojdbc11-23.5.0.24.07.jar", jar)
+			repository.FailAlways(repository.ArtifactPath("ojdbc11-23.5.0.24.07.jar"), http.StatusServiceUnavailable)
+
+			Expect(fw.Supply()).To(MatchError(ContainSubstring("failed to download Oracle JDBC 23.5.0.24.07")))
+			Expect(repository.RequestCount(repository.ArtifactPath("ojdbc11-23.5.0.24.07.jar"))).To(Equal(4))
+			Expect(installed()).To(BeEmpty())
+		})
+
+		It("fails when the repository has no index", func() {
+			os.Setenv("JBP_CONFIG_ORACLE_JDBC", fmt.Sprintf("{repository_root: %s/missing}", repository.RepositoryRoot()))
+
+			Expect(fw.Supply()).To(MatchError(ContainSubstring("failed to download the Oracle JDBC repository index")))
+		})
+
+		It("does not download when offline", func() {
+			os.Setenv("JBP_OFFLINE", "true")
+
+			Expect(fw.Supply()).To(MatchError(ContainSubstring("the buildpack runs offline")))
+			Expect(repository.Requests()).To(BeEmpty())
+		})
+	})
+
+	Describe("Finalize", func() {
+		It("adds the driver to CLASSPATH for the containers to link", func() {
+			driverDir := filepath.Join(depsDir, "0", "oracle_jdbc")
+			Expect(os.MkdirAll(driverDir, 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(driverDir, "ojdbc-23.5.0.24.07.jar"), []byte("jar"), 0644)).To(Succeed())
+
+			Expect(fw.Finalize()).To(Succeed())
+
+			content, err := os.ReadFile(filepath.Join(depsDir, "0", "profile.d", "oracle_jdbc.sh"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal(
+				"export CLASSPATH=\"$DEPS_DIR/0/oracle_jdbc/ojdbc-23.5.0.24.07.jar${CLASSPATH:+:$CLASSPATH}\"\n"))
+		})
+
+		It("fails when the driver is not installed", func() {
+			Expect(fw.Finalize()).To(MatchError(ContainSubstring("jdbc jar not found")))
+		})
+	})
+})
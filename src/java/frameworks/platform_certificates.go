@@ -0,0 +1,299 @@
+package frameworks
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
+)
+
+const (
+	// systemCertPathEnvVar is the directory in which Cloud Foundry provides the CA certificates trusted by the
+	// platform, e.g. of internal services and proxies
+	systemCertPathEnvVar = "CF_SYSTEM_CERT_PATH"
+	// appCertificatesDir is the directory of the application with additional CA certificates
+	appCertificatesDir = ".certs"
+	// platformCertificatesDir holds the dedicated trust store in the deps directory
+	platformCertificatesDir = "platform_certificates"
+	// trustStorePassword is the password of the JRE's cacerts, which the dedicated trust store keeps
+	trustStorePassword = "changeit"
+)
+
+// Trust stores that the certificates can be added to
+const (
+	jreTrustStore       = "jre"
+	dedicatedTrustStore = "dedicated"
+)
+
+// PlatformCertificatesFramework adds the CA certificates of the platform, in CF_SYSTEM_CERT_PATH, and those the
+// application brings in .certs to the trust store of the JVM, so that the application trusts platform-internal CAs
+// without keytool steps of its own. It is disabled by default: the Container Security Provider already trusts the
+// certificates of the container at runtime, whereas this framework runs keytool once per certificate and keeps the
+// certificates of the staging cell in the droplet.
+type PlatformCertificatesFramework struct {
+	context *common.Context
+}
+
+// NewPlatformCertificatesFramework creates a new platform CA certificates framework instance
+func NewPlatformCertificatesFramework(ctx *common.Context) *PlatformCertificatesFramework {
+	return &PlatformCertificatesFramework{context: ctx}
+}
+
+// platformCertificate is a CA certificate to add to the trust store
+type platformCertificate struct {
+	// alias identifies the certificate in the trust store, e.g. platform-0123456789ab
+	alias string
+	// source is the file the certificate was read from
+	source string
+	pem    []byte
+}
+
+// Detect returns the framework name if enabled and CF_SYSTEM_CERT_PATH or .certs holds certificate files
+func (p *PlatformCertificatesFramework) Detect() (string, error) {
+	cfg, err := p.loadConfig()
+	if err != nil {
+		if common.IsUnknownConfigKeysError(err) {
+			return "", err
+		}
+		p.context.Log.Warning("Failed to load platform certificates config: %s", err.Error())
+		return "", nil // Don't fail the build
+	}
+	if !cfg.Enabled {
+		return "", nil
+	}
+
+	if len(p.certificateFiles()) == 0 {
+		p.context.Log.Debug("Platform CA Certificates: no certificates in %s or %s", systemCertPathEnvVar, appCertificatesDir)
+		return "", nil
+	}
+	return "Platform CA Certificates", nil
+}
+
+// Supply does nothing: the certificates are added once the JRE is installed
+func (p *PlatformCertificatesFramework) Supply() error {
+	return nil
+}
+
+// Finalize adds the certificates to the cacerts of the JRE or, with the dedicated trust store, to a copy of it that
+// JAVA_OPTS points the JVM to
+func (p *PlatformCertificatesFramework) Finalize() error {
+	cfg, err := p.loadConfig()
+	if err != nil {
+		return err
+	}
+
+	certificates, err := p.certificates()
+	if err != nil {
+		return err
+	}
+	if len(certificates) == 0 {
+		return nil
+	}
+
+	cacerts, err := jreCACerts()
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(p.context.Stager.DepDir(), platformCertificatesDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s directory: %w", platformCertificatesDir, err)
+	}
+
+	trustStore := cacerts
+	if cfg.TrustStore == dedicatedTrustStore {
+		trustStore = filepath.Join(dir, "cacerts")
+		if err := copyFile(cacerts, trustStore); err != nil {
+			return fmt.Errorf("failed to copy the JRE's trust store: %w", err)
+		}
+	}
+
+	for _, certificate := range certificates {
+		if err := p.importCertificate(dir, trustStore, certificate); err != nil {
+			return err
+		}
+	}
+
+	if cfg.TrustStore == dedicatedTrustStore {
+		opts := fmt.Sprintf("-Djavax.net.ssl.trustStore=%s -Djavax.net.ssl.trustStorePassword=%s",
+			p.context.Droplet().Dep(platformCertificatesDir, "cacerts"), trustStorePassword)
+		if err := writeJavaOptsFile(p.context, javaopts.PlatformCertificates, "platform_certificates", opts); err != nil {
+			return fmt.Errorf("failed to write java_opts file: %w", err)
+		}
+	}
+
+	p.context.Log.Info("Added %d CA certificate(s) to the %s trust store", len(certificates), cfg.TrustStore)
+	return nil
+}
+
+// importCertificate adds certificate to trustStore with keytool, staging its PEM in dir
+func (p *PlatformCertificatesFramework) importCertificate(dir, trustStore string, certificate platformCertificate) error {
+	file := filepath.Join(dir, certificate.alias+".pem")
+	if err := os.WriteFile(file, certificate.pem, 0644); err != nil {
+		return fmt.Errorf("failed to write certificate %s: %w", certificate.alias, err)
+	}
+	defer os.Remove(file)
+
+	output := new(strings.Builder)
+	if err := p.context.Command.Execute(dir, output, output, filepath.Join(os.Getenv("JAVA_HOME"), "bin", "keytool"),
+		"-importcert", "-noprompt", "-alias", certificate.alias, "-file", file,
+		"-keystore", trustStore, "-storepass", trustStorePassword); err != nil {
+		return fmt.Errorf("failed to add the certificate of %s to the trust store: %w, output: %s",
+			certificate.source, err, output.String())
+	}
+	p.context.Log.Debug("Added %s from %s to %s", certificate.alias, certificate.source, trustStore)
+	return nil
+}
+
+// certificateFiles returns the files in CF_SYSTEM_CERT_PATH and the application's .certs directory, sorted within
+// each directory
+func (p *PlatformCertificatesFramework) certificateFiles() []string {
+	var files []string
+	for _, dir := range []string{os.Getenv(systemCertPathEnvVar), filepath.Join(p.context.Stager.BuildDir(), appCertificatesDir)} {
+		if dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		var names []string
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				names = append(names, entry.Name())
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	return files
+}
+
+// certificates reads the certificates of certificateFiles, in PEM, possibly several per file, or DER. A certificate
+// found in several files is added once; files without certificates are skipped with a warning.
+func (p *PlatformCertificatesFramework) certificates() ([]platformCertificate, error) {
+	var certificates []platformCertificate
+	seen := map[string]bool{}
+	for _, file := range p.certificateFiles() {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read certificate file: %w", err)
+		}
+
+		prefix := "platform"
+		if filepath.Base(filepath.Dir(file)) == appCertificatesDir {
+			prefix = "app"
+		}
+
+		ders := certificateDERs(data)
+		if len(ders) == 0 {
+			p.context.Log.Warning("Skipping %s: it contains no X.509 certificate", file)
+			continue
+		}
+		for _, der := range ders {
+			sum := sha256.Sum256(der)
+			fingerprint := hex.EncodeToString(sum[:])
+			if seen[fingerprint] {
+				continue
+			}
+			seen[fingerprint] = true
+			certificates = append(certificates, platformCertificate{
+				alias:  prefix + "-" + fingerprint[:12],
+				source: file,
+				pem:    pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			})
+		}
+	}
+	return certificates, nil
+}
+
+// certificateDERs returns the DER encoding of the X.509 certificates in data
+func certificateDERs(data []byte) [][]byte {
+	var ders [][]byte
+	for rest := data; ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err == nil {
+			ders = append(ders, block.Bytes)
+		}
+	}
+	if len(ders) == 0 {
+		if _, err := x509.ParseCertificate(data); err == nil {
+			ders = append(ders, data)
+		}
+	}
+	return ders
+}
+
+// jreCACerts returns the cacerts trust store of the JRE in JAVA_HOME: lib/security/cacerts of Java 9 and later, or
+// jre/lib/security/cacerts of a Java 8 JDK
+func jreCACerts() (string, error) {
+	javaHome := os.Getenv("JAVA_HOME")
+	if javaHome == "" {
+		return "", fmt.Errorf("JAVA_HOME is not set")
+	}
+	for _, path := range []string{
+		filepath.Join(javaHome, "lib", "security", "cacerts"),
+		filepath.Join(javaHome, "jre", "lib", "security", "cacerts"),
+	} {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no cacerts trust store in %s", javaHome)
+}
+
+// copyFile copies the file src to dst
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0644)
+}
+
+type platformCertificatesConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// TrustStore is jre to add the certificates to the JRE's cacerts, or dedicated to add them to a copy of it
+	TrustStore string `yaml:"trust_store"`
+}
+
+// Check returns a message if trust_store is not jre or dedicated
+func (c *platformCertificatesConfig) Check() []string {
+	if c.TrustStore != jreTrustStore && c.TrustStore != dedicatedTrustStore {
+		return []string{fmt.Sprintf("trust_store must be %s or %s, not %q", jreTrustStore, dedicatedTrustStore, c.TrustStore)}
+	}
+	return nil
+}
+
+func (p *PlatformCertificatesFramework) loadConfig() (*platformCertificatesConfig, error) {
+	// initialize default values
+	cfg := platformCertificatesConfig{
+		Enabled:    false,
+		TrustStore: jreTrustStore,
+	}
+	// overlay buildpack defaults and JBP_CONFIG_PLATFORM_CERTIFICATES over default values
+	if err := config.Load(p.context.Log, "platform_certificates", &cfg); err != nil {
+		return nil, err
+	}
+	if problems := cfg.Check(); len(problems) > 0 {
+		return nil, fmt.Errorf("invalid %s: %s", config.EnvVar("platform_certificates"), strings.Join(problems, "; "))
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,189 @@
+package frameworks_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/frameworks"
+	"github.com/cloudfoundry/libbuildpack"
+)
+
+// certificatePEM returns a self-signed CA certificate for name in PEM
+func certificatePEM(name string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+var _ = Describe("PlatformCertificates", func() {
+	var (
+		fw         *frameworks.PlatformCertificatesFramework
+		buildDir   string
+		depsDir    string
+		depDir     string
+		certDir    string
+		javaHome   string
+		cacerts    string
+		keytoolLog string
+	)
+
+	BeforeEach(func() {
+		var err error
+		buildDir, err = os.MkdirTemp("", "platform-certs-build")
+		Expect(err).NotTo(HaveOccurred())
+		depsDir, err = os.MkdirTemp("", "platform-certs-deps")
+		Expect(err).NotTo(HaveOccurred())
+		depDir = filepath.Join(depsDir, "0")
+		Expect(os.MkdirAll(depDir, 0755)).To(Succeed())
+		certDir, err = os.MkdirTemp("", "platform-certs-system")
+		Expect(err).NotTo(HaveOccurred())
+
+		// A fake keytool records its arguments, one invocation per line
+		javaHome, err = os.MkdirTemp("", "platform-certs-jdk")
+		Expect(err).NotTo(HaveOccurred())
+		keytoolLog = filepath.Join(javaHome, "keytool.log")
+		Expect(os.MkdirAll(filepath.Join(javaHome, "bin"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(javaHome, "bin", "keytool"),
+			[]byte("#!/bin/sh\necho \"$@\" >> "+keytoolLog+"\n"), 0755)).To(Succeed())
+		cacerts = filepath.Join(javaHome, "lib", "security", "cacerts")
+		Expect(os.MkdirAll(filepath.Dir(cacerts), 0755)).To(Succeed())
+		Expect(os.WriteFile(cacerts, []byte("jre cacerts"), 0644)).To(Succeed())
+		os.Setenv("JAVA_HOME", javaHome)
+		os.Setenv("CF_SYSTEM_CERT_PATH", certDir)
+		os.Setenv("JBP_CONFIG_PLATFORM_CERTIFICATES", "{enabled: true}")
+
+		logger := libbuildpack.NewLogger(GinkgoWriter)
+		fw = frameworks.NewPlatformCertificatesFramework(&common.Context{
+			Stager:  libbuildpack.NewStager([]string{buildDir, "", depsDir, "0"}, logger, &libbuildpack.Manifest{}),
+			Log:     logger,
+			Command: &libbuildpack.Command{},
+		})
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(buildDir)
+		os.RemoveAll(depsDir)
+		os.RemoveAll(certDir)
+		os.RemoveAll(javaHome)
+		os.Unsetenv("JAVA_HOME")
+		os.Unsetenv("CF_SYSTEM_CERT_PATH")
+		os.Unsetenv("JBP_CONFIG_PLATFORM_CERTIFICATES")
+	})
+
+	invocations := func() []string {
+		data, err := os.ReadFile(keytoolLog)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		Expect(err).NotTo(HaveOccurred())
+		return strings.Split(strings.TrimSpace(string(data)), "\n")
+	}
+
+	Describe("Detect", func() {
+		It("does not detect without certificates", func() {
+			Expect(fw.Detect()).To(BeEmpty())
+		})
+
+		It("detects the certificates of the platform", func() {
+			Expect(os.WriteFile(filepath.Join(certDir, "platform.crt"), certificatePEM("platform"), 0644)).To(Succeed())
+			Expect(fw.Detect()).To(Equal("Platform CA Certificates"))
+		})
+
+		It("detects the certificates of the application", func() {
+			os.Unsetenv("CF_SYSTEM_CERT_PATH")
+			Expect(os.MkdirAll(filepath.Join(buildDir, ".certs"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(buildDir, ".certs", "app.pem"), certificatePEM("app"), 0644)).To(Succeed())
+			Expect(fw.Detect()).To(Equal("Platform CA Certificates"))
+		})
+
+		It("does not detect by default", func() {
+			Expect(os.WriteFile(filepath.Join(certDir, "platform.crt"), certificatePEM("platform"), 0644)).To(Succeed())
+			os.Unsetenv("JBP_CONFIG_PLATFORM_CERTIFICATES")
+			Expect(fw.Detect()).To(BeEmpty())
+		})
+	})
+
+	Describe("Finalize", func() {
+		BeforeEach(func() {
+			bundle := append(certificatePEM("platform-a"), certificatePEM("platform-b")...)
+			Expect(os.WriteFile(filepath.Join(certDir, "bundle.pem"), bundle, 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(certDir, "README"), []byte("not a certificate"), 0644)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(buildDir, ".certs"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(buildDir, ".certs", "app.pem"), certificatePEM("app"), 0644)).To(Succeed())
+			// The same certificate in both directories is added once
+			Expect(os.WriteFile(filepath.Join(buildDir, ".certs", "copy.pem"), bundle, 0644)).To(Succeed())
+		})
+
+		It("adds every certificate to the JRE's cacerts", func() {
+			Expect(fw.Finalize()).To(Succeed())
+
+			imports := invocations()
+			Expect(imports).To(HaveLen(3))
+			for _, args := range imports {
+				Expect(args).To(HavePrefix("-importcert -noprompt -alias "))
+				Expect(args).To(HaveSuffix("-keystore " + cacerts + " -storepass changeit"))
+			}
+			Expect(imports[0]).To(MatchRegexp(`-alias platform-[0-9a-f]{12} `))
+			Expect(imports[1]).To(MatchRegexp(`-alias platform-[0-9a-f]{12} `))
+			Expect(imports[2]).To(MatchRegexp(`-alias app-[0-9a-f]{12} `))
+
+			Expect(filepath.Join(depDir, "java_opts", "16_platform_certificates.opts")).NotTo(BeAnExistingFile())
+			pems, err := filepath.Glob(filepath.Join(depDir, "platform_certificates", "*.pem"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pems).To(BeEmpty())
+		})
+
+		It("adds the certificates to a dedicated trust store", func() {
+			os.Setenv("JBP_CONFIG_PLATFORM_CERTIFICATES", "{enabled: true, trust_store: dedicated}")
+			Expect(fw.Finalize()).To(Succeed())
+
+			trustStore := filepath.Join(depDir, "platform_certificates", "cacerts")
+			Expect(os.ReadFile(trustStore)).To(Equal([]byte("jre cacerts")))
+			for _, args := range invocations() {
+				Expect(args).To(HaveSuffix("-keystore " + trustStore + " -storepass changeit"))
+			}
+
+			opts, err := os.ReadFile(filepath.Join(depDir, "java_opts", "16_platform_certificates.opts"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(opts)).To(Equal("-Djavax.net.ssl.trustStore=$DEPS_DIR/0/platform_certificates/cacerts -Djavax.net.ssl.trustStorePassword=changeit"))
+		})
+
+		It("uses the cacerts of a Java 8 JDK", func() {
+			Expect(os.RemoveAll(filepath.Join(javaHome, "lib"))).To(Succeed())
+			jdk8 := filepath.Join(javaHome, "jre", "lib", "security", "cacerts")
+			Expect(os.MkdirAll(filepath.Dir(jdk8), 0755)).To(Succeed())
+			Expect(os.WriteFile(jdk8, []byte("jre cacerts"), 0644)).To(Succeed())
+
+			Expect(fw.Finalize()).To(Succeed())
+			Expect(invocations()[0]).To(HaveSuffix("-keystore " + jdk8 + " -storepass changeit"))
+		})
+
+		It("rejects an unknown trust store", func() {
+			os.Setenv("JBP_CONFIG_PLATFORM_CERTIFICATES", "{enabled: true, trust_store: system}")
+			Expect(fw.Finalize()).To(MatchError(ContainSubstring("trust_store must be jre or dedicated")))
+		})
+	})
+})
@@ -3,12 +3,16 @@ package frameworks
 import (
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/applibs"
 	"path/filepath"
 	"strings"
 
 	"github.com/cloudfoundry/libbuildpack"
 )
 
+// postgresDriverPatterns match the PostgreSQL JDBC drivers an application may already contain
+var postgresDriverPatterns = []string{"postgresql-*.jar"}
+
 // PostgresqlJdbcFramework implements PostgreSQL JDBC driver support
 // Automatically installs PostgreSQL JDBC driver if a PostgreSQL service is bound
 type PostgresqlJdbcFramework struct {
@@ -24,12 +28,13 @@ func NewPostgresqlJdbcFramework(ctx *common.Context) *PostgresqlJdbcFramework {
 func (p *PostgresqlJdbcFramework) Detect() (string, error) {
 	// Check if PostgreSQL service is bound
 	if !p.hasPostgresService() {
+		p.context.Log.Debug("PostgreSQL JDBC: no postgres service bound")
 		return "", nil
 	}
 
 	// Don't install if driver is already present in application
-	if p.hasPostgresDriver() {
-		p.context.Log.Debug("PostgreSQL JDBC driver already present in application")
+	if driver, ok := applibs.Find(p.context.Stager.BuildDir(), postgresDriverPatterns...); ok {
+		p.context.Log.Info("PostgreSQL JDBC driver %s found in application, not installing the buildpack's driver", driver)
 		return "", nil
 	}
 
@@ -72,8 +77,7 @@ func (p *PostgresqlJdbcFramework) Finalize() error {
 		return nil
 	}
 
-	depsIdx := p.context.Stager.DepsIdx()
-	runtimePath := fmt.Sprintf("$DEPS_DIR/%s/postgresql_jdbc/%s", depsIdx, filepath.Base(matches[0]))
+	runtimePath := p.context.Droplet().Dep("postgresql_jdbc", filepath.Base(matches[0]))
 
 	profileScript := fmt.Sprintf("export CLASSPATH=\"%s${CLASSPATH:+:$CLASSPATH}\"\n", runtimePath)
 	if err := p.context.Stager.WriteProfileD("postgresql_jdbc.sh", profileScript); err != nil {
@@ -127,26 +131,6 @@ func (p *PostgresqlJdbcFramework) hasPostgresService() bool {
 	return false
 }
 
-// hasPostgresDriver checks if PostgreSQL JDBC driver is already in the application
-func (p *PostgresqlJdbcFramework) hasPostgresDriver() bool {
-	// Look for postgresql-*.jar in the application
-	patterns := []string{
-		filepath.Join(p.context.Stager.BuildDir(), "**", "postgresql-*.jar"),
-		filepath.Join(p.context.Stager.BuildDir(), "WEB-INF", "lib", "postgresql-*.jar"),
-		filepath.Join(p.context.Stager.BuildDir(), "BOOT-INF", "lib", "postgresql-*.jar"),
-		filepath.Join(p.context.Stager.BuildDir(), "lib", "postgresql-*.jar"),
-	}
-
-	for _, pattern := range patterns {
-		matches, err := filepath.Glob(pattern)
-		if err == nil && len(matches) > 0 {
-			return true
-		}
-	}
-
-	return false
-}
-
 func (p *PostgresqlJdbcFramework) DependencyIdentifier() string {
 	return "postgresql-jdbc"
 }
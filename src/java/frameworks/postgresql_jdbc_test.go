@@ -1,6 +1,7 @@
 package frameworks_test
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
 
@@ -183,6 +184,29 @@ var _ = Describe("PostgreSQLJDBC", func() {
 			})
 		})
 
+		Context("with postgres service but driver already in the lib directory of a distribution", func() {
+			var logs *bytes.Buffer
+
+			BeforeEach(func() {
+				os.Setenv("VCAP_SERVICES", postgresVCAPServices("postgres", "my-postgres", nil))
+				libDir := filepath.Join(buildDir, "orders-1.0", "lib")
+				Expect(os.MkdirAll(libDir, 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(libDir, "postgresql-42.7.3.jar"), []byte("fake"), 0644)).To(Succeed())
+
+				logs = new(bytes.Buffer)
+				ctx := newPostgresContext(buildDir, cacheDir, depsDir)
+				ctx.Log = libbuildpack.NewLogger(logs)
+				fw = frameworks.NewPostgresqlJdbcFramework(ctx)
+			})
+
+			It("returns empty string and names the driver", func() {
+				name, err := fw.Detect()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(name).To(BeEmpty())
+				Expect(logs.String()).To(ContainSubstring("PostgreSQL JDBC driver orders-1.0/lib/postgresql-42.7.3.jar found in application"))
+			})
+		})
+
 		Context("with invalid VCAP_SERVICES JSON", func() {
 			BeforeEach(func() {
 				os.Setenv("VCAP_SERVICES", "{invalid json")
@@ -4,10 +4,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
 	"github.com/cloudfoundry/java-buildpack/src/java/resources"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -28,20 +30,24 @@ func (p *ProtectAppSecurityProviderFramework) Detect() (string, error) {
 	// Check for bound ProtectApp service in VCAP_SERVICES
 	protectAppService, err := p.findProtectAppService()
 	if err != nil {
+		p.context.Log.Debug("ProtectApp: no protectapp service bound")
 		return "", nil // Service not found, don't enable
 	}
 
 	// Verify required credentials exist
 	credentials, ok := protectAppService["credentials"].(map[string]interface{})
 	if !ok {
+		p.context.Log.Debug("ProtectApp: the protectapp service has no credentials")
 		return "", nil
 	}
 
 	// Check for required fields: client and trusted_certificates
 	if _, ok := credentials["client"]; !ok {
+		p.context.Log.Debug("ProtectApp: the protectapp service has no client credential")
 		return "", nil
 	}
 	if _, ok := credentials["trusted_certificates"]; !ok {
+		p.context.Log.Debug("ProtectApp: the protectapp service has no trusted_certificates credential")
 		return "", nil
 	}
 
@@ -71,12 +77,13 @@ func (p *ProtectAppSecurityProviderFramework) Supply() error {
 	}
 
 	// Install default configuration from embedded resources
+	var causes []error
 	if err := p.installDefaultConfiguration(protectAppDir); err != nil {
-		p.context.Log.Warning("Could not install default ProtectApp configuration: %s", err.Error())
+		causes = append(causes, fmt.Errorf("could not install default ProtectApp configuration: %w", err))
 	}
 
 	p.context.Log.Debug("Installed ProtectApp Security Provider version %s", dep.Version)
-	return nil
+	return incomplete(causes...)
 }
 
 // installDefaultConfiguration installs the default IngrianNAE.properties from embedded resources
@@ -134,7 +141,6 @@ func (p *ProtectAppSecurityProviderFramework) Finalize() error {
 	}
 
 	// Get buildpack index for multi-buildpack support
-	depsIdx := p.context.Stager.DepsIdx()
 
 	// Get version for JAR name
 	dep, err := p.context.Manifest.DefaultVersion("protect-app-security-provider")
@@ -143,7 +149,7 @@ func (p *ProtectAppSecurityProviderFramework) Finalize() error {
 	}
 
 	// Build runtime paths
-	runtimeProtectAppDir := fmt.Sprintf("$DEPS_DIR/%s/protect_app_security_provider", depsIdx)
+	runtimeProtectAppDir := p.context.Droplet().Dep("protect_app_security_provider")
 	runtimeKeystorePath := filepath.Join(runtimeProtectAppDir, "nae-keystore.jks")
 	runtimeProtectAppJar := filepath.Join(runtimeProtectAppDir, "ext", fmt.Sprintf("IngrianNAE-%s.000.jar", dep.Version))
 
@@ -155,12 +161,18 @@ func (p *ProtectAppSecurityProviderFramework) Finalize() error {
 		fmt.Sprintf("-Dcom.ingrian.security.nae.Key_Store_Password=%s", keystorePassword),
 	}
 
-	// Add additional properties from credentials (excluding client and trusted_certificates)
-	for key, value := range credentials {
+	// Add additional properties from credentials (excluding client and trusted_certificates), in sorted order so
+	// that restaging an unchanged application writes the same options
+	var keys []string
+	for key := range credentials {
 		if key != "client" && key != "trusted_certificates" {
-			javaOptsSlice = append(javaOptsSlice, fmt.Sprintf("-Dcom.ingrian.security.nae.%s=%v", key, value))
+			keys = append(keys, key)
 		}
 	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		javaOptsSlice = append(javaOptsSlice, fmt.Sprintf("-Dcom.ingrian.security.nae.%s=%v", key, credentials[key]))
+	}
 
 	// Add security provider property
 	javaOptsSlice = append(javaOptsSlice, fmt.Sprintf("-Djava.security.properties=%s/java.security", runtimeProtectAppDir))
@@ -169,7 +181,7 @@ func (p *ProtectAppSecurityProviderFramework) Finalize() error {
 	javaOptsStr := strings.Join(javaOptsSlice, " ")
 
 	// Write to .opts file using priority 38
-	if err := writeJavaOptsFile(p.context, 38, "protect_app_security_provider", javaOptsStr); err != nil {
+	if err := writeJavaOptsFile(p.context, javaopts.ProtectAppSecurity, "protect_app_security_provider", javaOptsStr); err != nil {
 		return fmt.Errorf("failed to write java_opts file: %w", err)
 	}
 
@@ -308,7 +320,8 @@ func (p *ProtectAppSecurityProviderFramework) findProtectAppService() (map[strin
 		return nil, fmt.Errorf("failed to parse VCAP_SERVICES: %w", err)
 	}
 
-	for serviceType, serviceList := range services {
+	for _, serviceType := range sortedLabels(services) {
+		serviceList := services[serviceType]
 		if common.ContainsIgnoreCase(serviceType, "protectapp") {
 			if len(serviceList) > 0 {
 				return serviceList[0], nil
@@ -2,12 +2,20 @@ package frameworks_test
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
+	"sort"
+	"strings"
 
+	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	"github.com/cloudfoundry/java-buildpack/src/internal/mocks"
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/frameworks"
 	"github.com/cloudfoundry/java-buildpack/src/java/resources"
+	"github.com/cloudfoundry/libbuildpack"
 )
 
 var _ = Describe("ProtectAppSecurityProvider", func() {
@@ -97,4 +105,106 @@ var _ = Describe("ProtectAppSecurityProvider", func() {
 			Expect(existingStr).To(ContainSubstring("192.168.1.100"))
 		})
 	})
+
+	Describe("staging against a fake KMS", func() {
+		var (
+			fw       *frameworks.ProtectAppSecurityProviderFramework
+			mockCtrl *gomock.Controller
+			buildDir string
+			cacheDir string
+			depsDir  string
+			depDir   string
+		)
+
+		BeforeEach(func() {
+			var err error
+			buildDir, err = os.MkdirTemp("", "protectapp-build")
+			Expect(err).NotTo(HaveOccurred())
+			cacheDir, err = os.MkdirTemp("", "protectapp-cache")
+			Expect(err).NotTo(HaveOccurred())
+			depsDir, err = os.MkdirTemp("", "protectapp-deps")
+			Expect(err).NotTo(HaveOccurred())
+			depDir = filepath.Join(depsDir, "0")
+			Expect(os.MkdirAll(depDir, 0755)).To(Succeed())
+			os.Setenv("VCAP_SERVICES", readFixture("protect_app/vcap_services.json"))
+
+			// The installer lays out a stub provider JAR in place of the ProtectApp distribution
+			dep := libbuildpack.Dependency{Name: "protect-app-security-provider", Version: "8.3.0"}
+			mockCtrl = gomock.NewController(GinkgoT())
+			manifest := mocks.NewMockManifest(mockCtrl)
+			manifest.EXPECT().DefaultVersion("protect-app-security-provider").Return(dep, nil).AnyTimes()
+			installer := mocks.NewMockInstaller(mockCtrl)
+			installer.EXPECT().InstallDependency(dep, gomock.Any()).DoAndReturn(func(_ libbuildpack.Dependency, dir string) error {
+				Expect(os.MkdirAll(filepath.Join(dir, "ext"), 0755)).To(Succeed())
+				return os.WriteFile(filepath.Join(dir, "ext", "IngrianNAE-8.3.0.000.jar"), []byte("stub jar"), 0644)
+			}).AnyTimes()
+
+			logger := libbuildpack.NewLogger(GinkgoWriter)
+			fw = frameworks.NewProtectAppSecurityProviderFramework(&common.Context{
+				Stager:    libbuildpack.NewStager([]string{buildDir, cacheDir, depsDir, "0"}, logger, &libbuildpack.Manifest{}),
+				Manifest:  manifest,
+				Installer: installer,
+				Log:       logger,
+				Command:   &libbuildpack.Command{},
+			})
+		})
+
+		AfterEach(func() {
+			mockCtrl.Finish()
+			os.RemoveAll(buildDir)
+			os.RemoveAll(cacheDir)
+			os.RemoveAll(depsDir)
+			os.Unsetenv("VCAP_SERVICES")
+			os.Unsetenv("JAVA_HOME")
+		})
+
+		It("detects the service with the provider version", func() {
+			Expect(fw.Detect()).To(Equal("protect-app-security-provider=8.3.0"))
+		})
+
+		It("installs the default IngrianNAE.properties", func() {
+			Expect(fw.Supply()).To(Succeed())
+
+			expected, err := resources.GetResource("protect_app_security_provider/IngrianNAE.properties")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.ReadFile(filepath.Join(depDir, "protect_app_security_provider", "IngrianNAE.properties"))).To(Equal(expected))
+		})
+
+		It("generates the keystore, java.security and JAVA_OPTS", func() {
+			keytool, err := exec.LookPath("keytool")
+			if err != nil {
+				Skip("keytool is not on the PATH")
+			}
+			if _, err := exec.LookPath("openssl"); err != nil {
+				Skip("openssl is not on the PATH")
+			}
+			keytool, err = filepath.EvalSymlinks(keytool)
+			Expect(err).NotTo(HaveOccurred())
+			os.Setenv("JAVA_HOME", filepath.Dir(filepath.Dir(keytool)))
+
+			Expect(fw.Supply()).To(Succeed())
+			Expect(fw.Finalize()).To(Succeed())
+
+			protectAppDir := filepath.Join(depDir, "protect_app_security_provider")
+			expectGoldenFile(filepath.Join(protectAppDir, "java.security"), "protect_app/java.security", depDir, "$DIR")
+			expectGoldenFile(filepath.Join(depDir, "java_opts", "38_protect_app_security_provider.opts"), "protect_app/38_protect_app_security_provider.opts", depDir, "$DIR")
+
+			// Entries are listed as "<alias>, <creation date>, <type>,"; the dates differ between runs
+			output, err := exec.Command(keytool, "-list", "-keystore", filepath.Join(protectAppDir, "nae-keystore.jks"),
+				"-storepass", "nae-keystore-password").CombinedOutput()
+			Expect(err).NotTo(HaveOccurred(), string(output))
+			var entries []string
+			for _, line := range strings.Split(string(output), "\n") {
+				if fields := strings.Split(line, ", "); len(fields) >= 3 {
+					entries = append(entries, fields[0]+" "+strings.TrimSuffix(fields[len(fields)-1], ","))
+				}
+			}
+			sort.Strings(entries)
+			Expect(strings.Join(entries, "\n") + "\n").To(Equal(readFixture("protect_app/nae-keystore.txt")))
+
+			for _, scratch := range []string{"client-cert.pem", "client-key.pem", "client.p12", "trusted-cert-0.pem"} {
+				Expect(filepath.Join(protectAppDir, scratch)).NotTo(BeAnExistingFile())
+			}
+		})
+	})
 })
@@ -0,0 +1,217 @@
+package frameworks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+)
+
+// resourceTagsConfig is the JBP_CONFIG_RESOURCE_TAGS configuration, e.g.
+//
+//	enabled: true
+//	cloud_provider: aws
+//	query_metadata: true
+type resourceTagsConfig struct {
+	// Enabled tags the New Relic, Datadog, OpenTelemetry and Metrics Forwarder agents with the foundation, org, space and region
+	Enabled bool `yaml:"enabled"`
+	// Foundation names the Cloud Foundry foundation; it defaults to the domain of the cf_api URL
+	Foundation string `yaml:"foundation"`
+	// CloudProvider is the infrastructure the foundation runs on: aws, azure or gcp
+	CloudProvider string `yaml:"cloud_provider"`
+	Region        string `yaml:"region"`
+	// QueryMetadata looks up a region that is not configured in the metadata service of the cloud provider
+	QueryMetadata bool `yaml:"query_metadata"`
+	// MetadataTimeout bounds each metadata request, in seconds
+	MetadataTimeout int `yaml:"metadata_timeout"`
+}
+
+// resourceTag is one tag in the key conventions of the agents: name for New Relic labels and Datadog tags,
+// attribute for OpenTelemetry resource attributes
+type resourceTag struct {
+	name      string
+	attribute string
+	value     string
+}
+
+// cloudMetadataURL is the address of the metadata services of AWS and Azure, replaced in tests
+var cloudMetadataURL = "http://169.254.169.254"
+
+// gcpMetadataURL is the address of the GCP metadata server, replaced in tests
+var gcpMetadataURL = "http://metadata.google.internal"
+
+// metadataRegion is the result of a region lookup in the metadata service
+type metadataRegion struct {
+	region string
+	err    error
+}
+
+var (
+	metadataRegionsMu sync.Mutex
+	// metadataRegions caches the lookups by metadata service, so that the agents tagged in one staging share one
+	metadataRegions = map[string]metadataRegion{}
+)
+
+// resourceTags returns the tags that JBP_CONFIG_RESOURCE_TAGS enables, or nil if it is disabled. Tags without a
+// value are left out. A region that cannot be read from the metadata service is logged and left out, so the tags
+// never fail staging.
+func resourceTags(ctx *common.Context) ([]resourceTag, error) {
+	cfg := resourceTagsConfig{MetadataTimeout: 2}
+	if err := config.Load(ctx.Log, "resource_tags", &cfg); err != nil {
+		return nil, err
+	}
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	var app struct {
+		CFAPI            string `json:"cf_api"`
+		OrganizationName string `json:"organization_name"`
+		SpaceName        string `json:"space_name"`
+	}
+	_ = json.Unmarshal([]byte(os.Getenv("VCAP_APPLICATION")), &app)
+
+	foundation := cfg.Foundation
+	if foundation == "" {
+		foundation = foundationName(app.CFAPI)
+	}
+
+	provider := strings.ToLower(cfg.CloudProvider)
+	region := cfg.Region
+	if cfg.QueryMetadata && region == "" {
+		var err error
+		if region, err = cachedCloudRegion(provider, time.Duration(cfg.MetadataTimeout)*time.Second); err != nil {
+			ctx.Log.Warning("Unable to read the region from the %s metadata service: %s", provider, err.Error())
+		}
+	}
+
+	var tags []resourceTag
+	for _, tag := range []resourceTag{
+		{name: "foundation", attribute: "cloudfoundry.foundation", value: foundation},
+		{name: "org", attribute: "cloudfoundry.org.name", value: app.OrganizationName},
+		{name: "space", attribute: "cloudfoundry.space.name", value: app.SpaceName},
+		{name: "cloud_provider", attribute: "cloud.provider", value: provider},
+		{name: "region", attribute: "cloud.region", value: region},
+	} {
+		if tag.value != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// foundationName returns the domain of the cf_api URL without its api host, e.g. "sys.example.com" for
+// "https://api.sys.example.com"
+func foundationName(cfAPI string) string {
+	parsed, err := url.Parse(cfAPI)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(parsed.Hostname(), "api.")
+}
+
+// cachedCloudRegion returns the region of queryCloudRegion, querying the metadata service of provider once
+func cachedCloudRegion(provider string, timeout time.Duration) (string, error) {
+	key := provider + " " + cloudMetadataURL + " " + gcpMetadataURL
+
+	metadataRegionsMu.Lock()
+	defer metadataRegionsMu.Unlock()
+	cached, ok := metadataRegions[key]
+	if !ok {
+		cached.region, cached.err = queryCloudRegion(provider, timeout)
+		metadataRegions[key] = cached
+	}
+	return cached.region, cached.err
+}
+
+// queryCloudRegion reads the region of the cell staging the application from the metadata service of provider.
+// The cells of a foundation run in the region of the foundation, so it is the region of the application too. The
+// zone of the staging cell is not read: the cells that run the application may be in other zones.
+func queryCloudRegion(provider string, timeout time.Duration) (string, error) {
+	// The metadata services are link-local, so requests must not go through a proxy
+	client := &http.Client{Timeout: timeout, Transport: &http.Transport{Proxy: nil}}
+
+	switch provider {
+	case "aws":
+		token, err := metadataRequest(client, http.MethodPut, cloudMetadataURL+"/latest/api/token",
+			map[string]string{"X-aws-ec2-metadata-token-ttl-seconds": "60"})
+		if err != nil {
+			return "", err
+		}
+		return metadataRequest(client, http.MethodGet, cloudMetadataURL+"/latest/meta-data/placement/region",
+			map[string]string{"X-aws-ec2-metadata-token": token})
+
+	case "azure":
+		return metadataRequest(client, http.MethodGet,
+			cloudMetadataURL+"/metadata/instance/compute/location?api-version=2021-02-01&format=text",
+			map[string]string{"Metadata": "true"})
+
+	case "gcp":
+		// The zone is returned as projects/<number>/zones/<zone>, the region is the zone without its last part
+		zone, err := metadataRequest(client, http.MethodGet, gcpMetadataURL+"/computeMetadata/v1/instance/zone",
+			map[string]string{"Metadata-Flavor": "Google"})
+		if err != nil {
+			return "", err
+		}
+		zone = zone[strings.LastIndex(zone, "/")+1:]
+		if i := strings.LastIndex(zone, "-"); i > 0 {
+			return zone[:i], nil
+		}
+		return zone, nil
+
+	case "":
+		return "", fmt.Errorf("cloud_provider is not configured")
+	default:
+		return "", fmt.Errorf("unsupported cloud_provider %s, expected aws, azure or gcp", provider)
+	}
+}
+
+// metadataRequest returns the trimmed body of a metadata service response
+func metadataRequest(client *http.Client, method, endpoint string, header map[string]string) (string, error) {
+	req, err := http.NewRequest(method, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s returned HTTP %d", endpoint, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+// joinResourceTags joins tags as key<separator>value pairs separated by delimiter, e.g. "org:my-org,space:dev", using
+// the OpenTelemetry attribute keys if otel is true. Separators and delimiters in values are replaced with underscores,
+// as the agents cannot escape them.
+func joinResourceTags(tags []resourceTag, separator, delimiter string, otel bool) string {
+	sanitize := strings.NewReplacer(separator, "_", delimiter, "_")
+	pairs := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		key := tag.name
+		if otel {
+			key = tag.attribute
+		}
+		pairs = append(pairs, key+separator+sanitize.Replace(tag.value))
+	}
+	return strings.Join(pairs, delimiter)
+}
@@ -0,0 +1,154 @@
+package frameworks
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/libbuildpack"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ResourceTags", func() {
+	var (
+		ctx      *common.Context
+		server   *httptest.Server
+		requests []string
+	)
+
+	// metadataServer serves the given paths, checking the header every metadata service requires
+	metadataServer := func(header, value string, responses map[string]string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r.Method+" "+r.URL.Path)
+			body, ok := responses[r.URL.Path]
+			if !ok || r.Header.Get(header) != value {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(body))
+		}))
+	}
+
+	BeforeEach(func() {
+		ctx = &common.Context{Log: libbuildpack.NewLogger(GinkgoWriter)}
+		requests = nil
+		os.Setenv("VCAP_APPLICATION", `{"application_name":"orders","cf_api":"https://api.sys.example.com",`+
+			`"organization_name":"shop","space_name":"prod"}`)
+	})
+
+	AfterEach(func() {
+		if server != nil {
+			server.Close()
+			server = nil
+		}
+		cloudMetadataURL = "http://169.254.169.254"
+		gcpMetadataURL = "http://metadata.google.internal"
+		metadataRegions = map[string]metadataRegion{}
+		os.Unsetenv("JBP_CONFIG_RESOURCE_TAGS")
+		os.Unsetenv("VCAP_APPLICATION")
+	})
+
+	It("is disabled by default", func() {
+		Expect(resourceTags(ctx)).To(BeEmpty())
+	})
+
+	It("tags the foundation, org and space from VCAP_APPLICATION", func() {
+		os.Setenv("JBP_CONFIG_RESOURCE_TAGS", "{enabled: true}")
+
+		tags, err := resourceTags(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(joinResourceTags(tags, ":", ",", false)).To(Equal("foundation:sys.example.com,org:shop,space:prod"))
+		Expect(joinResourceTags(tags, "=", ",", true)).To(Equal(
+			"cloudfoundry.foundation=sys.example.com,cloudfoundry.org.name=shop,cloudfoundry.space.name=prod"))
+	})
+
+	It("replaces separators and delimiters in values", func() {
+		tags := []resourceTag{{name: "org", attribute: "cloudfoundry.org.name", value: "a:b,c"}}
+		Expect(joinResourceTags(tags, ":", ",", false)).To(Equal("org:a_b_c"))
+	})
+
+	It("reads the region from the AWS metadata service with a session token", func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r.Method+" "+r.URL.Path)
+			switch {
+			case r.Method == http.MethodPut && r.URL.Path == "/latest/api/token":
+				w.Write([]byte("token"))
+			case r.Header.Get("X-aws-ec2-metadata-token") != "token":
+				w.WriteHeader(http.StatusUnauthorized)
+			case r.URL.Path == "/latest/meta-data/placement/region":
+				w.Write([]byte("eu-west-1\n"))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		cloudMetadataURL = server.URL
+		os.Setenv("JBP_CONFIG_RESOURCE_TAGS", "{enabled: true, cloud_provider: aws, query_metadata: true}")
+
+		tags, err := resourceTags(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(joinResourceTags(tags, "=", ",", true)).To(HaveSuffix("cloud.provider=aws,cloud.region=eu-west-1"))
+		Expect(requests).To(Equal([]string{"PUT /latest/api/token", "GET /latest/meta-data/placement/region"}))
+	})
+
+	It("reads the region from the Azure metadata service", func() {
+		server = metadataServer("Metadata", "true", map[string]string{
+			"/metadata/instance/compute/location": "westeurope",
+		})
+		cloudMetadataURL = server.URL
+		os.Setenv("JBP_CONFIG_RESOURCE_TAGS", "{enabled: true, cloud_provider: azure, query_metadata: true}")
+
+		tags, err := resourceTags(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(joinResourceTags(tags, ":", ",", false)).To(HaveSuffix("cloud_provider:azure,region:westeurope"))
+	})
+
+	It("queries the metadata service once for all agents", func() {
+		server = metadataServer("Metadata", "true", map[string]string{
+			"/metadata/instance/compute/location": "westeurope",
+		})
+		cloudMetadataURL = server.URL
+		os.Setenv("JBP_CONFIG_RESOURCE_TAGS", "{enabled: true, cloud_provider: azure, query_metadata: true}")
+
+		for range 3 {
+			tags, err := resourceTags(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(joinResourceTags(tags, ":", ",", false)).To(HaveSuffix("region:westeurope"))
+		}
+		Expect(requests).To(HaveLen(1))
+	})
+
+	It("derives the region from the zone of the GCP metadata server", func() {
+		server = metadataServer("Metadata-Flavor", "Google", map[string]string{
+			"/computeMetadata/v1/instance/zone": "projects/123456/zones/us-central1-a",
+		})
+		gcpMetadataURL = server.URL
+		os.Setenv("JBP_CONFIG_RESOURCE_TAGS", "{enabled: true, cloud_provider: gcp, query_metadata: true}")
+
+		tags, err := resourceTags(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(joinResourceTags(tags, ":", ",", false)).To(HaveSuffix("cloud_provider:gcp,region:us-central1"))
+	})
+
+	It("prefers the configured region and does not query the metadata service", func() {
+		server = metadataServer("Metadata", "true", map[string]string{})
+		cloudMetadataURL = server.URL
+		os.Setenv("JBP_CONFIG_RESOURCE_TAGS", "{enabled: true, cloud_provider: azure, region: northeurope, query_metadata: true}")
+
+		tags, err := resourceTags(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(joinResourceTags(tags, ":", ",", false)).To(HaveSuffix("region:northeurope"))
+		Expect(requests).To(BeEmpty())
+	})
+
+	It("leaves out the region when the metadata service fails", func() {
+		server = metadataServer("Metadata", "true", map[string]string{})
+		cloudMetadataURL = server.URL
+		os.Setenv("JBP_CONFIG_RESOURCE_TAGS", "{enabled: true, cloud_provider: azure, query_metadata: true}")
+
+		tags, err := resourceTags(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(joinResourceTags(tags, ":", ",", false)).To(Equal("foundation:sys.example.com,org:shop,space:prod,cloud_provider:azure"))
+	})
+})
@@ -16,18 +16,32 @@
 package frameworks
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
-	"os"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/appname"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
 	"path/filepath"
 	"strings"
 )
 
 // RiverbedAppInternalsAgentFramework represents the Riverbed AppInternals agent framework
 type RiverbedAppInternalsAgentFramework struct {
-	context   *common.Context
-	agentPath string
+	context *common.Context
+}
+
+// riverbedAgent are the artifacts of the Riverbed AppInternals archive: the Java agent is loaded by default, the
+// native profiler with attach: agentpath
+var riverbedAgent = dualAgent{
+	name:      "Riverbed AppInternals agent",
+	preferred: attachJavaagent,
+	layouts: []agentLayout{
+		{jar: "lib/rvbd-agent.jar", native: map[string]string{"amd64": "lib/librpilj64.so"}},
+	},
+}
+
+type riverbedConfig struct {
+	Attach string `yaml:"attach"`
 }
 
 // NewRiverbedAppInternalsAgentFramework creates a new Riverbed AppInternals agent framework instance
@@ -63,48 +77,53 @@ func (r *RiverbedAppInternalsAgentFramework) Supply() error {
 		return fmt.Errorf("failed to install Riverbed AppInternals agent: %w", err)
 	}
 
-	// constructAgentJarPath can be skipped here and do it only in finalize, but it can be left as a double check
-	// if jar path exists after the dependency install
-	err = r.constructAgentJarPath(agentDir)
-	if err != nil {
-		return fmt.Errorf("riverbed appinternals agent JAR not found during supply: %w", err)
+	// Validate that the archive contains the agent the attach policy selects
+	if _, err := r.attachment(); err != nil {
+		return err
 	}
 
 	r.context.Log.Info("Riverbed AppInternals agent %s installed", dep.Version)
 	return nil
 }
 
+// Deprecation marks the Riverbed AppInternals agent as deprecated in favor of the OpenTelemetry Javaagent framework
+func (r *RiverbedAppInternalsAgentFramework) Deprecation() Deprecation {
+	return Deprecation{Deprecated: true, Sunset: "2027-06-30", Replacement: "the OpenTelemetry Javaagent framework"}
+}
+
 // Finalize configures the Riverbed AppInternals agent
 func (r *RiverbedAppInternalsAgentFramework) Finalize() error {
-	agentDir := filepath.Join(r.context.Stager.DepDir(), "riverbed_appinternals_agent")
-	err := r.constructAgentJarPath(agentDir)
+	attachment, err := r.attachment()
 	if err != nil {
-		return fmt.Errorf("riverbed appinternals agent JAR not found during finalize: %w", err)
+		return err
 	}
 
 	r.context.Log.BeginStep("Configuring Riverbed AppInternals agent")
 
 	// Get buildpack index for multi-buildpack support
-	depsIdx := r.context.Stager.DepsIdx()
 
 	// Convert staging path to runtime path
-	relPath, err := filepath.Rel(r.context.Stager.DepDir(), r.agentPath)
+	relPath, err := filepath.Rel(r.context.Stager.DepDir(), attachment.path)
 	if err != nil {
 		return fmt.Errorf("failed to determine relative path for Riverbed AppInternals agent: %w", err)
 	}
-	runtimeJarPath := filepath.Join(fmt.Sprintf("$DEPS_DIR/%s", depsIdx), relPath)
+	runtimeAgentPath := r.context.Droplet().Dep(relPath)
 
 	// Get credentials from service binding
 	credentials := r.getCredentials()
 
 	// Build all JAVA_OPTS options
 	var opts []string
-	opts = append(opts, fmt.Sprintf("-javaagent:%s", runtimeJarPath))
+	opts = append(opts, attachment.option(runtimeAgentPath))
 
 	// Configure moniker (application name)
 	moniker := credentials.Moniker
 	if moniker == "" {
-		moniker = r.getApplicationName()
+		name, err := r.getApplicationName()
+		if err != nil {
+			return err
+		}
+		moniker = name
 	}
 	if moniker != "" {
 		opts = append(opts, fmt.Sprintf("-Drvbd.moniker=%s", moniker))
@@ -117,7 +136,7 @@ func (r *RiverbedAppInternalsAgentFramework) Finalize() error {
 
 	// Write all options to .opts file
 	javaOpts := strings.Join(opts, " ")
-	if err := writeJavaOptsFile(r.context, 37, "riverbed_appinternals_agent", javaOpts); err != nil {
+	if err := writeJavaOptsFile(r.context, javaopts.RiverbedAppInternals, "riverbed_appinternals_agent", javaOpts); err != nil {
 		return fmt.Errorf("failed to write JAVA_OPTS for Riverbed AppInternals: %w", err)
 	}
 
@@ -198,33 +217,18 @@ func (r *RiverbedAppInternalsAgentFramework) getCredentials() RiverbedCredential
 	return creds
 }
 
-// getApplicationName returns the application name from VCAP_APPLICATION
-func (r *RiverbedAppInternalsAgentFramework) getApplicationName() string {
-	vcapApp := os.Getenv("VCAP_APPLICATION")
-	if vcapApp == "" {
-		return ""
-	}
-
-	var appData map[string]interface{}
-	if err := json.Unmarshal([]byte(vcapApp), &appData); err != nil {
-		return ""
-	}
-
-	if name, ok := appData["application_name"].(string); ok {
-		return name
-	}
-
-	return ""
+// getApplicationName returns the name the application is reported as, see appname.Name
+func (r *RiverbedAppInternalsAgentFramework) getApplicationName() (string, error) {
+	return appname.Name(r.context.Log, appname.App)
 }
 
-func (r *RiverbedAppInternalsAgentFramework) constructAgentJarPath(agentDir string) error {
-	// Find the installed agent directory (contains lib/rvbd-agent.jar)
-	agentJarPath := filepath.Join(agentDir, "lib", "rvbd-agent.jar")
-	if _, err := os.Stat(agentJarPath); err != nil {
-		return fmt.Errorf("agent jar not found after installation: %w", err)
+// attachment returns the installed artifact that attaches the agent according to the attach configuration
+func (r *RiverbedAppInternalsAgentFramework) attachment() (agentAttachment, error) {
+	rConfig := riverbedConfig{Attach: attachAuto}
+	if err := config.Load(r.context.Log, "riverbed_appinternals_agent", &rConfig); err != nil {
+		return agentAttachment{}, err
 	}
-	r.agentPath = agentJarPath
-	return nil
+	return riverbedAgent.resolve(filepath.Join(r.context.Stager.DepDir(), "riverbed_appinternals_agent"), rConfig.Attach)
 }
 
 func (r *RiverbedAppInternalsAgentFramework) DependencyIdentifier() string {
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -346,10 +347,64 @@ var _ = Describe("RiverbedAppInternalsAgent", func() {
 		})
 
 		Context("when the agent JAR is not present", func() {
+			It("returns an error naming the expected file", func() {
+				err := fw.Finalize()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Riverbed AppInternals agent not found: expected " +
+					filepath.Join(depsDir, "0", "riverbed_appinternals_agent", "lib", "rvbd-agent.jar")))
+			})
+		})
+
+		Context("with attach: agentpath in JBP_CONFIG_RIVERBED_APPINTERNALS_AGENT", func() {
+			BeforeEach(func() {
+				installRiverbedAgent(depsDir)
+				os.Setenv("JBP_CONFIG_RIVERBED_APPINTERNALS_AGENT", "{attach: agentpath}")
+			})
+
+			AfterEach(func() {
+				os.Unsetenv("JBP_CONFIG_RIVERBED_APPINTERNALS_AGENT")
+			})
+
+			It("attaches the native agent with -agentpath", func() {
+				if runtime.GOARCH != "amd64" {
+					Skip("the Riverbed AppInternals native agent is only available for amd64")
+				}
+				libDir := filepath.Join(depsDir, "0", "riverbed_appinternals_agent", "lib")
+				Expect(os.WriteFile(filepath.Join(libDir, "librpilj64.so"), []byte("fake so"), 0644)).To(Succeed())
+
+				Expect(fw.Finalize()).To(Succeed())
+				content, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "37_riverbed_appinternals_agent.opts"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(ContainSubstring("-agentpath:$DEPS_DIR/0/riverbed_appinternals_agent/lib/librpilj64.so"))
+				Expect(string(content)).NotTo(ContainSubstring("-javaagent"))
+			})
+
+			It("returns an error naming the native agent instead of falling back to the JAR", func() {
+				if runtime.GOARCH != "amd64" {
+					Skip("the Riverbed AppInternals native agent is only available for amd64")
+				}
+				err := fw.Finalize()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("expected " +
+					filepath.Join(depsDir, "0", "riverbed_appinternals_agent", "lib", "librpilj64.so")))
+				Expect(filepath.Join(depsDir, "0", "java_opts", "37_riverbed_appinternals_agent.opts")).NotTo(BeAnExistingFile())
+			})
+		})
+
+		Context("with an unknown attach mechanism", func() {
+			BeforeEach(func() {
+				installRiverbedAgent(depsDir)
+				os.Setenv("JBP_CONFIG_RIVERBED_APPINTERNALS_AGENT", "{attach: bootclasspath}")
+			})
+
+			AfterEach(func() {
+				os.Unsetenv("JBP_CONFIG_RIVERBED_APPINTERNALS_AGENT")
+			})
+
 			It("returns an error", func() {
 				err := fw.Finalize()
 				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(ContainSubstring("agent jar not found"))
+				Expect(err.Error()).To(ContainSubstring(`invalid attach "bootclasspath" for Riverbed AppInternals agent: must be auto, javaagent or agentpath`))
 			})
 		})
 	})
@@ -0,0 +1,41 @@
+package frameworks
+
+import "github.com/cloudfoundry/java-buildpack/src/java/common/config"
+
+// enabledConfig is the configuration of frameworks that can only be switched on or off
+type enabledConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+func init() {
+	for component, schema := range map[string]config.Schema{
+		"app_dynamics_agent":          func() interface{} { return &appDynamicsConfig{} },
+		"aspectj_weaver_agent":        func() interface{} { return &aspectjWeaverConfig{} },
+		"client_certificate_mapper":   func() interface{} { return &clientCertificateMapperConfig{} },
+		"container_security_provider": func() interface{} { return &containerSecurityProviderConfig{} },
+		"debug":                       func() interface{} { return &debugConfig{} },
+		"google_stackdriver_profiler": func() interface{} { return &googleStackDriveConfig{} },
+		"java_cf_env":                 func() interface{} { return &enabledConfig{} },
+		"java_memory_assistant":       func() interface{} { return &javaMemoryAssistantConfig{} },
+		"java_opts":                   func() interface{} { return &javaOptsRawConfig{} },
+		"jmx":                         func() interface{} { return &jmxConfig{} },
+		"jprofiler_profiler":          func() interface{} { return &jProfilerConfig{} },
+		"jrebel":                      func() interface{} { return &jrebelConfig{} },
+		"luna_security_provider":      func() interface{} { return &lunaSecurityProviderConfig{} },
+		"metric_writer":               func() interface{} { return &metricWriterConfig{} },
+		"metrics_forwarder":           func() interface{} { return &metricsForwarderConfig{} },
+		"open_telemetry_javaagent":    func() interface{} { return &openTelemetryConfig{} },
+		"oracle_jdbc":                 func() interface{} { return &oracleJdbcConfig{} },
+		"platform_certificates":       func() interface{} { return &platformCertificatesConfig{} },
+		"resource_tags":               func() interface{} { return &resourceTagsConfig{} },
+		"riverbed_appinternals_agent": func() interface{} { return &riverbedConfig{} },
+		"sealights":                   func() interface{} { return &sealightsAgentConfig{} },
+		"service_mappings":            func() interface{} { return &serviceMappingsConfig{} },
+		"sky_walking_agent":           func() interface{} { return &skyWalkingAgentConfig{} },
+		"spring_application_json":     func() interface{} { return &springApplicationJSONConfig{} },
+		"spring_auto_reconfiguration": func() interface{} { return &enabledConfig{} },
+		"your_kit_profiler":           func() interface{} { return &yourKitProfilerConfig{} },
+	} {
+		config.RegisterSchema(component, schema)
+	}
+}
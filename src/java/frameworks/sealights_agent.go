@@ -18,6 +18,9 @@ package frameworks
 import (
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/writable"
 	"os"
 	"path/filepath"
 
@@ -48,6 +51,7 @@ func (f *SealightsAgentFramework) Detect() (string, error) {
 		return "Sealights Agent", nil
 	}
 
+	f.context.Log.Debug("Sealights: no sealights service bound")
 	return "", nil
 }
 
@@ -118,14 +122,13 @@ func (f *SealightsAgentFramework) Finalize() error {
 	}
 
 	// Get buildpack index for multi-buildpack support
-	depsIdx := f.context.Stager.DepsIdx()
 
 	// Convert staging path to runtime path
 	relPath, err := filepath.Rel(f.context.Stager.DepDir(), agentPath)
 	if err != nil {
 		return fmt.Errorf("failed to compute relative path: %w", err)
 	}
-	runtimeAgentPath := filepath.Join(fmt.Sprintf("$DEPS_DIR/%s", depsIdx), relPath)
+	runtimeAgentPath := f.context.Droplet().Dep(relPath)
 
 	// Get service credentials
 	vcapServices, err := GetVCAPServices()
@@ -167,8 +170,16 @@ func (f *SealightsAgentFramework) Finalize() error {
 		systemProps += fmt.Sprintf(" -Dsl.log.level=%s", logLevel)
 	}
 
-	// Set log folder to runtime deps directory
-	systemProps += fmt.Sprintf(" -Dsl.log.folder=$DEPS_DIR/%s/sealights_logs", depsIdx)
+	// Set log folder to the runtime-writable sealights_logs directory, which is created at staging time
+	logFolder := filepath.Join(f.context.Stager.DepDir(), "sealights_logs")
+	if err := os.MkdirAll(logFolder, 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+	logFolderVar, err := writable.Register(f.context, "sealights_agent", logFolder, writable.Link)
+	if err != nil {
+		return fmt.Errorf("failed to register log directory: %w", err)
+	}
+	systemProps += " -Dsl.log.folder=" + logFolderVar
 
 	// Build javaagent argument
 	javaAgent := fmt.Sprintf("-javaagent:%s", runtimeAgentPath)
@@ -176,6 +187,9 @@ func (f *SealightsAgentFramework) Finalize() error {
 	// Add if custom config is at place
 	config, err := f.loadConfig()
 	if err != nil {
+		if common.IsUnknownConfigKeysError(err) {
+			return err
+		}
 		f.context.Log.Warning("Failed to load sealight config: %s", err.Error())
 		return nil // Don't fail the build
 	}
@@ -201,16 +215,10 @@ func (f *SealightsAgentFramework) Finalize() error {
 	javaOpts := fmt.Sprintf("%s %s", javaAgent, systemProps)
 
 	// Write to .opts file using priority 39
-	if err := writeJavaOptsFile(f.context, 39, "sealights_agent", javaOpts); err != nil {
+	if err := writeJavaOptsFile(f.context, javaopts.Sealights, "sealights_agent", javaOpts); err != nil {
 		return fmt.Errorf("failed to write java_opts file: %w", err)
 	}
 
-	// Create log directory at staging time
-	logFolder := filepath.Join(f.context.Stager.DepDir(), "sealights_logs")
-	if err := os.MkdirAll(logFolder, 0755); err != nil {
-		return fmt.Errorf("failed to create log directory: %w", err)
-	}
-
 	f.context.Log.Debug("Sealights Agent configured (priority 39)")
 	return nil
 }
@@ -223,17 +231,9 @@ func (f *SealightsAgentFramework) loadConfig() (*sealightsAgentConfig, error) {
 		Proxy:          "",
 		AutoUpgrade:    false,
 	}
-	config := os.Getenv("JBP_CONFIG_SEALIGHTS")
-	if config != "" {
-		yamlHandler := common.YamlHandler{}
-		err := yamlHandler.ValidateFields([]byte(config), &sConfig)
-		if err != nil {
-			f.context.Log.Warning("Unknown user config values: %s", err.Error())
-		}
-		// overlay JBP_CONFIG_SEALIGHTS over default values
-		if err = yamlHandler.Unmarshal([]byte(config), &sConfig); err != nil {
-			return nil, fmt.Errorf("failed to parse JBP_CONFIG_SEALIGHTS: %w", err)
-		}
+	// overlay buildpack defaults and JBP_CONFIG_SEALIGHTS over default values
+	if err := config.Load(f.context.Log, "sealights", &sConfig); err != nil {
+		return nil, err
 	}
 	return &sConfig, nil
 }
@@ -9,6 +9,7 @@ import (
 	. "github.com/onsi/gomega"
 
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/writable"
 	"github.com/cloudfoundry/java-buildpack/src/java/frameworks"
 	"github.com/cloudfoundry/libbuildpack"
 )
@@ -190,11 +191,20 @@ var _ = Describe("SealightsAgent", func() {
 				Expect(string(content)).To(ContainSubstring("-Dsl.token=secret-token"))
 			})
 
-			It("opts file contains -Dsl.log.folder pointing to $DEPS_DIR", func() {
+			It("opts file contains -Dsl.log.folder pointing to the runtime-writable sealights_logs directory", func() {
 				Expect(fw.Finalize()).To(Succeed())
 				content, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "39_sealights_agent.opts"))
 				Expect(err).NotTo(HaveOccurred())
-				Expect(string(content)).To(ContainSubstring("-Dsl.log.folder=$DEPS_DIR/0/sealights_logs"))
+				Expect(string(content)).To(ContainSubstring("-Dsl.log.folder=$JBP_WRITABLE_SEALIGHTS_LOGS"))
+
+				dirs, err := writable.Read(filepath.Join(depsDir, "0"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dirs).To(ConsistOf(writable.Dir{
+					Framework: "sealights_agent",
+					Path:      "$DEPS_DIR/0/sealights_logs",
+					Variable:  "JBP_WRITABLE_SEALIGHTS_LOGS",
+					Strategy:  writable.Link,
+				}))
 			})
 
 			It("creates the sealights_logs directory", func() {
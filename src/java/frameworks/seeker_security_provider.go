@@ -3,11 +3,14 @@ package frameworks
 import (
 	"encoding/json"
 	"fmt"
-	"github.com/cloudfoundry/java-buildpack/src/java/common"
-	"io"
-	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/httpclient"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
 
 	"github.com/cloudfoundry/libbuildpack"
 )
@@ -29,88 +32,75 @@ func (s *SeekerSecurityProviderFramework) Detect() (string, error) {
 	// Check for bound Seeker service in VCAP_SERVICES
 	seekerService, err := s.findSeekerService()
 	if err != nil {
+		s.context.Log.Debug("Seeker: no seeker service bound")
 		return "", nil // Service not found, don't enable
 	}
 
 	// Verify required credentials exist
 	credentials, ok := seekerService["credentials"].(map[string]interface{})
 	if !ok {
+		s.context.Log.Debug("Seeker: the seeker service has no credentials")
 		return "", nil
 	}
 
 	serverURL, ok := credentials["seeker_server_url"].(string)
 	if !ok || serverURL == "" {
+		s.context.Log.Debug("Seeker: the seeker service has no seeker_server_url credential")
 		return "", nil
 	}
 
 	return "seeker-security-provider", nil
 }
 
-// Supply installs the Seeker agent by downloading from Seeker server
+// Supply installs the Seeker agent by downloading it from the Seeker server. With the skiperrors credential, a
+// failed download returns an IncompleteError, which leaves the application without the agent instead of failing
+// staging unless framework_supply makes the framework strict.
 func (s *SeekerSecurityProviderFramework) Supply() error {
 	s.context.Log.Debug("Installing Synopsys Seeker Security Provider")
 
-	// Get Seeker service credentials
-	seekerService, err := s.findSeekerService()
+	credentials, err := s.credentials()
 	if err != nil {
-		return fmt.Errorf("Seeker service not found: %w", err)
-	}
-
-	credentials, ok := seekerService["credentials"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("Seeker service credentials not found")
+		return err
 	}
 
-	serverURL, ok := credentials["seeker_server_url"].(string)
-	if !ok || serverURL == "" {
-		return fmt.Errorf("seeker_server_url not found in service credentials")
-	}
-
-	// Construct agent download URL
-	// URL format: https://seeker.example.com/rest/api/latest/installers/agents/binaries/JAVA
-	agentURL := serverURL + "/rest/api/latest/installers/agents/binaries/JAVA"
-
 	seekerDir := filepath.Join(s.context.Stager.DepDir(), "seeker_security_provider")
 	if err := os.MkdirAll(seekerDir, 0755); err != nil {
 		return fmt.Errorf("failed to create Seeker directory: %w", err)
 	}
 
-	// Download and extract agent ZIP from Seeker server
-	s.context.Log.Info("Downloading Seeker agent from %s", agentURL)
-	if err := s.downloadAndExtractAgent(agentURL, seekerDir); err != nil {
-		return fmt.Errorf("failed to download Seeker agent: %w", err)
+	agentURL, err := credentials.resolvedAgentURL()
+	if err == nil {
+		// Download and extract agent ZIP from Seeker server
+		s.context.Log.Info("Downloading Seeker agent from %s", common.RedactURI(agentURL))
+		if err = s.downloadAndExtractAgent(agentURL, seekerDir, credentials.checksum); err != nil {
+			err = fmt.Errorf("failed to download Seeker agent: %w", err)
+		}
+	}
+	if err != nil {
+		if credentials.skipErrors {
+			return incomplete(fmt.Errorf("%w, skipping the Seeker agent because the service sets skiperrors", err))
+		}
+		return err
 	}
 
-	s.context.Log.Debug("Installed Synopsys Seeker Security Provider from %s", serverURL)
+	s.context.Log.Debug("Installed Synopsys Seeker Security Provider from %s", common.RedactURI(credentials.serverURL))
 	return nil
 }
 
-// downloadAndExtractAgent downloads the Seeker agent ZIP and extracts it
-func (s *SeekerSecurityProviderFramework) downloadAndExtractAgent(agentURL, seekerDir string) error {
+// downloadAndExtractAgent downloads the Seeker agent ZIP, verifies it against checksum, if one is given, and extracts it
+func (s *SeekerSecurityProviderFramework) downloadAndExtractAgent(agentURL, seekerDir, checksum string) error {
 	// Create temporary file for download
 	tmpFile, err := os.CreateTemp("", "seeker-agent-*.zip")
 	if err != nil {
 		return fmt.Errorf("failed to create temp file: %w", err)
 	}
 	defer os.Remove(tmpFile.Name())
-	defer tmpFile.Close()
+	tmpFile.Close()
 
 	// Download the ZIP archive from Seeker server
-	resp, err := http.Get(agentURL)
-	if err != nil {
+	if err := httpclient.Default().DownloadVerified(agentURL, tmpFile.Name(), checksum); err != nil {
 		return fmt.Errorf("HTTP request failed: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("HTTP request failed with status %d", resp.StatusCode)
-	}
-
-	// Write response to temp file
-	if _, err := io.Copy(tmpFile, resp.Body); err != nil {
-		return fmt.Errorf("failed to write agent to temp file: %w", err)
-	}
-	tmpFile.Close()
 
 	// Extract the ZIP to seekerDir without stripping (strip_top_level = false in Ruby)
 	s.context.Log.Info("Extracting Seeker agent to: %s", seekerDir)
@@ -124,46 +114,47 @@ func (s *SeekerSecurityProviderFramework) downloadAndExtractAgent(agentURL, seek
 		return fmt.Errorf("seeker-agent.jar not found in extracted ZIP: %w", err)
 	}
 
+	if err := common.RecordDownload(s.context.Stager.DepDir(), common.InstalledDependency{
+		Name: "seeker-agent", URI: agentURL, Dir: "seeker_security_provider",
+	}, tmpFile.Name()); err != nil {
+		s.context.Log.Warning("Could not record the Seeker agent for the SBOM: %s", err.Error())
+	}
+
 	return nil
 }
 
 // Finalize configures the Seeker agent for runtime
 func (s *SeekerSecurityProviderFramework) Finalize() error {
-	// Get Seeker service credentials
-	seekerService, err := s.findSeekerService()
+	credentials, err := s.credentials()
 	if err != nil {
-		return fmt.Errorf("Seeker service not found: %w", err)
+		return err
 	}
 
-	credentials, ok := seekerService["credentials"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("Seeker service credentials not found")
-	}
-
-	serverURL, ok := credentials["seeker_server_url"].(string)
-	if !ok || serverURL == "" {
-		return fmt.Errorf("seeker_server_url not found in service credentials")
+	if _, err := os.Stat(filepath.Join(s.context.Stager.DepDir(), "seeker_security_provider", "seeker-agent.jar")); err != nil {
+		if credentials.skipErrors {
+			s.context.Log.Warning("Seeker agent is not installed, the application runs without it")
+			return nil
+		}
+		return fmt.Errorf("seeker-agent.jar not found: %w", err)
 	}
 
-	// Get buildpack index for multi-buildpack support
-	depsIdx := s.context.Stager.DepsIdx()
-
 	// Build runtime agent path
-	agentJar := fmt.Sprintf("$DEPS_DIR/%s/seeker_security_provider/seeker-agent.jar", depsIdx)
+	agentJar := s.context.Droplet().Dep("seeker_security_provider", "seeker-agent.jar")
 
 	// Build javaagent option
 	javaOpts := fmt.Sprintf("-javaagent:%s", agentJar)
 
 	// Write to .opts file using priority 40
-	if err := writeJavaOptsFile(s.context, 40, "seeker_security_provider", javaOpts); err != nil {
+	if err := writeJavaOptsFile(s.context, javaopts.SeekerSecurityProvider, "seeker_security_provider", javaOpts); err != nil {
 		return fmt.Errorf("failed to write java_opts file: %w", err)
 	}
 
-	// Set SEEKER_SERVER_URL environment variable via profile.d
+	// Set SEEKER_SERVER_URL environment variable via profile.d. References that were not set at staging are
+	// resolved by the shell when the application starts.
 	profileScript := fmt.Sprintf(`#!/bin/bash
 # Configure Synopsys Seeker Security Provider
 export SEEKER_SERVER_URL="%s"
-`, serverURL)
+`, credentials.serverURL)
 
 	if err := s.context.Stager.WriteProfileD("seeker_security_provider.sh", profileScript); err != nil {
 		return fmt.Errorf("failed to write Seeker profile.d script: %w", err)
@@ -173,6 +164,75 @@ export SEEKER_SERVER_URL="%s"
 	return nil
 }
 
+// seekerAgentPath is the path of the Java agent download on a Seeker server
+const seekerAgentPath = "/rest/api/latest/installers/agents/binaries/JAVA"
+
+// seekerCredentials are the credentials of the bound Seeker service
+type seekerCredentials struct {
+	// serverURL is seeker_server_url with the ${NAME} references that are set at staging resolved
+	serverURL string
+	// agentURL is the agent_download_url credential, or the agent download of the server, with ${NAME} references
+	// and ${SEEKER_SERVER_URL} resolved
+	agentURL string
+	// unresolved are the references of agentURL that are not set at staging
+	unresolved []string
+	checksum   string
+	skipErrors bool
+}
+
+// credentials reads the credentials of the bound Seeker service. seeker_server_url and agent_download_url may refer
+// to environment variables as ${NAME}, e.g. https://seeker.${SEEKER_DOMAIN}, for enterprise servers whose address
+// differs between foundations; agent_download_url may also refer to the server as ${SEEKER_SERVER_URL}.
+func (s *SeekerSecurityProviderFramework) credentials() (*seekerCredentials, error) {
+	seekerService, err := s.findSeekerService()
+	if err != nil {
+		return nil, fmt.Errorf("Seeker service not found: %w", err)
+	}
+
+	credentials, ok := seekerService["credentials"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Seeker service credentials not found")
+	}
+
+	serverURL, ok := credentials["seeker_server_url"].(string)
+	if !ok || serverURL == "" {
+		return nil, fmt.Errorf("seeker_server_url not found in service credentials")
+	}
+	serverURL, _ = common.Interpolate(strings.TrimRight(serverURL, "/"), os.LookupEnv)
+
+	template, _ := credentials["agent_download_url"].(string)
+	if template == "" {
+		template = "${SEEKER_SERVER_URL}" + seekerAgentPath
+	}
+	agentURL, _ := common.Interpolate(template, func(name string) (string, bool) {
+		if name == "SEEKER_SERVER_URL" {
+			return serverURL, true
+		}
+		return os.LookupEnv(name)
+	})
+	// The references left include those of seeker_server_url
+	_, unresolved := common.Interpolate(agentURL, func(string) (string, bool) { return "", false })
+
+	c := &seekerCredentials{serverURL: serverURL, agentURL: agentURL, unresolved: unresolved}
+	c.checksum, _ = credentials["checksum"].(string)
+	switch skipErrors := credentials["skiperrors"].(type) {
+	case bool:
+		c.skipErrors = skipErrors
+	case string:
+		c.skipErrors, _ = strconv.ParseBool(skipErrors)
+	}
+	return c, nil
+}
+
+// resolvedAgentURL returns the URL of the agent download, or an error if it refers to variables not set at staging
+func (c *seekerCredentials) resolvedAgentURL() (string, error) {
+	if len(c.unresolved) > 0 {
+		return "", fmt.Errorf("the Seeker agent download %s refers to %s, which is not set at staging",
+			common.RedactURI(c.agentURL), strings.Join(c.unresolved, ", "))
+	}
+	return c.agentURL, nil
+}
+
 // findSeekerService locates the Seeker service in VCAP_SERVICES
 func (s *SeekerSecurityProviderFramework) findSeekerService() (map[string]interface{}, error) {
 	vcapServices := os.Getenv("VCAP_SERVICES")
@@ -185,7 +245,8 @@ func (s *SeekerSecurityProviderFramework) findSeekerService() (map[string]interf
 		return nil, fmt.Errorf("failed to parse VCAP_SERVICES: %w", err)
 	}
 
-	for serviceType, serviceList := range services {
+	for _, serviceType := range sortedLabels(services) {
+		serviceList := services[serviceType]
 		if common.ContainsIgnoreCase(serviceType, "seeker") {
 			if len(serviceList) > 0 {
 				return serviceList[0], nil
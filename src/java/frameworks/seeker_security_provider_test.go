@@ -1,7 +1,13 @@
 package frameworks_test
 
 import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 
@@ -273,6 +279,13 @@ var _ = Describe("SeekerSecurityProvider", func() {
 			})
 		})
 
+		Context("when the agent is not installed", func() {
+			It("returns an error", func() {
+				os.Setenv("VCAP_SERVICES", seekerVCAPServices("seeker", "my-seeker", nil, "https://seeker.example.com", ""))
+				Expect(fw.Finalize()).To(MatchError(ContainSubstring("seeker-agent.jar not found")))
+			})
+		})
+
 		Context("when VCAP_SERVICES is not set", func() {
 			It("returns an error", func() {
 				installSeekerAgent(depsDir)
@@ -308,4 +321,144 @@ var _ = Describe("SeekerSecurityProvider", func() {
 			})
 		})
 	})
+
+	Describe("staging against a fake Seeker server", func() {
+		var (
+			server   *httptest.Server
+			agentZip []byte
+			requests int
+		)
+
+		BeforeEach(func() {
+			// The agent archive contains a stub seeker-agent.jar
+			var archive bytes.Buffer
+			writer := zip.NewWriter(&archive)
+			file, err := writer.Create("seeker-agent.jar")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = file.Write([]byte("stub agent"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(writer.Close()).To(Succeed())
+			agentZip = archive.Bytes()
+
+			requests = 0
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				switch r.URL.Path {
+				case "/rest/api/latest/installers/agents/binaries/JAVA", "/enterprise/agents/java.zip":
+					w.Write(agentZip)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+			os.Unsetenv("SEEKER_TEST_SERVER")
+		})
+
+		profileScript := func() string {
+			content, err := os.ReadFile(filepath.Join(depsDir, "0", "profile.d", "seeker_security_provider.sh"))
+			Expect(err).NotTo(HaveOccurred())
+			return string(content)
+		}
+
+		It("installs the agent from the server and configures it", func() {
+			os.Setenv("VCAP_SERVICES", seekerVCAPServices("seeker", "fake-seeker", nil, server.URL, ""))
+
+			Expect(fw.Supply()).To(Succeed())
+			Expect(fw.Finalize()).To(Succeed())
+
+			depDir := filepath.Join(depsDir, "0")
+			Expect(os.ReadFile(filepath.Join(depDir, "seeker_security_provider", "seeker-agent.jar"))).To(Equal([]byte("stub agent")))
+			expectGoldenFile(filepath.Join(depDir, "java_opts", "40_seeker_security_provider.opts"), "seeker/40_seeker_security_provider.opts")
+			expectGoldenFile(filepath.Join(depDir, "profile.d", "seeker_security_provider.sh"), "seeker/seeker_security_provider.sh",
+				server.URL, "$SEEKER_SERVER")
+			Expect(requests).To(Equal(1))
+		})
+
+		It("verifies the agent against the checksum credential", func() {
+			digest := sha256.Sum256(agentZip)
+			os.Setenv("VCAP_SERVICES", seekerVCAPServices("seeker", "fake-seeker", nil, server.URL,
+				fmt.Sprintf(`"checksum":%q`, hex.EncodeToString(digest[:]))))
+
+			Expect(fw.Supply()).To(Succeed())
+		})
+
+		It("rejects an agent that does not match the checksum credential", func() {
+			os.Setenv("VCAP_SERVICES", seekerVCAPServices("seeker", "fake-seeker", nil, server.URL,
+				`"checksum":"0000000000000000000000000000000000000000000000000000000000000000"`))
+
+			Expect(fw.Supply()).To(MatchError(ContainSubstring("the download is corrupt or was tampered with")))
+			Expect(filepath.Join(depsDir, "0", "seeker_security_provider", "seeker-agent.jar")).NotTo(BeAnExistingFile())
+		})
+
+		It("resolves environment variables in the server URL", func() {
+			os.Setenv("SEEKER_TEST_SERVER", server.URL)
+			os.Setenv("VCAP_SERVICES", seekerVCAPServices("seeker", "fake-seeker", nil, "${SEEKER_TEST_SERVER}/", ""))
+
+			Expect(fw.Supply()).To(Succeed())
+			Expect(fw.Finalize()).To(Succeed())
+
+			Expect(requests).To(Equal(1))
+			Expect(profileScript()).To(ContainSubstring(fmt.Sprintf(`export SEEKER_SERVER_URL="%s"`, server.URL)))
+		})
+
+		It("downloads the agent from the agent_download_url credential", func() {
+			os.Setenv("VCAP_SERVICES", seekerVCAPServices("seeker", "fake-seeker", nil, server.URL,
+				`"agent_download_url":"${SEEKER_SERVER_URL}/enterprise/agents/java.zip"`))
+
+			Expect(fw.Supply()).To(Succeed())
+			Expect(filepath.Join(depsDir, "0", "seeker_security_provider", "seeker-agent.jar")).To(BeAnExistingFile())
+		})
+
+		It("leaves references that are not set at staging to the runtime", func() {
+			os.Setenv("VCAP_SERVICES", seekerVCAPServices("seeker", "fake-seeker", nil, "https://${SEEKER_RUNTIME_HOST}",
+				fmt.Sprintf(`"agent_download_url":"%s/enterprise/agents/java.zip"`, server.URL)))
+
+			Expect(fw.Supply()).To(Succeed())
+			Expect(fw.Finalize()).To(Succeed())
+
+			Expect(profileScript()).To(ContainSubstring(`export SEEKER_SERVER_URL="https://${SEEKER_RUNTIME_HOST}"`))
+		})
+
+		It("fails when the agent download refers to variables not set at staging", func() {
+			os.Setenv("VCAP_SERVICES", seekerVCAPServices("seeker", "fake-seeker", nil, "https://${SEEKER_RUNTIME_HOST}", ""))
+
+			Expect(fw.Supply()).To(MatchError(ContainSubstring("refers to SEEKER_RUNTIME_HOST, which is not set at staging")))
+			Expect(requests).To(BeZero())
+		})
+
+		It("fails when the agent cannot be downloaded", func() {
+			os.Setenv("VCAP_SERVICES", seekerVCAPServices("seeker", "fake-seeker", nil, server.URL+"/missing", ""))
+
+			Expect(fw.Supply()).To(MatchError(ContainSubstring("failed to download Seeker agent")))
+		})
+
+		Context("when the service sets skiperrors", func() {
+			BeforeEach(func() {
+				os.Setenv("VCAP_SERVICES", seekerVCAPServices("seeker", "fake-seeker", nil, server.URL+"/missing",
+					`"skiperrors":"true"`))
+			})
+
+			It("stages without the agent when it cannot be downloaded", func() {
+				err := fw.Supply()
+				Expect(frameworks.IsIncompleteError(err)).To(BeTrue())
+				Expect(err).To(MatchError(ContainSubstring("skipping the Seeker agent because the service sets skiperrors")))
+				Expect(fw.Finalize()).To(Succeed())
+
+				Expect(filepath.Join(depsDir, "0", "java_opts", "40_seeker_security_provider.opts")).NotTo(BeAnExistingFile())
+				Expect(filepath.Join(depsDir, "0", "profile.d", "seeker_security_provider.sh")).NotTo(BeAnExistingFile())
+			})
+
+			It("still installs an agent that can be downloaded", func() {
+				os.Setenv("VCAP_SERVICES", seekerVCAPServices("seeker", "fake-seeker", nil, server.URL, `"skiperrors":true`))
+
+				Expect(fw.Supply()).To(Succeed())
+				Expect(fw.Finalize()).To(Succeed())
+
+				Expect(filepath.Join(depsDir, "0", "java_opts", "40_seeker_security_provider.opts")).To(BeAnExistingFile())
+			})
+		})
+	})
 })
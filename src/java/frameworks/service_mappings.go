@@ -0,0 +1,224 @@
+package frameworks
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
+)
+
+var envVarNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ServiceMappingsFramework turns values of bound services into system properties or environment variables
+// according to rules in JBP_CONFIG_SERVICE_MAPPINGS, e.g.
+//
+//	rules:
+//	- service: mysql
+//	  from: credentials.uri
+//	  property: spring.datasource.url
+//	- service: mysql
+//	  from: credentials.password
+//	  env: DB_PASSWORD
+//
+// Operators ship rules in $BUILDPACK_DIR/config/service_mappings.yml or JBP_DEFAULT_SERVICE_MAPPINGS. It covers
+// one-off integrations that do not justify a dedicated framework.
+type ServiceMappingsFramework struct {
+	context *common.Context
+}
+
+// NewServiceMappingsFramework creates a new Service Mappings framework instance
+func NewServiceMappingsFramework(ctx *common.Context) *ServiceMappingsFramework {
+	return &ServiceMappingsFramework{context: ctx}
+}
+
+// serviceMappingRule maps one value of a bound service to a system property or an environment variable
+type serviceMappingRule struct {
+	// Service matches the service's label or a tag, or is contained in its name
+	Service string `yaml:"service"`
+	// From is the dotted path of the value in the service binding, e.g. credentials.uri
+	From     string `yaml:"from"`
+	Property string `yaml:"property"`
+	Env      string `yaml:"env"`
+}
+
+type serviceMappingsConfig struct {
+	Rules []serviceMappingRule `yaml:"rules"`
+}
+
+// serviceMapping is a rule resolved against the bound services
+type serviceMapping struct {
+	rule  serviceMappingRule
+	value string
+}
+
+// Detect checks if any rule matches a bound service
+func (s *ServiceMappingsFramework) Detect() (string, error) {
+	mappings, err := s.resolve()
+	if err != nil {
+		return "", err
+	}
+	if len(mappings) == 0 {
+		s.context.Log.Debug("Service Mappings: no mapping matches a bound service")
+		return "", nil
+	}
+
+	s.context.Log.Debug("Service Mappings framework detected with %d mappings", len(mappings))
+	return "Service Mappings", nil
+}
+
+// Supply does nothing, the mappings need no dependencies
+func (s *ServiceMappingsFramework) Supply() error {
+	return nil
+}
+
+// Finalize writes the mapped system properties to JAVA_OPTS and the mapped environment variables to profile.d
+func (s *ServiceMappingsFramework) Finalize() error {
+	s.context.Log.BeginStep("Configuring Service Mappings")
+
+	mappings, err := s.resolve()
+	if err != nil {
+		return err
+	}
+
+	var opts []string
+	var exports []string
+	for _, mapping := range mappings {
+		if mapping.rule.Property != "" {
+			// credentials are literal values, so $ must not be expanded when JAVA_OPTS is assembled
+			opts = append(opts, fmt.Sprintf("-D%s=%s", mapping.rule.Property, escapeValue(strings.ReplaceAll(mapping.value, "$", `\$`))))
+			s.context.Log.Info("Mapped %s of %s to system property %s", mapping.rule.From, mapping.rule.Service, mapping.rule.Property)
+		} else {
+			exports = append(exports, fmt.Sprintf("export %s=%s", mapping.rule.Env, shellEscape(mapping.value)))
+			s.context.Log.Info("Mapped %s of %s to environment variable %s", mapping.rule.From, mapping.rule.Service, mapping.rule.Env)
+		}
+	}
+
+	if len(opts) > 0 {
+		if err := writeJavaOptsFile(s.context, javaopts.ServiceMappings, "service_mappings", strings.Join(opts, " ")); err != nil {
+			return fmt.Errorf("failed to write JAVA_OPTS for Service Mappings: %w", err)
+		}
+	}
+
+	if len(exports) > 0 {
+		script := "#!/bin/bash\n# Environment variables mapped from bound services\n" + strings.Join(exports, "\n") + "\n"
+		if err := s.context.Stager.WriteProfileD("service_mappings.sh", script); err != nil {
+			return fmt.Errorf("failed to write service_mappings.sh: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// resolve returns the valid rules whose value is provided by a bound service. Rules that are invalid are
+// reported and skipped.
+func (s *ServiceMappingsFramework) resolve() ([]serviceMapping, error) {
+	cfg := serviceMappingsConfig{}
+	if err := config.Load(s.context.Log, "service_mappings", &cfg); err != nil {
+		return nil, err
+	}
+	return resolveServiceMappings(s.context, cfg.Rules), nil
+}
+
+// resolveServiceMappings returns the valid rules whose value is provided by a bound service. When several services
+// match a rule, the first one of VCAPServices.Sorted that provides the value is used.
+func resolveServiceMappings(ctx *common.Context, rules []serviceMappingRule) []serviceMapping {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	vcapServices, err := GetVCAPServices()
+	if err != nil {
+		ctx.Log.Warning("Failed to parse VCAP_SERVICES: %s", err.Error())
+		return nil
+	}
+	services := vcapServices.Sorted()
+
+	var mappings []serviceMapping
+	for _, rule := range rules {
+		if err := rule.validate(); err != nil {
+			ctx.Log.Warning("Ignoring service mapping %+v: %s", rule, err.Error())
+			continue
+		}
+
+		found := false
+		for _, service := range services {
+			if !matchesServiceFilter(service, rule.Service) {
+				continue
+			}
+			if value, ok := serviceValue(service, rule.From); ok {
+				mappings = append(mappings, serviceMapping{rule: rule, value: value})
+				found = true
+				break
+			}
+		}
+		if !found {
+			ctx.Log.Debug("Service mapping: no %s service provides %s", rule.Service, rule.From)
+		}
+	}
+	return mappings
+}
+
+// validate checks that the rule names a service, a value and exactly one target
+func (r serviceMappingRule) validate() error {
+	if r.Service == "" || r.From == "" {
+		return fmt.Errorf("service and from are required")
+	}
+	if (r.Property == "") == (r.Env == "") {
+		return fmt.Errorf("exactly one of property and env is required")
+	}
+	if r.Property != "" && strings.ContainsAny(r.Property, " \t\n=") {
+		return fmt.Errorf("invalid property name %q", r.Property)
+	}
+	if r.Env != "" && !envVarNamePattern.MatchString(r.Env) {
+		return fmt.Errorf("invalid environment variable name %q", r.Env)
+	}
+	return nil
+}
+
+// serviceValue returns the scalar at the dotted path in the service binding, e.g. credentials.uri or name
+func serviceValue(service VCAPService, path string) (string, bool) {
+	var current interface{} = map[string]interface{}{
+		"name":        service.Name,
+		"label":       service.Label,
+		"credentials": service.Credentials,
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		values, ok := current.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		if current, ok = values[key]; !ok {
+			return "", false
+		}
+	}
+
+	switch v := current.(type) {
+	case string:
+		return v, v != ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		return "", false
+	}
+}
+
+// matchesServiceFilter returns true if the service's label or a tag equals filter or its name contains filter,
+// ignoring case
+func matchesServiceFilter(service VCAPService, filter string) bool {
+	if strings.EqualFold(service.Label, filter) || common.ContainsIgnoreCase(service.Name, filter) {
+		return true
+	}
+	for _, tag := range service.Tags {
+		if strings.EqualFold(tag, filter) {
+			return true
+		}
+	}
+	return false
+}
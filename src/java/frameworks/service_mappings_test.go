@@ -0,0 +1,112 @@
+package frameworks_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/frameworks"
+	"github.com/cloudfoundry/libbuildpack"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ServiceMappingsFramework", func() {
+	const mysqlService = `{"p.mysql":[{"name":"orders-db","label":"p.mysql","tags":["mysql"],` +
+		`"credentials":{"uri":"mysql://db.example.com:3306/orders","port":3306,"password":"pa$$ word","tls":{"ca":"---CA---"}}}]}`
+
+	var (
+		framework *frameworks.ServiceMappingsFramework
+		tmpDir    string
+		depsDir   string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "service-mappings-test-*")
+		Expect(err).NotTo(HaveOccurred())
+		depsDir = filepath.Join(tmpDir, "deps")
+		Expect(os.MkdirAll(filepath.Join(depsDir, "0"), 0755)).To(Succeed())
+
+		logger := libbuildpack.NewLogger(GinkgoWriter)
+		manifest := &libbuildpack.Manifest{}
+		ctx := &common.Context{
+			Stager:   libbuildpack.NewStager([]string{tmpDir, "", depsDir, "0"}, logger, manifest),
+			Manifest: manifest,
+			Log:      logger,
+		}
+		framework = frameworks.NewServiceMappingsFramework(ctx)
+
+		os.Setenv("VCAP_SERVICES", mysqlService)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+		os.Unsetenv("VCAP_SERVICES")
+		os.Unsetenv("JBP_CONFIG_SERVICE_MAPPINGS")
+	})
+
+	Describe("Detect", func() {
+		It("does not detect without rules", func() {
+			name, err := framework.Detect()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(BeEmpty())
+		})
+
+		It("detects when a rule matches a bound service", func() {
+			os.Setenv("JBP_CONFIG_SERVICE_MAPPINGS", `{rules: [{service: mysql, from: credentials.uri, property: spring.datasource.url}]}`)
+
+			name, err := framework.Detect()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("Service Mappings"))
+		})
+
+		It("does not detect when no service provides the value", func() {
+			os.Setenv("JBP_CONFIG_SERVICE_MAPPINGS", `{rules: [{service: mysql, from: credentials.username, property: spring.datasource.username}]}`)
+
+			name, err := framework.Detect()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(BeEmpty())
+		})
+
+		It("ignores rules without exactly one target", func() {
+			os.Setenv("JBP_CONFIG_SERVICE_MAPPINGS", `{rules: [{service: mysql, from: credentials.uri, property: a.b, env: A_B}]}`)
+
+			name, err := framework.Detect()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(BeEmpty())
+		})
+	})
+
+	Describe("Finalize", func() {
+		It("renders system properties and environment variables", func() {
+			os.Setenv("JBP_CONFIG_SERVICE_MAPPINGS", `{rules: [
+				{service: p.mysql, from: credentials.uri, property: spring.datasource.url},
+				{service: orders-db, from: credentials.port, property: db.port},
+				{service: mysql, from: credentials.tls.ca, env: DB_CA},
+				{service: mysql, from: credentials.password, env: DB_PASSWORD}]}`)
+
+			Expect(framework.Finalize()).To(Succeed())
+
+			opts, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "43_service_mappings.opts"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(opts)).To(Equal("-Dspring.datasource.url=mysql://db.example.com:3306/orders -Ddb.port=3306"))
+
+			script, err := os.ReadFile(filepath.Join(depsDir, "0", "profile.d", "service_mappings.sh"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(script)).To(ContainSubstring("export DB_CA=---CA---\n"))
+			Expect(string(script)).To(ContainSubstring("export DB_PASSWORD='pa$$ word'\n"))
+		})
+
+		It("keeps $ in property values literal", func() {
+			os.Setenv("JBP_CONFIG_SERVICE_MAPPINGS", `{rules: [{service: mysql, from: credentials.password, property: db.password}]}`)
+
+			Expect(framework.Finalize()).To(Succeed())
+
+			opts, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "43_service_mappings.opts"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(opts)).To(Equal(`-Ddb.password=pa\$\$\ word`))
+			Expect(filepath.Join(depsDir, "0", "profile.d", "service_mappings.sh")).NotTo(BeAnExistingFile())
+		})
+	})
+})
@@ -19,6 +19,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/appname"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
 	"os"
 	"path/filepath"
 	"strings"
@@ -103,14 +106,13 @@ func (s *SkyWalkingAgentFramework) Finalize() error {
 	s.context.Log.BeginStep("Configuring SkyWalking agent")
 
 	// Get buildpack index for multi-buildpack support
-	depsIdx := s.context.Stager.DepsIdx()
 
 	// Convert staging path to runtime path
 	relPath, err := filepath.Rel(s.context.Stager.DepDir(), s.jarPath)
 	if err != nil {
 		return fmt.Errorf("failed to determine relative path for SkyWalking agent: %w", err)
 	}
-	runtimeJarPath := filepath.Join(fmt.Sprintf("$DEPS_DIR/%s", depsIdx), relPath)
+	runtimeJarPath := s.context.Droplet().Dep(relPath)
 
 	// Get credentials from service binding
 	credentials := s.getCredentials()
@@ -132,7 +134,7 @@ func (s *SkyWalkingAgentFramework) Finalize() error {
 
 	// Write all options to .opts file
 	javaOpts := strings.Join(opts, " ")
-	if err := writeJavaOptsFile(s.context, 41, "sky_walking_agent", javaOpts); err != nil {
+	if err := writeJavaOptsFile(s.context, javaopts.SkyWalking, "sky_walking_agent", javaOpts); err != nil {
 		return fmt.Errorf("failed to write JAVA_OPTS for SkyWalking: %w", err)
 	}
 
@@ -201,7 +203,11 @@ func (s *SkyWalkingAgentFramework) getCredentials() SkyWalkingCredentials {
 }
 
 func (s *SkyWalkingAgentFramework) getAppName() string {
-	appName := GetApplicationName(true)
+	appName, err := appname.Name(s.context.Log, appname.SpaceApp)
+	if err != nil {
+		s.context.Log.Warning("Failed to load application name config: %s", err.Error())
+		return ""
+	}
 	if appName != "" {
 		return appName
 	}
@@ -228,17 +234,9 @@ func (s *SkyWalkingAgentFramework) loadConfig() (*skyWalkingAgentConfig, error)
 	swaConfig := skyWalkingAgentConfig{
 		DefaultApplicationName: "",
 	}
-	config := os.Getenv("JBP_CONFIG_SKY_WALKING_AGENT")
-	if config != "" {
-		yamlHandler := common.YamlHandler{}
-		err := yamlHandler.ValidateFields([]byte(config), &swaConfig)
-		if err != nil {
-			s.context.Log.Warning("Unknown user config values: %s", err.Error())
-		}
-		// overlay JBP_CONFIG_SKY_WALKING_AGENT over default values
-		if err = yamlHandler.Unmarshal([]byte(config), &swaConfig); err != nil {
-			return nil, fmt.Errorf("failed to parse JBP_CONFIG_SKY_WALKING_AGENT: %w", err)
-		}
+	// overlay buildpack defaults and JBP_CONFIG_SKY_WALKING_AGENT over default values
+	if err := config.Load(s.context.Log, "sky_walking_agent", &swaConfig); err != nil {
+		return nil, err
 	}
 	return &swaConfig, nil
 }
@@ -18,6 +18,8 @@ package frameworks
 import (
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/appname"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
 	"os"
 	"path/filepath"
 	"strings"
@@ -110,14 +112,13 @@ func (s *SplunkOtelJavaAgentFramework) Finalize() error {
 	s.context.Log.BeginStep("Configuring Splunk OTEL Java agent")
 
 	// Get buildpack index for multi-buildpack support
-	depsIdx := s.context.Stager.DepsIdx()
 
 	// Convert staging path to runtime path
 	relPath, err := filepath.Rel(s.context.Stager.DepDir(), s.jarPath)
 	if err != nil {
 		return fmt.Errorf("failed to determine relative path for Splunk OTEL Java agent: %w", err)
 	}
-	runtimeJarPath := filepath.Join(fmt.Sprintf("$DEPS_DIR/%s", depsIdx), relPath)
+	runtimeJarPath := s.context.Droplet().Dep(relPath)
 
 	// Get credentials from service binding
 	credentials := s.getCredentials()
@@ -127,8 +128,12 @@ func (s *SplunkOtelJavaAgentFramework) Finalize() error {
 	opts = append(opts, fmt.Sprintf("-javaagent:%s", runtimeJarPath))
 
 	// Configure service name
-	if appName := GetApplicationName(false); appName != "" {
-		opts = append(opts, fmt.Sprintf("-Dotel.service.name=%s", appName))
+	appName, err := appname.Name(s.context.Log, appname.App)
+	if err != nil {
+		return err
+	}
+	if appName != "" {
+		opts = append(opts, fmt.Sprintf("-Dotel.service.name=%s", shellEscape(appName)))
 	}
 
 	// Configure OTLP endpoint
@@ -148,7 +153,7 @@ func (s *SplunkOtelJavaAgentFramework) Finalize() error {
 
 	// Write all options to .opts file
 	javaOpts := strings.Join(opts, " ")
-	if err := writeJavaOptsFile(s.context, 42, "splunk_otel_java_agent", javaOpts); err != nil {
+	if err := writeJavaOptsFile(s.context, javaopts.SplunkOtel, "splunk_otel_java_agent", javaOpts); err != nil {
 		return fmt.Errorf("failed to write JAVA_OPTS for Splunk OTEL: %w", err)
 	}
 
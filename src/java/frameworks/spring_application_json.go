@@ -0,0 +1,138 @@
+package frameworks
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+)
+
+// SpringApplicationJSONFramework renders values of bound services into SPRING_APPLICATION_JSON, for applications
+// that relied on Spring Auto-reconfiguration and read their settings from that variable. Its rules use the syntax
+// of the Service Mappings framework, e.g.
+//
+//	rules:
+//	- service: mysql
+//	  from: credentials.uri
+//	  property: spring.datasource.url
+//
+// The values are resolved from VCAP_SERVICES when the application starts, so that credentials are not written to
+// the droplet and a rebound service takes effect on restart. Properties of the application's own
+// SPRING_APPLICATION_JSON take precedence.
+type SpringApplicationJSONFramework struct {
+	context *common.Context
+}
+
+// springApplicationJSONScript resolves the rules in $rules against the bound services in $services like
+// resolveServiceMappings and merges the application's own properties in $application over the result
+const springApplicationJSONScript = `def scalar: if type == "string" then select(. != "") elif type == "number" or type == "boolean" then tostring else empty end;
+def matches($filter): ($filter | ascii_downcase) as $f
+  | ((.label // "") | ascii_downcase) == $f
+    or ((.name // "") | ascii_downcase | contains($f))
+    or any((.tags // [])[]; ascii_downcase == $f);
+(if $application == "" then {} else (try ($application | fromjson) catch null) end) as $app
+| if ($app | type) != "object" then error("SPRING_APPLICATION_JSON is not a JSON object, service bindings are not rendered into it") else . end
+| [(if $services == "" then {} else ($services | fromjson) end) | to_entries | sort_by(.key)[] | .value[]] as $sorted
+| reduce $rules[] as $rule ({};
+    ([$sorted[] | select(matches($rule.service)) | {name: .name, label: .label, credentials: .credentials}
+      | (try getpath($rule.from | split(".")) catch null) | scalar] | first) as $value
+    | if $value == null then . else .[$rule.property] = $value end)
+| . + $app`
+
+// NewSpringApplicationJSONFramework creates a new Spring Application JSON framework instance
+func NewSpringApplicationJSONFramework(ctx *common.Context) *SpringApplicationJSONFramework {
+	return &SpringApplicationJSONFramework{context: ctx}
+}
+
+type springApplicationJSONConfig struct {
+	Rules []serviceMappingRule `yaml:"rules"`
+}
+
+// Detect checks if any rule matches a bound service
+func (s *SpringApplicationJSONFramework) Detect() (string, error) {
+	mappings, err := s.mappings()
+	if err != nil {
+		return "", err
+	}
+	if len(mappings) == 0 {
+		s.context.Log.Debug("Spring Application JSON: no mapping matches a bound service")
+		return "", nil
+	}
+
+	s.context.Log.Debug("Spring Application JSON framework detected with %d mappings", len(mappings))
+	return "Spring Application JSON", nil
+}
+
+// Supply does nothing, the mappings need no dependencies
+func (s *SpringApplicationJSONFramework) Supply() error {
+	return nil
+}
+
+// Finalize writes the profile.d script that renders the mapped values into SPRING_APPLICATION_JSON at runtime
+func (s *SpringApplicationJSONFramework) Finalize() error {
+	rules, err := s.rules()
+	if err != nil {
+		return err
+	}
+	mappings := resolveServiceMappings(s.context, rules)
+	if len(mappings) == 0 {
+		return nil
+	}
+	s.context.Log.BeginStep("Configuring Spring Application JSON")
+
+	var properties []map[string]string
+	for _, rule := range rules {
+		if rule.validate() == nil {
+			properties = append(properties, map[string]string{"service": rule.Service, "from": rule.From, "property": rule.Property})
+		}
+	}
+	data, err := json.Marshal(properties)
+	if err != nil {
+		return fmt.Errorf("failed to render the Spring Application JSON rules: %w", err)
+	}
+
+	script := fmt.Sprintf(`#!/bin/bash
+# Service binding values rendered into SPRING_APPLICATION_JSON, under the properties the application sets itself
+if spring_application_json=$(jq -n -c -S --argjson rules %s --arg services "${VCAP_SERVICES:-}" --arg application "${SPRING_APPLICATION_JSON:-}" %s); then
+  export SPRING_APPLICATION_JSON="$spring_application_json"
+fi
+unset spring_application_json
+`, shellEscape(string(data)), shellEscape(springApplicationJSONScript))
+	if err := s.context.Stager.WriteProfileD("spring_application_json.sh", script); err != nil {
+		return fmt.Errorf("failed to write spring_application_json.sh: %w", err)
+	}
+
+	for _, mapping := range mappings {
+		s.context.Log.Info("Mapping %s of %s to %s in SPRING_APPLICATION_JSON", mapping.rule.From, mapping.rule.Service, mapping.rule.Property)
+	}
+	return nil
+}
+
+// mappings returns the rules whose value is provided by a bound service at staging. Invalid rules are reported and
+// skipped.
+func (s *SpringApplicationJSONFramework) mappings() ([]serviceMapping, error) {
+	rules, err := s.rules()
+	if err != nil {
+		return nil, err
+	}
+	return resolveServiceMappings(s.context, rules), nil
+}
+
+// rules returns the configured rules without those that set an environment variable, which are reported
+func (s *SpringApplicationJSONFramework) rules() ([]serviceMappingRule, error) {
+	cfg := springApplicationJSONConfig{}
+	if err := config.Load(s.context.Log, "spring_application_json", &cfg); err != nil {
+		return nil, err
+	}
+
+	var rules []serviceMappingRule
+	for _, rule := range cfg.Rules {
+		if rule.Env != "" {
+			s.context.Log.Warning("Ignoring Spring Application JSON rule %+v: env is not supported, use %s", rule, config.EnvVar("service_mappings"))
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
@@ -0,0 +1,143 @@
+package frameworks_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/frameworks"
+	"github.com/cloudfoundry/libbuildpack"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SpringApplicationJSONFramework", func() {
+	const vcapServices = `{"p.mysql":[{"name":"orders-db","label":"p.mysql","tags":["MySQL"],` +
+		`"credentials":{"uri":"mysql://db.example.com:3306/orders","port":3306,"password":"it's secret"}}]}`
+
+	var (
+		framework *frameworks.SpringApplicationJSONFramework
+		tmpDir    string
+		depsDir   string
+		buffer    *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "spring-application-json-test-*")
+		Expect(err).NotTo(HaveOccurred())
+		depsDir = filepath.Join(tmpDir, "deps")
+		Expect(os.MkdirAll(filepath.Join(depsDir, "0"), 0755)).To(Succeed())
+
+		buffer = new(bytes.Buffer)
+		logger := libbuildpack.NewLogger(io.MultiWriter(GinkgoWriter, buffer))
+		manifest := &libbuildpack.Manifest{}
+		ctx := &common.Context{
+			Stager:   libbuildpack.NewStager([]string{tmpDir, "", depsDir, "0"}, logger, manifest),
+			Manifest: manifest,
+			Log:      logger,
+		}
+		framework = frameworks.NewSpringApplicationJSONFramework(ctx)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+		os.Unsetenv("JBP_CONFIG_SPRING_APPLICATION_JSON")
+		os.Unsetenv("SPRING_APPLICATION_JSON")
+		os.Unsetenv("VCAP_SERVICES")
+	})
+
+	// runScript sources the profile.d script with the given environment and prints SPRING_APPLICATION_JSON
+	runScript := func(env ...string) string {
+		cmd := exec.Command("bash", "-c", `source "$0"; echo "$SPRING_APPLICATION_JSON"`,
+			filepath.Join(depsDir, "0", "profile.d", "spring_application_json.sh"))
+		cmd.Env = append([]string{"PATH=" + os.Getenv("PATH")}, env...)
+		output, err := cmd.CombinedOutput()
+		Expect(err).NotTo(HaveOccurred(), string(output))
+		return string(output)
+	}
+
+	Describe("Detect", func() {
+		BeforeEach(func() {
+			os.Setenv("VCAP_SERVICES", vcapServices)
+		})
+
+		It("does not detect without rules", func() {
+			name, err := framework.Detect()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(BeEmpty())
+		})
+
+		It("detects rules that match a bound service and ignores incomplete ones", func() {
+			os.Setenv("JBP_CONFIG_SPRING_APPLICATION_JSON", `{rules: [{service: mysql, from: credentials.uri}]}`)
+			name, err := framework.Detect()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(BeEmpty())
+
+			os.Setenv("JBP_CONFIG_SPRING_APPLICATION_JSON", `{rules: [{service: mysql, from: credentials.uri, env: DB_URL}]}`)
+			name, err = framework.Detect()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(BeEmpty())
+
+			os.Setenv("JBP_CONFIG_SPRING_APPLICATION_JSON", `{rules: [{service: mysql, from: credentials.uri, property: spring.datasource.url}]}`)
+			name, err = framework.Detect()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("Spring Application JSON"))
+		})
+	})
+
+	Describe("Finalize", func() {
+		BeforeEach(func() {
+			os.Setenv("VCAP_SERVICES", vcapServices)
+			os.Setenv("JBP_CONFIG_SPRING_APPLICATION_JSON", `{rules: [
+				{service: mysql, from: credentials.uri, property: spring.datasource.url},
+				{service: orders, from: credentials.port, property: db.port},
+				{service: p.mysql, from: credentials.password, property: spring.datasource.password},
+				{service: redis, from: credentials.host, property: spring.redis.host}]}`)
+		})
+
+		It("renders the bound values when the application starts", func() {
+			Expect(framework.Finalize()).To(Succeed())
+			Expect(runScript("VCAP_SERVICES=" + vcapServices)).To(Equal(
+				`{"db.port":"3306","spring.datasource.password":"it's secret","spring.datasource.url":"mysql://db.example.com:3306/orders"}` + "\n"))
+		})
+
+		It("does not write the credentials to the droplet", func() {
+			Expect(framework.Finalize()).To(Succeed())
+			script, err := os.ReadFile(filepath.Join(depsDir, "0", "profile.d", "spring_application_json.sh"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(script)).NotTo(ContainSubstring("secret"))
+			Expect(string(script)).NotTo(ContainSubstring("db.example.com"))
+		})
+
+		It("renders the services bound when the application starts", func() {
+			Expect(framework.Finalize()).To(Succeed())
+			Expect(runScript(`VCAP_SERVICES={"p.mysql":[{"name":"orders-db","label":"p.mysql","tags":["mysql"],"credentials":{"uri":"mysql://new.example.com/orders"}}],` +
+				`"p.redis":[{"name":"cache","label":"p.redis","tags":["redis"],"credentials":{"host":"redis.example.com"}}]}`)).To(Equal(
+				`{"spring.datasource.url":"mysql://new.example.com/orders","spring.redis.host":"redis.example.com"}` + "\n"))
+		})
+
+		It("keeps the properties of the application's SPRING_APPLICATION_JSON at runtime", func() {
+			Expect(framework.Finalize()).To(Succeed())
+			Expect(runScript("VCAP_SERVICES="+vcapServices, `SPRING_APPLICATION_JSON={"db.port":5432,"other":true}`)).To(Equal(
+				`{"db.port":5432,"other":true,"spring.datasource.password":"it's secret","spring.datasource.url":"mysql://db.example.com:3306/orders"}` + "\n"))
+		})
+
+		It("leaves a SPRING_APPLICATION_JSON that is not a JSON object as it is", func() {
+			Expect(framework.Finalize()).To(Succeed())
+			Expect(runScript("VCAP_SERVICES="+vcapServices, "SPRING_APPLICATION_JSON=spring.datasource.url=jdbc:h2:mem:test")).To(And(
+				ContainSubstring("SPRING_APPLICATION_JSON is not a JSON object"),
+				HaveSuffix("spring.datasource.url=jdbc:h2:mem:test\n")))
+		})
+
+		It("writes no script without bound services", func() {
+			os.Unsetenv("VCAP_SERVICES")
+			Expect(framework.Finalize()).To(Succeed())
+			Expect(filepath.Join(depsDir, "0", "profile.d", "spring_application_json.sh")).NotTo(BeAnExistingFile())
+			Expect(buffer.String()).NotTo(ContainSubstring("Configuring Spring Application JSON"))
+		})
+	})
+})
@@ -3,9 +3,9 @@ package frameworks
 import (
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/cloudfoundry/libbuildpack"
 )
@@ -26,13 +26,18 @@ func NewSpringAutoReconfigurationFramework(ctx *common.Context) *SpringAutoRecon
 // Detect checks if Spring Auto-reconfiguration should be included
 func (s *SpringAutoReconfigurationFramework) Detect() (string, error) {
 	// Check if enabled in configuration
-	enabled := s.isEnabled()
+	enabled, err := s.isEnabled()
+	if err != nil {
+		return "", err
+	}
 	if !enabled {
+		s.context.Log.Debug("Spring Auto-reconfiguration: disabled by configuration")
 		return "", nil
 	}
 
 	// Check if Spring is present
 	if !s.hasSpring() {
+		s.context.Log.Debug("Spring Auto-reconfiguration: no spring-core JAR in the application")
 		return "", nil
 	}
 
@@ -89,6 +94,11 @@ func (s *SpringAutoReconfigurationFramework) Supply() error {
 	return nil
 }
 
+// Deprecation marks Spring Auto-reconfiguration, which java-cfenv replaces, as deprecated
+func (s *SpringAutoReconfigurationFramework) Deprecation() Deprecation {
+	return Deprecation{Deprecated: true, Sunset: "2026-12-31", Replacement: "java-cfenv"}
+}
+
 // Finalize performs final Spring Auto-reconfiguration configuration
 func (s *SpringAutoReconfigurationFramework) Finalize() error {
 	// Add the JAR to additional libraries (classpath)
@@ -101,8 +111,7 @@ func (s *SpringAutoReconfigurationFramework) Finalize() error {
 		return nil
 	}
 
-	depsIdx := s.context.Stager.DepsIdx()
-	runtimePath := fmt.Sprintf("$DEPS_DIR/%s/spring_auto_reconfiguration/%s", depsIdx, filepath.Base(matches[0]))
+	runtimePath := s.context.Droplet().Dep("spring_auto_reconfiguration", filepath.Base(matches[0]))
 
 	profileScript := fmt.Sprintf("export CLASSPATH=\"%s${CLASSPATH:+:$CLASSPATH}\"\n", runtimePath)
 	if err := s.context.Stager.WriteProfileD("spring_auto_reconfiguration.sh", profileScript); err != nil {
@@ -115,63 +124,31 @@ func (s *SpringAutoReconfigurationFramework) Finalize() error {
 }
 
 // isEnabled checks if Spring Auto-reconfiguration is enabled in configuration
-func (s *SpringAutoReconfigurationFramework) isEnabled() bool {
-	// Check JBP_CONFIG_SPRING_AUTO_RECONFIGURATION environment variable
-	config := os.Getenv("JBP_CONFIG_SPRING_AUTO_RECONFIGURATION")
-	if config != "" {
-		// Parse the configuration string
-		// Expected format: '{enabled: true}' or '{enabled: false}'
-		// Simple check: if it contains "false", it's disabled
-		if strings.Contains(config, "false") {
-			return false
-		}
-		// If it contains "true" or any other value, consider it enabled
-		if strings.Contains(config, "true") {
-			return true
+// Expected format: JBP_CONFIG_SPRING_AUTO_RECONFIGURATION='{enabled: true}'
+// Unknown configuration keys are only returned as an error in strict mode
+func (s *SpringAutoReconfigurationFramework) isEnabled() (bool, error) {
+	// Default to disabled (changed Dec 2025 - deprecated since July 2019)
+	sarConfig := enabledConfig{Enabled: false}
+
+	if err := config.Load(s.context.Log, "spring_auto_reconfiguration", &sarConfig); err != nil {
+		if common.IsUnknownConfigKeysError(err) {
+			return false, err
 		}
-		// If config is set but doesn't contain true/false, default to disabled for safety
-		return false
+		s.context.Log.Warning("Failed to parse JBP_CONFIG_SPRING_AUTO_RECONFIGURATION, treating as disabled: %s", err)
+		return false, nil
 	}
-
-	// Default to disabled (changed Dec 2025 - deprecated since July 2019)
-	return false
+	return sarConfig.Enabled, nil
 }
 
 // hasSpring checks if Spring Core is present in the application
 func (s *SpringAutoReconfigurationFramework) hasSpring() bool {
-	// Check common locations for spring-core*.jar
-	// Note: Go's filepath.Glob does not support ** recursive patterns
-	commonPaths := []string{
-		filepath.Join(s.context.Stager.BuildDir(), "WEB-INF", "lib", "spring-core*.jar"),
-		filepath.Join(s.context.Stager.BuildDir(), "WEB-INF", "lib", "org.springframework.spring-core*.jar"),
-		filepath.Join(s.context.Stager.BuildDir(), "lib", "spring-core*.jar"),
-		filepath.Join(s.context.Stager.BuildDir(), "BOOT-INF", "lib", "spring-core*.jar"),
-	}
-
-	for _, path := range commonPaths {
-		matches, _ := filepath.Glob(path)
-		if len(matches) > 0 {
-			return true
-		}
-	}
-
-	return false
+	return s.context.Classpath().Contains("spring-core*.jar", "org.springframework.spring-core*.jar")
 }
 
 // hasJavaCfEnv checks if java-cfenv is present in the application
 func (s *SpringAutoReconfigurationFramework) hasJavaCfEnv() bool {
-	// Check common locations for java-cfenv*.jar
-	commonPaths := []string{
-		filepath.Join(s.context.Stager.BuildDir(), "WEB-INF", "lib", "java-cfenv*.jar"),
-		filepath.Join(s.context.Stager.BuildDir(), "lib", "java-cfenv*.jar"),
-		filepath.Join(s.context.Stager.BuildDir(), "BOOT-INF", "lib", "java-cfenv*.jar"),
-	}
-
-	for _, path := range commonPaths {
-		matches, _ := filepath.Glob(path)
-		if len(matches) > 0 {
-			return true
-		}
+	if s.context.Classpath().Contains("java-cfenv*.jar") {
+		return true
 	}
 
 	// Also check if java_cf_env framework is being installed
@@ -185,25 +162,7 @@ func (s *SpringAutoReconfigurationFramework) hasJavaCfEnv() bool {
 
 // hasSpringCloudConnectors checks if Spring Cloud Connectors are present
 func (s *SpringAutoReconfigurationFramework) hasSpringCloudConnectors() bool {
-	// Check common locations for Spring Cloud Connectors JARs
-	// Note: Go's filepath.Glob does not support ** recursive patterns
-	commonPaths := []string{
-		filepath.Join(s.context.Stager.BuildDir(), "WEB-INF", "lib", "spring-cloud-cloudfoundry-connector*.jar"),
-		filepath.Join(s.context.Stager.BuildDir(), "WEB-INF", "lib", "spring-cloud-spring-service-connector*.jar"),
-		filepath.Join(s.context.Stager.BuildDir(), "lib", "spring-cloud-cloudfoundry-connector*.jar"),
-		filepath.Join(s.context.Stager.BuildDir(), "lib", "spring-cloud-spring-service-connector*.jar"),
-		filepath.Join(s.context.Stager.BuildDir(), "BOOT-INF", "lib", "spring-cloud-cloudfoundry-connector*.jar"),
-		filepath.Join(s.context.Stager.BuildDir(), "BOOT-INF", "lib", "spring-cloud-spring-service-connector*.jar"),
-	}
-
-	for _, path := range commonPaths {
-		matches, _ := filepath.Glob(path)
-		if len(matches) > 0 {
-			return true
-		}
-	}
-
-	return false
+	return s.context.Classpath().Contains("spring-cloud-cloudfoundry-connector*.jar", "spring-cloud-spring-service-connector*.jar")
 }
 
 func (s *SpringAutoReconfigurationFramework) DependencyIdentifier() string {
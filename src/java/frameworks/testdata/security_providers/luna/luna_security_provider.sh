@@ -0,0 +1,9 @@
+export ChrystokiConfigurationPath=$DEPS_DIR/0/luna_security_provider
+if [ "$JBP_WRITABLE_LUNA_SECURITY_PROVIDER_HTL" != "$DEPS_DIR/0/luna_security_provider/htl" ] && [ -f "$ChrystokiConfigurationPath/Chrystoki.conf" ]; then
+  luna_config="${TMPDIR:-/tmp}/java-buildpack/luna_security_provider"
+  mkdir -p "$luna_config"
+  sed "s|^\\([[:space:]]*HtlDir[[:space:]]*=\\).*;|\\1 $JBP_WRITABLE_LUNA_SECURITY_PROVIDER_HTL;|" "$ChrystokiConfigurationPath/Chrystoki.conf" > "$luna_config/Chrystoki.conf"
+  export ChrystokiConfigurationPath="$luna_config"
+  unset luna_config
+fi
+export LD_LIBRARY_PATH=$DEPS_DIR/0/luna_security_provider/jsp/64${LD_LIBRARY_PATH:+:$LD_LIBRARY_PATH}
@@ -0,0 +1,3 @@
+#!/bin/bash
+# Configure Synopsys Seeker Security Provider
+export SEEKER_SERVER_URL="$SEEKER_SERVER"
@@ -0,0 +1,241 @@
+package frameworks_test
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/frameworks"
+	"github.com/cloudfoundry/libbuildpack"
+)
+
+// corpusFrameworks are the frameworks whose Detect decision depends on VCAP_SERVICES, keyed by the directory of
+// their payloads in testdata/vcap_services
+var corpusFrameworks = map[string]func(*common.Context) frameworks.Framework{
+	"app_dynamics": func(ctx *common.Context) frameworks.Framework { return frameworks.NewAppDynamicsFramework(ctx) },
+	"azure_application_insights": func(ctx *common.Context) frameworks.Framework {
+		return frameworks.NewAzureApplicationInsightsAgentFramework(ctx)
+	},
+	"checkmarx_iast": func(ctx *common.Context) frameworks.Framework { return frameworks.NewCheckmarxIASTAgentFramework(ctx) },
+	"contrast_security": func(ctx *common.Context) frameworks.Framework {
+		return frameworks.NewContrastSecurityAgentFramework(ctx)
+	},
+	"datadog":     func(ctx *common.Context) frameworks.Framework { return frameworks.NewDatadogJavaagentFramework(ctx) },
+	"elastic_apm": func(ctx *common.Context) frameworks.Framework { return frameworks.NewElasticApmAgentFramework(ctx) },
+	"google_stackdriver_profiler": func(ctx *common.Context) frameworks.Framework {
+		return frameworks.NewGoogleStackdriverProfilerFramework(ctx)
+	},
+	"introscope": func(ctx *common.Context) frameworks.Framework { return frameworks.NewIntroscopeAgentFramework(ctx) },
+	"jacoco":     func(ctx *common.Context) frameworks.Framework { return frameworks.NewJacocoAgentFramework(ctx) },
+	"luna": func(ctx *common.Context) frameworks.Framework {
+		return frameworks.NewLunaSecurityProviderFramework(ctx)
+	},
+	"maria_db": func(ctx *common.Context) frameworks.Framework { return frameworks.NewMariaDBJDBCFramework(ctx) },
+	"metrics_forwarder": func(ctx *common.Context) frameworks.Framework {
+		return frameworks.NewMetricsForwarderFramework(ctx)
+	},
+	"new_relic": func(ctx *common.Context) frameworks.Framework { return frameworks.NewNewRelicFramework(ctx) },
+	"open_telemetry": func(ctx *common.Context) frameworks.Framework {
+		return frameworks.NewOpenTelemetryJavaagentFramework(ctx)
+	},
+	"oracle_jdbc": func(ctx *common.Context) frameworks.Framework { return frameworks.NewOracleJdbcFramework(ctx) },
+	"postgresql":  func(ctx *common.Context) frameworks.Framework { return frameworks.NewPostgresqlJdbcFramework(ctx) },
+	"protect_app": func(ctx *common.Context) frameworks.Framework {
+		return frameworks.NewProtectAppSecurityProviderFramework(ctx)
+	},
+	"riverbed_appinternals": func(ctx *common.Context) frameworks.Framework {
+		return frameworks.NewRiverbedAppInternalsAgentFramework(ctx)
+	},
+	"sealights": func(ctx *common.Context) frameworks.Framework { return frameworks.NewSealightsAgentFramework(ctx) },
+	"seeker": func(ctx *common.Context) frameworks.Framework {
+		return frameworks.NewSeekerSecurityProviderFramework(ctx)
+	},
+	"sky_walking": func(ctx *common.Context) frameworks.Framework { return frameworks.NewSkyWalkingAgentFramework(ctx) },
+	"splunk_otel": func(ctx *common.Context) frameworks.Framework { return frameworks.NewSplunkOtelJavaAgentFramework(ctx) },
+}
+
+// corpusManifest lists the dependencies that frameworks require in the manifest before they report a binding
+const corpusManifest = `---
+language: java
+default_versions:
+- name: metrics-forwarder-agent
+  version: 1.x
+- name: protect-app-security-provider
+  version: 10.x
+dependencies:
+- name: metrics-forwarder-agent
+  version: 1.4.0
+  uri: https://example.com/metrics-forwarder-agent-1.4.0.jar
+  sha256: 0000000000000000000000000000000000000000000000000000000000000000
+  cf_stacks:
+  - cflinuxfs4
+- name: protect-app-security-provider
+  version: 10.0.0
+  uri: https://example.com/protect-app-security-provider-10.0.0.tar.gz
+  sha256: 0000000000000000000000000000000000000000000000000000000000000000
+  cf_stacks:
+  - cflinuxfs4
+`
+
+var _ = Describe("VCAP_SERVICES corpus", func() {
+	var (
+		ctx          *common.Context
+		buildDir     string
+		cacheDir     string
+		depsDir      string
+		buildpackDir string
+	)
+
+	BeforeEach(func() {
+		var err error
+		buildDir, err = os.MkdirTemp("", "corpus-build")
+		Expect(err).NotTo(HaveOccurred())
+		cacheDir, err = os.MkdirTemp("", "corpus-cache")
+		Expect(err).NotTo(HaveOccurred())
+		depsDir, err = os.MkdirTemp("", "corpus-deps")
+		Expect(err).NotTo(HaveOccurred())
+		buildpackDir, err = os.MkdirTemp("", "corpus-buildpack")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.MkdirAll(filepath.Join(depsDir, "0"), 0755)).To(Succeed())
+		// The Redis session store only applies to web applications
+		Expect(os.MkdirAll(filepath.Join(buildDir, "WEB-INF"), 0755)).To(Succeed())
+
+		Expect(os.WriteFile(filepath.Join(buildpackDir, "manifest.yml"), []byte(corpusManifest), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(buildpackDir, "VERSION"), []byte("1.0.0"), 0644)).To(Succeed())
+		os.Setenv("CF_STACK", "cflinuxfs4")
+
+		logger := libbuildpack.NewLogger(GinkgoWriter)
+		manifest, err := libbuildpack.NewManifest(buildpackDir, logger, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		ctx = &common.Context{
+			Stager:    libbuildpack.NewStager([]string{buildDir, cacheDir, depsDir, "0"}, logger, manifest),
+			Manifest:  manifest,
+			Installer: libbuildpack.NewInstaller(manifest),
+			Log:       logger,
+			Command:   &libbuildpack.Command{},
+		}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(buildDir)
+		os.RemoveAll(cacheDir)
+		os.RemoveAll(depsDir)
+		os.RemoveAll(buildpackDir)
+		os.Unsetenv("VCAP_SERVICES")
+		os.Unsetenv("CF_STACK")
+	})
+
+	// detected returns the corpus frameworks that detect the payload in testdata/vcap_services/<payload>.json
+	detected := func(payload string) []string {
+		content, err := os.ReadFile(filepath.Join("testdata", "vcap_services", payload+".json"))
+		Expect(err).NotTo(HaveOccurred())
+		os.Setenv("VCAP_SERVICES", string(content))
+
+		var names []string
+		for name, newFramework := range corpusFrameworks {
+			result, err := newFramework(ctx).Detect()
+			Expect(err).NotTo(HaveOccurred(), name)
+			if result != "" {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	It("has payloads for every framework", func() {
+		for name := range corpusFrameworks {
+			for _, variant := range []string{"marketplace", "user_provided", "credhub_ref"} {
+				Expect(filepath.Join("testdata", "vcap_services", name, variant+".json")).To(BeARegularFile())
+			}
+		}
+	})
+
+	// Frameworks that need credentials, e.g. a DSN or a collector host, do not detect a binding whose credentials are
+	// a CredHub reference that was not interpolated
+	DescribeTable("Detect",
+		func(payload string, expected ...string) {
+			if expected == nil {
+				expected = []string{}
+			}
+			Expect(detected(payload)).To(ConsistOf(expected))
+		},
+		Entry("app_dynamics marketplace", "app_dynamics/marketplace", "app_dynamics"),
+		Entry("app_dynamics user-provided", "app_dynamics/user_provided", "app_dynamics"),
+		Entry("app_dynamics CredHub reference", "app_dynamics/credhub_ref", "app_dynamics"),
+		Entry("azure_application_insights marketplace", "azure_application_insights/marketplace", "azure_application_insights"),
+		Entry("azure_application_insights user-provided", "azure_application_insights/user_provided", "azure_application_insights"),
+		Entry("azure_application_insights CredHub reference", "azure_application_insights/credhub_ref", "azure_application_insights"),
+		Entry("checkmarx_iast marketplace", "checkmarx_iast/marketplace", "checkmarx_iast"),
+		Entry("checkmarx_iast user-provided", "checkmarx_iast/user_provided", "checkmarx_iast"),
+		Entry("checkmarx_iast CredHub reference", "checkmarx_iast/credhub_ref", "checkmarx_iast"),
+		Entry("contrast_security marketplace", "contrast_security/marketplace", "contrast_security"),
+		Entry("contrast_security user-provided", "contrast_security/user_provided", "contrast_security"),
+		Entry("contrast_security CredHub reference", "contrast_security/credhub_ref", "contrast_security"),
+		Entry("datadog marketplace", "datadog/marketplace", "datadog"),
+		Entry("datadog user-provided", "datadog/user_provided", "datadog"),
+		Entry("datadog CredHub reference", "datadog/credhub_ref", "datadog"),
+		Entry("elastic_apm marketplace", "elastic_apm/marketplace", "elastic_apm"),
+		Entry("elastic_apm user-provided", "elastic_apm/user_provided", "elastic_apm"),
+		Entry("elastic_apm CredHub reference", "elastic_apm/credhub_ref"),
+		Entry("google_stackdriver_profiler marketplace", "google_stackdriver_profiler/marketplace", "google_stackdriver_profiler"),
+		Entry("google_stackdriver_profiler user-provided", "google_stackdriver_profiler/user_provided", "google_stackdriver_profiler"),
+		Entry("google_stackdriver_profiler CredHub reference", "google_stackdriver_profiler/credhub_ref", "google_stackdriver_profiler"),
+		Entry("introscope marketplace", "introscope/marketplace", "introscope"),
+		Entry("introscope user-provided", "introscope/user_provided", "introscope"),
+		Entry("introscope CredHub reference", "introscope/credhub_ref", "introscope"),
+		Entry("jacoco marketplace", "jacoco/marketplace", "jacoco"),
+		Entry("jacoco user-provided", "jacoco/user_provided", "jacoco"),
+		Entry("jacoco CredHub reference", "jacoco/credhub_ref"),
+		Entry("luna marketplace", "luna/marketplace", "luna"),
+		Entry("luna user-provided", "luna/user_provided", "luna"),
+		Entry("luna CredHub reference", "luna/credhub_ref", "luna"),
+		Entry("maria_db marketplace", "maria_db/marketplace", "maria_db"),
+		Entry("maria_db user-provided", "maria_db/user_provided", "maria_db"),
+		Entry("maria_db CredHub reference", "maria_db/credhub_ref"),
+		Entry("metrics_forwarder marketplace", "metrics_forwarder/marketplace", "metrics_forwarder"),
+		Entry("metrics_forwarder user-provided", "metrics_forwarder/user_provided", "metrics_forwarder"),
+		Entry("metrics_forwarder CredHub reference", "metrics_forwarder/credhub_ref"),
+		Entry("new_relic marketplace", "new_relic/marketplace", "new_relic"),
+		Entry("new_relic user-provided", "new_relic/user_provided", "new_relic"),
+		Entry("new_relic CredHub reference", "new_relic/credhub_ref", "new_relic"),
+		Entry("open_telemetry marketplace", "open_telemetry/marketplace", "open_telemetry"),
+		Entry("open_telemetry user-provided", "open_telemetry/user_provided", "open_telemetry"),
+		Entry("open_telemetry CredHub reference", "open_telemetry/credhub_ref", "open_telemetry"),
+		Entry("oracle_jdbc marketplace", "oracle_jdbc/marketplace", "oracle_jdbc"),
+		Entry("oracle_jdbc user-provided", "oracle_jdbc/user_provided", "oracle_jdbc"),
+		Entry("oracle_jdbc CredHub reference", "oracle_jdbc/credhub_ref"),
+		Entry("postgresql marketplace", "postgresql/marketplace", "postgresql"),
+		Entry("postgresql user-provided", "postgresql/user_provided", "postgresql"),
+		Entry("postgresql CredHub reference", "postgresql/credhub_ref"),
+		Entry("protect_app marketplace", "protect_app/marketplace", "protect_app"),
+		Entry("protect_app user-provided", "protect_app/user_provided", "protect_app"),
+		Entry("protect_app CredHub reference", "protect_app/credhub_ref"),
+		Entry("riverbed_appinternals marketplace", "riverbed_appinternals/marketplace", "riverbed_appinternals"),
+		Entry("riverbed_appinternals user-provided", "riverbed_appinternals/user_provided", "riverbed_appinternals"),
+		Entry("riverbed_appinternals CredHub reference", "riverbed_appinternals/credhub_ref", "riverbed_appinternals"),
+		Entry("sealights marketplace", "sealights/marketplace", "sealights"),
+		Entry("sealights user-provided", "sealights/user_provided", "sealights"),
+		Entry("sealights CredHub reference", "sealights/credhub_ref", "sealights"),
+		Entry("seeker marketplace", "seeker/marketplace", "seeker"),
+		Entry("seeker user-provided", "seeker/user_provided", "seeker"),
+		Entry("seeker CredHub reference", "seeker/credhub_ref"),
+		Entry("sky_walking marketplace", "sky_walking/marketplace", "sky_walking"),
+		Entry("sky_walking user-provided", "sky_walking/user_provided", "sky_walking"),
+		Entry("sky_walking CredHub reference", "sky_walking/credhub_ref", "sky_walking"),
+		Entry("splunk_otel marketplace", "splunk_otel/marketplace", "splunk_otel"),
+		Entry("splunk_otel user-provided", "splunk_otel/user_provided", "splunk_otel"),
+		Entry("splunk_otel CredHub reference", "splunk_otel/credhub_ref", "splunk_otel"),
+
+		Entry("a Spring Boot application with a database, a cache, a message broker and an APM agent",
+			"mixed/spring_boot_app", "new_relic", "postgresql"),
+		Entry("services that no framework uses", "mixed/unrelated"),
+		// A user-provided splunk-otel-collector matches both the Splunk and the OpenTelemetry name patterns
+		Entry("several observability services", "mixed/observability", "elastic_apm", "open_telemetry", "splunk_otel"),
+	)
+})
@@ -0,0 +1,162 @@
+package frameworks
+
+import (
+	"strings"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+)
+
+// versionedOption is a JVM option that only some major Java versions accept
+type versionedOption struct {
+	// name identifies the option regardless of its value: -XX:Name for -XX:+Name, -XX:-Name and -XX:Name=value,
+	// -Dname for system properties and the option itself up to any = otherwise, e.g. --add-opens
+	name string
+	// since is the first major version that recognizes the option; older ones would not start with it
+	since int
+	// experimentalUntil is the first major version that accepts the option without -XX:+UnlockExperimentalVMOptions,
+	// 0 if it never needed it
+	experimentalUntil int
+	// removedIn is the first major version that ignores or rejects the option, 0 if none does
+	removedIn int
+	// kept are the forms of a removed option the JVM still accepts, e.g. -Djava.security.manager=disallow
+	kept []string
+	// separateValue is true if the option may take its value as the next option, e.g. --add-opens m/p=ALL-UNNAMED
+	separateValue bool
+	// advice names the alternative in the warning that removes the option
+	advice string
+}
+
+// versionedOptions are the options frameworks or users commonly set that the JVM rejects or ignores on some of the
+// Java versions the buildpack installs. Class loader options are checked by CheckClassLoaderOptions.
+var versionedOptions = []versionedOption{
+	{name: "--add-opens", since: 9, separateValue: true, advice: "Java 8 does not encapsulate the JDK"},
+	{name: "--add-exports", since: 9, separateValue: true, advice: "Java 8 does not encapsulate the JDK"},
+	{name: "--add-reads", since: 9, separateValue: true, advice: "Java 8 does not encapsulate the JDK"},
+	{name: "--add-modules", since: 9, separateValue: true, advice: "Java 8 has no modules"},
+	{name: "--enable-native-access", since: 17, separateValue: true, advice: "earlier versions do not restrict native access"},
+	{name: "--sun-misc-unsafe-memory-access", since: 23, advice: "earlier versions do not restrict sun.misc.Unsafe"},
+	{name: "-XX:UseCompactObjectHeaders", since: 24, experimentalUntil: 25, advice: "compact object headers need Java 24 or later"},
+
+	{name: "-XX:MaxPermSize", removedIn: 8, advice: "Java 8 replaced the permanent generation with metaspace, which the memory calculator sizes"},
+	{name: "-XX:PermSize", removedIn: 8, advice: "Java 8 replaced the permanent generation with metaspace, which the memory calculator sizes"},
+	{name: "-Xincgc", removedIn: 9, advice: "incremental CMS was removed"},
+	{name: "-XX:CMSIncrementalMode", removedIn: 9, advice: "incremental CMS was removed"},
+	{name: "-XX:PrintGCDateStamps", removedIn: 9, advice: "use -Xlog:gc*::time"},
+	{name: "-XX:PrintGCTimeStamps", removedIn: 9, advice: "use -Xlog:gc*::uptime"},
+	{name: "-XX:PrintGCApplicationStoppedTime", removedIn: 9, advice: "use -Xlog:safepoint"},
+	{name: "-XX:PrintHeapAtGC", removedIn: 9, advice: "use -Xlog:gc+heap=debug"},
+	{name: "-XX:PrintTenuringDistribution", removedIn: 9, advice: "use -Xlog:gc+age=trace"},
+	{name: "-XX:UseGCLogFileRotation", removedIn: 9, advice: "use -Xlog:gc:<file>::filecount=<n>,filesize=<size>"},
+	{name: "-XX:NumberOfGCLogFiles", removedIn: 9, advice: "use -Xlog:gc:<file>::filecount=<n>"},
+	{name: "-XX:GCLogFileSize", removedIn: 9, advice: "use -Xlog:gc:<file>::filesize=<size>"},
+	{name: "-XX:UseParNewGC", removedIn: 10, advice: "the ParNew collector was removed"},
+	{name: "-XX:UseCGroupMemoryLimitForHeap", removedIn: 11, advice: "the JVM honors the memory limit of the container by default"},
+	{name: "-XX:AggressiveOpts", removedIn: 12, advice: "its optimizations are enabled by default or were removed"},
+	{name: "-XX:UseConcMarkSweepGC", removedIn: 14, advice: "the CMS collector was removed, the JVM uses G1 instead"},
+	{name: "--illegal-access", removedIn: 17, advice: "use --add-opens for the packages the application accesses"},
+	{name: "-XX:UseBiasedLocking", removedIn: 18, advice: "biased locking was removed"},
+	{name: "-XX:ZGenerational", removedIn: 24, advice: "ZGC is always generational"},
+	{name: "-Djava.security.manager", removedIn: 24, kept: []string{"-Djava.security.manager=disallow"},
+		advice: "the Security Manager was removed and the JVM refuses to enable it"},
+}
+
+// CheckVersionedOptions checks the options that frameworks and the user wrote to the .opts files against the major
+// version of the installed JRE. Options the version does not know yet, or no longer knows, are removed with a warning
+// that names the alternative, as the JVM would refuse to start with them or ignore them; options that are experimental
+// on the version are unlocked with -XX:+UnlockExperimentalVMOptions.
+//
+// Like CheckClassLoaderOptions, this must run after all frameworks are finalized and before the JAVA_OPTS assembly
+// script is written.
+func CheckVersionedOptions(ctx *common.Context) error {
+	javaVersion, err := common.GetJavaMajorVersion()
+	if err != nil {
+		ctx.Log.Debug("Unable to detect Java version, versioned options are not checked: %s", err.Error())
+		return nil
+	}
+
+	return rewriteJavaOptsFiles(ctx, func(source, opts string) (string, bool) {
+		return checkVersionedOptions(ctx, javaVersion, source, opts)
+	})
+}
+
+// checkVersionedOptions returns opts adapted to javaVersion and whether it changed them
+func checkVersionedOptions(ctx *common.Context, javaVersion int, source, opts string) (string, bool) {
+	tokens := strings.Fields(opts)
+	checked := make([]string, 0, len(tokens))
+	changed := false
+
+	for i := 0; i < len(tokens); i++ {
+		option, ok := findVersionedOption(tokens[i])
+		if !ok {
+			checked = append(checked, tokens[i])
+			continue
+		}
+
+		opt := tokens[i]
+		if option.separateValue && opt == option.name && i+1 < len(tokens) {
+			i++
+			opt += " " + tokens[i]
+		}
+
+		switch {
+		case javaVersion < option.since:
+			ctx.Log.Warning("Removing %s from %s: Java %d does not recognize it and would not start; %s",
+				opt, source, javaVersion, option.advice)
+			changed = true
+		case option.removedIn > 0 && javaVersion >= option.removedIn && !keptOption(option, opt):
+			ctx.Log.Warning("Removing %s from %s: Java %d no longer supports it; %s",
+				opt, source, javaVersion, option.advice)
+			changed = true
+		case javaVersion < option.experimentalUntil && !containsToken(checked, "-XX:+UnlockExperimentalVMOptions"):
+			ctx.Log.Info("Unlocking %s in %s: it is experimental on Java %d", opt, source, javaVersion)
+			checked = append(checked, "-XX:+UnlockExperimentalVMOptions", opt)
+			changed = true
+		default:
+			checked = append(checked, opt)
+		}
+	}
+
+	if !changed {
+		return opts, false
+	}
+	return strings.Join(checked, " "), true
+}
+
+// findVersionedOption returns the versioned option opt sets, if any
+func findVersionedOption(opt string) (versionedOption, bool) {
+	var name string
+	switch {
+	case strings.HasPrefix(opt, "-XX:"):
+		name, _, _ = strings.Cut(strings.TrimLeft(strings.TrimPrefix(opt, "-XX:"), "+-"), "=")
+		name = "-XX:" + name
+	default:
+		name, _, _ = strings.Cut(opt, "=")
+	}
+
+	for _, option := range versionedOptions {
+		if option.name == name {
+			return option, true
+		}
+	}
+	return versionedOption{}, false
+}
+
+// keptOption returns true if opt is a form of the removed option that the JVM still accepts
+func keptOption(option versionedOption, opt string) bool {
+	for _, kept := range option.kept {
+		if opt == kept {
+			return true
+		}
+	}
+	return false
+}
+
+// containsToken returns true if tokens contains token
+func containsToken(tokens []string, token string) bool {
+	for _, t := range tokens {
+		if t == token {
+			return true
+		}
+	}
+	return false
+}
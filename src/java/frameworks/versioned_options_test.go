@@ -0,0 +1,195 @@
+package frameworks_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/frameworks"
+	"github.com/cloudfoundry/libbuildpack"
+)
+
+var _ = Describe("CheckVersionedOptions", func() {
+	var (
+		ctx      *common.Context
+		logs     *bytes.Buffer
+		buildDir string
+		cacheDir string
+		depsDir  string
+		javaHome string
+	)
+
+	setJavaVersion := func(version string) {
+		Expect(os.WriteFile(filepath.Join(javaHome, "release"), []byte(`JAVA_VERSION="`+version+`"`), 0644)).To(Succeed())
+	}
+
+	writeOpts := func(name, opts string) {
+		Expect(os.MkdirAll(filepath.Join(depsDir, "0", "java_opts"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(depsDir, "0", "java_opts", name+".opts"), []byte(opts), 0644)).To(Succeed())
+	}
+
+	readOpts := func(name string) string {
+		content, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", name+".opts"))
+		Expect(err).NotTo(HaveOccurred())
+		return string(content)
+	}
+
+	BeforeEach(func() {
+		var err error
+		buildDir, err = os.MkdirTemp("", "versioned-options-build")
+		Expect(err).NotTo(HaveOccurred())
+		cacheDir, err = os.MkdirTemp("", "versioned-options-cache")
+		Expect(err).NotTo(HaveOccurred())
+		depsDir, err = os.MkdirTemp("", "versioned-options-deps")
+		Expect(err).NotTo(HaveOccurred())
+		javaHome, err = os.MkdirTemp("", "versioned-options-java")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.MkdirAll(filepath.Join(depsDir, "0"), 0755)).To(Succeed())
+		os.Setenv("JAVA_HOME", javaHome)
+
+		logs = new(bytes.Buffer)
+		ctx = newMariaDBContext(buildDir, cacheDir, depsDir)
+		ctx.Log = libbuildpack.NewLogger(logs)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(buildDir)
+		os.RemoveAll(cacheDir)
+		os.RemoveAll(depsDir)
+		os.RemoveAll(javaHome)
+		os.Unsetenv("JAVA_HOME")
+	})
+
+	Context("on Java 25", func() {
+		BeforeEach(func() {
+			setJavaVersion("25.0.1")
+		})
+
+		DescribeTable("removes the options Java 25 no longer supports",
+			func(opt, advice string) {
+				writeOpts("99_user_java_opts", "-Xmx512M "+opt+" -Dfoo=bar")
+
+				Expect(frameworks.CheckVersionedOptions(ctx)).To(Succeed())
+
+				Expect(readOpts("99_user_java_opts")).To(Equal("-Xmx512M -Dfoo=bar"))
+				Expect(logs.String()).To(ContainSubstring("Removing " + opt + " from 99_user_java_opts: Java 25 no longer supports it"))
+				Expect(logs.String()).To(ContainSubstring(advice))
+			},
+			Entry("enabling the Security Manager", "-Djava.security.manager=allow", "the Security Manager was removed"),
+			Entry("the default Security Manager", "-Djava.security.manager", "the Security Manager was removed"),
+			Entry("non-generational ZGC", "-XX:-ZGenerational", "ZGC is always generational"),
+			Entry("biased locking", "-XX:+UseBiasedLocking", "biased locking was removed"),
+			Entry("illegal access", "--illegal-access=permit", "use --add-opens"),
+			Entry("CMS", "-XX:+UseConcMarkSweepGC", "the CMS collector was removed"),
+			Entry("cgroup memory limit", "-XX:+UseCGroupMemoryLimitForHeap", "honors the memory limit of the container"),
+			Entry("GC log rotation", "-XX:GCLogFileSize=10M", "filesize=<size>"),
+			Entry("the permanent generation", "-XX:MaxPermSize=256M", "metaspace"),
+		)
+
+		It("keeps disallowing the Security Manager", func() {
+			writeOpts("99_user_java_opts", "-Djava.security.manager=disallow")
+
+			Expect(frameworks.CheckVersionedOptions(ctx)).To(Succeed())
+
+			Expect(readOpts("99_user_java_opts")).To(Equal("-Djava.security.manager=disallow"))
+			Expect(logs.String()).NotTo(ContainSubstring("WARNING"))
+		})
+
+		It("keeps compact object headers without unlocking them", func() {
+			writeOpts("99_user_java_opts", "-XX:+UseCompactObjectHeaders --enable-native-access=ALL-UNNAMED")
+
+			Expect(frameworks.CheckVersionedOptions(ctx)).To(Succeed())
+
+			Expect(readOpts("99_user_java_opts")).To(Equal("-XX:+UseCompactObjectHeaders --enable-native-access=ALL-UNNAMED"))
+		})
+	})
+
+	Context("on Java 24", func() {
+		BeforeEach(func() {
+			setJavaVersion("24.0.2")
+		})
+
+		It("unlocks compact object headers", func() {
+			writeOpts("99_user_java_opts", "-Xmx512M -XX:+UseCompactObjectHeaders")
+
+			Expect(frameworks.CheckVersionedOptions(ctx)).To(Succeed())
+
+			Expect(readOpts("99_user_java_opts")).To(Equal("-Xmx512M -XX:+UnlockExperimentalVMOptions -XX:+UseCompactObjectHeaders"))
+		})
+
+		It("does not unlock experimental options twice", func() {
+			opts := "-XX:+UnlockExperimentalVMOptions -XX:+UseCompactObjectHeaders"
+			writeOpts("99_user_java_opts", opts)
+
+			Expect(frameworks.CheckVersionedOptions(ctx)).To(Succeed())
+
+			Expect(readOpts("99_user_java_opts")).To(Equal(opts))
+		})
+	})
+
+	Context("on Java 17", func() {
+		BeforeEach(func() {
+			setJavaVersion("17.0.13")
+		})
+
+		It("removes options Java 17 does not recognize yet", func() {
+			writeOpts("99_user_java_opts", "-XX:+UseCompactObjectHeaders --sun-misc-unsafe-memory-access=allow -Dfoo=bar")
+
+			Expect(frameworks.CheckVersionedOptions(ctx)).To(Succeed())
+
+			Expect(readOpts("99_user_java_opts")).To(Equal("-Dfoo=bar"))
+			Expect(logs.String()).To(ContainSubstring("Removing -XX:+UseCompactObjectHeaders from 99_user_java_opts: " +
+				"Java 17 does not recognize it and would not start"))
+		})
+
+		It("keeps the options Java 17 supports", func() {
+			opts := "-Djava.security.manager=allow -XX:+UseZGC --add-opens java.base/java.lang=ALL-UNNAMED"
+			writeOpts("28_java_memory_assistant", opts)
+
+			Expect(frameworks.CheckVersionedOptions(ctx)).To(Succeed())
+
+			Expect(readOpts("28_java_memory_assistant")).To(Equal(opts))
+		})
+	})
+
+	Context("on Java 8", func() {
+		BeforeEach(func() {
+			setJavaVersion("1.8.0_422")
+		})
+
+		DescribeTable("removes module options with their values",
+			func(opts string) {
+				writeOpts("99_user_java_opts", opts+" -Dfoo=bar")
+
+				Expect(frameworks.CheckVersionedOptions(ctx)).To(Succeed())
+
+				Expect(readOpts("99_user_java_opts")).To(Equal("-Dfoo=bar"))
+				Expect(logs.String()).To(ContainSubstring("Java 8 does not recognize it and would not start"))
+			},
+			Entry("with the value as the next option", "--add-opens java.base/java.lang=ALL-UNNAMED"),
+			Entry("with the value after =", "--add-opens=java.base/java.lang=ALL-UNNAMED"),
+		)
+
+		It("keeps the GC logging options of Java 8", func() {
+			opts := "-XX:+PrintGCDateStamps -XX:+UseGCLogFileRotation -XX:+UseConcMarkSweepGC"
+			writeOpts("99_user_java_opts", opts)
+
+			Expect(frameworks.CheckVersionedOptions(ctx)).To(Succeed())
+
+			Expect(readOpts("99_user_java_opts")).To(Equal(opts))
+		})
+	})
+
+	It("does not check the options without JAVA_HOME", func() {
+		os.Unsetenv("JAVA_HOME")
+		writeOpts("99_user_java_opts", "-XX:+UseBiasedLocking")
+
+		Expect(frameworks.CheckVersionedOptions(ctx)).To(Succeed())
+
+		Expect(readOpts("99_user_java_opts")).To(Equal("-XX:+UseBiasedLocking"))
+	})
+})
@@ -18,6 +18,8 @@ package frameworks
 import (
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
 	"os"
 	"path/filepath"
 
@@ -42,14 +44,19 @@ func (f *YourKitProfilerFramework) DependencyIdentifier() string {
 func (f *YourKitProfilerFramework) Detect() (string, error) {
 	// YourKit is disabled by default
 	// Check for JBP_CONFIG_YOUR_KIT_PROFILER='{enabled: true}'
-	enabled := os.Getenv("JBP_CONFIG_YOUR_KIT_PROFILER")
-	if enabled != "" {
-		// Simple check - if env var contains "enabled" and "true"
-		if common.ContainsIgnoreCase(enabled, "enabled") && common.ContainsIgnoreCase(enabled, "true") {
-			return "YourKit Profiler", nil
+	ykConfig, err := f.loadConfig()
+	if err != nil {
+		if common.IsUnknownConfigKeysError(err) {
+			return "", err
 		}
+		f.context.Log.Warning("Failed to parse JBP_CONFIG_YOUR_KIT_PROFILER: %s", err)
+		return "", nil
+	}
+	if ykConfig.Enabled {
+		return "YourKit Profiler", nil
 	}
 
+	f.context.Log.Debug("YourKit Profiler: disabled (default), enable it with JBP_CONFIG_YOUR_KIT_PROFILER='{enabled: true}'")
 	return "", nil
 }
 
@@ -105,18 +112,17 @@ func (f *YourKitProfilerFramework) Finalize() error {
 	f.context.Log.Debug("Found YourKit agent at: %s", agentPath)
 
 	// Get buildpack index for multi-buildpack support
-	depsIdx := f.context.Stager.DepsIdx()
 
 	// Convert staging path to runtime path
 	relPath, err := filepath.Rel(f.context.Stager.DepDir(), agentPath)
 	if err != nil {
 		return fmt.Errorf("failed to compute relative path: %w", err)
 	}
-	runtimeAgentPath := filepath.Join(fmt.Sprintf("$DEPS_DIR/%s", depsIdx), relPath)
+	runtimeAgentPath := f.context.Droplet().Dep(relPath)
 
 	// Build agent options
 	// Default options: dir=<home>/yourkit, logdir=<home>/yourkit, port=10001, sessionname=<space>:<app>
-	runtimeHomeDir := fmt.Sprintf("$DEPS_DIR/%s/yourkit", depsIdx)
+	runtimeHomeDir := f.context.Droplet().Dep("yourkit")
 
 	// Create home directory at staging time
 	homeDir := filepath.Join(f.context.Stager.DepDir(), "yourkit")
@@ -124,27 +130,41 @@ func (f *YourKitProfilerFramework) Finalize() error {
 		return fmt.Errorf("failed to create yourkit directory: %w", err)
 	}
 
-	// Get session name from VCAP_APPLICATION (space:app)
-	sessionName := "cloudfoundry"
-
-	// Get port from config (default: 10001)
-	port := "10001"
-	portConfig := os.Getenv("JBP_CONFIG_YOUR_KIT_PROFILER")
-	if portConfig != "" && common.ContainsIgnoreCase(portConfig, "port") {
-		// Simple extraction (would need proper YAML parsing in production)
-		// For now, use default
+	ykConfig, err := f.loadConfig()
+	if err != nil {
+		return err
 	}
 
 	// Build agent path with options using runtime paths
-	agentOptions := fmt.Sprintf("dir=%s,logdir=%s,port=%s,sessionname=%s",
-		runtimeHomeDir, runtimeHomeDir, port, sessionName)
+	agentOptions := fmt.Sprintf("dir=%s,logdir=%s,port=%d,sessionname=%s",
+		runtimeHomeDir, runtimeHomeDir, ykConfig.Port, ykConfig.DefaultSessionName)
 	javaAgent := fmt.Sprintf("-agentpath:%s=%s", runtimeAgentPath, agentOptions)
 
 	// Write to .opts file using priority 45
-	if err := writeJavaOptsFile(f.context, 45, "your_kit_profiler", javaAgent); err != nil {
+	if err := writeJavaOptsFile(f.context, javaopts.YourKit, "your_kit_profiler", javaAgent); err != nil {
 		return fmt.Errorf("failed to write java_opts file: %w", err)
 	}
 
 	f.context.Log.Debug("YourKit Profiler configured (priority 45)")
 	return nil
 }
+
+type yourKitProfilerConfig struct {
+	Enabled            bool   `yaml:"enabled"`
+	Port               int    `yaml:"port"`
+	DefaultSessionName string `yaml:"default_session_name"`
+}
+
+func (f *YourKitProfilerFramework) loadConfig() (*yourKitProfilerConfig, error) {
+	// initialize default values
+	ykConfig := yourKitProfilerConfig{
+		Enabled:            false,
+		Port:               10001,
+		DefaultSessionName: "cloudfoundry",
+	}
+	// overlay buildpack defaults and JBP_CONFIG_YOUR_KIT_PROFILER over default values
+	if err := config.Load(f.context.Log, "your_kit_profiler", &ykConfig); err != nil {
+		return nil, err
+	}
+	return &ykConfig, nil
+}
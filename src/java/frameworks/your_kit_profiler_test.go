@@ -106,12 +106,12 @@ var _ = Describe("YourKitProfiler", func() {
 			})
 		})
 
-		Context("with JBP_CONFIG_YOUR_KIT_PROFILER containing 'ENABLED: TRUE' (uppercase)", func() {
+		Context("with JBP_CONFIG_YOUR_KIT_PROFILER containing 'enabled: TRUE' (uppercase value)", func() {
 			BeforeEach(func() {
-				os.Setenv("JBP_CONFIG_YOUR_KIT_PROFILER", "ENABLED: TRUE")
+				os.Setenv("JBP_CONFIG_YOUR_KIT_PROFILER", "enabled: TRUE")
 			})
 
-			It("returns 'YourKit Profiler' (case-insensitive match)", func() {
+			It("returns 'YourKit Profiler' (YAML boolean)", func() {
 				name, err := fw.Detect()
 				Expect(err).NotTo(HaveOccurred())
 				Expect(name).To(Equal("YourKit Profiler"))
@@ -188,6 +188,15 @@ var _ = Describe("YourKitProfiler", func() {
 				Expect(string(content)).To(ContainSubstring("sessionname="))
 			})
 
+			It("uses port and session name from JBP_CONFIG_YOUR_KIT_PROFILER", func() {
+				os.Setenv("JBP_CONFIG_YOUR_KIT_PROFILER", "{enabled: true, port: 10042, default_session_name: 'my-app'}")
+				Expect(fw.Finalize()).To(Succeed())
+				content, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "45_your_kit_profiler.opts"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(ContainSubstring("port=10042"))
+				Expect(string(content)).To(ContainSubstring("sessionname=my-app"))
+			})
+
 			It("creates the yourkit home directory", func() {
 				Expect(fw.Finalize()).To(Succeed())
 				Expect(filepath.Join(depsDir, "0", "yourkit")).To(BeADirectory())
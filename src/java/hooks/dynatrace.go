@@ -1,10 +1,98 @@
 package hooks
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/Dynatrace/libbuildpack-dynatrace"
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/appname"
 	"github.com/cloudfoundry/libbuildpack"
 )
 
 func init() {
-	libbuildpack.AddHook(dynatrace.NewHook("java", "process"))
+	libbuildpack.AddHook(offlineDynatraceHook{Hook: dynatrace.NewHook("java", "process")})
+	libbuildpack.AddHook(dynatraceNameHook{})
+}
+
+// offlineDynatraceHook stops an offline buildpack from downloading the Dynatrace OneAgent, which the Dynatrace hook
+// fetches from the Dynatrace API of a bound service at staging
+type offlineDynatraceHook struct {
+	libbuildpack.Hook
+}
+
+// AfterCompile installs the OneAgent unless the buildpack runs offline and a Dynatrace service is bound
+func (h offlineDynatraceHook) AfterCompile(stager *libbuildpack.Stager) error {
+	if !common.IsOffline() {
+		return h.Hook.AfterCompile(stager)
+	}
+
+	services, err := common.GetVCAPServices()
+	if err != nil {
+		return h.Hook.AfterCompile(stager)
+	}
+	service := services.GetServiceByNamePattern("dynatrace")
+	if service == nil {
+		return nil
+	}
+
+	url, _ := service.Credentials["customoneagenturl"].(string)
+	if url == "" {
+		url = dynatraceAPIURL(service)
+	}
+	return common.OfflineError("Dynatrace OneAgent for service "+service.Name, url,
+		"unbind the Dynatrace service, use the online buildpack, or set "+common.OfflineEnvVar+"=false if the Dynatrace API is reachable from staging")
+}
+
+// DynatraceEndpoint returns the URL of the Dynatrace cluster the OneAgent of a bound Dynatrace service reports to,
+// or "" if no Dynatrace service is bound
+func DynatraceEndpoint() string {
+	services, err := common.GetVCAPServices()
+	if err != nil {
+		return ""
+	}
+	service := services.GetServiceByNamePattern("dynatrace")
+	if service == nil {
+		return ""
+	}
+	return dynatraceAPIURL(service)
+}
+
+// dynatraceAPIURL returns the Dynatrace API URL of service: its apiurl credential or the SaaS URL of its environmentid
+func dynatraceAPIURL(service *common.VCAPService) string {
+	if url, _ := service.Credentials["apiurl"].(string); url != "" {
+		return url
+	}
+	environmentID, _ := service.Credentials["environmentid"].(string)
+	return fmt.Sprintf("https://%s.live.dynatrace.com/api", environmentID)
+}
+
+// dynatraceNameHook names the process group of the OneAgent after JBP_CONFIG_APPLICATION_NAME. Without a configured
+// template the OneAgent keeps detecting the process group itself.
+type dynatraceNameHook struct {
+	libbuildpack.DefaultHook
+}
+
+// AfterCompile exports DT_CLUSTER_ID with the configured application name, unless the application sets it itself
+func (h dynatraceNameHook) AfterCompile(stager *libbuildpack.Stager) error {
+	services, err := common.GetVCAPServices()
+	if err != nil || services.GetServiceByNamePattern("dynatrace") == nil {
+		return nil
+	}
+
+	template, err := appname.Template(stager.Logger(), "")
+	if err != nil || template == "" {
+		return err
+	}
+	name := appname.Resolve(template)
+	if name == "" {
+		return nil
+	}
+
+	quoted := "'" + strings.ReplaceAll(name, "'", `'"'"'`) + "'"
+	script := fmt.Sprintf("[ -n \"${DT_CLUSTER_ID:-}\" ] || export DT_CLUSTER_ID=%s\n", quoted)
+	if err := stager.WriteProfileD("dynatrace_name.sh", script); err != nil {
+		return fmt.Errorf("failed to write dynatrace_name.sh profile.d script: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,135 @@
+package hooks
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/Dynatrace/libbuildpack-dynatrace"
+	"github.com/cloudfoundry/java-buildpack/src/internal/fakevendors"
+	"github.com/cloudfoundry/libbuildpack"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Dynatrace", func() {
+	var (
+		api      *fakevendors.Dynatrace
+		hook     offlineDynatraceHook
+		oneAgent *dynatrace.Hook
+		stager   *libbuildpack.Stager
+		logs     *bytes.Buffer
+		buildDir string
+		depsDir  string
+	)
+
+	BeforeEach(func() {
+		var err error
+		buildDir, err = os.MkdirTemp("", "dynatrace-build")
+		Expect(err).NotTo(HaveOccurred())
+		depsDir, err = os.MkdirTemp("", "dynatrace-deps")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.MkdirAll(filepath.Join(depsDir, "0", "profile.d"), 0755)).To(Succeed())
+
+		api = fakevendors.NewDynatrace()
+		os.Setenv("VCAP_SERVICES", api.VCAPServices("dynatrace-service", nil))
+		os.Setenv("JBP_OFFLINE", "false")
+
+		logs = new(bytes.Buffer)
+		logger := libbuildpack.NewLogger(logs)
+		stager = libbuildpack.NewStager([]string{buildDir, "", depsDir, "0"}, logger, &libbuildpack.Manifest{})
+
+		oneAgent = dynatrace.NewHook("java", "process").(*dynatrace.Hook)
+		oneAgent.Log = logger
+		oneAgent.MaxDownloadRetries = 1
+		hook = offlineDynatraceHook{Hook: oneAgent}
+	})
+
+	AfterEach(func() {
+		api.Close()
+		os.RemoveAll(buildDir)
+		os.RemoveAll(depsDir)
+		os.Unsetenv("VCAP_SERVICES")
+		os.Unsetenv("JBP_OFFLINE")
+	})
+
+	agentConfig := func() string {
+		data, err := os.ReadFile(filepath.Join(buildDir, "dynatrace", "oneagent", "agent", "conf", "ruxitagentproc.conf"))
+		Expect(err).NotTo(HaveOccurred())
+		return string(data)
+	}
+
+	It("installs the OneAgent from the Dynatrace API of the bound service", func() {
+		Expect(hook.AfterCompile(stager)).To(Succeed())
+
+		requests := api.Requests()
+		Expect(requests).NotTo(BeEmpty())
+		Expect(requests[0].Path).To(Equal(api.Path(fakevendors.DynatraceInstallerPath)))
+		Expect(requests[0].Header.Get("Authorization")).To(Equal("Api-Token fake-api-token"))
+		Expect(requests[0].Query["include"]).To(ConsistOf("java", "process"))
+
+		env, err := os.ReadFile(filepath.Join(depsDir, "0", "profile.d", "dynatrace-env.sh"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(env)).To(ContainSubstring("export DT_TENANT=fake-environment"))
+		Expect(string(env)).To(ContainSubstring("export LD_PRELOAD=${HOME}/dynatrace/oneagent/agent/lib64/liboneagentproc.so"))
+
+		// The configuration of the environment takes precedence over that of the installer
+		Expect(agentConfig()).To(ContainSubstring("key1 api"))
+		Expect(logs.String()).To(ContainSubstring("Dynatrace OneAgent injection is set up."))
+	})
+
+	It("retries the download of the installer", func() {
+		api.FailNext(api.Path(fakevendors.DynatraceInstallerPath), 1, http.StatusServiceUnavailable)
+
+		Expect(hook.AfterCompile(stager)).To(Succeed())
+
+		Expect(api.RequestCount(api.Path(fakevendors.DynatraceInstallerPath))).To(Equal(2))
+		Expect(logs.String()).To(ContainSubstring("Error during installer download, retrying in 4s"))
+		Expect(filepath.Join(depsDir, "0", "profile.d", "dynatrace-env.sh")).To(BeAnExistingFile())
+	})
+
+	It("fails staging when the installer cannot be downloaded", func() {
+		oneAgent.MaxDownloadRetries = 0
+		api.FailAlways(api.Path(fakevendors.DynatraceInstallerPath), http.StatusBadGateway)
+
+		Expect(hook.AfterCompile(stager)).To(MatchError(ContainSubstring("download returned with status 502")))
+		Expect(filepath.Join(depsDir, "0", "profile.d", "dynatrace-env.sh")).NotTo(BeAnExistingFile())
+	})
+
+	It("fails staging when the API token is rejected", func() {
+		oneAgent.MaxDownloadRetries = 0
+		api.APIToken = "another-token"
+
+		Expect(hook.AfterCompile(stager)).To(MatchError(ContainSubstring("download returned with status 401")))
+	})
+
+	It("skips the OneAgent when the service allows errors", func() {
+		oneAgent.MaxDownloadRetries = 0
+		os.Setenv("VCAP_SERVICES", api.VCAPServices("dynatrace-service", map[string]interface{}{"skiperrors": "true"}))
+		api.FailAlways(api.Path(fakevendors.DynatraceInstallerPath), http.StatusBadGateway)
+
+		Expect(hook.AfterCompile(stager)).To(Succeed())
+		Expect(logs.String()).To(ContainSubstring("Error during installer download, skipping installation"))
+	})
+
+	It("keeps the configuration of the installer when that of the environment is unavailable", func() {
+		api.FailAlways(api.Path(fakevendors.DynatraceConfigPath), http.StatusInternalServerError)
+
+		Expect(hook.AfterCompile(stager)).To(Succeed())
+
+		Expect(agentConfig()).To(ContainSubstring("key1 installer"))
+		Expect(logs.String()).To(ContainSubstring("Failed to fetch updated OneAgent config from the API"))
+	})
+
+	It("does not download the OneAgent when offline", func() {
+		os.Setenv("JBP_OFFLINE", "true")
+
+		Expect(hook.AfterCompile(stager)).To(MatchError(ContainSubstring("Dynatrace OneAgent for service dynatrace-service")))
+		Expect(api.Requests()).To(BeEmpty())
+	})
+
+	It("reports the API of the bound service as endpoint", func() {
+		Expect(DynatraceEndpoint()).To(Equal(api.APIURL()))
+	})
+})
@@ -0,0 +1,195 @@
+package jres
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/libbuildpack"
+)
+
+// JRE extraction cache constants
+const (
+	// JRECacheDirName is the directory below the staging cache holding extracted JREs
+	JRECacheDirName = "jre"
+
+	// jreCacheLockTimeout is how long a staging waits for a concurrent staging to populate the cache
+	jreCacheLockTimeout = 2 * time.Minute
+
+	// jreCacheCompleteMarker marks a cache entry whose copy finished successfully
+	jreCacheCompleteMarker = ".complete"
+)
+
+var jreCacheLockPollInterval = 250 * time.Millisecond
+
+// InstallJRE installs dep into jreDir, reusing an already extracted copy from the staging cache.
+// Entries are keyed by dependency name, version and stack, so a cache shared between stagings
+// never serves a JRE built for another stack. A file lock guards each entry: concurrent stagings
+// of the same JRE wait for the first one to populate the cache instead of extracting twice.
+// Any cache failure falls back to a regular installation. The returned dependency is the one installed, which is
+// another patch version than dep after a patch version fallback.
+func InstallJRE(ctx *common.Context, dep libbuildpack.Dependency, jreDir string) (libbuildpack.Dependency, error) {
+	cacheRoot := ctx.Stager.CacheDir()
+	if cacheRoot == "" {
+		return common.InstallDependency(ctx, dep, jreDir)
+	}
+
+	entry := jreCacheEntry(cacheRoot, dep)
+	unlock, err := lockJRECacheEntry(entry)
+	if err != nil {
+		ctx.Log.Debug("JRE cache unavailable, installing without cache: %s", err.Error())
+		return common.InstallDependency(ctx, dep, jreDir)
+	}
+	defer unlock()
+
+	if exists, _ := libbuildpack.FileExists(filepath.Join(entry, jreCacheCompleteMarker)); exists {
+		err := restoreJRE(entry, jreDir)
+		if err == nil {
+			ctx.Log.Info("Using cached %s %s", dep.Name, dep.Version)
+			return dep, nil
+		}
+		ctx.Log.Warning("Could not restore cached %s %s, reinstalling: %s", dep.Name, dep.Version, err.Error())
+		os.RemoveAll(jreDir)
+	}
+
+	installed, err := common.InstallDependency(ctx, dep, jreDir)
+	if err != nil {
+		return installed, err
+	}
+	if installed.Version != dep.Version {
+		// A fallback patch version is not cached under the entry of the version requested
+		return installed, nil
+	}
+
+	if err := storeJRE(jreDir, entry); err != nil {
+		ctx.Log.Warning("Could not cache %s %s: %s", dep.Name, dep.Version, err.Error())
+	} else {
+		pruneJRECache(cacheRoot, dep, entry)
+	}
+	return installed, nil
+}
+
+// jreCacheEntry returns the cache directory of dep, e.g. <cache>/jre/openjdk-17.0.13-cflinuxfs4
+func jreCacheEntry(cacheRoot string, dep libbuildpack.Dependency) string {
+	return filepath.Join(cacheRoot, JRECacheDirName, fmt.Sprintf("%s-%s-%s", dep.Name, dep.Version, jreCacheStack()))
+}
+
+func jreCacheStack() string {
+	if stack := os.Getenv("CF_STACK"); stack != "" {
+		return stack
+	}
+	return "unknown"
+}
+
+// lockJRECacheEntry takes an exclusive flock(2) on <entry>.lock, waiting for a concurrent holder to release it.
+// The kernel releases the lock of a staging that crashed, so there are no stale locks to take over. The returned
+// function removes the lock file and releases the lock.
+func lockJRECacheEntry(entry string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(entry), 0755); err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(jreCacheLockTimeout)
+	for {
+		unlock, err := tryLockJRECacheEntry(entry)
+		if err != nil || unlock != nil {
+			return unlock, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for %s.lock", entry)
+		}
+		time.Sleep(jreCacheLockPollInterval)
+	}
+}
+
+// tryLockJRECacheEntry takes the lock of entry if no other staging holds it, and returns nil otherwise. A holder
+// removes the lock file before it releases the lock, so a lock taken on a file that is no longer at its path is
+// given up and taken again on the new file.
+func tryLockJRECacheEntry(entry string) (func(), error) {
+	lockFile := entry + ".lock"
+	for {
+		f, err := os.OpenFile(lockFile, os.O_CREATE|os.O_RDWR, 0644)
+		if err != nil {
+			return nil, err
+		}
+		if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+			f.Close()
+			if errors.Is(err, syscall.EWOULDBLOCK) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("failed to lock %s: %w", lockFile, err)
+		}
+
+		locked, statErr := f.Stat()
+		current, err := os.Stat(lockFile)
+		if statErr == nil && err == nil && os.SameFile(locked, current) {
+			f.Truncate(0)
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			return func() {
+				os.Remove(lockFile)
+				f.Close()
+			}, nil
+		}
+		f.Close()
+	}
+}
+
+// restoreJRE copies a cached JRE into jreDir
+func restoreJRE(entry, jreDir string) error {
+	if err := os.MkdirAll(jreDir, 0755); err != nil {
+		return err
+	}
+	if err := libbuildpack.CopyDirectory(entry, jreDir); err != nil {
+		return err
+	}
+	return os.Remove(filepath.Join(jreDir, jreCacheCompleteMarker))
+}
+
+// storeJRE copies jreDir into a temporary directory next to entry and renames it into place,
+// so an interrupted copy never leaves a partial entry behind
+func storeJRE(jreDir, entry string) error {
+	tmp := fmt.Sprintf("%s.tmp-%d", entry, os.Getpid())
+	os.RemoveAll(tmp)
+	if err := os.MkdirAll(tmp, 0755); err != nil {
+		return err
+	}
+	if err := libbuildpack.CopyDirectory(jreDir, tmp); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(tmp, jreCacheCompleteMarker), []byte{}, 0644); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+
+	os.RemoveAll(entry)
+	if err := os.Rename(tmp, entry); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	return nil
+}
+
+// pruneJRECache removes other cached versions of the same JRE and stack so the cache does not grow
+// with every JRE update. Entries that are currently locked by another staging are kept.
+func pruneJRECache(cacheRoot string, dep libbuildpack.Dependency, keep string) {
+	matches, err := filepath.Glob(filepath.Join(cacheRoot, JRECacheDirName, dep.Name+"-*-"+jreCacheStack()))
+	if err != nil {
+		return
+	}
+	for _, match := range matches {
+		if match == keep {
+			continue
+		}
+		unlock, err := tryLockJRECacheEntry(match)
+		if err != nil || unlock == nil {
+			continue
+		}
+		os.RemoveAll(match)
+		unlock()
+	}
+}
@@ -0,0 +1,167 @@
+package jres_test
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/cloudfoundry/java-buildpack/src/internal/mocks"
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/jres"
+	"github.com/cloudfoundry/libbuildpack"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("JRE cache", func() {
+	var (
+		ctx           *common.Context
+		mockCtrl      *gomock.Controller
+		mockInstaller *mocks.MockInstaller
+		buildDir      string
+		cacheDir      string
+		depsDir       string
+		dep           libbuildpack.Dependency
+	)
+
+	extract := func(_ libbuildpack.Dependency, dir string) error {
+		Expect(os.MkdirAll(filepath.Join(dir, "jdk", "bin"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "jdk", "bin", "java"), []byte("#!/bin/sh\n"), 0755)).To(Succeed())
+		return os.Symlink("jdk", filepath.Join(dir, "current"))
+	}
+
+	BeforeEach(func() {
+		var err error
+		buildDir, err = os.MkdirTemp("", "build")
+		Expect(err).NotTo(HaveOccurred())
+		cacheDir, err = os.MkdirTemp("", "cache")
+		Expect(err).NotTo(HaveOccurred())
+		depsDir, err = os.MkdirTemp("", "deps")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.MkdirAll(filepath.Join(depsDir, "0"), 0755)).To(Succeed())
+
+		os.Setenv("CF_STACK", "cflinuxfs4")
+
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockInstaller = mocks.NewMockInstaller(mockCtrl)
+
+		logger := libbuildpack.NewLogger(GinkgoWriter)
+		manifest := &libbuildpack.Manifest{}
+		ctx = &common.Context{
+			Stager:    libbuildpack.NewStager([]string{buildDir, cacheDir, depsDir, "0"}, logger, manifest),
+			Manifest:  manifest,
+			Installer: mockInstaller,
+			Log:       logger,
+		}
+
+		dep = libbuildpack.Dependency{Name: "openjdk", Version: "17.0.13"}
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+		os.Unsetenv("CF_STACK")
+		os.RemoveAll(buildDir)
+		os.RemoveAll(cacheDir)
+		os.RemoveAll(depsDir)
+	})
+
+	It("extracts the JRE once and restores it from the cache afterwards", func() {
+		mockInstaller.EXPECT().InstallDependency(dep, gomock.Any()).DoAndReturn(extract).Times(1)
+
+		first := filepath.Join(depsDir, "0", "jre")
+		Expect(jres.InstallJRE(ctx, dep, first)).To(Equal(dep))
+		Expect(filepath.Join(cacheDir, jres.JRECacheDirName, "openjdk-17.0.13-cflinuxfs4", "jdk", "bin", "java")).To(BeARegularFile())
+
+		second := filepath.Join(depsDir, "1", "jre")
+		Expect(jres.InstallJRE(ctx, dep, second)).To(Equal(dep))
+
+		info, err := os.Stat(filepath.Join(second, "jdk", "bin", "java"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0755)))
+
+		target, err := os.Readlink(filepath.Join(second, "current"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(target).To(Equal("jdk"))
+
+		Expect(filepath.Join(second, ".complete")).NotTo(BeAnExistingFile())
+		Expect(filepath.Join(cacheDir, jres.JRECacheDirName, "openjdk-17.0.13-cflinuxfs4.lock")).NotTo(BeAnExistingFile())
+	})
+
+	It("keys cache entries by stack", func() {
+		mockInstaller.EXPECT().InstallDependency(dep, gomock.Any()).DoAndReturn(extract).Times(2)
+
+		Expect(jres.InstallJRE(ctx, dep, filepath.Join(depsDir, "0", "jre"))).To(Equal(dep))
+
+		os.Setenv("CF_STACK", "cflinuxfs5")
+		Expect(jres.InstallJRE(ctx, dep, filepath.Join(depsDir, "1", "jre"))).To(Equal(dep))
+
+		Expect(filepath.Join(cacheDir, jres.JRECacheDirName, "openjdk-17.0.13-cflinuxfs4")).To(BeADirectory())
+		Expect(filepath.Join(cacheDir, jres.JRECacheDirName, "openjdk-17.0.13-cflinuxfs5")).To(BeADirectory())
+	})
+
+	It("removes cached entries of other versions of the same JRE", func() {
+		stale := filepath.Join(cacheDir, jres.JRECacheDirName, "openjdk-17.0.12-cflinuxfs4")
+		other := filepath.Join(cacheDir, jres.JRECacheDirName, "zulu-17.0.12-cflinuxfs4")
+		Expect(os.MkdirAll(stale, 0755)).To(Succeed())
+		Expect(os.MkdirAll(other, 0755)).To(Succeed())
+
+		mockInstaller.EXPECT().InstallDependency(dep, gomock.Any()).DoAndReturn(extract).Times(1)
+		Expect(jres.InstallJRE(ctx, dep, filepath.Join(depsDir, "0", "jre"))).To(Equal(dep))
+
+		Expect(stale).NotTo(BeAnExistingFile())
+		Expect(other).To(BeADirectory())
+	})
+
+	lockEntry := func(name string) *os.File {
+		lockFile := filepath.Join(cacheDir, jres.JRECacheDirName, name+".lock")
+		Expect(os.MkdirAll(filepath.Dir(lockFile), 0755)).To(Succeed())
+		f, err := os.OpenFile(lockFile, os.O_CREATE|os.O_RDWR, 0644)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)).To(Succeed())
+		return f
+	}
+
+	It("waits for a concurrent staging to release the lock", func() {
+		lock := lockEntry("openjdk-17.0.13-cflinuxfs4")
+
+		released := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			time.Sleep(500 * time.Millisecond)
+			close(released)
+			Expect(lock.Close()).To(Succeed())
+		}()
+
+		mockInstaller.EXPECT().InstallDependency(dep, gomock.Any()).DoAndReturn(
+			func(d libbuildpack.Dependency, dir string) error {
+				Expect(released).To(BeClosed())
+				return extract(d, dir)
+			}).Times(1)
+
+		Expect(jres.InstallJRE(ctx, dep, filepath.Join(depsDir, "0", "jre"))).To(Equal(dep))
+	})
+
+	It("takes over the lock file left by a crashed staging", func() {
+		lockFile := filepath.Join(cacheDir, jres.JRECacheDirName, "openjdk-17.0.13-cflinuxfs4.lock")
+		Expect(os.MkdirAll(filepath.Dir(lockFile), 0755)).To(Succeed())
+		Expect(os.WriteFile(lockFile, []byte("1\n"), 0644)).To(Succeed())
+
+		mockInstaller.EXPECT().InstallDependency(dep, gomock.Any()).DoAndReturn(extract).Times(1)
+		Expect(jres.InstallJRE(ctx, dep, filepath.Join(depsDir, "0", "jre"))).To(Equal(dep))
+		Expect(lockFile).NotTo(BeAnExistingFile())
+	})
+
+	It("keeps cached entries of other versions that another staging holds", func() {
+		locked := filepath.Join(cacheDir, jres.JRECacheDirName, "openjdk-17.0.12-cflinuxfs4")
+		Expect(os.MkdirAll(locked, 0755)).To(Succeed())
+		lock := lockEntry("openjdk-17.0.12-cflinuxfs4")
+		defer lock.Close()
+
+		mockInstaller.EXPECT().InstallDependency(dep, gomock.Any()).DoAndReturn(extract).Times(1)
+		Expect(jres.InstallJRE(ctx, dep, filepath.Join(depsDir, "0", "jre"))).To(Equal(dep))
+
+		Expect(locked).To(BeADirectory())
+	})
+})
@@ -0,0 +1,135 @@
+package jres
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+)
+
+// cpuCountOption sets the processors the JVM sizes its thread pools for to the container's CPU limit, read by
+// profile.d/00_cgroup.sh, and otherwise to the processors the container may run on
+const cpuCountOption = "-XX:ActiveProcessorCount=${CONTAINER_CPU_COUNT:-$(nproc)}"
+
+// cgroupLibrary reads the container's limits from cgroup v2 and falls back to cgroup v1. It is written to
+// $DEPS_DIR/<idx>/bin/cgroup.sh, where scripts can source it or run it with memory or cpus.
+const cgroupLibrary = `#!/bin/bash
+# Reads the memory and CPU limits of the container from cgroup v2, falling back to cgroup v1.
+# Source it for the cgroup_* functions, or run it with memory or cpus to print a limit.
+
+CGROUP_ROOT=${CGROUP_ROOT:-/sys/fs/cgroup}
+
+# cgroup_memory_limit prints the memory limit in bytes, or nothing if the container is not limited
+cgroup_memory_limit() {
+  local limit
+  if [ -f "$CGROUP_ROOT/memory.max" ]; then
+    limit=$(cat "$CGROUP_ROOT/memory.max")
+  elif [ -f "$CGROUP_ROOT/memory/memory.limit_in_bytes" ]; then
+    limit=$(cat "$CGROUP_ROOT/memory/memory.limit_in_bytes")
+  fi
+
+  # cgroup v2 reports max without a limit, cgroup v1 a number close to 2^63
+  case "$limit" in
+    ''|*[!0-9]*) return ;;
+  esac
+  if [ "${#limit}" -lt 19 ]; then
+    echo "$limit"
+  fi
+}
+
+# cgroup_cpu_count prints the number of CPUs the CPU quota allows, rounded up, or the number of CPUs the
+# container may run on if that is lower or there is no quota
+cgroup_cpu_count() {
+  local quota period cpus limited
+  if [ -f "$CGROUP_ROOT/cpu.max" ]; then
+    read -r quota period < "$CGROUP_ROOT/cpu.max"
+  elif [ -f "$CGROUP_ROOT/cpu/cpu.cfs_quota_us" ] && [ -f "$CGROUP_ROOT/cpu/cpu.cfs_period_us" ]; then
+    quota=$(cat "$CGROUP_ROOT/cpu/cpu.cfs_quota_us")
+    period=$(cat "$CGROUP_ROOT/cpu/cpu.cfs_period_us")
+  fi
+
+  cpus=$(nproc)
+  # cgroup v2 reports max and cgroup v1 -1 without a quota
+  case "$quota:$period" in
+    *[!0-9:]*|:*|*:|*:0) echo "$cpus"; return ;;
+  esac
+
+  limited=$(( (quota + period - 1) / period ))
+  if [ "$limited" -lt "$cpus" ]; then
+    cpus=$limited
+  fi
+  if [ "$cpus" -lt 1 ]; then
+    cpus=1
+  fi
+  echo "$cpus"
+}
+
+# memory_bytes converts a memory size like MEMORY_LIMIT, e.g. 1024m or 2G, to bytes
+memory_bytes() {
+  local size=$1
+  case "$size" in
+    *[kK]) echo $(( ${size%?} * 1024 )) ;;
+    *[mM]) echo $(( ${size%?} * 1024 * 1024 )) ;;
+    *[gG]) echo $(( ${size%?} * 1024 * 1024 * 1024 )) ;;
+    *[tT]) echo $(( ${size%?} * 1024 * 1024 * 1024 * 1024 )) ;;
+    *) echo $(( size )) ;;
+  esac
+}
+
+if [ "${BASH_SOURCE[0]}" = "$0" ]; then
+  case "$1" in
+    memory) cgroup_memory_limit ;;
+    cpus) cgroup_cpu_count ;;
+    *) echo "Usage: $0 memory|cpus" >&2; exit 1 ;;
+  esac
+fi
+`
+
+// cgroupProfileScript is sourced before the JAVA_OPTS are assembled. MEMORY_LIMIT is the limit the application was
+// pushed with; the container's cgroup can be lower, e.g. when sidecars share the container, and then the memory
+// calculator sizes the JVM for the cgroup.
+const cgroupProfileScript = `#!/bin/bash
+# Adjusts MEMORY_LIMIT to the container's cgroup and exports CONTAINER_CPU_COUNT
+source "$DEPS_DIR/%s/bin/cgroup.sh"
+
+CGROUP_MEMORY_LIMIT=$(cgroup_memory_limit)
+if [ -n "$CGROUP_MEMORY_LIMIT" ]; then
+  if [ -z "$MEMORY_LIMIT" ] || [ "$CGROUP_MEMORY_LIMIT" -lt "$(memory_bytes "$MEMORY_LIMIT")" ]; then
+    echo "Using the container's cgroup memory limit of $(( CGROUP_MEMORY_LIMIT / 1048576 ))m instead of MEMORY_LIMIT=${MEMORY_LIMIT:-unset}" >&2
+    export MEMORY_LIMIT="$(( CGROUP_MEMORY_LIMIT / 1048576 ))m"
+  fi
+fi
+unset CGROUP_MEMORY_LIMIT
+
+export CONTAINER_CPU_COUNT=$(cgroup_cpu_count)
+`
+
+// writeCgroupScripts writes the cgroup library and the profile.d script that applies the container's limits
+func writeCgroupScripts(ctx *common.Context) error {
+	binDir := filepath.Join(ctx.Stager.DepDir(), "bin")
+	if err := os.MkdirAll(binDir, 0755); err != nil {
+		return fmt.Errorf("failed to create bin directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(binDir, "cgroup.sh"), []byte(cgroupLibrary), 0755); err != nil {
+		return fmt.Errorf("failed to write cgroup.sh: %w", err)
+	}
+
+	// 00_cgroup.sh sorts before 00_java_opts.sh, which expands CONTAINER_CPU_COUNT
+	if err := ctx.Stager.WriteProfileD("00_cgroup.sh", fmt.Sprintf(cgroupProfileScript, ctx.Stager.DepsIdx())); err != nil {
+		return fmt.Errorf("failed to write 00_cgroup.sh: %w", err)
+	}
+	return nil
+}
+
+// writeContainerLimits applies the container's cgroup limits at runtime and sizes the JVM's thread pools for its
+// CPU limit. Every JRE calls it, through the memory calculator or directly.
+func writeContainerLimits(ctx *common.Context) error {
+	if err := writeCgroupScripts(ctx); err != nil {
+		return err
+	}
+	if err := WriteJavaOpts(ctx, cpuCountOption); err != nil {
+		return fmt.Errorf("failed to write CPU count: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package jres_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/jres"
+	"github.com/cloudfoundry/libbuildpack"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cgroup limits", func() {
+	var (
+		depsDir    string
+		cgroupRoot string
+	)
+
+	BeforeEach(func() {
+		var err error
+		depsDir, err = os.MkdirTemp("", "deps")
+		Expect(err).NotTo(HaveOccurred())
+		cgroupRoot, err = os.MkdirTemp("", "cgroup")
+		Expect(err).NotTo(HaveOccurred())
+
+		jreDir := filepath.Join(depsDir, "0", "jre")
+		Expect(os.MkdirAll(filepath.Join(jreDir, "bin"), 0755)).To(Succeed())
+
+		logger := libbuildpack.NewLogger(GinkgoWriter)
+		manifest := &libbuildpack.Manifest{}
+		ctx := &common.Context{
+			Stager:   libbuildpack.NewStager([]string{depsDir, "", depsDir, "0"}, logger, manifest),
+			Manifest: manifest,
+			Log:      logger,
+		}
+		Expect(jres.NewMemoryCalculator(ctx, jreDir, "17.0.13", 17).Finalize()).To(Succeed())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(depsDir)
+		os.RemoveAll(cgroupRoot)
+	})
+
+	writeCgroupFile := func(name, content string) {
+		Expect(os.MkdirAll(filepath.Dir(filepath.Join(cgroupRoot, name)), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(cgroupRoot, name), []byte(content+"\n"), 0644)).To(Succeed())
+	}
+
+	// limits sources profile.d/00_cgroup.sh with MEMORY_LIMIT and returns the adjusted MEMORY_LIMIT and CONTAINER_CPU_COUNT
+	limits := func(memoryLimit string) (string, string) {
+		cmd := exec.Command("bash", "-c", `source "$DEPS_DIR/0/profile.d/00_cgroup.sh" 2> /dev/null; echo "$MEMORY_LIMIT $CONTAINER_CPU_COUNT"`)
+		cmd.Env = append(os.Environ(), "DEPS_DIR="+depsDir, "CGROUP_ROOT="+cgroupRoot, "MEMORY_LIMIT="+memoryLimit)
+		output, err := cmd.CombinedOutput()
+		Expect(err).NotTo(HaveOccurred(), string(output))
+		fields := strings.Fields(string(output))
+		Expect(fields).To(HaveLen(2), string(output))
+		return fields[0], fields[1]
+	}
+
+	nproc := func() string {
+		output, err := exec.Command("nproc").Output()
+		Expect(err).NotTo(HaveOccurred())
+		return strings.TrimSpace(string(output))
+	}
+
+	It("applies a cgroup v2 memory limit below MEMORY_LIMIT and the CPU quota", func() {
+		writeCgroupFile("memory.max", "805306368")
+		writeCgroupFile("cpu.max", "50000 100000")
+
+		memory, cpus := limits("1G")
+		Expect(memory).To(Equal("768m"))
+		Expect(cpus).To(Equal("1"))
+	})
+
+	It("keeps MEMORY_LIMIT and the CPUs without cgroup v2 limits", func() {
+		writeCgroupFile("memory.max", "max")
+		writeCgroupFile("cpu.max", "max 100000")
+
+		memory, cpus := limits("1024m")
+		Expect(memory).To(Equal("1024m"))
+		Expect(cpus).To(Equal(nproc()))
+	})
+
+	It("keeps a MEMORY_LIMIT below the cgroup limit", func() {
+		writeCgroupFile("memory.max", "2147483648")
+
+		memory, _ := limits("512M")
+		Expect(memory).To(Equal("512M"))
+	})
+
+	It("falls back to cgroup v1", func() {
+		writeCgroupFile("memory/memory.limit_in_bytes", "536870912")
+		writeCgroupFile("cpu/cpu.cfs_quota_us", "100000")
+		writeCgroupFile("cpu/cpu.cfs_period_us", "100000")
+
+		memory, cpus := limits("1G")
+		Expect(memory).To(Equal("512m"))
+		Expect(cpus).To(Equal("1"))
+
+		writeCgroupFile("memory/memory.limit_in_bytes", "9223372036854771712")
+		writeCgroupFile("cpu/cpu.cfs_quota_us", "-1")
+		memory, cpus = limits("1G")
+		Expect(memory).To(Equal("1G"))
+		Expect(cpus).To(Equal(nproc()))
+	})
+
+	It("sizes the JVM for the container's CPUs", func() {
+		opts, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "05_jre.opts"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(opts)).To(ContainSubstring("-XX:ActiveProcessorCount=${CONTAINER_CPU_COUNT:-$(nproc)}"))
+	})
+
+	It("runs as a command", func() {
+		writeCgroupFile("memory.max", "805306368")
+
+		cmd := exec.Command(filepath.Join(depsDir, "0", "bin", "cgroup.sh"), "memory")
+		cmd.Env = append(os.Environ(), "CGROUP_ROOT="+cgroupRoot)
+		output, err := cmd.Output()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(strings.TrimSpace(string(output))).To(Equal("805306368"))
+	})
+})
@@ -0,0 +1,90 @@
+package jres
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+)
+
+// FixedMemoryConfig replaces the JVMKill agent and the memory calculator with a fixed set of memory options.
+// In very small containers (below 256M) their overhead matters, so operators can ship config/fixed_memory.yml
+// or applications can set JBP_CONFIG_FIXED_MEMORY, e.g.
+//
+//	enabled: true
+//	java_opts: -Xmx160M -Xss256K -XX:MaxMetaspaceSize=48M -XX:ReservedCodeCacheSize=16M
+type FixedMemoryConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	JavaOpts string `yaml:"java_opts"`
+}
+
+// LoadFixedMemoryConfig loads the fixed memory configuration. When enabled, the heap must be sized with -Xmx,
+// either in java_opts or in the application's JAVA_OPTS, as nothing else limits it.
+func LoadFixedMemoryConfig(ctx *common.Context) (FixedMemoryConfig, error) {
+	cfg := FixedMemoryConfig{}
+	if err := config.Load(ctx.Log, "fixed_memory", &cfg); err != nil {
+		return FixedMemoryConfig{}, err
+	}
+	if !cfg.Enabled {
+		return cfg, nil
+	}
+
+	userOpts, err := userJavaOpts()
+	if err != nil {
+		return FixedMemoryConfig{}, err
+	}
+	if !hasMaxHeap(cfg.JavaOpts) && !hasMaxHeap(userOpts) {
+		return FixedMemoryConfig{}, fmt.Errorf("%s disables the memory calculator, so -Xmx must be set in its java_opts or in JAVA_OPTS",
+			config.EnvVar("fixed_memory"))
+	}
+	return cfg, nil
+}
+
+// fixedMemoryEnabled returns true if the JVMKill agent and the memory calculator are replaced by fixed options
+func fixedMemoryEnabled(ctx *common.Context) (bool, error) {
+	cfg, err := LoadFixedMemoryConfig(ctx)
+	if err != nil {
+		return false, err
+	}
+	return cfg.Enabled, nil
+}
+
+// userJavaOpts returns the options the application adds through JBP_CONFIG_JAVA_OPTS and, unless
+// from_environment is false, the JAVA_OPTS environment variable
+func userJavaOpts() (string, error) {
+	javaOpts := struct {
+		FromEnvironment *bool       `yaml:"from_environment"`
+		JavaOpts        interface{} `yaml:"java_opts"`
+	}{}
+	if err := config.Decode("java_opts", &javaOpts); err != nil {
+		return "", err
+	}
+
+	var opts []string
+	switch v := javaOpts.JavaOpts.(type) {
+	case string:
+		opts = append(opts, v)
+	case []interface{}:
+		for _, opt := range v {
+			if s, ok := opt.(string); ok {
+				opts = append(opts, s)
+			}
+		}
+	}
+	if javaOpts.FromEnvironment == nil || *javaOpts.FromEnvironment {
+		opts = append(opts, os.Getenv("JAVA_OPTS"))
+	}
+	return strings.Join(opts, " "), nil
+}
+
+// hasMaxHeap returns true if opts contains an -Xmx option
+func hasMaxHeap(opts string) bool {
+	for _, opt := range strings.Fields(opts) {
+		if strings.HasPrefix(opt, "-Xmx") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package jres_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/jres"
+	"github.com/cloudfoundry/libbuildpack"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fixed memory", func() {
+	var (
+		ctx     *common.Context
+		depsDir string
+		jreDir  string
+	)
+
+	BeforeEach(func() {
+		var err error
+		depsDir, err = os.MkdirTemp("", "deps")
+		Expect(err).NotTo(HaveOccurred())
+		jreDir = filepath.Join(depsDir, "0", "jre")
+
+		logger := libbuildpack.NewLogger(GinkgoWriter)
+		manifest := &libbuildpack.Manifest{}
+		ctx = &common.Context{
+			Stager:    libbuildpack.NewStager([]string{depsDir, "", depsDir, "0"}, logger, manifest),
+			Manifest:  manifest,
+			Installer: &libbuildpack.Installer{},
+			Log:       logger,
+		}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(depsDir)
+		os.Unsetenv("JBP_CONFIG_FIXED_MEMORY")
+		os.Unsetenv("JBP_CONFIG_JAVA_OPTS")
+		os.Unsetenv("JAVA_OPTS")
+	})
+
+	Describe("LoadFixedMemoryConfig", func() {
+		It("is disabled by default", func() {
+			cfg, err := jres.LoadFixedMemoryConfig(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.Enabled).To(BeFalse())
+		})
+
+		It("requires -Xmx", func() {
+			os.Setenv("JBP_CONFIG_FIXED_MEMORY", "{enabled: true, java_opts: '-Xss256K'}")
+
+			_, err := jres.LoadFixedMemoryConfig(ctx)
+			Expect(err).To(MatchError(ContainSubstring("-Xmx must be set")))
+		})
+
+		It("accepts -Xmx from the application's JAVA_OPTS", func() {
+			os.Setenv("JBP_CONFIG_FIXED_MEMORY", "{enabled: true, java_opts: '-Xss256K'}")
+			os.Setenv("JAVA_OPTS", "-Xmx100M")
+
+			cfg, err := jres.LoadFixedMemoryConfig(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.Enabled).To(BeTrue())
+
+			os.Setenv("JBP_CONFIG_JAVA_OPTS", "{from_environment: false}")
+			_, err = jres.LoadFixedMemoryConfig(ctx)
+			Expect(err).To(HaveOccurred())
+
+			os.Setenv("JBP_CONFIG_JAVA_OPTS", "{from_environment: false, java_opts: ['-Xmx120M']}")
+			_, err = jres.LoadFixedMemoryConfig(ctx)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Context("when enabled", func() {
+		BeforeEach(func() {
+			os.Setenv("JBP_CONFIG_FIXED_MEMORY", "{enabled: true, java_opts: '-Xmx160M -Xss256K'}")
+		})
+
+		It("skips the JVMKill agent and the memory calculator", func() {
+			Expect(jres.NewJVMKillAgent(ctx, jreDir, "17.0.13").Supply()).To(Succeed())
+			Expect(jres.NewMemoryCalculator(ctx, jreDir, "17.0.13", 17).Supply()).To(Succeed())
+			Expect(filepath.Join(jreDir, "bin")).NotTo(BeADirectory())
+		})
+
+		It("writes the fixed options instead of the calculator script", func() {
+			Expect(os.MkdirAll(filepath.Join(jreDir, "bin"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(jreDir, "bin", "java-buildpack-memory-calculator-4.2.0"), []byte("#!/bin/sh\n"), 0755)).To(Succeed())
+
+			calculator := jres.NewMemoryCalculator(ctx, jreDir, "17.0.13", 17)
+			Expect(calculator.Finalize()).To(Succeed())
+			Expect(calculator.GetCalculatorCommand()).To(BeEmpty())
+
+			opts, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "05_fixed_memory.opts"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(opts)).To(Equal("-Xmx160M -Xss256K"))
+			Expect(filepath.Join(depsDir, "0", "bin", "memory_calculator.sh")).NotTo(BeAnExistingFile())
+		})
+	})
+
+	It("fails the memory calculator and the JVMKill agent on malformed configuration", func() {
+		os.Setenv("JBP_CONFIG_FIXED_MEMORY", "{enabled: [true]}")
+
+		Expect(jres.NewJVMKillAgent(ctx, jreDir, "17.0.13").Supply()).NotTo(Succeed())
+		Expect(jres.NewMemoryCalculator(ctx, jreDir, "17.0.13", 17).Supply()).NotTo(Succeed())
+		Expect(jres.NewMemoryCalculator(ctx, jreDir, "17.0.13", 17).Finalize()).NotTo(Succeed())
+	})
+})
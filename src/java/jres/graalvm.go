@@ -3,6 +3,7 @@ package jres
 import (
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
 	"os"
 	"path/filepath"
 )
@@ -16,6 +17,8 @@ type GraalVMJRE struct {
 	memoryCalc       *MemoryCalculator
 	jvmkill          *JVMKillAgent
 	installedVersion string
+
+	versionSelection
 }
 
 // NewGraalVMJRE creates a new GraalVM JRE provider
@@ -44,7 +47,7 @@ func (g *GraalVMJRE) Supply() error {
 	g.ctx.Log.BeginStep("Installing GraalVM JRE")
 
 	// Determine version
-	dep, err := GetJREVersion(g.ctx, "graalvm")
+	dep, err := GetJREVersion(g.ctx, "graalvm", g.selectedVersion)
 	if err != nil {
 		return fmt.Errorf("failed to determine GraalVM version from manifest: %w", err)
 	}
@@ -53,9 +56,11 @@ func (g *GraalVMJRE) Supply() error {
 	g.ctx.Log.Info("Installing GraalVM (%s)", g.version)
 
 	// Install JRE
-	if err := g.ctx.Installer.InstallDependency(dep, g.jreDir); err != nil {
+	installed, err := InstallJRE(g.ctx, dep, g.jreDir)
+	if err != nil {
 		return fmt.Errorf("failed to install GraalVM: %w (ensure repository_root is configured)", err)
 	}
+	g.version = installed.Version
 
 	// Find the actual JAVA_HOME (handle nested directories from tar extraction)
 	javaHome, err := g.findJavaHome()
@@ -72,6 +77,17 @@ func (g *GraalVMJRE) Supply() error {
 		g.ctx.Log.Debug("Created profile.d script: java.sh")
 	}
 
+	// Install the native-image toolchain for containers that compile ahead-of-time during staging
+	cfg, err := g.loadConfig()
+	if err != nil {
+		return err
+	}
+	if cfg.NativeImage.Enabled {
+		if err := g.installNativeImage(); err != nil {
+			return fmt.Errorf("failed to install GraalVM native-image: %w", err)
+		}
+	}
+
 	// Determine Java major version
 	javaMajorVersion, err := common.DetermineJavaVersion(javaHome)
 	if err != nil {
@@ -89,6 +105,9 @@ func (g *GraalVMJRE) Supply() error {
 
 	// Install Memory Calculator
 	g.memoryCalc = NewMemoryCalculator(g.ctx, g.jreDir, g.version, javaMajorVersion)
+	if err := g.memoryCalc.LoadConfig("graal_vm_jre"); err != nil {
+		return fmt.Errorf("invalid memory calculator configuration: %w", err)
+	}
 	if err := g.memoryCalc.Supply(); err != nil {
 		g.ctx.Log.Warning("Failed to install Memory Calculator: %s (continuing)", err.Error())
 		// Non-fatal - continue without memory calculator
@@ -144,6 +163,9 @@ func (g *GraalVMJRE) Finalize() error {
 	// Reconstruct Memory Calculator component if not already set
 	if g.memoryCalc == nil {
 		g.memoryCalc = NewMemoryCalculator(g.ctx, g.jreDir, g.version, javaMajorVersion)
+		if err := g.memoryCalc.LoadConfig("graal_vm_jre"); err != nil {
+			return fmt.Errorf("invalid memory calculator configuration: %w", err)
+		}
 	}
 
 	// Finalize Memory Calculator
@@ -212,6 +234,56 @@ func (g *GraalVMJRE) findJavaHome() (string, error) {
 	return "", fmt.Errorf("could not find valid JAVA_HOME in %s", g.jreDir)
 }
 
+// graalVMConfig is the part of JBP_CONFIG_GRAAL_VM_JRE read by the GraalVM JRE
+type graalVMConfig struct {
+	NativeImage struct {
+		Enabled bool `yaml:"enabled"`
+	} `yaml:"native_image"`
+}
+
+// loadConfig reads JBP_CONFIG_GRAAL_VM_JRE, which also carries the jre and memory_calculator settings
+func (g *GraalVMJRE) loadConfig() (graalVMConfig, error) {
+	cfg := graalVMConfig{}
+	if err := config.Decode("graal_vm_jre", &cfg); err != nil {
+		return graalVMConfig{}, err
+	}
+	return cfg, nil
+}
+
+// installNativeImage makes native-image available in JAVA_HOME/bin. GraalVM for JDK 17 and later ships it,
+// older releases install it with the GraalVM updater. GRAALVM_HOME is exported to the staging environment
+// of later phases and buildpacks.
+func (g *GraalVMJRE) installNativeImage() error {
+	if _, ok := NativeImagePath(g.javaHome); ok {
+		g.ctx.Log.Info("GraalVM native-image is bundled with GraalVM %s", g.version)
+	} else {
+		gu := filepath.Join(g.javaHome, "bin", "gu")
+		if _, err := os.Stat(gu); err != nil {
+			return fmt.Errorf("GraalVM %s provides neither native-image nor the gu updater", g.version)
+		}
+
+		g.ctx.Log.Info("Installing GraalVM native-image component")
+		if err := g.ctx.Command.Execute(g.javaHome, g.ctx.Log.Output(), g.ctx.Log.Output(), gu, "install", "--no-progress", "native-image"); err != nil {
+			return fmt.Errorf("gu install native-image failed: %w", err)
+		}
+		if _, ok := NativeImagePath(g.javaHome); !ok {
+			return fmt.Errorf("native-image not found in %s after gu install", filepath.Join(g.javaHome, "bin"))
+		}
+	}
+
+	return g.ctx.Stager.WriteEnvFile("GRAALVM_HOME", g.javaHome)
+}
+
+// NativeImagePath returns the native-image executable of the JDK at javaHome and whether it exists
+func NativeImagePath(javaHome string) (string, bool) {
+	if javaHome == "" {
+		return "", false
+	}
+	path := filepath.Join(javaHome, "bin", "native-image")
+	info, err := os.Stat(path)
+	return path, err == nil && !info.IsDir()
+}
+
 // writeProfileDScript creates a profile.d script that exports JAVA_HOME, JRE_HOME, and PATH at runtime
 // Delegates to the shared helper function in jre.go
 func (g *GraalVMJRE) writeProfileDScript() error {
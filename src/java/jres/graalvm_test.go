@@ -0,0 +1,124 @@
+package jres_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/java-buildpack/src/internal/mocks"
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/jres"
+	"github.com/cloudfoundry/libbuildpack"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GraalVM JRE", func() {
+	var (
+		ctrl         *gomock.Controller
+		mockManifest *mocks.MockManifest
+		mockCommand  *mocks.MockCommand
+		ctx          *common.Context
+		buildDir     string
+		depsDir      string
+		javaHome     string
+		bundled      bool
+	)
+
+	BeforeEach(func() {
+		var err error
+		buildDir, err = os.MkdirTemp("", "build")
+		Expect(err).NotTo(HaveOccurred())
+		depsDir, err = os.MkdirTemp("", "deps")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.MkdirAll(filepath.Join(depsDir, "0"), 0755)).To(Succeed())
+
+		ctrl = gomock.NewController(GinkgoT())
+		mockManifest = mocks.NewMockManifest(ctrl)
+		mockInstaller := mocks.NewMockInstaller(ctrl)
+		mockCommand = mocks.NewMockCommand(ctrl)
+
+		javaHome = filepath.Join(depsDir, "0", "jre", "graalvm-community-openjdk-21.0.5")
+		bundled = true
+
+		graalvm := libbuildpack.Dependency{Name: "graalvm", Version: "21.0.5"}
+		mockManifest.EXPECT().DefaultVersion("graalvm").Return(graalvm, nil).AnyTimes()
+		mockManifest.EXPECT().DefaultVersion(gomock.Any()).Return(libbuildpack.Dependency{}, errors.New("not in manifest")).AnyTimes()
+		mockInstaller.EXPECT().InstallDependency(graalvm, gomock.Any()).DoAndReturn(func(_ libbuildpack.Dependency, _ string) error {
+			Expect(os.MkdirAll(filepath.Join(javaHome, "bin"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(javaHome, "bin", "java"), []byte("#!/bin/sh\n"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(javaHome, "release"), []byte("JAVA_VERSION=\"21.0.5\"\n"), 0644)).To(Succeed())
+			if bundled {
+				Expect(os.WriteFile(filepath.Join(javaHome, "bin", "native-image"), []byte("#!/bin/sh\n"), 0755)).To(Succeed())
+			} else {
+				Expect(os.WriteFile(filepath.Join(javaHome, "bin", "gu"), []byte("#!/bin/sh\n"), 0755)).To(Succeed())
+			}
+			return nil
+		})
+
+		logger := libbuildpack.NewLogger(GinkgoWriter)
+		ctx = &common.Context{
+			Stager:    libbuildpack.NewStager([]string{buildDir, "", depsDir, "0"}, logger, &libbuildpack.Manifest{}),
+			Manifest:  mockManifest,
+			Installer: mockInstaller,
+			Log:       logger,
+			Command:   mockCommand,
+		}
+	})
+
+	AfterEach(func() {
+		ctrl.Finish()
+		os.RemoveAll(buildDir)
+		os.RemoveAll(depsDir)
+		os.Unsetenv("JBP_CONFIG_GRAAL_VM_JRE")
+	})
+
+	Describe("Supply", func() {
+		It("does not set up native-image by default", func() {
+			os.Setenv("JBP_CONFIG_GRAAL_VM_JRE", "{jre: {}}")
+
+			Expect(jres.NewGraalVMJRE(ctx).Supply()).To(Succeed())
+			Expect(filepath.Join(depsDir, "0", "env", "GRAALVM_HOME")).NotTo(BeAnExistingFile())
+		})
+
+		Context("with native_image enabled", func() {
+			BeforeEach(func() {
+				os.Setenv("JBP_CONFIG_GRAAL_VM_JRE", "{native_image: {enabled: true}}")
+			})
+
+			It("uses the bundled native-image and exports GRAALVM_HOME", func() {
+				Expect(jres.NewGraalVMJRE(ctx).Supply()).To(Succeed())
+
+				content, err := os.ReadFile(filepath.Join(depsDir, "0", "env", "GRAALVM_HOME"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(Equal(javaHome))
+			})
+
+			It("installs native-image with the GraalVM updater", func() {
+				bundled = false
+				gu := filepath.Join(javaHome, "bin", "gu")
+				mockCommand.EXPECT().Execute(javaHome, gomock.Any(), gomock.Any(), gu, "install", "--no-progress", "native-image").
+					DoAndReturn(func(_ string, _, _ io.Writer, _ string, _ ...string) error {
+						return os.WriteFile(filepath.Join(javaHome, "bin", "native-image"), []byte("#!/bin/sh\n"), 0755)
+					})
+
+				Expect(jres.NewGraalVMJRE(ctx).Supply()).To(Succeed())
+
+				path, ok := jres.NativeImagePath(javaHome)
+				Expect(ok).To(BeTrue())
+				Expect(path).To(Equal(filepath.Join(javaHome, "bin", "native-image")))
+			})
+
+			It("fails when native-image cannot be installed", func() {
+				bundled = false
+				mockCommand.EXPECT().Execute(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(errors.New("exit status 1"))
+
+				err := jres.NewGraalVMJRE(ctx).Supply()
+				Expect(err).To(MatchError(ContainSubstring("gu install native-image failed")))
+			})
+		})
+	})
+})
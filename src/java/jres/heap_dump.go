@@ -0,0 +1,191 @@
+package jres
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
+)
+
+// HeapDumpDirectory is where heap dumps are written at runtime unless a heap-dump volume service is bound
+const HeapDumpDirectory = "$TMPDIR/heapdumps"
+
+// HeapDumpStoreTag is the tag of the object store service that heap dumps are uploaded to
+const HeapDumpStoreTag = "heap-dump-store"
+
+// heapDumpSigV4CurlVersion is the first curl version that signs S3 requests with --aws-sigv4. cflinuxfs3 has 7.58.0.
+const heapDumpSigV4CurlVersion = "7.75.0"
+
+// heapDumpConfig enables heap dumps on OutOfMemoryError. Binding a service tagged heap-dump-store enables them as well.
+type heapDumpConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// HeapDump writes a heap dump when the JVM runs out of memory and, when an object store tagged heap-dump-store
+// is bound, uploads it after the JVM exits. Containers are ephemeral, so without the upload the dump is lost
+// with the crashed instance.
+type HeapDump struct {
+	ctx    *common.Context
+	upload bool
+}
+
+// NewHeapDump creates a new heap dump component
+func NewHeapDump(ctx *common.Context) *HeapDump {
+	return &HeapDump{ctx: ctx}
+}
+
+// Name returns the component name
+func (h *HeapDump) Name() string {
+	return "Heap Dump"
+}
+
+// Supply does nothing, the upload only needs curl and jq from the stack
+func (h *HeapDump) Supply() error {
+	return nil
+}
+
+// Finalize adds the heap dump options to JAVA_OPTS and creates the dump directory at runtime
+func (h *HeapDump) Finalize() error {
+	enabled, err := heapDumpEnabled(h.ctx)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return nil
+	}
+
+	h.ctx.Log.Info("Configuring heap dumps on OutOfMemoryError in %s", HeapDumpDirectory)
+	opts := fmt.Sprintf("-XX:+HeapDumpOnOutOfMemoryError -XX:HeapDumpPath=%s", HeapDumpDirectory)
+	if err := WriteJavaOptsWithPriority(h.ctx, javaopts.JRE, "heap_dump", opts); err != nil {
+		return fmt.Errorf("failed to add heap dump options to JAVA_OPTS: %w", err)
+	}
+
+	script := fmt.Sprintf("#!/bin/bash\nmkdir -p \"%s\"\n", HeapDumpDirectory)
+	if err := h.ctx.Stager.WriteProfileD("heap_dump.sh", script); err != nil {
+		return fmt.Errorf("failed to write heap_dump.sh: %w", err)
+	}
+
+	store, ok := heapDumpStore()
+	h.upload = ok
+	if h.upload {
+		h.ctx.Log.Info("Uploading heap dumps to the service tagged %s", HeapDumpStoreTag)
+		if _, bucket := store.Credentials["bucket"]; bucket && common.Stack() == "cflinuxfs3" {
+			h.ctx.Log.Warning("The curl of the cflinuxfs3 stack cannot sign S3 uploads, which needs curl %s or later. "+
+				"Heap dumps are kept in the container instead; push the application with the cflinuxfs4 stack to upload them",
+				heapDumpSigV4CurlVersion)
+		}
+	}
+	return nil
+}
+
+// WrapCommand returns the start command to use. When heap dumps are uploaded, command is moved into a launcher
+// script that runs it as a child process, forwards termination signals, and uploads the dumps once it exits.
+func (h *HeapDump) WrapCommand(command string) (string, error) {
+	if !h.upload {
+		return command, nil
+	}
+
+	binDir := filepath.Join(h.ctx.Stager.DepDir(), "bin")
+	if err := os.MkdirAll(binDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create bin directory: %w", err)
+	}
+
+	// The command runs in a function so that its own quoting and exec are preserved
+	script := fmt.Sprintf(`#!/bin/bash
+# Generated by the Java buildpack: uploads heap dumps to the service tagged %[1]s after the application exits
+start() {
+%[2]s
+}
+
+upload() {
+  local credentials prefix dump name
+  credentials=$(jq -cn 'env.VCAP_SERVICES // "{}" | fromjson | [.[][] | select(any((.tags // [])[]; . == "%[1]s"))][0].credentials // empty')
+  if [ -z "$credentials" ]; then
+    echo "No service tagged %[1]s is bound, keeping heap dumps" >&2
+    return
+  fi
+  field() { echo "$credentials" | jq -r --arg key "$1" '.[$key] // empty'; }
+  curl_version() { curl --version | awk 'NR == 1 { print $2 }'; }
+  prefix=$(jq -rn 'env.VCAP_APPLICATION // "{}" | fromjson | [.space_id // "unknown", .application_id // "unknown"] | join("/")')
+
+  for dump in "%[3]s"/*.hprof; do
+    [ -f "$dump" ] || continue
+    name="$prefix/$(basename "$dump")"
+    echo "Uploading heap dump $name" >&2
+    if [ -n "$(field bucket)" ]; then
+      local region endpoint
+      if [ "$(printf '%%s\n' %[4]s "$(curl_version)" | sort -V | head -n 1)" != %[4]s ]; then
+        echo "curl $(curl_version) cannot sign S3 uploads, %[4]s or later is needed, keeping heap dumps" >&2
+        return
+      fi
+      region=$(field region)
+      region=${region:-us-east-1}
+      endpoint=$(field endpoint)
+      endpoint=${endpoint:-https://s3.$region.amazonaws.com}
+      curl --silent --show-error --fail --aws-sigv4 "aws:amz:$region:s3" --user "$(field access_key_id):$(field secret_access_key)" \
+        --upload-file "$dump" "${endpoint%%/}/$(field bucket)/$name" && rm -f "$dump"
+    elif [ -n "$(field container_url)" ]; then
+      local url base query=
+      url=$(field container_url)
+      # The blob name goes before the SAS token, if the URL has one
+      base=${url%%%%\?*}
+      [[ "$url" == *\?* ]] && query="?${url#*\?}"
+      curl --silent --show-error --fail --header "x-ms-blob-type: BlockBlob" \
+        --upload-file "$dump" "${base%%/}/$name$query" && rm -f "$dump"
+    else
+      echo "Service tagged %[1]s has neither a bucket nor a container_url" >&2
+      return
+    fi
+  done
+}
+
+start &
+pid=$!
+trap 'kill -TERM $pid 2> /dev/null' TERM INT
+wait $pid
+status=$?
+while kill -0 $pid 2> /dev/null; do
+  wait $pid
+  status=$?
+done
+
+upload
+exit $status
+`, HeapDumpStoreTag, command, HeapDumpDirectory, heapDumpSigV4CurlVersion)
+
+	launcher := filepath.Join(binDir, "heap_dump_launcher.sh")
+	if err := os.WriteFile(launcher, []byte(script), 0755); err != nil {
+		return "", fmt.Errorf("failed to write heap dump launcher: %w", err)
+	}
+
+	return h.ctx.Droplet().AbsoluteDep("bin", "heap_dump_launcher.sh"), nil
+}
+
+// heapDumpEnabled returns true if heap dumps are configured or an object store for them is bound
+func heapDumpEnabled(ctx *common.Context) (bool, error) {
+	cfg := heapDumpConfig{}
+	if err := config.Load(ctx.Log, "heap_dump", &cfg); err != nil {
+		return false, err
+	}
+	_, ok := heapDumpStore()
+	return cfg.Enabled || ok, nil
+}
+
+// heapDumpStore returns the bound service tagged heap-dump-store, if any
+func heapDumpStore() (common.VCAPService, bool) {
+	vcapServices, err := common.GetVCAPServices()
+	if err != nil {
+		return common.VCAPService{}, false
+	}
+	for _, services := range vcapServices {
+		for _, service := range services {
+			if service.HasTag(HeapDumpStoreTag) {
+				return service, true
+			}
+		}
+	}
+	return common.VCAPService{}, false
+}
@@ -0,0 +1,183 @@
+package jres_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/jres"
+	"github.com/cloudfoundry/libbuildpack"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Heap Dump", func() {
+	var (
+		ctx      *common.Context
+		depsDir  string
+		heapDump *jres.HeapDump
+	)
+
+	BeforeEach(func() {
+		var err error
+		depsDir, err = os.MkdirTemp("", "deps")
+		Expect(err).NotTo(HaveOccurred())
+
+		logger := libbuildpack.NewLogger(GinkgoWriter)
+		manifest := &libbuildpack.Manifest{}
+		ctx = &common.Context{
+			Stager:   libbuildpack.NewStager([]string{depsDir, "", depsDir, "0"}, logger, manifest),
+			Manifest: manifest,
+			Log:      logger,
+		}
+		heapDump = jres.NewHeapDump(ctx)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(depsDir)
+		os.Unsetenv("JBP_CONFIG_HEAP_DUMP")
+		os.Unsetenv("VCAP_SERVICES")
+	})
+
+	It("is disabled by default", func() {
+		Expect(heapDump.Finalize()).To(Succeed())
+
+		Expect(filepath.Join(depsDir, "0", "java_opts", "05_heap_dump.opts")).NotTo(BeAnExistingFile())
+		Expect(heapDump.WrapCommand("eval exec java")).To(Equal("eval exec java"))
+	})
+
+	It("writes heap dumps under $TMPDIR when enabled", func() {
+		os.Setenv("JBP_CONFIG_HEAP_DUMP", "{enabled: true}")
+
+		Expect(heapDump.Finalize()).To(Succeed())
+
+		opts, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "05_heap_dump.opts"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(opts)).To(Equal("-XX:+HeapDumpOnOutOfMemoryError -XX:HeapDumpPath=$TMPDIR/heapdumps"))
+		Expect(filepath.Join(depsDir, "0", "profile.d", "heap_dump.sh")).To(BeAnExistingFile())
+		Expect(heapDump.WrapCommand("eval exec java")).To(Equal("eval exec java"))
+	})
+
+	It("warns at staging on cflinuxfs3 that S3 uploads need a newer curl", func() {
+		var log bytes.Buffer
+		ctx.Log = libbuildpack.NewLogger(io.MultiWriter(GinkgoWriter, &log))
+		os.Setenv("CF_STACK", "cflinuxfs3")
+		defer os.Unsetenv("CF_STACK")
+		os.Setenv("VCAP_SERVICES", `{"s3": [{"name": "dumps", "tags": ["heap-dump-store"], "credentials": {"bucket": "dumps"}}]}`)
+
+		Expect(heapDump.Finalize()).To(Succeed())
+
+		Expect(log.String()).To(ContainSubstring("The curl of the cflinuxfs3 stack cannot sign S3 uploads, which needs curl 7.75.0 or later"))
+	})
+
+	Context("with a service tagged heap-dump-store", func() {
+		var (
+			server   *httptest.Server
+			requests []*http.Request
+			bodies   []string
+			tmpDir   string
+			path     string
+		)
+
+		BeforeEach(func() {
+			for _, tool := range []string{"bash", "curl", "jq"} {
+				if _, err := exec.LookPath(tool); err != nil {
+					Skip(tool + " is not available")
+				}
+			}
+
+			requests, bodies = nil, nil
+			path = os.Getenv("PATH")
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				requests = append(requests, r)
+				bodies = append(bodies, string(body))
+				w.WriteHeader(http.StatusCreated)
+			}))
+
+			var err error
+			tmpDir, err = os.MkdirTemp("", "tmp")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			server.Close()
+			os.RemoveAll(tmpDir)
+		})
+
+		// runLauncher wraps a command that writes a heap dump and fails, and runs the generated launcher
+		runLauncher := func(credentials string) int {
+			vcapServices := `{"s3": [{"name": "dumps", "tags": ["heap-dump-store"], "credentials": ` + credentials + `}]}`
+			os.Setenv("VCAP_SERVICES", vcapServices)
+			Expect(heapDump.Finalize()).To(Succeed())
+
+			command, err := heapDump.WrapCommand(`mkdir -p "$TMPDIR/heapdumps" && echo dump > "$TMPDIR/heapdumps/java_pid1.hprof" && exit 3`)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(command).To(Equal("/home/vcap/deps/0/bin/heap_dump_launcher.sh"))
+
+			cmd := exec.Command("bash", filepath.Join(depsDir, "0", "bin", "heap_dump_launcher.sh"))
+			cmd.Env = []string{
+				"PATH=" + path,
+				"TMPDIR=" + tmpDir,
+				"VCAP_SERVICES=" + vcapServices,
+				`VCAP_APPLICATION={"space_id": "space", "application_id": "app"}`,
+			}
+			cmd.Stdout, cmd.Stderr = GinkgoWriter, GinkgoWriter
+			err = cmd.Run()
+			exitErr, ok := err.(*exec.ExitError)
+			Expect(ok).To(BeTrue(), "launcher should fail like the command")
+			return exitErr.ExitCode()
+		}
+
+		It("uploads the dump to an S3 bucket and keeps the exit status", func() {
+			Expect(runLauncher(`{"bucket": "dumps", "access_key_id": "key", "secret_access_key": "secret", "region": "eu-west-1", "endpoint": "` + server.URL + `"}`)).To(Equal(3))
+
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].Method).To(Equal(http.MethodPut))
+			Expect(requests[0].URL.Path).To(Equal("/dumps/space/app/java_pid1.hprof"))
+			Expect(requests[0].Header.Get("Authorization")).To(HavePrefix("AWS4-HMAC-SHA256 Credential=key/"))
+			Expect(strings.TrimSpace(bodies[0])).To(Equal("dump"))
+			Expect(filepath.Join(tmpDir, "heapdumps", "java_pid1.hprof")).NotTo(BeAnExistingFile())
+		})
+
+		It("uploads the dump to an Azure blob container", func() {
+			Expect(runLauncher(`{"container_url": "` + server.URL + `/dumps?sv=2022&sig=abc"}`)).To(Equal(3))
+
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].URL.Path).To(Equal("/dumps/space/app/java_pid1.hprof"))
+			Expect(requests[0].URL.RawQuery).To(Equal("sv=2022&sig=abc"))
+			Expect(requests[0].Header.Get("x-ms-blob-type")).To(Equal("BlockBlob"))
+		})
+
+		It("uploads the dump to an Azure blob container URL without a SAS token", func() {
+			Expect(runLauncher(`{"container_url": "` + server.URL + `/dumps/"}`)).To(Equal(3))
+
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].URL.Path).To(Equal("/dumps/space/app/java_pid1.hprof"))
+			Expect(requests[0].URL.RawQuery).To(BeEmpty())
+			Expect(strings.TrimSpace(bodies[0])).To(Equal("dump"))
+		})
+
+		It("keeps the dump when curl cannot sign S3 uploads", func() {
+			// cflinuxfs3 has curl 7.58.0, which does not know --aws-sigv4
+			realCurl, err := exec.LookPath("curl")
+			Expect(err).NotTo(HaveOccurred())
+			binDir := filepath.Join(tmpDir, "bin")
+			Expect(os.MkdirAll(binDir, 0755)).To(Succeed())
+			curl := "#!/bin/bash\n[ \"$1\" = --version ] && echo 'curl 7.58.0 (x86_64-pc-linux-gnu) libcurl/7.58.0' && exit 0\nexec " + realCurl + " \"$@\"\n"
+			Expect(os.WriteFile(filepath.Join(binDir, "curl"), []byte(curl), 0755)).To(Succeed())
+			path = binDir + string(os.PathListSeparator) + path
+
+			Expect(runLauncher(`{"bucket": "dumps", "access_key_id": "key", "secret_access_key": "secret", "endpoint": "` + server.URL + `"}`)).To(Equal(3))
+
+			Expect(requests).To(BeEmpty())
+			Expect(filepath.Join(tmpDir, "heapdumps", "java_pid1.hprof")).To(BeAnExistingFile())
+		})
+	})
+})
@@ -3,13 +3,16 @@ package jres
 import (
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
-// IBMJRE implements the JRE interface for IBM JRE
-// IBM JRE requires a user-provided repository via JBP_CONFIG_IBM_JRE environment variable
-// IBM JRE adds specific JVM options: -Xtune:virtualized -Xshareclasses:none
+// IBMJRE implements the JRE interface for the IBM JREs, which are built on the Eclipse OpenJ9 JVM
+// Both the IBM JRE and IBM Semeru Runtimes require a user-provided repository and are selected via their
+// JBP_CONFIG_<JRE> environment variable. OpenJ9 has no metaspace or code cache limits, so the memory calculator
+// uses the OpenJ9 profile, and it can keep loaded classes in a shared class cache sized with -Xscmx.
 type IBMJRE struct {
 	ctx              *common.Context
 	jreDir           string
@@ -18,46 +21,90 @@ type IBMJRE struct {
 	memoryCalc       *MemoryCalculator
 	jvmkill          *JVMKillAgent
 	installedVersion string
+
+	versionSelection
+
+	// id is the JRE's manifest dependency name, e.g. ibm
+	id string
+	// name is the name logged during staging
+	name string
+	// defaultJavaVersion is used if the Java version cannot be read from the installed JRE
+	defaultJavaVersion int
+	// sharedClasses enables the shared class cache unless configured otherwise
+	sharedClasses bool
+}
+
+// ibmConfig is the part of JBP_CONFIG_IBM_JRE and JBP_CONFIG_SEMERU_JRE read by the IBM JREs
+type ibmConfig struct {
+	SharedClasses struct {
+		Enabled   bool   `yaml:"enabled"`
+		CacheSize string `yaml:"cache_size"`
+	} `yaml:"shared_classes"`
+}
+
+// defaultIBMConfig returns the built-in ibmConfig, which enables the shared class cache if sharedClasses is set
+func defaultIBMConfig(sharedClasses bool) ibmConfig {
+	cfg := ibmConfig{}
+	cfg.SharedClasses.Enabled = sharedClasses
+	cfg.SharedClasses.CacheSize = "64M"
+	return cfg
 }
 
 // NewIBMJRE creates a new IBM JRE provider
+// It disables the shared class cache by default
 func NewIBMJRE(ctx *common.Context) *IBMJRE {
-	jreDir := filepath.Join(ctx.Stager.DepDir(), "jre")
+	return &IBMJRE{
+		ctx:                ctx,
+		jreDir:             filepath.Join(ctx.Stager.DepDir(), "jre"),
+		id:                 "ibm",
+		name:               "IBM JRE",
+		defaultJavaVersion: 8,
+	}
+}
 
+// NewSemeruJRE creates a new IBM Semeru JRE provider
+// It enables the shared class cache by default
+func NewSemeruJRE(ctx *common.Context) *IBMJRE {
 	return &IBMJRE{
-		ctx:    ctx,
-		jreDir: jreDir,
+		ctx:                ctx,
+		jreDir:             filepath.Join(ctx.Stager.DepDir(), "jre"),
+		id:                 "semeru",
+		name:               "IBM Semeru",
+		defaultJavaVersion: 17,
+		sharedClasses:      true,
 	}
 }
 
 // Name returns the name of this JRE provider
 func (i *IBMJRE) Name() string {
-	return "IBM JRE"
+	return i.name
 }
 
-// Detect returns true if IBM JRE should be used
-// IBM JRE requires explicit configuration via JBP_CONFIG_IBM_JRE environment variable
+// Detect returns true if the JRE should be used
+// IBM JREs require explicit configuration via their JBP_CONFIG_<JRE> environment variable
 func (i *IBMJRE) Detect() (bool, error) {
-	return DetectJREByEnv("ibm"), nil
+	return DetectJREByEnv(i.id), nil
 }
 
 // Supply installs the IBM JRE and its components
 func (i *IBMJRE) Supply() error {
-	i.ctx.Log.BeginStep("Installing IBM JRE")
+	i.ctx.Log.BeginStep("Installing %s", i.name)
 
 	// Determine version
-	dep, err := GetJREVersion(i.ctx, "ibm")
+	dep, err := GetJREVersion(i.ctx, i.id, i.selectedVersion)
 	if err != nil {
-		return fmt.Errorf("failed to determine IBM JRE version from manifest: %w", err)
+		return fmt.Errorf("failed to determine %s version from manifest: %w", i.name, err)
 	}
 
 	i.version = dep.Version
-	i.ctx.Log.Info("Installing IBM JRE (%s)", i.version)
+	i.ctx.Log.Info("Installing %s (%s)", i.name, i.version)
 
 	// Install JRE
-	if err := i.ctx.Installer.InstallDependency(dep, i.jreDir); err != nil {
-		return fmt.Errorf("failed to install IBM JRE: %w", err)
+	installed, err := InstallJRE(i.ctx, dep, i.jreDir)
+	if err != nil {
+		return fmt.Errorf("failed to install %s: %w", i.name, err)
 	}
+	i.version = installed.Version
 
 	// Find the actual JAVA_HOME (handle nested directories from tar extraction)
 	javaHome, err := i.findJavaHome()
@@ -78,7 +125,7 @@ func (i *IBMJRE) Supply() error {
 	javaMajorVersion, err := common.DetermineJavaVersion(javaHome)
 	if err != nil {
 		i.ctx.Log.Warning("Could not determine Java version: %s", err.Error())
-		javaMajorVersion = 8 // IBM JRE default
+		javaMajorVersion = i.defaultJavaVersion
 	}
 	i.ctx.Log.Info("Detected Java major version: %d", javaMajorVersion)
 
@@ -91,19 +138,27 @@ func (i *IBMJRE) Supply() error {
 
 	// Install Memory Calculator
 	i.memoryCalc = NewMemoryCalculator(i.ctx, i.jreDir, i.version, javaMajorVersion)
+	if err := i.memoryCalc.LoadConfig(i.id + "_jre"); err != nil {
+		return fmt.Errorf("invalid memory calculator configuration: %w", err)
+	}
 	if err := i.memoryCalc.Supply(); err != nil {
 		i.ctx.Log.Warning("Failed to install Memory Calculator: %s (continuing)", err.Error())
 		// Non-fatal - continue without memory calculator
 	}
 
-	i.ctx.Log.Info("IBM JRE installation complete")
+	i.ctx.Log.Info("%s installation complete", i.name)
 	return nil
 }
 
 // Finalize performs final JRE configuration
-// Adds IBM-specific JVM options: -Xtune:virtualized -Xshareclasses:none
+// Adds OpenJ9 options: -Xtune:virtualized and the shared class cache
 func (i *IBMJRE) Finalize() error {
-	i.ctx.Log.BeginStep("Finalizing IBM JRE configuration")
+	i.ctx.Log.BeginStep("Finalizing %s configuration", i.name)
+
+	cfg, err := i.loadConfig()
+	if err != nil {
+		return err
+	}
 
 	// Find the actual JAVA_HOME (needed if finalize is called on a fresh instance)
 	if i.javaHome == "" {
@@ -126,7 +181,7 @@ func (i *IBMJRE) Finalize() error {
 	}
 
 	// Determine Java major version for memory calculator
-	javaMajorVersion := 8 // IBM JRE default
+	javaMajorVersion := i.defaultJavaVersion
 	if i.javaHome != "" {
 		if ver, err := common.DetermineJavaVersion(i.javaHome); err == nil {
 			javaMajorVersion = ver
@@ -147,24 +202,30 @@ func (i *IBMJRE) Finalize() error {
 	// Reconstruct Memory Calculator component if not already set
 	if i.memoryCalc == nil {
 		i.memoryCalc = NewMemoryCalculator(i.ctx, i.jreDir, i.version, javaMajorVersion)
+		if err := i.memoryCalc.LoadConfig(i.id + "_jre"); err != nil {
+			return fmt.Errorf("invalid memory calculator configuration: %w", err)
+		}
 	}
 
+	// OpenJ9 sizes the heap with -Xmx like HotSpot, but has no metaspace or code cache limits
+	cacheSize := ""
+	if cfg.SharedClasses.Enabled {
+		cacheSize = cfg.SharedClasses.CacheSize
+	}
+	i.memoryCalc.SetProfile(OpenJ9MemoryProfile(cacheSize))
+
 	// Finalize Memory Calculator
 	if err := i.memoryCalc.Finalize(); err != nil {
 		i.ctx.Log.Warning("Failed to finalize Memory Calculator: %s", err.Error())
 		// Non-fatal
 	}
 
-	// Add IBM-specific JVM options
-	// -Xtune:virtualized - Optimizes for virtualized environments
-	// -Xshareclasses:none - Disables class data sharing (not supported in containers)
-	ibmOpts := "-Xtune:virtualized -Xshareclasses:none"
-	if err := WriteJavaOpts(i.ctx, ibmOpts); err != nil {
-		i.ctx.Log.Warning("Failed to write IBM JVM options: %s", err.Error())
+	if err := WriteJavaOpts(i.ctx, ibmJavaOpts(cfg)); err != nil {
+		i.ctx.Log.Warning("Failed to write %s JVM options: %s", i.name, err.Error())
 		// Non-fatal
 	}
 
-	i.ctx.Log.Info("IBM JRE finalization complete")
+	i.ctx.Log.Info("%s finalization complete", i.name)
 	return nil
 }
 
@@ -186,20 +247,41 @@ func (i *IBMJRE) MemoryCalculatorCommand() string {
 	return i.memoryCalc.GetCalculatorCommand()
 }
 
+// loadConfig reads JBP_CONFIG_<JRE>, which also carries the jre and memory_calculator settings
+func (i *IBMJRE) loadConfig() (ibmConfig, error) {
+	cfg := defaultIBMConfig(i.sharedClasses)
+	if err := config.Decode(i.id+"_jre", &cfg); err != nil {
+		return ibmConfig{}, err
+	}
+	return cfg, nil
+}
+
+// ibmJavaOpts returns the OpenJ9 options for cfg
+// -Xtune:virtualized optimizes for virtualized environments
+// -Xshareclasses keeps a shared class cache in the application's tmp directory, which survives restarts of the
+// JVM within the same container; nonfatal starts the JVM even if the cache cannot be created
+func ibmJavaOpts(cfg ibmConfig) string {
+	opts := []string{"-Xtune:virtualized"}
+	if cfg.SharedClasses.Enabled {
+		opts = append(opts, "-Xshareclasses:name=java-buildpack,cacheDir=$HOME/tmp/openj9-scc,nonfatal")
+	} else {
+		opts = append(opts, "-Xshareclasses:none")
+	}
+	return strings.Join(opts, " ")
+}
+
 // findJavaHome locates the actual JAVA_HOME directory after extraction
-// IBM JRE tarballs usually extract to ibm-java-* or jre subdirectories
+// IBM JRE tarballs usually extract to ibm-java-* or jre subdirectories, Semeru tarballs to jdk-* or jdk-*-jre
 func (i *IBMJRE) findJavaHome() (string, error) {
 	entries, err := os.ReadDir(i.jreDir)
 	if err != nil {
 		return "", fmt.Errorf("failed to read JRE directory: %w", err)
 	}
 
-	// Look for ibm-java-* or jre subdirectory
 	for _, entry := range entries {
 		if entry.IsDir() {
 			name := entry.Name()
-			// Check for common IBM JRE directory patterns
-			if (len(name) > 8 && name[:8] == "ibm-java") || name == "jre" {
+			if strings.HasPrefix(name, "ibm-java") || strings.HasPrefix(name, "jdk") || strings.HasPrefix(name, "jre") {
 				path := filepath.Join(i.jreDir, name)
 				// Verify it has a bin directory with java
 				if _, err := os.Stat(filepath.Join(path, "bin", "java")); err == nil {
@@ -0,0 +1,128 @@
+package jres_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/jres"
+	"github.com/cloudfoundry/libbuildpack"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("IBM JREs", func() {
+	var (
+		ctx      *common.Context
+		buildDir string
+		depsDir  string
+		jreDir   string
+	)
+
+	BeforeEach(func() {
+		var err error
+		buildDir, err = os.MkdirTemp("", "build")
+		Expect(err).NotTo(HaveOccurred())
+		depsDir, err = os.MkdirTemp("", "deps")
+		Expect(err).NotTo(HaveOccurred())
+
+		// Simulate an installed OpenJ9 JRE with a memory calculator that prints HotSpot options
+		jreDir = filepath.Join(depsDir, "0", "jre")
+		javaHome := filepath.Join(jreDir, "jdk-17.0.13+11-jre")
+		Expect(os.MkdirAll(filepath.Join(javaHome, "bin"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(javaHome, "bin", "java"), []byte("#!/bin/sh\n"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(javaHome, "release"), []byte("JAVA_VERSION=\"17.0.13\"\n"), 0644)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(jreDir, "bin"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(jreDir, "bin", "java-buildpack-memory-calculator-4.2.0"),
+			[]byte("#!/bin/sh\necho '-XX:MaxDirectMemorySize=10M -XX:MaxMetaspaceSize=99199K -XX:ReservedCodeCacheSize=240M -XX:CompressedClassSpaceSize=18134K -Xss1M -Xmx368042K'\n"),
+			0755)).To(Succeed())
+
+		logger := libbuildpack.NewLogger(GinkgoWriter)
+		manifest := &libbuildpack.Manifest{}
+		ctx = &common.Context{
+			Stager:    libbuildpack.NewStager([]string{buildDir, "", depsDir, "0"}, logger, manifest),
+			Manifest:  manifest,
+			Installer: &libbuildpack.Installer{},
+			Log:       logger,
+			Command:   &libbuildpack.Command{},
+		}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(buildDir)
+		os.RemoveAll(depsDir)
+		os.Unsetenv("JBP_CONFIG_IBM_JRE")
+		os.Unsetenv("JBP_CONFIG_SEMERU_JRE")
+		os.Unsetenv("JAVA_HOME")
+	})
+
+	// calculatedJavaOpts runs the memory calculator script written by Finalize
+	calculatedJavaOpts := func() string {
+		script := filepath.Join(depsDir, "0", "bin", "memory_calculator.sh")
+		output, err := exec.Command("bash", "-c", `MEMORY_LIMIT=1G; JAVA_OPTS=""; source "$0" > /dev/null; echo "$JAVA_OPTS"`, script).CombinedOutput()
+		Expect(err).NotTo(HaveOccurred(), string(output))
+		return string(output)
+	}
+
+	It("is selected with JBP_CONFIG_SEMERU_JRE", func() {
+		semeru := jres.NewSemeruJRE(ctx)
+		Expect(semeru.Detect()).To(BeFalse())
+
+		os.Setenv("JBP_CONFIG_SEMERU_JRE", "{jre: {version: 17.+}}")
+		Expect(semeru.Detect()).To(BeTrue())
+		Expect(semeru.Name()).To(Equal("IBM Semeru"))
+	})
+
+	It("replaces HotSpot memory options with OpenJ9 ones and enables the shared class cache", func() {
+		semeru := jres.NewSemeruJRE(ctx)
+		Expect(semeru.Finalize()).To(Succeed())
+
+		Expect(calculatedJavaOpts()).To(Equal(" -XX:MaxDirectMemorySize=10M -Xss1M -Xmx368042K -Xscmx64M\n"))
+		Expect(semeru.MemoryCalculatorCommand()).To(ContainSubstring("-Xscmx64M"))
+
+		opts, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "05_jre.opts"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(opts)).To(Equal("-XX:ActiveProcessorCount=${CONTAINER_CPU_COUNT:-$(nproc)} -Xtune:virtualized -Xshareclasses:name=java-buildpack,cacheDir=$HOME/tmp/openj9-scc,nonfatal"))
+	})
+
+	It("disables the shared class cache when configured", func() {
+		os.Setenv("JBP_CONFIG_SEMERU_JRE", "{shared_classes: {enabled: false}}")
+
+		Expect(jres.NewSemeruJRE(ctx).Finalize()).To(Succeed())
+
+		Expect(calculatedJavaOpts()).To(Equal(" -XX:MaxDirectMemorySize=10M -Xss1M -Xmx368042K\n"))
+		opts, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "05_jre.opts"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(opts)).To(Equal("-XX:ActiveProcessorCount=${CONTAINER_CPU_COUNT:-$(nproc)} -Xtune:virtualized -Xshareclasses:none"))
+	})
+
+	It("is selected with JBP_CONFIG_IBM_JRE", func() {
+		ibm := jres.NewIBMJRE(ctx)
+		Expect(ibm.Detect()).To(BeFalse())
+
+		os.Setenv("JBP_CONFIG_IBM_JRE", "{jre: {version: 8.+}}")
+		Expect(ibm.Detect()).To(BeTrue())
+		Expect(ibm.Name()).To(Equal("IBM JRE"))
+	})
+
+	It("gives the IBM JRE OpenJ9 memory options without the shared class cache", func() {
+		Expect(jres.NewIBMJRE(ctx).Finalize()).To(Succeed())
+
+		Expect(calculatedJavaOpts()).To(Equal(" -XX:MaxDirectMemorySize=10M -Xss1M -Xmx368042K\n"))
+		opts, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "05_jre.opts"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(opts)).To(Equal("-XX:ActiveProcessorCount=${CONTAINER_CPU_COUNT:-$(nproc)} -Xtune:virtualized -Xshareclasses:none"))
+	})
+
+	It("enables the shared class cache of the IBM JRE when configured", func() {
+		os.Setenv("JBP_CONFIG_IBM_JRE", "{shared_classes: {enabled: true, cache_size: 128M}}")
+
+		Expect(jres.NewIBMJRE(ctx).Finalize()).To(Succeed())
+
+		Expect(calculatedJavaOpts()).To(Equal(" -XX:MaxDirectMemorySize=10M -Xss1M -Xmx368042K -Xscmx128M\n"))
+		opts, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "05_jre.opts"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(opts)).To(Equal("-XX:ActiveProcessorCount=${CONTAINER_CPU_COUNT:-$(nproc)} -Xtune:virtualized -Xshareclasses:name=java-buildpack,cacheDir=$HOME/tmp/openj9-scc,nonfatal"))
+	})
+})
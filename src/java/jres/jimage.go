@@ -0,0 +1,125 @@
+package jres
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// jimageMagic starts the lib/modules file of Java 9 and later JREs, which holds the classes of the platform modules
+const jimageMagic = 0xCAFEDADA
+
+// jimageHeaderSize is the size of the header: the magic, the version, the flags, the resource count, the table
+// length and the sizes of the locations and of the strings, each a 32-bit integer in the byte order of the platform
+const jimageHeaderSize = 7 * 4
+
+// Attribute kinds of a location in a jimage file
+const (
+	jimageAttributeEnd       = 0
+	jimageAttributeExtension = 4
+	jimageAttributeCount     = 8
+)
+
+// findModulesImage returns the lib/modules file of the JRE installed in jreDir, or of the JDK directory the archive
+// extracted into it
+func findModulesImage(jreDir string) (string, error) {
+	for _, pattern := range []string{filepath.Join(jreDir, "lib", "modules"), filepath.Join(jreDir, "*", "lib", "modules")} {
+		matches, _ := filepath.Glob(pattern)
+		if len(matches) > 0 {
+			return matches[0], nil
+		}
+	}
+	return "", fmt.Errorf("no lib/modules in %s", jreDir)
+}
+
+// countJImageClasses returns the number of classes in the jimage file at path. Only the header and the index are
+// read, not the resources.
+func countJImageClasses(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	header := make([]byte, jimageHeaderSize)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return 0, fmt.Errorf("failed to read jimage header: %w", err)
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if order.Uint32(header) != jimageMagic {
+		order = binary.BigEndian
+		if order.Uint32(header) != jimageMagic {
+			return 0, fmt.Errorf("%s is not a jimage file", path)
+		}
+	}
+	tableLength := int64(order.Uint32(header[16:]))
+	locationsSize := int64(order.Uint32(header[20:]))
+	stringsSize := int64(order.Uint32(header[24:]))
+
+	// The index follows the header: the redirect table, the offsets of the locations, the locations and the strings
+	indexSize := 8*tableLength + locationsSize + stringsSize
+	if info, err := file.Stat(); err != nil || jimageHeaderSize+indexSize > info.Size() {
+		return 0, fmt.Errorf("%s has a truncated jimage index", path)
+	}
+	index := make([]byte, indexSize)
+	if _, err := io.ReadFull(file, index); err != nil {
+		return 0, fmt.Errorf("failed to read jimage index: %w", err)
+	}
+	offsets := index[4*tableLength : 8*tableLength]
+	locations := index[8*tableLength : 8*tableLength+locationsSize]
+	names := index[8*tableLength+locationsSize:]
+
+	count := 0
+	for i := int64(0); i < tableLength; i++ {
+		attributes, err := jimageAttributes(locations, int64(order.Uint32(offsets[4*i:])))
+		if err != nil {
+			return 0, err
+		}
+		if jimageString(names, attributes[jimageAttributeExtension]) == "class" {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// jimageAttributes decodes the attributes of the location at offset. Each attribute is a byte with its kind in the
+// upper five bits and its length minus one in the lower three, followed by its big-endian value.
+func jimageAttributes(locations []byte, offset int64) ([jimageAttributeCount]uint64, error) {
+	var attributes [jimageAttributeCount]uint64
+	for offset < int64(len(locations)) {
+		kind := locations[offset] >> 3
+		if kind == jimageAttributeEnd {
+			return attributes, nil
+		}
+		length := int64(locations[offset]&0x7) + 1
+		offset++
+		if offset+length > int64(len(locations)) {
+			break
+		}
+		var value uint64
+		for _, b := range locations[offset : offset+length] {
+			value = value<<8 | uint64(b)
+		}
+		if kind < jimageAttributeCount {
+			attributes[kind] = value
+		}
+		offset += length
+	}
+	return attributes, fmt.Errorf("truncated jimage location")
+}
+
+// jimageString returns the null-terminated string at offset
+func jimageString(names []byte, offset uint64) string {
+	if offset >= uint64(len(names)) {
+		return ""
+	}
+	value := names[offset:]
+	if end := bytes.IndexByte(value, 0); end >= 0 {
+		value = value[:end]
+	}
+	return string(value)
+}
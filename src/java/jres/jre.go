@@ -4,16 +4,19 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/components"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
 	"github.com/cloudfoundry/libbuildpack"
 )
 
 // Default JRE provider
 // Change this value to set which JRE is used by default when no JRE is explicitly configured
-// Valid values: "openjdk", "zulu", "sapmachine", "graalvm", "oracle", "ibm", "zing"
+// Valid values: "openjdk", "zulu", "sapmachine", "graalvm", "oracle", "ibm", "semeru", "zing"
 const DefaultJREProvider = "openjdk"
 
 // Memory calculator constants
@@ -22,6 +25,21 @@ const (
 	DefaultHeadroom     = 0
 	DefaultClassCount   = 18000 // Default class count when counting fails (after 35% factor: ~6300)
 	Java9ClassCount     = 42215 // Classes in Java 9+ JRE
+	// Java9ClassCountMaxVersion is the last Java version that Java9ClassCount applies to. The class libraries of
+	// later versions keep growing, e.g. by the Class-File and Foreign Function & Memory APIs, so their classes are
+	// counted in lib/modules of the installed JRE.
+	Java9ClassCountMaxVersion = 21
+	// DefaultClassCountSizeLimit is the size of an application in bytes above which its classes are estimated from
+	// its size rather than counted. Counting reads only the central directory of each JAR, so classes are always
+	// counted unless class_count_size_limit is configured.
+	DefaultClassCountSizeLimit = 0
+	// EstimatedClassesPerMB is the number of classes per MB of application files assumed by the estimate, which
+	// is that of a typical JAR of compiled classes. Resources and native libraries make it overestimate, which
+	// reserves more metaspace than needed rather than too little.
+	EstimatedClassesPerMB = 400
+	// MaxEstimatedClasses caps the classes the estimate assumes for the application, so that large resources do
+	// not reserve metaspace without bound
+	MaxEstimatedClasses = 100000
 )
 
 // JRE represents a Java Runtime Environment provider
@@ -54,14 +72,48 @@ type JRE interface {
 type Registry struct {
 	ctx        *common.Context
 	providers  []JRE
+	ids        map[JRE]string
 	defaultJRE JRE
 }
 
+// jreSelectionConfig is the provider-independent JRE selection in config/jre.yml and JBP_CONFIG_JRE, e.g.
+// '{provider: zulu, version: 17.+}'
+type jreSelectionConfig struct {
+	// Provider is the id of the JRE to use, e.g. "zulu", regardless of the provider-specific variables
+	Provider string `yaml:"provider"`
+	// Version constrains the version of the selected JRE unless its own JBP_CONFIG_<JRE> sets one
+	Version string `yaml:"version"`
+	// Priority lists provider ids whose JBP_CONFIG_<JRE> variables are consulted first
+	Priority []string `yaml:"priority"`
+}
+
+// appliesTo returns true if the selection constrains the version of the JRE registered with id
+func (c jreSelectionConfig) appliesTo(id string) bool {
+	return strings.TrimSpace(c.Version) != "" && (c.Provider == "" || strings.EqualFold(c.Provider, id))
+}
+
+// versionSelector is implemented by JREs whose version JBP_CONFIG_JRE can constrain
+type versionSelector interface {
+	selectVersion(pattern string)
+}
+
+// versionSelection holds the version pattern JBP_CONFIG_JRE selects for a JRE. Registry.Detect sets it on the JRE
+// it returns, so that JBP_CONFIG_JRE is loaded once per staging, and the JRE passes it to GetJREVersion.
+type versionSelection struct {
+	selectedVersion string
+}
+
+// selectVersion sets the version pattern selected by JBP_CONFIG_JRE
+func (v *versionSelection) selectVersion(pattern string) {
+	v.selectedVersion = pattern
+}
+
 // NewRegistry creates a new JRE registry
 func NewRegistry(ctx *common.Context) *Registry {
 	return &Registry{
 		ctx:       ctx,
 		providers: []JRE{},
+		ids:       map[JRE]string{},
 	}
 }
 
@@ -70,6 +122,12 @@ func (r *Registry) Register(jre JRE) {
 	r.providers = append(r.providers, jre)
 }
 
+// RegisterAs adds a JRE provider that can be selected with the given id in JBP_CONFIG_JRE
+func (r *Registry) RegisterAs(id string, jre JRE) {
+	r.Register(jre)
+	r.ids[jre] = id
+}
+
 // SetDefault sets the default JRE to use when no JRE is explicitly configured
 func (r *Registry) SetDefault(jre JRE) {
 	r.defaultJRE = jre
@@ -79,7 +137,8 @@ func (r *Registry) SetDefault(jre JRE) {
 // This ensures Supply and Finalize phases use the same detection order.
 // The default JRE is determined by the DefaultJREProvider constant.
 func (r *Registry) RegisterStandardJREs() {
-	// Create all JRE providers
+	// Create all JRE providers, in the order their JBP_CONFIG_<JRE> variables are consulted
+	ids := []string{"openjdk", "zulu", "sapmachine", "graalvm", "oracle", "ibm", "semeru", "zing"}
 	jreProviders := map[string]JRE{
 		"openjdk":    NewOpenJDKJRE(r.ctx),
 		"zulu":       NewZuluJRE(r.ctx),
@@ -87,6 +146,7 @@ func (r *Registry) RegisterStandardJREs() {
 		"graalvm":    NewGraalVMJRE(r.ctx),
 		"oracle":     NewOracleJRE(r.ctx),
 		"ibm":        NewIBMJRE(r.ctx),
+		"semeru":     NewSemeruJRE(r.ctx),
 		"zing":       NewZingJRE(r.ctx),
 	}
 
@@ -99,8 +159,8 @@ func (r *Registry) RegisterStandardJREs() {
 	r.SetDefault(defaultJRE)
 
 	// Register all JREs
-	for _, jre := range jreProviders {
-		r.Register(jre)
+	for _, id := range ids {
+		r.RegisterAs(id, jreProviders[id])
 	}
 }
 
@@ -116,27 +176,74 @@ func (r *Registry) Get(name string) JRE {
 }
 
 // Detect finds the JRE provider that should be used
+// JBP_CONFIG_JRE's provider takes precedence over JBP_JRE (e.g. "zulu"), the first JRE of the Ruby buildpack's
+// JBP_CONFIG_COMPONENTS and the provider-specific JBP_CONFIG_<JRE> variables, which are consulted in JBP_CONFIG_JRE's priority order and then in registration order
+// JREs that JBP_CONFIG_COMPONENTS excludes, e.g. '{jres: ["-ZuluJRE"]}', are not detected
 // If a JRE is explicitly configured, it uses that JRE and fails if detection errors
 // If no JRE is explicitly configured, it uses the configured default JRE
 // Returns the JRE, its name, and any error
 func (r *Registry) Detect() (JRE, string, error) {
+	selection := jreSelectionConfig{}
+	if err := config.Load(r.ctx.Log, "jre", &selection); err != nil {
+		return nil, "", err
+	}
+
+	jre, err := r.detect(selection)
+	if err != nil {
+		return nil, "", err
+	}
+	if selector, ok := jre.(versionSelector); ok && selection.appliesTo(r.ids[jre]) {
+		selector.selectVersion(strings.TrimSpace(selection.Version))
+	}
+	return jre, jre.Name(), nil
+}
+
+// detect returns the JRE provider selected by selection, JBP_JRE, JBP_CONFIG_COMPONENTS, the provider-specific
+// variables or the default, in that order
+func (r *Registry) detect(selection jreSelectionConfig) (JRE, error) {
 	var detectionErrors []error
 
-	// Check for deprecated JBP_CONFIG_COMPONENTS usage
-	if componentsEnv := os.Getenv("JBP_CONFIG_COMPONENTS"); componentsEnv != "" {
-		r.ctx.Log.Warning("JBP_CONFIG_COMPONENTS is deprecated for JRE selection and will be ignored")
-		r.ctx.Log.Warning("Use JRE-specific environment variables instead:")
-		r.ctx.Log.Warning("  - JBP_CONFIG_OPEN_JDK_JRE for OpenJDK")
-		r.ctx.Log.Warning("  - JBP_CONFIG_SAP_MACHINE_JRE for SapMachine")
-		r.ctx.Log.Warning("  - JBP_CONFIG_ZULU_JRE for Zulu")
-		r.ctx.Log.Warning("  - JBP_CONFIG_GRAAL_VM_JRE for GraalVM")
-		r.ctx.Log.Warning("  - JBP_CONFIG_IBM_JRE for IBM Semeru")
-		r.ctx.Log.Warning("  - JBP_CONFIG_ORACLE_JRE for Oracle")
-		r.ctx.Log.Warning("  - JBP_CONFIG_ZING_JRE for Azul Platform Prime")
+	if selection.Provider != "" {
+		jre, err := r.lookup(selection.Provider, config.EnvVar("jre"))
+		if err != nil {
+			return nil, err
+		}
+		r.ctx.Log.Info("Using JRE %s selected by %s", jre.Name(), config.EnvVar("jre"))
+		return jre, nil
+	}
+
+	if id := os.Getenv("JBP_JRE"); id != "" {
+		jre, err := r.lookup(id, "JBP_JRE")
+		if err != nil {
+			return nil, err
+		}
+		r.ctx.Log.Info("Using JRE %s selected by JBP_JRE", jre.Name())
+		return jre, nil
+	}
+
+	listed, err := r.jreComponents()
+	if err != nil {
+		return nil, err
+	}
+	jre, err := r.fromComponents(listed)
+	if err != nil {
+		return nil, err
+	}
+	if jre != nil {
+		r.ctx.Log.Info("Using JRE %s selected by JBP_CONFIG_COMPONENTS", jre.Name())
+		return jre, nil
+	}
+
+	providers, err := r.prioritized(selection.Priority)
+	if err != nil {
+		return nil, err
 	}
 
 	// Check if any JRE is explicitly configured
-	for _, jre := range r.providers {
+	for _, jre := range providers {
+		if !r.allows(listed, jre) {
+			continue
+		}
 		detected, err := jre.Detect()
 		if err != nil {
 			// Collect detection errors - if a JRE is explicitly configured but fails to detect,
@@ -145,7 +252,7 @@ func (r *Registry) Detect() (JRE, string, error) {
 			continue
 		}
 		if detected {
-			return jre, jre.Name(), nil
+			return jre, nil
 		}
 	}
 
@@ -156,18 +263,126 @@ func (r *Registry) Detect() (JRE, string, error) {
 		for _, err := range detectionErrors {
 			r.ctx.Log.Error("  - %s", err.Error())
 		}
-		return nil, "", fmt.Errorf("JRE detection failed with %d error(s)", len(detectionErrors))
+		return nil, fmt.Errorf("JRE detection failed with %d error(s)", len(detectionErrors))
 	}
 
 	// No explicit configuration found, use default JRE
 	if r.defaultJRE != nil {
+		if !r.allows(listed, r.defaultJRE) {
+			return nil, fmt.Errorf("the default JRE %s is excluded by JBP_CONFIG_COMPONENTS and no other JRE is configured",
+				r.defaultJRE.Name())
+		}
 		r.ctx.Log.Info("No JRE explicitly configured, using default: %s", r.defaultJRE.Name())
-		return r.defaultJRE, r.defaultJRE.Name(), nil
+		return r.defaultJRE, nil
 	}
 
 	// No JRE found and no default configured - this is an error condition
 	// A Java application cannot run without a JRE
-	return nil, "", fmt.Errorf("no JRE found and no default JRE configured")
+	return nil, fmt.Errorf("no JRE found and no default JRE configured")
+}
+
+// lookup returns the provider registered with id, which was read from source
+func (r *Registry) lookup(id, source string) (JRE, error) {
+	var known []string
+	for _, jre := range r.providers {
+		if registered, ok := r.ids[jre]; ok {
+			if strings.EqualFold(registered, id) {
+				return jre, nil
+			}
+			known = append(known, registered)
+		}
+	}
+	return nil, fmt.Errorf("unknown JRE provider %q in %s, valid values: %s", id, source, strings.Join(known, ", "))
+}
+
+// jreComponentIDs maps the Ruby buildpack's JRE component class names to provider ids
+var jreComponentIDs = map[string]string{
+	"OpenJdkJRE":    "openjdk",
+	"ZuluJRE":       "zulu",
+	"SapMachineJRE": "sapmachine",
+	"GraalVmJRE":    "graalvm",
+	"OracleJRE":     "oracle",
+	"IbmJRE":        "ibm",
+	"SemeruJRE":     "semeru",
+	"ZingJRE":       "zing",
+}
+
+// jreComponents returns the jres list of JBP_CONFIG_COMPONENTS
+func (r *Registry) jreComponents() (components.Selection, error) {
+	cfg, err := components.Load(r.ctx.Log)
+	if err != nil {
+		return components.Selection{}, err
+	}
+	selection := components.NewSelection("JRE", cfg.JREs)
+
+	known := make([]string, 0, len(jreComponentIDs))
+	for name := range jreComponentIDs {
+		known = append(known, name)
+	}
+	sort.Strings(known)
+	return selection, selection.Validate(known)
+}
+
+// fromComponents returns the provider of the first JRE included by JBP_CONFIG_COMPONENTS, or nil if it includes
+// none. Class names may be given with or without the JavaBuildpack::Jre:: module prefix.
+func (r *Registry) fromComponents(selection components.Selection) (JRE, error) {
+	if len(selection.Included) == 0 {
+		return nil, nil
+	}
+
+	name := selection.Included[0]
+	if len(selection.Included) > 1 {
+		r.ctx.Log.Warning("JBP_CONFIG_COMPONENTS lists %d JREs, using the first: %s", len(selection.Included), name)
+	}
+	return r.lookup(jreComponentID(name), "JBP_CONFIG_COMPONENTS")
+}
+
+// allows returns true if JBP_CONFIG_COMPONENTS does not exclude jre
+func (r *Registry) allows(selection components.Selection, jre JRE) bool {
+	for name, id := range jreComponentIDs {
+		if id == r.ids[jre] && !selection.Allows(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// jreComponentID returns the provider id of the JRE component class name, ignoring case
+func jreComponentID(name string) string {
+	for class, id := range jreComponentIDs {
+		if strings.EqualFold(class, name) {
+			return id
+		}
+	}
+	return ""
+}
+
+// prioritized returns the providers listed in priority first, followed by the remaining ones in registration order
+func (r *Registry) prioritized(priority []string) ([]JRE, error) {
+	var providers []JRE
+	for _, id := range priority {
+		jre, err := r.lookup(id, config.EnvVar("jre"))
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, jre)
+	}
+	for _, jre := range r.providers {
+		if !containsJRE(providers, jre) {
+			providers = append(providers, jre)
+		}
+	}
+	return providers, nil
+}
+
+// containsJRE returns true if jre is in providers
+func containsJRE(providers []JRE, jre JRE) bool {
+	for _, p := range providers {
+		if p == jre {
+			return true
+		}
+	}
+	return false
 }
 
 // Component represents a JRE component (memory calculator, jvmkill, etc.)
@@ -192,26 +407,16 @@ type BaseComponent struct {
 
 // Helper functions
 
-// DetectJREByEnv checks environment variables for JRE selection
+// DetectJREByEnv returns true if the application configures the JRE with its JBP_CONFIG_<JRE> variable
 // Takes the internal JRE name (e.g., "sapmachine", "openjdk", "zulu")
-// Checks both auto-generated and documented environment variable names
-// This matches the behavior of GetJREVersion and the Ruby buildpack
+// Checks both the auto-generated name, e.g. JBP_CONFIG_SAPMACHINE, and the documented name, e.g.
+// JBP_CONFIG_SAP_MACHINE_JRE. This matches the behavior of GetJREVersion and the Ruby buildpack
 func DetectJREByEnv(jreName string) bool {
-	// Check auto-generated name pattern (e.g., JBP_CONFIG_SAPMACHINE)
-	envKey := fmt.Sprintf("JBP_CONFIG_%s", strings.ToUpper(strings.ReplaceAll(jreName, "-", "_")))
-	if os.Getenv(envKey) != "" {
+	if config.IsSet(jreName) {
 		return true
 	}
-
-	// Check documented environment variable name from map
-	// This ensures backward compatibility with documented JBP_CONFIG_*_JRE convention
-	if documentedEnvKey, exists := jreNameToDocumentedEnvVar[jreName]; exists {
-		if os.Getenv(documentedEnvKey) != "" {
-			return true
-		}
-	}
-
-	return false
+	component := jreConfigComponent(jreName)
+	return component != "" && config.IsSet(component)
 }
 
 // jreNameToDocumentedEnvVar maps JRE names to their documented environment variable names
@@ -222,13 +427,23 @@ var jreNameToDocumentedEnvVar = map[string]string{
 	"zulu":       "JBP_CONFIG_ZULU_JRE",
 	"graalvm":    "JBP_CONFIG_GRAAL_VM_JRE",
 	"ibm":        "JBP_CONFIG_IBM_JRE",
+	"semeru":     "JBP_CONFIG_SEMERU_JRE",
 	"oracle":     "JBP_CONFIG_ORACLE_JRE",
 	"zing":       "JBP_CONFIG_ZING_JRE",
 }
 
 // GetJREVersion gets the desired JRE version from environment or uses default
-// Supports BP_JAVA_VERSION (simple version) and JBP_CONFIG_<JRE_NAME> (complex config)
-func GetJREVersion(ctx *common.Context, jreName string) (libbuildpack.Dependency, error) {
+// Supports BP_JAVA_VERSION (simple version) and JBP_CONFIG_<JRE_NAME> (complex config), which is read through
+// config.Load, so that it is merged over the buildpack's config/<jre>.yml and recorded in the effective configuration
+// selectedVersion is the JBP_CONFIG_JRE version Registry.Detect selected for the JRE, if any, which applies unless
+// JBP_CONFIG_<JRE_NAME> sets a version. It takes precedence over a version the buildpack's config/<jre>.yml sets.
+func GetJREVersion(ctx *common.Context, jreName, selectedVersion string) (libbuildpack.Dependency, error) {
+	// The manifest only offers the versions of the current stack, so a JRE published for other stacks only would
+	// otherwise be reported as missing
+	if err := common.DependencyStackError(ctx.Manifest, jreName); err != nil {
+		return libbuildpack.Dependency{}, err
+	}
+
 	// Check for simple BP_JAVA_VERSION environment variable first
 	// Format: "8", "11", "17", "21", etc. or version patterns like "11.+", "17.*"
 	if bpVersion := os.Getenv("BP_JAVA_VERSION"); bpVersion != "" {
@@ -254,31 +469,20 @@ func GetJREVersion(ctx *common.Context, jreName string) (libbuildpack.Dependency
 		return libbuildpack.Dependency{Name: jreName, Version: matchedVersion}, nil
 	}
 
-	// Check for JBP_CONFIG_<JRE_NAME> environment variable
-	// Try both the auto-generated name and the documented name for backward compatibility
-	envKey := fmt.Sprintf("JBP_CONFIG_%s", strings.ToUpper(strings.ReplaceAll(jreName, "-", "_")))
-	envVal := os.Getenv(envKey)
-
-	// If not found, check for documented environment variable name (e.g., JBP_CONFIG_OPEN_JDK_JRE)
-	// This ensures backward compatibility with documented naming conventions
-	if envVal == "" {
-		if documentedEnvKey, exists := jreNameToDocumentedEnvVar[jreName]; exists {
-			envVal = os.Getenv(documentedEnvKey)
-			if envVal != "" {
-				envKey = documentedEnvKey
-			}
-		}
+	// Check the JBP_CONFIG_<JRE_NAME> configuration
+	versionPattern, defaulted, err := configuredJREVersion(ctx, jreName)
+	if err != nil {
+		return libbuildpack.Dependency{}, err
 	}
 
-	if envVal != "" {
-		ctx.Log.Debug("Found %s='%s'", envKey, envVal)
-
-		versionPattern := parseJBPConfigVersion(envVal)
-		if versionPattern == "" {
-			return libbuildpack.Dependency{}, fmt.Errorf("could not parse version from %s='%s'", envKey, envVal)
-		}
-		ctx.Log.Debug("Parsed version pattern from %s: '%s'", envKey, versionPattern)
+	// Fall back to the provider-independent JBP_CONFIG_JRE version, which Registry.Detect selected for this JRE.
+	// The application's version overrides the one the buildpack ships in its defaults.
+	if (versionPattern == "" || defaulted) && selectedVersion != "" {
+		versionPattern = selectedVersion
+		ctx.Log.Debug("Using version pattern from %s: '%s'", config.EnvVar("jre"), versionPattern)
+	}
 
+	if versionPattern != "" {
 		normalizedPattern := normalizeVersionPattern(versionPattern)
 		ctx.Log.Debug("Normalized pattern: '%s' -> '%s'", versionPattern, normalizedPattern)
 
@@ -317,49 +521,89 @@ func normalizeVersionPattern(version string) string {
 	return version + ".*"
 }
 
-func parseJBPConfigVersion(configValue string) string {
-	re := regexp.MustCompile(`version:\s*['"]?([0-9]+[0-9.*+]*)['"]?`)
-	matches := re.FindStringSubmatch(configValue)
-	if len(matches) >= 2 {
-		return strings.TrimSpace(matches[1])
-	}
-	return ""
+// jreVersionConfig is the version selection in a JBP_CONFIG_<JRE> value, e.g. '{jre: {version: 17.+}}'
+type jreVersionConfig struct {
+	JRE struct {
+		Version string `yaml:"version"`
+	} `yaml:"jre"`
 }
 
-// WriteJavaOpts writes JAVA_OPTS to a .opts file for centralized assembly
-// JRE components use priority 05 to run early (before frameworks)
-func WriteJavaOpts(ctx *common.Context, opts string) error {
-	return WriteJavaOptsWithPriority(ctx, 05, "jre", opts)
+// version returns jre.version, or an empty string if the configuration does not constrain the version
+func (c jreVersionConfig) version() string {
+	return strings.TrimSpace(c.JRE.Version)
 }
 
-// WriteJavaOptsWithPriority writes JAVA_OPTS to a numbered .opts file for centralized assembly
-// Priority determines execution order (lower numbers run first)
-// Multiple calls with the same priority/name will append to the same file
-func WriteJavaOptsWithPriority(ctx *common.Context, priority int, name string, opts string) error {
-	// Create java_opts directory in deps
-	optsDir := filepath.Join(ctx.Stager.DepDir(), "java_opts")
-	if err := os.MkdirAll(optsDir, 0755); err != nil {
-		return fmt.Errorf("failed to create java_opts directory: %w", err)
+// jreConfigComponent returns the documented configuration component of a JRE, e.g. "open_jdk_jre" for "openjdk",
+// or an empty string for an unknown JRE
+func jreConfigComponent(jreName string) string {
+	envVar, ok := jreNameToDocumentedEnvVar[jreName]
+	if !ok {
+		return ""
+	}
+	return strings.ToLower(strings.TrimPrefix(envVar, config.EnvPrefix))
+}
+
+// newJREConfig returns the configuration struct of the documented component of a JRE holding its built-in defaults
+func newJREConfig(jreName string) interface{ version() string } {
+	switch jreName {
+	case "graalvm":
+		return &graalVMComponentConfig{}
+	case "ibm", "semeru":
+		return &ibmComponentConfig{ibmConfig: defaultIBMConfig(jreName == "semeru")}
+	default:
+		return &jreComponentConfig{}
 	}
+}
 
-	// Write .opts file with priority prefix (e.g., 05_jre.opts)
-	filename := fmt.Sprintf("%02d_%s.opts", priority, name)
-	optsFile := filepath.Join(optsDir, filename)
+// configuredJREVersion returns the version pattern the JRE's configuration selects, or an empty string if it
+// selects none, e.g. for '{native_image: {enabled: true}}'. defaulted is true if the version comes from the
+// buildpack's config/<jre>.yml only, so that the application's JBP_CONFIG_JRE can override it.
+// The auto-generated JBP_CONFIG_<JRE>, e.g. JBP_CONFIG_OPENJDK, takes precedence if the application sets it.
+// Otherwise the documented component, e.g. open_jdk_jre, is loaded, which merges config/open_jdk_jre.yml and
+// JBP_CONFIG_OPEN_JDK_JRE.
+func configuredJREVersion(ctx *common.Context, jreName string) (version string, defaulted bool, err error) {
+	if config.IsSet(jreName) {
+		cfg := jreVersionConfig{}
+		if err := config.Load(ctx.Log, jreName, &cfg); err != nil {
+			return "", false, fmt.Errorf("could not parse version from %s: %w", config.EnvVar(jreName), err)
+		}
+		ctx.Log.Debug("Parsed version pattern from %s: '%s'", config.EnvVar(jreName), cfg.version())
+		return cfg.version(), false, nil
+	}
 
-	// Append to existing content if file exists
-	var content string
-	if existing, err := os.ReadFile(optsFile); err == nil {
-		content = strings.TrimSpace(string(existing)) + " " + opts
-	} else {
-		content = opts
+	component := jreConfigComponent(jreName)
+	if component == "" {
+		return "", false, nil
+	}
+	cfg := newJREConfig(jreName)
+	if err := config.Load(ctx.Log, component, cfg); err != nil {
+		return "", false, fmt.Errorf("could not parse version from %s: %w", config.EnvVar(component), err)
 	}
+	if cfg.version() == "" {
+		return "", false, nil
+	}
+	ctx.Log.Debug("Parsed version pattern from %s: '%s'", config.EnvVar(component), cfg.version())
 
-	if err := os.WriteFile(optsFile, []byte(content), 0644); err != nil {
-		return fmt.Errorf("failed to write %s: %w", filename, err)
+	// Load has already parsed the variable, so it is valid
+	envCfg := jreVersionConfig{}
+	if config.IsSet(component) {
+		if data, err := config.Normalize([]byte(os.Getenv(config.EnvVar(component)))); err == nil {
+			_ = (common.YamlHandler{}).Unmarshal(data, &envCfg)
+		}
 	}
+	return cfg.version(), envCfg.version() == "", nil
+}
 
-	ctx.Log.Debug("Wrote JAVA_OPTS to %s (priority %d)", filename, priority)
-	return nil
+// WriteJavaOpts appends the JRE base options to 05_jre.opts for centralized assembly
+func WriteJavaOpts(ctx *common.Context, opts string) error {
+	return WriteJavaOptsWithPriority(ctx, javaopts.JRE, "jre", opts)
+}
+
+// WriteJavaOptsWithPriority writes JAVA_OPTS to a numbered .opts file for centralized assembly
+// Priority, one of the priorities declared by javaopts, determines the order in JAVA_OPTS (lower numbers come first)
+// Multiple calls with the same priority/name will append to the same file
+func WriteJavaOptsWithPriority(ctx *common.Context, priority int, name string, opts string) error {
+	return javaopts.Append(ctx.Log, ctx.Stager.DepDir(), javaopts.Contribution{Priority: priority, Name: name, Opts: opts})
 }
 
 // WriteJavaHomeProfileD creates a profile.d script that exports JAVA_HOME, JRE_HOME, and PATH at runtime
@@ -385,16 +629,7 @@ func WriteJavaHomeProfileD(ctx *common.Context, jreDir, javaHome string) error {
 
 	// Build the JAVA_HOME path using $DEPS_DIR environment variable
 	// This allows the path to work at runtime when the app is staged
-	// Use the actual buildpack index from ctx.Stager.DepsIdx() to support multi-buildpack scenarios
-	depsIdx := ctx.Stager.DepsIdx()
-	var javaHomePath string
-	if relPath == "." {
-		// JAVA_HOME is directly at jreDir
-		javaHomePath = fmt.Sprintf("$DEPS_DIR/%s/jre", depsIdx)
-	} else {
-		// JAVA_HOME is in a subdirectory (e.g., jdk-17.0.13)
-		javaHomePath = fmt.Sprintf("$DEPS_DIR/%s/jre/%s", depsIdx, relPath)
-	}
+	javaHomePath := ctx.Droplet().Dep("jre", relPath)
 
 	// Create the profile.d script content with JAVA_HOME, JRE_HOME, and PATH
 	// Following the pattern from reference buildpacks (Ruby, Python, Go)
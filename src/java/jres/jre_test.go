@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
 	"github.com/cloudfoundry/java-buildpack/src/java/jres"
 	"github.com/cloudfoundry/libbuildpack"
 	. "github.com/onsi/ginkgo/v2"
@@ -120,6 +122,121 @@ var _ = Describe("JRE Registry", func() {
 			Expect(name).To(Equal("SapMachine"))
 		})
 	})
+
+	Describe("JBP_CONFIG_JRE", func() {
+		BeforeEach(func() {
+			registry.RegisterStandardJREs()
+		})
+
+		AfterEach(func() {
+			os.Unsetenv("JBP_CONFIG_JRE")
+			os.Unsetenv("JBP_CONFIG_ZULU_JRE")
+			os.Unsetenv("JBP_CONFIG_SAP_MACHINE_JRE")
+		})
+
+		It("selects the provider regardless of provider-specific variables", func() {
+			os.Setenv("JBP_CONFIG_JRE", "{provider: zulu, version: 17.+}")
+			os.Setenv("JBP_CONFIG_SAP_MACHINE_JRE", "{jre: {version: 17.+}}")
+
+			_, name, err := registry.Detect()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("Zulu"))
+		})
+
+		It("rejects unknown providers", func() {
+			os.Setenv("JBP_CONFIG_JRE", "{provider: temurin}")
+
+			_, _, err := registry.Detect()
+			Expect(err).To(MatchError(ContainSubstring(`unknown JRE provider "temurin"`)))
+		})
+
+		It("consults provider-specific variables in priority order", func() {
+			os.Setenv("JBP_CONFIG_ZULU_JRE", "{jre: {version: 17.+}}")
+			os.Setenv("JBP_CONFIG_SAP_MACHINE_JRE", "{jre: {version: 17.+}}")
+
+			_, name, err := registry.Detect()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("Zulu"))
+
+			os.Setenv("JBP_CONFIG_JRE", "{priority: [sapmachine]}")
+			_, name, err = registry.Detect()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("SapMachine"))
+		})
+	})
+
+	Describe("JBP_JRE and JBP_CONFIG_COMPONENTS", func() {
+		BeforeEach(func() {
+			registry.RegisterStandardJREs()
+		})
+
+		AfterEach(func() {
+			os.Unsetenv("JBP_JRE")
+			os.Unsetenv("JBP_CONFIG_COMPONENTS")
+			os.Unsetenv("JBP_CONFIG_JRE")
+		})
+
+		It("selects the provider named by JBP_JRE", func() {
+			os.Setenv("JBP_JRE", "zulu")
+
+			_, name, err := registry.Detect()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("Zulu"))
+		})
+
+		It("rejects unknown providers in JBP_JRE", func() {
+			os.Setenv("JBP_JRE", "temurin")
+
+			_, _, err := registry.Detect()
+			Expect(err).To(MatchError(ContainSubstring(`unknown JRE provider "temurin" in JBP_JRE`)))
+		})
+
+		It("selects the first JRE component of JBP_CONFIG_COMPONENTS", func() {
+			os.Setenv("JBP_CONFIG_COMPONENTS", `{jres: ["ZuluJRE"]}`)
+
+			_, name, err := registry.Detect()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("Zulu"))
+
+			os.Setenv("JBP_CONFIG_COMPONENTS", `{jres: ["JavaBuildpack::Jre::SapMachineJRE"]}`)
+			_, name, err = registry.Detect()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("SapMachine"))
+		})
+
+		It("rejects unknown JRE components", func() {
+			os.Setenv("JBP_CONFIG_COMPONENTS", `{jres: ["TemurinJRE"]}`)
+
+			_, _, err := registry.Detect()
+			Expect(err).To(MatchError(ContainSubstring(`unknown JRE component "TemurinJRE"`)))
+		})
+
+		It("does not detect excluded JRE components", func() {
+			os.Setenv("JBP_CONFIG_ZULU_JRE", "{jre: {version: 17.+}}")
+			os.Setenv("JBP_CONFIG_COMPONENTS", `{jres: ["-ZuluJRE"]}`)
+			defer os.Unsetenv("JBP_CONFIG_ZULU_JRE")
+
+			_, name, err := registry.Detect()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("OpenJDK"))
+		})
+
+		It("fails if the default JRE is excluded and no other JRE is configured", func() {
+			os.Setenv("JBP_CONFIG_COMPONENTS", `{jres: ["-OpenJdkJRE"]}`)
+
+			_, _, err := registry.Detect()
+			Expect(err).To(MatchError(ContainSubstring("the default JRE OpenJDK is excluded by JBP_CONFIG_COMPONENTS")))
+		})
+
+		It("lets JBP_CONFIG_JRE take precedence", func() {
+			os.Setenv("JBP_JRE", "zulu")
+			os.Setenv("JBP_CONFIG_JRE", "{provider: sapmachine}")
+
+			_, name, err := registry.Detect()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("SapMachine"))
+		})
+	})
 })
 
 var _ = Describe("JRE Helper Functions", func() {
@@ -276,7 +393,7 @@ dependencies:
 		Context("with BP_JAVA_VERSION environment variable", func() {
 			It("resolves major version 8", func() {
 				os.Setenv("BP_JAVA_VERSION", "8")
-				dep, err := jres.GetJREVersion(ctx, "openjdk")
+				dep, err := jres.GetJREVersion(ctx, "openjdk", "")
 				Expect(err).NotTo(HaveOccurred())
 				Expect(dep.Name).To(Equal("openjdk"))
 				Expect(dep.Version).To(Equal("8.0.422"))
@@ -284,7 +401,7 @@ dependencies:
 
 			It("resolves major version 11", func() {
 				os.Setenv("BP_JAVA_VERSION", "11")
-				dep, err := jres.GetJREVersion(ctx, "openjdk")
+				dep, err := jres.GetJREVersion(ctx, "openjdk", "")
 				Expect(err).NotTo(HaveOccurred())
 				Expect(dep.Name).To(Equal("openjdk"))
 				Expect(dep.Version).To(Equal("11.0.25"))
@@ -292,7 +409,7 @@ dependencies:
 
 			It("resolves major version 17", func() {
 				os.Setenv("BP_JAVA_VERSION", "17")
-				dep, err := jres.GetJREVersion(ctx, "openjdk")
+				dep, err := jres.GetJREVersion(ctx, "openjdk", "")
 				Expect(err).NotTo(HaveOccurred())
 				Expect(dep.Name).To(Equal("openjdk"))
 				Expect(dep.Version).To(Equal("17.0.13"))
@@ -300,7 +417,7 @@ dependencies:
 
 			It("resolves major version 21", func() {
 				os.Setenv("BP_JAVA_VERSION", "21")
-				dep, err := jres.GetJREVersion(ctx, "openjdk")
+				dep, err := jres.GetJREVersion(ctx, "openjdk", "")
 				Expect(err).NotTo(HaveOccurred())
 				Expect(dep.Name).To(Equal("openjdk"))
 				Expect(dep.Version).To(Equal("21.0.5"))
@@ -308,7 +425,7 @@ dependencies:
 
 			It("handles version patterns with wildcards", func() {
 				os.Setenv("BP_JAVA_VERSION", "17.*")
-				dep, err := jres.GetJREVersion(ctx, "openjdk")
+				dep, err := jres.GetJREVersion(ctx, "openjdk", "")
 				Expect(err).NotTo(HaveOccurred())
 				Expect(dep.Name).To(Equal("openjdk"))
 				Expect(dep.Version).To(Equal("17.0.13"))
@@ -317,11 +434,17 @@ dependencies:
 
 		Context("without BP_JAVA_VERSION", func() {
 			It("returns default version from manifest", func() {
-				dep, err := jres.GetJREVersion(ctx, "openjdk")
+				dep, err := jres.GetJREVersion(ctx, "openjdk", "")
 				Expect(err).NotTo(HaveOccurred())
 				Expect(dep.Name).To(Equal("openjdk"))
 				Expect(dep.Version).To(ContainSubstring("17."))
 			})
+
+			It("names the stacks the JRE is available for on another stack", func() {
+				os.Setenv("CF_STACK", "cflinuxfs5")
+				_, err := jres.GetJREVersion(ctx, "openjdk", "")
+				Expect(err).To(MatchError("openjdk is not available for the cflinuxfs5 stack: manifest.yml has it for cflinuxfs4"))
+			})
 		})
 
 		Context("with JBP_CONFIG_OPENJDK", func() {
@@ -331,7 +454,7 @@ dependencies:
 
 			It("resolves version from JBP_CONFIG", func() {
 				os.Setenv("JBP_CONFIG_OPENJDK", "{jre: {version: 11.+}}")
-				dep, err := jres.GetJREVersion(ctx, "openjdk")
+				dep, err := jres.GetJREVersion(ctx, "openjdk", "")
 				Expect(err).NotTo(HaveOccurred())
 				Expect(dep.Name).To(Equal("openjdk"))
 				Expect(dep.Version).To(Equal("11.0.25"))
@@ -339,17 +462,24 @@ dependencies:
 
 			It("fails when requested version does not exist", func() {
 				os.Setenv("JBP_CONFIG_OPENJDK", "{jre: {version: 99.+}}")
-				_, err := jres.GetJREVersion(ctx, "openjdk")
+				_, err := jres.GetJREVersion(ctx, "openjdk", "")
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("no version of openjdk matching"))
 			})
 
 			It("fails when config format is invalid", func() {
 				os.Setenv("JBP_CONFIG_OPENJDK", "invalid config")
-				_, err := jres.GetJREVersion(ctx, "openjdk")
+				_, err := jres.GetJREVersion(ctx, "openjdk", "")
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("could not parse version"))
 			})
+
+			It("uses the default version when the config sets no version", func() {
+				os.Setenv("JBP_CONFIG_OPENJDK", "{memory_calculator: {stack_threads: 300}}")
+				dep, err := jres.GetJREVersion(ctx, "openjdk", "")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dep.Version).To(ContainSubstring("17."))
+			})
 		})
 
 		Context("with JBP_CONFIG_OPEN_JDK_JRE", func() {
@@ -359,7 +489,7 @@ dependencies:
 
 			It("resolves version 21.+ pattern", func() {
 				os.Setenv("JBP_CONFIG_OPEN_JDK_JRE", "{jre: {version: 21.+}}")
-				dep, err := jres.GetJREVersion(ctx, "openjdk")
+				dep, err := jres.GetJREVersion(ctx, "openjdk", "")
 				Expect(err).NotTo(HaveOccurred())
 				Expect(dep.Name).To(Equal("openjdk"))
 				Expect(dep.Version).To(Equal("21.0.5"))
@@ -367,7 +497,7 @@ dependencies:
 
 			It("resolves version 17.+ pattern", func() {
 				os.Setenv("JBP_CONFIG_OPEN_JDK_JRE", "{jre: {version: 17.+}}")
-				dep, err := jres.GetJREVersion(ctx, "openjdk")
+				dep, err := jres.GetJREVersion(ctx, "openjdk", "")
 				Expect(err).NotTo(HaveOccurred())
 				Expect(dep.Name).To(Equal("openjdk"))
 				Expect(dep.Version).To(Equal("17.0.13"))
@@ -375,7 +505,7 @@ dependencies:
 
 			It("resolves version 11.+ pattern", func() {
 				os.Setenv("JBP_CONFIG_OPEN_JDK_JRE", "{jre: {version: 11.+}}")
-				dep, err := jres.GetJREVersion(ctx, "openjdk")
+				dep, err := jres.GetJREVersion(ctx, "openjdk", "")
 				Expect(err).NotTo(HaveOccurred())
 				Expect(dep.Name).To(Equal("openjdk"))
 				Expect(dep.Version).To(Equal("11.0.25"))
@@ -383,14 +513,121 @@ dependencies:
 
 			It("fails when requested version does not exist", func() {
 				os.Setenv("JBP_CONFIG_OPEN_JDK_JRE", "{jre: {version: 99.+}}")
-				_, err := jres.GetJREVersion(ctx, "openjdk")
+				_, err := jres.GetJREVersion(ctx, "openjdk", "")
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("no version of openjdk matching"))
 			})
 
+			It("reads jre.version from block style YAML with other sections", func() {
+				os.Setenv("JBP_CONFIG_OPEN_JDK_JRE", "memory_calculator:\n  version: 3.+\njre:\n  version: '17.+'\n")
+				dep, err := jres.GetJREVersion(ctx, "openjdk", "")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dep.Version).To(Equal("17.0.13"))
+			})
+
+			It("accepts a plain major version", func() {
+				os.Setenv("JBP_CONFIG_OPEN_JDK_JRE", "{jre: {version: 21}}")
+				dep, err := jres.GetJREVersion(ctx, "openjdk", "")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dep.Version).To(Equal("21.0.5"))
+			})
+
+			It("merges the variable over the buildpack's config/open_jdk_jre.yml and records the result", func() {
+				buildpackDir := GinkgoT().TempDir()
+				Expect(os.MkdirAll(filepath.Join(buildpackDir, "config"), 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(buildpackDir, "config", "open_jdk_jre.yml"),
+					[]byte("jre:\n  version: 11.+\n"), 0644)).To(Succeed())
+				os.Setenv("BUILDPACK_DIR", buildpackDir)
+				defer os.Unsetenv("BUILDPACK_DIR")
+
+				dep, err := jres.GetJREVersion(ctx, "openjdk", "")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dep.Version).To(Equal("11.0.25"))
+
+				os.Setenv("JBP_CONFIG_OPEN_JDK_JRE", "{memory_calculator: {stack_threads: 100}}")
+				dep, err = jres.GetJREVersion(ctx, "openjdk", "")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dep.Version).To(Equal("11.0.25"))
+				Expect(config.Effective()["open_jdk_jre"].Sources).To(Equal([]string{config.SourceBuiltIn, "config/open_jdk_jre.yml", "JBP_CONFIG_OPEN_JDK_JRE"}))
+
+				os.Setenv("JBP_CONFIG_OPEN_JDK_JRE", "{jre: {version: 21.+}}")
+				dep, err = jres.GetJREVersion(ctx, "openjdk", "")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dep.Version).To(Equal("21.0.5"))
+			})
+
+			It("lets the JBP_CONFIG_JRE version override the version of config/open_jdk_jre.yml", func() {
+				buildpackDir := GinkgoT().TempDir()
+				Expect(os.MkdirAll(filepath.Join(buildpackDir, "config"), 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(buildpackDir, "config", "open_jdk_jre.yml"),
+					[]byte("jre:\n  version: 11.+\n"), 0644)).To(Succeed())
+				os.Setenv("BUILDPACK_DIR", buildpackDir)
+				defer os.Unsetenv("BUILDPACK_DIR")
+
+				dep, err := jres.GetJREVersion(ctx, "openjdk", "21.+")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dep.Version).To(Equal("21.0.5"))
+
+				os.Setenv("JBP_CONFIG_OPEN_JDK_JRE", "{jre: {version: 17.+}}")
+				dep, err = jres.GetJREVersion(ctx, "openjdk", "21.+")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dep.Version).To(Equal("17.0.13"))
+			})
+
 			It("prefers JBP_CONFIG_OPEN_JDK_JRE over default when both are unset", func() {
 				os.Setenv("JBP_CONFIG_OPEN_JDK_JRE", "{jre: {version: 21.+}}")
-				dep, err := jres.GetJREVersion(ctx, "openjdk")
+				dep, err := jres.GetJREVersion(ctx, "openjdk", "")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dep.Version).To(Equal("21.0.5"))
+			})
+		})
+
+		Context("with JBP_CONFIG_JRE", func() {
+			var (
+				registry *jres.Registry
+				output   *bytes.Buffer
+			)
+
+			BeforeEach(func() {
+				output = new(bytes.Buffer)
+				ctx.Log = libbuildpack.NewLogger(output)
+				registry = jres.NewRegistry(ctx)
+				registry.RegisterStandardJREs()
+			})
+
+			AfterEach(func() {
+				os.Unsetenv("JBP_CONFIG_JRE")
+			})
+
+			It("applies the version to the JRE the registry selects", func() {
+				os.Setenv("JBP_CONFIG_JRE", "{provider: zulu, version: 99}")
+
+				jre, _, err := registry.Detect()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(jre.Supply()).To(MatchError(ContainSubstring("no version of zulu matching '99'")))
+			})
+
+			It("is loaded once per staging", func() {
+				os.Setenv("JBP_CONFIG_JRE", "{provider: zulu, version: 99, unknown_key: true}")
+
+				jre, _, err := registry.Detect()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(jre.Supply()).To(HaveOccurred())
+				Expect(strings.Count(output.String(), "unknown_key")).To(Equal(1))
+			})
+		})
+
+		Context("with a version selected by JBP_CONFIG_JRE", func() {
+			It("uses the selected version", func() {
+				dep, err := jres.GetJREVersion(ctx, "openjdk", "11")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dep.Version).To(Equal("11.0.25"))
+			})
+
+			It("prefers the provider-specific version", func() {
+				os.Setenv("JBP_CONFIG_OPEN_JDK_JRE", "{jre: {version: 21}}")
+
+				dep, err := jres.GetJREVersion(ctx, "openjdk", "11")
 				Expect(err).NotTo(HaveOccurred())
 				Expect(dep.Version).To(Equal("21.0.5"))
 			})
@@ -401,7 +638,7 @@ dependencies:
 				os.Setenv("JBP_CONFIG_SAP_MACHINE_JRE", "{ jre: {version: 17.+} }")
 				defer os.Unsetenv("JBP_CONFIG_SAP_MACHINE_JRE")
 
-				dep, err := jres.GetJREVersion(ctx, "sapmachine")
+				dep, err := jres.GetJREVersion(ctx, "sapmachine", "")
 				Expect(err).NotTo(HaveOccurred())
 				Expect(dep.Name).To(Equal("sapmachine"))
 				Expect(dep.Version).To(Equal("17.0.17"))
@@ -411,7 +648,7 @@ dependencies:
 				os.Setenv("JBP_CONFIG_SAP_MACHINE_JRE", "{ jre: {version: 21.+} }")
 				defer os.Unsetenv("JBP_CONFIG_SAP_MACHINE_JRE")
 
-				dep, err := jres.GetJREVersion(ctx, "sapmachine")
+				dep, err := jres.GetJREVersion(ctx, "sapmachine", "")
 				Expect(err).NotTo(HaveOccurred())
 				Expect(dep.Name).To(Equal("sapmachine"))
 				Expect(dep.Version).To(Equal("21.0.9"))
@@ -421,7 +658,7 @@ dependencies:
 				os.Setenv("JBP_CONFIG_SAP_MACHINE_JRE", "{ jre: {version: 25.+} }")
 				defer os.Unsetenv("JBP_CONFIG_SAP_MACHINE_JRE")
 
-				dep, err := jres.GetJREVersion(ctx, "sapmachine")
+				dep, err := jres.GetJREVersion(ctx, "sapmachine", "")
 				Expect(err).NotTo(HaveOccurred())
 				Expect(dep.Name).To(Equal("sapmachine"))
 				Expect(dep.Version).To(Equal("25.0.1"))
@@ -431,7 +668,7 @@ dependencies:
 				os.Setenv("JBP_CONFIG_SAP_MACHINE_JRE", "{ jre: {version: 26.+} }")
 				defer os.Unsetenv("JBP_CONFIG_SAP_MACHINE_JRE")
 
-				_, err := jres.GetJREVersion(ctx, "sapmachine")
+				_, err := jres.GetJREVersion(ctx, "sapmachine", "")
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("no version of sapmachine matching '26.+' found in manifest"))
 			})
@@ -440,7 +677,7 @@ dependencies:
 				os.Setenv("JBP_CONFIG_ZULU_JRE", "{jre: {version: 11.+}}")
 				defer os.Unsetenv("JBP_CONFIG_ZULU_JRE")
 
-				dep, err := jres.GetJREVersion(ctx, "zulu")
+				dep, err := jres.GetJREVersion(ctx, "zulu", "")
 				Expect(err).NotTo(HaveOccurred())
 				Expect(dep.Name).To(Equal("zulu"))
 				Expect(dep.Version).To(Equal("11.0.25"))
@@ -450,7 +687,7 @@ dependencies:
 				os.Setenv("JBP_CONFIG_ZULU_JRE", "{jre: {version: 17.+}}")
 				defer os.Unsetenv("JBP_CONFIG_ZULU_JRE")
 
-				dep, err := jres.GetJREVersion(ctx, "zulu")
+				dep, err := jres.GetJREVersion(ctx, "zulu", "")
 				Expect(err).NotTo(HaveOccurred())
 				Expect(dep.Name).To(Equal("zulu"))
 				Expect(dep.Version).To(Equal("17.0.13"))
@@ -460,7 +697,7 @@ dependencies:
 				os.Setenv("JBP_CONFIG_ZULU_JRE", "{jre: {version: 18.+}}")
 				defer os.Unsetenv("JBP_CONFIG_ZULU_JRE")
 
-				_, err := jres.GetJREVersion(ctx, "zulu")
+				_, err := jres.GetJREVersion(ctx, "zulu", "")
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("no version of zulu matching '18.+' found in manifest"))
 			})
@@ -469,7 +706,7 @@ dependencies:
 				os.Setenv("JBP_CONFIG_GRAAL_VM_JRE", "{jre: {version: 22.1.+}}")
 				defer os.Unsetenv("JBP_CONFIG_GRAAL_VM_JRE")
 
-				_, err := jres.GetJREVersion(ctx, "graalvm")
+				_, err := jres.GetJREVersion(ctx, "graalvm", "")
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("no versions of graalvm found"))
 			})
@@ -478,7 +715,7 @@ dependencies:
 				os.Setenv("JBP_CONFIG_IBM_JRE", "{jre: {version: 1.8.+}}")
 				defer os.Unsetenv("JBP_CONFIG_IBM_JRE")
 
-				_, err := jres.GetJREVersion(ctx, "ibm")
+				_, err := jres.GetJREVersion(ctx, "ibm", "")
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("no versions of ibm found"))
 			})
@@ -487,7 +724,7 @@ dependencies:
 				os.Setenv("JBP_CONFIG_ORACLE_JRE", "{jre: {version: 17.+}}")
 				defer os.Unsetenv("JBP_CONFIG_ORACLE_JRE")
 
-				_, err := jres.GetJREVersion(ctx, "oracle")
+				_, err := jres.GetJREVersion(ctx, "oracle", "")
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("no versions of oracle found"))
 			})
@@ -496,7 +733,7 @@ dependencies:
 				os.Setenv("JBP_CONFIG_ZING_JRE", "{jre: {version: 17.+}}")
 				defer os.Unsetenv("JBP_CONFIG_ZING_JRE")
 
-				_, err := jres.GetJREVersion(ctx, "zing")
+				_, err := jres.GetJREVersion(ctx, "zing", "")
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("no versions of zing found"))
 			})
@@ -3,10 +3,69 @@ package jres
 import (
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
+// jvmKillConfig tunes the JVMKill agent, e.g.
+//
+//	JBP_CONFIG_JVMKILL='{time: 10, count: 2, print_heap_histogram: false}'
+type jvmKillConfig struct {
+	// Count is the number of resource exhaustion events the JVM survives within Time seconds before it is
+	// killed. Zero keeps the agent's defaults, which kill the JVM on the first event.
+	Time  int `yaml:"time"`
+	Count int `yaml:"count"`
+	// PrintHeapHistogram and PrintMemoryUsage print the largest types and the memory pools before the JVM is killed
+	PrintHeapHistogram bool `yaml:"print_heap_histogram"`
+	PrintMemoryUsage   bool `yaml:"print_memory_usage"`
+	// HeapDumpPath is where the terminal heap dump is written, instead of a heap-dump volume service
+	HeapDumpPath string `yaml:"heap_dump_path"`
+}
+
+// loadJVMKillConfig reads the JVMKill configuration, with the histogram and memory usage printed by default
+func loadJVMKillConfig(ctx *common.Context) (jvmKillConfig, error) {
+	cfg := jvmKillConfig{PrintHeapHistogram: true, PrintMemoryUsage: true}
+	if err := config.Load(ctx.Log, "jvmkill", &cfg); err != nil {
+		return cfg, err
+	}
+	if cfg.Time < 0 || cfg.Count < 0 {
+		return cfg, fmt.Errorf("jvmkill time and count must not be negative")
+	}
+	// The agent splits its options on commas
+	if strings.Contains(cfg.HeapDumpPath, ",") {
+		return cfg, fmt.Errorf("jvmkill heap_dump_path must not contain a comma: %s", cfg.HeapDumpPath)
+	}
+	return cfg, nil
+}
+
+// agentOptions renders the configuration as JVMKill agent options, omitting the agent's defaults
+func (c jvmKillConfig) agentOptions(heapDumpPath string) string {
+	opts := []string{"printHeapHistogram=" + agentFlag(c.PrintHeapHistogram)}
+	if !c.PrintMemoryUsage {
+		opts = append(opts, "printMemoryUsage=0")
+	}
+	if c.Time > 0 {
+		opts = append(opts, fmt.Sprintf("time=%d", c.Time))
+	}
+	if c.Count > 0 {
+		opts = append(opts, fmt.Sprintf("count=%d", c.Count))
+	}
+	if heapDumpPath != "" {
+		opts = append(opts, "heapDumpPath="+heapDumpPath)
+	}
+	return strings.Join(opts, ",")
+}
+
+// agentFlag renders a boolean agent option
+func agentFlag(enabled bool) string {
+	if enabled {
+		return "1"
+	}
+	return "0"
+}
+
 // JVMKillAgent manages the JVMKill agent
 // JVMKill is an agent that forcibly terminates the JVM when it is unable to allocate memory or
 // throws an OutOfMemoryError.
@@ -34,6 +93,13 @@ func (j *JVMKillAgent) Name() string {
 
 // Supply installs the JVMKill agent
 func (j *JVMKillAgent) Supply() error {
+	if fixed, err := fixedMemoryEnabled(j.ctx); err != nil {
+		return err
+	} else if fixed {
+		j.ctx.Log.Info("Skipping JVMKill Agent, fixed memory settings are configured")
+		return nil
+	}
+
 	// Get JVMKill version from manifest
 	dep, err := j.ctx.Manifest.DefaultVersion("jvmkill")
 	if err != nil {
@@ -145,25 +211,31 @@ func (j *JVMKillAgent) Finalize() error {
 	j.ctx.Log.Info("Configuring JVMKill Agent")
 	j.ctx.Log.Debug("JVMKill agent staging path: %s", j.agentPath)
 
-	// Convert absolute staging path to runtime-relative path
-	// Staging path: /tmp/contents.../deps/<idx>/jre/bin/jvmkill-1.16.0.so
-	// Runtime path: $DEPS_DIR/<idx>/jre/bin/jvmkill-1.16.0.so
-	runtimeAgentPath := j.convertToRuntimePath(j.agentPath)
+	// The absolute runtime path is used because startup scripts run before the profile.d scripts set $DEPS_DIR,
+	// e.g. /home/vcap/deps/<idx>/jre/bin/jvmkill-1.16.0.so
+	runtimeAgentPath, err := j.ctx.Droplet().AbsolutePath(j.agentPath)
+	if err != nil {
+		return fmt.Errorf("failed to locate JVMKill agent at runtime: %w", err)
+	}
 	j.ctx.Log.Debug("JVMKill agent runtime path: %s", runtimeAgentPath)
 
-	// Check if there's a volume service for heap dumps
-	heapDumpPath := j.getHeapDumpPath()
+	cfg, err := loadJVMKillConfig(j.ctx)
+	if err != nil {
+		return err
+	}
 
-	// Build agentpath with options
-	// Format: -agentpath:/path/to/jvmkill.so=printHeapHistogram=1,heapDumpPath=/path
-	var agentOpt string
+	// Check if there's a volume service for heap dumps, unless the path is configured
+	heapDumpPath := cfg.HeapDumpPath
+	if heapDumpPath == "" {
+		heapDumpPath = j.getHeapDumpPath()
+	}
 	if heapDumpPath != "" {
-		agentOpt = fmt.Sprintf("-agentpath:%s=printHeapHistogram=1,heapDumpPath=%s", runtimeAgentPath, heapDumpPath)
 		j.ctx.Log.Info("Write terminal heap dumps to %s", heapDumpPath)
-	} else {
-		agentOpt = fmt.Sprintf("-agentpath:%s=printHeapHistogram=1", runtimeAgentPath)
 	}
 
+	// Format: -agentpath:/path/to/jvmkill.so=printHeapHistogram=1,heapDumpPath=/path
+	agentOpt := fmt.Sprintf("-agentpath:%s=%s", runtimeAgentPath, cfg.agentOptions(heapDumpPath))
+
 	j.ctx.Log.Debug("Adding to JAVA_OPTS: %s", agentOpt)
 
 	// Add to JAVA_OPTS
@@ -176,22 +248,6 @@ func (j *JVMKillAgent) Finalize() error {
 	return nil
 }
 
-// convertToRuntimePath converts absolute staging path to runtime absolute path
-// Example: /tmp/contents.../deps/<idx>/jre/bin/jvmkill-1.16.0.so -> /home/vcap/deps/<idx>/jre/bin/jvmkill-1.16.0.so
-// Note: We use absolute path instead of $DEPS_DIR because startup scripts run before .profile.d scripts
-// are sourced, so $DEPS_DIR is not yet available at runtime.
-func (j *JVMKillAgent) convertToRuntimePath(stagingPath string) string {
-	// Extract filename and build runtime path
-	// We know the structure: <staging-path>/deps/<idx>/jre/bin/jvmkill-VERSION.so
-	// Runtime path: /home/vcap/deps/<idx>/jre/bin/jvmkill-VERSION.so
-
-	depsIdx := j.ctx.Stager.DepsIdx()
-	filename := filepath.Base(stagingPath)
-
-	// Build absolute runtime path (Cloud Foundry standard location)
-	return fmt.Sprintf("/home/vcap/deps/%s/jre/bin/%s", depsIdx, filename)
-}
-
 // getHeapDumpPath checks for volume service with heap-dump tag and returns path
 func (j *JVMKillAgent) getHeapDumpPath() string {
 	// Check VCAP_SERVICES for volume service with heap-dump tag
@@ -201,27 +257,30 @@ func (j *JVMKillAgent) getHeapDumpPath() string {
 	}
 
 	// Look for volume service with "heap-dump" tag
-	for _, services := range vcapServices {
-		for _, service := range services {
-			if service.HasTag("heap-dump") {
-				// Extract volume mount path from credentials
-				if volumeMounts, ok := service.Credentials["volume_mounts"].([]interface{}); ok && len(volumeMounts) > 0 {
-					if mount, ok := volumeMounts[0].(map[string]interface{}); ok {
-						if containerDir, ok := mount["container_dir"].(string); ok {
-							// Build heap dump path
-							// Format: /container/dir/space-id/app-id/instance-index.hprof
-							appDetails := j.getAppDetails()
-							return filepath.Join(containerDir,
-								appDetails.spaceID,
-								appDetails.appID,
-								"$CF_INSTANCE_INDEX-%FT%T%z-${CF_INSTANCE_GUID:0:8}.hprof")
-						}
+	for _, service := range vcapServices.Sorted() {
+		if service.HasTag("heap-dump") {
+			// Extract volume mount path from credentials
+			if volumeMounts, ok := service.Credentials["volume_mounts"].([]interface{}); ok && len(volumeMounts) > 0 {
+				if mount, ok := volumeMounts[0].(map[string]interface{}); ok {
+					if containerDir, ok := mount["container_dir"].(string); ok {
+						// Build heap dump path
+						// Format: /container/dir/space-id/app-id/instance-index.hprof
+						appDetails := j.getAppDetails()
+						return filepath.Join(containerDir,
+							appDetails.spaceID,
+							appDetails.appID,
+							"$CF_INSTANCE_INDEX-%FT%T%z-${CF_INSTANCE_GUID:0:8}.hprof")
 					}
 				}
 			}
 		}
 	}
 
+	// Otherwise write them where the heap dump component collects them
+	if enabled, err := heapDumpEnabled(j.ctx); err == nil && enabled {
+		return filepath.Join(HeapDumpDirectory, "$CF_INSTANCE_INDEX-%FT%T%z-${CF_INSTANCE_GUID:0:8}.hprof")
+	}
+
 	return ""
 }
 
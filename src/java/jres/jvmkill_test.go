@@ -0,0 +1,85 @@
+package jres_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/jres"
+	"github.com/cloudfoundry/libbuildpack"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("JVMKill Agent", func() {
+	var (
+		ctx     *common.Context
+		depsDir string
+		agent   *jres.JVMKillAgent
+	)
+
+	BeforeEach(func() {
+		var err error
+		depsDir, err = os.MkdirTemp("", "deps")
+		Expect(err).NotTo(HaveOccurred())
+
+		jreDir := filepath.Join(depsDir, "0", "jre")
+		Expect(os.MkdirAll(filepath.Join(jreDir, "bin"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(jreDir, "bin", "jvmkill-1.16.0.so"), []byte("fake-so-file"), 0644)).To(Succeed())
+
+		logger := libbuildpack.NewLogger(GinkgoWriter)
+		manifest := &libbuildpack.Manifest{}
+		ctx = &common.Context{
+			Stager:   libbuildpack.NewStager([]string{depsDir, "", depsDir, "0"}, logger, manifest),
+			Manifest: manifest,
+			Log:      logger,
+		}
+		agent = jres.NewJVMKillAgent(ctx, jreDir, "17.0.13")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(depsDir)
+		os.Unsetenv("JBP_CONFIG_JVMKILL")
+		os.Unsetenv("VCAP_SERVICES")
+	})
+
+	readOpts := func() string {
+		opts, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "05_jre.opts"))
+		Expect(err).NotTo(HaveOccurred())
+		return string(opts)
+	}
+
+	It("prints the heap histogram by default", func() {
+		Expect(agent.Finalize()).To(Succeed())
+
+		Expect(readOpts()).To(Equal("-agentpath:/home/vcap/deps/0/jre/bin/jvmkill-1.16.0.so=printHeapHistogram=1"))
+	})
+
+	It("renders the configured options", func() {
+		os.Setenv("JBP_CONFIG_JVMKILL", "{time: 10, count: 2, print_heap_histogram: false, print_memory_usage: false, heap_dump_path: /home/vcap/dumps/oom.hprof}")
+
+		Expect(agent.Finalize()).To(Succeed())
+
+		Expect(readOpts()).To(Equal("-agentpath:/home/vcap/deps/0/jre/bin/jvmkill-1.16.0.so=" +
+			"printHeapHistogram=0,printMemoryUsage=0,time=10,count=2,heapDumpPath=/home/vcap/dumps/oom.hprof"))
+	})
+
+	It("prefers the configured heap dump path to a heap-dump volume service", func() {
+		os.Setenv("VCAP_SERVICES", `{"nfs": [{"name": "dumps", "tags": ["heap-dump"], "credentials": {"volume_mounts": [{"container_dir": "/mnt/dumps"}]}}]}`)
+		Expect(agent.Finalize()).To(Succeed())
+		Expect(readOpts()).To(ContainSubstring("heapDumpPath=/mnt/dumps/"))
+
+		Expect(os.RemoveAll(filepath.Join(depsDir, "0", "java_opts"))).To(Succeed())
+		os.Setenv("JBP_CONFIG_JVMKILL", "{heap_dump_path: /tmp/oom.hprof}")
+		Expect(agent.Finalize()).To(Succeed())
+		Expect(readOpts()).To(HaveSuffix("heapDumpPath=/tmp/oom.hprof"))
+	})
+
+	It("rejects options the agent cannot parse", func() {
+		os.Setenv("JBP_CONFIG_JVMKILL", "{heap_dump_path: '/tmp/a,b.hprof'}")
+		Expect(agent.Finalize()).To(MatchError(ContainSubstring("must not contain a comma")))
+
+		os.Setenv("JBP_CONFIG_JVMKILL", "{count: -1}")
+		Expect(agent.Finalize()).To(MatchError(ContainSubstring("must not be negative")))
+	})
+})
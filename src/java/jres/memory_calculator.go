@@ -5,9 +5,13 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/javaopts"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -22,22 +26,92 @@ type MemoryCalculator struct {
 	calculatorPath   string
 	version          string
 	classCount       int
-	stackThreads     int
-	headroom         int
+	// classCountSizeLimit is the application size in bytes above which classes are estimated, or 0 to always count
+	classCountSizeLimit int64
+	stackThreads        int
+	headroom            int
+	headroomMB          int
+	memorySizes         map[string]string
+	profile             MemoryProfile
+}
+
+// MemoryCalculatorConfig is the memory_calculator section of a JRE's configuration in config/<jre>.yml and
+// JBP_CONFIG_<JRE>, e.g. JBP_CONFIG_OPEN_JDK_JRE='{memory_calculator: {stack_threads: 300, headroom: 10}}'
+type MemoryCalculatorConfig struct {
+	// ClassCount replaces the estimated number of loaded classes
+	ClassCount int `yaml:"class_count"`
+	// ClassCountSizeLimit is the size of the application, e.g. 2G, above which its classes are estimated from its
+	// size instead of counted, or 0 to always count them
+	ClassCountSizeLimit string `yaml:"class_count_size_limit"`
+	// Headroom is the percentage of the container's memory left out of the calculation
+	Headroom int `yaml:"headroom"`
+	// HeadroomMB is the memory in MB reserved for sidecars and other processes in the container, which is
+	// subtracted from MEMORY_LIMIT before the calculation
+	HeadroomMB int `yaml:"headroom_mb"`
+	// StackThreads is the number of threads whose stacks are reserved
+	StackThreads int `yaml:"stack_threads"`
+	// MemorySizes fixes the size of memory regions, e.g. {metaspace: 128M, stack: 512K}, so that the calculator
+	// only sizes the remaining ones
+	MemorySizes map[string]string `yaml:"memory_sizes"`
+}
+
+// memoryRegionOptions maps the memory_sizes regions to the JVM options that size them
+var memoryRegionOptions = map[string]string{
+	"heap":                   "-Xmx",
+	"stack":                  "-Xss",
+	"metaspace":              "-XX:MaxMetaspaceSize=",
+	"compressed_class_space": "-XX:CompressedClassSpaceSize=",
+	"direct_memory":          "-XX:MaxDirectMemorySize=",
+	"code_cache":             "-XX:ReservedCodeCacheSize=",
+}
+
+var memorySizePattern = regexp.MustCompile(`^[0-9]+[kKmMgG]?$`)
+
+// MemoryProfile adapts the memory calculator's HotSpot options to a JVM implementation
+type MemoryProfile struct {
+	// Name identifies the JVM implementation in logs
+	Name string
+	// DroppedOptions are calculated -XX options the JVM does not use, e.g. MaxMetaspaceSize
+	DroppedOptions []string
+	// ExtraOptions are appended to the calculated options
+	ExtraOptions []string
+}
+
+// HotSpotMemoryProfile passes the calculated options through unchanged
+var HotSpotMemoryProfile = MemoryProfile{Name: "HotSpot"}
+
+// OpenJ9MemoryProfile keeps -Xmx, -Xss and -XX:MaxDirectMemorySize, drops the HotSpot metaspace and code cache
+// limits OpenJ9 does not have and, if sharedClassCacheSize is set, sizes the shared class cache with -Xscmx
+func OpenJ9MemoryProfile(sharedClassCacheSize string) MemoryProfile {
+	profile := MemoryProfile{
+		Name:           "OpenJ9",
+		DroppedOptions: []string{"MaxMetaspaceSize", "CompressedClassSpaceSize", "ReservedCodeCacheSize"},
+	}
+	if sharedClassCacheSize != "" {
+		profile.ExtraOptions = []string{"-Xscmx" + sharedClassCacheSize}
+	}
+	return profile
 }
 
 // NewMemoryCalculator creates a new memory calculator
 func NewMemoryCalculator(ctx *common.Context, jreDir, jreVersion string, javaMajorVersion int) *MemoryCalculator {
 	return &MemoryCalculator{
-		ctx:              ctx,
-		jreDir:           jreDir,
-		jreVersion:       jreVersion,
-		javaMajorVersion: javaMajorVersion,
-		stackThreads:     DefaultStackThreads,
-		headroom:         DefaultHeadroom,
+		ctx:                 ctx,
+		jreDir:              jreDir,
+		jreVersion:          jreVersion,
+		javaMajorVersion:    javaMajorVersion,
+		classCountSizeLimit: DefaultClassCountSizeLimit,
+		stackThreads:        DefaultStackThreads,
+		headroom:            DefaultHeadroom,
+		profile:             HotSpotMemoryProfile,
 	}
 }
 
+// SetProfile selects the JVM implementation the calculated options are adapted to
+func (m *MemoryCalculator) SetProfile(profile MemoryProfile) {
+	m.profile = profile
+}
+
 // Name returns the component name
 func (m *MemoryCalculator) Name() string {
 	return "Memory Calculator"
@@ -45,6 +119,13 @@ func (m *MemoryCalculator) Name() string {
 
 // Supply installs the memory calculator
 func (m *MemoryCalculator) Supply() error {
+	if fixed, err := fixedMemoryEnabled(m.ctx); err != nil {
+		return err
+	} else if fixed {
+		m.ctx.Log.Info("Skipping Memory Calculator, fixed memory settings are configured")
+		return nil
+	}
+
 	// Get memory calculator version from manifest
 	dep, err := m.ctx.Manifest.DefaultVersion("memory-calculator")
 	if err != nil {
@@ -107,14 +188,17 @@ func (m *MemoryCalculator) Supply() error {
 
 	m.calculatorPath = finalPath
 
-	// Count classes in the application
-	if err := m.countClasses(); err != nil {
-		m.ctx.Log.Warning("Failed to count classes: %s (using default)", err.Error())
-		m.classCount = 0 // Will be calculated as 35% of actual later
+	// Count classes in the application, unless class_count is configured
+	if m.classCount == 0 {
+		if err := m.countClasses(); err != nil {
+			m.ctx.Log.Warning("Failed to count classes: %s (using default)", err.Error())
+			m.classCount = 0 // Will be calculated as 35% of actual later
+		}
 	}
 
 	m.ctx.Log.Info("Memory Calculator installed: Loaded Classes: %d, Threads: %d",
 		m.classCount, m.stackThreads)
+	m.logRecommendation()
 
 	// Clean up temp directory
 	os.RemoveAll(tempDir)
@@ -152,6 +236,28 @@ func (m *MemoryCalculator) detectInstalledCalculator() {
 
 // Finalize configures the memory calculator in the startup command
 func (m *MemoryCalculator) Finalize() error {
+	// The container's limits apply to the calculated and the fixed memory settings
+	if err := writeContainerLimits(m.ctx); err != nil {
+		return err
+	}
+
+	fixedMemory, err := LoadFixedMemoryConfig(m.ctx)
+	if err != nil {
+		return err
+	}
+	if fixedMemory.Enabled {
+		return m.finalizeFixedMemory(fixedMemory)
+	}
+
+	if err := m.writeMemoryReservation(); err != nil {
+		return err
+	}
+
+	// Memory sizes are fixed even if the calculator is not installed
+	if err := m.writeMemorySizes(); err != nil {
+		return err
+	}
+
 	// If calculatorPath not set, try to detect it from previous installation
 	if m.calculatorPath == "" {
 		m.detectInstalledCalculator()
@@ -162,6 +268,7 @@ func (m *MemoryCalculator) Finalize() error {
 	}
 
 	m.ctx.Log.Info("Configuring Memory Calculator")
+	m.ctx.Log.Debug("Memory Calculator profile: %s", m.profile.Name)
 
 	// The memory calculator command will be added to the startup script
 	// It's executed at runtime to calculate memory based on actual container limits
@@ -179,14 +286,14 @@ func (m *MemoryCalculator) Finalize() error {
 	scriptContent := fmt.Sprintf(`#!/bin/bash
 # Memory Calculator - calculates optimal JVM memory settings
 if [ -n "$MEMORY_LIMIT" ]; then
-  CALCULATED_MEMORY=$(%s)
+  %s
   echo "JVM Memory Configuration: $CALCULATED_MEMORY"
   export JAVA_OPTS="$JAVA_OPTS $CALCULATED_MEMORY"
 fi
 
 # Set MALLOC_ARENA_MAX to reduce memory overhead
 export MALLOC_ARENA_MAX=2
-`, calculatorCmd)
+`, m.calculatedMemory(calculatorCmd))
 
 	if err := os.WriteFile(memoryCalcScript, []byte(scriptContent), 0755); err != nil {
 		return fmt.Errorf("failed to write memory calculator script: %w", err)
@@ -197,6 +304,71 @@ export MALLOC_ARENA_MAX=2
 	return nil
 }
 
+// finalizeFixedMemory adds the operator-defined memory options to JAVA_OPTS instead of running the calculator
+func (m *MemoryCalculator) finalizeFixedMemory(cfg FixedMemoryConfig) error {
+	m.ctx.Log.Info("Configuring fixed memory settings: %s", cfg.JavaOpts)
+	if cfg.JavaOpts == "" {
+		return nil
+	}
+	return WriteJavaOptsWithPriority(m.ctx, javaopts.JRE, "fixed_memory", cfg.JavaOpts)
+}
+
+// memoryReservationScript subtracts the memory reserved for sidecars from MEMORY_LIMIT at runtime. It is sourced
+// after profile.d/00_cgroup.sh, so it starts from the container's cgroup limit if that is lower.
+const memoryReservationScript = `#!/bin/bash
+# Leaves %[2]dm of the container's memory to sidecars and other processes
+source "$DEPS_DIR/%[1]s/bin/cgroup.sh"
+
+if [ -n "$MEMORY_LIMIT" ]; then
+  JVM_MEMORY_LIMIT=$(( $(memory_bytes "$MEMORY_LIMIT") / 1048576 - %[2]d ))
+  if [ "$JVM_MEMORY_LIMIT" -gt 0 ]; then
+    echo "Reserving %[2]dm of MEMORY_LIMIT=$MEMORY_LIMIT for sidecars, sizing the JVM for ${JVM_MEMORY_LIMIT}m" >&2
+    export MEMORY_LIMIT="${JVM_MEMORY_LIMIT}m"
+  else
+    echo "WARNING: headroom_mb of %[2]dm does not fit into MEMORY_LIMIT=$MEMORY_LIMIT, ignoring it" >&2
+  fi
+  unset JVM_MEMORY_LIMIT
+fi
+`
+
+// writeMemoryReservation writes profile.d/00_memory_reservation.sh if headroom_mb reserves memory for sidecars.
+// The reservation is only configured: VCAP_APPLICATION carries the limits of the whole container, and the memory
+// of the sidecars declared in the application manifest reaches neither staging nor the application.
+func (m *MemoryCalculator) writeMemoryReservation() error {
+	if m.headroomMB == 0 {
+		return nil
+	}
+
+	m.ctx.Log.Info("Memory Calculator reserves %dM of the container's memory for sidecars", m.headroomMB)
+	script := fmt.Sprintf(memoryReservationScript, m.ctx.Stager.DepsIdx(), m.headroomMB)
+	if err := m.ctx.Stager.WriteProfileD("00_memory_reservation.sh", script); err != nil {
+		return fmt.Errorf("failed to write 00_memory_reservation.sh: %w", err)
+	}
+	return nil
+}
+
+// writeMemorySizes adds the options of the configured memory_sizes to JAVA_OPTS. The calculator receives them
+// with $JAVA_OPTS and sizes the remaining regions around them.
+func (m *MemoryCalculator) writeMemorySizes() error {
+	if len(m.memorySizes) == 0 {
+		return nil
+	}
+
+	regions := make([]string, 0, len(m.memorySizes))
+	for region := range m.memorySizes {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+
+	opts := make([]string, 0, len(regions))
+	for _, region := range regions {
+		opts = append(opts, memoryRegionOptions[region]+m.memorySizes[region])
+	}
+
+	m.ctx.Log.Info("Memory Calculator memory sizes: %s", strings.Join(opts, " "))
+	return WriteJavaOptsWithPriority(m.ctx, javaopts.JRE, "memory_sizes", strings.Join(opts, " "))
+}
+
 // buildCalculatorCommand builds the memory calculator command with all arguments (v4.x format)
 func (m *MemoryCalculator) buildCalculatorCommand() string {
 	args := []string{
@@ -228,6 +400,18 @@ func (m *MemoryCalculator) buildCalculatorCommand() string {
 func (m *MemoryCalculator) countClasses() error {
 	buildDir := m.ctx.Stager.BuildDir()
 
+	if m.classCountSizeLimit > 0 {
+		size, err := applicationSize(buildDir)
+		if err != nil {
+			return fmt.Errorf("failed to determine application size: %w", err)
+		}
+		if size > m.classCountSizeLimit {
+			m.estimateClasses(size)
+			return nil
+		}
+		m.ctx.Log.Debug("Counting the classes of the %s application", formatMegabytes(size))
+	}
+
 	classCount := 0
 
 	// Walk the build directory
@@ -270,9 +454,7 @@ func (m *MemoryCalculator) countClasses() error {
 	}
 
 	// Add JRE classes for Java 9+
-	if m.javaMajorVersion >= 9 {
-		classCount += Java9ClassCount
-	}
+	classCount += m.jreClassCount()
 
 	// Apply 35% factor as per original buildpack logic
 	// This accounts for the fact that not all classes are loaded
@@ -283,6 +465,59 @@ func (m *MemoryCalculator) countClasses() error {
 	return nil
 }
 
+// estimateClasses estimates the loaded classes of an application of size bytes that is too large to count them
+func (m *MemoryCalculator) estimateClasses(size int64) {
+	classCount := int(size / (1024 * 1024) * EstimatedClassesPerMB)
+	if classCount > MaxEstimatedClasses {
+		classCount = MaxEstimatedClasses
+	}
+	classCount += m.jreClassCount()
+	m.classCount = int(float64(classCount) * 0.35)
+
+	m.ctx.Log.Info("Application is %s, larger than class_count_size_limit %s: estimated %d loaded classes from its "+
+		"size instead of counting them. Set memory_calculator.class_count for an exact number",
+		formatMegabytes(size), formatMegabytes(m.classCountSizeLimit), m.classCount)
+}
+
+// jreClassCount returns the number of classes of the JRE that count towards the loaded classes: none before Java 9,
+// whose metaspace sizing did not include them, Java9ClassCount up to Java9ClassCountMaxVersion and the classes in
+// lib/modules of later versions, or Java9ClassCount if they cannot be counted
+func (m *MemoryCalculator) jreClassCount() int {
+	if m.javaMajorVersion < 9 {
+		return 0
+	}
+	if m.javaMajorVersion <= Java9ClassCountMaxVersion {
+		return Java9ClassCount
+	}
+
+	image, err := findModulesImage(m.jreDir)
+	if err == nil {
+		var count int
+		if count, err = countJImageClasses(image); err == nil {
+			m.ctx.Log.Debug("Java %d JRE has %d classes", m.javaMajorVersion, count)
+			return count
+		}
+	}
+	m.ctx.Log.Debug("Unable to count the classes of the Java %d JRE, assuming %d: %s", m.javaMajorVersion,
+		Java9ClassCount, err.Error())
+	return Java9ClassCount
+}
+
+// applicationSize returns the total size of the files in buildDir
+func applicationSize(buildDir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(buildDir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return nil // Skip files we can't access
+		}
+		if info, err := entry.Info(); err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
 // countClassesInJar counts .class and .groovy files in a JAR file
 func (m *MemoryCalculator) countClassesInJar(jarPath string) (int, error) {
 	// Open JAR file as ZIP
@@ -314,8 +549,7 @@ func (m *MemoryCalculator) GetCalculatorCommand() string {
 		return ""
 	}
 
-	// Convert staging path to runtime path
-	runtimePath := m.convertToRuntimePath(m.calculatorPath)
+	runtimePath := m.runtimeCalculatorPath()
 
 	// Build calculator args (v4.x uses double-dash long flags)
 	args := []string{
@@ -341,38 +575,37 @@ func (m *MemoryCalculator) GetCalculatorCommand() string {
 
 	calcCmd := strings.Join(args, " ")
 
-	return fmt.Sprintf(`CALCULATED_MEMORY=$(%s) && echo JVM Memory Configuration: $CALCULATED_MEMORY && JAVA_OPTS="$JAVA_OPTS $CALCULATED_MEMORY" && MALLOC_ARENA_MAX=2`, calcCmd)
+	return fmt.Sprintf(`%s && echo JVM Memory Configuration: $CALCULATED_MEMORY && JAVA_OPTS="$JAVA_OPTS $CALCULATED_MEMORY" && MALLOC_ARENA_MAX=2`, m.calculatedMemory(calcCmd))
 }
 
-// convertToRuntimePath converts a staging path to a runtime path
-// Example: /tmp/staging/deps/0/jre/bin/calculator -> /home/vcap/deps/0/jre/bin/calculator
-func (m *MemoryCalculator) convertToRuntimePath(stagingPath string) string {
-	depsIdx := m.ctx.Stager.DepsIdx()
-
-	// Extract the relative path from deps/<idx>/ onwards
-	// stagingPath: /tmp/.../deps/<idx>/jre/bin/java-buildpack-memory-calculator-X.X.X
-	// We want: /home/vcap/deps/<idx>/jre/bin/java-buildpack-memory-calculator-X.X.X
-
-	// Find "jre/bin/" in the path
-	if idx := strings.Index(stagingPath, "jre/bin/"); idx != -1 {
-		relativePath := stagingPath[idx:]
-		return fmt.Sprintf("/home/vcap/deps/%s/%s", depsIdx, relativePath)
+// calculatedMemory returns the shell commands that set CALCULATED_MEMORY to the output of calcCmd, adapted to
+// the memory profile. A failing calculator still fails the commands.
+func (m *MemoryCalculator) calculatedMemory(calcCmd string) string {
+	commands := []string{fmt.Sprintf("CALCULATED_MEMORY=$(%s)", calcCmd)}
+	if len(m.profile.DroppedOptions) > 0 {
+		commands = append(commands, fmt.Sprintf(`CALCULATED_MEMORY=$(echo "$CALCULATED_MEMORY" | sed -E 's/ ?-XX:(%s)=[^ ]*//g')`,
+			strings.Join(m.profile.DroppedOptions, "|")))
 	}
-
-	// Fallback: just use the filename
-	filename := filepath.Base(stagingPath)
-	return fmt.Sprintf("/home/vcap/deps/%s/jre/bin/%s", depsIdx, filename)
+	if len(m.profile.ExtraOptions) > 0 {
+		commands = append(commands, fmt.Sprintf(`CALCULATED_MEMORY="$CALCULATED_MEMORY %s"`, strings.Join(m.profile.ExtraOptions, " ")))
+	}
+	return strings.Join(commands, " && ")
 }
 
-// LoadConfig loads memory calculator configuration from environment/config
-func (m *MemoryCalculator) LoadConfig() {
-	// Check for environment overrides
-	// JBP_CONFIG_OPEN_JDK_JRE='{memory_calculator: {stack_threads: 300}}'
-
-	// For now, using defaults
-	// In production, we'd parse JSON from environment variables
+// runtimeCalculatorPath returns the absolute runtime path of the calculator, which the start command runs before the
+// profile.d scripts set $DEPS_DIR
+func (m *MemoryCalculator) runtimeCalculatorPath() string {
+	runtimePath, err := m.ctx.Droplet().AbsolutePath(m.calculatorPath)
+	if err != nil {
+		return m.ctx.Droplet().AbsoluteDep("jre", "bin", filepath.Base(m.calculatorPath))
+	}
+	return runtimePath
+}
 
-	// Check specific environment variables
+// LoadConfig reads the memory_calculator section of the JRE configuration component, e.g. "open_jdk_jre",
+// from config/<component>.yml and JBP_CONFIG_<COMPONENT>. The MEMORY_CALCULATOR_STACK_THREADS and
+// MEMORY_CALCULATOR_HEADROOM environment variables are still honored if the configuration does not set them.
+func (m *MemoryCalculator) LoadConfig(component string) error {
 	if val := os.Getenv("MEMORY_CALCULATOR_STACK_THREADS"); val != "" {
 		if threads, err := strconv.Atoi(val); err == nil {
 			m.stackThreads = threads
@@ -384,6 +617,65 @@ func (m *MemoryCalculator) LoadConfig() {
 			m.headroom = headroom
 		}
 	}
+
+	jreConfig := struct {
+		MemoryCalculator MemoryCalculatorConfig `yaml:"memory_calculator"`
+	}{}
+	if err := config.Decode(component, &jreConfig); err != nil {
+		return err
+	}
+	cfg := jreConfig.MemoryCalculator
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	// A configured class count is used as is, unlike the counted classes which are scaled to 35%
+	if cfg.ClassCount > 0 {
+		m.classCount = cfg.ClassCount
+	}
+	if cfg.ClassCountSizeLimit != "" {
+		limit, err := parseMemorySize(cfg.ClassCountSizeLimit)
+		if err != nil {
+			return fmt.Errorf("invalid size %q for class_count_size_limit: %w", cfg.ClassCountSizeLimit, err)
+		}
+		m.classCountSizeLimit = limit
+	}
+	if cfg.StackThreads > 0 {
+		m.stackThreads = cfg.StackThreads
+	}
+	if cfg.Headroom > 0 {
+		m.headroom = cfg.Headroom
+	}
+	m.headroomMB = cfg.HeadroomMB
+	m.memorySizes = cfg.MemorySizes
+	return nil
+}
+
+// Validate returns an error if a setting is out of range or memory_sizes names an unknown region or an invalid size
+func (c MemoryCalculatorConfig) Validate() error {
+	if c.ClassCount < 0 || c.StackThreads < 0 || c.HeadroomMB < 0 {
+		return fmt.Errorf("class_count, stack_threads and headroom_mb must not be negative")
+	}
+	if c.Headroom < 0 || c.Headroom >= 100 {
+		return fmt.Errorf("headroom must be a percentage between 0 and 99, found %d", c.Headroom)
+	}
+	if c.ClassCountSizeLimit != "" && !memorySizePattern.MatchString(c.ClassCountSizeLimit) {
+		return fmt.Errorf("invalid size %q for class_count_size_limit", c.ClassCountSizeLimit)
+	}
+	regions := make([]string, 0, len(c.MemorySizes))
+	for region := range c.MemorySizes {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	for _, region := range regions {
+		if _, ok := memoryRegionOptions[region]; !ok {
+			return fmt.Errorf("unknown memory region %q in memory_sizes", region)
+		}
+		if size := c.MemorySizes[region]; !memorySizePattern.MatchString(size) {
+			return fmt.Errorf("invalid size %q for memory region %s", size, region)
+		}
+	}
+	return nil
 }
 
 // Helper function to copy files
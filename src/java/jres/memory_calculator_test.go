@@ -0,0 +1,316 @@
+package jres_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/jres"
+	"github.com/cloudfoundry/libbuildpack"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Memory Calculator", func() {
+	var (
+		ctx          *common.Context
+		depsDir      string
+		buildpackDir string
+		jreDir       string
+		calculator   *jres.MemoryCalculator
+	)
+
+	BeforeEach(func() {
+		var err error
+		depsDir, err = os.MkdirTemp("", "deps")
+		Expect(err).NotTo(HaveOccurred())
+		buildpackDir, err = os.MkdirTemp("", "buildpack")
+		Expect(err).NotTo(HaveOccurred())
+		os.Setenv("BUILDPACK_DIR", buildpackDir)
+
+		jreDir = filepath.Join(depsDir, "0", "jre")
+		Expect(os.MkdirAll(filepath.Join(jreDir, "bin"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(jreDir, "bin", "java-buildpack-memory-calculator-4.2.0"), []byte("#!/bin/sh\n"), 0755)).To(Succeed())
+
+		logger := libbuildpack.NewLogger(GinkgoWriter)
+		manifest := &libbuildpack.Manifest{}
+		ctx = &common.Context{
+			Stager:   libbuildpack.NewStager([]string{depsDir, "", depsDir, "0"}, logger, manifest),
+			Manifest: manifest,
+			Log:      logger,
+		}
+		calculator = jres.NewMemoryCalculator(ctx, jreDir, "17.0.13", 17)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(depsDir)
+		os.RemoveAll(buildpackDir)
+		os.Unsetenv("BUILDPACK_DIR")
+		os.Unsetenv("JBP_CONFIG_OPEN_JDK_JRE")
+		os.Unsetenv("MEMORY_CALCULATOR_STACK_THREADS")
+	})
+
+	Describe("LoadConfig", func() {
+		It("passes the configured settings to the calculator", func() {
+			os.Setenv("JBP_CONFIG_OPEN_JDK_JRE", "{jre: {version: 17.+}, memory_calculator: {stack_threads: 300, headroom: 10, class_count: 500}}")
+
+			Expect(calculator.LoadConfig("open_jdk_jre")).To(Succeed())
+			Expect(calculator.Finalize()).To(Succeed())
+
+			Expect(calculator.GetCalculatorCommand()).To(ContainSubstring("--head-room=10 --loaded-class-count=500 --thread-count=300"))
+		})
+
+		It("reads the buildpack's config file and lets the environment override it", func() {
+			Expect(os.MkdirAll(filepath.Join(buildpackDir, "config"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(buildpackDir, "config", "open_jdk_jre.yml"),
+				[]byte("memory_calculator:\n  stack_threads: 50\n  headroom: 5\n"), 0644)).To(Succeed())
+			os.Setenv("JBP_CONFIG_OPEN_JDK_JRE", "{memory_calculator: {stack_threads: 25}}")
+
+			Expect(calculator.LoadConfig("open_jdk_jre")).To(Succeed())
+			Expect(calculator.Finalize()).To(Succeed())
+
+			Expect(calculator.GetCalculatorCommand()).To(ContainSubstring("--head-room=5 "))
+			Expect(calculator.GetCalculatorCommand()).To(ContainSubstring("--thread-count=25 "))
+		})
+
+		It("keeps the built-in settings and the legacy environment variables with the shipped config file", func() {
+			shipped, err := filepath.Abs(filepath.Join("..", "..", ".."))
+			Expect(err).NotTo(HaveOccurred())
+			os.Setenv("BUILDPACK_DIR", shipped)
+
+			Expect(calculator.LoadConfig("open_jdk_jre")).To(Succeed())
+			Expect(calculator.Finalize()).To(Succeed())
+			Expect(calculator.GetCalculatorCommand()).NotTo(ContainSubstring("--head-room"))
+			Expect(calculator.GetCalculatorCommand()).To(ContainSubstring("--thread-count=250 "))
+
+			os.Setenv("MEMORY_CALCULATOR_STACK_THREADS", "100")
+			Expect(calculator.LoadConfig("open_jdk_jre")).To(Succeed())
+			Expect(calculator.Finalize()).To(Succeed())
+			Expect(calculator.GetCalculatorCommand()).To(ContainSubstring("--thread-count=100 "))
+		})
+
+		It("prefers the configuration over the legacy environment variables", func() {
+			os.Setenv("MEMORY_CALCULATOR_STACK_THREADS", "100")
+			Expect(calculator.LoadConfig("open_jdk_jre")).To(Succeed())
+			Expect(calculator.Finalize()).To(Succeed())
+			Expect(calculator.GetCalculatorCommand()).To(ContainSubstring("--thread-count=100 "))
+
+			os.Setenv("JBP_CONFIG_OPEN_JDK_JRE", "{memory_calculator: {stack_threads: 200}}")
+			Expect(calculator.LoadConfig("open_jdk_jre")).To(Succeed())
+			Expect(calculator.Finalize()).To(Succeed())
+			Expect(calculator.GetCalculatorCommand()).To(ContainSubstring("--thread-count=200 "))
+		})
+
+		It("adds memory_sizes to JAVA_OPTS", func() {
+			os.Setenv("JBP_CONFIG_OPEN_JDK_JRE", "{memory_calculator: {memory_sizes: {stack: 512K, metaspace: 128M}}}")
+
+			Expect(calculator.LoadConfig("open_jdk_jre")).To(Succeed())
+			Expect(calculator.Finalize()).To(Succeed())
+
+			opts, err := os.ReadFile(filepath.Join(depsDir, "0", "java_opts", "05_memory_sizes.opts"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(opts)).To(Equal("-XX:MaxMetaspaceSize=128M -Xss512K"))
+		})
+
+		It("rejects unknown memory regions and sizes", func() {
+			os.Setenv("JBP_CONFIG_OPEN_JDK_JRE", "{memory_calculator: {memory_sizes: {permgen: 64M}}}")
+			Expect(calculator.LoadConfig("open_jdk_jre")).To(MatchError(ContainSubstring(`unknown memory region "permgen"`)))
+
+			os.Setenv("JBP_CONFIG_OPEN_JDK_JRE", "{memory_calculator: {memory_sizes: {heap: 1.5G}}}")
+			Expect(calculator.LoadConfig("open_jdk_jre")).To(MatchError(ContainSubstring(`invalid size "1.5G"`)))
+
+			os.Setenv("JBP_CONFIG_OPEN_JDK_JRE", "{memory_calculator: {headroom: 100}}")
+			Expect(calculator.LoadConfig("open_jdk_jre")).To(MatchError(ContainSubstring("headroom")))
+
+			os.Setenv("JBP_CONFIG_OPEN_JDK_JRE", "{memory_calculator: {headroom_mb: -1}}")
+			Expect(calculator.LoadConfig("open_jdk_jre")).To(MatchError(ContainSubstring("headroom_mb")))
+		})
+
+		It("reserves headroom_mb of MEMORY_LIMIT for sidecars", func() {
+			Expect(calculator.Finalize()).To(Succeed())
+			Expect(filepath.Join(depsDir, "0", "profile.d", "00_memory_reservation.sh")).NotTo(BeAnExistingFile())
+
+			os.Setenv("JBP_CONFIG_OPEN_JDK_JRE", "{memory_calculator: {headroom_mb: 256}}")
+			Expect(calculator.LoadConfig("open_jdk_jre")).To(Succeed())
+			Expect(calculator.Finalize()).To(Succeed())
+
+			// The profile.d scripts run in order before the calculator, here without cgroup limits
+			memoryLimit := func(limit string) string {
+				cmd := exec.Command("bash", "-c", `for script in "$DEPS_DIR"/0/profile.d/*.sh; do source "$script"; done 2> /dev/null; echo "$MEMORY_LIMIT"`)
+				cmd.Env = append(os.Environ(), "DEPS_DIR="+depsDir, "CGROUP_ROOT="+depsDir, "MEMORY_LIMIT="+limit)
+				output, err := cmd.CombinedOutput()
+				Expect(err).NotTo(HaveOccurred(), string(output))
+				return strings.TrimSpace(string(output))
+			}
+			Expect(memoryLimit("1024m")).To(Equal("768m"))
+			Expect(memoryLimit("1G")).To(Equal("768m"))
+			Expect(memoryLimit("256m")).To(Equal("256m"))
+		})
+	})
+
+	Describe("class counting", func() {
+		var logs *bytes.Buffer
+
+		// writeApplicationFile writes a sparse file of size MB to the application
+		writeApplicationFile := func(name string, size int64) {
+			f, err := os.Create(filepath.Join(depsDir, name))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(f.Truncate(size << 20)).To(Succeed())
+			Expect(f.Close()).To(Succeed())
+		}
+
+		BeforeEach(func() {
+			logs = new(bytes.Buffer)
+			ctx.Log = libbuildpack.NewLogger(logs)
+		})
+
+		It("counts the classes of large applications by default", func() {
+			writeApplicationFile("huge.jar", 2048)
+
+			Expect(calculator.Finalize()).To(Succeed())
+
+			Expect(calculator.GetCalculatorCommand()).To(ContainSubstring("--loaded-class-count=14775 "))
+			Expect(logs.String()).NotTo(ContainSubstring("class_count_size_limit"))
+		})
+
+		It("estimates the classes of applications above class_count_size_limit from their size", func() {
+			os.Setenv("JBP_CONFIG_OPEN_JDK_JRE", "{memory_calculator: {class_count_size_limit: 10M}}")
+			writeApplicationFile("huge.jar", 100)
+
+			Expect(calculator.LoadConfig("open_jdk_jre")).To(Succeed())
+			Expect(calculator.Finalize()).To(Succeed())
+
+			// 35% of 100 MB at 400 classes per MB and the classes of the JRE
+			Expect(calculator.GetCalculatorCommand()).To(ContainSubstring("--loaded-class-count=28775 "))
+			Expect(logs.String()).To(ContainSubstring("larger than class_count_size_limit 10M: estimated 28775 loaded classes"))
+		})
+
+		It("caps the estimated classes of the application", func() {
+			os.Setenv("JBP_CONFIG_OPEN_JDK_JRE", "{memory_calculator: {class_count_size_limit: 10M}}")
+			writeApplicationFile("huge.jar", 1000)
+
+			Expect(calculator.LoadConfig("open_jdk_jre")).To(Succeed())
+			Expect(calculator.Finalize()).To(Succeed())
+
+			// 35% of MaxEstimatedClasses and the classes of the JRE
+			Expect(calculator.GetCalculatorCommand()).To(ContainSubstring("--loaded-class-count=49775 "))
+		})
+
+		It("always counts with a class_count_size_limit of 0", func() {
+			os.Setenv("JBP_CONFIG_OPEN_JDK_JRE", "{memory_calculator: {class_count_size_limit: 0}}")
+			writeApplicationFile("huge.jar", 100)
+
+			Expect(calculator.LoadConfig("open_jdk_jre")).To(Succeed())
+			Expect(calculator.Finalize()).To(Succeed())
+
+			Expect(logs.String()).NotTo(ContainSubstring("class_count_size_limit"))
+		})
+
+		It("does not count or estimate a configured class_count", func() {
+			os.Setenv("JBP_CONFIG_OPEN_JDK_JRE", "{memory_calculator: {class_count: 12000, class_count_size_limit: 10M}}")
+			writeApplicationFile("huge.jar", 100)
+
+			Expect(calculator.LoadConfig("open_jdk_jre")).To(Succeed())
+			Expect(calculator.Finalize()).To(Succeed())
+
+			Expect(calculator.GetCalculatorCommand()).To(ContainSubstring("--loaded-class-count=12000 "))
+			Expect(logs.String()).NotTo(ContainSubstring("class_count_size_limit"))
+		})
+
+		Context("on Java 25", func() {
+			BeforeEach(func() {
+				calculator = jres.NewMemoryCalculator(ctx, jreDir, "25.0.1", 25)
+			})
+
+			It("counts the classes in lib/modules of the JRE", func() {
+				Expect(os.MkdirAll(filepath.Join(jreDir, "jdk-25.0.1+8-jre", "lib"), 0755)).To(Succeed())
+				writeJImage(filepath.Join(jreDir, "jdk-25.0.1+8-jre", "lib", "modules"), 50000, 120)
+
+				Expect(calculator.Finalize()).To(Succeed())
+
+				// 35% of the 50000 classes of the JRE
+				Expect(calculator.GetCalculatorCommand()).To(ContainSubstring("--loaded-class-count=17500 "))
+			})
+
+			It("falls back to the Java 9 class count without lib/modules", func() {
+				Expect(calculator.Finalize()).To(Succeed())
+
+				Expect(calculator.GetCalculatorCommand()).To(ContainSubstring("--loaded-class-count=14775 "))
+			})
+		})
+
+		It("rejects an invalid class_count_size_limit", func() {
+			os.Setenv("JBP_CONFIG_OPEN_JDK_JRE", "{memory_calculator: {class_count_size_limit: 2GB}}")
+
+			Expect(calculator.LoadConfig("open_jdk_jre")).To(MatchError(ContainSubstring(`invalid size "2GB" for class_count_size_limit`)))
+		})
+
+		It("rejects a class_count_size_limit out of range", func() {
+			os.Setenv("JBP_CONFIG_OPEN_JDK_JRE", "{memory_calculator: {class_count_size_limit: 99999999999999999999G}}")
+
+			Expect(calculator.LoadConfig("open_jdk_jre")).To(MatchError(ContainSubstring(`invalid size "99999999999999999999G" for class_count_size_limit`)))
+		})
+	})
+
+	Describe("Recommendation", func() {
+		It("estimates the regions the calculator reserves with the default settings", func() {
+			r := calculator.Recommendation()
+			Expect(r.ClassCount).To(Equal(6300))
+			Expect(r.Threads).To(Equal(250))
+			Expect(r.Metaspace).To(Equal(int64(6300*5800 + 14000000)))
+			Expect(r.ThreadStacks).To(Equal(int64(250 << 20)))
+			Expect(r.CodeCache).To(Equal(int64(240 << 20)))
+			Expect(r.DirectMemory).To(Equal(int64(10 << 20)))
+			Expect(r.Heap).To(BeZero())
+			Expect(r.MinimumMemoryLimit).To(Equal(r.Metaspace + r.ThreadStacks + r.CodeCache + r.DirectMemory))
+		})
+
+		It("uses the configured memory sizes, threads and headroom", func() {
+			os.Setenv("JBP_CONFIG_OPEN_JDK_JRE", "{memory_calculator: {class_count: 1000, stack_threads: 100, "+
+				"headroom: 20, headroom_mb: 64, memory_sizes: {stack: 512K, heap: 256M, code_cache: 64m}}}")
+			Expect(calculator.LoadConfig("open_jdk_jre")).To(Succeed())
+
+			r := calculator.Recommendation()
+			Expect(r.Metaspace).To(Equal(int64(1000*5800 + 14000000)))
+			Expect(r.ThreadStacks).To(Equal(int64(50 << 20)))
+			Expect(r.CodeCache).To(Equal(int64(64 << 20)))
+			Expect(r.Heap).To(Equal(int64(256 << 20)))
+
+			required := r.Metaspace + r.ThreadStacks + r.CodeCache + r.DirectMemory + r.Heap
+			Expect(r.MinimumMemoryLimit).To(BeNumerically("~", required*100/80+64<<20, 1))
+		})
+	})
+})
+
+// writeJImage writes a jimage file with the index of classes classes and others other resources
+func writeJImage(path string, classes, others int) {
+	names := []byte("\x00class\x00properties\x00")
+	const classOffset, propertiesOffset = 1, 7
+
+	var locations []byte
+	var offsets []uint32
+	for i := 0; i < classes+others; i++ {
+		extension := byte(classOffset)
+		if i >= classes {
+			extension = propertiesOffset
+		}
+		offsets = append(offsets, uint32(len(locations)))
+		// a one byte extension attribute, a two byte offset attribute and the end
+		locations = append(locations, 4<<3, extension, 5<<3|1, byte(i>>8), byte(i), 0)
+	}
+
+	var image bytes.Buffer
+	for _, value := range []uint32{0xCAFEDADA, 1 << 16, 0, uint32(len(offsets)), uint32(len(offsets)),
+		uint32(len(locations)), uint32(len(names))} {
+		Expect(binary.Write(&image, binary.LittleEndian, value)).To(Succeed())
+	}
+	Expect(binary.Write(&image, binary.LittleEndian, make([]int32, len(offsets)))).To(Succeed())
+	Expect(binary.Write(&image, binary.LittleEndian, offsets)).To(Succeed())
+	image.Write(locations)
+	image.Write(names)
+	Expect(os.WriteFile(path, image.Bytes(), 0644)).To(Succeed())
+}
@@ -0,0 +1,118 @@
+package jres
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Sizes the memory calculator v4 uses for the regions it does not derive from the container's memory. See
+// https://github.com/cloudfoundry/java-buildpack-memory-calculator.
+const (
+	calculatorClassSize     = 5800     // bytes of metaspace per loaded class
+	calculatorClassOverhead = 14000000 // bytes of metaspace independent of the number of classes
+	calculatorCodeCache     = 240 * 1024 * 1024
+	calculatorDirectMemory  = 10 * 1024 * 1024
+	calculatorStackSize     = 1024 * 1024
+)
+
+// MemoryRecommendation is the sizing guidance derived at staging time from the inputs of the memory calculator.
+// Sizes are in bytes.
+type MemoryRecommendation struct {
+	ClassCount   int
+	Threads      int
+	Metaspace    int64
+	ThreadStacks int64
+	CodeCache    int64
+	DirectMemory int64
+	// Heap is the heap fixed with memory_sizes, or 0 if the calculator sizes it
+	Heap int64
+	// MinimumMemoryLimit is the MEMORY_LIMIT the regions above need, including headroom and headroom_mb. Unless
+	// the heap is fixed, a container of this size leaves no memory for the heap and the calculator fails.
+	MinimumMemoryLimit int64
+}
+
+// Recommendation estimates the regions the memory calculator will reserve at start-up from the class count, the
+// thread count and the configured memory_sizes, and the smallest MEMORY_LIMIT that holds them
+func (m *MemoryCalculator) Recommendation() MemoryRecommendation {
+	classCount := m.classCount
+	if classCount == 0 {
+		classCount = int(float64(DefaultClassCount) * 0.35)
+	}
+
+	r := MemoryRecommendation{
+		ClassCount:   classCount,
+		Threads:      m.stackThreads,
+		Metaspace:    m.fixedSize("metaspace", int64(classCount)*calculatorClassSize+calculatorClassOverhead),
+		ThreadStacks: int64(m.stackThreads) * m.fixedSize("stack", calculatorStackSize),
+		CodeCache:    m.fixedSize("code_cache", calculatorCodeCache),
+		DirectMemory: m.fixedSize("direct_memory", calculatorDirectMemory),
+		Heap:         m.fixedSize("heap", 0),
+	}
+
+	required := r.Metaspace + r.ThreadStacks + r.CodeCache + r.DirectMemory + r.Heap
+	// The calculator sizes the regions from the memory left after headroom, which is taken from MEMORY_LIMIT after
+	// headroom_mb is subtracted
+	required = (required*100 + int64(100-m.headroom) - 1) / int64(100-m.headroom)
+	r.MinimumMemoryLimit = required + int64(m.headroomMB)*1024*1024
+	return r
+}
+
+// logRecommendation logs the Recommendation as guidance for sizing the application
+func (m *MemoryCalculator) logRecommendation() {
+	r := m.Recommendation()
+
+	heap, minimum := "the remainder of the memory", formatMegabytes(r.MinimumMemoryLimit)+" plus the heap"
+	if r.Heap > 0 {
+		heap, minimum = formatMegabytes(r.Heap)+" (memory_sizes)", formatMegabytes(r.MinimumMemoryLimit)
+	}
+
+	m.ctx.Log.Info("Memory Calculator sizing recommendation for %d loaded classes and %d threads:", r.ClassCount, r.Threads)
+	m.ctx.Log.Info("  Metaspace:            %s (-XX:MaxMetaspaceSize)", formatMegabytes(r.Metaspace))
+	m.ctx.Log.Info("  Thread stacks:        %s (%d threads x %s)", formatMegabytes(r.ThreadStacks), r.Threads,
+		formatMegabytes(r.ThreadStacks/int64(max(r.Threads, 1))))
+	m.ctx.Log.Info("  Code cache:           %s", formatMegabytes(r.CodeCache))
+	m.ctx.Log.Info("  Direct memory:        %s", formatMegabytes(r.DirectMemory))
+	m.ctx.Log.Info("  Heap:                 %s", heap)
+	m.ctx.Log.Info("  Minimum MEMORY_LIMIT: %s; lower stack_threads or set memory_sizes to reduce it", minimum)
+}
+
+// fixedSize returns the size of region fixed with memory_sizes in bytes, or size if it is not fixed
+func (m *MemoryCalculator) fixedSize(region string, size int64) int64 {
+	value, ok := m.memorySizes[region]
+	if !ok {
+		return size
+	}
+
+	bytes, err := parseMemorySize(value)
+	if err != nil {
+		return size
+	}
+	return bytes
+}
+
+// parseMemorySize returns the bytes of a size in the JVM's notation, e.g. 512K or 2G
+func parseMemorySize(value string) (int64, error) {
+	if value == "" {
+		return 0, fmt.Errorf("empty size")
+	}
+	multiplier := int64(1)
+	switch strings.ToUpper(value[len(value)-1:]) {
+	case "K":
+		multiplier = 1024
+	case "M":
+		multiplier = 1024 * 1024
+	case "G":
+		multiplier = 1024 * 1024 * 1024
+	}
+	number, err := strconv.ParseInt(strings.TrimRight(value, "kKmMgG"), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return number * multiplier, nil
+}
+
+// formatMegabytes formats bytes as whole megabytes, rounded up
+func formatMegabytes(bytes int64) string {
+	return fmt.Sprintf("%dM", (bytes+1024*1024-1)/(1024*1024))
+}
@@ -17,6 +17,8 @@ type OpenJDKJRE struct {
 	memoryCalc       *MemoryCalculator
 	jvmkill          *JVMKillAgent
 	installedVersion string
+
+	versionSelection
 }
 
 // NewOpenJDKJRE creates a new OpenJDK JRE provider
@@ -45,7 +47,7 @@ func (o *OpenJDKJRE) Supply() error {
 	o.ctx.Log.BeginStep("Installing OpenJDK JRE")
 
 	// Determine version
-	dep, err := GetJREVersion(o.ctx, "openjdk")
+	dep, err := GetJREVersion(o.ctx, "openjdk", o.selectedVersion)
 	if err != nil {
 		return fmt.Errorf("failed to determine OpenJDK version from manifest: %w", err)
 	}
@@ -54,9 +56,11 @@ func (o *OpenJDKJRE) Supply() error {
 	o.ctx.Log.Info("Installing OpenJDK (%s)", o.version)
 
 	// Install JRE
-	if err := o.ctx.Installer.InstallDependency(dep, o.jreDir); err != nil {
+	installed, err := InstallJRE(o.ctx, dep, o.jreDir)
+	if err != nil {
 		return fmt.Errorf("failed to install OpenJDK: %w", err)
 	}
+	o.version = installed.Version
 
 	// Find the actual JAVA_HOME (handle nested directories from tar extraction)
 	javaHome, err := o.findJavaHome()
@@ -114,6 +118,9 @@ func (o *OpenJDKJRE) Supply() error {
 
 	// Install Memory Calculator
 	o.memoryCalc = NewMemoryCalculator(o.ctx, o.jreDir, o.version, javaMajorVersion)
+	if err := o.memoryCalc.LoadConfig("open_jdk_jre"); err != nil {
+		return fmt.Errorf("invalid memory calculator configuration: %w", err)
+	}
 	if err := o.memoryCalc.Supply(); err != nil {
 		o.ctx.Log.Warning("Failed to install Memory Calculator: %s (continuing)", err.Error())
 		// Non-fatal - continue without memory calculator
@@ -170,8 +177,7 @@ func (o *OpenJDKJRE) Finalize() error {
 	// Add base JAVA_OPTS for compatibility with Ruby buildpack
 	// These are standard JVM options that should be set for all OpenJDK-like JREs
 	baseOpts := []string{
-		"-Djava.io.tmpdir=$TMPDIR",          // Temp directory
-		"-XX:ActiveProcessorCount=$(nproc)", // CPU count
+		"-Djava.io.tmpdir=$TMPDIR", // Temp directory
 	}
 
 	// Note: We do NOT set -Djava.ext.dirs= here because frameworks like Container Security Provider
@@ -185,6 +191,9 @@ func (o *OpenJDKJRE) Finalize() error {
 	// Reconstruct Memory Calculator component if not already set
 	if o.memoryCalc == nil {
 		o.memoryCalc = NewMemoryCalculator(o.ctx, o.jreDir, o.version, javaMajorVersion)
+		if err := o.memoryCalc.LoadConfig("open_jdk_jre"); err != nil {
+			return fmt.Errorf("invalid memory calculator configuration: %w", err)
+		}
 	}
 
 	// Finalize Memory Calculator
@@ -17,6 +17,8 @@ type OracleJRE struct {
 	memoryCalc       *MemoryCalculator
 	jvmkill          *JVMKillAgent
 	installedVersion string
+
+	versionSelection
 }
 
 // NewOracleJRE creates a new Oracle JRE provider
@@ -45,7 +47,7 @@ func (o *OracleJRE) Supply() error {
 	o.ctx.Log.BeginStep("Installing Oracle JRE")
 
 	// Determine version
-	dep, err := GetJREVersion(o.ctx, "oracle")
+	dep, err := GetJREVersion(o.ctx, "oracle", o.selectedVersion)
 	if err != nil {
 		return fmt.Errorf("failed to determine Oracle JRE version from manifest: %w", err)
 	}
@@ -54,9 +56,11 @@ func (o *OracleJRE) Supply() error {
 	o.ctx.Log.Info("Installing Oracle JRE (%s)", o.version)
 
 	// Install JRE
-	if err := o.ctx.Installer.InstallDependency(dep, o.jreDir); err != nil {
+	installed, err := InstallJRE(o.ctx, dep, o.jreDir)
+	if err != nil {
 		return fmt.Errorf("failed to install Oracle JRE: %w", err)
 	}
+	o.version = installed.Version
 
 	// Find the actual JAVA_HOME (handle nested directories from tar extraction)
 	javaHome, err := o.findJavaHome()
@@ -90,6 +94,9 @@ func (o *OracleJRE) Supply() error {
 
 	// Install Memory Calculator
 	o.memoryCalc = NewMemoryCalculator(o.ctx, o.jreDir, o.version, javaMajorVersion)
+	if err := o.memoryCalc.LoadConfig("oracle_jre"); err != nil {
+		return fmt.Errorf("invalid memory calculator configuration: %w", err)
+	}
 	if err := o.memoryCalc.Supply(); err != nil {
 		o.ctx.Log.Warning("Failed to install Memory Calculator: %s (continuing)", err.Error())
 		// Non-fatal - continue without memory calculator
@@ -145,6 +152,9 @@ func (o *OracleJRE) Finalize() error {
 	// Reconstruct Memory Calculator component if not already set
 	if o.memoryCalc == nil {
 		o.memoryCalc = NewMemoryCalculator(o.ctx, o.jreDir, o.version, javaMajorVersion)
+		if err := o.memoryCalc.LoadConfig("oracle_jre"); err != nil {
+			return fmt.Errorf("invalid memory calculator configuration: %w", err)
+		}
 	}
 
 	// Finalize Memory Calculator
@@ -16,6 +16,8 @@ type SapMachineJRE struct {
 	memoryCalc       *MemoryCalculator
 	jvmkill          *JVMKillAgent
 	installedVersion string
+
+	versionSelection
 }
 
 // NewSapMachineJRE creates a new SAP Machine JRE provider
@@ -44,7 +46,7 @@ func (s *SapMachineJRE) Supply() error {
 	s.ctx.Log.BeginStep("Installing SAP Machine JRE")
 
 	// Determine version
-	dep, err := GetJREVersion(s.ctx, "sapmachine")
+	dep, err := GetJREVersion(s.ctx, "sapmachine", s.selectedVersion)
 	if err != nil {
 		return fmt.Errorf("failed to determine SAP Machine version from manifest: %w", err)
 	}
@@ -53,9 +55,11 @@ func (s *SapMachineJRE) Supply() error {
 	s.ctx.Log.Info("Installing SAP Machine (%s)", s.version)
 
 	// Install JRE
-	if err := s.ctx.Installer.InstallDependency(dep, s.jreDir); err != nil {
+	installed, err := InstallJRE(s.ctx, dep, s.jreDir)
+	if err != nil {
 		return fmt.Errorf("failed to install SAP Machine: %w", err)
 	}
+	s.version = installed.Version
 
 	// Find the actual JAVA_HOME (handle nested directories from tar extraction)
 	javaHome, err := s.findJavaHome()
@@ -89,6 +93,9 @@ func (s *SapMachineJRE) Supply() error {
 
 	// Install Memory Calculator
 	s.memoryCalc = NewMemoryCalculator(s.ctx, s.jreDir, s.version, javaMajorVersion)
+	if err := s.memoryCalc.LoadConfig("sap_machine_jre"); err != nil {
+		return fmt.Errorf("invalid memory calculator configuration: %w", err)
+	}
 	if err := s.memoryCalc.Supply(); err != nil {
 		s.ctx.Log.Warning("Failed to install Memory Calculator: %s (continuing)", err.Error())
 		// Non-fatal - continue without memory calculator
@@ -144,6 +151,9 @@ func (s *SapMachineJRE) Finalize() error {
 	// Reconstruct Memory Calculator component if not already set
 	if s.memoryCalc == nil {
 		s.memoryCalc = NewMemoryCalculator(s.ctx, s.jreDir, s.version, javaMajorVersion)
+		if err := s.memoryCalc.LoadConfig("sap_machine_jre"); err != nil {
+			return fmt.Errorf("invalid memory calculator configuration: %w", err)
+		}
 	}
 
 	// Finalize Memory Calculator
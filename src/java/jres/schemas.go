@@ -0,0 +1,48 @@
+package jres
+
+import (
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+)
+
+// jreComponentConfig is the JBP_CONFIG_<JRE> configuration shared by all JREs, e.g. JBP_CONFIG_OPEN_JDK_JRE
+type jreComponentConfig struct {
+	jreVersionConfig `yaml:",inline"`
+	MemoryCalculator MemoryCalculatorConfig `yaml:"memory_calculator"`
+}
+
+// Check reports memory calculator settings that LoadConfig rejects
+func (c *jreComponentConfig) Check() []string {
+	if err := c.MemoryCalculator.Validate(); err != nil {
+		return []string{err.Error()}
+	}
+	return nil
+}
+
+// graalVMComponentConfig is JBP_CONFIG_GRAAL_VM_JRE
+type graalVMComponentConfig struct {
+	jreComponentConfig `yaml:",inline"`
+	graalVMConfig      `yaml:",inline"`
+}
+
+// ibmComponentConfig is JBP_CONFIG_IBM_JRE and JBP_CONFIG_SEMERU_JRE
+type ibmComponentConfig struct {
+	jreComponentConfig `yaml:",inline"`
+	ibmConfig          `yaml:",inline"`
+}
+
+func init() {
+	for component, schema := range map[string]config.Schema{
+		"fixed_memory": func() interface{} { return &FixedMemoryConfig{} },
+		"heap_dump":    func() interface{} { return &heapDumpConfig{} },
+		"jre":          func() interface{} { return &jreSelectionConfig{} },
+		"jvmkill":      func() interface{} { return &jvmKillConfig{} },
+	} {
+		config.RegisterSchema(component, schema)
+	}
+
+	for jreName := range jreNameToDocumentedEnvVar {
+		// The auto-generated variable, e.g. JBP_CONFIG_OPENJDK, only selects the version
+		config.RegisterSchema(jreName, func() interface{} { return &jreVersionConfig{} })
+		config.RegisterSchema(jreConfigComponent(jreName), func() interface{} { return newJREConfig(jreName) })
+	}
+}
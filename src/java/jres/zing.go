@@ -16,6 +16,8 @@ type ZingJRE struct {
 	version          string
 	javaHome         string
 	installedVersion string
+
+	versionSelection
 }
 
 // NewZingJRE creates a new Zing JRE provider
@@ -45,7 +47,7 @@ func (z *ZingJRE) Supply() error {
 	z.ctx.Log.BeginStep("Installing Zing JRE")
 
 	// Determine version
-	dep, err := GetJREVersion(z.ctx, "zing")
+	dep, err := GetJREVersion(z.ctx, "zing", z.selectedVersion)
 	if err != nil {
 		return fmt.Errorf("failed to determine Zing JRE version from manifest: %w", err)
 	}
@@ -54,9 +56,11 @@ func (z *ZingJRE) Supply() error {
 	z.ctx.Log.Info("Installing Zing JRE (%s)", z.version)
 
 	// Install JRE
-	if err := z.ctx.Installer.InstallDependency(dep, z.jreDir); err != nil {
+	installed, err := InstallJRE(z.ctx, dep, z.jreDir)
+	if err != nil {
 		return fmt.Errorf("failed to install Zing JRE: %w", err)
 	}
+	z.version = installed.Version
 
 	// Find the actual JAVA_HOME (handle nested directories from tar extraction)
 	javaHome, err := z.findJavaHome()
@@ -109,6 +113,11 @@ func (z *ZingJRE) Finalize() error {
 		// Non-fatal
 	}
 
+	// Zing sizes its own memory, but runs within the container's CPU limit like the other JREs
+	if err := writeContainerLimits(z.ctx); err != nil {
+		return err
+	}
+
 	z.ctx.Log.Info("Zing JRE finalization complete")
 	return nil
 }
@@ -16,6 +16,8 @@ type ZuluJRE struct {
 	memoryCalc       *MemoryCalculator
 	jvmkill          *JVMKillAgent
 	installedVersion string
+
+	versionSelection
 }
 
 // NewZuluJRE creates a new Zulu JRE provider
@@ -44,7 +46,7 @@ func (z *ZuluJRE) Supply() error {
 	z.ctx.Log.BeginStep("Installing Zulu JRE")
 
 	// Determine version
-	dep, err := GetJREVersion(z.ctx, "zulu")
+	dep, err := GetJREVersion(z.ctx, "zulu", z.selectedVersion)
 	if err != nil {
 		return fmt.Errorf("failed to determine Zulu version from manifest: %w", err)
 	}
@@ -53,9 +55,11 @@ func (z *ZuluJRE) Supply() error {
 	z.ctx.Log.Info("Installing Zulu (%s)", z.version)
 
 	// Install JRE
-	if err := z.ctx.Installer.InstallDependency(dep, z.jreDir); err != nil {
+	installed, err := InstallJRE(z.ctx, dep, z.jreDir)
+	if err != nil {
 		return fmt.Errorf("failed to install Zulu: %w", err)
 	}
+	z.version = installed.Version
 
 	// Find the actual JAVA_HOME (handle nested directories from tar extraction)
 	javaHome, err := z.findJavaHome()
@@ -89,6 +93,9 @@ func (z *ZuluJRE) Supply() error {
 
 	// Install Memory Calculator
 	z.memoryCalc = NewMemoryCalculator(z.ctx, z.jreDir, z.version, javaMajorVersion)
+	if err := z.memoryCalc.LoadConfig("zulu_jre"); err != nil {
+		return fmt.Errorf("invalid memory calculator configuration: %w", err)
+	}
 	if err := z.memoryCalc.Supply(); err != nil {
 		z.ctx.Log.Warning("Failed to install Memory Calculator: %s (continuing)", err.Error())
 		// Non-fatal - continue without memory calculator
@@ -144,6 +151,9 @@ func (z *ZuluJRE) Finalize() error {
 	// Reconstruct Memory Calculator component if not already set
 	if z.memoryCalc == nil {
 		z.memoryCalc = NewMemoryCalculator(z.ctx, z.jreDir, z.version, javaMajorVersion)
+		if err := z.memoryCalc.LoadConfig("zulu_jre"); err != nil {
+			return fmt.Errorf("invalid memory calculator configuration: %w", err)
+		}
 	}
 
 	// Finalize Memory Calculator
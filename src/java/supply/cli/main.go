@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
 	_ "github.com/cloudfoundry/java-buildpack/src/java/hooks" // Register hooks (Dynatrace)
 	"github.com/cloudfoundry/java-buildpack/src/java/supply"
 	"github.com/cloudfoundry/libbuildpack"
@@ -35,8 +36,19 @@ func main() {
 		os.Exit(10)
 	}
 
-	installer := libbuildpack.NewInstaller(manifest)
+	installer, err := common.NewDependencyInstaller(manifest, logger)
+	if err != nil {
+		logger.Error("Unable to load dependency mirrors: %s", err.Error())
+		os.Exit(10)
+	}
 	stager := libbuildpack.NewStager(os.Args[1:], logger, manifest)
+	installer.RecordInstallsTo(filepath.Join(stager.DepDir(), common.InstalledDependenciesFile))
+
+	// Explain an unsupported stack before libbuildpack rejects it as not found in the manifest
+	if err := common.CheckStack(manifest, common.Stack()); err != nil {
+		logger.Error("%s", err.Error())
+		os.Exit(11)
+	}
 
 	if err := stager.CheckBuildpackValid(); err != nil {
 		os.Exit(11)
@@ -0,0 +1,67 @@
+package supply
+
+import (
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/httpclient"
+	"github.com/cloudfoundry/java-buildpack/src/java/frameworks"
+	"github.com/cloudfoundry/java-buildpack/src/java/hooks"
+)
+
+// endpointCheckConfig enables probing the backends of the installed agents at staging, e.g.
+//
+//	enabled: true
+//	timeout: 5
+type endpointCheckConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Timeout bounds probing a single endpoint, in seconds
+	Timeout int `yaml:"timeout"`
+}
+
+// checkEndpoints probes the endpoints reported by the detected frameworks through frameworks.EndpointProvider and
+// the Dynatrace cluster of a bound Dynatrace service, and logs whether they are reachable. Unreachable endpoints are
+// reported as warnings so that egress and firewall misconfigurations surface at staging rather than as agents that
+// silently fail to report at runtime; they do not fail staging.
+func (s *Supplier) checkEndpoints(detected []frameworks.Framework, names []string) error {
+	cfg := endpointCheckConfig{Timeout: 5}
+	if err := config.Load(s.Log, "endpoint_check", &cfg); err != nil {
+		return err
+	}
+	if !cfg.Enabled {
+		return nil
+	}
+
+	type endpoint struct {
+		owner string
+		url   string
+	}
+	var endpoints []endpoint
+	for i, framework := range detected {
+		if provider, ok := framework.(frameworks.EndpointProvider); ok {
+			for _, url := range provider.Endpoints() {
+				endpoints = append(endpoints, endpoint{owner: names[i], url: url})
+			}
+		}
+	}
+	if url := hooks.DynatraceEndpoint(); url != "" {
+		endpoints = append(endpoints, endpoint{owner: "Dynatrace OneAgent", url: url})
+	}
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	s.Log.BeginStep("Checking agent endpoints")
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	for _, e := range endpoints {
+		addresses, err := httpclient.Default().Probe(e.url, timeout)
+		if err != nil {
+			s.Log.Warning("%s endpoint %s is not reachable from staging: %s. "+
+				"The agent will not report data unless egress to it is allowed", e.owner, e.url, err.Error())
+			continue
+		}
+		s.Log.Info("%s endpoint %s is reachable (%s)", e.owner, e.url, strings.Join(addresses, ", "))
+	}
+	return nil
+}
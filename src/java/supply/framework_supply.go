@@ -0,0 +1,186 @@
+package supply
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common/components"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/interrupt"
+	"github.com/cloudfoundry/java-buildpack/src/java/frameworks"
+)
+
+// frameworkSupplyConfig limits the installation of each framework, usually set by the operator with
+// JBP_DEFAULT_FRAMEWORK_SUPPLY, e.g.
+//
+//	timeout: 300
+//	continue_on_error: true
+//	strict_frameworks: [ContrastSecurityAgent, SeekerSecurityProvider]
+type frameworkSupplyConfig struct {
+	// Timeout bounds the installation of a single framework, in seconds; 0 does not limit it
+	Timeout int `yaml:"timeout"`
+	// ContinueOnError stages the application without a framework whose installation failed or timed out
+	ContinueOnError bool `yaml:"continue_on_error"`
+	// Strict fails staging when any framework cannot complete its installation, including the failures frameworks
+	// otherwise only warn about and continue_on_error
+	Strict bool `yaml:"strict"`
+	// StrictFrameworks are the frameworks that are strict if Strict is not set, by the name JBP_CONFIG_COMPONENTS
+	// lists them by, e.g. ContrastSecurityAgent
+	StrictFrameworks []string `yaml:"strict_frameworks"`
+}
+
+// isStrict returns true if the framework JBP_CONFIG_COMPONENTS lists as component is strict
+func (c frameworkSupplyConfig) isStrict(component string) bool {
+	if c.Strict {
+		return true
+	}
+	for _, name := range c.StrictFrameworks {
+		if component != "" && strings.EqualFold(components.ClassName(name), component) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateStrictFrameworks returns an error for the first strict framework that is none of the known components, so
+// that a typo does not silently stage an application without a mandated framework
+func (c frameworkSupplyConfig) validateStrictFrameworks(known []string) error {
+	for _, name := range c.StrictFrameworks {
+		found := false
+		for _, component := range known {
+			found = found || strings.EqualFold(components.ClassName(name), component)
+		}
+		if !found {
+			return fmt.Errorf("unknown framework %q in strict_frameworks of framework_supply, valid values: %s",
+				name, strings.Join(known, ", "))
+		}
+	}
+	return nil
+}
+
+// maxInterruptGrace bounds how long SupplyFramework waits for an interrupted framework to return
+const maxInterruptGrace = 30 * time.Second
+
+// TimeoutError is returned by SupplyFramework when a framework does not finish installing in time
+type TimeoutError struct {
+	Timeout time.Duration
+	// Abandoned is set if the framework did not return after it was interrupted and may still be installing
+	Abandoned bool
+}
+
+func (e *TimeoutError) Error() string {
+	if e.Abandoned {
+		return fmt.Sprintf("installation did not finish within %s and did not stop when it was interrupted", e.Timeout)
+	}
+	return fmt.Sprintf("installation did not finish within %s", e.Timeout)
+}
+
+// isAbandoned returns true if err is a TimeoutError of a framework that did not stop when it was interrupted
+func isAbandoned(err error) bool {
+	var timeoutErr *TimeoutError
+	return errors.As(err, &timeoutErr) && timeoutErr.Abandoned
+}
+
+// SupplyFramework runs framework.Supply and returns a TimeoutError if it does not return within timeout; a timeout
+// of 0 waits for it to return. After a timeout the downloads and commands of the framework are interrupted (see
+// package interrupt), and once Supply returned, whatever it added to depDir is removed, so that neither its files
+// nor its temporary files remain for the following frameworks. Supply gets as long as timeout, at most
+// maxInterruptGrace, to return after the interrupt; if it does not, the TimeoutError is Abandoned and the files are
+// left alone, since Supply may still be writing them.
+func SupplyFramework(framework frameworks.Framework, depDir string, timeout time.Duration) error {
+	if timeout <= 0 {
+		return framework.Supply()
+	}
+
+	existing, err := dirEntries(depDir)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer interrupt.Bind(ctx)()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- framework.Supply()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+	}
+
+	cancel()
+	grace := timeout
+	if grace > maxInterruptGrace {
+		grace = maxInterruptGrace
+	}
+	timeoutErr := &TimeoutError{Timeout: timeout}
+	select {
+	case <-done:
+	case <-time.After(grace):
+		timeoutErr.Abandoned = true
+		return timeoutErr
+	}
+
+	if err := removeAdded(depDir, existing); err != nil {
+		return fmt.Errorf("%w, and its files could not be removed: %s", timeoutErr, err.Error())
+	}
+	return timeoutErr
+}
+
+// dirEntries returns the names of the entries of dir
+func dirEntries(dir string) (map[string]bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	names := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		names[entry.Name()] = true
+	}
+	return names, nil
+}
+
+// removeAdded removes the entries of dir other than existing
+func removeAdded(dir string, existing map[string]bool) error {
+	entries, err := dirEntries(dir)
+	if err != nil {
+		return err
+	}
+	for name := range entries {
+		if existing[name] {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadFrameworkSupplyConfig reads the framework_supply configuration. The strict settings of the operator, from
+// the buildpack defaults file and JBP_DEFAULT_FRAMEWORK_SUPPLY, apply even if the application sets
+// JBP_CONFIG_FRAMEWORK_SUPPLY, which can only add to them.
+func (s *Supplier) loadFrameworkSupplyConfig() (frameworkSupplyConfig, error) {
+	cfg := frameworkSupplyConfig{}
+	if err := config.Load(s.Log, "framework_supply", &cfg); err != nil {
+		return cfg, err
+	}
+
+	operator := frameworkSupplyConfig{}
+	if err := config.DecodeOperator("framework_supply", &operator); err != nil {
+		return cfg, err
+	}
+	cfg.Strict = cfg.Strict || operator.Strict
+	cfg.StrictFrameworks = append(cfg.StrictFrameworks, operator.StrictFrameworks...)
+	return cfg, nil
+}
@@ -0,0 +1,248 @@
+package supply_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cloudfoundry/java-buildpack/src/java/common/interrupt"
+	"github.com/cloudfoundry/java-buildpack/src/java/frameworks"
+	"github.com/cloudfoundry/java-buildpack/src/java/supply"
+	"github.com/cloudfoundry/libbuildpack"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// stubFramework is a framework whose Supply takes delay and returns err
+type stubFramework struct {
+	delay time.Duration
+	err   error
+}
+
+func (f stubFramework) Detect() (string, error) { return "Stub", nil }
+func (f stubFramework) Finalize() error         { return nil }
+func (f stubFramework) Supply() error {
+	time.Sleep(f.delay)
+	return f.err
+}
+
+// hangingFramework is a framework whose Supply installs into dir and then runs a command that does not finish
+type hangingFramework struct {
+	dir string
+}
+
+func (f hangingFramework) Detect() (string, error) { return "Hanging", nil }
+func (f hangingFramework) Finalize() error         { return nil }
+func (f hangingFramework) Supply() error {
+	if err := os.MkdirAll(f.dir, 0755); err != nil {
+		return err
+	}
+	return interrupt.Command("sleep", "60").Run()
+}
+
+// stubbornFramework is a framework whose Supply installs into dir and then ignores interrupts until release is closed
+type stubbornFramework struct {
+	dir     string
+	release chan struct{}
+}
+
+func (f stubbornFramework) Detect() (string, error) { return "Stubborn", nil }
+func (f stubbornFramework) Finalize() error         { return nil }
+func (f stubbornFramework) Supply() error {
+	if err := os.MkdirAll(f.dir, 0755); err != nil {
+		return err
+	}
+	<-f.release
+	return nil
+}
+
+var _ = Describe("SupplyFramework", func() {
+	var depDir string
+
+	BeforeEach(func() {
+		var err error
+		depDir, err = os.MkdirTemp("", "dep")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(depDir)
+	})
+
+	It("returns the result of a framework that finishes in time", func() {
+		Expect(supply.SupplyFramework(stubFramework{}, depDir, time.Second)).To(Succeed())
+
+		failure := errors.New("download failed")
+		Expect(supply.SupplyFramework(stubFramework{err: failure}, depDir, time.Second)).To(MatchError(failure))
+	})
+
+	It("returns a TimeoutError for a framework that does not finish in time", func() {
+		err := supply.SupplyFramework(stubFramework{delay: 60 * time.Millisecond}, depDir, 50*time.Millisecond)
+
+		var timeoutErr *supply.TimeoutError
+		Expect(errors.As(err, &timeoutErr)).To(BeTrue())
+		Expect(err).To(MatchError("installation did not finish within 50ms"))
+	})
+
+	It("gives up on a framework that does not stop when it is interrupted and keeps its files", func() {
+		release := make(chan struct{})
+		defer close(release)
+
+		started := time.Now()
+		err := supply.SupplyFramework(stubbornFramework{dir: filepath.Join(depDir, "stubborn"), release: release}, depDir, 50*time.Millisecond)
+
+		var timeoutErr *supply.TimeoutError
+		Expect(errors.As(err, &timeoutErr)).To(BeTrue())
+		Expect(timeoutErr.Abandoned).To(BeTrue())
+		Expect(err).To(MatchError("installation did not finish within 50ms and did not stop when it was interrupted"))
+		Expect(time.Since(started)).To(BeNumerically("<", time.Second))
+		Expect(filepath.Join(depDir, "stubborn")).To(BeADirectory())
+	})
+
+	It("interrupts a framework that does not finish in time and removes what it installed", func() {
+		Expect(os.Mkdir(filepath.Join(depDir, "jre"), 0755)).To(Succeed())
+
+		started := time.Now()
+		err := supply.SupplyFramework(hangingFramework{dir: filepath.Join(depDir, "hanging")}, depDir, 100*time.Millisecond)
+
+		Expect(err).To(MatchError("installation did not finish within 100ms"))
+		Expect(time.Since(started)).To(BeNumerically("<", 10*time.Second))
+		Expect(filepath.Join(depDir, "hanging")).NotTo(BeAnExistingFile())
+		Expect(filepath.Join(depDir, "jre")).To(BeADirectory())
+	})
+
+	It("waits for the framework without a timeout", func() {
+		Expect(supply.SupplyFramework(stubFramework{delay: 20 * time.Millisecond}, depDir, 0)).To(Succeed())
+	})
+})
+
+var _ = Describe("SupplyFrameworks", func() {
+	var (
+		buffer     *bytes.Buffer
+		depsDir    string
+		supplier   *supply.Supplier
+		agent      *stubFramework
+		failing    *stubFramework
+		incomplete *stubFramework
+		candidates []frameworks.Candidate
+	)
+
+	BeforeEach(func() {
+		buffer = new(bytes.Buffer)
+		var err error
+		depsDir, err = os.MkdirTemp("", "deps")
+		Expect(err).NotTo(HaveOccurred())
+		logger := libbuildpack.NewLogger(buffer)
+		supplier = &supply.Supplier{
+			Stager: libbuildpack.NewStager([]string{depsDir, "", depsDir, "0"}, logger, &libbuildpack.Manifest{}),
+			Log:    logger,
+		}
+
+		agent = &stubFramework{}
+		failing = &stubFramework{err: errors.New("download failed")}
+		incomplete = &stubFramework{err: &frameworks.IncompleteError{Causes: []error{errors.New("could not install default configuration")}}}
+		candidates = []frameworks.Candidate{
+			{Component: "NewRelicAgent", Framework: agent, Allowed: true},
+			{Component: "SeekerSecurityProvider", Framework: failing, Allowed: true},
+			{Component: "ContrastSecurityAgent", Framework: incomplete, Allowed: true},
+		}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(depsDir)
+		os.Unsetenv("JBP_CONFIG_FRAMEWORK_SUPPLY")
+		os.Unsetenv("JBP_DEFAULT_FRAMEWORK_SUPPLY")
+	})
+
+	It("stages a framework that could not complete its installation with a warning", func() {
+		installed, names, err := supplier.SupplyFrameworks(candidates,
+			[]frameworks.Framework{agent, incomplete}, []string{"New Relic Agent", "Contrast Security Agent"})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(installed).To(Equal([]frameworks.Framework{agent, incomplete}))
+		Expect(names).To(Equal([]string{"New Relic Agent", "Contrast Security Agent"}))
+		Expect(buffer.String()).To(ContainSubstring("Framework Contrast Security Agent did not complete its installation, " +
+			"staging with it anyway: could not install default configuration"))
+	})
+
+	It("fails on a framework that could not complete its installation in strict mode", func() {
+		os.Setenv("JBP_CONFIG_FRAMEWORK_SUPPLY", "{strict: true}")
+
+		_, _, err := supplier.SupplyFrameworks(candidates,
+			[]frameworks.Framework{agent, incomplete}, []string{"New Relic Agent", "Contrast Security Agent"})
+
+		Expect(err).To(MatchError("framework Contrast Security Agent is strict and could not complete its installation: " +
+			"could not install default configuration"))
+	})
+
+	It("stages without a failed framework with continue_on_error unless it is strict", func() {
+		os.Setenv("JBP_CONFIG_FRAMEWORK_SUPPLY", "{continue_on_error: true}")
+
+		installed, _, err := supplier.SupplyFrameworks(candidates,
+			[]frameworks.Framework{agent, failing}, []string{"New Relic Agent", "Seeker Security Provider"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(installed).To(Equal([]frameworks.Framework{agent}))
+		Expect(supplier.SkippedFrameworks).To(Equal([]string{"Seeker Security Provider"}))
+
+		os.Setenv("JBP_CONFIG_FRAMEWORK_SUPPLY", "{continue_on_error: true, strict_frameworks: [SeekerSecurityProvider]}")
+
+		_, _, err = supplier.SupplyFrameworks(candidates,
+			[]frameworks.Framework{agent, failing}, []string{"New Relic Agent", "Seeker Security Provider"})
+		Expect(err).To(MatchError("framework Seeker Security Provider is strict and could not complete its installation: " +
+			"download failed"))
+	})
+
+	It("fails on a framework that keeps installing after its timeout despite continue_on_error", func() {
+		os.Setenv("JBP_CONFIG_FRAMEWORK_SUPPLY", "{continue_on_error: true, timeout: 1}")
+		release := make(chan struct{})
+		defer close(release)
+		stubborn := stubbornFramework{dir: filepath.Join(depsDir, "0", "stubborn"), release: release}
+
+		_, _, err := supplier.SupplyFrameworks(candidates,
+			[]frameworks.Framework{agent, stubborn}, []string{"New Relic Agent", "Stubborn"})
+		Expect(err).To(MatchError("failed to install framework Stubborn: installation did not finish within 1s " +
+			"and did not stop when it was interrupted"))
+		Expect(filepath.Join(depsDir, "0", "stubborn")).To(BeADirectory())
+	})
+
+	It("only makes the frameworks listed in strict_frameworks strict", func() {
+		os.Setenv("JBP_CONFIG_FRAMEWORK_SUPPLY", "{strict_frameworks: [SeekerSecurityProvider]}")
+
+		installed, _, err := supplier.SupplyFrameworks(candidates,
+			[]frameworks.Framework{agent, incomplete}, []string{"New Relic Agent", "Contrast Security Agent"})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(installed).To(HaveLen(2))
+		Expect(supplier.StrictFrameworks).To(BeEmpty())
+	})
+
+	It("records the strict frameworks it installed for the finalize phase", func() {
+		os.Setenv("JBP_CONFIG_FRAMEWORK_SUPPLY", "{strict_frameworks: [NewRelicAgent]}")
+
+		_, _, err := supplier.SupplyFrameworks(candidates, []frameworks.Framework{agent}, []string{"New Relic Agent"})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(supplier.StrictFrameworks).To(Equal([]string{"New Relic Agent"}))
+	})
+
+	It("keeps the operator's strict frameworks when the application sets its own configuration", func() {
+		os.Setenv("JBP_DEFAULT_FRAMEWORK_SUPPLY", "{strict_frameworks: [ContrastSecurityAgent]}")
+		os.Setenv("JBP_CONFIG_FRAMEWORK_SUPPLY", "{strict: false, timeout: 300}")
+
+		_, _, err := supplier.SupplyFrameworks(candidates,
+			[]frameworks.Framework{agent, incomplete}, []string{"New Relic Agent", "Contrast Security Agent"})
+
+		Expect(err).To(MatchError(ContainSubstring("framework Contrast Security Agent is strict")))
+	})
+
+	It("fails on an unknown framework in strict_frameworks", func() {
+		os.Setenv("JBP_CONFIG_FRAMEWORK_SUPPLY", "{strict_frameworks: [ContrastAgent]}")
+
+		_, _, err := supplier.SupplyFrameworks(candidates, []frameworks.Framework{agent}, []string{"New Relic Agent"})
+
+		Expect(err).To(MatchError(`unknown framework "ContrastAgent" in strict_frameworks of framework_supply, ` +
+			"valid values: NewRelicAgent, SeekerSecurityProvider, ContrastSecurityAgent"))
+	})
+})
@@ -0,0 +1,14 @@
+package supply
+
+import (
+	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/httpclient"
+)
+
+func init() {
+	config.RegisterSchema("dependency_installer", func() interface{} { return &common.InstallerConfig{} })
+	config.RegisterSchema("endpoint_check", func() interface{} { return &endpointCheckConfig{} })
+	config.RegisterSchema("framework_supply", func() interface{} { return &frameworkSupplyConfig{} })
+	config.RegisterSchema("http_client", func() interface{} { return &httpclient.Config{} })
+}
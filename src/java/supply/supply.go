@@ -2,9 +2,14 @@ package supply
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/cloudfoundry/java-buildpack/src/java/common"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/config"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/features"
+	"github.com/cloudfoundry/java-buildpack/src/java/common/httpclient"
 
 	"github.com/cloudfoundry/java-buildpack/src/java/containers"
 	"github.com/cloudfoundry/java-buildpack/src/java/frameworks"
@@ -19,12 +24,70 @@ type Supplier struct {
 	Log       *libbuildpack.Logger
 	Command   common.Command
 	Container containers.Container
+
+	// SkippedFrameworks are the frameworks whose installation failed or timed out and that the application is
+	// staged without, because framework_supply allows it
+	SkippedFrameworks []string
+	// StrictFrameworks are the installed frameworks that framework_supply makes strict, whose failures to finalize
+	// also fail staging
+	StrictFrameworks []string
+
+	disk *common.DiskGuard
 }
 
 // Run performs the supply phase
 func Run(s *Supplier) error {
 	s.Log.BeginStep("Supplying Java")
 
+	// Resolve ${VAR} references and adapt configuration written for the Ruby buildpack before any component reads it
+	common.InterpolateConfig(s.Log)
+	common.MigrateLegacyConfig(s.Log)
+
+	// Proxy, CA bundle, timeout and retry settings of the downloads that do not go through the manifest
+	httpConfig := httpclient.DefaultConfig()
+	if err := config.Load(s.Log, "http_client", &httpConfig); err != nil {
+		s.Log.Error("Invalid HTTP client configuration: %s", err.Error())
+		return err
+	}
+	if httpConfig.CABundle != "" && !filepath.IsAbs(httpConfig.CABundle) {
+		httpConfig.CABundle = filepath.Join(s.Stager.BuildDir(), httpConfig.CABundle)
+	}
+	if err := httpclient.Configure(httpConfig); err != nil {
+		s.Log.Error("Invalid HTTP client configuration: %s", err.Error())
+		return err
+	}
+	httpclient.Default().SetLogger(s.Log)
+	httpclient.Default().EnableCache(filepath.Join(s.Stager.CacheDir(), "downloads"))
+
+	// Patch version fallback and extraction of the dependencies installed from the manifest
+	installerConfig := common.DefaultInstallerConfig()
+	if err := config.Load(s.Log, "dependency_installer", &installerConfig); err != nil {
+		s.Log.Error("Invalid dependency installer configuration: %s", err.Error())
+		return err
+	}
+	if installer, ok := s.Installer.(interface{ Configure(common.InstallerConfig) }); ok {
+		installer.Configure(installerConfig)
+	}
+
+	// Check installations against the disk space left for staging and remove the temporary files of each step as
+	// soon as it completes, as a JRE and several agents with their downloads can exceed small staging disk quotas
+	s.disk = common.NewDiskGuard(s.Log, installerConfig.DiskLimit, s.Stager.DepDir(), s.Stager.BuildDir(), s.Stager.CacheDir())
+	if err := s.disk.UseTempDir(); err != nil {
+		s.Log.Warning("Could not create a temporary directory for staging, temporary files are not groomed: %s", err.Error())
+	}
+	defer s.disk.Close()
+	if installer, ok := s.Installer.(interface{ GuardDiskUsage(*common.DiskGuard) }); ok {
+		installer.GuardDiskUsage(s.disk)
+	}
+
+	// Report the feature flags once, the components they gate read them where they apply
+	flags, err := features.Load(s.Log)
+	if err != nil {
+		s.Log.Error("Invalid feature flags: %s", err.Error())
+		return err
+	}
+	flags.Log(s.Log)
+
 	// Create container context
 	ctx := &common.Context{
 		Stager:    s.Stager,
@@ -45,6 +108,13 @@ func Run(s *Supplier) error {
 		return err
 	}
 	if container == nil {
+		if artifacts := containers.WindowsArtifacts(s.Stager.BuildDir()); len(artifacts) > 0 {
+			s.Log.Error("No suitable container found for this application: it is a Windows application (%s). The Java "+
+				"buildpack runs applications on Linux: include the Linux start script of a distribution next to its .bat "+
+				"script, or push Windows applications with the hwc or binary buildpack on a Windows stack",
+				strings.Join(artifacts, ", "))
+			return fmt.Errorf("no suitable container found: %s only run on Windows", strings.Join(artifacts, ", "))
+		}
 		s.Log.Error("No suitable container found for this application")
 		return fmt.Errorf("no suitable container found")
 	}
@@ -57,6 +127,7 @@ func Run(s *Supplier) error {
 	if err != nil {
 		return err
 	}
+	s.disk.Groom(jreName)
 
 	// Install frameworks (APM agents, etc.)
 	if err := s.installFrameworks(); err != nil {
@@ -69,20 +140,29 @@ func Run(s *Supplier) error {
 		s.Log.Error("Failed to supply container: %s", err.Error())
 		return err
 	}
+	s.disk.Groom(containerName)
 
 	// Write all supply phase config in a single call so finalize can read it.
 	// WriteConfigYml always overwrites the file, so all keys must be written together.
 	// This follows the pattern established by go-buildpack and dotnet-core-buildpack.
 	if err := s.Stager.WriteConfigYml(map[string]string{
-		"container":   containerName,
-		"jre":         jreName,
-		"jre_version": jre.Version(),
-		"java_home":   jre.JavaHome(),
+		"container":          containerName,
+		"jre":                jreName,
+		"jre_version":        jre.Version(),
+		"java_home":          jre.JavaHome(),
+		"skipped_frameworks": strings.Join(s.SkippedFrameworks, ","),
+		"strict_frameworks":  strings.Join(s.StrictFrameworks, ","),
 	}); err != nil {
 		s.Log.Error("Could not write config: %s", err.Error())
 		return err
 	}
 
+	if err := config.WriteEffective(s.Log, filepath.Join(s.Stager.DepDir(), config.EffectiveConfigFile)); err != nil {
+		s.Log.Error("Could not write effective configuration: %s", err.Error())
+		return err
+	}
+
+	s.disk.Report()
 	return nil
 }
 
@@ -113,6 +193,12 @@ func (s *Supplier) installJRE() (jres.JRE, string, error) {
 
 	s.Log.Info("Selected JRE: %s", jreName)
 
+	// Fixed memory settings replace the memory calculator, so a missing -Xmx must fail staging
+	if _, err := jres.LoadFixedMemoryConfig(ctx); err != nil {
+		s.Log.Error("Invalid fixed memory configuration: %s", err.Error())
+		return nil, "", err
+	}
+
 	// Install the JRE
 	if err := jre.Supply(); err != nil {
 		s.Log.Error("Failed to install JRE: %s", err.Error())
@@ -140,29 +226,103 @@ func (s *Supplier) installFrameworks() error {
 
 	// Detect all frameworks that should be installed
 	detectedFrameworks, frameworkNames, err := registry.DetectAll()
+	if common.IsUnknownConfigKeysError(err) {
+		return err
+	}
 	if err != nil {
 		s.Log.Warning("Failed to detect frameworks: %s", err.Error())
 		return nil // Don't fail the build if framework detection fails
 	}
 
+	for _, deprecated := range registry.Deprecated(detectedFrameworks, frameworkNames) {
+		s.Log.Warning("%s", deprecated.Warning())
+	}
+
 	if len(detectedFrameworks) == 0 {
 		s.Log.Info("No frameworks detected")
-		return nil
+		return s.checkEndpoints(nil, nil)
+	}
+
+	candidates, err := registry.Candidates()
+	if err != nil {
+		return err
 	}
 
 	s.Log.BeginStep("Installing frameworks [%v]", strings.Join(frameworkNames, ", "))
 
-	// Install all detected frameworks
-	// Framework installation errors are fatal and will abort the build,
-	// matching the behavior of the Ruby buildpack
-	for i, framework := range detectedFrameworks {
-		s.Log.Info("Installing %s%s", frameworkNames[i], s.frameworkVersionSuffix(framework))
-		if err := framework.Supply(); err != nil {
-			return fmt.Errorf("failed to install framework %s: %w", frameworkNames[i], err)
+	installed, installedNames, err := s.SupplyFrameworks(candidates, detectedFrameworks, frameworkNames)
+	if err != nil {
+		return err
+	}
+
+	return s.checkEndpoints(installed, installedNames)
+}
+
+// SupplyFrameworks installs the detected frameworks, named names, and returns the ones the application is staged
+// with and their names. candidates are the registered frameworks, which strict_frameworks of framework_supply refers
+// to by their components.
+// Framework installation errors are fatal and will abort the build, matching the behavior of the Ruby buildpack,
+// unless framework_supply sets continue_on_error, the framework is not strict and it did not keep installing after
+// its timeout. A framework that could not complete its installation, returning a frameworks.IncompleteError, is
+// staged with a warning unless it is strict.
+func (s *Supplier) SupplyFrameworks(candidates []frameworks.Candidate, detected []frameworks.Framework, names []string) ([]frameworks.Framework, []string, error) {
+	supplyConfig, err := s.loadFrameworkSupplyConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	componentNames := make(map[frameworks.Framework]string, len(candidates))
+	var known []string
+	for _, candidate := range candidates {
+		if candidate.Component != "" {
+			componentNames[candidate.Framework] = candidate.Component
+			known = append(known, candidate.Component)
 		}
 	}
+	if err := supplyConfig.validateStrictFrameworks(known); err != nil {
+		return nil, nil, err
+	}
+	timeout := time.Duration(supplyConfig.Timeout) * time.Second
 
-	return nil
+	var installed []frameworks.Framework
+	var installedNames, failures []string
+	for i, framework := range detected {
+		s.Log.Info("Installing %s%s", names[i], s.frameworkVersionSuffix(framework))
+		err := SupplyFramework(framework, s.Stager.DepDir(), timeout)
+		if s.disk != nil {
+			s.disk.Groom(names[i])
+		}
+		strict := supplyConfig.isStrict(componentNames[framework])
+		if frameworks.IsIncompleteError(err) && !strict {
+			s.Log.Warning("Framework %s did not complete its installation, staging with it anyway: %s", names[i], err.Error())
+			err = nil
+		}
+		if err == nil {
+			installed = append(installed, framework)
+			installedNames = append(installedNames, names[i])
+			if strict {
+				s.StrictFrameworks = append(s.StrictFrameworks, names[i])
+			}
+			continue
+		}
+		if strict {
+			return nil, nil, fmt.Errorf("framework %s is strict and could not complete its installation: %w", names[i], err)
+		}
+		// A framework that is still installing could change the droplet of the following frameworks
+		if !supplyConfig.ContinueOnError || common.IsUnknownConfigKeysError(err) || isAbandoned(err) {
+			return nil, nil, fmt.Errorf("failed to install framework %s: %w", names[i], err)
+		}
+		s.Log.Warning("Failed to install framework %s, staging without it: %s", names[i], err.Error())
+		s.SkippedFrameworks = append(s.SkippedFrameworks, names[i])
+		failures = append(failures, fmt.Sprintf("%s (%s)", names[i], err.Error()))
+	}
+
+	if len(failures) > 0 {
+		s.Log.Warning("The application is staged without %d of %d frameworks: %s",
+			len(failures), len(detected), strings.Join(failures, "; "))
+	}
+
+	return installed, installedNames, nil
 }
 
 func (s *Supplier) frameworkVersionSuffix(framework frameworks.Framework) string {
@@ -1,6 +1,8 @@
 package supply_test
 
 import (
+	"bytes"
+	"io"
 	"os"
 	"path/filepath"
 	"time"
@@ -90,6 +92,9 @@ dependencies: []
 	})
 
 	Describe("Various Container Supply", func() {
+		// ccmInstallDir is where the client certificate mapper is installed, which depends on the application type
+		var ccmInstallDir string
+
 		BeforeEach(func() {
 			// create jdk install dir
 			jdkInstallDir := filepath.Join(depsDir, depsIdx, "jre")
@@ -121,20 +126,22 @@ dependencies: []
 			mockInstaller.EXPECT().InstallDependency(depMemCalc, memCalcInstallDir).Return(nil)
 
 			// adjust mocks for the mandatory frameworks used during staging
-			ccmInstallDir := filepath.Join(depsDir, depsIdx, "client_certificate_mapper")
-			Expect(os.MkdirAll(filepath.Join(ccmInstallDir), 0755)).To(Succeed())
+			ccmInstallDir = filepath.Join(depsDir, depsIdx, "client_certificate_mapper")
 			cspInstallDir := filepath.Join(depsDir, depsIdx, "container_security_provider")
 			Expect(os.MkdirAll(filepath.Join(cspInstallDir), 0755)).To(Succeed())
 
-			depClientCertificateMapper := libbuildpack.Dependency{Name: "client-certificate-mapper", Version: "2.0.1"}
-			mockManifest.EXPECT().DefaultVersion("client-certificate-mapper").Return(depClientCertificateMapper, nil).Times(2)
 			depContainerSecProvider := libbuildpack.Dependency{Name: "container-security-provider", Version: "1.20.0"}
 			mockManifest.EXPECT().DefaultVersion("container-security-provider").Return(depContainerSecProvider, nil).Times(2)
 
-			mockInstaller.EXPECT().InstallDependency(depClientCertificateMapper, ccmInstallDir).Return(nil)
 			mockInstaller.EXPECT().InstallDependency(depContainerSecProvider, cspInstallDir).Return(nil)
 		})
 
+		JustBeforeEach(func() {
+			depClientCertificateMapper := libbuildpack.Dependency{Name: "client-certificate-mapper", Version: "2.0.1"}
+			mockManifest.EXPECT().DefaultVersion("client-certificate-mapper").Return(depClientCertificateMapper, nil).Times(2)
+			mockInstaller.EXPECT().InstallDependency(depClientCertificateMapper, ccmInstallDir).Return(nil)
+		})
+
 		Context("When a Tomcat application is present", func() {
 			BeforeEach(func() {
 				// Create WEB-INF directory
@@ -171,6 +178,9 @@ dependencies: []
 				mockInstaller.EXPECT().InstallDependency(depTomcatLoggingSupport, tomcatLoggingSupportInstallDir).Return(nil)
 
 				mockManifest.EXPECT().GetEntry(depTomcatLoggingSupport).Return(&libbuildpack.ManifestEntry{}, nil)
+
+				// The client certificate mapper of servlet applications is installed as a Tomcat overlay
+				ccmInstallDir = filepath.Join(depsDir, depsIdx, "tomcat_overlays", "client_certificate_mapper", "lib")
 			})
 
 			It("Supply passes successfully", func() {
@@ -248,6 +258,18 @@ dependencies: []
 			It("Supply passes successfully", func() {
 				Expect(supply.Run(supplier)).To(Succeed())
 			})
+
+			It("reports the disk usage of staging and removes its temporary files", func() {
+				logs := new(bytes.Buffer)
+				supplier.Log = libbuildpack.NewLogger(io.MultiWriter(logs, GinkgoWriter))
+				tempDir := os.TempDir()
+
+				Expect(supply.Run(supplier)).To(Succeed())
+
+				Expect(logs.String()).To(MatchRegexp(`Staging uses \d+ MB of disk space \(\d+ MB before supply`))
+				Expect(os.TempDir()).To(Equal(tempDir))
+				Expect(filepath.Glob(filepath.Join(tempDir, "java-buildpack-staging*"))).To(BeEmpty())
+			})
 		})
 	})
 